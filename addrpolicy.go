@@ -0,0 +1,190 @@
+// Copyright (c) The Cryptorium Authors.
+// Licensed under the MIT License.
+
+package flashbot
+
+import (
+	"context"
+
+	"github.com/ethereum/go-ethereum/accounts/abi"
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/pkg/errors"
+)
+
+// CallTargetDecoder returns the addresses a TX calls through the called contract,
+// i.e. the targets of a multicall or a proxy execute call.
+type CallTargetDecoder func(to common.Address, data []byte) ([]common.Address, error)
+
+// AddressPolicy rejects the TXs whose to address or decoded inner call targets
+// aren't on the allowlist, when one is set, or are on the denylist.
+type AddressPolicy struct {
+	allow    map[common.Address]bool
+	deny     map[common.Address]bool
+	decoders []CallTargetDecoder
+}
+
+// NewAllowlist returns a policy that allows only the given addresses.
+// The contract creations are rejected as well.
+func NewAllowlist(addrs ...common.Address) *AddressPolicy {
+	return (&AddressPolicy{allow: make(map[common.Address]bool)}).Allow(addrs...)
+}
+
+// NewDenylist returns a policy that allows all but the given addresses.
+func NewDenylist(addrs ...common.Address) *AddressPolicy {
+	return (&AddressPolicy{}).Deny(addrs...)
+}
+
+// Allow adds the addresses to the allowlist and turns the policy into an allowlist when it is a denylist.
+func (self *AddressPolicy) Allow(addrs ...common.Address) *AddressPolicy {
+	if self.allow == nil {
+		self.allow = make(map[common.Address]bool)
+	}
+	for _, a := range addrs {
+		self.allow[a] = true
+	}
+	return self
+}
+
+// Deny adds the addresses to the denylist which takes precedence over the allowlist.
+func (self *AddressPolicy) Deny(addrs ...common.Address) *AddressPolicy {
+	if self.deny == nil {
+		self.deny = make(map[common.Address]bool)
+	}
+	for _, a := range addrs {
+		self.deny[a] = true
+	}
+	return self
+}
+
+// WithDecoder adds a decoder for the inner call targets which are checked the same way as the to address.
+func (self *AddressPolicy) WithDecoder(d CallTargetDecoder) *AddressPolicy {
+	self.decoders = append(self.decoders, d)
+	return self
+}
+
+// Check returns an ErrPolicyRejected when the call isn't allowed, a nil to is a contract creation.
+func (self *AddressPolicy) Check(to *common.Address, data []byte) error {
+	if to == nil {
+		if self.allow != nil {
+			return errors.Wrap(ErrPolicyRejected, "contract creation with an allowlist")
+		}
+		return nil
+	}
+	if err := self.checkAddr(*to, "to"); err != nil {
+		return err
+	}
+	for _, d := range self.decoders {
+		targets, err := d(*to, data)
+		if err != nil {
+			return errors.Wrapf(err, "decode call targets to:%v", to.Hex())
+		}
+		for _, t := range targets {
+			if err := self.checkAddr(t, "inner call target"); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+func (self *AddressPolicy) checkAddr(addr common.Address, kind string) error {
+	if self.deny[addr] {
+		return errors.Wrapf(ErrPolicyRejected, "%v address:%v is on the denylist", kind, addr.Hex())
+	}
+	if self.allow != nil && !self.allow[addr] {
+		return errors.Wrapf(ErrPolicyRejected, "%v address:%v isn't on the allowlist", kind, addr.Hex())
+	}
+	return nil
+}
+
+func (self *AddressPolicy) CheckTx(tx *types.Transaction) error {
+	return self.Check(tx.To(), tx.Data())
+}
+
+// CheckSetCodeTx checks the recipient and the calls of a set code TX
+// and the delegate addresses of its authorizations.
+func (self *AddressPolicy) CheckSetCodeTx(tx *SetCodeTx) error {
+	to := tx.To
+	if err := self.Check(&to, tx.Data); err != nil {
+		return err
+	}
+	for _, auth := range tx.AuthList {
+		if err := self.checkAddr(auth.Address, "authorization delegate"); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// CheckBundle checks all signed TXs of the bundle.
+func (self *AddressPolicy) CheckBundle(txsHex []string) error {
+	for i, txHex := range txsHex {
+		tx, err := decodeBundleTx(txHex)
+		if err != nil {
+			return errors.Wrapf(err, "decode TX index:%v", i)
+		}
+		if tx.setCode != nil {
+			err = self.CheckSetCodeTx(tx.setCode)
+		} else {
+			err = self.CheckTx(tx.tx)
+		}
+		if err != nil {
+			return errors.Wrapf(err, "TX index:%v hash:%v", i, tx.hash.Hex())
+		}
+	}
+	return nil
+}
+
+// TxOption returns an option that rejects building the TXs not allowed by the policy.
+func (self *AddressPolicy) TxOption() TxOption {
+	return func(ctx context.Context, from common.Address, spec *TxSpec) error {
+		return self.Check(spec.To, spec.Data)
+	}
+}
+
+// ABICallTargets returns a decoder for the calls of the ABI methods
+// that returns all address and address array arguments as call targets.
+// The calls of unknown methods have no targets.
+func ABICallTargets(parsed *abi.ABI) CallTargetDecoder {
+	return func(to common.Address, data []byte) ([]common.Address, error) {
+		if len(data) < 4 {
+			return nil, nil
+		}
+		method, err := parsed.MethodById(data[:4])
+		if err != nil {
+			return nil, nil
+		}
+		args, err := method.Inputs.Unpack(data[4:])
+		if err != nil {
+			return nil, errors.Wrapf(err, "unpack method:%v", method.Name)
+		}
+		var res []common.Address
+		for _, arg := range args {
+			switch v := arg.(type) {
+			case common.Address:
+				res = append(res, v)
+			case []common.Address:
+				res = append(res, v...)
+			}
+		}
+		return res, nil
+	}
+}
+
+// SetAddressPolicy enables the policy for the signed TXs of all sent bundles and private TXs.
+func (self *Flashbot) SetAddressPolicy(p *AddressPolicy) {
+	self.mtx.Lock()
+	defer self.mtx.Unlock()
+	self.addrPolicy = p
+}
+
+func (self *Flashbot) checkAddressPolicy(txsHex ...string) error {
+	self.mtx.RLock()
+	p := self.addrPolicy
+	self.mtx.RUnlock()
+	if p == nil {
+		return nil
+	}
+	return p.CheckBundle(txsHex)
+}
@@ -0,0 +1,105 @@
+// Copyright (c) The Cryptorium Authors.
+// Licensed under the MIT License.
+
+package flashbot
+
+import (
+	"context"
+	"encoding/json"
+	"math/big"
+	"strings"
+	"testing"
+
+	"github.com/cryptoriums/packages/testutil"
+	"github.com/ethereum/go-ethereum/accounts/abi"
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/pkg/errors"
+)
+
+func TestAddressPolicy(t *testing.T) {
+	a, b, c := common.HexToAddress("0xa"), common.HexToAddress("0xb"), common.HexToAddress("0xc")
+
+	allow := NewAllowlist(a, b).Deny(b)
+	testutil.Ok(t, allow.Check(&a, nil))
+	testutil.Assert(t, errors.Is(allow.Check(&b, nil), ErrPolicyRejected), "denied address allowed")
+	testutil.Assert(t, errors.Is(allow.Check(&c, nil), ErrPolicyRejected), "address not on the allowlist allowed")
+	testutil.Assert(t, errors.Is(allow.Check(nil, nil), ErrPolicyRejected), "contract creation allowed")
+
+	deny := NewDenylist(b)
+	testutil.Ok(t, deny.Check(&a, nil))
+	testutil.Ok(t, deny.Check(nil, nil))
+	testutil.NotOk(t, deny.Check(&b, nil))
+
+	parsed, err := abi.JSON(strings.NewReader(`[{"name":"execute","type":"function","inputs":[{"name":"target","type":"address"},{"name":"data","type":"bytes"}]}]`))
+	testutil.Ok(t, err)
+	inner := func(target common.Address) []byte {
+		data, err := parsed.Pack("execute", target, []byte{1})
+		testutil.Ok(t, err)
+		return data
+	}
+	allow = NewAllowlist(a).WithDecoder(ABICallTargets(&parsed))
+	testutil.Ok(t, allow.Check(&a, inner(a)))
+	testutil.Ok(t, allow.Check(&a, []byte{1, 2, 3, 4}))
+	err = allow.Check(&a, inner(c))
+	testutil.Assert(t, errors.Is(err, ErrPolicyRejected), "inner call target not checked:%v", err)
+}
+
+func TestAddressPolicySend(t *testing.T) {
+	ctx := context.Background()
+	relay := newRelayMock(t, func(method string, params json.RawMessage) (interface{}, *jsonError) {
+		return Result{BundleHash: "0xbundle"}, nil
+	})
+	fb := newTestFlashbot(t, relay.URL)
+
+	allowed, denied := common.HexToAddress("0xa"), common.HexToAddress("0xb")
+	policy := NewAllowlist(allowed)
+
+	_, _, err := fb.BuildTx(ctx, 1, 0, TxSpec{To: &denied, Gas: 21_000, GasFeeCap: big.NewInt(1)}, policy.TxOption())
+	testutil.Assert(t, errors.Is(err, ErrPolicyRejected), "TX build not rejected:%v", err)
+
+	txAllowed, _, err := fb.BuildTx(ctx, 1, 0, TxSpec{To: &allowed, Gas: 21_000, GasFeeCap: big.NewInt(1)})
+	testutil.Ok(t, err)
+	txDenied, _, err := fb.BuildTx(ctx, 1, 1, TxSpec{To: &denied, Gas: 21_000, GasFeeCap: big.NewInt(1)})
+	testutil.Ok(t, err)
+
+	fb.SetAddressPolicy(policy)
+	_, err = fb.SendBundle(ctx, []string{txAllowed, txDenied}, 10)
+	testutil.Assert(t, errors.Is(err, ErrPolicyRejected), "bundle not rejected:%v", err)
+	_, err = fb.SendPrivateTransaction(ctx, txDenied, 10, false)
+	testutil.Assert(t, errors.Is(err, ErrPolicyRejected), "private TX not rejected:%v", err)
+	_, err = fb.MevSendBundle(ctx, MevSendBundleParams{Inclusion: Inclusion{Block: "0xa"}, Body: []MevBundleItem{{Tx: txDenied}}})
+	testutil.Assert(t, errors.Is(err, ErrPolicyRejected), "MEV-Share bundle not rejected:%v", err)
+	testutil.Equals(t, 0, len(relay.Methods()))
+
+	_, err = fb.SendBundle(ctx, []string{txAllowed}, 10)
+	testutil.Ok(t, err)
+	testutil.Equals(t, []string{"eth_sendBundle"}, relay.Methods())
+}
+
+func TestAddressPolicySetCode(t *testing.T) {
+	ctx := context.Background()
+	relay := newRelayMock(t, func(method string, params json.RawMessage) (interface{}, *jsonError) {
+		return Result{BundleHash: "0xbundle"}, nil
+	})
+	fb := newTestFlashbot(t, relay.URL)
+	allowed, denied := common.HexToAddress("0xa"), common.HexToAddress("0xb")
+	fb.SetAddressPolicy(NewAllowlist(allowed))
+
+	setCode := func(to, delegate common.Address) string {
+		auth, err := fb.SignSetCodeAuthorization(1, delegate, 1)
+		testutil.Ok(t, err)
+		_, txHex, err := fb.NewSignedSetCodeTX(1, 0, to, nil, 100_000, big.NewInt(2e9), big.NewInt(1e9), nil, []SetCodeAuthorization{auth})
+		testutil.Ok(t, err)
+		return txHex
+	}
+
+	_, err := fb.SendBundle(ctx, []string{setCode(denied, allowed)}, 10)
+	testutil.Assert(t, errors.Is(err, ErrPolicyRejected), "set code TX recipient not checked:%v", err)
+	_, err = fb.SendBundle(ctx, []string{setCode(allowed, denied)}, 10)
+	testutil.Assert(t, errors.Is(err, ErrPolicyRejected), "authorization delegate not checked:%v", err)
+	testutil.Equals(t, 0, len(relay.Methods()))
+
+	_, err = fb.SendBundle(ctx, []string{setCode(allowed, allowed)}, 10)
+	testutil.Ok(t, err)
+	testutil.Equals(t, []string{"eth_sendBundle"}, relay.Methods())
+}
@@ -0,0 +1,230 @@
+// Copyright (c) The Cryptorium Authors.
+// Licensed under the MIT License.
+
+package flashbot
+
+import (
+	"sort"
+	"sync"
+	"time"
+)
+
+// DefaultAnalyticsRetention is how long the builder events are kept by default.
+const DefaultAnalyticsRetention = 7 * 24 * time.Hour
+
+// analyticsPruneInterval limits how often the expired events are removed.
+const analyticsPruneInterval = time.Minute
+
+type builderEventKind int
+
+const (
+	builderConsidered builderEventKind = iota
+	builderSealed
+	builderIncluded
+	builderWon
+)
+
+func (self builderEventKind) String() string {
+	switch self {
+	case builderConsidered:
+		return "considered"
+	case builderSealed:
+		return "sealed"
+	case builderIncluded:
+		return "included"
+	default:
+		return "won"
+	}
+}
+
+type builderEvent struct {
+	kind    builderEventKind
+	builder string
+	time    time.Time
+	latency time.Duration
+}
+
+// BuilderReport summarizes the bundle handling of a builder.
+type BuilderReport struct {
+	Builder    string
+	Considered int
+	Sealed     int
+	// Included counts the own bundles that landed in the blocks of the builder.
+	Included int
+	// InclusionRate is the share of the considered bundles that were included.
+	InclusionRate float64
+	// AvgSealLatency is the average time from the bundle submission to the seal.
+	AvgSealLatency time.Duration
+	// Wins counts the observed blocks built by the builder.
+	Wins     int
+	WinShare float64
+}
+
+// BuilderAnalytics tracks the per builder inclusion rate, seal latency and block win share
+// from the bundle stats, the own inclusions and the delivered payloads.
+// The events older than the retention are removed so the reports cover at most the retention window.
+type BuilderAnalytics struct {
+	mtx       sync.RWMutex
+	events    []builderEvent
+	retention time.Duration
+	pruned    time.Time
+	metrics   Metrics
+	now       func() time.Time
+}
+
+func NewBuilderAnalytics() *BuilderAnalytics {
+	return &BuilderAnalytics{now: time.Now, retention: DefaultAnalyticsRetention}
+}
+
+// SetRetention sets how long the events are kept, zero keeps them forever.
+func (self *BuilderAnalytics) SetRetention(d time.Duration) {
+	self.mtx.Lock()
+	defer self.mtx.Unlock()
+	self.retention = d
+	self.pruned = time.Time{}
+	self.prune()
+}
+
+// SetMetrics exports every recorded event as a MetricBuilderEvents count
+// and the seal latencies to MetricBuilderSealLatency.
+func (self *BuilderAnalytics) SetMetrics(m Metrics) {
+	self.mtx.Lock()
+	defer self.mtx.Unlock()
+	self.metrics = m
+}
+
+// add appends the event, exports it and removes the expired ones.
+// The caller holds the lock.
+func (self *BuilderAnalytics) add(e builderEvent) {
+	self.events = append(self.events, e)
+	if self.metrics != nil {
+		self.metrics.Add(MetricBuilderEvents, 1, "builder", e.builder, "kind", e.kind.String())
+		if e.kind == builderSealed && e.latency > 0 {
+			self.metrics.Observe(MetricBuilderSealLatency, e.latency.Seconds(), "builder", e.builder)
+		}
+	}
+	self.prune()
+}
+
+// prune removes the events older than the retention at most once per analyticsPruneInterval.
+// The stats events can arrive out of order so all events are checked.
+func (self *BuilderAnalytics) prune() {
+	now := self.now()
+	if self.retention <= 0 || now.Sub(self.pruned) < analyticsPruneInterval {
+		return
+	}
+	self.pruned = now
+	cutoff := now.Add(-self.retention)
+	kept := self.events[:0]
+	for _, e := range self.events {
+		if !e.time.Before(cutoff) {
+			kept = append(kept, e)
+		}
+	}
+	for i := len(kept); i < len(self.events); i++ {
+		self.events[i] = builderEvent{}
+	}
+	self.events = kept
+}
+
+// RecordStats records the builders that considered and sealed a bundle.
+// Call it once per bundle with the final stats.
+func (self *BuilderAnalytics) RecordStats(stats BundleStats) {
+	self.mtx.Lock()
+	defer self.mtx.Unlock()
+	for _, b := range stats.ConsideredByBuildersAt {
+		self.add(builderEvent{kind: builderConsidered, builder: b.Pubkey, time: b.Timestamp})
+	}
+	for _, b := range stats.SealedByBuildersAt {
+		e := builderEvent{kind: builderSealed, builder: b.Pubkey, time: b.Timestamp}
+		if !stats.SubmittedAt.IsZero() {
+			e.latency = b.Timestamp.Sub(stats.SubmittedAt)
+		}
+		self.add(e)
+	}
+}
+
+// RecordInclusion records that an own bundle landed in a block built by the builder.
+func (self *BuilderAnalytics) RecordInclusion(builder string) {
+	self.mtx.Lock()
+	defer self.mtx.Unlock()
+	self.add(builderEvent{kind: builderIncluded, builder: builder, time: self.now()})
+}
+
+// RecordWinner records the builder of a delivered block.
+func (self *BuilderAnalytics) RecordWinner(trace BidTrace) {
+	self.mtx.Lock()
+	defer self.mtx.Unlock()
+	self.add(builderEvent{kind: builderWon, builder: trace.BuilderPubkey, time: self.now()})
+}
+
+// Reports returns the reports for the events since the given time, zero means all,
+// sorted by the win share.
+func (self *BuilderAnalytics) Reports(since time.Time) []BuilderReport {
+	self.mtx.RLock()
+	defer self.mtx.RUnlock()
+
+	type acc struct {
+		BuilderReport
+		latency  time.Duration
+		latencyN int
+	}
+	byBuilder := make(map[string]*acc)
+	wins := 0
+	for _, e := range self.events {
+		if !since.IsZero() && e.time.Before(since) {
+			continue
+		}
+		a := byBuilder[e.builder]
+		if a == nil {
+			a = &acc{BuilderReport: BuilderReport{Builder: e.builder}}
+			byBuilder[e.builder] = a
+		}
+		switch e.kind {
+		case builderConsidered:
+			a.Considered++
+		case builderSealed:
+			a.Sealed++
+			if e.latency > 0 {
+				a.latency += e.latency
+				a.latencyN++
+			}
+		case builderIncluded:
+			a.Included++
+		case builderWon:
+			a.Wins++
+			wins++
+		}
+	}
+
+	res := make([]BuilderReport, 0, len(byBuilder))
+	for _, a := range byBuilder {
+		if a.Considered > 0 {
+			a.InclusionRate = float64(a.Included) / float64(a.Considered)
+		}
+		if a.latencyN > 0 {
+			a.AvgSealLatency = a.latency / time.Duration(a.latencyN)
+		}
+		if wins > 0 {
+			a.WinShare = float64(a.Wins) / float64(wins)
+		}
+		res = append(res, a.BuilderReport)
+	}
+	sort.Slice(res, func(i, j int) bool {
+		if res[i].WinShare != res[j].WinShare {
+			return res[i].WinShare > res[j].WinShare
+		}
+		return res[i].Builder < res[j].Builder
+	})
+	return res
+}
+
+// Report returns the report of one builder for the events since the given time.
+func (self *BuilderAnalytics) Report(builder string, since time.Time) BuilderReport {
+	for _, r := range self.Reports(since) {
+		if r.Builder == builder {
+			return r
+		}
+	}
+	return BuilderReport{Builder: builder}
+}
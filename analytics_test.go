@@ -0,0 +1,67 @@
+// Copyright (c) The Cryptorium Authors.
+// Licensed under the MIT License.
+
+package flashbot
+
+import (
+	"testing"
+	"time"
+
+	"github.com/cryptoriums/packages/testutil"
+)
+
+func TestBuilderAnalytics(t *testing.T) {
+	a := NewBuilderAnalytics()
+	submitted := time.Now()
+	a.RecordStats(BundleStats{
+		SubmittedAt:            submitted,
+		ConsideredByBuildersAt: []BuilderStats{{Pubkey: "b1", Timestamp: submitted}, {Pubkey: "b2", Timestamp: submitted}},
+		SealedByBuildersAt:     []BuilderStats{{Pubkey: "b1", Timestamp: submitted.Add(200 * time.Millisecond)}},
+	})
+	a.RecordStats(BundleStats{
+		SubmittedAt:            submitted,
+		ConsideredByBuildersAt: []BuilderStats{{Pubkey: "b1", Timestamp: submitted}},
+		SealedByBuildersAt:     []BuilderStats{{Pubkey: "b1", Timestamp: submitted.Add(400 * time.Millisecond)}},
+	})
+	a.RecordInclusion("b1")
+	a.RecordWinner(BidTrace{BuilderPubkey: "b1"})
+	a.RecordWinner(BidTrace{BuilderPubkey: "b2"})
+	a.RecordWinner(BidTrace{BuilderPubkey: "b1"})
+
+	reports := a.Reports(time.Time{})
+	testutil.Equals(t, 2, len(reports))
+	b1 := reports[0]
+	testutil.Equals(t, "b1", b1.Builder)
+	testutil.Equals(t, 0.5, b1.InclusionRate)
+	testutil.Equals(t, 300*time.Millisecond, b1.AvgSealLatency)
+	testutil.Equals(t, 2.0/3, b1.WinShare)
+	testutil.Equals(t, 1, a.Report("b2", time.Time{}).Considered)
+	testutil.Equals(t, 0, a.Report("b2", time.Now().Add(time.Hour)).Considered)
+}
+
+func TestBuilderAnalyticsRetention(t *testing.T) {
+	a := NewBuilderAnalytics()
+	metrics := newMetricsMock()
+	a.SetMetrics(metrics)
+	now := time.Now()
+	a.now = func() time.Time { return now }
+	a.SetRetention(time.Hour)
+
+	a.RecordStats(BundleStats{
+		SubmittedAt:            now.Add(-2 * time.Hour),
+		ConsideredByBuildersAt: []BuilderStats{{Pubkey: "b1", Timestamp: now.Add(-2 * time.Hour)}},
+		SealedByBuildersAt:     []BuilderStats{{Pubkey: "b1", Timestamp: now.Add(-2*time.Hour + time.Second)}},
+	})
+	a.RecordWinner(BidTrace{BuilderPubkey: "b1"})
+	// The old stats are kept until the next prune.
+	testutil.Equals(t, 1, a.Report("b1", time.Time{}).Considered)
+
+	now = now.Add(analyticsPruneInterval)
+	a.RecordWinner(BidTrace{BuilderPubkey: "b2"})
+	testutil.Equals(t, 2, len(a.events))
+	testutil.Equals(t, BuilderReport{Builder: "b1", Wins: 1, WinShare: 0.5}, a.Report("b1", time.Time{}))
+
+	testutil.Equals(t, []float64{1}, metrics.get(MetricBuilderEvents, "builder", "b1", "kind", "considered"))
+	testutil.Equals(t, []float64{1}, metrics.get(MetricBuilderSealLatency, "builder", "b1"))
+	testutil.Equals(t, []float64{1}, metrics.get(MetricBuilderEvents, "builder", "b2", "kind", "won"))
+}
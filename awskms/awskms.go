@@ -0,0 +1,61 @@
+// Copyright (c) The Cryptorium Authors.
+// Licensed under the MIT License.
+
+// Package awskms provides an AWS KMS backend for the flashbot KMS signer.
+package awskms
+
+import (
+	"context"
+	"crypto/ecdsa"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/kms"
+	kmstypes "github.com/aws/aws-sdk-go-v2/service/kms/types"
+	"github.com/kachan28/flashbot"
+	"github.com/pkg/errors"
+)
+
+// Client is the subset of the AWS KMS client used by the backend.
+type Client interface {
+	GetPublicKey(ctx context.Context, params *kms.GetPublicKeyInput, optFns ...func(*kms.Options)) (*kms.GetPublicKeyOutput, error)
+	Sign(ctx context.Context, params *kms.SignInput, optFns ...func(*kms.Options)) (*kms.SignOutput, error)
+}
+
+// Backend signs with an ECC_SECG_P256K1 KMS key.
+type Backend struct {
+	client Client
+	keyID  string
+}
+
+func New(client Client, keyID string) *Backend {
+	return &Backend{client: client, keyID: keyID}
+}
+
+// NewSigner returns a flashbot signer for the given KMS key ID or alias.
+func NewSigner(ctx context.Context, client Client, keyID string) (flashbot.Signer, error) {
+	return flashbot.NewKMSSigner(ctx, New(client, keyID))
+}
+
+func (self *Backend) PublicKey(ctx context.Context) (*ecdsa.PublicKey, error) {
+	out, err := self.client.GetPublicKey(ctx, &kms.GetPublicKeyInput{KeyId: aws.String(self.keyID)})
+	if err != nil {
+		return nil, errors.Wrapf(err, "get public key id:%v", self.keyID)
+	}
+	if out.KeySpec != kmstypes.KeySpecEccSecgP256k1 {
+		return nil, errors.Errorf("unsupported key spec:%v id:%v", out.KeySpec, self.keyID)
+	}
+	return flashbot.ParsePKIXPublicKey(out.PublicKey)
+}
+
+func (self *Backend) SignDigest(ctx context.Context, digest []byte) ([]byte, error) {
+	out, err := self.client.Sign(ctx, &kms.SignInput{
+		KeyId:            aws.String(self.keyID),
+		Message:          digest,
+		MessageType:      kmstypes.MessageTypeDigest,
+		SigningAlgorithm: kmstypes.SigningAlgorithmSpecEcdsaSha256,
+	})
+	if err != nil {
+		return nil, errors.Wrapf(err, "sign id:%v", self.keyID)
+	}
+	return out.Signature, nil
+}
@@ -0,0 +1,138 @@
+// Copyright (c) The Cryptorium Authors.
+// Licensed under the MIT License.
+
+package flashbot
+
+import (
+	"context"
+	"net/http"
+	"net/url"
+	"strconv"
+	"time"
+
+	"github.com/pkg/errors"
+)
+
+const BlocksAPIURL = "https://blocks.flashbots.net"
+
+type FlashbotsBlock struct {
+	BlockNumber       uint64             `json:"block_number"`
+	Miner             string             `json:"miner"`
+	MinerReward       string             `json:"miner_reward"`
+	CoinbaseTransfers string             `json:"coinbase_transfers"`
+	GasUsed           uint64             `json:"gas_used"`
+	GasPrice          string             `json:"gas_price"`
+	Transactions      []FlashbotsBlockTx `json:"transactions"`
+}
+
+type FlashbotsBlockTx struct {
+	TransactionHash  string `json:"transaction_hash"`
+	TxIndex          int    `json:"tx_index"`
+	BundleType       string `json:"bundle_type"`
+	BundleIndex      int    `json:"bundle_index"`
+	BlockNumber      uint64 `json:"block_number"`
+	EOAAddress       string `json:"eoa_address"`
+	ToAddress        string `json:"to_address"`
+	GasUsed          uint64 `json:"gas_used"`
+	GasPrice         string `json:"gas_price"`
+	CoinbaseTransfer string `json:"coinbase_transfer"`
+	TotalMinerReward string `json:"total_miner_reward"`
+}
+
+type FlashbotsBlocksResponse struct {
+	Blocks            []FlashbotsBlock `json:"blocks"`
+	LatestBlockNumber uint64           `json:"latest_block_number"`
+}
+
+type FlashbotsTxsResponse struct {
+	Transactions      []FlashbotsBlockTx `json:"transactions"`
+	LatestBlockNumber uint64             `json:"latest_block_number"`
+}
+
+// BlocksQuery filters the blocks, the zero values are not sent.
+type BlocksQuery struct {
+	BlockNumber uint64
+	Miner       string
+	From        uint64
+	To          uint64
+	Limit       int
+}
+
+// BlocksAPI is a client for the Flashbots blocks API.
+type BlocksAPI struct {
+	baseURL string
+	client  *http.Client
+}
+
+// NewBlocksAPI returns a client for the API at the base URL, BlocksAPIURL by default.
+func NewBlocksAPI(baseURL string, client *http.Client) *BlocksAPI {
+	if baseURL == "" {
+		baseURL = BlocksAPIURL
+	}
+	if client == nil {
+		client = &http.Client{Timeout: 30 * time.Second}
+	}
+	return &BlocksAPI{baseURL: baseURL, client: client}
+}
+
+// Blocks returns the blocks with Flashbots bundles.
+func (self *BlocksAPI) Blocks(ctx context.Context, query BlocksQuery) (*FlashbotsBlocksResponse, error) {
+	q := url.Values{}
+	if query.BlockNumber != 0 {
+		q.Set("block_number", strconv.FormatUint(query.BlockNumber, 10))
+	}
+	if query.Miner != "" {
+		q.Set("miner", query.Miner)
+	}
+	if query.From != 0 {
+		q.Set("from", strconv.FormatUint(query.From, 10))
+	}
+	if query.To != 0 {
+		q.Set("to", strconv.FormatUint(query.To, 10))
+	}
+	if query.Limit != 0 {
+		q.Set("limit", strconv.Itoa(query.Limit))
+	}
+	resp := &FlashbotsBlocksResponse{}
+	if err := getJSON(ctx, self.client, self.url("/v1/blocks", q), resp); err != nil {
+		return nil, errors.Wrap(err, "flashbots blocks")
+	}
+	return resp, nil
+}
+
+// Block returns the block or nil when it has no Flashbots bundles.
+func (self *BlocksAPI) Block(ctx context.Context, blockNum uint64) (*FlashbotsBlock, error) {
+	resp, err := self.Blocks(ctx, BlocksQuery{BlockNumber: blockNum})
+	if err != nil {
+		return nil, err
+	}
+	for i := range resp.Blocks {
+		if resp.Blocks[i].BlockNumber == blockNum {
+			return &resp.Blocks[i], nil
+		}
+	}
+	return nil, nil
+}
+
+// Transactions returns the latest Flashbots TXs before the block, zero means the latest block.
+func (self *BlocksAPI) Transactions(ctx context.Context, before uint64, limit int) (*FlashbotsTxsResponse, error) {
+	q := url.Values{}
+	if before != 0 {
+		q.Set("before", strconv.FormatUint(before, 10))
+	}
+	if limit != 0 {
+		q.Set("limit", strconv.Itoa(limit))
+	}
+	resp := &FlashbotsTxsResponse{}
+	if err := getJSON(ctx, self.client, self.url("/v1/transactions", q), resp); err != nil {
+		return nil, errors.Wrap(err, "flashbots transactions")
+	}
+	return resp, nil
+}
+
+func (self *BlocksAPI) url(path string, q url.Values) string {
+	if len(q) == 0 {
+		return self.baseURL + path
+	}
+	return self.baseURL + path + "?" + q.Encode()
+}
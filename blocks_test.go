@@ -0,0 +1,46 @@
+// Copyright (c) The Cryptorium Authors.
+// Licensed under the MIT License.
+
+package flashbot
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/cryptoriums/packages/testutil"
+)
+
+func TestBlocksAPI(t *testing.T) {
+	ctx := context.Background()
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/v1/blocks":
+			testutil.Equals(t, "10", r.URL.Query().Get("block_number"))
+			testutil.Ok(t, json.NewEncoder(w).Encode(FlashbotsBlocksResponse{
+				Blocks: []FlashbotsBlock{{
+					BlockNumber:  10,
+					MinerReward:  "100",
+					Transactions: []FlashbotsBlockTx{{TransactionHash: "0x01", GasPrice: "5"}},
+				}},
+				LatestBlockNumber: 12,
+			}))
+		case "/v1/transactions":
+			testutil.Equals(t, "12", r.URL.Query().Get("before"))
+			testutil.Ok(t, json.NewEncoder(w).Encode(FlashbotsTxsResponse{Transactions: []FlashbotsBlockTx{{TransactionHash: "0x02"}}}))
+		}
+	}))
+	defer srv.Close()
+
+	api := NewBlocksAPI(srv.URL, nil)
+	block, err := api.Block(ctx, 10)
+	testutil.Ok(t, err)
+	testutil.Equals(t, "100", block.MinerReward)
+	testutil.Equals(t, "0x01", block.Transactions[0].TransactionHash)
+
+	txs, err := api.Transactions(ctx, 12, 0)
+	testutil.Ok(t, err)
+	testutil.Equals(t, "0x02", txs.Transactions[0].TransactionHash)
+}
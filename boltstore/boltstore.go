@@ -0,0 +1,100 @@
+// Copyright (c) The Cryptorium Authors.
+// Licensed under the MIT License.
+
+// Package boltstore implements a flashbot.BundleStore backed by a bbolt database file.
+package boltstore
+
+import (
+	"context"
+	"encoding/json"
+	"os"
+	"time"
+
+	"github.com/kachan28/flashbot"
+	"github.com/pkg/errors"
+	bolt "go.etcd.io/bbolt"
+)
+
+var bucketBundles = []byte("bundles")
+
+type Store struct {
+	db *bolt.DB
+}
+
+var _ flashbot.BundleStore = (*Store)(nil)
+
+// New opens or creates the database at the path.
+func New(path string) (*Store, error) {
+	db, err := bolt.Open(path, os.FileMode(0o600), &bolt.Options{Timeout: time.Second})
+	if err != nil {
+		return nil, errors.Wrapf(err, "open bolt db:%v", path)
+	}
+	err = db.Update(func(tx *bolt.Tx) error {
+		_, err := tx.CreateBucketIfNotExists(bucketBundles)
+		return err
+	})
+	if err != nil {
+		db.Close()
+		return nil, errors.Wrap(err, "create bundles bucket")
+	}
+	return &Store{db: db}, nil
+}
+
+func (self *Store) Save(ctx context.Context, b *flashbot.ManagedBundle) error {
+	data, err := json.Marshal(b)
+	if err != nil {
+		return errors.Wrap(err, "marshal bundle")
+	}
+	return self.db.Update(func(tx *bolt.Tx) error {
+		return tx.Bucket(bucketBundles).Put([]byte(b.ID), data)
+	})
+}
+
+func (self *Store) Get(ctx context.Context, id string) (*flashbot.ManagedBundle, error) {
+	b := &flashbot.ManagedBundle{}
+	err := self.db.View(func(tx *bolt.Tx) error {
+		data := tx.Bucket(bucketBundles).Get([]byte(id))
+		if data == nil {
+			return errors.Wrapf(flashbot.ErrBundleNotFound, "id:%v", id)
+		}
+		return errors.Wrap(json.Unmarshal(data, b), "unmarshal bundle")
+	})
+	if err != nil {
+		return nil, err
+	}
+	return b, nil
+}
+
+func (self *Store) List(ctx context.Context, filter flashbot.BundleFilter) ([]*flashbot.ManagedBundle, error) {
+	var res []*flashbot.ManagedBundle
+	err := self.db.View(func(tx *bolt.Tx) error {
+		return tx.Bucket(bucketBundles).ForEach(func(k, v []byte) error {
+			if err := ctx.Err(); err != nil {
+				return err
+			}
+			b := &flashbot.ManagedBundle{}
+			if err := json.Unmarshal(v, b); err != nil {
+				return errors.Wrapf(err, "unmarshal bundle id:%v", string(k))
+			}
+			if filter.Match(b) {
+				res = append(res, b)
+			}
+			return nil
+		})
+	})
+	if err != nil {
+		return nil, err
+	}
+	flashbot.SortBundles(res)
+	return res, nil
+}
+
+func (self *Store) Delete(ctx context.Context, id string) error {
+	return self.db.Update(func(tx *bolt.Tx) error {
+		return tx.Bucket(bucketBundles).Delete([]byte(id))
+	})
+}
+
+func (self *Store) Close() error {
+	return self.db.Close()
+}
@@ -0,0 +1,49 @@
+// Copyright (c) The Cryptorium Authors.
+// Licensed under the MIT License.
+
+package boltstore
+
+import (
+	"context"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/cryptoriums/packages/testutil"
+	"github.com/kachan28/flashbot"
+	"github.com/pkg/errors"
+)
+
+func TestStore(t *testing.T) {
+	ctx := context.Background()
+	path := filepath.Join(t.TempDir(), "bundles.db")
+	store, err := New(path)
+	testutil.Ok(t, err)
+
+	now := time.Now().UTC()
+	testutil.Ok(t, store.Save(ctx, &flashbot.ManagedBundle{ID: "a", State: flashbot.BundlePending, CreatedAt: now}))
+	testutil.Ok(t, store.Save(ctx, &flashbot.ManagedBundle{ID: "b", State: flashbot.BundleIncluded, CreatedAt: now.Add(time.Second)}))
+	testutil.Ok(t, store.Close())
+
+	store, err = New(path)
+	testutil.Ok(t, err)
+	defer store.Close()
+
+	b, err := store.Get(ctx, "a")
+	testutil.Ok(t, err)
+	testutil.Equals(t, flashbot.BundlePending, b.State)
+
+	all, err := store.List(ctx, flashbot.BundleFilter{})
+	testutil.Ok(t, err)
+	testutil.Equals(t, 2, len(all))
+	testutil.Equals(t, "a", all[0].ID)
+
+	included, err := store.List(ctx, flashbot.BundleFilter{States: []flashbot.BundleState{flashbot.BundleIncluded}})
+	testutil.Ok(t, err)
+	testutil.Equals(t, 1, len(included))
+	testutil.Equals(t, "b", included[0].ID)
+
+	testutil.Ok(t, store.Delete(ctx, "a"))
+	_, err = store.Get(ctx, "a")
+	testutil.Assert(t, errors.Is(err, flashbot.ErrBundleNotFound), "unexpected error:%v", err)
+}
@@ -0,0 +1,133 @@
+// Copyright (c) The Cryptorium Authors.
+// Licensed under the MIT License.
+
+package flashbot
+
+import (
+	"context"
+	"math/big"
+
+	"github.com/pkg/errors"
+)
+
+// BribeBuild builds the bundle paying the given bribe.
+type BribeBuild func(ctx context.Context, bribe *big.Int) ([]string, error)
+
+// BribeAccept reports whether the simulated bundle with the bribe meets the goal.
+// It must be monotonic, i.e. once accepted a higher bribe is accepted as well.
+type BribeAccept func(bribe *big.Int, sim *Response, profit *SimProfit) bool
+
+type TuneBribeOpts struct {
+	// Min and Max bound the search, Max is the highest bribe that still leaves the intended net profit.
+	Min *big.Int
+	Max *big.Int
+	// Precision stops the search when the range is narrower, one gwei by default.
+	Precision *big.Int
+	// MaxIterations limits the number of simulations, 32 by default.
+	MaxIterations int
+	Accept        BribeAccept
+	// StateBlock is the block whose state is used for the simulations, zero means the latest.
+	StateBlock uint64
+}
+
+type TunedBribe struct {
+	Bribe       *big.Int
+	Txs         []string
+	Sim         *Response
+	Profit      *SimProfit
+	Simulations int
+}
+
+// TuneBribe binary searches for the lowest bribe in the range that the Accept function accepts
+// and returns the bundle built with it.
+func (self *Flashbot) TuneBribe(ctx context.Context, build BribeBuild, opts TuneBribeOpts) (*TunedBribe, error) {
+	if opts.Max == nil || opts.Accept == nil {
+		return nil, errors.New("bribe tuning needs a max bribe and an accept function")
+	}
+	lo := new(big.Int)
+	if opts.Min != nil {
+		lo.Set(opts.Min)
+	}
+	hi := new(big.Int).Set(opts.Max)
+	if lo.Cmp(hi) > 0 {
+		return nil, errors.Errorf("min bribe:%v above max:%v", lo, hi)
+	}
+	precision := opts.Precision
+	if precision == nil || precision.Sign() <= 0 {
+		precision = big.NewInt(1e9)
+	}
+	maxIterations := opts.MaxIterations
+	if maxIterations <= 0 {
+		maxIterations = 32
+	}
+
+	res := &TunedBribe{}
+	try := func(bribe *big.Int) (*TunedBribe, bool, error) {
+		txs, err := build(ctx, bribe)
+		if err != nil {
+			return nil, false, errors.Wrapf(err, "build bundle bribe:%v", bribe)
+		}
+		sim, err := self.CallBundle(ctx, txs, opts.StateBlock)
+		res.Simulations++
+		if err != nil {
+			return nil, false, errors.Wrapf(err, "simulate bundle bribe:%v", bribe)
+		}
+		profit, err := ParseSimProfit(sim)
+		if err != nil {
+			return nil, false, err
+		}
+		t := &TunedBribe{Bribe: new(big.Int).Set(bribe), Txs: txs, Sim: sim, Profit: profit}
+		return t, opts.Accept(bribe, sim, profit), nil
+	}
+
+	best, ok, err := try(hi)
+	if err != nil {
+		return nil, err
+	}
+	if !ok {
+		return nil, errors.Errorf("max bribe:%v not accepted", hi)
+	}
+
+	for i := 1; i < maxIterations && new(big.Int).Sub(hi, lo).Cmp(precision) > 0; i++ {
+		mid := new(big.Int).Add(lo, hi)
+		mid.Rsh(mid, 1)
+		t, ok, err := try(mid)
+		if err != nil {
+			return nil, err
+		}
+		if ok {
+			hi, best = mid, t
+		} else {
+			lo = mid
+		}
+	}
+	best.Simulations = res.Simulations
+	return best, nil
+}
+
+// CoinbaseBribe builds the bundle by appending a coinbase payment with the bribe as the amount.
+func (self *Flashbot) CoinbaseBribe(txsHex []string, netID int64, nonce uint64, payment CoinbasePayment) BribeBuild {
+	return func(ctx context.Context, bribe *big.Int) ([]string, error) {
+		payment.Amount = bribe
+		return self.AppendCoinbasePayment(txsHex, netID, nonce, payment)
+	}
+}
+
+// AcceptBundleGasPrice accepts the bundles that succeed and pay the builder
+// at least the given price per unit of gas.
+func AcceptBundleGasPrice(minGasPrice *big.Int) BribeAccept {
+	return func(bribe *big.Int, sim *Response, profit *SimProfit) bool {
+		var gasUsed uint64
+		for _, r := range sim.Results {
+			if r.Error != "" || r.Revert != "" {
+				return false
+			}
+			gasUsed += r.GasUsed
+		}
+		if gasUsed == 0 {
+			return false
+		}
+		price := new(big.Int).Div(profit.CoinbaseDiff, new(big.Int).SetUint64(gasUsed))
+		return price.Cmp(minGasPrice) >= 0
+	}
+}
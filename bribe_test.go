@@ -0,0 +1,49 @@
+// Copyright (c) The Cryptorium Authors.
+// Licensed under the MIT License.
+
+package flashbot
+
+import (
+	"context"
+	"encoding/json"
+	"math/big"
+	"testing"
+
+	"github.com/cryptoriums/packages/testutil"
+	"github.com/ethereum/go-ethereum/common"
+)
+
+func TestTuneBribe(t *testing.T) {
+	ctx := context.Background()
+	relay := newRelayMock(t, func(method string, params json.RawMessage) (interface{}, *jsonError) {
+		var p []ParamsCall
+		testutil.Ok(t, json.Unmarshal(params, &p))
+		payment, err := DecodeTx(p[0].Txs[len(p[0].Txs)-1])
+		testutil.Ok(t, err)
+		// The builder gets the gas fees and the payment.
+		coinbaseDiff := new(big.Int).Add(big.NewInt(21_000), payment.Value())
+		return Result{
+			Metadata: Metadata{CoinbaseDiff: coinbaseDiff.String(), GasFees: "21000"},
+			Results:  []TxResult{{GasUsed: 21_000}},
+		}, nil
+	})
+	fb := newTestFlashbot(t, relay.URL)
+
+	build := fb.CoinbaseBribe(nil, 1, 0, CoinbasePayment{
+		Recipient: common.HexToAddress("0xc0"),
+		GasFeeCap: big.NewInt(1),
+	})
+	res, err := fb.TuneBribe(ctx, build, TuneBribeOpts{
+		Min:       big.NewInt(1),
+		Max:       big.NewInt(1e9),
+		Precision: big.NewInt(1),
+		// 1 wei of gas fees plus at least 100 wei of payment per unit of gas.
+		Accept: AcceptBundleGasPrice(big.NewInt(101)),
+	})
+	testutil.Ok(t, err)
+	testutil.Equals(t, big.NewInt(2_100_000), res.Bribe)
+	testutil.Equals(t, len(relay.Methods()), res.Simulations)
+
+	_, err = fb.TuneBribe(ctx, build, TuneBribeOpts{Max: big.NewInt(1), Accept: AcceptBundleGasPrice(big.NewInt(101))})
+	testutil.NotOk(t, err)
+}
@@ -0,0 +1,196 @@
+// Copyright (c) The Cryptorium Authors.
+// Licensed under the MIT License.
+
+package flashbot
+
+import (
+	"math/big"
+	"sync"
+	"time"
+
+	"github.com/pkg/errors"
+)
+
+// ErrBudgetExceeded is returned when a bundle would push the spending over the cap.
+var ErrBudgetExceeded = errors.New("spending budget exceeded")
+
+type budgetSpend struct {
+	key    string
+	time   time.Time
+	amount *big.Int
+}
+
+// BudgetGuard tracks the gas fees and the coinbase payments of the bundles over a rolling window
+// and rejects the submissions whose cost would exceed the cap.
+// A submission reserves its cost before it is sent so that concurrent submissions can't overspend,
+// the reservation is then committed with the realized spend or released when the bundle isn't sent.
+type BudgetGuard struct {
+	mtx    sync.Mutex
+	cap    *big.Int
+	window time.Duration
+	spends []*budgetSpend
+	now    func() time.Time
+}
+
+func NewBudgetGuard(cap *big.Int, window time.Duration) *BudgetGuard {
+	return &BudgetGuard{cap: new(big.Int).Set(cap), window: window, now: time.Now}
+}
+
+// Reserve atomically reserves the amount for the key, usually the bundle hash,
+// and returns ErrBudgetExceeded when it would push the spending over the cap.
+// A key that is already reserved isn't counted twice, i.e. a bundle sent for several blocks
+// and only a higher amount reserves the difference.
+func (self *BudgetGuard) Reserve(key string, amount *big.Int) error {
+	_, err := self.reserve(key, amount)
+	return err
+}
+
+// reserve returns whether the call added the reservation so that the caller
+// only releases what it reserved.
+func (self *BudgetGuard) reserve(key string, amount *big.Int) (bool, error) {
+	self.mtx.Lock()
+	defer self.mtx.Unlock()
+	spent := self.spentLocked()
+	s := self.findLocked(key)
+	extra := amount
+	if s != nil {
+		if amount.Cmp(s.amount) <= 0 {
+			return false, nil
+		}
+		extra = new(big.Int).Sub(amount, s.amount)
+	}
+	if new(big.Int).Add(spent, extra).Cmp(self.cap) > 0 {
+		return false, errors.Wrapf(ErrBudgetExceeded, "spent:%v amount:%v cap:%v window:%v", spent, amount, self.cap, self.window)
+	}
+	if s != nil {
+		s.amount = new(big.Int).Set(amount)
+		return false, nil
+	}
+	self.spends = append(self.spends, &budgetSpend{key: key, time: self.now(), amount: new(big.Int).Set(amount)})
+	return true, nil
+}
+
+// Commit replaces the reservation of the key with the realized spend, i.e. once the bundle is included.
+// A key without a reservation adds the spend.
+func (self *BudgetGuard) Commit(key string, amount *big.Int) {
+	self.mtx.Lock()
+	defer self.mtx.Unlock()
+	self.spentLocked()
+	if s := self.findLocked(key); s != nil {
+		s.amount = new(big.Int).Set(amount)
+		return
+	}
+	self.spends = append(self.spends, &budgetSpend{key: key, time: self.now(), amount: new(big.Int).Set(amount)})
+}
+
+// Release drops the reservation of the key, i.e. when the send failed or the bundle wasn't included.
+func (self *BudgetGuard) Release(key string) {
+	self.mtx.Lock()
+	defer self.mtx.Unlock()
+	for i, s := range self.spends {
+		if s.key == key {
+			self.spends = append(self.spends[:i], self.spends[i+1:]...)
+			return
+		}
+	}
+}
+
+// CommitPnL commits the gas and the coinbase payments of an included bundle under its ID.
+func (self *BudgetGuard) CommitPnL(p *BundlePnL) {
+	self.Commit(p.ID, new(big.Int).Add(p.GasPaid, p.CoinbasePaid))
+}
+
+// Spent returns the total spending in the current window.
+func (self *BudgetGuard) Spent() *big.Int {
+	self.mtx.Lock()
+	defer self.mtx.Unlock()
+	return self.spentLocked()
+}
+
+// Remaining returns how much can still be spent in the current window.
+func (self *BudgetGuard) Remaining() *big.Int {
+	self.mtx.Lock()
+	defer self.mtx.Unlock()
+	remaining := new(big.Int).Sub(self.cap, self.spentLocked())
+	if remaining.Sign() < 0 {
+		return new(big.Int)
+	}
+	return remaining
+}
+
+func (self *BudgetGuard) findLocked(key string) *budgetSpend {
+	if key == "" {
+		return nil
+	}
+	for _, s := range self.spends {
+		if s.key == key {
+			return s
+		}
+	}
+	return nil
+}
+
+func (self *BudgetGuard) spentLocked() *big.Int {
+	cutoff := self.now().Add(-self.window)
+	i := 0
+	for i < len(self.spends) && !self.spends[i].time.After(cutoff) {
+		i++
+	}
+	self.spends = self.spends[i:]
+
+	spent := new(big.Int)
+	for _, s := range self.spends {
+		spent.Add(spent, s.amount)
+	}
+	return spent
+}
+
+// SetBudget enforces the spending cap on all the bundle submissions.
+// Each bundle reserves the max its TXs can spend, the gas limit at the fee cap plus the value,
+// under its bundle hash before it is sent and the reservation is released when the send fails.
+// Commit the realized spend once the bundle is included, see CommitPnL.
+func (self *Flashbot) SetBudget(g *BudgetGuard) {
+	self.mtx.Lock()
+	defer self.mtx.Unlock()
+	self.budget = g
+}
+
+// reserveBudget returns the func that releases the reservation made by this call.
+func (self *Flashbot) reserveBudget(txsHex []string) (func(), error) {
+	self.mtx.RLock()
+	g := self.budget
+	self.mtx.RUnlock()
+	if g == nil {
+		return func() {}, nil
+	}
+	key, err := ComputeBundleHash(txsHex)
+	if err != nil {
+		return nil, errors.Wrap(err, "bundle hash for the budget")
+	}
+	cost, err := maxTxsCost(txsHex)
+	if err != nil {
+		return nil, err
+	}
+	reserved, err := g.reserve(key.Hex(), cost)
+	if err != nil {
+		return nil, err
+	}
+	if !reserved {
+		return func() {}, nil
+	}
+	return func() { g.Release(key.Hex()) }, nil
+}
+
+// maxTxsCost returns the max the TXs can spend, the gas limit at the fee cap plus the value.
+func maxTxsCost(txsHex []string) (*big.Int, error) {
+	cost := new(big.Int)
+	for i, txHex := range txsHex {
+		tx, err := decodeBundleTx(txHex)
+		if err != nil {
+			return nil, errors.Wrapf(err, "decode TX index:%v", i)
+		}
+		cost.Add(cost, new(big.Int).Mul(new(big.Int).SetUint64(tx.gas), tx.gasFeeCap))
+		cost.Add(cost, tx.value)
+	}
+	return cost, nil
+}
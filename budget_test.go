@@ -0,0 +1,124 @@
+// Copyright (c) The Cryptorium Authors.
+// Licensed under the MIT License.
+
+package flashbot
+
+import (
+	"context"
+	"encoding/json"
+	"math/big"
+	"strconv"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/cryptoriums/packages/testutil"
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/pkg/errors"
+)
+
+func TestBudgetGuard(t *testing.T) {
+	now := time.Now()
+	guard := NewBudgetGuard(big.NewInt(1000), time.Hour)
+	guard.now = func() time.Time { return now }
+
+	testutil.Ok(t, guard.Reserve("a", big.NewInt(600)))
+	// The same bundle isn't counted twice.
+	testutil.Ok(t, guard.Reserve("a", big.NewInt(600)))
+	testutil.Equals(t, big.NewInt(600), guard.Spent())
+	now = now.Add(30 * time.Minute)
+	testutil.Ok(t, guard.Reserve("b", big.NewInt(400)))
+	err := guard.Reserve("c", big.NewInt(1))
+	testutil.Assert(t, errors.Is(err, ErrBudgetExceeded), "unexpected error:%v", err)
+
+	guard.Release("b")
+	guard.CommitPnL(&BundlePnL{ID: "a", GasPaid: big.NewInt(100), CoinbasePaid: big.NewInt(200)})
+	testutil.Equals(t, big.NewInt(300), guard.Spent())
+	testutil.Ok(t, guard.Reserve("c", big.NewInt(700)))
+	testutil.Equals(t, int64(0), guard.Remaining().Int64())
+
+	// The first spend leaves the window.
+	now = now.Add(31 * time.Minute)
+	testutil.Equals(t, big.NewInt(300), guard.Remaining())
+}
+
+func TestBudgetGuardConcurrent(t *testing.T) {
+	guard := NewBudgetGuard(big.NewInt(1000), time.Hour)
+	var (
+		wg       sync.WaitGroup
+		reserved int64
+	)
+	for i := 0; i < 50; i++ {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			if guard.Reserve(strconv.Itoa(i), big.NewInt(100)) == nil {
+				atomic.AddInt64(&reserved, 1)
+			}
+		}(i)
+	}
+	wg.Wait()
+	testutil.Equals(t, int64(10), reserved)
+	testutil.Equals(t, big.NewInt(1000), guard.Spent())
+}
+
+func TestSendBundleBudget(t *testing.T) {
+	ctx := context.Background()
+	var reject bool
+	relay := newRelayMock(t, func(method string, params json.RawMessage) (interface{}, *jsonError) {
+		if reject {
+			return nil, &jsonError{Code: -32000, Message: "rejected"}
+		}
+		return map[string]string{"bundleHash": "0x01"}, nil
+	})
+	fb := newTestFlashbot(t, relay.URL)
+	to := common.HexToAddress("0x02")
+	tx1, _, err := fb.BuildTx(ctx, 1, 0, TxSpec{To: &to, Gas: 21_000, GasFeeCap: big.NewInt(10)})
+	testutil.Ok(t, err)
+	tx2, _, err := fb.BuildTx(ctx, 1, 1, TxSpec{To: &to, Gas: 21_000, GasFeeCap: big.NewInt(10), Value: big.NewInt(1)})
+	testutil.Ok(t, err)
+
+	guard := NewBudgetGuard(big.NewInt(300_000), time.Hour)
+	fb.SetBudget(guard)
+
+	_, err = fb.SendBundle(ctx, []string{tx1}, 10)
+	testutil.Ok(t, err)
+	testutil.Equals(t, big.NewInt(210_000), guard.Spent())
+	// Sending the same bundle for the next block doesn't reserve it again.
+	_, err = fb.SendBundle(ctx, []string{tx1}, 11)
+	testutil.Ok(t, err)
+	_, err = fb.SendBundle(ctx, []string{tx2}, 11)
+	testutil.Assert(t, errors.Is(err, ErrBudgetExceeded), "unexpected error:%v", err)
+	testutil.Equals(t, 2, len(relay.Methods()))
+
+	// A failed send releases its reservation.
+	guard.Release(mustBundleHash(t, tx1))
+	reject = true
+	_, err = fb.SendBundle(ctx, []string{tx2}, 11)
+	testutil.NotOk(t, err)
+	testutil.Equals(t, int64(0), guard.Spent().Int64())
+
+	reject = false
+	_, err = fb.MevSendBundle(ctx, MevSendBundleParams{Inclusion: Inclusion{Block: "0xb"}, Body: []MevBundleItem{{Tx: tx2}}})
+	testutil.Ok(t, err)
+	testutil.Equals(t, big.NewInt(210_001), guard.Spent())
+}
+
+func mustBundleHash(t *testing.T, txsHex ...string) string {
+	hash, err := ComputeBundleHash(txsHex)
+	testutil.Ok(t, err)
+	return hash.Hex()
+}
+
+func TestSimulateAndSendBudget(t *testing.T) {
+	ctx := context.Background()
+	relay := newRelayMock(t, func(method string, params json.RawMessage) (interface{}, *jsonError) {
+		return Result{Metadata: Metadata{CoinbaseDiff: "1500", EthSentToCoinbase: "1000", GasFees: "500"}}, nil
+	})
+	fb := newTestFlashbot(t, relay.URL)
+
+	_, _, err := fb.SimulateAndSend(ctx, []string{"0x01"}, 10, SimulateAndSendOpts{Budget: NewBudgetGuard(big.NewInt(1499), time.Hour)})
+	testutil.Assert(t, errors.Is(err, ErrBudgetExceeded), "unexpected error:%v", err)
+	testutil.Equals(t, []string{"eth_callBundle"}, relay.Methods())
+}
@@ -0,0 +1,238 @@
+// Copyright (c) The Cryptorium Authors.
+// Licensed under the MIT License.
+
+package flashbot
+
+import (
+	"context"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/common/hexutil"
+	"github.com/ethereum/go-ethereum/crypto"
+	"github.com/pkg/errors"
+)
+
+// BundleBuilder assembles the eth_sendBundle params step by step.
+// The first error stops all further steps and is returned by Build.
+//
+//	params, err := fb.NewBundleBuilder(ctx, 1).
+//		Nonce(nonce).
+//		AddSignedTx(victimTx).
+//		AddTx(backrunSpec).
+//		AllowRevert(0).
+//		TargetBlock(head + 1).
+//		Build()
+type BundleBuilder struct {
+	ctx         context.Context
+	signer      Signer
+	netID       int64
+	nonce       *uint64
+	nonces      *NonceManager
+	opts        []TxOption
+	txs         []string
+	hashes      []common.Hash
+	allowRevert map[int]bool
+	blockNum    uint64
+	uuid        string
+	minTs       uint64
+	maxTs       uint64
+	err         error
+}
+
+// NewBundleBuilder returns a builder that signs the added TX specs with the signer.
+// The signer can be nil when only pre-signed TXs are added.
+func NewBundleBuilder(ctx context.Context, signer Signer, netID int64) *BundleBuilder {
+	return &BundleBuilder{
+		ctx:         ctx,
+		signer:      signer,
+		netID:       netID,
+		allowRevert: make(map[int]bool),
+	}
+}
+
+// NewBundleBuilder returns a builder that signs the added TX specs with the TX signer.
+func (self *Flashbot) NewBundleBuilder(ctx context.Context, netID int64) *BundleBuilder {
+	return NewBundleBuilder(ctx, self.TxSigner(), netID)
+}
+
+// Nonce sets the nonce of the first TX added with AddTx, the following ones are sequential.
+func (self *BundleBuilder) Nonce(nonce uint64) *BundleBuilder {
+	self.nonce = &nonce
+	return self
+}
+
+// NonceManager makes AddTx reserve the nonces from the manager.
+func (self *BundleBuilder) NonceManager(nonces *NonceManager) *BundleBuilder {
+	self.nonces = nonces
+	return self
+}
+
+// TxOptions are applied to every TX added with AddTx.
+func (self *BundleBuilder) TxOptions(opts ...TxOption) *BundleBuilder {
+	self.opts = append(self.opts, opts...)
+	return self
+}
+
+// AddSignedTx appends a hex encoded signed TX.
+func (self *BundleBuilder) AddSignedTx(txHex string) *BundleBuilder {
+	if self.err != nil {
+		return self
+	}
+	raw, err := hexutil.Decode(txHex)
+	if err != nil {
+		self.err = errors.Wrapf(err, "decode TX index:%v", len(self.txs))
+		return self
+	}
+	self.txs = append(self.txs, txHex)
+	self.hashes = append(self.hashes, crypto.Keccak256Hash(raw))
+	return self
+}
+
+// AddTx builds, signs and appends a TX.
+func (self *BundleBuilder) AddTx(spec TxSpec) *BundleBuilder {
+	if self.err != nil {
+		return self
+	}
+	if self.signer == nil {
+		self.err = errors.New("the builder has no signer")
+		return self
+	}
+
+	var nonce uint64
+	switch {
+	case self.nonces != nil:
+		n, err := self.nonces.Next(self.ctx, self.signer.Address())
+		if err != nil {
+			self.err = errors.Wrapf(err, "reserve nonce TX index:%v", len(self.txs))
+			return self
+		}
+		nonce = n
+	case self.nonce != nil:
+		nonce = *self.nonce
+		*self.nonce++
+	default:
+		self.err = errors.New("set the nonce or the nonce manager before adding TXs")
+		return self
+	}
+
+	txHex, tx, err := BuildTx(self.ctx, self.signer, self.netID, nonce, spec, self.opts...)
+	if err != nil {
+		if self.nonces != nil {
+			self.nonces.Release(self.signer.Address(), nonce, 1)
+		}
+		self.err = errors.Wrapf(err, "build TX index:%v", len(self.txs))
+		return self
+	}
+	self.txs = append(self.txs, txHex)
+	self.hashes = append(self.hashes, tx.Hash())
+	return self
+}
+
+// AddTxs builds, signs and appends the TXs concurrently, see SignAll.
+// The nonces are assigned in the order of the specs.
+func (self *BundleBuilder) AddTxs(specs ...TxSpec) *BundleBuilder {
+	if self.err != nil || len(specs) == 0 {
+		return self
+	}
+	if self.signer == nil {
+		self.err = errors.New("the builder has no signer")
+		return self
+	}
+
+	var nonce uint64
+	switch {
+	case self.nonces != nil:
+		n, err := self.nonces.Reserve(self.ctx, self.signer.Address(), uint64(len(specs)))
+		if err != nil {
+			self.err = errors.Wrapf(err, "reserve nonces TX index:%v", len(self.txs))
+			return self
+		}
+		nonce = n
+	case self.nonce != nil:
+		nonce = *self.nonce
+	default:
+		self.err = errors.New("set the nonce or the nonce manager before adding TXs")
+		return self
+	}
+
+	txsHex, txs, err := SignAll(self.ctx, self.signer, self.netID, sequentialJobs(nonce, specs), 0, self.opts...)
+	if err != nil {
+		if self.nonces != nil {
+			self.nonces.Release(self.signer.Address(), nonce, uint64(len(specs)))
+		}
+		self.err = errors.Wrapf(err, "build TXs from index:%v", len(self.txs))
+		return self
+	}
+	if self.nonce != nil {
+		*self.nonce += uint64(len(specs))
+	}
+	self.txs = append(self.txs, txsHex...)
+	for _, tx := range txs {
+		self.hashes = append(self.hashes, tx.Hash())
+	}
+	return self
+}
+
+// AllowRevert allows the TX at the index to revert without invalidating the bundle.
+func (self *BundleBuilder) AllowRevert(i int) *BundleBuilder {
+	self.allowRevert[i] = true
+	return self
+}
+
+func (self *BundleBuilder) TargetBlock(blockNum uint64) *BundleBuilder {
+	self.blockNum = blockNum
+	return self
+}
+
+// ReplacementUUID allows replacing or canceling the bundle with a later submission using the same UUID.
+func (self *BundleBuilder) ReplacementUUID(uuid string) *BundleBuilder {
+	self.uuid = uuid
+	return self
+}
+
+// Timestamps sets the unix timestamp range in which the bundle is valid, zero means no limit.
+func (self *BundleBuilder) Timestamps(minTs uint64, maxTs uint64) *BundleBuilder {
+	self.minTs = minTs
+	self.maxTs = maxTs
+	return self
+}
+
+// Txs returns the hex encoded TXs added so far.
+func (self *BundleBuilder) Txs() []string {
+	return append([]string(nil), self.txs...)
+}
+
+func (self *BundleBuilder) Build() (ParamsSend, error) {
+	if self.err != nil {
+		return ParamsSend{}, self.err
+	}
+	if len(self.txs) == 0 {
+		return ParamsSend{}, errors.New("bundle has no TXs")
+	}
+	if self.blockNum == 0 {
+		return ParamsSend{}, errors.New("bundle target block is not set")
+	}
+	if self.maxTs != 0 && self.minTs > self.maxTs {
+		return ParamsSend{}, errors.Errorf("bundle min timestamp:%v is after the max timestamp:%v", self.minTs, self.maxTs)
+	}
+
+	for i := range self.allowRevert {
+		if i < 0 || i >= len(self.txs) {
+			return ParamsSend{}, errors.Errorf("allowed revert index:%v out of range TXs count:%v", i, len(self.txs))
+		}
+	}
+
+	params := ParamsSend{
+		Txs:             self.Txs(),
+		BlockNum:        hexutil.EncodeUint64(self.blockNum),
+		MinTimestamp:    self.minTs,
+		MaxTimestamp:    self.maxTs,
+		ReplacementUUID: self.uuid,
+	}
+	for i := range self.txs {
+		if self.allowRevert[i] {
+			params.RevertingTxHashes = append(params.RevertingTxHashes, self.hashes[i].Hex())
+		}
+	}
+	return params, nil
+}
@@ -0,0 +1,54 @@
+// Copyright (c) The Cryptorium Authors.
+// Licensed under the MIT License.
+
+package flashbot
+
+import (
+	"context"
+	"math/big"
+	"testing"
+
+	"github.com/cryptoriums/packages/testutil"
+	"github.com/ethereum/go-ethereum/common/hexutil"
+	"github.com/ethereum/go-ethereum/crypto"
+)
+
+func TestBundleBuilder(t *testing.T) {
+	ctx := context.Background()
+	prvKey, err := crypto.GenerateKey()
+	testutil.Ok(t, err)
+	pubKey := crypto.PubkeyToAddress(prvKey.PublicKey)
+
+	fb, err := New(prvKey, &Api{URL: "http://localhost"})
+	testutil.Ok(t, err)
+	f := fb.(*Flashbot)
+
+	spec := TxSpec{To: &pubKey, Gas: 21_000, GasFeeCap: big.NewInt(2e9)}
+	signedHex, signed, err := f.BuildTx(ctx, 5, 10, spec)
+	testutil.Ok(t, err)
+
+	params, err := f.NewBundleBuilder(ctx, 5).
+		Nonce(11).
+		AddSignedTx(signedHex).
+		AddTx(spec).
+		AllowRevert(0).
+		TargetBlock(100).
+		ReplacementUUID("uuid").
+		Build()
+	testutil.Ok(t, err)
+	testutil.Equals(t, 2, len(params.Txs))
+	testutil.Equals(t, []string{signed.Hash().Hex()}, params.RevertingTxHashes)
+	testutil.Equals(t, hexutil.EncodeUint64(100), params.BlockNum)
+	testutil.Equals(t, "uuid", params.ReplacementUUID)
+
+	decoded, err := DecodeTx(params.Txs[1])
+	testutil.Ok(t, err)
+	testutil.Equals(t, uint64(11), decoded.Nonce())
+
+	_, err = f.NewBundleBuilder(ctx, 5).AddTx(spec).TargetBlock(100).Build()
+	testutil.NotOk(t, err)
+	_, err = f.NewBundleBuilder(ctx, 5).AddSignedTx(signedHex).Build()
+	testutil.NotOk(t, err)
+	_, err = f.NewBundleBuilder(ctx, 5).AddSignedTx(signedHex).AllowRevert(3).TargetBlock(100).Build()
+	testutil.NotOk(t, err)
+}
@@ -0,0 +1,160 @@
+// Copyright (c) The Cryptorium Authors.
+// Licensed under the MIT License.
+
+package flashbot
+
+import (
+	"encoding/json"
+	"io"
+	"os"
+
+	"github.com/ethereum/go-ethereum/common/hexutil"
+	"github.com/pkg/errors"
+)
+
+// BundleFileVersion is the current version of the bundle file format.
+const BundleFileVersion = 1
+
+// MaxBundleFileBlocks is the max number of blocks a bundle file can target.
+const MaxBundleFileBlocks = 100
+
+// BundleFile is the JSON format used to save the bundles to disk
+// and to hand them off between the systems.
+type BundleFile struct {
+	Version int      `json:"version"`
+	Txs     []string `json:"txs"`
+	// BlockNumber is the first target block and MaxBlockNumber the last one,
+	// only BlockNumber is targeted when MaxBlockNumber is 0.
+	BlockNumber       uint64            `json:"blockNumber"`
+	MaxBlockNumber    uint64            `json:"maxBlockNumber,omitempty"`
+	MinTimestamp      uint64            `json:"minTimestamp,omitempty"`
+	MaxTimestamp      uint64            `json:"maxTimestamp,omitempty"`
+	RevertingTxHashes []string          `json:"revertingTxHashes,omitempty"`
+	ReplacementUUID   string            `json:"replacementUuid,omitempty"`
+	Tags              []string          `json:"tags,omitempty"`
+	Metadata          map[string]string `json:"metadata,omitempty"`
+}
+
+// NewBundleFile creates the file format of the bundle params.
+func NewBundleFile(p ParamsSend) (*BundleFile, error) {
+	blockNum, err := hexutil.DecodeUint64(p.BlockNum)
+	if err != nil {
+		return nil, errors.Wrapf(err, "decode bundle block number:%v", p.BlockNum)
+	}
+	return &BundleFile{
+		Version:           BundleFileVersion,
+		Txs:               p.Txs,
+		BlockNumber:       blockNum,
+		MinTimestamp:      p.MinTimestamp,
+		MaxTimestamp:      p.MaxTimestamp,
+		RevertingTxHashes: p.RevertingTxHashes,
+		ReplacementUUID:   p.ReplacementUUID,
+	}, nil
+}
+
+func (self *BundleFile) Validate() error {
+	if self.Version != BundleFileVersion {
+		return errors.Errorf("unsupported bundle file version:%v", self.Version)
+	}
+	if len(self.Txs) == 0 {
+		return errors.New("bundle has no TXs")
+	}
+	if self.BlockNumber == 0 {
+		return errors.New("bundle has no target block")
+	}
+	if self.MaxBlockNumber != 0 && self.MaxBlockNumber < self.BlockNumber {
+		return errors.Errorf("max block:%v before the target block:%v", self.MaxBlockNumber, self.BlockNumber)
+	}
+	if self.MaxBlockNumber != 0 && self.MaxBlockNumber-self.BlockNumber >= MaxBundleFileBlocks {
+		return errors.Errorf("block range from:%v to:%v over the max:%v blocks", self.BlockNumber, self.MaxBlockNumber, MaxBundleFileBlocks)
+	}
+	if self.MaxTimestamp != 0 && self.MaxTimestamp < self.MinTimestamp {
+		return errors.Errorf("max timestamp:%v before the min timestamp:%v", self.MaxTimestamp, self.MinTimestamp)
+	}
+	return nil
+}
+
+// Blocks returns all the target blocks, at most MaxBundleFileBlocks.
+func (self *BundleFile) Blocks() []uint64 {
+	last := self.MaxBlockNumber
+	if last < self.BlockNumber {
+		last = self.BlockNumber
+	}
+	if last-self.BlockNumber >= MaxBundleFileBlocks {
+		last = self.BlockNumber + MaxBundleFileBlocks - 1
+	}
+	blocks := make([]uint64, 0, last-self.BlockNumber+1)
+	for b := self.BlockNumber; b <= last; b++ {
+		blocks = append(blocks, b)
+	}
+	return blocks
+}
+
+// Params returns the send params for each of the target blocks.
+func (self *BundleFile) Params() []ParamsSend {
+	var res []ParamsSend
+	for _, b := range self.Blocks() {
+		res = append(res, ParamsSend{
+			BlockNum:          hexutil.EncodeUint64(b),
+			Txs:               self.Txs,
+			MinTimestamp:      self.MinTimestamp,
+			MaxTimestamp:      self.MaxTimestamp,
+			RevertingTxHashes: self.RevertingTxHashes,
+			ReplacementUUID:   self.ReplacementUUID,
+		})
+	}
+	return res
+}
+
+// SaveBundle validates and writes the bundle as indented JSON.
+// The version is set to the current one when empty.
+func SaveBundle(w io.Writer, b *BundleFile) error {
+	if b.Version == 0 {
+		b.Version = BundleFileVersion
+	}
+	if err := b.Validate(); err != nil {
+		return err
+	}
+	enc := json.NewEncoder(w)
+	enc.SetIndent("", "  ")
+	return errors.Wrap(enc.Encode(b), "encode bundle file")
+}
+
+// LoadBundle reads and validates a bundle.
+func LoadBundle(r io.Reader) (*BundleFile, error) {
+	b := &BundleFile{}
+	dec := json.NewDecoder(r)
+	dec.DisallowUnknownFields()
+	if err := dec.Decode(b); err != nil {
+		return nil, errors.Wrap(err, "decode bundle file")
+	}
+	if err := b.Validate(); err != nil {
+		return nil, err
+	}
+	return b, nil
+}
+
+func SaveBundleFile(path string, b *BundleFile) error {
+	f, err := os.Create(path)
+	if err != nil {
+		return errors.Wrapf(err, "create bundle file:%v", path)
+	}
+	if err := SaveBundle(f, b); err != nil {
+		_ = f.Close()
+		return err
+	}
+	return errors.Wrapf(f.Close(), "close bundle file:%v", path)
+}
+
+func LoadBundleFile(path string) (*BundleFile, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, errors.Wrapf(err, "open bundle file:%v", path)
+	}
+	defer f.Close()
+	b, err := LoadBundle(f)
+	if err != nil {
+		return nil, errors.Wrapf(err, "bundle file:%v", path)
+	}
+	return b, nil
+}
@@ -0,0 +1,62 @@
+// Copyright (c) The Cryptorium Authors.
+// Licensed under the MIT License.
+
+package flashbot
+
+import (
+	"bytes"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/cryptoriums/packages/testutil"
+)
+
+func TestBundleFile(t *testing.T) {
+	b, err := NewBundleFile(ParamsSend{
+		BlockNum:          "0xa",
+		Txs:               []string{"0x01", "0x02"},
+		RevertingTxHashes: []string{"0xaa"},
+		ReplacementUUID:   "uuid",
+	})
+	testutil.Ok(t, err)
+	b.MaxBlockNumber = 12
+	b.Tags = []string{"arb"}
+	b.Metadata = map[string]string{"strategy": "backrun"}
+
+	path := filepath.Join(t.TempDir(), "bundle.json")
+	testutil.Ok(t, SaveBundleFile(path, b))
+	loaded, err := LoadBundleFile(path)
+	testutil.Ok(t, err)
+	testutil.Equals(t, b, loaded)
+
+	testutil.Equals(t, []uint64{10, 11, 12}, loaded.Blocks())
+	params := loaded.Params()
+	testutil.Equals(t, 3, len(params))
+	testutil.Equals(t, "0xc", params[2].BlockNum)
+	testutil.Equals(t, []string{"0xaa"}, params[2].RevertingTxHashes)
+	testutil.Equals(t, "uuid", params[0].ReplacementUUID)
+
+	var buf bytes.Buffer
+	testutil.NotOk(t, SaveBundle(&buf, &BundleFile{Txs: []string{"0x01"}}))
+
+	for name, data := range map[string]string{
+		"unknown field":   `{"version":1,"txs":["0x01"],"blockNumber":1,"block":2}`,
+		"unknown version": `{"version":2,"txs":["0x01"],"blockNumber":1}`,
+		"no txs":          `{"version":1,"blockNumber":1}`,
+		"bad range":       `{"version":1,"txs":["0x01"],"blockNumber":3,"maxBlockNumber":2}`,
+		"oversized range": `{"version":1,"txs":["0x01"],"blockNumber":1,"maxBlockNumber":18446744073709551615}`,
+	} {
+		t.Run(name, func(t *testing.T) {
+			_, err := LoadBundle(strings.NewReader(data))
+			testutil.NotOk(t, err)
+		})
+	}
+
+	b = &BundleFile{Version: BundleFileVersion, Txs: []string{"0x01"}, BlockNumber: 1, MaxBlockNumber: MaxBundleFileBlocks}
+	testutil.Ok(t, b.Validate())
+	testutil.Equals(t, MaxBundleFileBlocks, len(b.Blocks()))
+	b.MaxBlockNumber++
+	testutil.NotOk(t, b.Validate())
+	testutil.Equals(t, MaxBundleFileBlocks, len(b.Blocks()))
+}
@@ -0,0 +1,47 @@
+// Copyright (c) The Cryptorium Authors.
+// Licensed under the MIT License.
+
+package flashbot
+
+import (
+	"strings"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/common/hexutil"
+	"github.com/ethereum/go-ethereum/crypto"
+	"github.com/pkg/errors"
+)
+
+// ErrBundleHashMismatch is returned when the relay returned bundle hash isn't the locally computed one.
+var ErrBundleHashMismatch = errors.New("bundle hash mismatch")
+
+// ComputeBundleHash returns the bundle hash as computed by the Flashbots relay,
+// the keccak256 of the concatenated TX hashes.
+// It is known before sending so it can be used to correlate the bundle before the relay replies.
+func ComputeBundleHash(txsHex []string) (common.Hash, error) {
+	hashes := make([]byte, 0, len(txsHex)*common.HashLength)
+	for i, txHex := range txsHex {
+		raw, err := hexutil.Decode(strings.TrimSpace(txHex))
+		if err != nil {
+			return common.Hash{}, errors.Wrapf(err, "decode TX index:%v", i)
+		}
+		hashes = append(hashes, crypto.Keccak256(raw)...)
+	}
+	return crypto.Keccak256Hash(hashes), nil
+}
+
+// VerifyBundleHash checks the relay returned bundle hash against the locally computed one.
+func VerifyBundleHash(txsHex []string, bundleHash string) error {
+	exp, err := ComputeBundleHash(txsHex)
+	if err != nil {
+		return err
+	}
+	act, err := hexutil.Decode(bundleHash)
+	if err != nil {
+		return errors.Wrapf(err, "decode bundle hash:%v", bundleHash)
+	}
+	if len(act) != common.HashLength || common.BytesToHash(act) != exp {
+		return errors.Wrapf(ErrBundleHashMismatch, "exp:%v act:%v", exp.Hex(), bundleHash)
+	}
+	return nil
+}
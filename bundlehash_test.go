@@ -0,0 +1,56 @@
+// Copyright (c) The Cryptorium Authors.
+// Licensed under the MIT License.
+
+package flashbot
+
+import (
+	"context"
+	"math/big"
+	"testing"
+
+	"github.com/cryptoriums/packages/testutil"
+	"github.com/ethereum/go-ethereum/crypto"
+	"github.com/pkg/errors"
+)
+
+func TestComputeBundleHash(t *testing.T) {
+	prvKey, err := crypto.GenerateKey()
+	testutil.Ok(t, err)
+	pubKey := crypto.PubkeyToAddress(prvKey.PublicKey)
+	fb, err := New(prvKey, &Api{URL: "http://localhost"})
+	testutil.Ok(t, err)
+
+	spec := TxSpec{To: &pubKey, Gas: 21_000, GasFeeCap: big.NewInt(2e9), GasTipCap: big.NewInt(1e9)}
+	txsHex, _, err := fb.(*Flashbot).SignTxs(context.Background(), 5, 0, []TxSpec{spec, spec})
+	testutil.Ok(t, err)
+
+	var concat []byte
+	for _, txHex := range txsHex {
+		tx, err := DecodeTx(txHex)
+		testutil.Ok(t, err)
+		concat = append(concat, tx.Hash().Bytes()...)
+	}
+
+	hash, err := ComputeBundleHash(txsHex)
+	testutil.Ok(t, err)
+	testutil.Equals(t, crypto.Keccak256Hash(concat), hash)
+
+	// The order of the TXs changes the hash.
+	reversed, err := ComputeBundleHash([]string{txsHex[1], txsHex[0]})
+	testutil.Ok(t, err)
+	testutil.Assert(t, reversed != hash, "reordered bundle has the same hash")
+
+	testutil.Ok(t, VerifyBundleHash(txsHex, hash.Hex()))
+	err = VerifyBundleHash(txsHex, reversed.Hex())
+	testutil.Assert(t, errors.Is(err, ErrBundleHashMismatch), "unexpected error:%v", err)
+	testutil.NotOk(t, VerifyBundleHash(txsHex, "0x01"))
+
+	_, err = ComputeBundleHash([]string{"0xzz"})
+	testutil.NotOk(t, err)
+
+	// The dry run returns the same hash as the local computation.
+	fb.(*Flashbot).SetDryRun(true)
+	resp, err := fb.SendBundle(context.Background(), txsHex, 10)
+	testutil.Ok(t, err)
+	testutil.Equals(t, hash.Hex(), resp.BundleHash)
+}
@@ -0,0 +1,107 @@
+// Copyright (c) The Cryptorium Authors.
+// Licensed under the MIT License.
+
+package flashbot
+
+import (
+	"context"
+	"math/big"
+
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/params"
+	"github.com/pkg/errors"
+)
+
+// ReplacementBumpPercent is the fee increase used for replacement TXs.
+// The nodes require at least 10% for both the fee cap and the tip.
+const ReplacementBumpPercent = 13
+
+// DefaultCancelTip is the min tip of the cancel TXs used when none is given.
+const DefaultCancelTip = params.GWei
+
+// NewCancelTX builds a zero value self transfer for the nonce
+// with the fees of the stuck TX bumped enough to replace it.
+// The tip is at least the given one, DefaultCancelTip when nil, and the fee cap is at least
+// twice the next block base fee plus the tip so that the TX stays valid even after 5 consecutive full blocks.
+func (self *Flashbot) NewCancelTX(netID int64, nonce uint64, stuckFeeCap, stuckTip, nextBaseFee, tip *big.Int) (string, *types.Transaction, error) {
+	signer := self.TxSigner()
+	if signer == nil {
+		return "", nil, errors.New("private key or signer is not set")
+	}
+	if stuckFeeCap == nil || stuckTip == nil {
+		return "", nil, errors.New("the stuck TX fee cap and tip are required")
+	}
+	if nextBaseFee == nil {
+		return "", nil, errors.New("the next block base fee is required")
+	}
+	if tip == nil {
+		tip = big.NewInt(DefaultCancelTip)
+	}
+
+	tipCap := bumpFee(stuckTip)
+	if tipCap.Cmp(tip) < 0 {
+		tipCap.Set(tip)
+	}
+	feeCap := bumpFee(stuckFeeCap)
+	if minFeeCap := new(big.Int).Add(mulFloat(nextBaseFee, 2), tipCap); feeCap.Cmp(minFeeCap) < 0 {
+		feeCap = minFeeCap
+	}
+
+	from := signer.Address()
+	spec := TxSpec{
+		To:        &from,
+		Gas:       params.TxGas,
+		GasFeeCap: feeCap,
+		GasTipCap: tipCap,
+	}
+	return self.SignTx(spec.TxData(netID, nonce))
+}
+
+// CancelStuckNonce replaces the TX stuck at the nonce with a cancel TX
+// and sends it through eth_sendPrivateTransaction so the subsequent bundle nonces are unblocked.
+// The stuck TX must be sent by the TxSigner address as the cancel TX uses the same nonce.
+// The backend provides the head for the base fee and the tip is the min tip, see NewCancelTX.
+func (self *Flashbot) CancelStuckNonce(
+	ctx context.Context,
+	netID int64,
+	backend HeaderBackend,
+	stuck *types.Transaction,
+	tip *big.Int,
+	maxBlockNum uint64,
+) (*SendPrivateTransactionResponse, *types.Transaction, error) {
+	signer := self.TxSigner()
+	if signer == nil {
+		return nil, nil, errors.New("private key or signer is not set")
+	}
+	sender, err := types.Sender(types.LatestSignerForChainID(stuck.ChainId()), stuck)
+	if err != nil {
+		return nil, nil, errors.Wrap(err, "recover stuck TX sender")
+	}
+	if sender != signer.Address() {
+		return nil, nil, errors.Errorf("stuck TX sender isn't the signer sender:%v signer:%v", sender, signer.Address())
+	}
+
+	header, err := backend.HeaderByNumber(ctx, nil)
+	if err != nil {
+		return nil, nil, errors.Wrap(err, "getting chain header")
+	}
+	txHex, tx, err := self.NewCancelTX(netID, stuck.Nonce(), stuck.GasFeeCap(), stuck.GasTipCap(), NextBaseFee(header), tip)
+	if err != nil {
+		return nil, nil, errors.Wrap(err, "create cancel TX")
+	}
+	resp, err := self.SendPrivateTransaction(ctx, txHex, maxBlockNum, true)
+	if err != nil {
+		return nil, nil, errors.Wrapf(err, "send cancel TX nonce:%v", stuck.Nonce())
+	}
+	return resp, tx, nil
+}
+
+func bumpFee(fee *big.Int) *big.Int {
+	bumped := new(big.Int).Mul(fee, big.NewInt(100+ReplacementBumpPercent))
+	bumped.Div(bumped, big.NewInt(100))
+	// Make sure tiny fees still increase.
+	if bumped.Cmp(fee) <= 0 {
+		bumped.Add(fee, big.NewInt(1))
+	}
+	return bumped
+}
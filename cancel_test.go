@@ -0,0 +1,78 @@
+// Copyright (c) The Cryptorium Authors.
+// Licensed under the MIT License.
+
+package flashbot
+
+import (
+	"context"
+	"encoding/json"
+	"math/big"
+	"testing"
+
+	"github.com/cryptoriums/packages/testutil"
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/params"
+)
+
+func TestCancelStuckNonce(t *testing.T) {
+	ctx := context.Background()
+	var sent []ParamsPrivateTransaction
+	relay := newRelayMock(t, func(method string, params json.RawMessage) (interface{}, *jsonError) {
+		var p []ParamsPrivateTransaction
+		testutil.Ok(t, json.Unmarshal(params, &p))
+		sent = append(sent, p...)
+		return "0xhash", nil
+	})
+	fb := newTestFlashbot(t, relay.URL)
+	to := common.HexToAddress("0x70")
+
+	_, stuck, err := fb.SignTx(TxSpec{
+		To:        &to,
+		Gas:       100_000,
+		GasFeeCap: big.NewInt(100),
+		GasTipCap: big.NewInt(5),
+	}.TxData(1, 7))
+	testutil.Ok(t, err)
+
+	head := headerMock{&types.Header{Number: big.NewInt(10), GasLimit: 30_000_000, GasUsed: 15_000_000, BaseFee: big.NewInt(10)}}
+	resp, cancel, err := fb.CancelStuckNonce(ctx, 1, head, stuck, big.NewInt(2), 20)
+	testutil.Ok(t, err)
+	testutil.Equals(t, "0xhash", resp.Result)
+	testutil.Equals(t, []string{"eth_sendPrivateTransaction"}, relay.Methods())
+	testutil.Equals(t, 1, len(sent))
+	testutil.Equals(t, "0x14", sent[0].МaxBlockNumber)
+
+	// A zero value self transfer at the same nonce with the bumped fees.
+	from := fb.TxSigner().Address()
+	testutil.Equals(t, &from, cancel.To())
+	testutil.Equals(t, uint64(7), cancel.Nonce())
+	testutil.Equals(t, params.TxGas, cancel.Gas())
+	testutil.Equals(t, int64(0), cancel.Value().Int64())
+	testutil.Equals(t, big.NewInt(113), cancel.GasFeeCap())
+	testutil.Equals(t, big.NewInt(6), cancel.GasTipCap())
+
+	// The fees are raised to the current base fee and the min tip when the stuck TX is underpriced.
+	head.header.BaseFee = big.NewInt(1000)
+	_, cancel, err = fb.CancelStuckNonce(ctx, 1, head, stuck, big.NewInt(50), 20)
+	testutil.Ok(t, err)
+	testutil.Equals(t, big.NewInt(2050), cancel.GasFeeCap())
+	testutil.Equals(t, big.NewInt(50), cancel.GasTipCap())
+	_, cancel, err = fb.CancelStuckNonce(ctx, 1, head, stuck, nil, 20)
+	testutil.Ok(t, err)
+	testutil.Equals(t, big.NewInt(DefaultCancelTip), cancel.GasTipCap())
+	testutil.Equals(t, big.NewInt(DefaultCancelTip+2000), cancel.GasFeeCap())
+
+	// The TX of another sender can't be replaced by the signer.
+	other := newTestFlashbot(t, relay.URL)
+	_, stuck, err = other.SignTx(TxSpec{To: &to, Gas: 21_000, GasFeeCap: big.NewInt(100), GasTipCap: big.NewInt(5)}.TxData(1, 7))
+	testutil.Ok(t, err)
+	_, _, err = fb.CancelStuckNonce(ctx, 1, head, stuck, nil, 20)
+	testutil.NotOk(t, err)
+	testutil.Equals(t, 3, len(relay.Methods()))
+
+	noSigner, err := New(nil, &Api{URL: relay.URL})
+	testutil.Ok(t, err)
+	_, _, err = noSigner.(*Flashbot).CancelStuckNonce(ctx, 1, head, stuck, nil, 20)
+	testutil.NotOk(t, err)
+}
@@ -0,0 +1,137 @@
+// Copyright (c) The Cryptorium Authors.
+// Licensed under the MIT License.
+
+package flashbot
+
+import (
+	"context"
+	"math/big"
+	"net/url"
+	"strings"
+
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/pkg/errors"
+)
+
+var ErrChainIDMismatch = errors.New("chain ID mismatch")
+
+// relayChainIDs are the chains of the well known relay hosts.
+var relayChainIDs = map[string]int64{
+	"relay.flashbots.net":         1,
+	"rpc.flashbots.net":           1,
+	"api.edennetwork.io":          1,
+	"relay-goerli.flashbots.net":  5,
+	"relay-sepolia.flashbots.net": 11155111,
+}
+
+// RelayChainID returns the chain ID of a well known relay.
+func RelayChainID(relayURL string) (int64, bool) {
+	u, err := url.Parse(relayURL)
+	if err != nil {
+		return 0, false
+	}
+	id, ok := relayChainIDs[strings.ToLower(u.Hostname())]
+	return id, ok
+}
+
+// ChainIDBackend is the subset of the node methods used for the chain ID check.
+type ChainIDBackend interface {
+	ChainID(ctx context.Context) (*big.Int, error)
+}
+
+// CheckChainID returns an ErrChainIDMismatch when the node chain ID isn't netID.
+func CheckChainID(ctx context.Context, backend ChainIDBackend, netID int64) error {
+	id, err := backend.ChainID(ctx)
+	if err != nil {
+		return errors.Wrap(err, "get node chain ID")
+	}
+	if !id.IsInt64() || id.Int64() != netID {
+		return errors.Wrapf(ErrChainIDMismatch, "node:%v config:%v", id, netID)
+	}
+	return nil
+}
+
+// VerifyChainID checks the chain ID of the node, when not nil, and of the relay, when it is a well known one,
+// against netID and after that refuses to sign TXs for any other chain ID.
+func (self *Flashbot) VerifyChainID(ctx context.Context, backend ChainIDBackend, netID int64) error {
+	if id, ok := RelayChainID(self.api.URL); ok && id != netID {
+		return errors.Wrapf(ErrChainIDMismatch, "relay:%v chain:%v config:%v", self.api.URL, id, netID)
+	}
+	if backend != nil {
+		if err := CheckChainID(ctx, backend, netID); err != nil {
+			return err
+		}
+	}
+	self.mtx.Lock()
+	defer self.mtx.Unlock()
+	self.chainID = big.NewInt(netID)
+	return nil
+}
+
+// chainIDSigner refuses to sign TXs for any chain ID other than the verified one.
+type chainIDSigner struct {
+	Signer
+	chainID *big.Int
+}
+
+func (self chainIDSigner) SignTx(tx *types.Transaction, chainID *big.Int) (*types.Transaction, error) {
+	if chainID == nil || chainID.Cmp(self.chainID) != 0 {
+		return nil, errors.Wrapf(ErrChainIDMismatch, "TX:%v verified:%v", chainID, self.chainID)
+	}
+	return self.Signer.SignTx(tx, chainID)
+}
+
+func (self chainIDSigner) SignTxWith(tx *types.Transaction, chainSigner types.Signer) (*types.Transaction, error) {
+	if chainSigner.ChainID().Cmp(self.chainID) != 0 {
+		return nil, errors.Wrapf(ErrChainIDMismatch, "TX:%v verified:%v", chainSigner.ChainID(), self.chainID)
+	}
+	if s, ok := self.Signer.(chainSignerTx); ok {
+		return s.SignTxWith(tx, chainSigner)
+	}
+	return self.Signer.SignTx(tx, chainSigner.ChainID())
+}
+
+// Option configures the client in New.
+type Option func(*Flashbot)
+
+// WithChainSigner sets the TX signing scheme, i.e. for a custom fork,
+// instead of the latest one for the chain ID.
+// The key and the KMS signers sign with it while the wallet, clef and web3signer signers
+// return ErrChainSignerUnsupported for any other than the latest scheme.
+func WithChainSigner(chainSigner types.Signer) Option {
+	return func(fb *Flashbot) { fb.chainSigner = chainSigner }
+}
+
+// ChainSigner returns the chain ID and the TX signing scheme used for the network.
+// Both are created once per network and reused for all TXs.
+func (self *Flashbot) ChainSigner(netID int64) (*big.Int, types.Signer, error) {
+	chainSigner, err := self.chainSignerFor(big.NewInt(netID))
+	if err != nil {
+		return nil, nil, err
+	}
+	return chainSigner.ChainID(), chainSigner, nil
+}
+
+func (self *Flashbot) chainSignerFor(chainID *big.Int) (types.Signer, error) {
+	key := chainID.String()
+	self.mtx.RLock()
+	chainSigner := self.chainSigner
+	if chainSigner == nil {
+		chainSigner = self.chainSigners[key]
+	}
+	self.mtx.RUnlock()
+
+	if chainSigner == nil {
+		chainSigner = types.LatestSignerForChainID(chainID)
+		self.mtx.Lock()
+		if self.chainSigners == nil {
+			self.chainSigners = make(map[string]types.Signer)
+		}
+		self.chainSigners[key] = chainSigner
+		self.mtx.Unlock()
+	}
+	if chainSigner.ChainID().Cmp(chainID) != 0 {
+		return nil, errors.Wrapf(ErrChainIDMismatch, "chain signer:%v network:%v", chainSigner.ChainID(), chainID)
+	}
+	return chainSigner, nil
+}
@@ -0,0 +1,57 @@
+// Copyright (c) The Cryptorium Authors.
+// Licensed under the MIT License.
+
+package flashbot
+
+import (
+	"context"
+	"math/big"
+	"testing"
+
+	"github.com/cryptoriums/packages/testutil"
+	"github.com/ethereum/go-ethereum/crypto"
+	"github.com/pkg/errors"
+)
+
+type chainIDBackend int64
+
+func (self chainIDBackend) ChainID(ctx context.Context) (*big.Int, error) {
+	return big.NewInt(int64(self)), nil
+}
+
+func TestVerifyChainID(t *testing.T) {
+	ctx := context.Background()
+	prvKey, err := crypto.GenerateKey()
+	testutil.Ok(t, err)
+
+	id, ok := RelayChainID("https://relay-goerli.flashbots.net/")
+	testutil.Assert(t, ok, "goerli relay should be known")
+	testutil.Equals(t, int64(5), id)
+	_, ok = RelayChainID("https://relay.example")
+	testutil.Assert(t, !ok, "unknown relay should not have a chain ID")
+
+	testutil.Ok(t, CheckChainID(ctx, chainIDBackend(5), 5))
+	testutil.Assert(t, errors.Is(CheckChainID(ctx, chainIDBackend(1), 5), ErrChainIDMismatch), "node mismatch not detected")
+
+	fb, err := New(prvKey, &Api{URL: "https://relay.flashbots.net"})
+	testutil.Ok(t, err)
+	err = fb.(*Flashbot).VerifyChainID(ctx, nil, 5)
+	testutil.Assert(t, errors.Is(err, ErrChainIDMismatch), "relay mismatch not detected:%v", err)
+
+	fb, err = New(prvKey, &Api{URL: "https://relay.example"})
+	testutil.Ok(t, err)
+	to := crypto.PubkeyToAddress(prvKey.PublicKey)
+	spec := TxSpec{To: &to, Gas: 21_000, GasFeeCap: big.NewInt(1)}
+
+	// Any chain ID can be signed before the verification.
+	_, _, err = fb.SignTx(spec.TxData(1, 0))
+	testutil.Ok(t, err)
+
+	testutil.Assert(t, errors.Is(fb.(*Flashbot).VerifyChainID(ctx, chainIDBackend(1), 5), ErrChainIDMismatch), "node mismatch not detected")
+	testutil.Ok(t, fb.(*Flashbot).VerifyChainID(ctx, chainIDBackend(5), 5))
+
+	_, _, err = fb.SignTx(spec.TxData(5, 0))
+	testutil.Ok(t, err)
+	_, _, err = fb.SignTx(spec.TxData(1, 0))
+	testutil.Assert(t, errors.Is(err, ErrChainIDMismatch), "TX for another chain signed:%v", err)
+}
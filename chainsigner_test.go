@@ -0,0 +1,95 @@
+// Copyright (c) The Cryptorium Authors.
+// Licensed under the MIT License.
+
+package flashbot
+
+import (
+	"context"
+	"math/big"
+	"sync/atomic"
+	"testing"
+
+	"github.com/cryptoriums/packages/testutil"
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/crypto"
+	"github.com/pkg/errors"
+)
+
+// forkSigner is a custom signing scheme that counts its uses.
+type forkSigner struct {
+	types.Signer
+	signs *int32
+}
+
+func (self forkSigner) Hash(tx *types.Transaction) common.Hash {
+	atomic.AddInt32(self.signs, 1)
+	return self.Signer.Hash(tx)
+}
+
+func TestChainSigner(t *testing.T) {
+	ctx := context.Background()
+	prvKey, err := crypto.GenerateKey()
+	testutil.Ok(t, err)
+	to := common.HexToAddress("0x01")
+	spec := TxSpec{To: &to, Gas: 21_000, GasFeeCap: big.NewInt(1e9)}
+
+	fb, err := New(prvKey, &Api{URL: "http://localhost"})
+	testutil.Ok(t, err)
+	f := fb.(*Flashbot)
+
+	// The chain signer is created once per network.
+	chainID, s1, err := f.ChainSigner(5)
+	testutil.Ok(t, err)
+	testutil.Equals(t, big.NewInt(5), chainID)
+	_, s2, err := f.ChainSigner(5)
+	testutil.Ok(t, err)
+	testutil.Assert(t, s1 == s2, "chain signer should be cached")
+	_, s3, err := f.ChainSigner(1)
+	testutil.Ok(t, err)
+	testutil.Assert(t, s1 != s3, "each network has its own chain signer")
+
+	_, tx, err := f.BuildTx(ctx, 5, 0, spec)
+	testutil.Ok(t, err)
+	testutil.Equals(t, big.NewInt(5), tx.ChainId())
+
+	// An explicit chain signer is used for all TXs.
+	var signs int32
+	custom := forkSigner{Signer: types.NewLondonSigner(big.NewInt(5)), signs: &signs}
+	fb, err = New(prvKey, &Api{URL: "http://localhost"}, WithChainSigner(custom))
+	testutil.Ok(t, err)
+	f = fb.(*Flashbot)
+	txsHex, txs, err := f.SignTxs(ctx, 5, 0, []TxSpec{spec, spec})
+	testutil.Ok(t, err)
+	testutil.Equals(t, 2, len(txsHex))
+	testutil.Equals(t, int32(2), atomic.LoadInt32(&signs))
+	sender, err := types.Sender(custom, txs[1])
+	testutil.Ok(t, err)
+	testutil.Equals(t, crypto.PubkeyToAddress(prvKey.PublicKey), sender)
+
+	_, _, err = f.BuildTx(ctx, 1, 0, spec)
+	testutil.Assert(t, errors.Is(err, ErrChainIDMismatch), "unexpected error:%v", err)
+
+	// The verified chain ID still applies with a chain signer.
+	fb, err = New(prvKey, &Api{URL: "http://localhost"}, WithChainSigner(types.NewLondonSigner(big.NewInt(1))))
+	testutil.Ok(t, err)
+	f = fb.(*Flashbot)
+	testutil.Ok(t, f.VerifyChainID(ctx, nil, 5))
+	_, _, err = f.BuildTx(ctx, 1, 0, spec)
+	testutil.Assert(t, errors.Is(err, ErrChainIDMismatch), "unexpected error:%v", err)
+}
+
+func BenchmarkBuildTx(b *testing.B) {
+	prvKey, err := crypto.GenerateKey()
+	testutil.Ok(b, err)
+	fb, err := New(prvKey, &Api{URL: "http://localhost"})
+	testutil.Ok(b, err)
+	to := common.HexToAddress("0x01")
+	spec := TxSpec{To: &to, Gas: 21_000, GasFeeCap: big.NewInt(1e9)}
+	b.ReportAllocs()
+	for i := 0; i < b.N; i++ {
+		if _, _, err := fb.(*Flashbot).BuildTx(context.Background(), 1, uint64(i), spec); err != nil {
+			b.Fatal(err)
+		}
+	}
+}
@@ -0,0 +1,54 @@
+// Copyright (c) The Cryptorium Authors.
+// Licensed under the MIT License.
+
+package main
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"text/tabwriter"
+
+	"github.com/ethereum/go-ethereum/common/hexutil"
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/kachan28/flashbot"
+)
+
+type DecodeCmd struct {
+	File string `arg:"" optional:"" default:"-" help:"File with the signed TXs hex as a JSON array or one per line, stdin by default."`
+}
+
+func (self *DecodeCmd) Run(g *Globals) error {
+	txs, err := readTxs(self.File)
+	if err != nil {
+		return err
+	}
+	decoded, err := flashbot.DecodeBundle(txs)
+	if err != nil {
+		return err
+	}
+	return printDecoded(os.Stdout, decoded)
+}
+
+func printDecoded(out io.Writer, txs []flashbot.DecodedTx) error {
+	w := tabwriter.NewWriter(out, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(w, "#\tHASH\tFROM\tTO\tNONCE\tGAS\tGAS PRICE/FEE CAP\tTIP\tVALUE\tSELECTOR")
+	for i, d := range txs {
+		tx := d.Tx
+		to := "create"
+		if tx.To() != nil {
+			to = tx.To().Hex()
+		}
+		tip := "-"
+		if tx.Type() == types.DynamicFeeTxType {
+			tip = tx.GasTipCap().String()
+		}
+		selector := "-"
+		if len(tx.Data()) >= 4 {
+			selector = hexutil.Encode(tx.Data()[:4])
+		}
+		fmt.Fprintf(w, "%d\t%v\t%v\t%v\t%d\t%d\t%v\t%v\t%v\t%v\n",
+			i, tx.Hash().Hex(), d.Sender.Hex(), to, tx.Nonce(), tx.Gas(), tx.GasFeeCap(), tip, tx.Value(), selector)
+	}
+	return w.Flush()
+}
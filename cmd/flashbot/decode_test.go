@@ -0,0 +1,49 @@
+// Copyright (c) The Cryptorium Authors.
+// Licensed under the MIT License.
+
+package main
+
+import (
+	"bytes"
+	"math/big"
+	"strings"
+	"testing"
+
+	"github.com/cryptoriums/packages/testutil"
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/common/hexutil"
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/crypto"
+	"github.com/kachan28/flashbot"
+)
+
+func TestPrintDecoded(t *testing.T) {
+	prvKey, err := crypto.GenerateKey()
+	testutil.Ok(t, err)
+	to := common.HexToAddress("0x01")
+	tx, err := types.SignNewTx(prvKey, types.LatestSignerForChainID(big.NewInt(1)), &types.DynamicFeeTx{
+		ChainID:   big.NewInt(1),
+		Nonce:     7,
+		GasTipCap: big.NewInt(2),
+		GasFeeCap: big.NewInt(30),
+		Gas:       50_000,
+		To:        &to,
+		Value:     big.NewInt(5),
+		Data:      common.FromHex("0xa9059cbb0000"),
+	})
+	testutil.Ok(t, err)
+	raw, err := tx.MarshalBinary()
+	testutil.Ok(t, err)
+
+	decoded, err := flashbot.DecodeBundle([]string{hexutil.Encode(raw)})
+	testutil.Ok(t, err)
+	var out bytes.Buffer
+	testutil.Ok(t, printDecoded(&out, decoded))
+
+	lines := strings.Split(strings.TrimSpace(out.String()), "\n")
+	testutil.Equals(t, 2, len(lines))
+	fields := strings.Fields(lines[1])
+	testutil.Equals(t, []string{
+		"0", tx.Hash().Hex(), crypto.PubkeyToAddress(prvKey.PublicKey).Hex(), to.Hex(), "7", "50000", "30", "2", "5", "0xa9059cbb",
+	}, fields)
+}
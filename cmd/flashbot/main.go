@@ -0,0 +1,337 @@
+// Copyright (c) The Cryptorium Authors.
+// Licensed under the MIT License.
+
+// Command flashbot sends, simulates and inspects bundles from the command line.
+// The keys and the relays are read from a config file or from the FLASHBOT_* env vars
+// that can also be set in a .env file.
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"io"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/alecthomas/kong"
+	"github.com/ethereum/go-ethereum/common/hexutil"
+	"github.com/ethereum/go-ethereum/ethclient"
+	"github.com/kachan28/flashbot"
+	"github.com/kachan28/flashbot/boltstore"
+	"github.com/pkg/errors"
+)
+
+type Globals struct {
+	Config  string        `help:"YAML, TOML or JSON config file, the env vars are used when not set." type:"existingfile"`
+	EnvFile string        `help:"Env file with the FLASHBOT_* vars, used when it exists." default:".env"`
+	Relay   string        `help:"Relay URL or relay name from the config file, the first configured relay by default."`
+	Timeout time.Duration `help:"Timeout for the whole command." default:"30s"`
+
+	nodeURL string
+}
+
+// client creates the client for the selected relay.
+func (self *Globals) client() (*flashbot.Flashbot, error) {
+	var (
+		clients []flashbot.Flashboter
+		names   []string
+	)
+	if self.Config != "" {
+		cfg, err := flashbot.LoadConfigFile(self.Config)
+		if err != nil {
+			return nil, err
+		}
+		if clients, err = cfg.Clients(); err != nil {
+			return nil, err
+		}
+		for _, r := range cfg.Relays {
+			names = append(names, r.Name)
+		}
+		self.nodeURL = cfg.NodeURL
+	} else {
+		var (
+			cfg *flashbot.Config
+			err error
+		)
+		if _, errS := os.Stat(self.EnvFile); errS == nil {
+			cfg, err = flashbot.LoadFromEnvFile(self.EnvFile)
+		} else {
+			cfg, err = flashbot.LoadFromEnv()
+		}
+		if err != nil {
+			return nil, err
+		}
+		if self.Relay != "" && !contains(cfg.RelayURLs, self.Relay) {
+			cfg.RelayURLs = []string{self.Relay}
+		}
+		if clients, err = cfg.Clients(); err != nil {
+			return nil, err
+		}
+		names = make([]string, len(clients))
+		self.nodeURL = cfg.NodeURL
+	}
+
+	if self.Relay == "" {
+		return clients[0].(*flashbot.Flashbot), nil
+	}
+	for i, c := range clients {
+		if names[i] == self.Relay || c.Api().URL == self.Relay {
+			return c.(*flashbot.Flashbot), nil
+		}
+	}
+	return nil, errors.Errorf("relay not in the config:%v", self.Relay)
+}
+
+// blockNum returns the block or the next block from the node when it is 0.
+func (self *Globals) blockNum(ctx context.Context, blockNum uint64) (uint64, error) {
+	if blockNum != 0 {
+		return blockNum, nil
+	}
+	if self.nodeURL == "" {
+		return 0, errors.Errorf("set the block or the node url in %v", flashbot.EnvNodeURL)
+	}
+	client, err := ethclient.DialContext(ctx, self.nodeURL)
+	if err != nil {
+		return 0, errors.Wrapf(err, "connect to node:%v", self.nodeURL)
+	}
+	defer client.Close()
+	head, err := client.BlockNumber(ctx)
+	if err != nil {
+		return 0, errors.Wrap(err, "get head block number")
+	}
+	return head + 1, nil
+}
+
+func (self *Globals) context() (context.Context, context.CancelFunc) {
+	return context.WithTimeout(context.Background(), self.Timeout)
+}
+
+type SendCmd struct {
+	File   string `arg:"" help:"Bundle file or the signed TXs hex as a JSON array or one per line, - for stdin."`
+	Block  uint64 `help:"Target block, the bundle file blocks or the next block when 0."`
+	Blocks uint64 `help:"Number of consecutive blocks to target." default:"1"`
+}
+
+func (self *SendCmd) Run(g *Globals) error {
+	b, err := readBundle(self.File)
+	if err != nil {
+		return err
+	}
+	fb, err := g.client()
+	if err != nil {
+		return err
+	}
+	ctx, cncl := g.context()
+	defer cncl()
+
+	// The bundle file target blocks are used unless overridden by the flags.
+	if b.BlockNumber == 0 || self.Block != 0 {
+		if self.Blocks == 0 {
+			return errors.New("the number of blocks should be positive")
+		}
+		blockNum, err := g.blockNum(ctx, self.Block)
+		if err != nil {
+			return err
+		}
+		b.BlockNumber, b.MaxBlockNumber = blockNum, blockNum+self.Blocks-1
+		if err := b.Validate(); err != nil {
+			return err
+		}
+	}
+
+	var res []flashbot.BlockSubmission
+	for _, p := range b.Params() {
+		resp, err := fb.SendBundleParams(ctx, p)
+		blockNum, _ := hexutil.DecodeUint64(p.BlockNum)
+		res = append(res, flashbot.BlockSubmission{BlockNum: blockNum, Response: resp, Err: err})
+	}
+	return printJSON(res)
+}
+
+type SimulateCmd struct {
+	File       string `arg:"" help:"File with the signed TXs hex as a JSON array or one per line, - for stdin."`
+	StateBlock uint64 `help:"Block which state is used for the simulation, the latest when 0."`
+}
+
+func (self *SimulateCmd) Run(g *Globals) error {
+	txs, err := readTxs(self.File)
+	if err != nil {
+		return err
+	}
+	fb, err := g.client()
+	if err != nil {
+		return err
+	}
+	ctx, cncl := g.context()
+	defer cncl()
+	resp, err := fb.CallBundle(ctx, txs, self.StateBlock)
+	if err != nil {
+		return err
+	}
+	return printJSON(resp.Result)
+}
+
+type StatsCmd struct {
+	BundleHash string `arg:"" help:"Bundle hash."`
+	Block      uint64 `required:"" help:"Target block of the bundle."`
+}
+
+func (self *StatsCmd) Run(g *Globals) error {
+	fb, err := g.client()
+	if err != nil {
+		return err
+	}
+	ctx, cncl := g.context()
+	defer cncl()
+	stats, err := fb.GetBundleStatsV2(ctx, self.BundleHash, self.Block)
+	if err != nil {
+		return err
+	}
+	return printJSON(stats.Result)
+}
+
+type UserStatsCmd struct {
+	Block uint64 `help:"Block for the stats, the next block when 0."`
+}
+
+func (self *UserStatsCmd) Run(g *Globals) error {
+	fb, err := g.client()
+	if err != nil {
+		return err
+	}
+	ctx, cncl := g.context()
+	defer cncl()
+	blockNum, err := g.blockNum(ctx, self.Block)
+	if err != nil {
+		return err
+	}
+	stats, err := fb.GetUserStats(ctx, blockNum)
+	if err != nil {
+		return err
+	}
+	return printJSON(stats.Result)
+}
+
+type CancelCmd struct {
+	ReplacementUUID string `arg:"" help:"Replacement UUID of the bundle to cancel."`
+}
+
+func (self *CancelCmd) Run(g *Globals) error {
+	fb, err := g.client()
+	if err != nil {
+		return err
+	}
+	ctx, cncl := g.context()
+	defer cncl()
+	resp, err := fb.CancelBundle(ctx, self.ReplacementUUID)
+	if err != nil {
+		return err
+	}
+	return printJSON(resp)
+}
+
+type ExportCmd struct {
+	DB     string `arg:"" help:"Bolt bundle store file." type:"existingfile"`
+	Format string `help:"Output format." enum:"json,csv" default:"json"`
+	SentTo string `help:"Only the bundles sent to the relay URL."`
+}
+
+func (self *ExportCmd) Run(g *Globals) error {
+	store, err := boltstore.New(self.DB)
+	if err != nil {
+		return err
+	}
+	defer store.Close()
+	ctx, cncl := g.context()
+	defer cncl()
+	return flashbot.ExportBundles(ctx, os.Stdout, store, flashbot.BundleFilter{Relay: self.SentTo}, flashbot.ExportFormat(self.Format))
+}
+
+var cli struct {
+	Globals
+
+	Send      SendCmd      `cmd:"" help:"Send a bundle."`
+	Simulate  SimulateCmd  `cmd:"" help:"Simulate a bundle."`
+	Stats     StatsCmd     `cmd:"" help:"Show the stats of a bundle."`
+	Userstats UserStatsCmd `cmd:"" help:"Show the stats of the auth identity."`
+	Cancel    CancelCmd    `cmd:"" help:"Cancel a bundle by its replacement UUID."`
+	Export    ExportCmd    `cmd:"" help:"Export the bundles history from a bundle store."`
+	Decode    DecodeCmd    `cmd:"" help:"Decode and show the bundle TXs."`
+	Watch     WatchCmd     `cmd:"" help:"Watch a bundle until it is included, dropped or the wait times out."`
+}
+
+func main() {
+	ctx := kong.Parse(&cli,
+		kong.Name("flashbot"),
+		kong.Description("Flashbots relay client."),
+		kong.UsageOnError(),
+	)
+	ctx.FatalIfErrorf(ctx.Run(&cli.Globals))
+}
+
+func readTxs(path string) ([]string, error) {
+	b, err := readBundle(path)
+	if err != nil {
+		return nil, err
+	}
+	return b.Txs, nil
+}
+
+// readBundle reads a bundle file in the library format
+// or just the TXs as a JSON array or one per line.
+// Only the bundle file format has the target blocks.
+func readBundle(path string) (*flashbot.BundleFile, error) {
+	var (
+		data []byte
+		err  error
+	)
+	if path == "-" {
+		data, err = io.ReadAll(os.Stdin)
+	} else {
+		data, err = os.ReadFile(path)
+	}
+	if err != nil {
+		return nil, errors.Wrapf(err, "read bundle file:%v", path)
+	}
+
+	trimmed := strings.TrimSpace(string(data))
+	if strings.HasPrefix(trimmed, "{") {
+		b, err := flashbot.LoadBundle(strings.NewReader(trimmed))
+		if err != nil {
+			return nil, errors.Wrapf(err, "bundle file:%v", path)
+		}
+		return b, nil
+	}
+
+	var txs []string
+	if strings.HasPrefix(trimmed, "[") {
+		if err := json.Unmarshal(data, &txs); err != nil {
+			return nil, errors.Wrapf(err, "decode bundle file:%v", path)
+		}
+	} else {
+		txs = strings.Fields(trimmed)
+	}
+	if len(txs) == 0 {
+		return nil, errors.Errorf("no TXs in the bundle file:%v", path)
+	}
+	for i := range txs {
+		txs[i] = strings.TrimSpace(txs[i])
+	}
+	return &flashbot.BundleFile{Version: flashbot.BundleFileVersion, Txs: txs}, nil
+}
+
+func printJSON(v interface{}) error {
+	enc := json.NewEncoder(os.Stdout)
+	enc.SetIndent("", "  ")
+	return enc.Encode(v)
+}
+
+func contains(list []string, v string) bool {
+	for _, l := range list {
+		if l == v {
+			return true
+		}
+	}
+	return false
+}
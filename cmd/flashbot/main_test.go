@@ -0,0 +1,55 @@
+// Copyright (c) The Cryptorium Authors.
+// Licensed under the MIT License.
+
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/cryptoriums/packages/testutil"
+	"github.com/kachan28/flashbot"
+)
+
+func TestReadTxs(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "bundle.json")
+	testutil.Ok(t, os.WriteFile(path, []byte(`[" 0x01", "0x02"]`), 0o600))
+	txs, err := readTxs(path)
+	testutil.Ok(t, err)
+	testutil.Equals(t, []string{"0x01", "0x02"}, txs)
+
+	testutil.Ok(t, os.WriteFile(path, []byte("0x01\n0x02\n"), 0o600))
+	txs, err = readTxs(path)
+	testutil.Ok(t, err)
+	testutil.Equals(t, []string{"0x01", "0x02"}, txs)
+
+	testutil.Ok(t, os.WriteFile(path, []byte(`{"version":1,"txs":["0x01"],"blockNumber":5,"maxBlockNumber":6}`), 0o600))
+	b, err := readBundle(path)
+	testutil.Ok(t, err)
+	testutil.Equals(t, []uint64{5, 6}, b.Blocks())
+
+	testutil.Ok(t, os.WriteFile(path, []byte(`[]`), 0o600))
+	_, err = readTxs(path)
+	testutil.NotOk(t, err)
+}
+
+func TestClient(t *testing.T) {
+	t.Setenv(flashbot.EnvAuthKey, "4c0883a69102937d6231471b5dbb6204fe5129617082792ae468d01a3f362318")
+	t.Setenv(flashbot.EnvRelayURLs, "https://a,https://b")
+
+	g := &Globals{EnvFile: filepath.Join(t.TempDir(), ".env")}
+	fb, err := g.client()
+	testutil.Ok(t, err)
+	testutil.Equals(t, "https://a", fb.Api().URL)
+
+	g.Relay = "https://b"
+	fb, err = g.client()
+	testutil.Ok(t, err)
+	testutil.Equals(t, "https://b", fb.Api().URL)
+
+	g.Relay = "https://c"
+	fb, err = g.client()
+	testutil.Ok(t, err)
+	testutil.Equals(t, "https://c", fb.Api().URL)
+}
@@ -0,0 +1,137 @@
+// Copyright (c) The Cryptorium Authors.
+// Licensed under the MIT License.
+
+package main
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"os"
+	"time"
+
+	"github.com/ethereum/go-ethereum"
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/ethclient"
+	"github.com/kachan28/flashbot"
+	"github.com/pkg/errors"
+)
+
+type WatchCmd struct {
+	BundleHash string        `arg:"" help:"Bundle hash."`
+	Block      uint64        `required:"" help:"Target block of the bundle."`
+	Txs        string        `help:"File with the bundle TXs to detect the inclusion, without it only the stats are watched." type:"existingfile"`
+	Interval   time.Duration `help:"Polling interval." default:"2s"`
+	Wait       time.Duration `help:"Max watching time." default:"5m"`
+}
+
+// chainWatcher is the subset of the node methods used to detect the inclusion.
+type chainWatcher interface {
+	BlockNumber(ctx context.Context) (uint64, error)
+	TransactionReceipt(ctx context.Context, txHash common.Hash) (*types.Receipt, error)
+}
+
+func (self *WatchCmd) Run(g *Globals) error {
+	fb, err := g.client()
+	if err != nil {
+		return err
+	}
+	var hashes []common.Hash
+	if self.Txs != "" {
+		txs, err := readTxs(self.Txs)
+		if err != nil {
+			return err
+		}
+		decoded, err := flashbot.DecodeBundle(txs)
+		if err != nil {
+			return err
+		}
+		for _, d := range decoded {
+			hashes = append(hashes, d.Tx.Hash())
+		}
+	}
+
+	ctx, cncl := context.WithTimeout(context.Background(), self.Wait)
+	defer cncl()
+
+	var chain chainWatcher
+	if g.nodeURL != "" {
+		client, err := ethclient.DialContext(ctx, g.nodeURL)
+		if err != nil {
+			return errors.Wrapf(err, "connect to node:%v", g.nodeURL)
+		}
+		defer client.Close()
+		chain = client
+	}
+	return self.watch(ctx, os.Stdout, fb, chain, hashes)
+}
+
+func (self *WatchCmd) watch(ctx context.Context, out io.Writer, fb *flashbot.Flashbot, chain chainWatcher, hashes []common.Hash) error {
+	stats := fb.WatchBundleStats(ctx, self.BundleHash, self.Block, self.Interval)
+	ticker := time.NewTicker(self.Interval)
+	defer ticker.Stop()
+
+	last := flashbot.BundleStatusUnknown
+	for {
+		select {
+		case <-ctx.Done():
+			return errors.Errorf("timeout waiting for the bundle last status:%v", last)
+		case u, ok := <-stats:
+			if !ok {
+				// Sealed or the context is done so only the chain is watched from now on.
+				stats = nil
+				continue
+			}
+			if u.Err != nil {
+				fmt.Fprintf(out, "%v stats error: %v\n", time.Now().Format(time.RFC3339), u.Err)
+				continue
+			}
+			last = u.Status
+			fmt.Fprintf(out, "%v %v\n", time.Now().Format(time.RFC3339), u.Status)
+			if chain == nil && u.Status == flashbot.BundleStatusSealed {
+				return nil
+			}
+		case <-ticker.C:
+			if chain == nil {
+				continue
+			}
+			head, err := chain.BlockNumber(ctx)
+			if err != nil {
+				fmt.Fprintf(out, "%v node error: %v\n", time.Now().Format(time.RFC3339), err)
+				continue
+			}
+			if head < self.Block {
+				continue
+			}
+			if len(hashes) > 0 {
+				included, err := bundleIncluded(ctx, chain, hashes, self.Block)
+				if err != nil {
+					fmt.Fprintf(out, "%v node error: %v\n", time.Now().Format(time.RFC3339), err)
+					continue
+				}
+				if included {
+					fmt.Fprintf(out, "%v included block:%v\n", time.Now().Format(time.RFC3339), self.Block)
+					return nil
+				}
+			}
+			return errors.Errorf("bundle not included target block:%v head:%v last status:%v", self.Block, head, last)
+		}
+	}
+}
+
+func bundleIncluded(ctx context.Context, chain chainWatcher, hashes []common.Hash, blockNum uint64) (bool, error) {
+	for _, h := range hashes {
+		r, err := chain.TransactionReceipt(ctx, h)
+		if errors.Is(err, ethereum.NotFound) {
+			return false, nil
+		}
+		if err != nil {
+			return false, errors.Wrapf(err, "get receipt TX:%v", h.Hex())
+		}
+		if r.BlockNumber == nil || r.BlockNumber.Uint64() != blockNum {
+			return false, nil
+		}
+	}
+	return true, nil
+}
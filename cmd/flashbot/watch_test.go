@@ -0,0 +1,70 @@
+// Copyright (c) The Cryptorium Authors.
+// Licensed under the MIT License.
+
+package main
+
+import (
+	"bytes"
+	"context"
+	"math/big"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/cryptoriums/packages/testutil"
+	"github.com/ethereum/go-ethereum"
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/crypto"
+	"github.com/kachan28/flashbot"
+)
+
+type chainMock struct {
+	head     uint64
+	included bool
+}
+
+func (self *chainMock) BlockNumber(ctx context.Context) (uint64, error) {
+	return atomic.AddUint64(&self.head, 1), nil
+}
+
+func (self *chainMock) TransactionReceipt(ctx context.Context, txHash common.Hash) (*types.Receipt, error) {
+	if !self.included {
+		return nil, ethereum.NotFound
+	}
+	return &types.Receipt{BlockNumber: big.NewInt(10)}, nil
+}
+
+func TestWatch(t *testing.T) {
+	relay := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		_, _ = w.Write([]byte(`{"jsonrpc":"2.0","id":1,"result":{"isSimulated":true,"sentToMinersAt":"2022-01-01T00:00:00Z"}}`))
+	}))
+	defer relay.Close()
+	prvKey, err := crypto.GenerateKey()
+	testutil.Ok(t, err)
+	fb, err := flashbot.New(prvKey, &flashbot.Api{URL: relay.URL})
+	testutil.Ok(t, err)
+
+	cmd := &WatchCmd{BundleHash: "0xbundle", Block: 10, Interval: 5 * time.Millisecond}
+	hashes := []common.Hash{{1}}
+
+	var out bytes.Buffer
+	err = cmd.watch(context.Background(), &out, fb.(*flashbot.Flashbot), &chainMock{head: 8, included: true}, hashes)
+	testutil.Ok(t, err)
+	testutil.Assert(t, strings.Contains(out.String(), "included block:10"), "unexpected output:%v", out.String())
+
+	out.Reset()
+	err = cmd.watch(context.Background(), &out, fb.(*flashbot.Flashbot), &chainMock{head: 8}, hashes)
+	testutil.NotOk(t, err)
+	testutil.Assert(t, strings.Contains(err.Error(), "not included"), "unexpected error:%v", err)
+
+	ctx, cncl := context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cncl()
+	out.Reset()
+	err = cmd.watch(ctx, &out, fb.(*flashbot.Flashbot), nil, nil)
+	testutil.NotOk(t, err)
+	testutil.Assert(t, strings.Contains(out.String(), "sent to builders"), "unexpected output:%v", out.String())
+}
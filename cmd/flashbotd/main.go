@@ -0,0 +1,317 @@
+// Copyright (c) The Cryptorium Authors.
+// Licensed under the MIT License.
+
+// Command flashbotd runs the bundle gateway with the relays from a config file.
+//
+//	POST /v1/bundles       submit a bundle in the bundle file format
+//	GET  /v1/bundles       list the bundles, filtered by the state query params
+//	GET  /v1/bundles/{id}  the bundle status on each relay
+//	POST /v1/simulate      simulate the TXs
+//	GET  /v1/relays        the relay names
+//
+// The relays are reloaded from the config file on SIGHUP or when the file changes
+// while the bundles in flight are kept, see daemon.Server.SetRelays.
+//
+// The same API without the listing is served over gRPC with --grpc-listen, see daemon/gatewaypb/gateway.proto.
+// The relay metrics are served in the Prometheus format on /metrics with --metrics-listen.
+package main
+
+import (
+	"context"
+	"net"
+	"net/http"
+	"os"
+	"os/signal"
+	"reflect"
+	"syscall"
+	"time"
+
+	"github.com/alecthomas/kong"
+	"github.com/ethereum/go-ethereum/ethclient"
+	"github.com/go-kit/log"
+	"github.com/go-kit/log/level"
+	"github.com/kachan28/flashbot"
+	"github.com/kachan28/flashbot/daemon"
+	"github.com/kachan28/flashbot/prommetrics"
+	"github.com/pkg/errors"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promhttp"
+	"google.golang.org/grpc"
+)
+
+var cli struct {
+	Config        string        `required:"" help:"YAML, TOML or JSON config file." type:"existingfile"`
+	Listen        string        `help:"HTTP listen address." default:":8080"`
+	GRPCListen    string        `name:"grpc-listen" help:"gRPC listen address, disabled when empty."`
+	MetricsListen string        `name:"metrics-listen" help:"Prometheus metrics listen address, disabled when empty."`
+	Tokens        []string      `required:"" help:"API tokens." env:"FLASHBOTD_TOKENS"`
+	TrackInterval time.Duration `help:"Interval for tracking the bundles inclusion, needs the node URL in the config." default:"2s"`
+	Retention     time.Duration `help:"How long the finished bundles are kept for the status requests." default:"1h"`
+	WatchInterval time.Duration `help:"Interval for checking the config file for changes, 0 reloads only on SIGHUP." default:"10s"`
+}
+
+func main() {
+	kong.Parse(&cli, kong.Name("flashbotd"), kong.Description("Flashbots bundle gateway."))
+
+	logger := log.With(
+		log.NewLogfmtLogger(log.NewSyncWriter(os.Stderr)),
+		"ts", log.DefaultTimestampUTC,
+		"caller", log.DefaultCaller,
+	)
+	if err := run(logger); err != nil {
+		level.Error(logger).Log("msg", "exiting", "err", err)
+		os.Exit(1)
+	}
+}
+
+func run(logger log.Logger) error {
+	cfg, err := flashbot.LoadConfigFile(cli.Config)
+	if err != nil {
+		return err
+	}
+	var metrics flashbot.Metrics
+	reg := prometheus.NewRegistry()
+	if cli.MetricsListen != "" {
+		metrics = prommetrics.New(reg, "flashbot")
+	}
+	relays, err := relaysFromConfig(logger, cfg, metrics)
+	if err != nil {
+		return err
+	}
+	srv, err := daemon.New(logger, relays, cli.Tokens)
+	if err != nil {
+		return err
+	}
+	srv.SetRetention(cli.Retention)
+
+	ctx, cncl := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer cncl()
+
+	if cfg.NodeURL != "" {
+		client, err := ethclient.DialContext(ctx, cfg.NodeURL)
+		if err != nil {
+			return errors.Wrapf(err, "connect to node:%v", cfg.NodeURL)
+		}
+		defer client.Close()
+		if cfg.CheckChainID {
+			if err := verifyChainID(ctx, relays, client, cfg.ChainID); err != nil {
+				return err
+			}
+		}
+		go srv.Track(ctx, client, cli.TrackInterval)
+	} else {
+		level.Warn(logger).Log("msg", "no node URL in the config so the bundles inclusion isn't tracked")
+		if cfg.CheckChainID {
+			if err := verifyChainID(ctx, relays, nil, cfg.ChainID); err != nil {
+				return err
+			}
+		}
+	}
+
+	reload := make(chan os.Signal, 1)
+	signal.Notify(reload, syscall.SIGHUP)
+	defer signal.Stop(reload)
+	go flashbot.WatchConfigFile(ctx, cli.Config, cli.WatchInterval, reload, func(newCfg *flashbot.FileConfig, err error) {
+		if err == nil {
+			err = reloadRelays(logger, srv, cfg, newCfg, metrics)
+		}
+		if err != nil {
+			level.Error(logger).Log("msg", "config reload, keeping the current config", "err", err)
+			return
+		}
+		// The next reload is compared against the applied config.
+		cfg = newCfg
+		level.Info(logger).Log("msg", "config reloaded", "relays", len(newCfg.Relays))
+	})
+
+	httpSrv := &http.Server{Addr: cli.Listen, Handler: srv, ReadHeaderTimeout: 10 * time.Second}
+	errc := make(chan error, 3)
+	go func() {
+		level.Info(logger).Log("msg", "listening", "addr", cli.Listen, "relays", len(relays))
+		errc <- errors.Wrap(httpSrv.ListenAndServe(), "http server")
+	}()
+
+	var metricsSrv *http.Server
+	if cli.MetricsListen != "" {
+		mux := http.NewServeMux()
+		mux.Handle("/metrics", promhttp.HandlerFor(reg, promhttp.HandlerOpts{}))
+		metricsSrv = &http.Server{Addr: cli.MetricsListen, Handler: mux, ReadHeaderTimeout: 10 * time.Second}
+		go func() {
+			level.Info(logger).Log("msg", "listening metrics", "addr", cli.MetricsListen)
+			errc <- errors.Wrap(metricsSrv.ListenAndServe(), "metrics server")
+		}()
+	}
+
+	var grpcSrv *grpc.Server
+	if cli.GRPCListen != "" {
+		lis, err := net.Listen("tcp", cli.GRPCListen)
+		if err != nil {
+			return errors.Wrapf(err, "grpc listen:%v", cli.GRPCListen)
+		}
+		grpcSrv = daemon.NewGRPCServer(srv)
+		defer grpcSrv.Stop()
+		go func() {
+			level.Info(logger).Log("msg", "listening grpc", "addr", cli.GRPCListen)
+			errc <- errors.Wrap(grpcSrv.Serve(lis), "grpc server")
+		}()
+	}
+
+	select {
+	case err := <-errc:
+		return err
+	case <-ctx.Done():
+	}
+	// Stop taking new requests first so that the in-flight gRPC and HTTP submissions finish before the managers are closed.
+	shutdownCtx, cncl := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cncl()
+	if grpcSrv != nil {
+		stopGRPC(shutdownCtx, grpcSrv)
+	}
+	errH := httpSrv.Shutdown(shutdownCtx)
+	if metricsSrv != nil {
+		_ = metricsSrv.Shutdown(shutdownCtx)
+	}
+	if err := srv.Close(shutdownCtx); err != nil {
+		return errors.Wrap(err, "close relays")
+	}
+	return errors.Wrap(errH, "http server shutdown")
+}
+
+// stopGRPC waits for the running gRPC calls until the context is done
+// and then closes the rest, i.e. the StreamStatus streams which can stay open until the client cancels.
+func stopGRPC(ctx context.Context, srv *grpc.Server) {
+	done := make(chan struct{})
+	go func() {
+		srv.GracefulStop()
+		close(done)
+	}()
+	select {
+	case <-done:
+	case <-ctx.Done():
+		srv.Stop()
+		<-done
+	}
+}
+
+// relaysFromConfig creates the relay clients that export to the metrics when not nil.
+func relaysFromConfig(logger log.Logger, cfg *flashbot.FileConfig, metrics flashbot.Metrics) ([]daemon.Relay, error) {
+	clients, err := cfg.Clients()
+	if err != nil {
+		return nil, err
+	}
+	relays := make([]daemon.Relay, len(clients))
+	for i, c := range clients {
+		relays[i] = daemon.Relay{Name: cfg.Relays[i].Name, Client: c}
+		c.Api().OnTiming = logTiming(log.With(logger, "relay", c.Api().URL))
+		c.Api().Metrics = metrics
+	}
+	return relays, nil
+}
+
+// logTiming logs the phase times of every relay request so that the slow phase of a submission can be found.
+func logTiming(logger log.Logger) func(flashbot.RequestTiming) {
+	return func(t flashbot.RequestTiming) {
+		level.Debug(logger).Log(
+			"msg", "relay request timing",
+			"method", t.Method,
+			"attempts", t.Attempts,
+			"reused", t.Reused,
+			"dns", t.DNS,
+			"connect", t.Connect,
+			"tls", t.TLS,
+			"ttfb", t.TTFB,
+			"total", t.Total,
+		)
+	}
+}
+
+// reloadRelays applies the relays from the new config.
+// The chain ID and the node URL need a restart as the bundles in flight depend on them.
+// Only the relays with a changed config get a new client, the others keep their client and connections.
+func reloadRelays(logger log.Logger, srv *daemon.Server, cfg, newCfg *flashbot.FileConfig, metrics flashbot.Metrics) error {
+	if newCfg.ChainID != cfg.ChainID {
+		return errors.Errorf("chain ID change needs a restart current:%v new:%v", cfg.ChainID, newCfg.ChainID)
+	}
+	if newCfg.NodeURL != cfg.NodeURL {
+		return errors.New("node URL change needs a restart")
+	}
+
+	changed := *newCfg
+	changed.Relays = nil
+	relays := make([]daemon.Relay, len(newCfg.Relays))
+	var created []int
+	for i, r := range newCfg.Relays {
+		relays[i].Name = r.Name
+		if client, ok := srv.Client(relayName(r)); ok && relayUnchanged(cfg, newCfg, r) {
+			relays[i].Client = client
+			continue
+		}
+		changed.Relays = append(changed.Relays, r)
+		created = append(created, i)
+	}
+	fresh, err := relaysFromConfig(logger, &changed, metrics)
+	if err != nil {
+		return err
+	}
+	for j, i := range created {
+		relays[i].Client = fresh[j].Client
+	}
+	if newCfg.CheckChainID {
+		err = verifyChainID(context.Background(), fresh, nil, newCfg.ChainID)
+	}
+	if err == nil {
+		err = srv.SetRelays(relays)
+	}
+	if err != nil {
+		for _, r := range fresh {
+			if closer, ok := r.Client.(interface{ CloseIdleConnections() }); ok {
+				closer.CloseIdleConnections()
+			}
+		}
+		return err
+	}
+	return nil
+}
+
+// relayName is the name the daemon uses for the relay.
+func relayName(r flashbot.RelayConfig) string {
+	if r.Name == "" {
+		return r.URL
+	}
+	return r.Name
+}
+
+// relayUnchanged reports whether the relay has the same client config in both configs.
+func relayUnchanged(cfg, newCfg *flashbot.FileConfig, r flashbot.RelayConfig) bool {
+	if cfg.Timeout != newCfg.Timeout || !reflect.DeepEqual(cfg.Retry, newCfg.Retry) {
+		return false
+	}
+	for _, old := range cfg.Relays {
+		if relayName(old) != relayName(r) {
+			continue
+		}
+		return reflect.DeepEqual(old, r) &&
+			cfg.Identities[r.Identity] == newCfg.Identities[r.Identity] &&
+			cfg.Identities[r.TxIdentity] == newCfg.Identities[r.TxIdentity]
+	}
+	return false
+}
+
+func verifyChainID(ctx context.Context, relays []daemon.Relay, backend flashbot.ChainIDBackend, netID int64) error {
+	if backend != nil {
+		if err := flashbot.CheckChainID(ctx, backend, netID); err != nil {
+			return err
+		}
+	}
+	for _, r := range relays {
+		fb, ok := r.Client.(*flashbot.Flashbot)
+		if !ok {
+			continue
+		}
+		// The node is already checked.
+		if err := fb.VerifyChainID(ctx, nil, netID); err != nil {
+			return errors.Wrapf(err, "relay:%v", r.Name)
+		}
+	}
+	return nil
+}
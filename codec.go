@@ -0,0 +1,62 @@
+// Copyright (c) The Cryptorium Authors.
+// Licensed under the MIT License.
+
+package flashbot
+
+import (
+	"bytes"
+	"encoding/json"
+)
+
+// Codec encodes the relay requests and decodes the replies
+// so that a faster JSON implementation can be used on the hot path.
+// It must produce the same output as encoding/json as the relay signature is over the encoded body.
+type Codec interface {
+	Marshal(v interface{}) ([]byte, error)
+	Unmarshal(data []byte, v interface{}) error
+}
+
+// bufferEncoder is implemented by the codecs that can encode straight into a buffer
+// without allocating an intermediate slice.
+type bufferEncoder interface {
+	EncodeTo(buf *bytes.Buffer, v interface{}) error
+}
+
+// StdCodec is the encoding/json codec used by default.
+type StdCodec struct{}
+
+func (StdCodec) Marshal(v interface{}) ([]byte, error) {
+	return json.Marshal(v)
+}
+
+func (StdCodec) Unmarshal(data []byte, v interface{}) error {
+	return json.Unmarshal(data, v)
+}
+
+// EncodeTo appends the encoding of v to the buffer without the trailing newline of json.Encoder.
+func (StdCodec) EncodeTo(buf *bytes.Buffer, v interface{}) error {
+	if err := json.NewEncoder(buf).Encode(v); err != nil {
+		return err
+	}
+	buf.Truncate(buf.Len() - 1)
+	return nil
+}
+
+func (self *Flashbot) codec() Codec {
+	if self.api.Codec == nil {
+		return StdCodec{}
+	}
+	return self.api.Codec
+}
+
+func encodeTo(codec Codec, buf *bytes.Buffer, v interface{}) error {
+	if enc, ok := codec.(bufferEncoder); ok {
+		return enc.EncodeTo(buf, v)
+	}
+	data, err := codec.Marshal(v)
+	if err != nil {
+		return err
+	}
+	buf.Write(data)
+	return nil
+}
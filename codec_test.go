@@ -0,0 +1,104 @@
+// Copyright (c) The Cryptorium Authors.
+// Licensed under the MIT License.
+
+package flashbot
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"strings"
+	"sync/atomic"
+	"testing"
+
+	"github.com/cryptoriums/packages/testutil"
+)
+
+// countingCodec is a codec without EncodeTo so it goes through Marshal.
+type countingCodec struct {
+	marshals   int32
+	unmarshals int32
+}
+
+func (self *countingCodec) Marshal(v interface{}) ([]byte, error) {
+	atomic.AddInt32(&self.marshals, 1)
+	return json.Marshal(v)
+}
+
+func (self *countingCodec) Unmarshal(data []byte, v interface{}) error {
+	atomic.AddInt32(&self.unmarshals, 1)
+	return json.Unmarshal(data, v)
+}
+
+func TestCodec(t *testing.T) {
+	var bodies []string
+	relay := newRelayMock(t, func(method string, params json.RawMessage) (interface{}, *jsonError) {
+		bodies = append(bodies, string(params))
+		return Result{BundleHash: "0xbundle"}, nil
+	})
+	codec := &countingCodec{}
+	fb := newTestFlashbot(t, relay.URL)
+	fb.api.Codec = codec
+
+	resp, err := fb.SendBundle(context.Background(), []string{"0x01"}, 10)
+	testutil.Ok(t, err)
+	testutil.Equals(t, "0xbundle", resp.BundleHash)
+	// The method and the params.
+	testutil.Equals(t, int32(2), atomic.LoadInt32(&codec.marshals))
+	// The reply isn't checked for a relay error when the retries are off.
+	testutil.Equals(t, int32(1), atomic.LoadInt32(&codec.unmarshals))
+
+	// The encoding through Marshal is the same as the default one.
+	fb.api.Codec = nil
+	_, err = fb.SendBundle(context.Background(), []string{"0x01"}, 10)
+	testutil.Ok(t, err)
+	testutil.Equals(t, bodies[0], bodies[1])
+}
+
+func benchmarkBundle(txs int) ParamsSend {
+	params := ParamsSend{BlockNum: "0x10"}
+	tx := "0x" + strings.Repeat("ab", 1000)
+	for i := 0; i < txs; i++ {
+		params.Txs = append(params.Txs, tx)
+	}
+	return params
+}
+
+// BenchmarkEncodeMessage shows the marshaling cost of large bundles
+// with the buffer encoding of the default codec and with a Marshal only codec.
+func BenchmarkEncodeMessage(b *testing.B) {
+	for _, txs := range []int{10, 100, 500} {
+		params := benchmarkBundle(txs)
+		for _, c := range []struct {
+			name  string
+			codec Codec
+		}{{"std", StdCodec{}}, {"marshal", &countingCodec{}}} {
+			codec := c.codec
+			b.Run(fmt.Sprintf("%v/txs=%v", c.name, txs), func(b *testing.B) {
+				var buf bytes.Buffer
+				b.ReportAllocs()
+				for i := 0; i < b.N; i++ {
+					buf.Reset()
+					if err := encodeMessage(&buf, codec, "eth_sendBundle", params); err != nil {
+						b.Fatal(err)
+					}
+				}
+				b.SetBytes(int64(buf.Len()))
+			})
+		}
+	}
+}
+
+func BenchmarkDecodeResponse(b *testing.B) {
+	results := make([]TxResult, 100)
+	resp, err := json.Marshal(map[string]interface{}{"jsonrpc": "2.0", "id": 1, "result": Result{BundleHash: "0xbundle", Results: results}})
+	testutil.Ok(b, err)
+	b.ReportAllocs()
+	b.SetBytes(int64(len(resp)))
+	for i := 0; i < b.N; i++ {
+		if _, err := parseResp(StdCodec{}, resp, 10, nil); err != nil {
+			b.Fatal(err)
+		}
+	}
+}
@@ -0,0 +1,82 @@
+// Copyright (c) The Cryptorium Authors.
+// Licensed under the MIT License.
+
+package flashbot
+
+import (
+	"math/big"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/pkg/errors"
+)
+
+const (
+	coinbaseTransferGas = 21_000
+	coinbaseContractGas = 35_000
+)
+
+// CoinbasePayment describes the payment to the block builder
+// which is usually the last TX of the bundle.
+type CoinbasePayment struct {
+	Amount *big.Int
+	// Contract that forwards msg.value to block.coinbase when called with Data.
+	// When nil the amount is transferred directly to the Recipient.
+	Contract *common.Address
+	Data     []byte
+	// Recipient of the direct transfer, i.e. the fee recipient of the targeted builder.
+	Recipient common.Address
+	GasFeeCap *big.Int
+	GasTipCap *big.Int
+	// Gas limit, when zero it defaults to a value that covers a plain transfer or a forwarding contract call.
+	Gas uint64
+}
+
+// NewCoinbasePaymentTX builds and signs the coinbase payment TX.
+func (self *Flashbot) NewCoinbasePaymentTX(netID int64, nonce uint64, payment CoinbasePayment) (string, *types.Transaction, error) {
+	if payment.Amount == nil || payment.Amount.Sign() <= 0 {
+		return "", nil, errors.New("coinbase payment amount should be positive")
+	}
+	if payment.GasFeeCap == nil || payment.GasFeeCap.Sign() == 0 {
+		return "", nil, errors.New("for EIP1559 TXs the gasMaxFee should not be zero")
+	}
+
+	spec := TxSpec{
+		Value:     payment.Amount,
+		Gas:       payment.Gas,
+		GasFeeCap: payment.GasFeeCap,
+		GasTipCap: payment.GasTipCap,
+	}
+	if payment.Contract != nil {
+		spec.To = payment.Contract
+		spec.Data = payment.Data
+		if spec.Gas == 0 {
+			spec.Gas = coinbaseContractGas + IntrinsicGas(payment.Data, nil, false) - coinbaseTransferGas
+		}
+	} else {
+		if (payment.Recipient == common.Address{}) {
+			return "", nil, errors.New("coinbase payment needs a contract or a recipient")
+		}
+		recipient := payment.Recipient
+		spec.To = &recipient
+		if spec.Gas == 0 {
+			spec.Gas = coinbaseTransferGas
+		}
+	}
+	if spec.GasTipCap == nil {
+		spec.GasTipCap = new(big.Int)
+	}
+
+	return self.SignTx(spec.TxData(netID, nonce))
+}
+
+// AppendCoinbasePayment signs the coinbase payment TX and appends it as the last bundle TX.
+func (self *Flashbot) AppendCoinbasePayment(txsHex []string, netID int64, nonce uint64, payment CoinbasePayment) ([]string, error) {
+	txHex, _, err := self.NewCoinbasePaymentTX(netID, nonce, payment)
+	if err != nil {
+		return nil, errors.Wrap(err, "create coinbase payment TX")
+	}
+	bundle := make([]string, 0, len(txsHex)+1)
+	bundle = append(bundle, txsHex...)
+	return append(bundle, txHex), nil
+}
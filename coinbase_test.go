@@ -0,0 +1,86 @@
+// Copyright (c) The Cryptorium Authors.
+// Licensed under the MIT License.
+
+package flashbot
+
+import (
+	"context"
+	"math/big"
+	"testing"
+
+	"github.com/cryptoriums/packages/testutil"
+	"github.com/ethereum/go-ethereum/common"
+)
+
+func TestNewCoinbasePaymentTX(t *testing.T) {
+	fb := newTestFlashbot(t, "http://localhost")
+	recipient := common.HexToAddress("0xc0")
+
+	_, tx, err := fb.NewCoinbasePaymentTX(1, 3, CoinbasePayment{
+		Amount:    big.NewInt(1000),
+		Recipient: recipient,
+		GasFeeCap: big.NewInt(10),
+	})
+	testutil.Ok(t, err)
+	testutil.Equals(t, &recipient, tx.To())
+	testutil.Equals(t, big.NewInt(1000), tx.Value())
+	testutil.Equals(t, uint64(3), tx.Nonce())
+	testutil.Equals(t, uint64(coinbaseTransferGas), tx.Gas())
+	testutil.Equals(t, int64(0), tx.GasTipCap().Int64())
+
+	// The forwarding contract gets the data and a gas limit that covers it.
+	contract := common.HexToAddress("0xc1")
+	data := []byte{0xde, 0xad, 0xbe, 0xef}
+	_, tx, err = fb.NewCoinbasePaymentTX(1, 3, CoinbasePayment{
+		Amount:    big.NewInt(1000),
+		Contract:  &contract,
+		Data:      data,
+		Recipient: recipient,
+		GasFeeCap: big.NewInt(10),
+		GasTipCap: big.NewInt(2),
+	})
+	testutil.Ok(t, err)
+	testutil.Equals(t, &contract, tx.To())
+	testutil.Equals(t, data, tx.Data())
+	testutil.Equals(t, coinbaseContractGas+IntrinsicGas(data, nil, false)-coinbaseTransferGas, tx.Gas())
+	testutil.Equals(t, big.NewInt(2), tx.GasTipCap())
+
+	_, tx, err = fb.NewCoinbasePaymentTX(1, 3, CoinbasePayment{Amount: big.NewInt(1), Recipient: recipient, GasFeeCap: big.NewInt(10), Gas: 50_000})
+	testutil.Ok(t, err)
+	testutil.Equals(t, uint64(50_000), tx.Gas())
+
+	for _, payment := range []CoinbasePayment{
+		{Recipient: recipient, GasFeeCap: big.NewInt(10)},
+		{Amount: big.NewInt(-1), Recipient: recipient, GasFeeCap: big.NewInt(10)},
+		{Amount: big.NewInt(1), Recipient: recipient},
+		{Amount: big.NewInt(1), GasFeeCap: big.NewInt(10)},
+	} {
+		_, _, err := fb.NewCoinbasePaymentTX(1, 3, payment)
+		testutil.NotOk(t, err)
+	}
+}
+
+func TestAppendCoinbasePayment(t *testing.T) {
+	fb := newTestFlashbot(t, "http://localhost")
+	to := common.HexToAddress("0x70")
+	txsHex, _, err := fb.SignTxs(context.Background(), 1, 0, []TxSpec{{To: &to, Gas: 21_000, GasFeeCap: big.NewInt(10)}})
+	testutil.Ok(t, err)
+
+	bundle, err := fb.AppendCoinbasePayment(txsHex, 1, 1, CoinbasePayment{
+		Amount:    big.NewInt(1000),
+		Recipient: common.HexToAddress("0xc0"),
+		GasFeeCap: big.NewInt(10),
+	})
+	testutil.Ok(t, err)
+	testutil.Equals(t, 2, len(bundle))
+	testutil.Equals(t, txsHex[0], bundle[0])
+	payment, err := DecodeTx(bundle[1])
+	testutil.Ok(t, err)
+	testutil.Equals(t, uint64(1), payment.Nonce())
+	testutil.Equals(t, big.NewInt(1000), payment.Value())
+	// The input isn't modified.
+	testutil.Equals(t, 1, len(txsHex))
+
+	_, err = fb.AppendCoinbasePayment(txsHex, 1, 1, CoinbasePayment{Recipient: common.HexToAddress("0xc0")})
+	testutil.NotOk(t, err)
+}
@@ -0,0 +1,164 @@
+// Copyright (c) The Cryptorium Authors.
+// Licensed under the MIT License.
+
+package flashbot
+
+import (
+	"context"
+	"math/big"
+	"strings"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/pkg/errors"
+)
+
+// CompetitionBackend is implemented by ethclient.Client and Node.
+type CompetitionBackend interface {
+	BlockByNumber(ctx context.Context, number *big.Int) (*types.Block, error)
+	TransactionReceipt(ctx context.Context, txHash common.Hash) (*types.Receipt, error)
+}
+
+// TouchedBackend returns the contracts touched when executing the bundle on top of the state block,
+// i.e. the pools called through a router or a bot contract which aren't visible in the TXs themselves.
+// It is implemented by Node with eth_createAccessList and by localsim.Simulator.
+type TouchedBackend interface {
+	TouchedContracts(ctx context.Context, txsHex []string, blockNumState uint64) ([]common.Address, error)
+}
+
+var _ TouchedBackend = (*Node)(nil)
+
+// ConflictingTx is a landed TX that touched the same contracts as the missed bundle.
+type ConflictingTx struct {
+	Hash  common.Hash
+	Index int
+	From  common.Address
+	To    *common.Address
+	// Contracts are the contracts shared with the missed bundle.
+	Contracts         []common.Address
+	EffectiveGasPrice *big.Int
+	// BundleType and CoinbaseTransfer are set when the TX is known to the blocks API.
+	BundleType       string
+	CoinbaseTransfer string
+}
+
+type CompetitionReport struct {
+	BlockNum uint64
+	Coinbase common.Address
+	// Conflicts are in the block order so the first one is the likely winner.
+	Conflicts []ConflictingTx
+}
+
+// Winner returns the first conflicting TX or nil.
+func (self *CompetitionReport) Winner() *ConflictingTx {
+	if len(self.Conflicts) == 0 {
+		return nil
+	}
+	return &self.Conflicts[0]
+}
+
+// AnalyzeCompetition finds the TXs in the landed block that touched the same contracts as the missed bundle,
+// i.e. were called, emitted logs or were in the access lists, and reports their effective gas price.
+// The contracts of the bundle are the ones touched by its simulation on top of the previous block,
+// without the touched backend only the TX recipients and access lists are known
+// which misses the contracts called through a bot contract.
+// The blocks API is optional and used to tell which of the conflicts were bundles.
+func AnalyzeCompetition(ctx context.Context, backend CompetitionBackend, blocks *BlocksAPI, touched TouchedBackend, txsHex []string, blockNum uint64) (*CompetitionReport, error) {
+	bundle, err := DecodeBundle(txsHex)
+	if err != nil {
+		return nil, err
+	}
+	own := make(map[common.Hash]bool)
+	contracts := make(map[common.Address]bool)
+	for _, tx := range bundle {
+		own[tx.Tx.Hash()] = true
+		for _, a := range touchedByTx(tx.Tx) {
+			contracts[a] = true
+		}
+	}
+	if touched != nil && blockNum > 0 {
+		addrs, err := touched.TouchedContracts(ctx, txsHex, blockNum-1)
+		if err != nil {
+			return nil, errors.Wrap(err, "get bundle touched contracts")
+		}
+		for _, a := range addrs {
+			contracts[a] = true
+		}
+	}
+
+	block, err := backend.BlockByNumber(ctx, new(big.Int).SetUint64(blockNum))
+	if err != nil {
+		return nil, errors.Wrapf(err, "get block:%v", blockNum)
+	}
+	report := &CompetitionReport{BlockNum: blockNum, Coinbase: block.Coinbase()}
+
+	var fbTxs map[string]FlashbotsBlockTx
+	if blocks != nil {
+		fbBlock, err := blocks.Block(ctx, blockNum)
+		if err != nil {
+			return nil, errors.Wrap(err, "get flashbots block")
+		}
+		if fbBlock != nil {
+			fbTxs = make(map[string]FlashbotsBlockTx, len(fbBlock.Transactions))
+			for _, tx := range fbBlock.Transactions {
+				fbTxs[strings.ToLower(tx.TransactionHash)] = tx
+			}
+		}
+	}
+
+	for i, tx := range block.Transactions() {
+		if own[tx.Hash()] {
+			continue
+		}
+		touched := touchedByTx(tx)
+		receipt, err := backend.TransactionReceipt(ctx, tx.Hash())
+		if err != nil {
+			return nil, errors.Wrapf(err, "get receipt TX:%v", tx.Hash().Hex())
+		}
+		for _, l := range receipt.Logs {
+			touched = append(touched, l.Address)
+		}
+
+		var shared []common.Address
+		seen := make(map[common.Address]bool)
+		for _, a := range touched {
+			if contracts[a] && !seen[a] {
+				seen[a] = true
+				shared = append(shared, a)
+			}
+		}
+		if len(shared) == 0 {
+			continue
+		}
+
+		from, err := TxSender(tx)
+		if err != nil {
+			return nil, err
+		}
+		c := ConflictingTx{
+			Hash:              tx.Hash(),
+			Index:             i,
+			From:              from,
+			To:                tx.To(),
+			Contracts:         shared,
+			EffectiveGasPrice: EffectiveGasPrice(tx, block.BaseFee()),
+		}
+		if fbTx, ok := fbTxs[strings.ToLower(tx.Hash().Hex())]; ok {
+			c.BundleType = fbTx.BundleType
+			c.CoinbaseTransfer = fbTx.CoinbaseTransfer
+		}
+		report.Conflicts = append(report.Conflicts, c)
+	}
+	return report, nil
+}
+
+func touchedByTx(tx *types.Transaction) []common.Address {
+	var res []common.Address
+	if tx.To() != nil {
+		res = append(res, *tx.To())
+	}
+	for _, t := range tx.AccessList() {
+		res = append(res, t.Address)
+	}
+	return res
+}
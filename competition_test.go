@@ -0,0 +1,138 @@
+// Copyright (c) The Cryptorium Authors.
+// Licensed under the MIT License.
+
+package flashbot
+
+import (
+	"context"
+	"math/big"
+	"testing"
+
+	"github.com/cryptoriums/packages/testutil"
+	"github.com/ethereum/go-ethereum"
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/common/hexutil"
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/crypto"
+	"github.com/ethereum/go-ethereum/trie"
+)
+
+type touchedMock struct {
+	touched       map[common.Address][]common.Address
+	blockNumState uint64
+}
+
+func (self *touchedMock) TouchedContracts(ctx context.Context, txsHex []string, blockNumState uint64) ([]common.Address, error) {
+	self.blockNumState = blockNumState
+	var res []common.Address
+	for _, tx := range txsHex {
+		decoded, err := DecodeTx(tx)
+		if err != nil {
+			return nil, err
+		}
+		res = append(res, self.touched[*decoded.To()]...)
+	}
+	return res, nil
+}
+
+type competitionMock struct {
+	block    *types.Block
+	receipts map[common.Hash]*types.Receipt
+}
+
+func (self *competitionMock) BlockByNumber(ctx context.Context, number *big.Int) (*types.Block, error) {
+	return self.block, nil
+}
+
+func (self *competitionMock) TransactionReceipt(ctx context.Context, txHash common.Hash) (*types.Receipt, error) {
+	r, ok := self.receipts[txHash]
+	if !ok {
+		return nil, ethereum.NotFound
+	}
+	return r, nil
+}
+
+func TestAnalyzeCompetition(t *testing.T) {
+	prvKey, err := crypto.GenerateKey()
+	testutil.Ok(t, err)
+	pool := common.HexToAddress("0x01")
+	router := common.HexToAddress("0x02")
+	other := common.HexToAddress("0x03")
+
+	sign := func(to common.Address, tip int64) *types.Transaction {
+		tx, err := types.SignNewTx(prvKey, types.LatestSignerForChainID(big.NewInt(1)), &types.DynamicFeeTx{
+			ChainID:   big.NewInt(1),
+			To:        &to,
+			Gas:       21000,
+			GasFeeCap: big.NewInt(100),
+			GasTipCap: big.NewInt(tip),
+		})
+		testutil.Ok(t, err)
+		return tx
+	}
+	own := sign(pool, 1)
+	unrelated := sign(other, 2)
+	// Calls the router which emits a log from the pool.
+	winner := sign(router, 30)
+
+	receipts := map[common.Hash]*types.Receipt{
+		unrelated.Hash(): {},
+		winner.Hash():    {Logs: []*types.Log{{Address: pool}}},
+	}
+	header := &types.Header{Number: big.NewInt(10), BaseFee: big.NewInt(10), Coinbase: common.HexToAddress("0xc0")}
+	block := types.NewBlock(header, []*types.Transaction{unrelated, winner}, nil, nil, trie.NewStackTrie(nil))
+
+	ownHex, err := own.MarshalBinary()
+	testutil.Ok(t, err)
+	report, err := AnalyzeCompetition(context.Background(), &competitionMock{block: block, receipts: receipts}, nil, nil, []string{hexutil.Encode(ownHex)}, 10)
+	testutil.Ok(t, err)
+	testutil.Equals(t, 1, len(report.Conflicts))
+	testutil.Equals(t, winner.Hash(), report.Winner().Hash)
+	testutil.Equals(t, 1, report.Winner().Index)
+	testutil.Equals(t, []common.Address{pool}, report.Winner().Contracts)
+	testutil.Equals(t, big.NewInt(40), report.Winner().EffectiveGasPrice)
+}
+
+func TestAnalyzeCompetitionBotContract(t *testing.T) {
+	prvKey, err := crypto.GenerateKey()
+	testutil.Ok(t, err)
+	pool := common.HexToAddress("0x01")
+	router := common.HexToAddress("0x02")
+	bot := common.HexToAddress("0x04")
+
+	sign := func(to common.Address, tip int64) *types.Transaction {
+		tx, err := types.SignNewTx(prvKey, types.LatestSignerForChainID(big.NewInt(1)), &types.DynamicFeeTx{
+			ChainID:   big.NewInt(1),
+			To:        &to,
+			Gas:       21000,
+			GasFeeCap: big.NewInt(100),
+			GasTipCap: big.NewInt(tip),
+		})
+		testutil.Ok(t, err)
+		return tx
+	}
+	// The own TX calls the bot contract which swaps in the pool.
+	own := sign(bot, 1)
+	winner := sign(router, 30)
+	receipts := map[common.Hash]*types.Receipt{winner.Hash(): {Logs: []*types.Log{{Address: pool}}}}
+	header := &types.Header{Number: big.NewInt(10), BaseFee: big.NewInt(10)}
+	block := types.NewBlock(header, []*types.Transaction{winner}, nil, nil, trie.NewStackTrie(nil))
+	backend := &competitionMock{block: block, receipts: receipts}
+
+	ownHex, err := own.MarshalBinary()
+	testutil.Ok(t, err)
+	bundle := []string{hexutil.Encode(ownHex)}
+
+	// Only the bot contract is known from the TX itself.
+	report, err := AnalyzeCompetition(context.Background(), backend, nil, nil, bundle, 10)
+	testutil.Ok(t, err)
+	testutil.Equals(t, 0, len(report.Conflicts))
+
+	touched := &touchedMock{touched: map[common.Address][]common.Address{bot: {bot, pool}}}
+	report, err = AnalyzeCompetition(context.Background(), backend, nil, touched, bundle, 10)
+	testutil.Ok(t, err)
+	testutil.Equals(t, uint64(9), touched.blockNumState)
+	testutil.Equals(t, 1, len(report.Conflicts))
+	testutil.Equals(t, winner.Hash(), report.Winner().Hash)
+	testutil.Equals(t, []common.Address{pool}, report.Winner().Contracts)
+}
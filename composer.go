@@ -0,0 +1,117 @@
+// Copyright (c) The Cryptorium Authors.
+// Licensed under the MIT License.
+
+package flashbot
+
+import (
+	"context"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/pkg/errors"
+)
+
+// BundleComposer builds bundles out of TXs signed by different accounts,
+// i.e. a funder account that tops up an executor account within the same bundle.
+// Each account gets its nonces from the nonce manager independently.
+type BundleComposer struct {
+	netID   int64
+	nonces  *NonceManager
+	signers map[common.Address]Signer
+	items   []composerItem
+}
+
+type composerItem struct {
+	from common.Address
+	spec TxSpec
+}
+
+func NewBundleComposer(netID int64, nonces *NonceManager, signers ...Signer) *BundleComposer {
+	c := &BundleComposer{
+		netID:   netID,
+		nonces:  nonces,
+		signers: make(map[common.Address]Signer),
+	}
+	for _, signer := range signers {
+		c.AddSigner(signer)
+	}
+	return c
+}
+
+func (self *BundleComposer) AddSigner(signer Signer) {
+	self.signers[signer.Address()] = signer
+}
+
+// Add appends a TX signed by the from account.
+func (self *BundleComposer) Add(from common.Address, spec TxSpec) error {
+	if _, ok := self.signers[from]; !ok {
+		return errors.Errorf("no signer for account:%v", from.Hex())
+	}
+	self.items = append(self.items, composerItem{from: from, spec: spec})
+	return nil
+}
+
+// Len returns the number of added TXs.
+func (self *BundleComposer) Len() int {
+	return len(self.items)
+}
+
+// Reset removes all added TXs.
+func (self *BundleComposer) Reset() {
+	self.items = nil
+}
+
+// Build reserves the nonces for every account and signs the TXs in the order they were added.
+// The reservations are released when any of the TXs fails to build.
+func (self *BundleComposer) Build(ctx context.Context, opts ...TxOption) ([]string, []*types.Transaction, error) {
+	if len(self.items) == 0 {
+		return nil, nil, errors.New("no TXs added")
+	}
+
+	var (
+		counts = make(map[common.Address]uint64)
+		order  []common.Address
+	)
+	for _, item := range self.items {
+		if _, ok := counts[item.from]; !ok {
+			order = append(order, item.from)
+		}
+		counts[item.from]++
+	}
+
+	starts := make(map[common.Address]uint64)
+	release := func() {
+		// Release in reverse so that each reservation is the last one for its account.
+		for i := len(order) - 1; i >= 0; i-- {
+			if start, ok := starts[order[i]]; ok {
+				self.nonces.Release(order[i], start, counts[order[i]])
+			}
+		}
+	}
+	for _, addr := range order {
+		start, err := self.nonces.Reserve(ctx, addr, counts[addr])
+		if err != nil {
+			release()
+			return nil, nil, errors.Wrapf(err, "reserve nonces account:%v", addr.Hex())
+		}
+		starts[addr] = start
+	}
+
+	next := make(map[common.Address]uint64)
+	for addr, start := range starts {
+		next[addr] = start
+	}
+	txsHex := make([]string, 0, len(self.items))
+	txs := make([]*types.Transaction, 0, len(self.items))
+	for i, item := range self.items {
+		txHex, tx, err := BuildTx(ctx, self.signers[item.from], self.netID, next[item.from], item.spec, opts...)
+		if err != nil {
+			release()
+			return nil, nil, errors.Wrapf(err, "build TX index:%v account:%v", i, item.from.Hex())
+		}
+		next[item.from]++
+		txsHex = append(txsHex, txHex)
+		txs = append(txs, tx)
+	}
+	return txsHex, txs, nil
+}
@@ -0,0 +1,51 @@
+// Copyright (c) The Cryptorium Authors.
+// Licensed under the MIT License.
+
+package flashbot
+
+import (
+	"context"
+	"math/big"
+	"testing"
+
+	"github.com/cryptoriums/packages/testutil"
+	"github.com/ethereum/go-ethereum/crypto"
+)
+
+func TestBundleComposer(t *testing.T) {
+	funderKey, err := crypto.GenerateKey()
+	testutil.Ok(t, err)
+	funder, err := NewKeySigner(funderKey)
+	testutil.Ok(t, err)
+	executorKey, err := crypto.GenerateKey()
+	testutil.Ok(t, err)
+	executor, err := NewKeySigner(executorKey)
+	testutil.Ok(t, err)
+
+	nonces := NewNonceManager(&nonceBackendMock{nonce: 2})
+	c := NewBundleComposer(5, nonces, funder, executor)
+
+	to := executor.Address()
+	spec := TxSpec{To: &to, Gas: 21_000, GasFeeCap: big.NewInt(2e9)}
+	testutil.Ok(t, c.Add(funder.Address(), spec))
+	testutil.Ok(t, c.Add(executor.Address(), spec))
+	testutil.Ok(t, c.Add(executor.Address(), spec))
+
+	txsHex, _, err := c.Build(context.Background())
+	testutil.Ok(t, err)
+
+	decoded, err := DecodeBundle(txsHex)
+	testutil.Ok(t, err)
+	testutil.Equals(t, funder.Address(), decoded[0].Sender)
+	testutil.Equals(t, uint64(2), decoded[0].Tx.Nonce())
+	testutil.Equals(t, executor.Address(), decoded[1].Sender)
+	testutil.Equals(t, uint64(2), decoded[1].Tx.Nonce())
+	testutil.Equals(t, uint64(3), decoded[2].Tx.Nonce())
+
+	// Failed builds give back the nonces.
+	testutil.Ok(t, c.Add(funder.Address(), TxSpec{To: &to, Gas: 21_000}))
+	_, _, err = c.Build(context.Background())
+	testutil.NotOk(t, err)
+	next, _ := nonces.Peek(executor.Address())
+	testutil.Equals(t, uint64(4), next)
+}
@@ -0,0 +1,187 @@
+// Copyright (c) The Cryptorium Authors.
+// Licensed under the MIT License.
+
+package flashbot
+
+import (
+	"bufio"
+	"crypto/ecdsa"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/ethereum/go-ethereum/crypto"
+	"github.com/pkg/errors"
+)
+
+// The env vars used by LoadFromEnv.
+// The keys can be given inline as hex or with the _FILE variants as a path to a file with the hex key.
+const (
+	EnvChainID     = "FLASHBOT_CHAIN_ID"
+	EnvRelayURLs   = "FLASHBOT_RELAY_URLS"
+	EnvAuthKey     = "FLASHBOT_AUTH_KEY"
+	EnvAuthKeyFile = "FLASHBOT_AUTH_KEY_FILE"
+	EnvTxKey       = "FLASHBOT_TX_KEY"
+	EnvTxKeyFile   = "FLASHBOT_TX_KEY_FILE"
+	EnvNodeURL     = "FLASHBOT_NODE_URL"
+	EnvTimeout     = "FLASHBOT_TIMEOUT"
+)
+
+const defaultTimeout = 10 * time.Second
+
+type Config struct {
+	ChainID int64
+	// RelayURLs defaults to the flashbots relay of the chain.
+	RelayURLs []string
+	// AuthKey signs the relay requests.
+	AuthKey *ecdsa.PrivateKey
+	// TxKey signs the bundle TXs, the AuthKey signs them when nil.
+	TxKey   *ecdsa.PrivateKey
+	NodeURL string
+	// Timeout is the relay request timeout.
+	Timeout time.Duration
+}
+
+// LoadFromEnv loads the config from the env vars.
+func LoadFromEnv() (*Config, error) {
+	return loadConfig(os.LookupEnv)
+}
+
+// LoadFromEnvFile loads the config from a .env file with one KEY=VALUE per line.
+// The env vars that are already set take precedence over the file.
+func LoadFromEnvFile(path string) (*Config, error) {
+	vars, err := readEnvFile(path)
+	if err != nil {
+		return nil, err
+	}
+	return loadConfig(func(key string) (string, bool) {
+		if v, ok := os.LookupEnv(key); ok {
+			return v, true
+		}
+		v, ok := vars[key]
+		return v, ok
+	})
+}
+
+func loadConfig(lookup func(string) (string, bool)) (*Config, error) {
+	cfg := &Config{ChainID: 1, Timeout: defaultTimeout}
+	get := func(key string) string {
+		v, _ := lookup(key)
+		return strings.TrimSpace(v)
+	}
+
+	if v := get(EnvChainID); v != "" {
+		id, err := strconv.ParseInt(v, 10, 64)
+		if err != nil || id <= 0 {
+			return nil, errors.Errorf("%v should be a positive integer got:%q", EnvChainID, v)
+		}
+		cfg.ChainID = id
+	}
+
+	for _, u := range strings.Split(get(EnvRelayURLs), ",") {
+		if u = strings.TrimSpace(u); u != "" {
+			cfg.RelayURLs = append(cfg.RelayURLs, u)
+		}
+	}
+	if len(cfg.RelayURLs) == 0 {
+		url, err := relayURLDefault(cfg.ChainID)
+		if err != nil {
+			return nil, errors.Wrapf(err, "no %v set and no default relay", EnvRelayURLs)
+		}
+		cfg.RelayURLs = []string{url}
+	}
+
+	var err error
+	if cfg.AuthKey, err = loadKey(get(EnvAuthKey), get(EnvAuthKeyFile), EnvAuthKey, EnvAuthKeyFile); err != nil {
+		return nil, err
+	}
+	if cfg.AuthKey == nil {
+		return nil, errors.Errorf("the auth key is required, set %v or %v", EnvAuthKey, EnvAuthKeyFile)
+	}
+	if cfg.TxKey, err = loadKey(get(EnvTxKey), get(EnvTxKeyFile), EnvTxKey, EnvTxKeyFile); err != nil {
+		return nil, err
+	}
+
+	cfg.NodeURL = get(EnvNodeURL)
+
+	if v := get(EnvTimeout); v != "" {
+		if cfg.Timeout, err = time.ParseDuration(v); err != nil || cfg.Timeout <= 0 {
+			return nil, errors.Errorf("%v should be a positive duration like 10s got:%q", EnvTimeout, v)
+		}
+	}
+	return cfg, nil
+}
+
+func loadKey(inline, path, inlineName, pathName string) (*ecdsa.PrivateKey, error) {
+	if inline != "" && path != "" {
+		return nil, errors.Errorf("set only one of %v and %v", inlineName, pathName)
+	}
+	name := inlineName
+	if path != "" {
+		raw, err := os.ReadFile(path)
+		if err != nil {
+			return nil, errors.Wrapf(err, "read %v:%v", pathName, path)
+		}
+		inline, name = strings.TrimSpace(string(raw)), pathName
+	}
+	if inline == "" {
+		return nil, nil
+	}
+	key, err := crypto.HexToECDSA(strings.TrimPrefix(inline, "0x"))
+	if err != nil {
+		// The error isn't wrapped to avoid leaking the key material in the logs.
+		return nil, errors.Errorf("%v isn't a valid hex private key", name)
+	}
+	return key, nil
+}
+
+func readEnvFile(path string) (map[string]string, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, errors.Wrapf(err, "open env file:%v", path)
+	}
+	defer f.Close()
+
+	vars := make(map[string]string)
+	scanner := bufio.NewScanner(f)
+	for n := 1; scanner.Scan(); n++ {
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+		key, val, ok := strings.Cut(strings.TrimPrefix(line, "export "), "=")
+		if !ok {
+			return nil, errors.Errorf("invalid env file line:%v path:%v", n, path)
+		}
+		val = strings.TrimSpace(val)
+		if len(val) >= 2 && (val[0] == '"' || val[0] == '\'') && val[len(val)-1] == val[0] {
+			val = val[1 : len(val)-1]
+		}
+		vars[strings.TrimSpace(key)] = val
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, errors.Wrapf(err, "read env file:%v", path)
+	}
+	return vars, nil
+}
+
+// Clients creates a client for each of the relays.
+func (self *Config) Clients() ([]Flashboter, error) {
+	var res []Flashboter
+	for _, url := range self.RelayURLs {
+		f, err := New(self.AuthKey, &Api{URL: url, SupportsSimulation: true, Timeout: self.Timeout})
+		if err != nil {
+			return nil, errors.Wrapf(err, "create client relay:%v", url)
+		}
+		if self.TxKey != nil {
+			txSigner, err := NewKeySigner(self.TxKey)
+			if err != nil {
+				return nil, err
+			}
+			f.(*Flashbot).SetTxSigner(txSigner)
+		}
+		res = append(res, f)
+	}
+	return res, nil
+}
@@ -0,0 +1,92 @@
+// Copyright (c) The Cryptorium Authors.
+// Licensed under the MIT License.
+
+package flashbot
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/cryptoriums/packages/testutil"
+	"github.com/ethereum/go-ethereum/crypto"
+)
+
+const testKeyHex = "4c0883a69102937d6231471b5dbb6204fe5129617082792ae468d01a3f362318"
+
+func TestLoadFromEnv(t *testing.T) {
+	for _, k := range []string{EnvChainID, EnvRelayURLs, EnvAuthKey, EnvAuthKeyFile, EnvTxKey, EnvTxKeyFile, EnvNodeURL, EnvTimeout} {
+		t.Setenv(k, "")
+	}
+
+	_, err := LoadFromEnv()
+	testutil.NotOk(t, err)
+	testutil.Assert(t, strings.Contains(err.Error(), EnvAuthKey), "unexpected error:%v", err)
+
+	t.Setenv(EnvAuthKey, "0x"+testKeyHex)
+	cfg, err := LoadFromEnv()
+	testutil.Ok(t, err)
+	testutil.Equals(t, int64(1), cfg.ChainID)
+	testutil.Equals(t, []string{"https://relay.flashbots.net"}, cfg.RelayURLs)
+	testutil.Equals(t, defaultTimeout, cfg.Timeout)
+
+	keyFile := filepath.Join(t.TempDir(), "key")
+	testutil.Ok(t, os.WriteFile(keyFile, []byte(testKeyHex+"\n"), 0o600))
+	t.Setenv(EnvTxKeyFile, keyFile)
+	t.Setenv(EnvRelayURLs, "https://a, https://b")
+	t.Setenv(EnvChainID, "5")
+	t.Setenv(EnvTimeout, "3s")
+	cfg, err = LoadFromEnv()
+	testutil.Ok(t, err)
+	testutil.Equals(t, int64(5), cfg.ChainID)
+	testutil.Equals(t, []string{"https://a", "https://b"}, cfg.RelayURLs)
+	testutil.Equals(t, 3*time.Second, cfg.Timeout)
+	testutil.Equals(t, crypto.PubkeyToAddress(cfg.AuthKey.PublicKey), crypto.PubkeyToAddress(cfg.TxKey.PublicKey))
+
+	clients, err := cfg.Clients()
+	testutil.Ok(t, err)
+	testutil.Equals(t, 2, len(clients))
+	testutil.Equals(t, 3*time.Second, clients[1].Api().Timeout)
+
+	t.Setenv(EnvTxKey, testKeyHex)
+	_, err = LoadFromEnv()
+	testutil.NotOk(t, err)
+	t.Setenv(EnvTxKey, "")
+
+	t.Setenv(EnvTimeout, "soon")
+	_, err = LoadFromEnv()
+	testutil.NotOk(t, err)
+
+	t.Setenv(EnvTimeout, "")
+	t.Setenv(EnvAuthKey, "bad")
+	_, err = LoadFromEnv()
+	testutil.NotOk(t, err)
+	testutil.Assert(t, !strings.Contains(err.Error(), "bad"), "key material in the error:%v", err)
+}
+
+func TestLoadFromEnvFile(t *testing.T) {
+	for _, k := range []string{EnvChainID, EnvRelayURLs, EnvAuthKey, EnvAuthKeyFile, EnvTxKey, EnvTxKeyFile, EnvNodeURL, EnvTimeout} {
+		t.Setenv(k, "")
+		testutil.Ok(t, os.Unsetenv(k))
+	}
+	path := filepath.Join(t.TempDir(), ".env")
+	testutil.Ok(t, os.WriteFile(path, []byte(`
+# comment
+export FLASHBOT_AUTH_KEY="`+testKeyHex+`"
+FLASHBOT_NODE_URL='http://node:8545'
+FLASHBOT_CHAIN_ID=5
+`), 0o600))
+
+	t.Setenv(EnvChainID, "1")
+	cfg, err := LoadFromEnvFile(path)
+	testutil.Ok(t, err)
+	testutil.Equals(t, "http://node:8545", cfg.NodeURL)
+	// The env takes precedence.
+	testutil.Equals(t, int64(1), cfg.ChainID)
+
+	testutil.Ok(t, os.WriteFile(path, []byte("INVALID\n"), 0o600))
+	_, err = LoadFromEnvFile(path)
+	testutil.NotOk(t, err)
+}
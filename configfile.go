@@ -0,0 +1,372 @@
+// Copyright (c) The Cryptorium Authors.
+// Licensed under the MIT License.
+
+package flashbot
+
+import (
+	"bytes"
+	"context"
+	"crypto/ecdsa"
+	"encoding/json"
+	"math/big"
+	"net/url"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/BurntSushi/toml"
+	"github.com/pkg/errors"
+	"gopkg.in/yaml.v2"
+)
+
+// Duration is a time.Duration that is decoded from strings like "1.5s" in the config files.
+type Duration time.Duration
+
+func (self *Duration) UnmarshalText(text []byte) error {
+	d, err := time.ParseDuration(string(text))
+	if err != nil {
+		return errors.Wrapf(err, "parse duration:%v", string(text))
+	}
+	*self = Duration(d)
+	return nil
+}
+
+func (self *Duration) UnmarshalYAML(unmarshal func(interface{}) error) error {
+	var s string
+	if err := unmarshal(&s); err != nil {
+		return err
+	}
+	return self.UnmarshalText([]byte(s))
+}
+
+// IdentityConfig is a signing key given inline as hex, as a path to a file with the hex key
+// or as a path to an encrypted keystore file.
+// The keystore passphrase is resolved as in KeyFromKeystore.
+type IdentityConfig struct {
+	Key          string `yaml:"key" toml:"key" json:"key"`
+	KeyFile      string `yaml:"keyFile" toml:"keyFile" json:"keyFile"`
+	Keystore     string `yaml:"keystore" toml:"keystore" json:"keystore"`
+	KeystorePass string `yaml:"keystorePass" toml:"keystorePass" json:"keystorePass"`
+}
+
+func (self IdentityConfig) load(name string) (*ecdsa.PrivateKey, error) {
+	set := 0
+	for _, v := range []string{self.Key, self.KeyFile, self.Keystore} {
+		if v != "" {
+			set++
+		}
+	}
+	if set != 1 {
+		return nil, errors.Errorf("identity:%v should set exactly one of key, keyFile and keystore", name)
+	}
+	if self.Keystore != "" {
+		return KeyFromKeystore(self.Keystore, self.KeystorePass)
+	}
+	key, err := loadKey(self.Key, self.KeyFile, "identity "+name+" key", "identity "+name+" keyFile")
+	if err != nil {
+		return nil, err
+	}
+	return key, nil
+}
+
+type RetryConfig struct {
+	MaxAttempts int      `yaml:"maxAttempts" toml:"maxAttempts" json:"maxAttempts"`
+	Backoff     Duration `yaml:"backoff" toml:"backoff" json:"backoff"`
+	MaxBackoff  Duration `yaml:"maxBackoff" toml:"maxBackoff" json:"maxBackoff"`
+}
+
+func (self RetryConfig) policy() RetryPolicy {
+	return RetryPolicy{
+		MaxAttempts: self.MaxAttempts,
+		Backoff:     time.Duration(self.Backoff),
+		MaxBackoff:  time.Duration(self.MaxBackoff),
+	}
+}
+
+// RelayConfig is a relay or a builder endpoint.
+type RelayConfig struct {
+	Name string `yaml:"name" toml:"name" json:"name"`
+	URL  string `yaml:"url" toml:"url" json:"url"`
+	// Identity is the name of the auth signing identity.
+	Identity string `yaml:"identity" toml:"identity" json:"identity"`
+	// TxIdentity is the name of the identity that signs the TXs, the auth identity when empty.
+	TxIdentity string `yaml:"txIdentity" toml:"txIdentity" json:"txIdentity"`
+	// Simulation is true by default.
+	Simulation *bool             `yaml:"simulation" toml:"simulation" json:"simulation"`
+	MethodCall string            `yaml:"methodCall" toml:"methodCall" json:"methodCall"`
+	MethodSend string            `yaml:"methodSend" toml:"methodSend" json:"methodSend"`
+	Headers    map[string]string `yaml:"headers" toml:"headers" json:"headers"`
+	// Timeout and Retry override the top level ones.
+	Timeout Duration     `yaml:"timeout" toml:"timeout" json:"timeout"`
+	Retry   *RetryConfig `yaml:"retry" toml:"retry" json:"retry"`
+	// MaxTxs and MaxBodySize are the relay bundle limits, unlimited when 0.
+	MaxTxs      int `yaml:"maxTxs" toml:"maxTxs" json:"maxTxs"`
+	MaxBodySize int `yaml:"maxBodySize" toml:"maxBodySize" json:"maxBodySize"`
+	// RateLimit is the max requests per second sent to the relay with bursts of up to RateBurst, unlimited when 0.
+	// In TOML it needs to be written as a float like 5.0.
+	RateLimit float64 `yaml:"rateLimit" toml:"rateLimit" json:"rateLimit"`
+	RateBurst int     `yaml:"rateBurst" toml:"rateBurst" json:"rateBurst"`
+	// MaxIdleConns and IdleConnTimeout tune the connection pool, see TransportConfig for the defaults.
+	MaxIdleConns    int      `yaml:"maxIdleConns" toml:"maxIdleConns" json:"maxIdleConns"`
+	IdleConnTimeout Duration `yaml:"idleConnTimeout" toml:"idleConnTimeout" json:"idleConnTimeout"`
+	HTTP2           bool     `yaml:"http2" toml:"http2" json:"http2"`
+	// NoDelay sets TCP_NODELAY, on when not set.
+	NoDelay             *bool    `yaml:"noDelay" toml:"noDelay" json:"noDelay"`
+	DialTimeout         Duration `yaml:"dialTimeout" toml:"dialTimeout" json:"dialTimeout"`
+	TLSHandshakeTimeout Duration `yaml:"tlsHandshakeTimeout" toml:"tlsHandshakeTimeout" json:"tlsHandshakeTimeout"`
+	// TLSSessionCache is the number of TLS sessions kept for resumption, 64 when not set and disabled when negative.
+	TLSSessionCache int `yaml:"tlsSessionCache" toml:"tlsSessionCache" json:"tlsSessionCache"`
+	// InsecureSkipVerify skips the verification of the relay certificate, only for testing.
+	InsecureSkipVerify bool `yaml:"insecureSkipVerify" toml:"insecureSkipVerify" json:"insecureSkipVerify"`
+	// MaxResponseSize is the size limit of the relay replies in bytes, see DefaultMaxResponseSize.
+	MaxResponseSize int64 `yaml:"maxResponseSize" toml:"maxResponseSize" json:"maxResponseSize"`
+	// IPs pins the relay host to the addresses skipping the DNS lookups.
+	IPs []string `yaml:"ips" toml:"ips" json:"ips"`
+}
+
+// BundleDefaults are the default bundle options used with SimulateAndSend.
+type BundleDefaults struct {
+	// Blocks is the number of consecutive blocks to target.
+	Blocks uint64 `yaml:"blocks" toml:"blocks" json:"blocks"`
+	// MinProfit is in wei.
+	MinProfit  string `yaml:"minProfit" toml:"minProfit" json:"minProfit"`
+	MaxGasUsed uint64 `yaml:"maxGasUsed" toml:"maxGasUsed" json:"maxGasUsed"`
+	NoReverts  bool   `yaml:"noReverts" toml:"noReverts" json:"noReverts"`
+}
+
+// SimulateAndSendOpts converts the defaults to the SimulateAndSend options.
+func (self BundleDefaults) SimulateAndSendOpts() (SimulateAndSendOpts, error) {
+	opts := SimulateAndSendOpts{Blocks: self.Blocks}
+	if self.MinProfit != "" {
+		v, ok := new(big.Int).SetString(self.MinProfit, 10)
+		if !ok {
+			return opts, errors.Errorf("parse min profit:%v", self.MinProfit)
+		}
+		opts.MinProfit = v
+	}
+	if self.MaxGasUsed > 0 {
+		opts.Policies = append(opts.Policies, MaxGasUsedPolicy(self.MaxGasUsed))
+	}
+	if self.NoReverts {
+		opts.Policies = append(opts.Policies, NoRevertPolicy())
+	}
+	return opts, nil
+}
+
+// FileConfig is the declarative config loaded from a YAML, TOML or JSON file.
+type FileConfig struct {
+	ChainID int64  `yaml:"chainId" toml:"chainId" json:"chainId"`
+	NodeURL string `yaml:"nodeUrl" toml:"nodeUrl" json:"nodeUrl"`
+	// CheckChainID verifies the chain ID against the node and the well known relays at startup.
+	CheckChainID bool                      `yaml:"checkChainId" toml:"checkChainId" json:"checkChainId"`
+	Timeout      Duration                  `yaml:"timeout" toml:"timeout" json:"timeout"`
+	Retry        RetryConfig               `yaml:"retry" toml:"retry" json:"retry"`
+	Identities   map[string]IdentityConfig `yaml:"identities" toml:"identities" json:"identities"`
+	Relays       []RelayConfig             `yaml:"relays" toml:"relays" json:"relays"`
+	Bundle       BundleDefaults            `yaml:"bundle" toml:"bundle" json:"bundle"`
+}
+
+// LoadConfigFile loads the config with the format selected by the file extension.
+func LoadConfigFile(path string) (*FileConfig, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, errors.Wrapf(err, "read config file:%v", path)
+	}
+	cfg, err := ParseConfig(data, strings.TrimPrefix(filepath.Ext(path), "."))
+	if err != nil {
+		return nil, errors.Wrapf(err, "config file:%v", path)
+	}
+	return cfg, nil
+}
+
+// WatchConfigFile reloads the config file each time its modification time changes, checked every interval,
+// or a value is received from the trigger, i.e. a channel passed to signal.Notify for SIGHUP.
+// The onChange func gets either the new config or the load error, in which case the old config should be kept.
+// It blocks until the context is canceled.
+func WatchConfigFile(ctx context.Context, path string, interval time.Duration, trigger <-chan os.Signal, onChange func(*FileConfig, error)) {
+	modTime := func() time.Time {
+		if fi, err := os.Stat(path); err == nil {
+			return fi.ModTime()
+		}
+		return time.Time{}
+	}
+	last := modTime()
+
+	var tick <-chan time.Time
+	if interval > 0 {
+		ticker := time.NewTicker(interval)
+		defer ticker.Stop()
+		tick = ticker.C
+	}
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-trigger:
+			last = modTime()
+		case <-tick:
+			mt := modTime()
+			if mt.Equal(last) {
+				continue
+			}
+			last = mt
+		}
+		onChange(LoadConfigFile(path))
+	}
+}
+
+// ParseConfig parses and validates the config in one of the yaml, toml or json formats.
+func ParseConfig(data []byte, format string) (*FileConfig, error) {
+	cfg := &FileConfig{ChainID: 1, Timeout: Duration(defaultTimeout)}
+	var err error
+	switch strings.ToLower(format) {
+	case "yaml", "yml":
+		err = yaml.UnmarshalStrict(data, cfg)
+	case "toml":
+		var md toml.MetaData
+		md, err = toml.Decode(string(data), cfg)
+		if err == nil && len(md.Undecoded()) > 0 {
+			err = errors.Errorf("unknown fields:%v", md.Undecoded())
+		}
+	case "json":
+		dec := json.NewDecoder(bytes.NewReader(data))
+		dec.DisallowUnknownFields()
+		err = dec.Decode(cfg)
+	default:
+		return nil, errors.Errorf("unsupported config format:%q", format)
+	}
+	if err != nil {
+		return nil, errors.Wrapf(err, "decode %v config", format)
+	}
+	if err := cfg.Validate(); err != nil {
+		return nil, err
+	}
+	return cfg, nil
+}
+
+func (self *FileConfig) Validate() error {
+	if self.ChainID <= 0 {
+		return errors.Errorf("chainId should be positive got:%v", self.ChainID)
+	}
+	if len(self.Relays) == 0 {
+		return errors.New("at least one relay is required")
+	}
+	names := make(map[string]bool)
+	for i, r := range self.Relays {
+		if r.URL == "" {
+			return errors.Errorf("relay index:%v has no url", i)
+		}
+		if r.Name != "" {
+			if names[r.Name] {
+				return errors.Errorf("duplicate relay name:%v", r.Name)
+			}
+			names[r.Name] = true
+		}
+		if _, ok := self.Identities[r.Identity]; !ok {
+			return errors.Errorf("relay:%v unknown identity:%q", r.URL, r.Identity)
+		}
+		if _, ok := self.Identities[r.TxIdentity]; r.TxIdentity != "" && !ok {
+			return errors.Errorf("relay:%v unknown tx identity:%q", r.URL, r.TxIdentity)
+		}
+		if r.MaxTxs < 0 || r.MaxBodySize < 0 || r.MaxResponseSize < 0 {
+			return errors.Errorf("relay:%v negative bundle limits", r.URL)
+		}
+		if r.RateLimit < 0 || r.RateBurst < 0 {
+			return errors.Errorf("relay:%v negative rate limit", r.URL)
+		}
+		if r.MaxIdleConns < 0 || r.IdleConnTimeout < 0 || r.DialTimeout < 0 || r.TLSHandshakeTimeout < 0 {
+			return errors.Errorf("relay:%v negative connection pool limits", r.URL)
+		}
+	}
+	return nil
+}
+
+// Clients creates a client for each of the relays in the config order.
+// The identities used by more than one relay are loaded once.
+func (self *FileConfig) Clients() ([]Flashboter, error) {
+	keys := make(map[string]*ecdsa.PrivateKey)
+	key := func(name string) (*ecdsa.PrivateKey, error) {
+		if k, ok := keys[name]; ok {
+			return k, nil
+		}
+		k, err := self.Identities[name].load(name)
+		if err != nil {
+			return nil, err
+		}
+		keys[name] = k
+		return k, nil
+	}
+
+	var res []Flashboter
+	for _, r := range self.Relays {
+		api := &Api{
+			URL:                r.URL,
+			SupportsSimulation: r.Simulation == nil || *r.Simulation,
+			MethodCall:         r.MethodCall,
+			MethodSend:         r.MethodSend,
+			CustomHeaders:      r.Headers,
+			Timeout:            time.Duration(self.Timeout),
+			Retry:              self.Retry.policy(),
+			Limits:             RelayLimits{MaxTxs: r.MaxTxs, MaxBodySize: r.MaxBodySize},
+			MaxResponseSize:    r.MaxResponseSize,
+			Transport: TransportConfig{
+				MaxIdleConns:        r.MaxIdleConns,
+				MaxIdleConnsPerHost: r.MaxIdleConns,
+				IdleConnTimeout:     time.Duration(r.IdleConnTimeout),
+				ForceHTTP2:          r.HTTP2,
+				NoDelay:             r.NoDelay,
+				DialTimeout:         time.Duration(r.DialTimeout),
+				TLSHandshakeTimeout: time.Duration(r.TLSHandshakeTimeout),
+				TLSSessionCacheSize: r.TLSSessionCache,
+				InsecureSkipVerify:  r.InsecureSkipVerify,
+			},
+		}
+		if r.Timeout != 0 {
+			api.Timeout = time.Duration(r.Timeout)
+		}
+		if r.Retry != nil {
+			api.Retry = r.Retry.policy()
+		}
+		if len(r.IPs) > 0 {
+			u, err := url.Parse(r.URL)
+			if err != nil {
+				return nil, errors.Wrapf(err, "parse relay url:%v", r.URL)
+			}
+			resolver := NewPinnedResolver(0)
+			if err := resolver.Pin(u.Hostname(), r.IPs...); err != nil {
+				return nil, errors.Wrapf(err, "pin relay:%v", r.URL)
+			}
+			api.Transport.Resolver = resolver
+		}
+		if r.RateLimit > 0 {
+			limiter, err := NewRateLimiter(r.RateLimit, r.RateBurst)
+			if err != nil {
+				return nil, errors.Wrapf(err, "create rate limiter relay:%v", r.URL)
+			}
+			api.RateLimiter = limiter
+		}
+
+		authKey, err := key(r.Identity)
+		if err != nil {
+			return nil, err
+		}
+		f, err := New(authKey, api)
+		if err != nil {
+			return nil, errors.Wrapf(err, "create client relay:%v", r.URL)
+		}
+		if r.TxIdentity != "" {
+			txKey, err := key(r.TxIdentity)
+			if err != nil {
+				return nil, err
+			}
+			txSigner, err := NewKeySigner(txKey)
+			if err != nil {
+				return nil, err
+			}
+			f.(*Flashbot).SetTxSigner(txSigner)
+		}
+		res = append(res, f)
+	}
+	return res, nil
+}
@@ -0,0 +1,205 @@
+// Copyright (c) The Cryptorium Authors.
+// Licensed under the MIT License.
+
+package flashbot
+
+import (
+	"context"
+	"encoding/hex"
+	"math/big"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/cryptoriums/packages/testutil"
+	"github.com/ethereum/go-ethereum/crypto"
+)
+
+func TestParseConfig(t *testing.T) {
+	keyFile := filepath.Join(t.TempDir(), "key")
+	testutil.Ok(t, os.WriteFile(keyFile, []byte(testKeyHex), 0o600))
+	txKey, err := crypto.GenerateKey()
+	testutil.Ok(t, err)
+	txKeyHex := crypto.FromECDSA(txKey)
+
+	yamlCfg := `
+chainId: 5
+timeout: 2s
+retry:
+  maxAttempts: 3
+  backoff: 50ms
+identities:
+  auth:
+    keyFile: ` + keyFile + `
+  trader:
+    key: "` + hex.EncodeToString(txKeyHex) + `"
+relays:
+  - name: flashbots
+    url: https://relay-goerli.flashbots.net
+    identity: auth
+    txIdentity: trader
+  - name: builder
+    url: https://builder.example
+    identity: auth
+    simulation: false
+    timeout: 500ms
+    rateLimit: 5
+    rateBurst: 2
+    maxIdleConns: 4
+    idleConnTimeout: 30s
+    http2: true
+    ips: ["127.0.0.1"]
+    dialTimeout: 2s
+    retry:
+      maxAttempts: 1
+bundle:
+  blocks: 3
+  minProfit: "1000"
+  noReverts: true
+`
+	tomlCfg := `
+chainId = 5
+timeout = "2s"
+
+[retry]
+maxAttempts = 3
+backoff = "50ms"
+
+[identities.auth]
+keyFile = "` + keyFile + `"
+
+[identities.trader]
+key = "` + hex.EncodeToString(txKeyHex) + `"
+
+[[relays]]
+name = "flashbots"
+url = "https://relay-goerli.flashbots.net"
+identity = "auth"
+txIdentity = "trader"
+
+[[relays]]
+name = "builder"
+url = "https://builder.example"
+identity = "auth"
+simulation = false
+timeout = "500ms"
+rateLimit = 5.0
+rateBurst = 2
+maxIdleConns = 4
+idleConnTimeout = "30s"
+http2 = true
+ips = ["127.0.0.1"]
+dialTimeout = "2s"
+[relays.retry]
+maxAttempts = 1
+
+[bundle]
+blocks = 3
+minProfit = "1000"
+noReverts = true
+`
+	for format, data := range map[string]string{"yaml": yamlCfg, "toml": tomlCfg} {
+		t.Run(format, func(t *testing.T) {
+			cfg, err := ParseConfig([]byte(data), format)
+			testutil.Ok(t, err)
+			testutil.Equals(t, int64(5), cfg.ChainID)
+			testutil.Equals(t, uint64(3), cfg.Bundle.Blocks)
+
+			clients, err := cfg.Clients()
+			testutil.Ok(t, err)
+			testutil.Equals(t, 2, len(clients))
+
+			fb := clients[0].(*Flashbot)
+			testutil.Assert(t, fb.Api().SupportsSimulation, "simulation should be on by default")
+			testutil.Equals(t, 2*time.Second, fb.Api().Timeout)
+			testutil.Equals(t, RetryPolicy{MaxAttempts: 3, Backoff: 50 * time.Millisecond}, fb.Api().Retry)
+			testutil.Equals(t, crypto.PubkeyToAddress(txKey.PublicKey), fb.TxSigner().Address())
+			testutil.Equals(t, fb.Signer().Address(), clients[1].(*Flashbot).Signer().Address())
+
+			builder := clients[1].Api()
+			testutil.Assert(t, !builder.SupportsSimulation, "simulation should be off")
+			testutil.Equals(t, 500*time.Millisecond, builder.Timeout)
+			testutil.Equals(t, 1, builder.Retry.MaxAttempts)
+			testutil.Assert(t, fb.Api().RateLimiter == nil, "rate limit should be off by default")
+			testutil.Equals(t, 5.0, builder.RateLimiter.rate)
+			testutil.Equals(t, 2.0, builder.RateLimiter.burst)
+			testutil.Equals(t, TransportConfig{
+				MaxIdleConns:        4,
+				MaxIdleConnsPerHost: 4,
+				IdleConnTimeout:     30 * time.Second,
+				ForceHTTP2:          true,
+				DialTimeout:         2 * time.Second,
+				Resolver:            builder.Transport.Resolver,
+			}, builder.Transport)
+			testutil.Equals(t, []string{"127.0.0.1"}, builder.Transport.Resolver.IPs("builder.example"))
+
+			opts, err := cfg.Bundle.SimulateAndSendOpts()
+			testutil.Ok(t, err)
+			testutil.Equals(t, big.NewInt(1000), opts.MinProfit)
+			testutil.Equals(t, uint64(3), opts.Blocks)
+			testutil.Equals(t, 1, len(opts.Policies))
+		})
+	}
+}
+
+func TestParseConfigInvalid(t *testing.T) {
+	for name, tc := range map[string]struct {
+		data   string
+		format string
+	}{
+		"unknown format":   {"", "ini"},
+		"unknown field":    {"chainId: 1\nrelayz: []", "yaml"},
+		"unknown toml key": {"chainId = 1\nrelayz = 1", "toml"},
+		"no relays":        {"chainId: 1", "yaml"},
+		"unknown identity": {"relays:\n  - url: https://a\n    identity: x", "yaml"},
+		"bad duration":     {"timeout: soon", "yaml"},
+	} {
+		t.Run(name, func(t *testing.T) {
+			_, err := ParseConfig([]byte(tc.data), tc.format)
+			testutil.NotOk(t, err)
+		})
+	}
+
+	cfg, err := ParseConfig([]byte("identities:\n  a:\n    key: \"0x01\"\n    keyFile: /tmp/k\nrelays:\n  - url: https://a\n    identity: a"), "yaml")
+	testutil.Ok(t, err)
+	_, err = cfg.Clients()
+	testutil.NotOk(t, err)
+}
+
+func TestWatchConfigFile(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "config.yaml")
+	config := func(url string) []byte {
+		return []byte("identities:\n  a:\n    key: \"" + strings.Repeat("11", 32) + "\"\nrelays:\n  - url: " + url + "\n    identity: a")
+	}
+	testutil.Ok(t, os.WriteFile(path, config("https://a"), 0o600))
+
+	ctx, cncl := context.WithCancel(context.Background())
+	defer cncl()
+	trigger := make(chan os.Signal, 1)
+	// Buffered as writing the file and setting its time can trigger two reloads.
+	changes := make(chan *FileConfig, 10)
+	errs := make(chan error, 10)
+	go WatchConfigFile(ctx, path, 10*time.Millisecond, trigger, func(cfg *FileConfig, err error) {
+		if err != nil {
+			errs <- err
+			return
+		}
+		changes <- cfg
+	})
+
+	trigger <- os.Interrupt
+	cfg := <-changes
+	testutil.Equals(t, "https://a", cfg.Relays[0].URL)
+
+	testutil.Ok(t, os.WriteFile(path, config("https://b"), 0o600))
+	testutil.Ok(t, os.Chtimes(path, time.Now(), time.Now().Add(time.Minute)))
+	for cfg.Relays[0].URL != "https://b" {
+		cfg = <-changes
+	}
+
+	testutil.Ok(t, os.WriteFile(path, []byte("relays: []"), 0o600))
+	testutil.Ok(t, os.Chtimes(path, time.Now(), time.Now().Add(2*time.Minute)))
+	testutil.NotOk(t, <-errs)
+}
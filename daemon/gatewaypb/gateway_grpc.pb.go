@@ -0,0 +1,209 @@
+// Code generated by protoc-gen-go-grpc. DO NOT EDIT.
+// versions:
+// - protoc-gen-go-grpc v1.2.0
+// - protoc             (unknown)
+// source: gateway.proto
+
+package gatewaypb
+
+import (
+	context "context"
+	grpc "google.golang.org/grpc"
+	codes "google.golang.org/grpc/codes"
+	status "google.golang.org/grpc/status"
+)
+
+// This is a compile-time assertion to ensure that this generated file
+// is compatible with the grpc package it is being compiled against.
+// Requires gRPC-Go v1.32.0 or later.
+const _ = grpc.SupportPackageIsVersion7
+
+// GatewayClient is the client API for Gateway service.
+//
+// For semantics around ctx use and closing/ending streaming RPCs, please refer to https://pkg.go.dev/google.golang.org/grpc/?tab=doc#ClientConn.NewStream.
+type GatewayClient interface {
+	SubmitBundle(ctx context.Context, in *SubmitBundleRequest, opts ...grpc.CallOption) (*SubmitBundleResponse, error)
+	Simulate(ctx context.Context, in *SimulateRequest, opts ...grpc.CallOption) (*SimResult, error)
+	// StreamStatus sends the bundle status on each relay it was sent to and then every change
+	// until the bundle reaches a final state on all relays.
+	StreamStatus(ctx context.Context, in *StreamStatusRequest, opts ...grpc.CallOption) (Gateway_StreamStatusClient, error)
+}
+
+type gatewayClient struct {
+	cc grpc.ClientConnInterface
+}
+
+func NewGatewayClient(cc grpc.ClientConnInterface) GatewayClient {
+	return &gatewayClient{cc}
+}
+
+func (c *gatewayClient) SubmitBundle(ctx context.Context, in *SubmitBundleRequest, opts ...grpc.CallOption) (*SubmitBundleResponse, error) {
+	out := new(SubmitBundleResponse)
+	err := c.cc.Invoke(ctx, "/flashbot.gateway.v1.Gateway/SubmitBundle", in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *gatewayClient) Simulate(ctx context.Context, in *SimulateRequest, opts ...grpc.CallOption) (*SimResult, error) {
+	out := new(SimResult)
+	err := c.cc.Invoke(ctx, "/flashbot.gateway.v1.Gateway/Simulate", in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *gatewayClient) StreamStatus(ctx context.Context, in *StreamStatusRequest, opts ...grpc.CallOption) (Gateway_StreamStatusClient, error) {
+	stream, err := c.cc.NewStream(ctx, &Gateway_ServiceDesc.Streams[0], "/flashbot.gateway.v1.Gateway/StreamStatus", opts...)
+	if err != nil {
+		return nil, err
+	}
+	x := &gatewayStreamStatusClient{stream}
+	if err := x.ClientStream.SendMsg(in); err != nil {
+		return nil, err
+	}
+	if err := x.ClientStream.CloseSend(); err != nil {
+		return nil, err
+	}
+	return x, nil
+}
+
+type Gateway_StreamStatusClient interface {
+	Recv() (*BundleStatus, error)
+	grpc.ClientStream
+}
+
+type gatewayStreamStatusClient struct {
+	grpc.ClientStream
+}
+
+func (x *gatewayStreamStatusClient) Recv() (*BundleStatus, error) {
+	m := new(BundleStatus)
+	if err := x.ClientStream.RecvMsg(m); err != nil {
+		return nil, err
+	}
+	return m, nil
+}
+
+// GatewayServer is the server API for Gateway service.
+// All implementations must embed UnimplementedGatewayServer
+// for forward compatibility
+type GatewayServer interface {
+	SubmitBundle(context.Context, *SubmitBundleRequest) (*SubmitBundleResponse, error)
+	Simulate(context.Context, *SimulateRequest) (*SimResult, error)
+	// StreamStatus sends the bundle status on each relay it was sent to and then every change
+	// until the bundle reaches a final state on all relays.
+	StreamStatus(*StreamStatusRequest, Gateway_StreamStatusServer) error
+	mustEmbedUnimplementedGatewayServer()
+}
+
+// UnimplementedGatewayServer must be embedded to have forward compatible implementations.
+type UnimplementedGatewayServer struct {
+}
+
+func (UnimplementedGatewayServer) SubmitBundle(context.Context, *SubmitBundleRequest) (*SubmitBundleResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method SubmitBundle not implemented")
+}
+func (UnimplementedGatewayServer) Simulate(context.Context, *SimulateRequest) (*SimResult, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method Simulate not implemented")
+}
+func (UnimplementedGatewayServer) StreamStatus(*StreamStatusRequest, Gateway_StreamStatusServer) error {
+	return status.Errorf(codes.Unimplemented, "method StreamStatus not implemented")
+}
+func (UnimplementedGatewayServer) mustEmbedUnimplementedGatewayServer() {}
+
+// UnsafeGatewayServer may be embedded to opt out of forward compatibility for this service.
+// Use of this interface is not recommended, as added methods to GatewayServer will
+// result in compilation errors.
+type UnsafeGatewayServer interface {
+	mustEmbedUnimplementedGatewayServer()
+}
+
+func RegisterGatewayServer(s grpc.ServiceRegistrar, srv GatewayServer) {
+	s.RegisterService(&Gateway_ServiceDesc, srv)
+}
+
+func _Gateway_SubmitBundle_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(SubmitBundleRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(GatewayServer).SubmitBundle(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: "/flashbot.gateway.v1.Gateway/SubmitBundle",
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(GatewayServer).SubmitBundle(ctx, req.(*SubmitBundleRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _Gateway_Simulate_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(SimulateRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(GatewayServer).Simulate(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: "/flashbot.gateway.v1.Gateway/Simulate",
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(GatewayServer).Simulate(ctx, req.(*SimulateRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _Gateway_StreamStatus_Handler(srv interface{}, stream grpc.ServerStream) error {
+	m := new(StreamStatusRequest)
+	if err := stream.RecvMsg(m); err != nil {
+		return err
+	}
+	return srv.(GatewayServer).StreamStatus(m, &gatewayStreamStatusServer{stream})
+}
+
+type Gateway_StreamStatusServer interface {
+	Send(*BundleStatus) error
+	grpc.ServerStream
+}
+
+type gatewayStreamStatusServer struct {
+	grpc.ServerStream
+}
+
+func (x *gatewayStreamStatusServer) Send(m *BundleStatus) error {
+	return x.ServerStream.SendMsg(m)
+}
+
+// Gateway_ServiceDesc is the grpc.ServiceDesc for Gateway service.
+// It's only intended for direct use with grpc.RegisterService,
+// and not to be introspected or modified (even as a copy)
+var Gateway_ServiceDesc = grpc.ServiceDesc{
+	ServiceName: "flashbot.gateway.v1.Gateway",
+	HandlerType: (*GatewayServer)(nil),
+	Methods: []grpc.MethodDesc{
+		{
+			MethodName: "SubmitBundle",
+			Handler:    _Gateway_SubmitBundle_Handler,
+		},
+		{
+			MethodName: "Simulate",
+			Handler:    _Gateway_Simulate_Handler,
+		},
+	},
+	Streams: []grpc.StreamDesc{
+		{
+			StreamName:    "StreamStatus",
+			Handler:       _Gateway_StreamStatus_Handler,
+			ServerStreams: true,
+		},
+	},
+	Metadata: "gateway.proto",
+}
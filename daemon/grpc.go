@@ -0,0 +1,277 @@
+// Copyright (c) The Cryptorium Authors.
+// Licensed under the MIT License.
+
+package daemon
+
+import (
+	"context"
+	"crypto/subtle"
+	"strings"
+	"time"
+
+	"github.com/kachan28/flashbot"
+	"github.com/kachan28/flashbot/daemon/gatewaypb"
+	"github.com/pkg/errors"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/credentials"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+)
+
+// NewGRPCServer returns a gRPC server with the gateway service registered
+// and the same token authentication as the HTTP API.
+func NewGRPCServer(srv *Server, opts ...grpc.ServerOption) *grpc.Server {
+	opts = append(opts,
+		grpc.ChainUnaryInterceptor(func(ctx context.Context, req interface{}, _ *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (interface{}, error) {
+			if err := srv.authorizedGRPC(ctx); err != nil {
+				return nil, err
+			}
+			return handler(ctx, req)
+		}),
+		grpc.ChainStreamInterceptor(func(s interface{}, ss grpc.ServerStream, _ *grpc.StreamServerInfo, handler grpc.StreamHandler) error {
+			if err := srv.authorizedGRPC(ss.Context()); err != nil {
+				return err
+			}
+			return handler(s, ss)
+		}),
+	)
+	g := grpc.NewServer(opts...)
+	gatewaypb.RegisterGatewayServer(g, &grpcGateway{srv: srv})
+	return g
+}
+
+// TokenCredentials adds the API token to each call of a gateway client,
+// i.e. grpc.WithPerRPCCredentials(daemon.TokenCredentials(token)).
+func TokenCredentials(token string) credentials.PerRPCCredentials {
+	return tokenCredentials(token)
+}
+
+type tokenCredentials string
+
+func (self tokenCredentials) GetRequestMetadata(ctx context.Context, uri ...string) (map[string]string, error) {
+	return map[string]string{"authorization": "Bearer " + string(self)}, nil
+}
+
+// RequireTransportSecurity is false so that the gateway can be used over a plain internal connection.
+func (self tokenCredentials) RequireTransportSecurity() bool {
+	return false
+}
+
+func (self *Server) authorizedGRPC(ctx context.Context) error {
+	md, _ := metadata.FromIncomingContext(ctx)
+	for _, v := range md.Get("authorization") {
+		token := strings.TrimPrefix(v, "Bearer ")
+		if token == "" {
+			continue
+		}
+		for _, t := range self.tokens {
+			if subtle.ConstantTimeCompare([]byte(token), t) == 1 {
+				return nil
+			}
+		}
+	}
+	return status.Error(codes.Unauthenticated, "missing or invalid API token")
+}
+
+type grpcGateway struct {
+	gatewaypb.UnimplementedGatewayServer
+	srv *Server
+}
+
+func (self *grpcGateway) SubmitBundle(ctx context.Context, req *gatewaypb.SubmitBundleRequest) (*gatewaypb.SubmitBundleResponse, error) {
+	resp, err := self.srv.Submit(ctx, &SubmitRequest{
+		BundleFile: flashbot.BundleFile{
+			Txs:               req.Txs,
+			BlockNumber:       req.BlockNumber,
+			MaxBlockNumber:    req.MaxBlockNumber,
+			MinTimestamp:      req.MinTimestamp,
+			MaxTimestamp:      req.MaxTimestamp,
+			RevertingTxHashes: req.RevertingTxHashes,
+			ReplacementUUID:   req.ReplacementUuid,
+		},
+		Relays:   req.Relays,
+		Simulate: req.Simulate,
+	})
+	if err != nil {
+		return nil, grpcError(err)
+	}
+	res := &gatewaypb.SubmitBundleResponse{Id: resp.ID}
+	for _, s := range resp.Submissions {
+		sub := &gatewaypb.RelaySubmission{Relay: s.Relay, BundleHash: s.BundleHash, Error: s.Error}
+		if s.Sim != nil {
+			sub.Sim = toPBResult(&s.Sim.Result)
+		}
+		res.Submissions = append(res.Submissions, sub)
+	}
+	return res, nil
+}
+
+func (self *grpcGateway) Simulate(ctx context.Context, req *gatewaypb.SimulateRequest) (*gatewaypb.SimResult, error) {
+	res, err := self.srv.Simulate(ctx, &SimulateRequest{Txs: req.Txs, StateBlock: req.StateBlock, Relay: req.Relay})
+	if err != nil {
+		return nil, grpcError(err)
+	}
+	return toPBResult(res), nil
+}
+
+// streamPollInterval is how often StreamStatus re-reads the bundle,
+// i.e. when an event was dropped because the subscriber was too slow.
+var streamPollInterval = time.Second
+
+// streamSubscribe subscribes StreamStatus to the manager events.
+var streamSubscribe = func(m *flashbot.BundleManager) (<-chan flashbot.BundleEvent, func()) {
+	return m.Subscribe(16)
+}
+
+func (self *grpcGateway) StreamStatus(req *gatewaypb.StreamStatusRequest, stream gatewaypb.Gateway_StreamStatusServer) error {
+	ctx := stream.Context()
+
+	// Subscribe before reading the current status so that no change is missed in between.
+	type update struct {
+		relay string
+		event flashbot.BundleEvent
+	}
+	updates := make(chan update)
+	var managers []*relay
+	for _, rl := range self.srv.allRelays() {
+		if _, err := rl.manager.Get(req.Id); err != nil {
+			continue
+		}
+		events, unsubscribe := streamSubscribe(rl.manager)
+		defer unsubscribe()
+		go func(name string) {
+			for e := range events {
+				select {
+				case updates <- update{relay: name, event: e}:
+				case <-ctx.Done():
+					return
+				}
+			}
+		}(rl.name)
+		managers = append(managers, rl)
+	}
+
+	current := make(map[string]*flashbot.ManagedBundle)
+	send := func(name string, b *flashbot.ManagedBundle) error {
+		if b == nil || b.ID != req.Id {
+			return nil
+		}
+		if prev := current[name]; prev != nil {
+			// The events can be older than the last re-read.
+			if len(b.History) < len(prev.History) {
+				return nil
+			}
+			if prev.State == b.State && prev.TargetBlock() == b.TargetBlock() {
+				return nil
+			}
+		}
+		current[name] = b
+		return stream.Send(toPBStatus(BundleStatus{Relay: name, Bundle: b}))
+	}
+	// reread gets the latest state of the bundle from every manager
+	// so that a dropped event delays the update only until the next poll.
+	reread := func() error {
+		for _, rl := range managers {
+			b, err := rl.manager.Get(req.Id)
+			if err != nil {
+				// Removed bundles are in a terminal state so the last one sent stays.
+				continue
+			}
+			if err := send(rl.name, b); err != nil {
+				return err
+			}
+		}
+		return nil
+	}
+
+	if err := reread(); err != nil {
+		return err
+	}
+	if len(current) == 0 {
+		return grpcError(errors.Wrapf(ErrNotFound, "bundle id:%v", req.Id))
+	}
+
+	ticker := time.NewTicker(streamPollInterval)
+	defer ticker.Stop()
+	for !self.srv.final(current) {
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case u := <-updates:
+			if err := send(u.relay, u.event.Bundle); err != nil {
+				return err
+			}
+		case <-ticker.C:
+			if err := reread(); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+// final returns whether none of the bundles will change state anymore.
+// Expired bundles still within their block range are resubmitted by the tracking.
+func (self *Server) final(bundles map[string]*flashbot.ManagedBundle) bool {
+	self.mtx.RLock()
+	defer self.mtx.RUnlock()
+	for _, b := range bundles {
+		if !b.State.Terminal() {
+			return false
+		}
+		if b.State == flashbot.BundleExpired && self.maxBlocks[b.ID] > b.TargetBlock() {
+			return false
+		}
+	}
+	return true
+}
+
+func grpcError(err error) error {
+	switch {
+	case errors.Is(err, ErrInvalidRequest):
+		return status.Error(codes.InvalidArgument, err.Error())
+	case errors.Is(err, ErrNotFound):
+		return status.Error(codes.NotFound, err.Error())
+	}
+	return status.Error(codes.Unavailable, err.Error())
+}
+
+func toPBResult(r *flashbot.Result) *gatewaypb.SimResult {
+	res := &gatewaypb.SimResult{
+		BundleHash:        r.BundleHash,
+		BundleGasPrice:    r.BundleGasPrice,
+		CoinbaseDiff:      r.CoinbaseDiff,
+		EthSentToCoinbase: r.EthSentToCoinbase,
+		GasFees:           r.GasFees,
+	}
+	for _, tx := range r.Results {
+		res.Results = append(res.Results, &gatewaypb.TxResult{
+			TxHash:            tx.TxHash,
+			FromAddress:       tx.FromAddress,
+			GasPrice:          tx.GasPrice,
+			GasUsed:           tx.GasUsed,
+			CoinbaseDiff:      tx.CoinbaseDiff,
+			EthSentToCoinbase: tx.EthSentToCoinbase,
+			GasFees:           tx.GasFees,
+			Error:             tx.Error,
+			Revert:            tx.Revert,
+		})
+	}
+	return res
+}
+
+func toPBStatus(s BundleStatus) *gatewaypb.BundleStatus {
+	res := &gatewaypb.BundleStatus{
+		Relay:         s.Relay,
+		Id:            s.Bundle.ID,
+		State:         string(s.Bundle.State),
+		TargetBlock:   s.Bundle.TargetBlock(),
+		BundleHash:    s.Bundle.BundleHash,
+		IncludedBlock: s.Bundle.IncludedBlock,
+	}
+	if n := len(s.Bundle.History); n > 0 {
+		res.Error = s.Bundle.History[n-1].Err
+	}
+	return res
+}
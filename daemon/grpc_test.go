@@ -0,0 +1,131 @@
+// Copyright (c) The Cryptorium Authors.
+// Licensed under the MIT License.
+
+package daemon
+
+import (
+	"context"
+	"io"
+	"net"
+	"testing"
+	"time"
+
+	"github.com/cryptoriums/packages/testutil"
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/crypto"
+	"github.com/go-kit/log"
+	"github.com/kachan28/flashbot"
+	"github.com/kachan28/flashbot/daemon/gatewaypb"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/credentials/insecure"
+	"google.golang.org/grpc/status"
+	"google.golang.org/grpc/test/bufconn"
+)
+
+func newGRPCClient(t *testing.T, srv *Server, token string) gatewaypb.GatewayClient {
+	lis := bufconn.Listen(1 << 20)
+	g := NewGRPCServer(srv)
+	go func() { _ = g.Serve(lis) }()
+	t.Cleanup(g.Stop)
+
+	conn, err := grpc.Dial("bufnet",
+		grpc.WithContextDialer(func(ctx context.Context, _ string) (net.Conn, error) { return lis.DialContext(ctx) }),
+		grpc.WithTransportCredentials(insecure.NewCredentials()),
+		grpc.WithPerRPCCredentials(TokenCredentials(token)),
+	)
+	testutil.Ok(t, err)
+	t.Cleanup(func() { conn.Close() })
+	return gatewaypb.NewGatewayClient(conn)
+}
+
+func TestGRPC(t *testing.T) {
+	ctx := context.Background()
+	srv, err := New(log.NewNopLogger(), []Relay{{Name: "a", Client: newRelay(t)}}, []string{"secret"})
+	testutil.Ok(t, err)
+
+	_, err = newGRPCClient(t, srv, "wrong").Simulate(ctx, &gatewaypb.SimulateRequest{Txs: []string{"0x01"}})
+	testutil.Equals(t, codes.Unauthenticated, status.Code(err))
+
+	client := newGRPCClient(t, srv, "secret")
+
+	sim, err := client.Simulate(ctx, &gatewaypb.SimulateRequest{Txs: []string{"0x01"}})
+	testutil.Ok(t, err)
+	testutil.Equals(t, "100", sim.CoinbaseDiff)
+
+	_, err = client.SubmitBundle(ctx, &gatewaypb.SubmitBundleRequest{Txs: []string{"0x01"}})
+	testutil.Equals(t, codes.InvalidArgument, status.Code(err))
+
+	resp, err := client.SubmitBundle(ctx, &gatewaypb.SubmitBundleRequest{Txs: []string{"0x01"}, BlockNumber: 10, MaxBlockNumber: 11, Simulate: true})
+	testutil.Ok(t, err)
+	testutil.Equals(t, 1, len(resp.Submissions))
+	testutil.Equals(t, "0xbundle", resp.Submissions[0].BundleHash)
+	testutil.Equals(t, "100", resp.Submissions[0].Sim.CoinbaseDiff)
+
+	stream, err := client.StreamStatus(ctx, &gatewaypb.StreamStatusRequest{Id: "missing"})
+	testutil.Ok(t, err)
+	_, err = stream.Recv()
+	testutil.Equals(t, codes.NotFound, status.Code(err))
+
+	stream, err = client.StreamStatus(ctx, &gatewaypb.StreamStatusRequest{Id: resp.Id})
+	testutil.Ok(t, err)
+	st, err := stream.Recv()
+	testutil.Ok(t, err)
+	testutil.Equals(t, string(flashbot.BundlePending), st.State)
+	testutil.Equals(t, uint64(10), st.TargetBlock)
+
+	// Not included in the first block so it is resubmitted for the next one.
+	chain := &chainMock{head: 10, included: map[common.Hash]uint64{}}
+	testutil.Ok(t, srv.Tick(ctx, chain))
+	var states []string
+	for {
+		st, err = stream.Recv()
+		testutil.Ok(t, err)
+		states = append(states, st.State)
+		if st.TargetBlock == 11 && st.State == string(flashbot.BundlePending) {
+			break
+		}
+	}
+	testutil.Equals(t, string(flashbot.BundleExpired), states[0])
+
+	chain.head = 11
+	chain.included[crypto.Keccak256Hash([]byte{1})] = 11
+	testutil.Ok(t, srv.Tick(ctx, chain))
+	st, err = stream.Recv()
+	testutil.Ok(t, err)
+	testutil.Equals(t, string(flashbot.BundleIncluded), st.State)
+	testutil.Equals(t, uint64(11), st.IncludedBlock)
+	_, err = stream.Recv()
+	testutil.Equals(t, io.EOF, err)
+}
+
+func TestGRPCStreamStatusDroppedEvents(t *testing.T) {
+	ctx := context.Background()
+	// No events reach the stream so it depends on re-reading the bundle.
+	interval, subscribe := streamPollInterval, streamSubscribe
+	t.Cleanup(func() { streamPollInterval, streamSubscribe = interval, subscribe })
+	streamPollInterval = 10 * time.Millisecond
+	streamSubscribe = func(m *flashbot.BundleManager) (<-chan flashbot.BundleEvent, func()) {
+		return make(chan flashbot.BundleEvent), func() {}
+	}
+
+	srv, err := New(log.NewNopLogger(), []Relay{{Name: "a", Client: newRelay(t)}}, []string{"secret"})
+	testutil.Ok(t, err)
+	client := newGRPCClient(t, srv, "secret")
+	resp, err := client.SubmitBundle(ctx, &gatewaypb.SubmitBundleRequest{Txs: []string{"0x01"}, BlockNumber: 10})
+	testutil.Ok(t, err)
+
+	stream, err := client.StreamStatus(ctx, &gatewaypb.StreamStatusRequest{Id: resp.Id})
+	testutil.Ok(t, err)
+	st, err := stream.Recv()
+	testutil.Ok(t, err)
+	testutil.Equals(t, string(flashbot.BundlePending), st.State)
+
+	chain := &chainMock{head: 10, included: map[common.Hash]uint64{crypto.Keccak256Hash([]byte{1}): 10}}
+	testutil.Ok(t, srv.Tick(ctx, chain))
+	st, err = stream.Recv()
+	testutil.Ok(t, err)
+	testutil.Equals(t, string(flashbot.BundleIncluded), st.State)
+	_, err = stream.Recv()
+	testutil.Equals(t, io.EOF, err)
+}
@@ -0,0 +1,609 @@
+// Copyright (c) The Cryptorium Authors.
+// Licensed under the MIT License.
+
+// Package daemon is a gateway that exposes the bundle submission, the simulation and the status
+// over an authenticated HTTP/JSON API for the non Go components.
+package daemon
+
+import (
+	"context"
+	"crypto/subtle"
+	"encoding/json"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/ethereum/go-ethereum"
+	"github.com/go-kit/log"
+	"github.com/go-kit/log/level"
+	"github.com/google/uuid"
+	"github.com/kachan28/flashbot"
+	"github.com/pkg/errors"
+)
+
+const (
+	// DefaultRetention is how long the bundles in a terminal state are kept for the status requests.
+	DefaultRetention = time.Hour
+	// MaxRequestSize is the size limit of the request bodies in bytes.
+	MaxRequestSize = 5 << 20
+)
+
+// Relay is one of the relays the bundles are sent to.
+type Relay struct {
+	Name   string
+	Client flashbot.Flashboter
+}
+
+type relay struct {
+	name    string
+	client  flashbot.Flashboter
+	manager *flashbot.BundleManager
+}
+
+type Server struct {
+	logger log.Logger
+	tokens [][]byte
+
+	mtx    sync.RWMutex
+	relays []*relay
+	// retired are the relays removed by a reload that still have bundles in flight.
+	// They are only tracked until all their bundles reach a terminal state.
+	retired []*relay
+	// maxBlocks are the last target blocks of the bundles submitted for a block range.
+	maxBlocks map[string]uint64
+	retention time.Duration
+}
+
+// New creates a server that sends the bundles to all relays.
+// The requests need an "Authorization: Bearer <token>" header with one of the tokens.
+func New(logger log.Logger, relays []Relay, tokens []string) (*Server, error) {
+	if len(tokens) == 0 {
+		return nil, errors.New("at least one API token is required")
+	}
+	self := &Server{logger: logger, maxBlocks: make(map[string]uint64), retention: DefaultRetention}
+	for _, t := range tokens {
+		self.tokens = append(self.tokens, []byte(t))
+	}
+	if err := self.SetRelays(relays); err != nil {
+		return nil, err
+	}
+	return self, nil
+}
+
+// SetRelays replaces the relays, i.e. after a config reload.
+// The relays with an existing name keep their bundle manager so the bundles in flight are still tracked
+// and the removed relays are tracked until their bundles in flight reach a terminal state.
+// The clients that are no longer used have their idle connections closed.
+func (self *Server) SetRelays(relays []Relay) error {
+	if len(relays) == 0 {
+		return errors.New("at least one relay is required")
+	}
+	self.mtx.Lock()
+	defer self.mtx.Unlock()
+
+	var unused []flashbot.Flashboter
+
+	existing := make(map[string]*relay, len(self.relays))
+	for _, r := range append(self.retired, self.relays...) {
+		existing[r.name] = r
+	}
+	var res []*relay
+	seen := make(map[string]bool)
+	for _, r := range relays {
+		name := r.Name
+		if name == "" {
+			name = r.Client.Api().URL
+		}
+		if seen[name] {
+			return errors.Errorf("duplicate relay name:%v", name)
+		}
+		seen[name] = true
+		if e, ok := existing[name]; ok {
+			if e.client != r.Client {
+				e.manager.SetClient(r.Client)
+				unused = append(unused, e.client)
+				e = &relay{name: name, client: r.Client, manager: e.manager}
+			}
+			res = append(res, e)
+			continue
+		}
+		res = append(res, &relay{name: name, client: r.Client, manager: flashbot.NewBundleManager(r.Client)})
+	}
+
+	var retired []*relay
+	for name, r := range existing {
+		if seen[name] {
+			continue
+		}
+		if inFlight(r.manager) {
+			retired = append(retired, r)
+			continue
+		}
+		unused = append(unused, r.client)
+	}
+	self.relays = res
+	self.retired = retired
+	closeClients(unused, res, retired)
+	return nil
+}
+
+// closeClients closes the idle connections of the clients not used by any of the relays.
+func closeClients(clients []flashbot.Flashboter, relays ...[]*relay) {
+	used := make(map[flashbot.Flashboter]bool)
+	for _, rr := range relays {
+		for _, r := range rr {
+			used[r.client] = true
+		}
+	}
+	for _, c := range clients {
+		if used[c] {
+			continue
+		}
+		if closer, ok := c.(interface{ CloseIdleConnections() }); ok {
+			closer.CloseIdleConnections()
+		}
+	}
+}
+
+func inFlight(m *flashbot.BundleManager) bool {
+	for _, b := range m.List() {
+		if !b.State.Terminal() {
+			return true
+		}
+	}
+	return false
+}
+
+// allRelays returns the active and the retired relays.
+func (self *Server) allRelays() []*relay {
+	self.mtx.RLock()
+	defer self.mtx.RUnlock()
+	return append(append([]*relay(nil), self.relays...), self.retired...)
+}
+
+func (self *Server) isRetired(rl *relay) bool {
+	for _, r := range self.retired {
+		if r.manager == rl.manager {
+			return true
+		}
+	}
+	return false
+}
+
+// pruneRetired removes the retired relays without bundles in flight.
+func (self *Server) pruneRetired() {
+	self.mtx.Lock()
+	defer self.mtx.Unlock()
+	var retired []*relay
+	var unused []flashbot.Flashboter
+	for _, r := range self.retired {
+		if inFlight(r.manager) {
+			retired = append(retired, r)
+			continue
+		}
+		unused = append(unused, r.client)
+	}
+	self.retired = retired
+	closeClients(unused, self.relays, retired)
+}
+
+// SetRetention sets how long the bundles in a terminal state are kept before the tracking removes them.
+func (self *Server) SetRetention(d time.Duration) {
+	self.mtx.Lock()
+	defer self.mtx.Unlock()
+	self.retention = d
+}
+
+// evict removes the bundles that reached a terminal state before the retention period.
+func (self *Server) evict(now time.Time) {
+	self.mtx.RLock()
+	cutoff := now.Add(-self.retention)
+	self.mtx.RUnlock()
+	for _, rl := range self.allRelays() {
+		for _, b := range rl.manager.List() {
+			if !b.State.Terminal() || b.UpdatedAt.After(cutoff) {
+				continue
+			}
+			if err := rl.manager.Remove(b.ID); err != nil {
+				level.Error(self.logger).Log("msg", "remove bundle", "id", b.ID, "relay", rl.name, "err", err)
+				continue
+			}
+			self.mtx.Lock()
+			delete(self.maxBlocks, b.ID)
+			self.mtx.Unlock()
+		}
+	}
+}
+
+// Close closes the bundle managers of all relays, i.e. after the HTTP server shutdown,
+// so that the running submissions finish within the deadline.
+func (self *Server) Close(ctx context.Context) error {
+	var err error
+	for _, r := range self.allRelays() {
+		if errC := r.manager.Close(ctx); errC != nil && err == nil {
+			err = errors.Wrapf(errC, "close relay:%v", r.name)
+		}
+	}
+	return err
+}
+
+// Client returns the client of the active relay with the name.
+func (self *Server) Client(name string) (flashbot.Flashboter, bool) {
+	self.mtx.RLock()
+	defer self.mtx.RUnlock()
+	for _, r := range self.relays {
+		if r.name == name {
+			return r.client, true
+		}
+	}
+	return nil, false
+}
+
+func (self *Server) Relays() []string {
+	self.mtx.RLock()
+	defer self.mtx.RUnlock()
+	names := make([]string, len(self.relays))
+	for i, r := range self.relays {
+		names[i] = r.name
+	}
+	return names
+}
+
+func (self *Server) selectRelays(names []string) ([]*relay, error) {
+	self.mtx.RLock()
+	defer self.mtx.RUnlock()
+	if len(names) == 0 {
+		return append([]*relay(nil), self.relays...), nil
+	}
+	var res []*relay
+	for _, n := range names {
+		found := false
+		for _, r := range self.relays {
+			if r.name == n {
+				res = append(res, r)
+				found = true
+				break
+			}
+		}
+		if !found {
+			return nil, errors.Wrapf(ErrInvalidRequest, "unknown relay:%v", n)
+		}
+	}
+	return res, nil
+}
+
+func (self *Server) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	if !self.authorized(r) {
+		writeJSON(w, http.StatusUnauthorized, map[string]string{"error": "missing or invalid API token"})
+		return
+	}
+	switch {
+	case r.URL.Path == "/v1/bundles" && r.Method == http.MethodPost:
+		self.handleSubmit(w, r)
+	case r.URL.Path == "/v1/bundles" && r.Method == http.MethodGet:
+		self.handleList(w, r)
+	case strings.HasPrefix(r.URL.Path, "/v1/bundles/") && r.Method == http.MethodGet:
+		self.handleGet(w, r, strings.TrimPrefix(r.URL.Path, "/v1/bundles/"))
+	case r.URL.Path == "/v1/simulate" && r.Method == http.MethodPost:
+		self.handleSimulate(w, r)
+	case r.URL.Path == "/v1/relays" && r.Method == http.MethodGet:
+		writeJSON(w, http.StatusOK, self.Relays())
+	default:
+		writeError(w, errors.Wrapf(ErrNotFound, "endpoint:%v %v", r.Method, r.URL.Path))
+	}
+}
+
+func (self *Server) authorized(r *http.Request) bool {
+	token := strings.TrimPrefix(r.Header.Get("Authorization"), "Bearer ")
+	if token == "" {
+		return false
+	}
+	for _, t := range self.tokens {
+		if subtle.ConstantTimeCompare([]byte(token), t) == 1 {
+			return true
+		}
+	}
+	return false
+}
+
+type SubmitRequest struct {
+	flashbot.BundleFile
+	// Relays are the relay names to send to, all relays when empty.
+	Relays []string `json:"relays,omitempty"`
+	// Simulate runs the simulation on each relay that supports it before sending.
+	Simulate bool `json:"simulate,omitempty"`
+}
+
+type RelaySubmission struct {
+	Relay      string             `json:"relay"`
+	BundleHash string             `json:"bundleHash,omitempty"`
+	Sim        *flashbot.Response `json:"sim,omitempty"`
+	Error      string             `json:"error,omitempty"`
+}
+
+type SubmitResponse struct {
+	ID          string            `json:"id"`
+	Submissions []RelaySubmission `json:"submissions"`
+}
+
+// ErrInvalidRequest and ErrNotFound are the errors caused by the request, all others are relay or chain errors.
+var (
+	ErrInvalidRequest = errors.New("invalid request")
+	ErrNotFound       = errors.New("not found")
+)
+
+// Submit sends the bundle to the selected relays and returns the bundle ID which is its replacement UUID.
+func (self *Server) Submit(ctx context.Context, req *SubmitRequest) (*SubmitResponse, error) {
+	if req.Version == 0 {
+		req.Version = flashbot.BundleFileVersion
+	}
+	if err := req.Validate(); err != nil {
+		return nil, errors.Wrap(ErrInvalidRequest, err.Error())
+	}
+	relays, err := self.selectRelays(req.Relays)
+	if err != nil {
+		return nil, err
+	}
+	if req.ReplacementUUID == "" {
+		req.ReplacementUUID = uuid.NewString()
+	}
+	params := req.Params()[0]
+
+	resp := &SubmitResponse{ID: req.ReplacementUUID, Submissions: make([]RelaySubmission, len(relays))}
+	var wg sync.WaitGroup
+	for i, rl := range relays {
+		wg.Add(1)
+		go func(i int, rl *relay) {
+			defer wg.Done()
+			resp.Submissions[i] = self.submit(ctx, rl, params, req.Simulate)
+		}(i, rl)
+	}
+	wg.Wait()
+
+	if req.MaxBlockNumber > req.BlockNumber {
+		self.mtx.Lock()
+		self.maxBlocks[req.ReplacementUUID] = req.MaxBlockNumber
+		self.mtx.Unlock()
+	}
+	return resp, nil
+}
+
+func (self *Server) handleSubmit(w http.ResponseWriter, r *http.Request) {
+	req := &SubmitRequest{}
+	if err := json.NewDecoder(http.MaxBytesReader(w, r.Body, MaxRequestSize)).Decode(req); err != nil {
+		writeError(w, errors.Wrap(ErrInvalidRequest, "decode request:"+err.Error()))
+		return
+	}
+	resp, err := self.Submit(r.Context(), req)
+	if err != nil {
+		writeError(w, err)
+		return
+	}
+	writeJSON(w, http.StatusOK, resp)
+}
+
+func (self *Server) submit(ctx context.Context, rl *relay, params flashbot.ParamsSend, simulate bool) RelaySubmission {
+	res := RelaySubmission{Relay: rl.name}
+	id, err := rl.manager.Add(params)
+	if err != nil {
+		res.Error = err.Error()
+		return res
+	}
+	if simulate && rl.client.Api().SupportsSimulation {
+		if res.Sim, err = rl.manager.Simulate(ctx, id); err != nil {
+			res.Error = errors.Wrap(err, "simulate").Error()
+			return res
+		}
+	}
+	sent, err := rl.manager.Submit(ctx, id)
+	if err != nil {
+		res.Error = errors.Wrap(err, "submit").Error()
+		return res
+	}
+	res.BundleHash = sent.BundleHash
+	level.Info(self.logger).Log("msg", "bundle submitted", "id", id, "relay", rl.name, "block", params.BlockNum, "hash", sent.BundleHash)
+	return res
+}
+
+type BundleStatus struct {
+	Relay  string                  `json:"relay"`
+	Bundle *flashbot.ManagedBundle `json:"bundle"`
+}
+
+// Status returns the bundle status on each relay it was sent to.
+func (self *Server) Status(id string) ([]BundleStatus, error) {
+	relays := self.allRelays()
+	var res []BundleStatus
+	for _, rl := range relays {
+		if b, err := rl.manager.Get(id); err == nil {
+			res = append(res, BundleStatus{Relay: rl.name, Bundle: b})
+		}
+	}
+	if len(res) == 0 {
+		return nil, errors.Wrapf(ErrNotFound, "bundle id:%v", id)
+	}
+	return res, nil
+}
+
+func (self *Server) handleGet(w http.ResponseWriter, r *http.Request, id string) {
+	res, err := self.Status(id)
+	if err != nil {
+		writeError(w, err)
+		return
+	}
+	writeJSON(w, http.StatusOK, res)
+}
+
+func (self *Server) handleList(w http.ResponseWriter, r *http.Request) {
+	var states []flashbot.BundleState
+	for _, s := range r.URL.Query()["state"] {
+		states = append(states, flashbot.BundleState(s))
+	}
+	relays := self.allRelays()
+	res := []BundleStatus{}
+	for _, rl := range relays {
+		for _, b := range rl.manager.List(states...) {
+			res = append(res, BundleStatus{Relay: rl.name, Bundle: b})
+		}
+	}
+	writeJSON(w, http.StatusOK, res)
+}
+
+type SimulateRequest struct {
+	Txs        []string `json:"txs"`
+	StateBlock uint64   `json:"stateBlock,omitempty"`
+	// Relay is the relay name to use, the first one supporting simulations when empty.
+	Relay string `json:"relay,omitempty"`
+}
+
+// Simulate runs the TXs on the selected relay or the first one that supports simulations.
+func (self *Server) Simulate(ctx context.Context, req *SimulateRequest) (*flashbot.Result, error) {
+	if len(req.Txs) == 0 {
+		return nil, errors.Wrap(ErrInvalidRequest, "bundle has no TXs")
+	}
+	var names []string
+	if req.Relay != "" {
+		names = []string{req.Relay}
+	}
+	relays, err := self.selectRelays(names)
+	if err != nil {
+		return nil, err
+	}
+	for _, rl := range relays {
+		if !rl.client.Api().SupportsSimulation {
+			continue
+		}
+		resp, err := rl.client.CallBundle(ctx, req.Txs, req.StateBlock)
+		if err != nil {
+			return nil, errors.Wrapf(err, "simulate relay:%v", rl.name)
+		}
+		return &resp.Result, nil
+	}
+	return nil, errors.Wrap(ErrInvalidRequest, "no relay supports simulations")
+}
+
+func (self *Server) handleSimulate(w http.ResponseWriter, r *http.Request) {
+	req := &SimulateRequest{}
+	if err := json.NewDecoder(http.MaxBytesReader(w, r.Body, MaxRequestSize)).Decode(req); err != nil {
+		writeError(w, errors.Wrap(ErrInvalidRequest, "decode request:"+err.Error()))
+		return
+	}
+	res, err := self.Simulate(r.Context(), req)
+	if err != nil {
+		writeError(w, err)
+		return
+	}
+	writeJSON(w, http.StatusOK, res)
+}
+
+// ChainBackend is the subset of the node methods used to track the inclusion.
+type ChainBackend interface {
+	BlockNumber(ctx context.Context) (uint64, error)
+	flashbot.ReceiptBackend
+}
+
+// Track polls the chain every interval until the context is done to mark the included bundles,
+// expire the ones past their target block and resubmit the bundles with a block range for the next block.
+// The bundles in a terminal state for longer than the retention are removed, see SetRetention.
+func (self *Server) Track(ctx context.Context, backend ChainBackend, interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		if err := self.Tick(ctx, backend); err != nil {
+			level.Error(self.logger).Log("msg", "tracking bundles", "err", err)
+		}
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// Tick runs a single tracking round.
+func (self *Server) Tick(ctx context.Context, backend ChainBackend) error {
+	head, err := backend.BlockNumber(ctx)
+	if err != nil {
+		return errors.Wrap(err, "get head block number")
+	}
+	relays := self.allRelays()
+	for _, rl := range relays {
+		for _, b := range rl.manager.List(flashbot.BundlePending) {
+			if b.TargetBlock() > head {
+				continue
+			}
+			included, err := includedIn(ctx, backend, b)
+			if err != nil {
+				return err
+			}
+			if included {
+				if err := rl.manager.MarkIncluded(b.ID, b.TargetBlock()); err != nil {
+					return err
+				}
+				level.Info(self.logger).Log("msg", "bundle included", "id", b.ID, "relay", rl.name, "block", b.TargetBlock())
+			}
+		}
+
+		for _, id := range rl.manager.Expire(head) {
+			self.mtx.RLock()
+			max, ranged := self.maxBlocks[id]
+			retired := self.isRetired(rl)
+			self.mtx.RUnlock()
+			if head+1 > max {
+				// The range is over for all relays as they share the target blocks.
+				if ranged {
+					self.mtx.Lock()
+					delete(self.maxBlocks, id)
+					self.mtx.Unlock()
+				}
+				continue
+			}
+			if retired {
+				continue
+			}
+			if err := rl.manager.Retarget(id, head+1); err != nil {
+				return err
+			}
+			if _, err := rl.manager.Submit(ctx, id); err != nil {
+				level.Error(self.logger).Log("msg", "resubmit bundle", "id", id, "relay", rl.name, "err", err)
+			}
+		}
+	}
+	self.evict(time.Now())
+	self.pruneRetired()
+	return nil
+}
+
+func includedIn(ctx context.Context, backend flashbot.ReceiptBackend, b *flashbot.ManagedBundle) (bool, error) {
+	for _, h := range b.TxHashes {
+		r, err := backend.TransactionReceipt(ctx, h)
+		if errors.Is(err, ethereum.NotFound) {
+			return false, nil
+		}
+		if err != nil {
+			return false, errors.Wrapf(err, "get receipt TX:%v", h.Hex())
+		}
+		if r.BlockNumber == nil || r.BlockNumber.Uint64() != b.TargetBlock() {
+			return false, nil
+		}
+	}
+	return true, nil
+}
+
+func writeJSON(w http.ResponseWriter, status int, v interface{}) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	_ = json.NewEncoder(w).Encode(v)
+}
+
+func writeError(w http.ResponseWriter, err error) {
+	status := http.StatusBadGateway
+	switch {
+	case errors.Is(err, ErrInvalidRequest):
+		status = http.StatusBadRequest
+	case errors.Is(err, ErrNotFound):
+		status = http.StatusNotFound
+	}
+	writeJSON(w, status, map[string]string{"error": err.Error()})
+}
@@ -0,0 +1,245 @@
+// Copyright (c) The Cryptorium Authors.
+// Licensed under the MIT License.
+
+package daemon
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"io"
+	"math/big"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/cryptoriums/packages/testutil"
+	"github.com/ethereum/go-ethereum"
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/crypto"
+	"github.com/go-kit/log"
+	"github.com/kachan28/flashbot"
+	"github.com/pkg/errors"
+)
+
+func newRelay(t *testing.T) flashbot.Flashboter {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, err := io.ReadAll(r.Body)
+		testutil.Ok(t, err)
+		if strings.Contains(string(body), "eth_callBundle") {
+			_, _ = w.Write([]byte(`{"jsonrpc":"2.0","id":1,"result":{"bundleHash":"0xsim","coinbaseDiff":"100"}}`))
+			return
+		}
+		_, _ = w.Write([]byte(`{"jsonrpc":"2.0","id":1,"result":{"bundleHash":"0xbundle"}}`))
+	}))
+	t.Cleanup(srv.Close)
+	prvKey, err := crypto.GenerateKey()
+	testutil.Ok(t, err)
+	fb, err := flashbot.New(prvKey, &flashbot.Api{URL: srv.URL, SupportsSimulation: true})
+	testutil.Ok(t, err)
+	return fb
+}
+
+type chainMock struct {
+	head     uint64
+	included map[common.Hash]uint64
+}
+
+func (self *chainMock) BlockNumber(ctx context.Context) (uint64, error) { return self.head, nil }
+
+func (self *chainMock) TransactionReceipt(ctx context.Context, hash common.Hash) (*types.Receipt, error) {
+	b, ok := self.included[hash]
+	if !ok {
+		return nil, ethereum.NotFound
+	}
+	return &types.Receipt{BlockNumber: new(big.Int).SetUint64(b)}, nil
+}
+
+func call(t *testing.T, srv http.Handler, method, path, token string, body interface{}, dst interface{}) int {
+	var buf bytes.Buffer
+	if body != nil {
+		testutil.Ok(t, json.NewEncoder(&buf).Encode(body))
+	}
+	req := httptest.NewRequest(method, path, &buf)
+	if token != "" {
+		req.Header.Set("Authorization", "Bearer "+token)
+	}
+	rec := httptest.NewRecorder()
+	srv.ServeHTTP(rec, req)
+	if dst != nil {
+		testutil.Ok(t, json.NewDecoder(rec.Body).Decode(dst))
+	}
+	return rec.Code
+}
+
+func TestServer(t *testing.T) {
+	_, err := New(log.NewNopLogger(), []Relay{{Name: "a", Client: newRelay(t)}}, nil)
+	testutil.NotOk(t, err)
+
+	srv, err := New(log.NewNopLogger(), []Relay{{Name: "a", Client: newRelay(t)}, {Name: "b", Client: newRelay(t)}}, []string{"secret"})
+	testutil.Ok(t, err)
+
+	testutil.Equals(t, http.StatusUnauthorized, call(t, srv, http.MethodGet, "/v1/relays", "", nil, nil))
+	testutil.Equals(t, http.StatusUnauthorized, call(t, srv, http.MethodGet, "/v1/relays", "wrong", nil, nil))
+	var relays []string
+	testutil.Equals(t, http.StatusOK, call(t, srv, http.MethodGet, "/v1/relays", "secret", nil, &relays))
+	testutil.Equals(t, []string{"a", "b"}, relays)
+
+	req := SubmitRequest{BundleFile: flashbot.BundleFile{Txs: []string{"0x01"}, BlockNumber: 10}, Simulate: true}
+	var submitted SubmitResponse
+	testutil.Equals(t, http.StatusOK, call(t, srv, http.MethodPost, "/v1/bundles", "secret", req, &submitted))
+	testutil.Equals(t, 2, len(submitted.Submissions))
+	testutil.Equals(t, "0xbundle", submitted.Submissions[1].BundleHash)
+	testutil.Equals(t, "100", submitted.Submissions[0].Sim.CoinbaseDiff)
+
+	ranged := SubmitRequest{BundleFile: flashbot.BundleFile{Txs: []string{"0x02"}, BlockNumber: 10, MaxBlockNumber: 11}, Relays: []string{"b"}}
+	var submittedRanged SubmitResponse
+	testutil.Equals(t, http.StatusOK, call(t, srv, http.MethodPost, "/v1/bundles", "secret", ranged, &submittedRanged))
+	testutil.Equals(t, 1, len(submittedRanged.Submissions))
+
+	unknown := SubmitRequest{BundleFile: flashbot.BundleFile{Txs: []string{"0x01"}, BlockNumber: 10}, Relays: []string{"c"}}
+	testutil.Equals(t, http.StatusBadRequest, call(t, srv, http.MethodPost, "/v1/bundles", "secret", unknown, nil))
+
+	var statuses []BundleStatus
+	testutil.Equals(t, http.StatusOK, call(t, srv, http.MethodGet, "/v1/bundles/"+submitted.ID, "secret", nil, &statuses))
+	testutil.Equals(t, 2, len(statuses))
+	testutil.Equals(t, flashbot.BundlePending, statuses[0].Bundle.State)
+	testutil.Equals(t, http.StatusNotFound, call(t, srv, http.MethodGet, "/v1/bundles/missing", "secret", nil, nil))
+
+	var sim flashbot.Result
+	testutil.Equals(t, http.StatusOK, call(t, srv, http.MethodPost, "/v1/simulate", "secret", SimulateRequest{Txs: []string{"0x01"}}, &sim))
+	testutil.Equals(t, "100", sim.CoinbaseDiff)
+
+	chain := &chainMock{head: 10, included: map[common.Hash]uint64{crypto.Keccak256Hash([]byte{1}): 10}}
+	testutil.Ok(t, srv.Tick(context.Background(), chain))
+
+	testutil.Equals(t, http.StatusOK, call(t, srv, http.MethodGet, "/v1/bundles?state=included", "secret", nil, &statuses))
+	testutil.Equals(t, 2, len(statuses))
+	// The ranged bundle is resubmitted for the next block.
+	testutil.Equals(t, http.StatusOK, call(t, srv, http.MethodGet, "/v1/bundles/"+submittedRanged.ID, "secret", nil, &statuses))
+	testutil.Equals(t, flashbot.BundlePending, statuses[0].Bundle.State)
+	testutil.Equals(t, uint64(11), statuses[0].Bundle.TargetBlock())
+
+	chain.head = 11
+	testutil.Ok(t, srv.Tick(context.Background(), chain))
+	testutil.Equals(t, http.StatusOK, call(t, srv, http.MethodGet, "/v1/bundles/"+submittedRanged.ID, "secret", nil, &statuses))
+	testutil.Equals(t, flashbot.BundleExpired, statuses[0].Bundle.State)
+}
+
+func TestServerSetRelays(t *testing.T) {
+	ctx := context.Background()
+	srv, err := New(log.NewNopLogger(), []Relay{{Name: "a", Client: newRelay(t)}, {Name: "b", Client: newRelay(t)}}, []string{"secret"})
+	testutil.Ok(t, err)
+
+	resp, err := srv.Submit(ctx, &SubmitRequest{BundleFile: flashbot.BundleFile{Txs: []string{"0x01"}, BlockNumber: 10}})
+	testutil.Ok(t, err)
+
+	testutil.NotOk(t, srv.SetRelays(nil))
+	testutil.NotOk(t, srv.SetRelays([]Relay{{Name: "a", Client: newRelay(t)}, {Name: "a", Client: newRelay(t)}}))
+
+	// Relay a gets a new client and relay b is removed while its bundle is still pending.
+	testutil.Ok(t, srv.SetRelays([]Relay{{Name: "a", Client: newRelay(t)}, {Name: "c", Client: newRelay(t)}}))
+	testutil.Equals(t, []string{"a", "c"}, srv.Relays())
+	statuses, err := srv.Status(resp.ID)
+	testutil.Ok(t, err)
+	testutil.Equals(t, 2, len(statuses))
+	testutil.Equals(t, "b", statuses[1].Relay)
+
+	_, err = srv.Submit(ctx, &SubmitRequest{BundleFile: flashbot.BundleFile{Txs: []string{"0x02"}, BlockNumber: 10}, Relays: []string{"b"}})
+	testutil.Assert(t, errors.Is(err, ErrInvalidRequest), "unexpected error:%v", err)
+
+	// Once the bundle expires the removed relay isn't tracked anymore.
+	testutil.Ok(t, srv.Tick(ctx, &chainMock{head: 10}))
+	statuses, err = srv.Status(resp.ID)
+	testutil.Ok(t, err)
+	testutil.Equals(t, 1, len(statuses))
+	testutil.Equals(t, "a", statuses[0].Relay)
+	testutil.Equals(t, flashbot.BundleExpired, statuses[0].Bundle.State)
+}
+
+// closeCounter counts the CloseIdleConnections calls of the client.
+type closeCounter struct {
+	flashbot.Flashboter
+	closed int
+}
+
+func (self *closeCounter) CloseIdleConnections() {
+	self.closed++
+}
+
+func TestServerSetRelaysClose(t *testing.T) {
+	ctx := context.Background()
+	a, b, c := &closeCounter{Flashboter: newRelay(t)}, &closeCounter{Flashboter: newRelay(t)}, &closeCounter{Flashboter: newRelay(t)}
+	srv, err := New(log.NewNopLogger(), []Relay{{Name: "a", Client: a}, {Name: "b", Client: b}, {Name: "c", Client: c}}, []string{"secret"})
+	testutil.Ok(t, err)
+	_, err = srv.Submit(ctx, &SubmitRequest{BundleFile: flashbot.BundleFile{Txs: []string{"0x01"}, BlockNumber: 10}, Relays: []string{"c"}})
+	testutil.Ok(t, err)
+
+	// Relay a keeps its client, b is removed and c is removed with a bundle in flight.
+	testutil.Ok(t, srv.SetRelays([]Relay{{Name: "a", Client: a}}))
+	testutil.Equals(t, 0, a.closed)
+	testutil.Equals(t, 1, b.closed)
+	testutil.Equals(t, 0, c.closed)
+	client, ok := srv.Client("a")
+	testutil.Assert(t, ok, "relay a should be active")
+	testutil.Assert(t, client == a, "relay a should keep its client")
+	_, ok = srv.Client("c")
+	testutil.Assert(t, !ok, "relay c shouldn't be active")
+
+	// The replaced client is closed.
+	a2 := &closeCounter{Flashboter: newRelay(t)}
+	testutil.Ok(t, srv.SetRelays([]Relay{{Name: "a", Client: a2}}))
+	testutil.Equals(t, 1, a.closed)
+	testutil.Equals(t, 0, a2.closed)
+
+	// The retired client is closed once its bundle expires.
+	testutil.Ok(t, srv.Tick(ctx, &chainMock{head: 10}))
+	testutil.Equals(t, 1, c.closed)
+}
+
+func TestServerClose(t *testing.T) {
+	srv, err := New(log.NewNopLogger(), []Relay{{Name: "a", Client: newRelay(t)}}, []string{"secret"})
+	testutil.Ok(t, err)
+	testutil.Ok(t, srv.Close(context.Background()))
+
+	resp, err := srv.Submit(context.Background(), &SubmitRequest{BundleFile: flashbot.BundleFile{Txs: []string{"0x01"}, BlockNumber: 10}})
+	testutil.Ok(t, err)
+	testutil.Equals(t, 1, len(resp.Submissions))
+	testutil.Assert(t, resp.Submissions[0].Error != "", "submission after close should fail")
+}
+
+func TestServerEvict(t *testing.T) {
+	ctx := context.Background()
+	srv, err := New(log.NewNopLogger(), []Relay{{Name: "a", Client: newRelay(t)}}, []string{"secret"})
+	testutil.Ok(t, err)
+
+	ranged, err := srv.Submit(ctx, &SubmitRequest{BundleFile: flashbot.BundleFile{Txs: []string{"0x01"}, BlockNumber: 10, MaxBlockNumber: 11}})
+	testutil.Ok(t, err)
+	single, err := srv.Submit(ctx, &SubmitRequest{BundleFile: flashbot.BundleFile{Txs: []string{"0x02"}, BlockNumber: 10}})
+	testutil.Ok(t, err)
+
+	// The finished bundles are kept for the retention period.
+	testutil.Ok(t, srv.Tick(ctx, &chainMock{head: 10}))
+	testutil.Ok(t, srv.Tick(ctx, &chainMock{head: 11}))
+	_, err = srv.Status(single.ID)
+	testutil.Ok(t, err)
+	testutil.Equals(t, 0, len(srv.maxBlocks))
+
+	srv.SetRetention(0)
+	testutil.Ok(t, srv.Tick(ctx, &chainMock{head: 12}))
+	for _, id := range []string{ranged.ID, single.ID} {
+		_, err = srv.Status(id)
+		testutil.Assert(t, errors.Is(err, ErrNotFound), "finished bundle not removed:%v", err)
+	}
+}
+
+func TestServerRequestSize(t *testing.T) {
+	srv, err := New(log.NewNopLogger(), []Relay{{Name: "a", Client: newRelay(t)}}, []string{"secret"})
+	testutil.Ok(t, err)
+	oversized := SubmitRequest{BundleFile: flashbot.BundleFile{Txs: []string{"0x" + strings.Repeat("01", MaxRequestSize)}, BlockNumber: 10}}
+	testutil.Equals(t, http.StatusBadRequest, call(t, srv, http.MethodPost, "/v1/bundles", "secret", oversized, nil))
+	testutil.Equals(t, http.StatusBadRequest, call(t, srv, http.MethodPost, "/v1/simulate", "secret", SimulateRequest{Txs: oversized.Txs}, nil))
+	testutil.Equals(t, 0, len(srv.allRelays()[0].manager.List()))
+}
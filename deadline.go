@@ -0,0 +1,122 @@
+// Copyright (c) The Cryptorium Authors.
+// Licensed under the MIT License.
+
+package flashbot
+
+import (
+	"context"
+	"sync/atomic"
+	"time"
+
+	"github.com/pkg/errors"
+)
+
+// ErrTargetBlockPassed is returned when the target block was built before the bundle was submitted.
+var ErrTargetBlockPassed = errors.New("target block already passed")
+
+// ErrTimestampWindowPassed is returned when the max timestamp of the bundle is already in the past.
+var ErrTimestampWindowPassed = errors.New("bundle max timestamp already passed")
+
+// BlockNumberBackend is implemented by ethclient.Client and Node.
+type BlockNumberBackend interface {
+	BlockNumber(ctx context.Context) (uint64, error)
+}
+
+// SendBundleBeforeBlock sends the bundle and aborts the request with ErrTargetBlockPassed
+// once the chain head reaches the target block since the auction for it has ended.
+// The head is polled every interval, one second by default.
+func (self *Flashbot) SendBundleBeforeBlock(
+	ctx context.Context,
+	txsHex []string,
+	blockNum uint64,
+	heads BlockNumberBackend,
+	interval time.Duration,
+) (*Response, error) {
+	if interval <= 0 {
+		interval = time.Second
+	}
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+
+	var passed int32
+	go func() {
+		ticker := time.NewTicker(interval)
+		defer ticker.Stop()
+		for {
+			// Polling errors are ignored as the relay request still has the parent deadline.
+			if head, err := heads.BlockNumber(ctx); err == nil && head >= blockNum {
+				atomic.StoreInt32(&passed, 1)
+				cancel()
+				return
+			}
+			select {
+			case <-ctx.Done():
+				return
+			case <-ticker.C:
+			}
+		}
+	}()
+
+	resp, err := self.SendBundle(ctx, txsHex, blockNum)
+	if atomic.LoadInt32(&passed) == 1 {
+		return nil, errors.Wrapf(ErrTargetBlockPassed, "block:%v", blockNum)
+	}
+	return resp, err
+}
+
+type targetCheck struct {
+	heads   BlockNumberBackend
+	maxSkew time.Duration
+}
+
+// SetTargetCheck enables the checks that fail fast before each bundle send instead of letting the relay ignore the bundle.
+// With heads set the bundles with a target block at or before the chain head are rejected with ErrTargetBlockPassed.
+// The bundles whose max timestamp is older than the local clock minus maxSkew are rejected with ErrTimestampWindowPassed.
+func (self *Flashbot) SetTargetCheck(heads BlockNumberBackend, maxSkew time.Duration) {
+	self.mtx.Lock()
+	defer self.mtx.Unlock()
+	self.targetCheck = &targetCheck{heads: heads, maxSkew: maxSkew}
+}
+
+func (self *Flashbot) checkTarget(ctx context.Context, minTs, maxTs uint64, blockNum uint64) error {
+	self.mtx.RLock()
+	c := self.targetCheck
+	self.mtx.RUnlock()
+	if c == nil {
+		return nil
+	}
+	if err := CheckTimestamps(minTs, maxTs, time.Now(), c.maxSkew); err != nil {
+		return err
+	}
+	if c.heads == nil {
+		return nil
+	}
+	return CheckTargetBlock(ctx, c.heads, blockNum)
+}
+
+// CheckTargetBlock returns ErrTargetBlockPassed when the chain head already reached the target block.
+func CheckTargetBlock(ctx context.Context, heads BlockNumberBackend, blockNum uint64) error {
+	head, err := heads.BlockNumber(ctx)
+	if err != nil {
+		return errors.Wrap(err, "get head block number")
+	}
+	if head >= blockNum {
+		return errors.Wrapf(ErrTargetBlockPassed, "block:%v head:%v", blockNum, head)
+	}
+	return nil
+}
+
+// CheckTimestamps validates the bundle timestamp range, 0 means not set, against the clock
+// allowing the given skew between the local clock and the block timestamps.
+func CheckTimestamps(minTs, maxTs uint64, now time.Time, maxSkew time.Duration) error {
+	if maxTs == 0 {
+		return nil
+	}
+	if minTs > maxTs {
+		return errors.Errorf("min timestamp:%v after the max timestamp:%v", minTs, maxTs)
+	}
+	if earliest := now.Add(-maxSkew).Unix(); int64(maxTs) < earliest {
+		return errors.Wrapf(ErrTimestampWindowPassed, "max timestamp:%v now:%v skew:%v", maxTs, now.Unix(), maxSkew)
+	}
+	return nil
+}
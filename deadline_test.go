@@ -0,0 +1,66 @@
+// Copyright (c) The Cryptorium Authors.
+// Licensed under the MIT License.
+
+package flashbot
+
+import (
+	"context"
+	"encoding/json"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/cryptoriums/packages/testutil"
+	"github.com/pkg/errors"
+)
+
+type blockNumberMock struct {
+	head uint64
+}
+
+func (self *blockNumberMock) BlockNumber(ctx context.Context) (uint64, error) {
+	return atomic.LoadUint64(&self.head), nil
+}
+
+func TestSendBundleBeforeBlock(t *testing.T) {
+	ctx := context.Background()
+	release := make(chan struct{})
+	relay := newRelayMock(t, func(method string, params json.RawMessage) (interface{}, *jsonError) {
+		<-release
+		return Result{BundleHash: "0xbundle"}, nil
+	})
+	defer close(release)
+	fb := newTestFlashbot(t, relay.URL)
+
+	heads := &blockNumberMock{head: 9}
+	go func() {
+		time.Sleep(20 * time.Millisecond)
+		atomic.StoreUint64(&heads.head, 10)
+	}()
+	_, err := fb.SendBundleBeforeBlock(ctx, []string{"0x01"}, 10, heads, time.Millisecond)
+	testutil.Assert(t, errors.Is(err, ErrTargetBlockPassed), "unexpected error:%v", err)
+}
+
+func TestTargetCheck(t *testing.T) {
+	ctx := context.Background()
+	relay := newRelayMock(t, func(method string, params json.RawMessage) (interface{}, *jsonError) {
+		return Result{BundleHash: "0xbundle"}, nil
+	})
+	fb := newTestFlashbot(t, relay.URL)
+	fb.SetTargetCheck(&blockNumberMock{head: 10}, time.Minute)
+
+	_, err := fb.SendBundle(ctx, []string{"0x01"}, 10)
+	testutil.Assert(t, errors.Is(err, ErrTargetBlockPassed), "unexpected error:%v", err)
+
+	now := uint64(time.Now().Unix())
+	_, err = fb.SendBundleParams(ctx, ParamsSend{Txs: []string{"0x01"}, BlockNum: "0xb", MaxTimestamp: now - 120})
+	testutil.Assert(t, errors.Is(err, ErrTimestampWindowPassed), "unexpected error:%v", err)
+	_, err = fb.SendBundleParams(ctx, ParamsSend{Txs: []string{"0x01"}, BlockNum: "0xb", MinTimestamp: now + 10, MaxTimestamp: now})
+	testutil.NotOk(t, err)
+	testutil.Equals(t, 0, len(relay.Methods()))
+
+	// Within the allowed skew.
+	_, err = fb.SendBundleParams(ctx, ParamsSend{Txs: []string{"0x01"}, BlockNum: "0xb", MaxTimestamp: now - 30})
+	testutil.Ok(t, err)
+	testutil.Equals(t, []string{"eth_sendBundle"}, relay.Methods())
+}
@@ -0,0 +1,162 @@
+// Copyright (c) The Cryptorium Authors.
+// Licensed under the MIT License.
+
+package flashbot
+
+import (
+	"math/big"
+	"strings"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/common/hexutil"
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/rlp"
+	"github.com/pkg/errors"
+)
+
+type DecodedTx struct {
+	Tx     *types.Transaction
+	Sender common.Address
+}
+
+// DecodeTx decodes a hex encoded signed TX as used in the bundle params.
+func DecodeTx(txHex string) (*types.Transaction, error) {
+	raw, err := hexutil.Decode(strings.TrimSpace(txHex))
+	if err != nil {
+		return nil, errors.Wrap(err, "decode TX hex")
+	}
+	if len(raw) > 0 && raw[0] == SetCodeTxType {
+		return nil, errors.New("set code TXs can't be decoded as types.Transaction, use DecodeSetCodeTx")
+	}
+	tx := &types.Transaction{}
+	if err := tx.UnmarshalBinary(raw); err != nil {
+		return nil, errors.Wrap(err, "unmarshal TX")
+	}
+	return tx, nil
+}
+
+// DecodeSetCodeTx decodes a hex encoded signed EIP-7702 TX.
+func DecodeSetCodeTx(txHex string) (*SetCodeTx, error) {
+	raw, err := hexutil.Decode(strings.TrimSpace(txHex))
+	if err != nil {
+		return nil, errors.Wrap(err, "decode TX hex")
+	}
+	if len(raw) == 0 || raw[0] != SetCodeTxType {
+		return nil, errors.New("not a set code TX")
+	}
+	tx := &SetCodeTx{}
+	if err := rlp.DecodeBytes(raw[1:], tx); err != nil {
+		return nil, errors.Wrap(err, "rlp decode set code TX")
+	}
+	return tx, nil
+}
+
+// bundleTx are the fields of a signed bundle TX used by the pre-submission checks
+// for all TX types including the set code TXs which types.Transaction can't decode.
+type bundleTx struct {
+	hash       common.Hash
+	chainID    *big.Int
+	nonce      uint64
+	to         *common.Address
+	data       []byte
+	accessList types.AccessList
+	gas        uint64
+	gasFeeCap  *big.Int
+	gasTipCap  *big.Int
+	value      *big.Int
+	// authList is only set for the set code TXs.
+	authList []SetCodeAuthorization
+
+	tx      *types.Transaction
+	setCode *SetCodeTx
+}
+
+func decodeBundleTx(txHex string) (*bundleTx, error) {
+	raw, err := hexutil.Decode(strings.TrimSpace(txHex))
+	if err != nil {
+		return nil, errors.Wrap(err, "decode TX hex")
+	}
+	if len(raw) > 0 && raw[0] == SetCodeTxType {
+		tx, err := DecodeSetCodeTx(txHex)
+		if err != nil {
+			return nil, err
+		}
+		hash, err := tx.Hash()
+		if err != nil {
+			return nil, err
+		}
+		to := tx.To
+		return &bundleTx{
+			hash:       hash,
+			chainID:    tx.ChainID,
+			nonce:      tx.Nonce,
+			to:         &to,
+			data:       tx.Data,
+			accessList: tx.AccessList,
+			gas:        tx.Gas,
+			gasFeeCap:  tx.GasFeeCap,
+			gasTipCap:  tx.GasTipCap,
+			value:      tx.Value,
+			authList:   tx.AuthList,
+			setCode:    tx,
+		}, nil
+	}
+	tx, err := DecodeTx(txHex)
+	if err != nil {
+		return nil, err
+	}
+	return &bundleTx{
+		hash:       tx.Hash(),
+		chainID:    tx.ChainId(),
+		nonce:      tx.Nonce(),
+		to:         tx.To(),
+		data:       tx.Data(),
+		accessList: tx.AccessList(),
+		gas:        tx.Gas(),
+		gasFeeCap:  tx.GasFeeCap(),
+		gasTipCap:  tx.GasTipCap(),
+		value:      tx.Value(),
+		tx:         tx,
+	}, nil
+}
+
+func (self *bundleTx) sender() (common.Address, error) {
+	if self.setCode != nil {
+		sender, err := self.setCode.Sender()
+		if err != nil {
+			return common.Address{}, errors.Wrapf(err, "recover sender TX:%v", self.hash.Hex())
+		}
+		return sender, nil
+	}
+	return TxSender(self.tx)
+}
+
+// TxSender recovers the address that signed the TX.
+func TxSender(tx *types.Transaction) (common.Address, error) {
+	var signer types.Signer = types.HomesteadSigner{}
+	if tx.Protected() {
+		signer = types.LatestSignerForChainID(tx.ChainId())
+	}
+	sender, err := types.Sender(signer, tx)
+	if err != nil {
+		return common.Address{}, errors.Wrapf(err, "recover sender TX:%v", tx.Hash().Hex())
+	}
+	return sender, nil
+}
+
+// DecodeBundle decodes the hex encoded bundle TXs and recovers their senders.
+func DecodeBundle(txsHex []string) ([]DecodedTx, error) {
+	txs := make([]DecodedTx, 0, len(txsHex))
+	for i, txHex := range txsHex {
+		tx, err := DecodeTx(txHex)
+		if err != nil {
+			return nil, errors.Wrapf(err, "decode TX index:%v", i)
+		}
+		sender, err := TxSender(tx)
+		if err != nil {
+			return nil, errors.Wrapf(err, "TX index:%v", i)
+		}
+		txs = append(txs, DecodedTx{Tx: tx, Sender: sender})
+	}
+	return txs, nil
+}
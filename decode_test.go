@@ -0,0 +1,56 @@
+// Copyright (c) The Cryptorium Authors.
+// Licensed under the MIT License.
+
+package flashbot
+
+import (
+	"context"
+	"math/big"
+	"testing"
+
+	"github.com/cryptoriums/packages/testutil"
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/crypto"
+)
+
+func TestDecodeBundle(t *testing.T) {
+	prvKey, err := crypto.GenerateKey()
+	testutil.Ok(t, err)
+	pubKey := crypto.PubkeyToAddress(prvKey.PublicKey)
+
+	fb, err := New(prvKey, &Api{URL: "http://localhost"})
+	testutil.Ok(t, err)
+	f := fb.(*Flashbot)
+
+	spec := TxSpec{
+		To:        &pubKey,
+		Gas:       21_000,
+		GasFeeCap: big.NewInt(2e9),
+	}
+	txsHex, txs, err := f.SignTxs(context.Background(), 5, 0, []TxSpec{spec, spec})
+	testutil.Ok(t, err)
+
+	decoded, err := DecodeBundle(txsHex)
+	testutil.Ok(t, err)
+	testutil.Equals(t, len(txs), len(decoded))
+	for i, d := range decoded {
+		testutil.Equals(t, txs[i].Hash(), d.Tx.Hash())
+		testutil.Equals(t, pubKey, d.Sender)
+	}
+
+	auth, err := f.SignSetCodeAuthorization(5, common.Address{}, 1)
+	testutil.Ok(t, err)
+	setCodeTx, setCodeHex, err := f.NewSignedSetCodeTX(5, 0, pubKey, nil, 100_000, big.NewInt(2e9), nil, nil, []SetCodeAuthorization{auth})
+	testutil.Ok(t, err)
+
+	_, err = DecodeTx(setCodeHex)
+	testutil.NotOk(t, err)
+
+	decodedSetCode, err := DecodeSetCodeTx(setCodeHex)
+	testutil.Ok(t, err)
+	expHash, err := setCodeTx.Hash()
+	testutil.Ok(t, err)
+	actHash, err := decodedSetCode.Hash()
+	testutil.Ok(t, err)
+	testutil.Equals(t, expHash, actHash)
+}
@@ -0,0 +1,143 @@
+// Copyright (c) The Cryptorium Authors.
+// Licensed under the MIT License.
+
+package flashbot
+
+import (
+	"context"
+	"net"
+	"sync"
+	"time"
+
+	"github.com/pkg/errors"
+)
+
+// DialFunc is the signature of net.Dialer.DialContext.
+type DialFunc func(ctx context.Context, network, addr string) (net.Conn, error)
+
+// PinnedResolver resolves the relay hostnames ahead of time so that the requests don't wait for DNS lookups.
+// The hosts can also be pinned to static IPs which are never refreshed.
+// When all cached IPs fail to connect or a host isn't resolved yet the dial falls back to the normal DNS lookup.
+type PinnedResolver struct {
+	lookup   func(ctx context.Context, host string) ([]string, error)
+	interval time.Duration
+
+	mtx    sync.RWMutex
+	hosts  map[string][]string
+	pinned map[string]bool
+}
+
+// NewPinnedResolver creates a resolver that refreshes the resolved hosts every interval, one minute by default.
+func NewPinnedResolver(interval time.Duration) *PinnedResolver {
+	if interval <= 0 {
+		interval = time.Minute
+	}
+	return &PinnedResolver{
+		lookup:   net.DefaultResolver.LookupHost,
+		interval: interval,
+		hosts:    make(map[string][]string),
+		pinned:   make(map[string]bool),
+	}
+}
+
+// Pin makes the dials to the host use only the IPs.
+func (self *PinnedResolver) Pin(host string, ips ...string) error {
+	for _, ip := range ips {
+		if net.ParseIP(ip) == nil {
+			return errors.Errorf("invalid IP:%v host:%v", ip, host)
+		}
+	}
+	self.mtx.Lock()
+	defer self.mtx.Unlock()
+	self.hosts[host] = append([]string(nil), ips...)
+	self.pinned[host] = true
+	return nil
+}
+
+// Resolve looks up the hosts and caches their IPs.
+// A failed lookup keeps the previously resolved IPs and the first error is returned after all hosts are tried.
+func (self *PinnedResolver) Resolve(ctx context.Context, hosts ...string) error {
+	var firstErr error
+	for _, host := range hosts {
+		self.mtx.RLock()
+		pinned := self.pinned[host]
+		self.mtx.RUnlock()
+		if pinned || net.ParseIP(host) != nil {
+			continue
+		}
+		ips, err := self.lookup(ctx, host)
+		if err == nil && len(ips) == 0 {
+			err = errors.New("no addresses")
+		}
+		if err != nil {
+			if firstErr == nil {
+				firstErr = errors.Wrapf(err, "resolve host:%v", host)
+			}
+			self.mtx.Lock()
+			if _, ok := self.hosts[host]; !ok {
+				// Tracked so that the refresh retries it.
+				self.hosts[host] = nil
+			}
+			self.mtx.Unlock()
+			continue
+		}
+		self.mtx.Lock()
+		self.hosts[host] = ips
+		self.mtx.Unlock()
+	}
+	return firstErr
+}
+
+// Run refreshes the resolved hosts until the context is done.
+// The refresh errors are ignored as the stale IPs are still used.
+func (self *PinnedResolver) Run(ctx context.Context) {
+	ticker := time.NewTicker(self.interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			_ = self.Resolve(ctx, self.resolvedHosts()...)
+		}
+	}
+}
+
+// IPs returns the cached IPs of the host.
+func (self *PinnedResolver) IPs(host string) []string {
+	self.mtx.RLock()
+	defer self.mtx.RUnlock()
+	return append([]string(nil), self.hosts[host]...)
+}
+
+func (self *PinnedResolver) resolvedHosts() []string {
+	self.mtx.RLock()
+	defer self.mtx.RUnlock()
+	var hosts []string
+	for h := range self.hosts {
+		if !self.pinned[h] {
+			hosts = append(hosts, h)
+		}
+	}
+	return hosts
+}
+
+// Dial wraps the dial function to connect to the cached IPs of the host.
+func (self *PinnedResolver) Dial(dial DialFunc) DialFunc {
+	return func(ctx context.Context, network, addr string) (net.Conn, error) {
+		host, port, err := net.SplitHostPort(addr)
+		if err != nil {
+			return dial(ctx, network, addr)
+		}
+		for _, ip := range self.IPs(host) {
+			conn, err := dial(ctx, network, net.JoinHostPort(ip, port))
+			if err == nil {
+				return conn, nil
+			}
+			if ctx.Err() != nil {
+				return nil, err
+			}
+		}
+		return dial(ctx, network, addr)
+	}
+}
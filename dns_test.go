@@ -0,0 +1,78 @@
+// Copyright (c) The Cryptorium Authors.
+// Licensed under the MIT License.
+
+package flashbot
+
+import (
+	"context"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"sync"
+	"testing"
+
+	"github.com/cryptoriums/packages/testutil"
+	"github.com/pkg/errors"
+)
+
+func TestPinnedResolver(t *testing.T) {
+	ctx := context.Background()
+	var (
+		mtx     sync.Mutex
+		lookups int
+		fail    bool
+	)
+	r := NewPinnedResolver(0)
+	r.lookup = func(ctx context.Context, host string) ([]string, error) {
+		mtx.Lock()
+		defer mtx.Unlock()
+		lookups++
+		if fail {
+			return nil, errors.New("dns down")
+		}
+		return []string{"10.0.0.1"}, nil
+	}
+
+	testutil.Ok(t, r.Resolve(ctx, "relay.example"))
+	testutil.Equals(t, []string{"10.0.0.1"}, r.IPs("relay.example"))
+
+	// A failed refresh keeps the resolved IPs.
+	fail = true
+	testutil.NotOk(t, r.Resolve(ctx, "relay.example"))
+	testutil.Equals(t, []string{"10.0.0.1"}, r.IPs("relay.example"))
+
+	// Pinned hosts are never looked up.
+	testutil.Ok(t, r.Pin("pinned.example", "10.0.0.2"))
+	testutil.Ok(t, r.Resolve(ctx, "pinned.example"))
+	testutil.Equals(t, 2, lookups)
+	testutil.Equals(t, []string{"relay.example"}, r.resolvedHosts())
+	testutil.NotOk(t, r.Pin("bad.example", "not-an-ip"))
+
+	// The dials go to the cached IP and fall back to the original address when it fails.
+	var dialed []string
+	dial := r.Dial(func(ctx context.Context, network, addr string) (net.Conn, error) {
+		dialed = append(dialed, addr)
+		return nil, errors.New("unreachable")
+	})
+	_, err := dial(ctx, "tcp", "relay.example:443")
+	testutil.NotOk(t, err)
+	testutil.Equals(t, []string{"10.0.0.1:443", "relay.example:443"}, dialed)
+}
+
+func TestPinnedTransport(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		_, _ = w.Write([]byte(`{"jsonrpc":"2.0","id":1,"result":{"bundleHash":"0xbundle"}}`))
+	}))
+	defer srv.Close()
+	u, err := url.Parse(srv.URL)
+	testutil.Ok(t, err)
+
+	// The relay host doesn't exist in DNS but is pinned to the test server.
+	r := NewPinnedResolver(0)
+	testutil.Ok(t, r.Pin("relay.invalid", u.Hostname()))
+	fb := newTestFlashbot(t, "http://relay.invalid:"+u.Port())
+	fb.api.Transport.Resolver = r
+	_, err = fb.req(context.Background(), "eth_sendBundle")
+	testutil.Ok(t, err)
+}
@@ -0,0 +1,96 @@
+// Copyright (c) The Cryptorium Authors.
+// Licensed under the MIT License.
+
+package flashbot
+
+import (
+	"context"
+	"time"
+
+	"github.com/pkg/errors"
+)
+
+// ErrDryRun is returned in dry run mode for the submissions that can't be simulated instead,
+// i.e. mev_sendBundle and the private TXs.
+var ErrDryRun = errors.New("dry run, not sent")
+
+// DryRunRecord is a bundle or another submission that would have been sent in dry run mode.
+type DryRunRecord struct {
+	Method string
+	// Params are set for the eth_sendBundle submissions and Request for all the others.
+	Params  ParamsSend
+	Request []interface{}
+	Time    time.Time
+	// Sim is nil when the relay doesn't support simulations.
+	Sim    *Response
+	Profit *SimProfit
+	Err    error
+}
+
+// SetDryRun enables or disables the dry run mode.
+// In dry run mode the bundles are simulated instead of sent and recorded as would-have-sent
+// so that strategies can be validated in production conditions without spending.
+// Every other submission is recorded and fails with ErrDryRun without reaching the relay.
+func (self *Flashbot) SetDryRun(enabled bool) {
+	self.mtx.Lock()
+	defer self.mtx.Unlock()
+	self.dryRun = enabled
+}
+
+func (self *Flashbot) DryRun() bool {
+	self.mtx.RLock()
+	defer self.mtx.RUnlock()
+	return self.dryRun
+}
+
+// DryRunRecords returns the bundles recorded in dry run mode.
+func (self *Flashbot) DryRunRecords() []DryRunRecord {
+	self.mtx.RLock()
+	defer self.mtx.RUnlock()
+	return append([]DryRunRecord(nil), self.dryRuns...)
+}
+
+// dryRunSend simulates the bundle and returns the simulation as the send response
+// with the bundle hash calculated locally.
+func (self *Flashbot) dryRunSend(ctx context.Context, method string, param ParamsSend, blockNum uint64) (*Response, error) {
+	rec := DryRunRecord{Method: method, Params: param, Time: time.Now()}
+	defer func() { self.recordDryRun(rec) }()
+
+	bundleHash, err := ComputeBundleHash(param.Txs)
+	if err != nil {
+		rec.Err = err
+		return nil, rec.Err
+	}
+	resp := &Response{}
+	if self.api.SupportsSimulation {
+		sim, err := self.CallBundle(ctx, param.Txs, 0)
+		if err != nil {
+			rec.Err = errors.Wrapf(err, "dry run simulation block:%v", blockNum)
+			return nil, rec.Err
+		}
+		rec.Sim = sim
+		if rec.Profit, err = ParseSimProfit(sim); err != nil {
+			rec.Err = err
+			return nil, err
+		}
+		resp.Result = sim.Result
+	}
+	resp.BundleHash = bundleHash.Hex()
+	return resp, nil
+}
+
+// dryRunGate stops the submissions in dry run mode.
+// All the submissions go through it so a new submit method can't bypass the dry run.
+func (self *Flashbot) dryRunGate(kind RequestKind, method string, params []interface{}) error {
+	if kind != RequestSubmit || !self.DryRun() {
+		return nil
+	}
+	self.recordDryRun(DryRunRecord{Method: method, Request: params, Time: time.Now(), Err: ErrDryRun})
+	return errors.Wrapf(ErrDryRun, "method:%v", method)
+}
+
+func (self *Flashbot) recordDryRun(rec DryRunRecord) {
+	self.mtx.Lock()
+	defer self.mtx.Unlock()
+	self.dryRuns = append(self.dryRuns, rec)
+}
@@ -0,0 +1,56 @@
+// Copyright (c) The Cryptorium Authors.
+// Licensed under the MIT License.
+
+package flashbot
+
+import (
+	"context"
+	"encoding/json"
+	"math/big"
+	"testing"
+
+	"github.com/cryptoriums/packages/testutil"
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/pkg/errors"
+)
+
+func TestDryRun(t *testing.T) {
+	ctx := context.Background()
+	relay := newRelayMock(t, func(method string, params json.RawMessage) (interface{}, *jsonError) {
+		return Result{Metadata: Metadata{CoinbaseDiff: "1500", GasFees: "500"}}, nil
+	})
+	fb := newTestFlashbot(t, relay.URL)
+	fb.SetDryRun(true)
+
+	resp, err := fb.SendBundle(ctx, []string{"0x01"}, 10)
+	testutil.Ok(t, err)
+	testutil.Assert(t, resp.BundleHash != "", "missing bundle hash")
+	testutil.Equals(t, []string{"eth_callBundle"}, relay.Methods())
+
+	recs := fb.DryRunRecords()
+	testutil.Equals(t, 1, len(recs))
+	testutil.Equals(t, "0xa", recs[0].Params.BlockNum)
+	testutil.Equals(t, big.NewInt(1500), recs[0].Profit.CoinbaseDiff)
+
+	// The other submissions don't reach the relay.
+	to := fb.TxSigner().Address()
+	txHex, stuck, err := fb.SignTx(TxSpec{To: &to, Gas: 21_000, GasFeeCap: big.NewInt(10), GasTipCap: big.NewInt(1)}.TxData(1, 0))
+	testutil.Ok(t, err)
+	_, err = fb.SendPrivateTransaction(ctx, txHex, 20, false)
+	testutil.Assert(t, errors.Is(err, ErrDryRun), "unexpected error:%v", err)
+	_, _, err = fb.CancelStuckNonce(ctx, 1, headerMock{&types.Header{BaseFee: big.NewInt(1)}}, stuck, nil, 20)
+	testutil.Assert(t, errors.Is(err, ErrDryRun), "unexpected error:%v", err)
+	_, err = fb.MevSendBundle(ctx, MevSendBundleParams{Inclusion: Inclusion{Block: "0xa"}, Body: []MevBundleItem{{Tx: txHex}}})
+	testutil.Assert(t, errors.Is(err, ErrDryRun), "unexpected error:%v", err)
+	_, err = fb.Forward(ctx, "eth_sendBundle", json.RawMessage(`[{"txs":["0x01"],"blockNumber":"0xa"}]`))
+	testutil.Assert(t, errors.Is(err, ErrDryRun), "unexpected error:%v", err)
+	testutil.Equals(t, []string{"eth_callBundle"}, relay.Methods())
+	recs = fb.DryRunRecords()
+	testutil.Equals(t, 5, len(recs))
+	testutil.Equals(t, "mev_sendBundle", recs[3].Method)
+
+	fb.SetDryRun(false)
+	_, err = fb.SendBundle(ctx, []string{"0x01"}, 10)
+	testutil.Ok(t, err)
+	testutil.Equals(t, []string{"eth_callBundle", "eth_sendBundle"}, relay.Methods())
+}
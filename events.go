@@ -0,0 +1,123 @@
+// Copyright (c) The Cryptorium Authors.
+// Licensed under the MIT License.
+
+package flashbot
+
+import (
+	"time"
+)
+
+type BundleEventType string
+
+const (
+	EventSimulated  BundleEventType = "simulated"
+	EventSubmitted  BundleEventType = "submitted"
+	EventConsidered BundleEventType = "considered"
+	EventIncluded   BundleEventType = "included"
+	// EventDropped is also emitted for expired bundles.
+	EventDropped BundleEventType = "dropped"
+	EventError   BundleEventType = "error"
+)
+
+type BundleEvent struct {
+	Type BundleEventType
+	// Bundle is a snapshot taken right after the event.
+	Bundle   *ManagedBundle
+	Block    uint64
+	Response *Response
+	// Builder is set for the considered events.
+	Builder string
+	Err     error
+	Time    time.Time
+}
+
+// BundleHooks are called synchronously from the goroutine that caused the event
+// so they should return quickly. They can subscribe and replace the hooks.
+type BundleHooks struct {
+	OnSimulated  func(BundleEvent)
+	OnSubmitted  func(BundleEvent)
+	OnConsidered func(BundleEvent)
+	OnIncluded   func(BundleEvent)
+	OnDropped    func(BundleEvent)
+	OnError      func(BundleEvent)
+}
+
+func (self BundleHooks) hook(t BundleEventType) func(BundleEvent) {
+	switch t {
+	case EventSimulated:
+		return self.OnSimulated
+	case EventSubmitted:
+		return self.OnSubmitted
+	case EventConsidered:
+		return self.OnConsidered
+	case EventIncluded:
+		return self.OnIncluded
+	case EventDropped:
+		return self.OnDropped
+	case EventError:
+		return self.OnError
+	}
+	return nil
+}
+
+// SetHooks replaces the lifecycle callbacks of the manager.
+func (self *BundleManager) SetHooks(hooks BundleHooks) {
+	self.evMtx.Lock()
+	defer self.evMtx.Unlock()
+	self.hooks = hooks
+}
+
+// Subscribe returns a channel that receives all lifecycle events and a function to unsubscribe.
+// Events are dropped when the channel buffer is full so that a slow reader doesn't block the manager.
+func (self *BundleManager) Subscribe(buffer int) (<-chan BundleEvent, func()) {
+	ch := make(chan BundleEvent, buffer)
+	self.evMtx.Lock()
+	self.subs = append(self.subs, ch)
+	self.evMtx.Unlock()
+
+	return ch, func() {
+		self.evMtx.Lock()
+		defer self.evMtx.Unlock()
+		for i, sub := range self.subs {
+			if sub == ch {
+				self.subs = append(self.subs[:i], self.subs[i+1:]...)
+				close(ch)
+				return
+			}
+		}
+	}
+}
+
+// MarkConsidered records that a builder considered the bundle, i.e. as reported by the bundle stats.
+func (self *BundleManager) MarkConsidered(id string, builder string) error {
+	if _, err := self.Get(id); err != nil {
+		return err
+	}
+	self.emit(BundleEvent{Type: EventConsidered, Builder: builder}, id)
+	return nil
+}
+
+func (self *BundleManager) emit(e BundleEvent, id string) {
+	e.Time = time.Now()
+	if b, err := self.Get(id); err == nil {
+		e.Bundle = b
+	}
+
+	// The hook is called without the lock so that it can set the hooks or subscribe.
+	self.evMtx.RLock()
+	f := self.hooks.hook(e.Type)
+	self.evMtx.RUnlock()
+	if f != nil {
+		f(e)
+	}
+
+	// The sends hold the lock so that unsubscribe doesn't close a channel while sending on it.
+	self.evMtx.RLock()
+	defer self.evMtx.RUnlock()
+	for _, sub := range self.subs {
+		select {
+		case sub <- e:
+		default:
+		}
+	}
+}
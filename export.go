@@ -0,0 +1,92 @@
+// Copyright (c) The Cryptorium Authors.
+// Licensed under the MIT License.
+
+package flashbot
+
+import (
+	"context"
+	"encoding/csv"
+	"encoding/json"
+	"io"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/pkg/errors"
+)
+
+type ExportFormat string
+
+const (
+	ExportJSON ExportFormat = "json"
+	ExportCSV  ExportFormat = "csv"
+)
+
+var exportCSVHeader = []string{
+	"id", "relay", "state", "target_block", "included_block", "bundle_hash",
+	"tx_hashes", "error", "created_at", "updated_at",
+}
+
+// ExportBundles writes the stored bundles matching the filter to the writer.
+// The JSON format is an array of the full bundle records
+// and the CSV format has a summary row per bundle.
+func ExportBundles(ctx context.Context, w io.Writer, store BundleStore, filter BundleFilter, format ExportFormat) error {
+	bundles, err := store.List(ctx, filter)
+	if err != nil {
+		return errors.Wrap(err, "list stored bundles")
+	}
+
+	switch format {
+	case ExportJSON:
+		if bundles == nil {
+			bundles = []*ManagedBundle{}
+		}
+		enc := json.NewEncoder(w)
+		enc.SetIndent("", "  ")
+		return errors.Wrap(enc.Encode(bundles), "encode bundles")
+	case ExportCSV:
+		cw := csv.NewWriter(w)
+		if err := cw.Write(exportCSVHeader); err != nil {
+			return errors.Wrap(err, "write csv header")
+		}
+		for _, b := range bundles {
+			if err := cw.Write(exportCSVRow(b)); err != nil {
+				return errors.Wrapf(err, "write csv row id:%v", b.ID)
+			}
+		}
+		cw.Flush()
+		return errors.Wrap(cw.Error(), "flush csv")
+	default:
+		return errors.Errorf("unsupported export format:%v", format)
+	}
+}
+
+func exportCSVRow(b *ManagedBundle) []string {
+	hashes := make([]string, 0, len(b.TxHashes))
+	for _, h := range b.TxHashes {
+		hashes = append(hashes, h.Hex())
+	}
+	var lastErr string
+	for i := len(b.History) - 1; i >= 0; i-- {
+		if b.History[i].Err != "" {
+			lastErr = b.History[i].Err
+			break
+		}
+	}
+	var included string
+	if b.IncludedBlock != 0 {
+		included = strconv.FormatUint(b.IncludedBlock, 10)
+	}
+	return []string{
+		b.ID,
+		b.Relay,
+		string(b.State),
+		strconv.FormatUint(b.TargetBlock(), 10),
+		included,
+		b.BundleHash,
+		strings.Join(hashes, ";"),
+		lastErr,
+		b.CreatedAt.UTC().Format(time.RFC3339),
+		b.UpdatedAt.UTC().Format(time.RFC3339),
+	}
+}
@@ -0,0 +1,58 @@
+// Copyright (c) The Cryptorium Authors.
+// Licensed under the MIT License.
+
+package flashbot
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/cryptoriums/packages/testutil"
+	"github.com/ethereum/go-ethereum/common"
+)
+
+func TestExportBundles(t *testing.T) {
+	ctx := context.Background()
+	store := NewMemoryStore()
+	created := time.Date(2022, 1, 1, 0, 0, 0, 0, time.UTC)
+	testutil.Ok(t, store.Save(ctx, &ManagedBundle{
+		ID:            "a",
+		Relay:         "https://relay.flashbots.net",
+		Params:        ParamsSend{BlockNum: "0xa"},
+		TxHashes:      []common.Hash{{1}, {2}},
+		State:         BundleIncluded,
+		IncludedBlock: 10,
+		CreatedAt:     created,
+		UpdatedAt:     created,
+	}))
+	testutil.Ok(t, store.Save(ctx, &ManagedBundle{
+		ID:        "b",
+		Relay:     "http://other",
+		Params:    ParamsSend{BlockNum: "0xb"},
+		State:     BundleDropped,
+		History:   []BundleStateChange{{State: BundleDropped, Err: "reorged"}},
+		CreatedAt: created.Add(time.Hour),
+		UpdatedAt: created.Add(time.Hour),
+	}))
+
+	buf := &bytes.Buffer{}
+	testutil.Ok(t, ExportBundles(ctx, buf, store, BundleFilter{}, ExportCSV))
+	lines := strings.Split(strings.TrimSpace(buf.String()), "\n")
+	testutil.Equals(t, 3, len(lines))
+	testutil.Equals(t, "b,http://other,dropped,11,,,,reorged,2022-01-01T01:00:00Z,2022-01-01T01:00:00Z", lines[2])
+
+	buf.Reset()
+	filter := BundleFilter{Relay: "https://relay.flashbots.net", To: created.Add(time.Minute)}
+	testutil.Ok(t, ExportBundles(ctx, buf, store, filter, ExportJSON))
+	var exported []*ManagedBundle
+	testutil.Ok(t, json.Unmarshal(buf.Bytes(), &exported))
+	testutil.Equals(t, 1, len(exported))
+	testutil.Equals(t, "a", exported[0].ID)
+	testutil.Equals(t, 2, len(exported[0].TxHashes))
+
+	testutil.NotOk(t, ExportBundles(ctx, buf, store, BundleFilter{}, "xml"))
+}
@@ -0,0 +1,47 @@
+// Copyright (c) The Cryptorium Authors.
+// Licensed under the MIT License.
+
+package flashbot
+
+import (
+	"context"
+	"sync"
+
+	"github.com/pkg/errors"
+)
+
+// BlockSubmission is the relay reply for one of the target blocks.
+type BlockSubmission struct {
+	BlockNum uint64
+	Response *Response
+	Err      error
+}
+
+// SendBundleForBlocks sends the same bundle for each of the nBlocks blocks starting at fromBlock.
+// The requests run concurrently and the results are in the order of the target blocks.
+// An error is returned only when the relay rejected the bundle for all blocks.
+func (self *Flashbot) SendBundleForBlocks(ctx context.Context, txsHex []string, fromBlock uint64, nBlocks uint64) ([]BlockSubmission, error) {
+	if nBlocks == 0 {
+		return nil, errors.New("the number of blocks should be positive")
+	}
+
+	res := make([]BlockSubmission, nBlocks)
+	var wg sync.WaitGroup
+	for i := uint64(0); i < nBlocks; i++ {
+		wg.Add(1)
+		go func(i uint64) {
+			defer wg.Done()
+			blockNum := fromBlock + i
+			resp, err := self.SendBundle(ctx, txsHex, blockNum)
+			res[i] = BlockSubmission{BlockNum: blockNum, Response: resp, Err: err}
+		}(i)
+	}
+	wg.Wait()
+
+	for _, r := range res {
+		if r.Err == nil {
+			return res, nil
+		}
+	}
+	return res, errors.Wrapf(res[0].Err, "bundle rejected for all blocks from:%v count:%v", fromBlock, nBlocks)
+}
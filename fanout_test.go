@@ -0,0 +1,33 @@
+// Copyright (c) The Cryptorium Authors.
+// Licensed under the MIT License.
+
+package flashbot
+
+import (
+	"context"
+	"encoding/json"
+	"testing"
+
+	"github.com/cryptoriums/packages/testutil"
+)
+
+func TestSendBundleForBlocks(t *testing.T) {
+	ctx := context.Background()
+	relay := newRelayMock(t, func(method string, params json.RawMessage) (interface{}, *jsonError) {
+		var p []ParamsSend
+		testutil.Ok(t, json.Unmarshal(params, &p))
+		if p[0].BlockNum == "0xb" {
+			return nil, &jsonError{Code: -32000, Message: "rejected"}
+		}
+		return Result{BundleHash: "0xbundle"}, nil
+	})
+	fb := newTestFlashbot(t, relay.URL)
+
+	res, err := fb.SendBundleForBlocks(ctx, []string{"0x01"}, 10, 3)
+	testutil.Ok(t, err)
+	testutil.Equals(t, 3, len(res))
+	testutil.Ok(t, res[0].Err)
+	testutil.NotOk(t, res[1].Err)
+	testutil.Equals(t, uint64(11), res[1].BlockNum)
+	testutil.Ok(t, res[2].Err)
+}
@@ -0,0 +1,148 @@
+// Copyright (c) The Cryptorium Authors.
+// Licensed under the MIT License.
+
+package flashbot
+
+import (
+	"context"
+	"math/big"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/params"
+	"github.com/pkg/errors"
+)
+
+// HeaderBackend is implemented by ethclient.Client and Node.
+type HeaderBackend interface {
+	HeaderByNumber(ctx context.Context, number *big.Int) (*types.Header, error)
+}
+
+// NextBaseFee returns the base fee of the block following the given header
+// according to the EIP-1559 rules.
+func NextBaseFee(parent *types.Header) *big.Int {
+	if parent.BaseFee == nil {
+		return new(big.Int).SetUint64(params.InitialBaseFee)
+	}
+	gasTarget := parent.GasLimit / params.ElasticityMultiplier
+	if gasTarget == 0 || parent.GasUsed == gasTarget {
+		return new(big.Int).Set(parent.BaseFee)
+	}
+
+	if parent.GasUsed > gasTarget {
+		delta := new(big.Int).SetUint64(parent.GasUsed - gasTarget)
+		delta.Mul(delta, parent.BaseFee)
+		delta.Div(delta, new(big.Int).SetUint64(gasTarget))
+		delta.Div(delta, big.NewInt(params.BaseFeeChangeDenominator))
+		if delta.Sign() == 0 {
+			delta.SetInt64(1)
+		}
+		return delta.Add(delta, parent.BaseFee)
+	}
+
+	delta := new(big.Int).SetUint64(gasTarget - parent.GasUsed)
+	delta.Mul(delta, parent.BaseFee)
+	delta.Div(delta, new(big.Int).SetUint64(gasTarget))
+	delta.Div(delta, big.NewInt(params.BaseFeeChangeDenominator))
+	next := delta.Sub(parent.BaseFee, delta)
+	if next.Sign() < 0 {
+		next.SetInt64(0)
+	}
+	return next
+}
+
+// BaseFeeRange is the projected base fee of a future block.
+type BaseFeeRange struct {
+	BlockNum uint64
+	// Min assumes that all blocks in between are empty and Max that they are full.
+	Min *big.Int
+	Max *big.Int
+}
+
+// PredictBaseFee projects the base fee of the nAhead blocks following the header.
+// The first one is exact as it depends only on the header
+// and the range of the next ones widens with each block as their gas used is unknown.
+func PredictBaseFee(header *types.Header, nAhead int) ([]BaseFeeRange, error) {
+	if nAhead < 0 {
+		return nil, errors.Errorf("blocks ahead can't be negative:%v", nAhead)
+	}
+	res := make([]BaseFeeRange, 0, nAhead)
+	next := NextBaseFee(header)
+	min, max := next, next
+	for i := 0; i < nAhead; i++ {
+		res = append(res, BaseFeeRange{
+			BlockNum: header.Number.Uint64() + uint64(i) + 1,
+			Min:      new(big.Int).Set(min),
+			Max:      new(big.Int).Set(max),
+		})
+		min = NextBaseFee(&types.Header{BaseFee: min, GasLimit: header.GasLimit, GasUsed: 0})
+		max = NextBaseFee(&types.Header{BaseFee: max, GasLimit: header.GasLimit, GasUsed: header.GasLimit})
+	}
+	return res, nil
+}
+
+// WithAutoFeesAhead fills the fee cap and the tip of the specs that don't have them set
+// so that the TXs stay valid up to nAhead blocks after the head even if all blocks in between are full.
+func WithAutoFeesAhead(backend HeaderBackend, nAhead int, tip *big.Int) TxOption {
+	return func(ctx context.Context, _ common.Address, spec *TxSpec) error {
+		if spec.GasFeeCap != nil && spec.GasTipCap != nil {
+			return nil
+		}
+		if nAhead < 1 {
+			return errors.Errorf("blocks ahead should be positive:%v", nAhead)
+		}
+		header, err := backend.HeaderByNumber(ctx, nil)
+		if err != nil {
+			return errors.Wrap(err, "getting chain header")
+		}
+		if spec.GasTipCap == nil {
+			spec.GasTipCap = new(big.Int)
+			if tip != nil {
+				spec.GasTipCap.Set(tip)
+			}
+		}
+		if spec.GasFeeCap == nil {
+			fees, err := PredictBaseFee(header, nAhead)
+			if err != nil {
+				return err
+			}
+			spec.GasFeeCap = new(big.Int).Add(fees[len(fees)-1].Max, spec.GasTipCap)
+		}
+		return nil
+	}
+}
+
+// WithAutoFees fills the fee cap and the tip of the specs that don't have them set.
+// The fee cap is the projected next block base fee times the multiplier plus the tip.
+// A multiplier of 2 keeps the TX valid even after 5 consecutive full blocks.
+func WithAutoFees(backend HeaderBackend, multiplier float64, tip *big.Int) TxOption {
+	return func(ctx context.Context, _ common.Address, spec *TxSpec) error {
+		if spec.GasFeeCap != nil && spec.GasTipCap != nil {
+			return nil
+		}
+		if multiplier < 1 {
+			return errors.Errorf("base fee multiplier can't be lower than 1:%v", multiplier)
+		}
+		header, err := backend.HeaderByNumber(ctx, nil)
+		if err != nil {
+			return errors.Wrap(err, "getting chain header")
+		}
+
+		if spec.GasTipCap == nil {
+			spec.GasTipCap = new(big.Int)
+			if tip != nil {
+				spec.GasTipCap.Set(tip)
+			}
+		}
+		if spec.GasFeeCap == nil {
+			spec.GasFeeCap = mulFloat(NextBaseFee(header), multiplier)
+			spec.GasFeeCap.Add(spec.GasFeeCap, spec.GasTipCap)
+		}
+		return nil
+	}
+}
+
+func mulFloat(v *big.Int, m float64) *big.Int {
+	res, _ := new(big.Float).Mul(new(big.Float).SetInt(v), big.NewFloat(m)).Int(nil)
+	return res
+}
@@ -0,0 +1,81 @@
+// Copyright (c) The Cryptorium Authors.
+// Licensed under the MIT License.
+
+package flashbot
+
+import (
+	"context"
+	"math/big"
+	"testing"
+
+	"github.com/cryptoriums/packages/testutil"
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/core/types"
+)
+
+func TestNextBaseFee(t *testing.T) {
+	for _, tc := range []struct {
+		gasUsed uint64
+		exp     int64
+	}{
+		{gasUsed: 15_000_000, exp: 1_000_000_000},
+		{gasUsed: 30_000_000, exp: 1_125_000_000},
+		{gasUsed: 0, exp: 875_000_000},
+		{gasUsed: 7_500_000, exp: 937_500_000},
+	} {
+		header := &types.Header{
+			GasLimit: 30_000_000,
+			GasUsed:  tc.gasUsed,
+			BaseFee:  big.NewInt(1_000_000_000),
+		}
+		testutil.Equals(t, big.NewInt(tc.exp), NextBaseFee(header))
+	}
+}
+
+func TestPredictBaseFee(t *testing.T) {
+	header := &types.Header{
+		Number:   big.NewInt(100),
+		GasLimit: 30_000_000,
+		GasUsed:  30_000_000,
+		BaseFee:  big.NewInt(1_000_000_000),
+	}
+	fees, err := PredictBaseFee(header, 3)
+	testutil.Ok(t, err)
+	testutil.Equals(t, 3, len(fees))
+
+	testutil.Equals(t, uint64(101), fees[0].BlockNum)
+	testutil.Equals(t, big.NewInt(1_125_000_000), fees[0].Min)
+	testutil.Equals(t, big.NewInt(1_125_000_000), fees[0].Max)
+
+	testutil.Equals(t, uint64(102), fees[1].BlockNum)
+	testutil.Equals(t, big.NewInt(984_375_000), fees[1].Min)
+	testutil.Equals(t, big.NewInt(1_265_625_000), fees[1].Max)
+
+	testutil.Equals(t, uint64(103), fees[2].BlockNum)
+	testutil.Equals(t, big.NewInt(1_423_828_125), fees[2].Max)
+
+	fees, err = PredictBaseFee(header, 0)
+	testutil.Ok(t, err)
+	testutil.Equals(t, 0, len(fees))
+	_, err = PredictBaseFee(header, -1)
+	testutil.NotOk(t, err)
+}
+
+func TestWithAutoFeesAhead(t *testing.T) {
+	header := &types.Header{Number: big.NewInt(100), GasLimit: 30_000_000, GasUsed: 30_000_000, BaseFee: big.NewInt(1_000_000_000)}
+	opt := WithAutoFeesAhead(headerMock{header}, 2, big.NewInt(5))
+	spec := &TxSpec{}
+	testutil.Ok(t, opt(context.Background(), common.Address{}, spec))
+	testutil.Equals(t, big.NewInt(5), spec.GasTipCap)
+	testutil.Equals(t, big.NewInt(1_265_625_005), spec.GasFeeCap)
+
+	testutil.NotOk(t, WithAutoFeesAhead(headerMock{header}, 0, nil)(context.Background(), common.Address{}, &TxSpec{}))
+}
+
+type headerMock struct {
+	header *types.Header
+}
+
+func (self headerMock) HeaderByNumber(ctx context.Context, number *big.Int) (*types.Header, error) {
+	return self.header, nil
+}
@@ -8,17 +8,20 @@ import (
 	"bytes"
 	"context"
 	"crypto/ecdsa"
-	"crypto/tls"
+	"encoding/hex"
 	"encoding/json"
 	"fmt"
 	"io"
+	"math/big"
 	"net/http"
 	"net/http/httputil"
+	"strings"
+	"sync"
 	"time"
 
-	"github.com/ethereum/go-ethereum/accounts"
 	"github.com/ethereum/go-ethereum/common"
 	"github.com/ethereum/go-ethereum/common/hexutil"
+	"github.com/ethereum/go-ethereum/core/types"
 	"github.com/ethereum/go-ethereum/crypto"
 	"github.com/pkg/errors"
 )
@@ -27,10 +30,13 @@ type Flashboter interface {
 	SendPrivateTransaction(ctx context.Context, txHex string, blockNum uint64, fast bool) (*SendPrivateTransactionResponse, error)
 	CancelPrivateTransaction(ctx context.Context, txHash common.Hash) (*CancelPrivateTransactionResponse, error)
 	SendBundle(ctx context.Context, txsHex []string, blockNum uint64) (*Response, error)
+	SendBundleParams(ctx context.Context, param ParamsSend) (*Response, error)
+	CancelBundle(ctx context.Context, replacementUUID string) (*CancelBundleResponse, error)
 	CallBundle(ctx context.Context, txsHex []string, blockNumState uint64) (*Response, error)
 	GetBundleStats(ctx context.Context, bundleHash string, blockNum uint64) (*ResultBundleStats, error)
 	GetUserStats(ctx context.Context, blockNum uint64) (*ResultUserStats, error)
 	SimulateBundle(ctx context.Context, txsHex []string, blockNum uint64) (*SimBundleResult, error)
+	SignTx(txdata types.TxData) (string, *types.Transaction, error)
 	Api() *Api
 }
 
@@ -54,6 +60,10 @@ type ParamsCall struct {
 	Txs           []string `json:"txs,omitempty"`
 	BlockNum      string   `json:"blockNumber,omitempty"`
 	StateBlockNum string   `json:"stateBlockNumber,omitempty"`
+	Timestamp     uint64   `json:"timestamp,omitempty"`
+	Coinbase      string   `json:"coinbase,omitempty"`
+	// StateOverrides are supported only by some of the builders.
+	StateOverrides StateOverride `json:"stateOverrides,omitempty"`
 }
 
 type ParamsStats struct {
@@ -62,15 +72,20 @@ type ParamsStats struct {
 }
 
 type ParamsSend struct {
-	BlockNum string   `json:"blockNumber,omitempty"`
-	Txs      []string `json:"txs,omitempty"`
+	BlockNum          string   `json:"blockNumber,omitempty"`
+	Txs               []string `json:"txs,omitempty"`
+	MinTimestamp      uint64   `json:"minTimestamp,omitempty"`
+	MaxTimestamp      uint64   `json:"maxTimestamp,omitempty"`
+	RevertingTxHashes []string `json:"revertingTxHashes,omitempty"`
+	ReplacementUUID   string   `json:"replacementUuid,omitempty"`
 }
 
 type ParamsPrivateTransaction struct {
 	Tx             string `json:"tx,omitempty"`
 	МaxBlockNumber string `json:"maxBlockNumber,omitempty"`
 	Preferences    struct {
-		Fast bool `json:"fast,omitempty"`
+		Fast     bool               `json:"fast,omitempty"`
+		Validity *PrivateTxValidity `json:"validity,omitempty"`
 	} `json:"preferences,omitempty"`
 }
 
@@ -136,6 +151,16 @@ type BundleStats struct {
 	SimulatedAt    time.Time
 	SubmittedAt    time.Time
 	SentToMinersAt time.Time
+	// ReceivedAt is reported by flashbots_getBundleStatsV2 instead of the SubmittedAt.
+	ReceivedAt time.Time
+	// Reported only by flashbots_getBundleStatsV2.
+	ConsideredByBuildersAt []BuilderStats
+	SealedByBuildersAt     []BuilderStats
+}
+
+type BuilderStats struct {
+	Pubkey    string
+	Timestamp time.Time
 }
 
 type TxResult struct {
@@ -156,11 +181,48 @@ type Error struct {
 type Response struct {
 	Error  `json:"error,omitempty"`
 	Result `json:"result,omitempty"`
+	// Timing is the time spent in each phase of the relay request.
+	Timing RequestTiming `json:"-"`
 }
 
 type Flashbot struct {
-	prvKey *ecdsa.PrivateKey
-	pubKey *common.Address
+	mtx        sync.RWMutex
+	clientOnce sync.Once
+	client     *http.Client
+	connInfo   ConnInfo
+	handshakes HandshakeStats
+	lastTiming RequestTiming
+	// latency is created on first use like the client.
+	latencyOnce sync.Once
+	latency     *RelayLatency
+	// drain tracks the in-flight requests for Close.
+	drain drain
+	// signerHex caches the checksummed address of the auth signer for the signature header.
+	signerHex signerHex
+	prvKey    *ecdsa.PrivateKey
+	signer    Signer
+	// Optional signer for the TXs used when the auth key should be different
+	// or can't sign TXs or the other way around.
+	txSigner   Signer
+	prevSigner Signer
+
+	// In dry run mode the bundles are only simulated and recorded.
+	dryRun  bool
+	dryRuns []DryRunRecord
+
+	spamGuard   *SpamGuard
+	budget      *BudgetGuard
+	addrPolicy  *AddressPolicy
+	targetCheck *targetCheck
+	heads       BlockNumberBackend
+	simCache    *SimCache
+
+	// chainID is set by VerifyChainID and then the TX signer refuses any other chain ID.
+	chainID *big.Int
+	// chainSigner is the TX signing scheme chosen with WithChainSigner,
+	// otherwise the latest one for each chain ID is created once and cached in chainSigners.
+	chainSigner  types.Signer
+	chainSigners map[string]types.Signer
 
 	// The api spec for the relay.
 	// Different relays use different api method names and this allows making it configurable.
@@ -173,6 +235,26 @@ type Api struct {
 	MethodCall         string
 	MethodSend         string
 	CustomHeaders      map[string]string
+	// Timeout is the relay request timeout, no timeout when 0.
+	Timeout time.Duration
+	// Retry is the policy for the failed requests, no retries by default.
+	Retry RetryPolicy
+	// Limits are checked before sending a bundle so that it isn't bounced by the relay.
+	Limits RelayLimits
+	// RateLimiter is applied to every request including the retries, no limit when nil.
+	RateLimiter *RateLimiter
+	// Transport tunes the connection pool of the client created for the relay.
+	Transport TransportConfig
+	// Client overrides the client created for the relay, i.e. to share one pool between relays.
+	Client *http.Client
+	// MaxResponseSize is the size limit of the replies in bytes, DefaultMaxResponseSize when 0.
+	MaxResponseSize int64
+	// Codec encodes the requests and decodes the bundle replies, encoding/json when nil.
+	Codec Codec
+	// OnTiming is called after every request with its phase times, i.e. to log or export the slow phases.
+	OnTiming func(RequestTiming)
+	// Metrics exports the relay request measurements with the relay URL as the relay label, nothing is exported when nil.
+	Metrics Metrics
 }
 
 func DefaultApi(netID int64) (*Api, error) {
@@ -214,7 +296,7 @@ func NewMulti(netID int64, prvKey *ecdsa.PrivateKey, apis ...*Api) ([]Flashboter
 	return flashbots, nil
 }
 
-func New(prvKey *ecdsa.PrivateKey, api *Api) (Flashboter, error) {
+func New(prvKey *ecdsa.PrivateKey, api *Api, opts ...Option) (Flashboter, error) {
 	if api == nil {
 		return nil, errors.New("api can't be empty")
 	}
@@ -222,6 +304,9 @@ func New(prvKey *ecdsa.PrivateKey, api *Api) (Flashboter, error) {
 	fb := &Flashbot{
 		api: api,
 	}
+	for _, opt := range opts {
+		opt(fb)
+	}
 
 	if prvKey != nil {
 		return fb, fb.SetKey(prvKey)
@@ -234,21 +319,118 @@ func (self *Flashbot) Api() *Api {
 }
 
 func (self *Flashbot) PrvKey() *ecdsa.PrivateKey {
+	self.mtx.RLock()
+	defer self.mtx.RUnlock()
 	return self.prvKey
 }
 
 func (self *Flashbot) SetKey(prvKey *ecdsa.PrivateKey) error {
+	signer, err := NewKeySigner(prvKey)
+	if err != nil {
+		return err
+	}
+	self.mtx.Lock()
+	defer self.mtx.Unlock()
 	self.prvKey = prvKey
-	pubKeyE, ok := prvKey.Public().(*ecdsa.PublicKey)
-	if !ok {
-		return errors.New("casting private key to ECDSA")
+	self.signer = signer
+
+	return nil
+}
+
+// NewWithSigner creates an instance that signs with an external signer instead of a private key.
+func NewWithSigner(signer Signer, api *Api, opts ...Option) (Flashboter, error) {
+	fb, err := New(nil, api, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return fb, fb.(*Flashbot).SetSigner(signer)
+}
+
+func (self *Flashbot) Signer() Signer {
+	self.mtx.RLock()
+	defer self.mtx.RUnlock()
+	return self.signer
+}
+
+// TxSigner returns the signer used for the TXs.
+func (self *Flashbot) TxSigner() Signer {
+	self.mtx.RLock()
+	defer self.mtx.RUnlock()
+	signer := self.signer
+	if self.txSigner != nil {
+		signer = self.txSigner
+	}
+	if self.chainID != nil && signer != nil {
+		return chainIDSigner{Signer: signer, chainID: self.chainID}
+	}
+	return signer
+}
+
+// SetTxSigner sets a separate signer for the TXs so that the auth identity
+// and the account that signs the bundle TXs can live in different places.
+func (self *Flashbot) SetTxSigner(signer Signer) {
+	self.mtx.Lock()
+	defer self.mtx.Unlock()
+	self.txSigner = signer
+}
+
+// SetSigner replaces the signer. The private key is cleared
+// so the methods that require raw key access return an error.
+func (self *Flashbot) SetSigner(signer Signer) error {
+	if signer == nil {
+		return errors.New("signer can't be empty")
+	}
+	self.mtx.Lock()
+	defer self.mtx.Unlock()
+	self.prvKey = nil
+	self.signer = signer
+
+	return nil
+}
+
+// RotateAuthKey atomically replaces the key used for the X-Flashbots-Signature header.
+// See RotateAuthSigner.
+func (self *Flashbot) RotateAuthKey(prvKey *ecdsa.PrivateKey, keepPrevious bool) error {
+	signer, err := NewKeySigner(prvKey)
+	if err != nil {
+		return err
 	}
-	pubKey := crypto.PubkeyToAddress(*pubKeyE)
-	self.pubKey = &pubKey
+	return self.RotateAuthSigner(signer, keepPrevious)
+}
+
+// RotateAuthSigner atomically replaces the signer used for the X-Flashbots-Signature header
+// so that a de-prioritized reputation identity can be swapped without recreating the client.
+// Requests already in flight keep the signature they were sent with.
+// The TX signing is not affected: when no separate TX signer is set
+// the replaced signer becomes the TX signer.
+// When keepPrevious is true the replaced signer is also available through PrevAuthSigner
+// for verifying the requests signed before the rotation.
+func (self *Flashbot) RotateAuthSigner(signer Signer, keepPrevious bool) error {
+	if signer == nil {
+		return errors.New("signer can't be empty")
+	}
+	self.mtx.Lock()
+	defer self.mtx.Unlock()
+
+	if self.txSigner == nil {
+		self.txSigner = self.signer
+	}
+	self.prevSigner = nil
+	if keepPrevious {
+		self.prevSigner = self.signer
+	}
+	self.signer = signer
 
 	return nil
 }
 
+// PrevAuthSigner returns the auth signer replaced by the last rotation when it was kept.
+func (self *Flashbot) PrevAuthSigner() Signer {
+	self.mtx.RLock()
+	defer self.mtx.RUnlock()
+	return self.prevSigner
+}
+
 type SendPrivateTransactionResponse struct {
 	Error  `json:"error,omitempty"`
 	Result string `json:"result,omitempty"`
@@ -264,7 +446,14 @@ func (self *Flashbot) SendPrivateTransaction(ctx context.Context, txHex string,
 		Tx:             txHex,
 		МaxBlockNumber: hexutil.EncodeUint64(blockNum),
 	}
-	resp, err := self.req(ctx, "eth_sendPrivateTransaction", param)
+	return self.sendPrivateTransaction(ctx, param, blockNum)
+}
+
+func (self *Flashbot) sendPrivateTransaction(ctx context.Context, param ParamsPrivateTransaction, blockNum uint64) (*SendPrivateTransactionResponse, error) {
+	if err := self.checkAddressPolicy(param.Tx); err != nil {
+		return nil, err
+	}
+	resp, err := self.reqKind(ctx, RequestSubmit, "eth_sendPrivateTransaction", param)
 	if err != nil {
 		return nil, errors.Wrap(err, "flashbot private TX request")
 	}
@@ -308,34 +497,122 @@ func (self *Flashbot) CancelPrivateTransaction(ctx context.Context, txHash commo
 	return rr, nil
 }
 
+type ParamsCancelBundle struct {
+	ReplacementUUID string `json:"replacementUuid"`
+}
+
+type CancelBundleResponse struct {
+	Error  `json:"error,omitempty"`
+	Result interface{} `json:"result,omitempty"`
+}
+
+// CancelBundle cancels all bundles sent with the replacement UUID.
+func (self *Flashbot) CancelBundle(ctx context.Context, replacementUUID string) (*CancelBundleResponse, error) {
+	param := ParamsCancelBundle{
+		ReplacementUUID: replacementUUID,
+	}
+	resp, err := self.req(ctx, "eth_cancelBundle", param)
+	if err != nil {
+		return nil, errors.Wrap(err, "flashbot cancel bundle request")
+	}
+
+	rr := &CancelBundleResponse{}
+
+	err = json.Unmarshal(resp, rr)
+	if err != nil {
+		return nil, errors.Wrapf(err, "unmarshal flashbot response:%v", string(resp))
+	}
+
+	if rr.Error.Code != 0 {
+		return nil, errors.Errorf("flashbot request returned an error:%+v,%v", rr.Error, rr.Message)
+	}
+
+	return rr, nil
+}
+
 func (self *Flashbot) SendBundle(
 	ctx context.Context,
 	txsHex []string,
 	blockNum uint64,
+) (*Response, error) {
+	return self.SendBundleParams(ctx, ParamsSend{
+		Txs:      txsHex,
+		BlockNum: hexutil.EncodeUint64(blockNum),
+	})
+}
+
+// SendBundleParams sends a bundle with all the optional params like the ones produced by the BundleBuilder.
+func (self *Flashbot) SendBundleParams(
+	ctx context.Context,
+	param ParamsSend,
 ) (*Response, error) {
 	method := "eth_sendBundle"
 	if self.api.MethodSend != "" {
 		method = self.api.MethodSend
 	}
 
-	param := ParamsSend{
-		Txs:      txsHex,
-		BlockNum: hexutil.EncodeUint64(blockNum),
+	blockNum, err := hexutil.DecodeUint64(param.BlockNum)
+	if err != nil {
+		return nil, errors.Wrapf(err, "decode bundle block number:%v", param.BlockNum)
+	}
+
+	if err := self.checkBundle(ctx, method, param, param.Txs, blockNum, param.MinTimestamp, param.MaxTimestamp); err != nil {
+		return nil, err
+	}
+	if self.DryRun() {
+		return self.dryRunSend(ctx, method, param, blockNum)
+	}
+	release, err := self.reserveBudget(param.Txs)
+	if err != nil {
+		return nil, err
+	}
+	if err := self.takeSpamQuota(blockNum); err != nil {
+		release()
+		return nil, err
 	}
 
-	resp, err := self.req(ctx, method, param)
+	resp, timing, err := self.reqTimed(ctx, RequestSubmit, method, param)
 	if err != nil {
+		release()
 		return nil, errors.Wrap(err, "flashbot send request")
 	}
 
-	rr, err := parseResp(resp, blockNum)
+	rr, err := parseResp(self.codec(), resp, blockNum, self.api.Retry.ErrorRules)
 	if err != nil {
+		release()
 		return nil, err
 	}
+	rr.Timing = timing
 
 	return rr, nil
 }
 
+// checkBundle runs the checks shared by all the bundle submits before the dry run and the spam quota,
+// the address policy, the relay limits and the target block and timestamps.
+func (self *Flashbot) checkBundle(ctx context.Context, method string, param interface{}, txsHex []string, blockNum, minTs, maxTs uint64) error {
+	if err := self.checkAddressPolicy(txsHex...); err != nil {
+		return err
+	}
+	if err := self.checkLimits(method, param, len(txsHex)); err != nil {
+		return err
+	}
+	return self.checkTarget(ctx, minTs, maxTs, blockNum)
+}
+
+func (self *Flashbot) checkLimits(method string, param interface{}, txs int) error {
+	if self.api.Limits == (RelayLimits{}) {
+		return nil
+	}
+	size, err := requestSize(method, param)
+	if err != nil {
+		return errors.Wrap(err, "bundle request size")
+	}
+	if violations := self.api.Limits.Check(txs, size); len(violations) > 0 {
+		return &ValidationError{Violations: violations}
+	}
+	return nil
+}
+
 func (self *Flashbot) SimulateBundle(
 	ctx context.Context,
 	txsHex []string,
@@ -365,12 +642,12 @@ func (self *Flashbot) SimulateBundle(
 		Version: version,
 	}
 
-	resp, err := self.req(ctx, methodSim, params)
+	resp, err := self.reqKind(ctx, RequestSimulate, methodSim, params)
 	if err != nil {
 		return nil, errors.Wrap(err, "flashbot send simulate bundle request")
 	}
 
-	rr, err := parseMevResp(resp, blockNum)
+	rr, err := parseMevResp(self.codec(), resp, blockNum)
 	if err != nil {
 		return nil, err
 	}
@@ -383,35 +660,99 @@ func (self *Flashbot) CallBundle(
 	txsHex []string,
 	_blockNumState uint64,
 ) (*Response, error) {
+	return self.CallBundleOverride(ctx, txsHex, _blockNumState, nil)
+}
+
+// CallBundleOverride simulates the bundle on top of the state block with the state overrides applied.
+// Relays that don't support overrides return an error or ignore them.
+func (self *Flashbot) CallBundleOverride(
+	ctx context.Context,
+	txsHex []string,
+	_blockNumState uint64,
+	overrides StateOverride,
+) (*Response, error) {
+	return self.CallBundleAt(ctx, txsHex, CallOpts{StateBlock: _blockNumState, Overrides: overrides})
+}
+
+// CallOpts pin the simulation block, the zero values use the relay defaults.
+type CallOpts struct {
+	// StateBlock is the block which state the bundle is executed on top of, the latest when 0.
+	StateBlock uint64
+	// BlockNum is the number of the simulated block, the state block + 1 by default.
+	BlockNum uint64
+	// Timestamp of the simulated block.
+	Timestamp uint64
+	// Coinbase of the simulated block.
+	Coinbase *common.Address
+	// Overrides are supported only by some of the builders.
+	Overrides StateOverride
+}
+
+// HistoricalSimulator simulates bundles pinned to any past state block,
+// i.e. for research and regression tests against known past opportunities.
+// It is implemented by the relay clients and the localsim Simulator.
+type HistoricalSimulator interface {
+	CallBundleAt(ctx context.Context, txsHex []string, opts CallOpts) (*Response, error)
+}
+
+// CallBundleAt simulates the bundle on top of the state block with the simulated block fields set by the opts.
+// The relays only keep the recent state so use the localsim Simulator with an archive node for the older blocks.
+func (self *Flashbot) CallBundleAt(ctx context.Context, txsHex []string, opts CallOpts) (*Response, error) {
 	if !self.api.SupportsSimulation {
 		return nil, errors.Errorf("doesn't support simulations relay:%v", self.api.URL)
 	}
 
+	var (
+		cache    *SimCache
+		cacheKey simKey
+	)
+	if len(opts.Overrides) == 0 && opts.BlockNum == 0 && opts.Timestamp == 0 && opts.Coinbase == nil {
+		var cached *Response
+		if cache, cacheKey, cached = self.cachedSim(txsHex, opts.StateBlock); cached != nil {
+			return cached, nil
+		}
+	}
+
 	method := "eth_callBundle"
 	if self.api.MethodSend != "" {
 		method = self.api.MethodSend
 	}
 
-	blockDummy := uint64(100000000000000)
+	blockNum := opts.BlockNum
 	blockNumState := "latest"
-	if _blockNumState != 0 {
-		blockNumState = hexutil.EncodeUint64(_blockNumState)
+	if opts.StateBlock != 0 {
+		blockNumState = hexutil.EncodeUint64(opts.StateBlock)
+		if blockNum == 0 {
+			blockNum = opts.StateBlock + 1
+		}
+	}
+	if blockNum == 0 {
+		blockNum = uint64(100000000000000)
 	}
 	param := ParamsCall{
-		Txs:           txsHex,
-		BlockNum:      hexutil.EncodeUint64(blockDummy),
-		StateBlockNum: blockNumState,
+		Txs:            txsHex,
+		BlockNum:       hexutil.EncodeUint64(blockNum),
+		StateBlockNum:  blockNumState,
+		Timestamp:      opts.Timestamp,
+		StateOverrides: opts.Overrides,
+	}
+	if opts.Coinbase != nil {
+		param.Coinbase = opts.Coinbase.Hex()
 	}
 
-	resp, err := self.req(ctx, method, param)
+	resp, timing, err := self.reqTimed(ctx, RequestSimulate, method, param)
 	if err != nil {
 		return nil, errors.Wrap(err, "flashbot call request")
 	}
 
-	rr, err := parseResp(resp, blockDummy)
+	rr, err := parseResp(self.codec(), resp, blockNum, self.api.Retry.ErrorRules)
 	if err != nil {
 		return nil, err
 	}
+	rr.Timing = timing
+	if cache != nil {
+		cache.put(cacheKey, rr)
+	}
 
 	return rr, nil
 }
@@ -447,6 +788,36 @@ func (self *Flashbot) GetBundleStats(
 
 }
 
+// GetBundleStatsV2 returns the bundle stats with the builders that considered and sealed the bundle.
+// The V2 reply has no SentToMinersAt and its SubmittedAt is set from the ReceivedAt.
+func (self *Flashbot) GetBundleStatsV2(
+	ctx context.Context,
+	bundleHash string,
+	blockNum uint64,
+) (*ResultBundleStats, error) {
+	param := ParamsStats{
+		BundleHash: bundleHash,
+		BlockNum:   hexutil.EncodeUint64(blockNum),
+	}
+
+	resp, err := self.req(ctx, "flashbots_getBundleStatsV2", param)
+	if err != nil {
+		return nil, errors.Wrap(err, "flashbot bundle stats V2 request")
+	}
+
+	rr := &ResultBundleStats{}
+	if err := self.codec().Unmarshal(resp, rr); err != nil {
+		return nil, errors.Wrap(err, "unmarshal flashbot bundle stats V2 response")
+	}
+	if rr.Error.Code != 0 {
+		return nil, errors.Errorf("flashbot request returned an error:%+v,%v", rr.Error, rr.Message)
+	}
+	if rr.Result.SubmittedAt.IsZero() {
+		rr.Result.SubmittedAt = rr.Result.ReceivedAt
+	}
+	return rr, nil
+}
+
 func (self *Flashbot) GetUserStats(
 	ctx context.Context,
 	blockNum uint64,
@@ -474,10 +845,10 @@ func (self *Flashbot) GetUserStats(
 
 }
 
-func parseMevResp(resp []byte, blockNum uint64) (*SimBundleResult, error) {
+func parseMevResp(codec Codec, resp []byte, blockNum uint64) (*SimBundleResult, error) {
 	rr := &SimBundleResult{}
 
-	err := json.Unmarshal(resp, rr)
+	err := codec.Unmarshal(resp, rr)
 	if err != nil {
 		return nil, errors.Wrapf(err, "unmarshal flashbot response:%v", string(resp))
 	}
@@ -490,12 +861,12 @@ func parseMevResp(resp []byte, blockNum uint64) (*SimBundleResult, error) {
 	return rr, nil
 }
 
-func parseResp(resp []byte, blockNum uint64) (*Response, error) {
+func parseResp(codec Codec, resp []byte, blockNum uint64, rules []ErrorRule) (*Response, error) {
 	rr := &Response{
 		Result: Result{},
 	}
 
-	err := json.Unmarshal(resp, rr)
+	err := codec.Unmarshal(resp, rr)
 	if err != nil {
 		return nil, errors.Wrapf(err, "unmarshal flashbot response:%v", string(resp))
 	}
@@ -505,31 +876,116 @@ func parseResp(resp []byte, blockNum uint64) (*Response, error) {
 		if len(rr.Result.Results) > 0 {
 			errStr += fmt.Sprintf(" Result:%+v , Revert:%+v, GasUsed:%+v", rr.Result.Results[0].Error, rr.Result.Results[0].Revert, rr.Result.Results[0].GasUsed)
 		}
+		if rr.Error.Code != 0 {
+			return nil, errors.WithMessage(&RelayError{
+				Code:    rr.Error.Code,
+				Message: rr.Error.Message,
+				Class:   ClassifyRelayError(rules, rr.Error.Code, rr.Error.Message),
+			}, errStr)
+		}
 		return nil, errors.New(errStr)
 	}
 
 	return rr, nil
 }
 
+// Forward sends a JSON-RPC request with the already encoded params array
+// signed by this client identity and returns the raw reply.
+// The rate limiter and the retry policy of the client are applied as for any other request.
+func (self *Flashbot) Forward(ctx context.Context, method string, params json.RawMessage) ([]byte, error) {
+	var args []json.RawMessage
+	if len(params) > 0 {
+		if err := json.Unmarshal(params, &args); err != nil {
+			return nil, errors.Wrap(err, "params should be an array")
+		}
+	}
+	in := make([]interface{}, len(args))
+	for i, a := range args {
+		in[i] = a
+	}
+	// The forwarded requests are classified by the method name as there is no call site.
+	kind := RequestOther
+	switch method {
+	case "eth_sendBundle", "mev_sendBundle", "eth_sendPrivateTransaction", "eth_sendPrivateRawTransaction", self.api.MethodSend:
+		kind = RequestSubmit
+	}
+	return self.reqKind(ctx, kind, method, in...)
+}
+
 func (self *Flashbot) req(ctx context.Context, method string, params ...interface{}) ([]byte, error) {
-	msg, err := newMessage(method, params...)
-	if err != nil {
-		return nil, errors.Wrap(err, "marshaling flashbot tx params")
+	return self.reqKind(ctx, RequestOther, method, params...)
+}
+
+// reqKind is like req for the submissions and the simulations
+// so that their latency is recorded whatever the method names of the relay are.
+func (self *Flashbot) reqKind(ctx context.Context, kind RequestKind, method string, params ...interface{}) ([]byte, error) {
+	res, _, err := self.reqTimed(ctx, kind, method, params...)
+	return res, err
+}
+
+// reqTimed is like reqKind and also returns the phase times of the last attempt.
+func (self *Flashbot) reqTimed(ctx context.Context, kind RequestKind, method string, params ...interface{}) ([]byte, RequestTiming, error) {
+	if !self.drain.enter() {
+		return nil, RequestTiming{Method: method, Kind: kind}, ErrClosed
+	}
+	defer self.drain.leave()
+	if err := self.dryRunGate(kind, method, params); err != nil {
+		return nil, RequestTiming{Method: method, Kind: kind}, err
 	}
 
-	payload, err := json.Marshal(msg)
-	if err != nil {
+	var (
+		timing   RequestTiming
+		attempts int
+	)
+	res, err := self.api.Retry.do(ctx, self.codec(), func() ([]byte, error) {
+		attempts++
+		tr := newRequestTrace(self)
+		res, err := self.reqOnce(tr.context(ctx), method, params...)
+		timing = tr.done(method)
+		timing.Kind = kind
+		timing.Attempts = attempts
+		self.recordTiming(timing)
+		if err == nil {
+			self.observeLatency(timing)
+		}
+		return res, err
+	})
+	return res, timing, err
+}
+
+func (self *Flashbot) reqOnce(ctx context.Context, method string, params ...interface{}) ([]byte, error) {
+	if err := self.api.RateLimiter.Wait(ctx); err != nil {
 		return nil, err
 	}
 
-	req, err := http.NewRequestWithContext(ctx, "POST", self.api.URL, io.NopCloser(bytes.NewReader(payload)))
-	if err != nil {
-		return nil, errors.Wrap(err, "creatting flashbot request")
+	buf := payloadPool.Get().(*bytes.Buffer)
+	buf.Reset()
+	body := &pooledBody{buf: buf}
+	if err := encodeMessage(buf, self.codec(), method, params...); err != nil {
+		body.Close()
+		return nil, errors.Wrap(err, "marshaling flashbot tx params")
 	}
-	signedP, err := signPayload(payload, self.prvKey, self.pubKey)
+	payload := buf.Bytes()
+	body.Reader = bytes.NewReader(payload)
+
+	signedP, err := self.signatureHeader(payload)
 	if err != nil {
+		body.Close()
 		return nil, errors.Wrap(err, "signing flashbot request")
 	}
+
+	if self.api.Timeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, self.api.Timeout)
+		defer cancel()
+	}
+	// The transport closes the body once it is sent which returns the buffer to the pool.
+	req, err := http.NewRequestWithContext(ctx, "POST", self.api.URL, body)
+	if err != nil {
+		body.Close()
+		return nil, errors.Wrap(err, "creatting flashbot request")
+	}
+	req.ContentLength = int64(len(payload))
 	req.Header.Add("content-type", "application/json")
 	req.Header.Add("Accept", "application/json")
 	req.Header.Add("X-Flashbots-Signature", signedP)
@@ -538,39 +994,71 @@ func (self *Flashbot) req(ctx context.Context, method string, params ...interfac
 		req.Header.Add(n, v)
 	}
 
-	mevHTTPClient := &http.Client{
-		Transport: &http.Transport{
-			TLSClientConfig: &tls.Config{InsecureSkipVerify: true},
-		},
-	}
-	resp, err := mevHTTPClient.Do(req)
+	resp, err := self.httpClient().Do(req)
 	if err != nil {
 		return nil, errors.Wrap(err, "flashbot request")
 	}
+	defer resp.Body.Close()
+
+	info := connInfo(resp)
+	self.mtx.Lock()
+	self.connInfo = info
+	self.mtx.Unlock()
 
+	maxSize := self.api.MaxResponseSize
+	if maxSize <= 0 {
+		maxSize = DefaultMaxResponseSize
+	}
 	if resp.StatusCode/100 != 2 {
+		resp.Body = limitedBody{Reader: io.LimitReader(resp.Body, maxSize), Closer: resp.Body}
 		respDump, err := httputil.DumpResponse(resp, true)
 		if err != nil {
-			return nil, errors.Errorf("bad response status %v", resp.Status)
+			return nil, &StatusError{StatusCode: resp.StatusCode, Msg: fmt.Sprintf("bad response status %v", resp.Status)}
 		}
 		reqDump, err := httputil.DumpRequestOut(req, true)
 		if err != nil {
-			return nil, errors.Errorf("bad response resp respDump:%v", string(respDump))
+			return nil, &StatusError{StatusCode: resp.StatusCode, Msg: fmt.Sprintf("bad response resp respDump:%v", string(respDump))}
 		}
-		return nil, errors.Errorf("bad response resp respDump:%v reqDump:%v", string(respDump), string(reqDump))
+		return nil, &StatusError{StatusCode: resp.StatusCode, Msg: fmt.Sprintf("bad response resp respDump:%v reqDump:%v", string(respDump), string(reqDump))}
 	}
 
-	res, err := io.ReadAll(resp.Body)
-	if err != nil {
-		return nil, errors.Wrap(err, "reading flashbot reply")
+	if resp.ContentLength > maxSize {
+		return nil, errors.Wrapf(ErrResponseTooLarge, "content length:%v max:%v", resp.ContentLength, maxSize)
 	}
+	return readBody(resp.Body, maxSize)
+}
+
+// DefaultMaxResponseSize is the size limit of the relay replies when Api.MaxResponseSize is not set.
+const DefaultMaxResponseSize = 32 << 20
+
+// ErrResponseTooLarge is returned when the relay reply is over the max response size.
+var ErrResponseTooLarge = errors.New("response too large")
 
-	err = resp.Body.Close()
+type limitedBody struct {
+	io.Reader
+	io.Closer
+}
+
+// readBody reads the reply into a pooled buffer so that it doesn't grow a new slice for every reply
+// and returns a copy of the exact size.
+func readBody(r io.Reader, maxSize int64) ([]byte, error) {
+	buf := payloadPool.Get().(*bytes.Buffer)
+	buf.Reset()
+	defer func() {
+		if buf.Cap() <= payloadPoolMax {
+			payloadPool.Put(buf)
+		}
+	}()
+
+	// One more byte tells apart a reply of exactly the max size from a larger one.
+	n, err := buf.ReadFrom(io.LimitReader(r, maxSize+1))
 	if err != nil {
-		return nil, errors.Wrap(err, "closing flashbot reply body")
+		return nil, errors.Wrap(err, "reading flashbot reply")
 	}
-
-	return res, nil
+	if n > maxSize {
+		return nil, errors.Wrapf(ErrResponseTooLarge, "max:%v", maxSize)
+	}
+	return append([]byte(nil), buf.Bytes()...), nil
 }
 
 // A value of this type can a JSON-RPC request, notification, successful response or
@@ -601,19 +1089,98 @@ func newMessage(method string, paramsIn ...interface{}) (*jsonrpcMessage, error)
 	return msg, nil
 }
 
-func signPayload(payload []byte, prvKey *ecdsa.PrivateKey, pubKey *common.Address) (string, error) {
-	if prvKey == nil || pubKey == nil {
-		return "", errors.New("private or public key is not set")
+func signPayload(payload []byte, signer Signer) (string, error) {
+	if signer == nil {
+		return "", errors.New("private key or signer is not set")
 	}
-	signature, err := crypto.Sign(
-		accounts.TextHash([]byte(hexutil.Encode(crypto.Keccak256(payload)))),
-		prvKey,
-	)
+	return signPayloadAddr(payload, signer, signer.Address().Hex())
+}
+
+// signPayloadAddr signs the payload hash with the signer whose hex address is already known.
+func signPayloadAddr(payload []byte, signer Signer, addrHex string) (string, error) {
+	hash := crypto.Keccak256Hash(payload)
+	var text [2 + 2*common.HashLength]byte
+	copy(text[:], "0x")
+	hex.Encode(text[2:], hash[:])
+	signature, err := signer.SignText(text[:])
 	if err != nil {
 		return "", errors.Wrap(err, "sign the payload")
 	}
 
-	return pubKey.Hex() + ":" + hexutil.Encode(signature), nil
+	if len(signature) != crypto.SignatureLength {
+		return "", errors.Errorf("invalid signature length:%v", len(signature))
+	}
+	var sigHex [2 * crypto.SignatureLength]byte
+	hex.Encode(sigHex[:], signature)
+
+	var b strings.Builder
+	b.Grow(len(addrHex) + 3 + len(sigHex))
+	b.WriteString(addrHex)
+	b.WriteString(":0x")
+	b.Write(sigHex[:])
+	return b.String(), nil
+}
+
+// signatureHeader returns the X-Flashbots-Signature header for the payload.
+// The checksummed address of the signer is computed once per signer address.
+func (self *Flashbot) signatureHeader(payload []byte) (string, error) {
+	self.mtx.RLock()
+	signer, cached := self.signer, self.signerHex
+	self.mtx.RUnlock()
+	if signer == nil {
+		return "", errors.New("private key or signer is not set")
+	}
+	// The signers are compared by address as not all of their dynamic types are comparable.
+	if addr := signer.Address(); cached.addr != addr || cached.hex == "" {
+		cached = signerHex{addr: addr, hex: addr.Hex()}
+		self.mtx.Lock()
+		self.signerHex = cached
+		self.mtx.Unlock()
+	}
+	return signPayloadAddr(payload, signer, cached.hex)
+}
+
+type signerHex struct {
+	addr common.Address
+	hex  string
+}
+
+var payloadPool = sync.Pool{New: func() interface{} { return new(bytes.Buffer) }}
+
+// payloadPoolMax is the max capacity of a buffer returned to the pool
+// so that a single huge request doesn't keep its memory around.
+const payloadPoolMax = 1 << 20
+
+// pooledBody returns its buffer to the pool when closed.
+type pooledBody struct {
+	*bytes.Reader
+	buf  *bytes.Buffer
+	once sync.Once
+}
+
+func (self *pooledBody) Close() error {
+	self.once.Do(func() {
+		if self.buf.Cap() <= payloadPoolMax {
+			payloadPool.Put(self.buf)
+		}
+	})
+	return nil
+}
+
+// encodeMessage writes the JSON-RPC request to the buffer in the same format as marshaling newMessage.
+func encodeMessage(buf *bytes.Buffer, codec Codec, method string, params ...interface{}) error {
+	buf.WriteString(`{"jsonrpc":"2.0","id":1,"method":`)
+	if err := encodeTo(codec, buf, method); err != nil {
+		return err
+	}
+	if params != nil { // prevent sending "params":null
+		buf.WriteString(`,"params":`)
+		if err := encodeTo(codec, buf, params); err != nil {
+			return err
+		}
+	}
+	buf.WriteByte('}')
+	return nil
 }
 
 func relayURLDefault(netID int64) (string, error) {
@@ -0,0 +1,231 @@
+// Copyright (c) The Cryptorium Authors.
+// Licensed under the MIT License.
+
+// Package forksim simulates bundles on a local anvil or hardhat fork
+// by mining the bundle TXs in a single block and reverting the fork afterwards
+// so that the bundles can be tested against a deterministic forked state.
+//
+// The fork must order the pending TXs by arrival to keep the bundle order.
+// Spawn starts anvil with "--order fifo" and hardhat needs the mining.mempool.order option set to "fifo".
+package forksim
+
+import (
+	"context"
+	"math/big"
+	"os/exec"
+	"strconv"
+	"time"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/common/hexutil"
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/ethclient"
+	"github.com/ethereum/go-ethereum/rpc"
+	"github.com/kachan28/flashbot"
+	"github.com/pkg/errors"
+)
+
+type Fork struct {
+	URL    string
+	rpc    *rpc.Client
+	client *ethclient.Client
+	cmd    *exec.Cmd
+}
+
+// Attach connects to an already running fork.
+func Attach(ctx context.Context, url string) (*Fork, error) {
+	client, err := rpc.DialContext(ctx, url)
+	if err != nil {
+		return nil, errors.Wrapf(err, "connect to fork:%v", url)
+	}
+	return &Fork{URL: url, rpc: client, client: ethclient.NewClient(client)}, nil
+}
+
+type SpawnOpts struct {
+	// Bin is the anvil binary, "anvil" by default.
+	Bin     string
+	ForkURL string
+	// ForkBlock is the block to fork from, the latest when 0.
+	ForkBlock uint64
+	// Port is the listening port, 8545 by default.
+	Port int
+	// Timeout for the fork to start accepting requests, 30 seconds by default.
+	Timeout time.Duration
+	Args    []string
+}
+
+// Spawn starts an anvil fork and waits until it accepts requests.
+// The process is stopped with Close.
+func Spawn(ctx context.Context, opts SpawnOpts) (*Fork, error) {
+	if opts.Bin == "" {
+		opts.Bin = "anvil"
+	}
+	if opts.Port == 0 {
+		opts.Port = 8545
+	}
+	if opts.Timeout == 0 {
+		opts.Timeout = 30 * time.Second
+	}
+	args := []string{"--port", strconv.Itoa(opts.Port), "--order", "fifo"}
+	if opts.ForkURL != "" {
+		args = append(args, "--fork-url", opts.ForkURL)
+	}
+	if opts.ForkBlock != 0 {
+		args = append(args, "--fork-block-number", strconv.FormatUint(opts.ForkBlock, 10))
+	}
+	cmd := exec.Command(opts.Bin, append(args, opts.Args...)...)
+	if err := cmd.Start(); err != nil {
+		return nil, errors.Wrapf(err, "start:%v", opts.Bin)
+	}
+
+	url := "http://127.0.0.1:" + strconv.Itoa(opts.Port)
+	ctx, cncl := context.WithTimeout(ctx, opts.Timeout)
+	defer cncl()
+	for {
+		fork, err := Attach(ctx, url)
+		if err == nil {
+			if _, err = fork.client.ChainID(ctx); err == nil {
+				fork.cmd = cmd
+				return fork, nil
+			}
+			fork.rpc.Close()
+		}
+		select {
+		case <-ctx.Done():
+			_ = cmd.Process.Kill()
+			_ = cmd.Wait()
+			return nil, errors.Wrapf(err, "waiting for the fork to start url:%v", url)
+		case <-time.After(200 * time.Millisecond):
+		}
+	}
+}
+
+// Close disconnects from the fork and stops it when started with Spawn.
+func (self *Fork) Close() error {
+	self.rpc.Close()
+	if self.cmd == nil {
+		return nil
+	}
+	if err := self.cmd.Process.Kill(); err != nil {
+		return errors.Wrap(err, "stop fork process")
+	}
+	_ = self.cmd.Wait()
+	return nil
+}
+
+// Simulate mines the bundle TXs in the next fork block and returns the results
+// in the shape of the relay eth_callBundle response.
+// The fork state is reverted to the one before the call.
+//
+// A block receipt doesn't show the payments per TX so
+// the coinbase diff per TX only includes the gas fees and the direct
+// payments are only in the bundle totals.
+func (self *Fork) Simulate(ctx context.Context, txsHex []string, timestamp uint64) (res *flashbot.Result, err error) {
+	var snapshot hexutil.Big
+	if err := self.rpc.CallContext(ctx, &snapshot, "evm_snapshot"); err != nil {
+		return nil, errors.Wrap(err, "evm_snapshot")
+	}
+	defer func() {
+		var reverted bool
+		if errR := self.rpc.CallContext(context.Background(), &reverted, "evm_revert", &snapshot); errR != nil && err == nil {
+			err = errors.Wrap(errR, "evm_revert")
+		}
+		if errA := self.rpc.CallContext(context.Background(), nil, "evm_setAutomine", true); errA != nil && err == nil {
+			err = errors.Wrap(errA, "enable automine")
+		}
+	}()
+
+	if err := self.rpc.CallContext(ctx, nil, "evm_setAutomine", false); err != nil {
+		return nil, errors.Wrap(err, "disable automine")
+	}
+	parent, err := self.client.HeaderByNumber(ctx, nil)
+	if err != nil {
+		return nil, errors.Wrap(err, "get fork head")
+	}
+
+	txs := make([]*types.Transaction, len(txsHex))
+	for i, txHex := range txsHex {
+		if txs[i], err = flashbot.DecodeTx(txHex); err != nil {
+			return nil, errors.Wrapf(err, "decode TX index:%v", i)
+		}
+		var hash common.Hash
+		if err := self.rpc.CallContext(ctx, &hash, "eth_sendRawTransaction", txHex); err != nil {
+			return nil, errors.Wrapf(err, "send TX index:%v", i)
+		}
+	}
+
+	if timestamp != 0 {
+		err = self.rpc.CallContext(ctx, nil, "evm_mine", hexutil.Uint64(timestamp))
+	} else {
+		err = self.rpc.CallContext(ctx, nil, "evm_mine")
+	}
+	if err != nil {
+		return nil, errors.Wrap(err, "evm_mine")
+	}
+	header, err := self.client.HeaderByNumber(ctx, new(big.Int).Add(parent.Number, big.NewInt(1)))
+	if err != nil {
+		return nil, errors.Wrap(err, "get mined block")
+	}
+
+	res = &flashbot.Result{}
+	gasFees := new(big.Int)
+	var gasUsed uint64
+	for i, tx := range txs {
+		receipt, err := self.client.TransactionReceipt(ctx, tx.Hash())
+		if err != nil {
+			return nil, errors.Wrapf(err, "get receipt index:%v hash:%v", i, tx.Hash().Hex())
+		}
+		if receipt.BlockNumber == nil || receipt.BlockNumber.Cmp(header.Number) != 0 {
+			return nil, errors.Errorf("TX not mined in the bundle block index:%v hash:%v", i, tx.Hash().Hex())
+		}
+		from, err := types.Sender(types.LatestSignerForChainID(tx.ChainId()), tx)
+		if err != nil {
+			return nil, errors.Wrapf(err, "get TX sender index:%v", i)
+		}
+
+		price := flashbot.EffectiveGasPrice(tx, header.BaseFee)
+		fees := new(big.Int).Set(price)
+		if header.BaseFee != nil {
+			fees.Sub(fees, header.BaseFee)
+		}
+		fees.Mul(fees, new(big.Int).SetUint64(receipt.GasUsed))
+		gasFees.Add(gasFees, fees)
+		gasUsed += receipt.GasUsed
+
+		txRes := flashbot.TxResult{
+			Metadata: flashbot.Metadata{
+				CoinbaseDiff:      fees.String(),
+				EthSentToCoinbase: "0",
+				GasFees:           fees.String(),
+			},
+			FromAddress: from.Hex(),
+			GasPrice:    price.String(),
+			TxHash:      tx.Hash().Hex(),
+			GasUsed:     receipt.GasUsed,
+		}
+		if receipt.Status == types.ReceiptStatusFailed {
+			txRes.Error = "execution reverted"
+		}
+		res.Results = append(res.Results, txRes)
+	}
+
+	before, err := self.client.BalanceAt(ctx, header.Coinbase, parent.Number)
+	if err != nil {
+		return nil, errors.Wrap(err, "get coinbase balance before")
+	}
+	after, err := self.client.BalanceAt(ctx, header.Coinbase, header.Number)
+	if err != nil {
+		return nil, errors.Wrap(err, "get coinbase balance after")
+	}
+	coinbaseDiff := new(big.Int).Sub(after, before)
+	res.Metadata = flashbot.Metadata{
+		CoinbaseDiff:      coinbaseDiff.String(),
+		EthSentToCoinbase: new(big.Int).Sub(coinbaseDiff, gasFees).String(),
+		GasFees:           gasFees.String(),
+	}
+	res.BundleGasPrice = "0"
+	if gasUsed > 0 {
+		res.BundleGasPrice = new(big.Int).Div(coinbaseDiff, new(big.Int).SetUint64(gasUsed)).String()
+	}
+	return res, nil
+}
@@ -0,0 +1,149 @@
+// Copyright (c) The Cryptorium Authors.
+// Licensed under the MIT License.
+
+package forksim
+
+import (
+	"context"
+	"math/big"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/cryptoriums/packages/testutil"
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/common/hexutil"
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/crypto"
+	"github.com/ethereum/go-ethereum/params"
+	"github.com/ethereum/go-ethereum/rpc"
+)
+
+var coinbase = common.HexToAddress("0xc0")
+
+type forkMock struct {
+	head     *types.Header
+	automine bool
+	reverted bool
+	pending  []*types.Transaction
+	receipts map[common.Hash]*types.Receipt
+	tip      *big.Int
+}
+
+type evmService struct{ fork *forkMock }
+
+func (self *evmService) Snapshot() hexutil.Big { return hexutil.Big(*big.NewInt(1)) }
+
+func (self *evmService) Revert(id hexutil.Big) bool {
+	self.fork.reverted = true
+	return true
+}
+
+func (self *evmService) SetAutomine(enabled bool) { self.fork.automine = enabled }
+
+func (self *evmService) Mine(ts *hexutil.Uint64) {
+	head := &types.Header{
+		ParentHash: self.fork.head.Hash(),
+		Number:     new(big.Int).Add(self.fork.head.Number, big.NewInt(1)),
+		Coinbase:   coinbase,
+		GasLimit:   30_000_000,
+		BaseFee:    big.NewInt(params.GWei),
+		Difficulty: new(big.Int),
+		Time:       self.fork.head.Time + 12,
+	}
+	if ts != nil {
+		head.Time = uint64(*ts)
+	}
+	for i, tx := range self.fork.pending {
+		status := types.ReceiptStatusSuccessful
+		if i == 1 {
+			status = types.ReceiptStatusFailed
+		}
+		self.fork.receipts[tx.Hash()] = &types.Receipt{
+			Status:      status,
+			TxHash:      tx.Hash(),
+			GasUsed:     21_000,
+			BlockNumber: head.Number,
+			Logs:        []*types.Log{},
+		}
+	}
+	self.fork.head = head
+}
+
+type ethService struct{ fork *forkMock }
+
+func (self *ethService) ChainId() *hexutil.Big { return (*hexutil.Big)(big.NewInt(1)) }
+
+func (self *ethService) SendRawTransaction(raw hexutil.Bytes) (common.Hash, error) {
+	tx := &types.Transaction{}
+	if err := tx.UnmarshalBinary(raw); err != nil {
+		return common.Hash{}, err
+	}
+	self.fork.pending = append(self.fork.pending, tx)
+	return tx.Hash(), nil
+}
+
+func (self *ethService) GetBlockByNumber(number rpc.BlockNumber, full bool) *types.Header {
+	return self.fork.head
+}
+
+func (self *ethService) GetTransactionReceipt(hash common.Hash) *types.Receipt {
+	return self.fork.receipts[hash]
+}
+
+func (self *ethService) GetBalance(addr common.Address, number rpc.BlockNumber) *hexutil.Big {
+	if number.Int64() <= 100 {
+		return (*hexutil.Big)(big.NewInt(params.Ether))
+	}
+	return (*hexutil.Big)(new(big.Int).Add(big.NewInt(params.Ether), self.fork.tip))
+}
+
+func TestSimulate(t *testing.T) {
+	fork := &forkMock{
+		head:     &types.Header{Number: big.NewInt(100), Difficulty: new(big.Int), BaseFee: big.NewInt(params.GWei)},
+		automine: true,
+		receipts: make(map[common.Hash]*types.Receipt),
+	}
+	srv := rpc.NewServer()
+	testutil.Ok(t, srv.RegisterName("evm", &evmService{fork: fork}))
+	testutil.Ok(t, srv.RegisterName("eth", &ethService{fork: fork}))
+	httpSrv := httptest.NewServer(srv)
+	defer httpSrv.Close()
+
+	prvKey, err := crypto.GenerateKey()
+	testutil.Ok(t, err)
+	signer := types.LatestSignerForChainID(big.NewInt(1))
+	var txs []string
+	for i := 0; i < 2; i++ {
+		tx, err := types.SignNewTx(prvKey, signer, &types.DynamicFeeTx{
+			ChainID:   big.NewInt(1),
+			Nonce:     uint64(i),
+			GasTipCap: big.NewInt(2 * params.GWei),
+			GasFeeCap: big.NewInt(10 * params.GWei),
+			Gas:       21_000,
+			To:        &coinbase,
+		})
+		testutil.Ok(t, err)
+		raw, err := tx.MarshalBinary()
+		testutil.Ok(t, err)
+		txs = append(txs, hexutil.Encode(raw))
+	}
+	fees := big.NewInt(2 * 2 * params.GWei * 21_000)
+	fork.tip = new(big.Int).Add(fees, big.NewInt(params.GWei))
+
+	f, err := Attach(context.Background(), httpSrv.URL)
+	testutil.Ok(t, err)
+	defer f.Close()
+
+	res, err := f.Simulate(context.Background(), txs, 0)
+	testutil.Ok(t, err)
+	testutil.Assert(t, fork.reverted, "fork state not reverted")
+	testutil.Assert(t, fork.automine, "automine not enabled back")
+
+	testutil.Equals(t, 2, len(res.Results))
+	testutil.Equals(t, "", res.Results[0].Error)
+	testutil.Equals(t, "execution reverted", res.Results[1].Error)
+	testutil.Equals(t, "3000000000", res.Results[0].GasPrice)
+	testutil.Equals(t, fees.String(), res.GasFees)
+	testutil.Equals(t, fork.tip.String(), res.CoinbaseDiff)
+	testutil.Equals(t, "1000000000", res.EthSentToCoinbase)
+}
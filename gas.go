@@ -0,0 +1,111 @@
+// Copyright (c) The Cryptorium Authors.
+// Licensed under the MIT License.
+
+package flashbot
+
+import (
+	"context"
+
+	"github.com/ethereum/go-ethereum"
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/pkg/errors"
+)
+
+// GasEstimator is implemented by ethclient.Client and Node.
+type GasEstimator interface {
+	EstimateGas(ctx context.Context, msg ethereum.CallMsg) (uint64, error)
+}
+
+// bundleEstimateGasTotal is the gas split between the TXs while simulating them for the gas estimation.
+const bundleEstimateGasTotal = 25_000_000
+
+// MinGasEstimateMargin is the lowest margin over the simulated gas used accepted by SignTxsWithGasEstimate.
+// The gas used is after the refunds, which can be up to a fifth of the spent gas,
+// and a TX with calls also needs the 1/64 of the gas that the EVM keeps back at each call.
+const MinGasEstimateMargin = 1.3
+
+// WithAutoGas sets the gas limit of the specs that don't have it set
+// to the node eth_estimateGas result increased by the margin, i.e. 1.2 for 20%.
+// The estimation runs against the latest state so use SignTxsWithGasEstimate
+// when the TX depends on the effects of the earlier bundle TXs.
+func WithAutoGas(backend GasEstimator, margin float64) TxOption {
+	return func(ctx context.Context, from common.Address, spec *TxSpec) error {
+		if spec.Gas != 0 {
+			return nil
+		}
+		if margin < 1 {
+			return errors.Errorf("gas margin can't be lower than 1:%v", margin)
+		}
+		gas, err := backend.EstimateGas(ctx, ethereum.CallMsg{
+			From:       from,
+			To:         spec.To,
+			Value:      spec.Value,
+			Data:       spec.Data,
+			GasFeeCap:  spec.GasFeeCap,
+			GasTipCap:  spec.GasTipCap,
+			AccessList: spec.AccessList,
+		})
+		if err != nil {
+			return errors.Wrap(err, "estimate gas")
+		}
+		spec.Gas = uint64(float64(gas) * margin)
+		return nil
+	}
+}
+
+// SignTxsWithGasEstimate signs the specs like SignTxs, but the specs without a gas limit
+// get one from simulating the whole bundle through CallBundle
+// so that each TX is estimated against the state left by the earlier bundle TXs.
+// The TXs are first signed with a high placeholder gas limit
+// so the sender needs a balance that covers it at the spec fee cap.
+// The margin can't be lower than MinGasEstimateMargin as the simulation reports the gas used and not the needed gas limit.
+func (self *Flashbot) SignTxsWithGasEstimate(
+	ctx context.Context,
+	netID int64,
+	nonce uint64,
+	specs []TxSpec,
+	margin float64,
+	opts ...TxOption,
+) ([]string, []*types.Transaction, error) {
+	if margin < MinGasEstimateMargin {
+		return nil, nil, errors.Errorf("gas margin can't be lower than %v:%v", MinGasEstimateMargin, margin)
+	}
+	if len(specs) == 0 {
+		return nil, nil, errors.New("no TX specs")
+	}
+
+	var estimate bool
+	specsSim := make([]TxSpec, len(specs))
+	for i, spec := range specs {
+		if spec.Gas == 0 {
+			estimate = true
+			spec.Gas = bundleEstimateGasTotal / uint64(len(specs))
+		}
+		specsSim[i] = spec
+	}
+	if !estimate {
+		return self.SignTxs(ctx, netID, nonce, specs, opts...)
+	}
+
+	txsHex, _, err := self.SignTxs(ctx, netID, nonce, specsSim, opts...)
+	if err != nil {
+		return nil, nil, errors.Wrap(err, "sign TXs for the simulation")
+	}
+	resp, err := self.CallBundle(ctx, txsHex, 0)
+	if err != nil {
+		return nil, nil, errors.Wrap(err, "simulate the bundle for gas estimation")
+	}
+	if len(resp.Results) != len(specs) {
+		return nil, nil, errors.Errorf("simulation results count mismatch exp:%v act:%v", len(specs), len(resp.Results))
+	}
+
+	specsFinal := make([]TxSpec, len(specs))
+	copy(specsFinal, specs)
+	for i := range specsFinal {
+		if specsFinal[i].Gas == 0 {
+			specsFinal[i].Gas = uint64(float64(resp.Results[i].GasUsed) * margin)
+		}
+	}
+	return self.SignTxs(ctx, netID, nonce, specsFinal, opts...)
+}
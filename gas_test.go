@@ -0,0 +1,97 @@
+// Copyright (c) The Cryptorium Authors.
+// Licensed under the MIT License.
+
+package flashbot
+
+import (
+	"context"
+	"encoding/json"
+	"math/big"
+	"testing"
+
+	"github.com/cryptoriums/packages/testutil"
+	"github.com/ethereum/go-ethereum"
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/pkg/errors"
+)
+
+type gasEstimatorMock struct {
+	gas  uint64
+	err  error
+	msgs []ethereum.CallMsg
+}
+
+func (self *gasEstimatorMock) EstimateGas(ctx context.Context, msg ethereum.CallMsg) (uint64, error) {
+	self.msgs = append(self.msgs, msg)
+	return self.gas, self.err
+}
+
+func TestWithAutoGas(t *testing.T) {
+	ctx := context.Background()
+	fb := newTestFlashbot(t, "http://localhost")
+	to := common.HexToAddress("0x70")
+	estimator := &gasEstimatorMock{gas: 100_000}
+
+	_, txs, err := fb.SignTxs(ctx, 1, 0, []TxSpec{
+		{To: &to, Data: []byte{1}, GasFeeCap: big.NewInt(1)},
+		{To: &to, Gas: 21_000, GasFeeCap: big.NewInt(1)},
+	}, WithAutoGas(estimator, 1.2))
+	testutil.Ok(t, err)
+	testutil.Equals(t, uint64(120_000), txs[0].Gas())
+	testutil.Equals(t, uint64(21_000), txs[1].Gas())
+	// Only the spec without a gas limit is estimated.
+	testutil.Equals(t, 1, len(estimator.msgs))
+	testutil.Equals(t, fb.TxSigner().Address(), estimator.msgs[0].From)
+	testutil.Equals(t, []byte{1}, estimator.msgs[0].Data)
+
+	_, _, err = fb.SignTxs(ctx, 1, 0, []TxSpec{{To: &to, GasFeeCap: big.NewInt(1)}}, WithAutoGas(estimator, 0.9))
+	testutil.NotOk(t, err)
+
+	estimator.err = errors.New("execution reverted")
+	_, _, err = fb.SignTxs(ctx, 1, 0, []TxSpec{{To: &to, GasFeeCap: big.NewInt(1)}}, WithAutoGas(estimator, 1.2))
+	testutil.NotOk(t, err)
+}
+
+func TestSignTxsWithGasEstimate(t *testing.T) {
+	ctx := context.Background()
+	var simulated []uint64
+	relay := newRelayMock(t, func(method string, params json.RawMessage) (interface{}, *jsonError) {
+		var p []ParamsCall
+		testutil.Ok(t, json.Unmarshal(params, &p))
+		simulated = simulated[:0]
+		for _, txHex := range p[0].Txs {
+			tx, err := DecodeTx(txHex)
+			testutil.Ok(t, err)
+			simulated = append(simulated, tx.Gas())
+		}
+		return Result{Results: []TxResult{{GasUsed: 100_000}, {GasUsed: 21_000}}}, nil
+	})
+	fb := newTestFlashbot(t, relay.URL)
+	to := common.HexToAddress("0x70")
+	specs := []TxSpec{
+		{To: &to, Data: []byte{1}, GasFeeCap: big.NewInt(1)},
+		{To: &to, Gas: 30_000, GasFeeCap: big.NewInt(1)},
+	}
+
+	_, txs, err := fb.SignTxsWithGasEstimate(ctx, 1, 0, specs, 1.5)
+	testutil.Ok(t, err)
+	testutil.Equals(t, []string{"eth_callBundle"}, relay.Methods())
+	// The simulation uses the placeholder for the TX without a gas limit.
+	testutil.Equals(t, []uint64{bundleEstimateGasTotal / 2, 30_000}, simulated)
+	testutil.Equals(t, uint64(150_000), txs[0].Gas())
+	testutil.Equals(t, uint64(30_000), txs[1].Gas())
+	testutil.Equals(t, uint64(0), specs[0].Gas)
+
+	// The margin that sets the gas limit to the gas used is rejected.
+	_, _, err = fb.SignTxsWithGasEstimate(ctx, 1, 0, specs, 1)
+	testutil.NotOk(t, err)
+	// The relay returns more results than TXs.
+	_, _, err = fb.SignTxsWithGasEstimate(ctx, 1, 0, specs[:1], MinGasEstimateMargin)
+	testutil.NotOk(t, err)
+
+	// Nothing is simulated when all specs have a gas limit.
+	_, txs, err = fb.SignTxsWithGasEstimate(ctx, 1, 0, specs[1:], MinGasEstimateMargin)
+	testutil.Ok(t, err)
+	testutil.Equals(t, uint64(30_000), txs[0].Gas())
+	testutil.Equals(t, 2, len(relay.Methods()))
+}
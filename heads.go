@@ -0,0 +1,175 @@
+// Copyright (c) The Cryptorium Authors.
+// Licensed under the MIT License.
+
+package flashbot
+
+import (
+	"context"
+	"sync"
+	"time"
+
+	"github.com/ethereum/go-ethereum"
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/rpc"
+	"github.com/pkg/errors"
+)
+
+// NewHeadSubscriber is implemented by ethclient.Client and Node when connected over websocket or IPC.
+type NewHeadSubscriber interface {
+	SubscribeNewHead(ctx context.Context, ch chan<- *types.Header) (ethereum.Subscription, error)
+}
+
+// HeadWatcher feeds the new chain heads to its subscribers, i.e. as the heads of ResubmitUntilIncluded.
+// It subscribes to newHeads when the backend supports it and otherwise polls the latest header.
+type HeadWatcher struct {
+	backend  HeaderBackend
+	interval time.Duration
+
+	mtx  sync.RWMutex
+	head *types.Header
+	subs []chan *types.Header
+}
+
+// NewHeadWatcher creates a watcher for the backend which also needs to implement NewHeadSubscriber for the subscriptions.
+// The interval is used for polling and for retrying a failed subscription, one second by default.
+func NewHeadWatcher(backend HeaderBackend, interval time.Duration) *HeadWatcher {
+	if interval <= 0 {
+		interval = time.Second
+	}
+	return &HeadWatcher{backend: backend, interval: interval}
+}
+
+// Run watches the heads until the context is done and then closes all subscriber channels.
+// A failed subscription falls back to polling until resubscribing succeeds.
+func (self *HeadWatcher) Run(ctx context.Context) {
+	defer self.closeSubs()
+
+	subscriber, canSubscribe := self.backend.(NewHeadSubscriber)
+	for {
+		if canSubscribe {
+			err := self.watchSubscription(ctx, subscriber)
+			if ctx.Err() != nil {
+				return
+			}
+			if errors.Is(err, rpc.ErrNotificationsUnsupported) {
+				canSubscribe = false
+			}
+		}
+		// Errors are ignored by the polling which just tries again on the next interval.
+		if h, err := self.backend.HeaderByNumber(ctx, nil); err == nil {
+			self.publish(h)
+		}
+		select {
+		case <-ctx.Done():
+			return
+		case <-time.After(self.interval):
+		}
+	}
+}
+
+func (self *HeadWatcher) watchSubscription(ctx context.Context, subscriber NewHeadSubscriber) error {
+	ch := make(chan *types.Header, 16)
+	sub, err := subscriber.SubscribeNewHead(ctx, ch)
+	if err != nil {
+		return err
+	}
+	defer sub.Unsubscribe()
+
+	// The subscription sends only the following heads.
+	if h, err := self.backend.HeaderByNumber(ctx, nil); err == nil {
+		self.publish(h)
+	}
+	for {
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case err := <-sub.Err():
+			if err == nil {
+				err = errors.New("subscription closed")
+			}
+			return err
+		case h := <-ch:
+			self.publish(h)
+		}
+	}
+}
+
+// publish sends the header unless it is already the current head.
+// Reorged heads with the same number are sent as they have a different hash.
+func (self *HeadWatcher) publish(h *types.Header) {
+	self.mtx.Lock()
+	defer self.mtx.Unlock()
+	if self.head != nil && (self.head.Hash() == h.Hash() || h.Number.Cmp(self.head.Number) < 0) {
+		return
+	}
+	self.head = h
+	for _, sub := range self.subs {
+		select {
+		case sub <- h:
+		default:
+		}
+	}
+}
+
+// Head returns the latest head or nil before the first one.
+func (self *HeadWatcher) Head() *types.Header {
+	self.mtx.RLock()
+	defer self.mtx.RUnlock()
+	return self.head
+}
+
+// Subscribe returns a channel that receives the new heads and a function to unsubscribe.
+// Heads are dropped when the channel buffer is full so that a slow reader doesn't block the others.
+func (self *HeadWatcher) Subscribe(buffer int) (<-chan *types.Header, func()) {
+	ch := make(chan *types.Header, buffer)
+	self.mtx.Lock()
+	self.subs = append(self.subs, ch)
+	self.mtx.Unlock()
+
+	return ch, func() {
+		self.mtx.Lock()
+		defer self.mtx.Unlock()
+		for i, sub := range self.subs {
+			if sub == ch {
+				self.subs = append(self.subs[:i], self.subs[i+1:]...)
+				close(ch)
+				return
+			}
+		}
+	}
+}
+
+func (self *HeadWatcher) closeSubs() {
+	self.mtx.Lock()
+	defer self.mtx.Unlock()
+	for _, sub := range self.subs {
+		close(sub)
+	}
+	self.subs = nil
+}
+
+// ResubmitUntilIncluded runs the resubmission with the heads of the watcher
+// starting from the current head.
+func (self *HeadWatcher) ResubmitUntilIncluded(
+	ctx context.Context,
+	fb *Flashbot,
+	txsHex []string,
+	receipts ReceiptBackend,
+	cfg ResubmitConfig,
+) (*ResubmitResult, error) {
+	heads, unsubscribe := self.Subscribe(4)
+	defer unsubscribe()
+	head := self.Head()
+	if head == nil {
+		select {
+		case <-ctx.Done():
+			return nil, errors.Wrap(ctx.Err(), "wait for the first head")
+		case h, ok := <-heads:
+			if !ok {
+				return nil, errors.New("head watcher stopped")
+			}
+			head = h
+		}
+	}
+	return fb.ResubmitUntilIncluded(ctx, txsHex, head.Number.Uint64(), heads, receipts, cfg)
+}
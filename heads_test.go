@@ -0,0 +1,111 @@
+// Copyright (c) The Cryptorium Authors.
+// Licensed under the MIT License.
+
+package flashbot
+
+import (
+	"context"
+	"math/big"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/cryptoriums/packages/testutil"
+	"github.com/ethereum/go-ethereum"
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/event"
+	"github.com/ethereum/go-ethereum/rpc"
+)
+
+type pollingHeads struct {
+	mtx  sync.Mutex
+	head uint64
+}
+
+func (self *pollingHeads) HeaderByNumber(ctx context.Context, number *big.Int) (*types.Header, error) {
+	self.mtx.Lock()
+	defer self.mtx.Unlock()
+	return &types.Header{Number: new(big.Int).SetUint64(self.head), BaseFee: big.NewInt(7)}, nil
+}
+
+func (self *pollingHeads) set(head uint64) {
+	self.mtx.Lock()
+	defer self.mtx.Unlock()
+	self.head = head
+}
+
+type subscribingHeads struct {
+	pollingHeads
+	feed event.Feed
+	err  error
+}
+
+func (self *subscribingHeads) SubscribeNewHead(ctx context.Context, ch chan<- *types.Header) (ethereum.Subscription, error) {
+	if self.err != nil {
+		return nil, self.err
+	}
+	return self.feed.Subscribe(ch), nil
+}
+
+func TestHeadWatcherPolling(t *testing.T) {
+	ctx, cncl := context.WithCancel(context.Background())
+	backend := &pollingHeads{head: 10}
+	w := NewHeadWatcher(backend, time.Millisecond)
+	heads, _ := w.Subscribe(10)
+	done := make(chan struct{})
+	go func() {
+		w.Run(ctx)
+		close(done)
+	}()
+
+	h := <-heads
+	testutil.Equals(t, uint64(10), h.Number.Uint64())
+	testutil.Equals(t, big.NewInt(7), h.BaseFee)
+
+	backend.set(11)
+	h = <-heads
+	testutil.Equals(t, uint64(11), h.Number.Uint64())
+	testutil.Equals(t, uint64(11), w.Head().Number.Uint64())
+
+	cncl()
+	<-done
+	_, ok := <-heads
+	testutil.Assert(t, !ok, "subscriber channel should be closed")
+}
+
+func TestHeadWatcherSubscription(t *testing.T) {
+	ctx, cncl := context.WithCancel(context.Background())
+	defer cncl()
+	backend := &subscribingHeads{pollingHeads: pollingHeads{head: 10}}
+	// A long interval so that all following heads come from the subscription.
+	w := NewHeadWatcher(backend, time.Hour)
+	heads, unsubscribe := w.Subscribe(10)
+	go w.Run(ctx)
+
+	testutil.Equals(t, uint64(10), (<-heads).Number.Uint64())
+	for backend.feed.Send(&types.Header{Number: big.NewInt(11)}) == 0 {
+		time.Sleep(time.Millisecond)
+	}
+	testutil.Equals(t, uint64(11), (<-heads).Number.Uint64())
+	// Old heads are ignored.
+	backend.feed.Send(&types.Header{Number: big.NewInt(9)})
+	backend.feed.Send(&types.Header{Number: big.NewInt(12)})
+	testutil.Equals(t, uint64(12), (<-heads).Number.Uint64())
+
+	unsubscribe()
+	_, ok := <-heads
+	testutil.Assert(t, !ok, "subscriber channel should be closed")
+}
+
+func TestHeadWatcherUnsupportedSubscription(t *testing.T) {
+	ctx, cncl := context.WithCancel(context.Background())
+	defer cncl()
+	backend := &subscribingHeads{pollingHeads: pollingHeads{head: 10}, err: rpc.ErrNotificationsUnsupported}
+	w := NewHeadWatcher(backend, time.Millisecond)
+	heads, _ := w.Subscribe(10)
+	go w.Run(ctx)
+
+	testutil.Equals(t, uint64(10), (<-heads).Number.Uint64())
+	backend.set(11)
+	testutil.Equals(t, uint64(11), (<-heads).Number.Uint64())
+}
@@ -0,0 +1,155 @@
+// Copyright (c) The Cryptorium Authors.
+// Licensed under the MIT License.
+
+package flashbot
+
+import (
+	"context"
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/url"
+	"strconv"
+	"time"
+
+	"github.com/pkg/errors"
+)
+
+type MevShareHistoryInfo struct {
+	Count        uint64 `json:"count"`
+	MinBlock     uint64 `json:"minBlock"`
+	MaxBlock     uint64 `json:"maxBlock"`
+	MinTimestamp uint64 `json:"minTimestamp"`
+	MaxTimestamp uint64 `json:"maxTimestamp"`
+	MaxLimit     uint64 `json:"maxLimit"`
+}
+
+type MevShareHistoryEntry struct {
+	Block     uint64        `json:"block"`
+	Timestamp uint64        `json:"timestamp"`
+	Hint      MevShareEvent `json:"hint"`
+}
+
+// MevShareHistoryQuery filters the history, the zero values are not sent.
+type MevShareHistoryQuery struct {
+	BlockStart     uint64
+	BlockEnd       uint64
+	TimestampStart uint64
+	TimestampEnd   uint64
+	Limit          uint64
+	Offset         uint64
+}
+
+func (self MevShareHistoryQuery) values() url.Values {
+	v := url.Values{}
+	for _, p := range []struct {
+		key string
+		val uint64
+	}{
+		{"blockStart", self.BlockStart},
+		{"blockEnd", self.BlockEnd},
+		{"timestampStart", self.TimestampStart},
+		{"timestampEnd", self.TimestampEnd},
+		{"limit", self.Limit},
+		{"offset", self.Offset},
+	} {
+		if p.val != 0 {
+			v.Set(p.key, strconv.FormatUint(p.val, 10))
+		}
+	}
+	return v
+}
+
+// MevShareHistory is a client for the MEV-Share historical hints API.
+type MevShareHistory struct {
+	baseURL string
+	client  *http.Client
+}
+
+// NewMevShareHistory returns a client for the history API at the base URL, MevShareEventsURL by default.
+func NewMevShareHistory(baseURL string, client *http.Client) *MevShareHistory {
+	if baseURL == "" {
+		baseURL = MevShareEventsURL
+	}
+	if client == nil {
+		client = &http.Client{Timeout: 30 * time.Second}
+	}
+	return &MevShareHistory{baseURL: baseURL, client: client}
+}
+
+// Info returns the range and the size of the available history.
+func (self *MevShareHistory) Info(ctx context.Context) (*MevShareHistoryInfo, error) {
+	info := &MevShareHistoryInfo{}
+	if err := self.get(ctx, "/api/v1/history/info", nil, info); err != nil {
+		return nil, errors.Wrap(err, "mev-share history info")
+	}
+	return info, nil
+}
+
+// History returns one page of the historical hints.
+func (self *MevShareHistory) History(ctx context.Context, query MevShareHistoryQuery) ([]MevShareHistoryEntry, error) {
+	var entries []MevShareHistoryEntry
+	if err := self.get(ctx, "/api/v1/history", query.values(), &entries); err != nil {
+		return nil, errors.Wrap(err, "mev-share history")
+	}
+	return entries, nil
+}
+
+// EachHistory pages through the history matching the query and calls f for every entry.
+// The query limit is the page size, the max allowed by the API when zero.
+func (self *MevShareHistory) EachHistory(ctx context.Context, query MevShareHistoryQuery, f func(MevShareHistoryEntry) error) error {
+	if query.Limit == 0 {
+		info, err := self.Info(ctx)
+		if err != nil {
+			return err
+		}
+		query.Limit = info.MaxLimit
+	}
+	if query.Limit == 0 {
+		return errors.New("history page size can't be zero")
+	}
+	for {
+		entries, err := self.History(ctx, query)
+		if err != nil {
+			return err
+		}
+		for _, e := range entries {
+			if err := f(e); err != nil {
+				return err
+			}
+		}
+		if uint64(len(entries)) < query.Limit {
+			return nil
+		}
+		query.Offset += uint64(len(entries))
+	}
+}
+
+func (self *MevShareHistory) get(ctx context.Context, path string, query url.Values, dst interface{}) error {
+	u := self.baseURL + path
+	if len(query) > 0 {
+		u += "?" + query.Encode()
+	}
+	return getJSON(ctx, self.client, u, dst)
+}
+
+// getJSON sends a GET request and decodes the JSON response.
+func getJSON(ctx context.Context, client *http.Client, u string, dst interface{}) error {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, u, nil)
+	if err != nil {
+		return errors.Wrap(err, "create request")
+	}
+	resp, err := client.Do(req)
+	if err != nil {
+		return errors.Wrap(err, "request")
+	}
+	defer resp.Body.Close()
+	body, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return errors.Wrap(err, "read response")
+	}
+	if resp.StatusCode != http.StatusOK {
+		return errors.Errorf("bad response status:%v body:%v", resp.Status, string(body))
+	}
+	return errors.Wrapf(json.Unmarshal(body, dst), "unmarshal response:%v", string(body))
+}
@@ -0,0 +1,49 @@
+// Copyright (c) The Cryptorium Authors.
+// Licensed under the MIT License.
+
+package flashbot
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"testing"
+
+	"github.com/cryptoriums/packages/testutil"
+)
+
+func TestMevShareHistory(t *testing.T) {
+	ctx := context.Background()
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/api/v1/history/info":
+			testutil.Ok(t, json.NewEncoder(w).Encode(MevShareHistoryInfo{Count: 5, MinBlock: 1, MaxBlock: 5, MaxLimit: 2}))
+		case "/api/v1/history":
+			testutil.Equals(t, "3", r.URL.Query().Get("blockStart"))
+			limit, _ := strconv.Atoi(r.URL.Query().Get("limit"))
+			offset, _ := strconv.Atoi(r.URL.Query().Get("offset"))
+			var entries []MevShareHistoryEntry
+			for i := offset; i < offset+limit && i < 5; i++ {
+				entries = append(entries, MevShareHistoryEntry{Block: uint64(i + 1)})
+			}
+			testutil.Ok(t, json.NewEncoder(w).Encode(entries))
+		default:
+			w.WriteHeader(http.StatusNotFound)
+		}
+	}))
+	defer srv.Close()
+
+	h := NewMevShareHistory(srv.URL, nil)
+	info, err := h.Info(ctx)
+	testutil.Ok(t, err)
+	testutil.Equals(t, uint64(5), info.Count)
+
+	var blocks []uint64
+	testutil.Ok(t, h.EachHistory(ctx, MevShareHistoryQuery{BlockStart: 3}, func(e MevShareHistoryEntry) error {
+		blocks = append(blocks, e.Block)
+		return nil
+	}))
+	testutil.Equals(t, []uint64{1, 2, 3, 4, 5}, blocks)
+}
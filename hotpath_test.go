@@ -0,0 +1,129 @@
+// Copyright (c) The Cryptorium Authors.
+// Licensed under the MIT License.
+
+package flashbot
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/cryptoriums/packages/testutil"
+	"github.com/ethereum/go-ethereum/crypto"
+	"github.com/pkg/errors"
+)
+
+func TestEncodeMessage(t *testing.T) {
+	for _, params := range [][]interface{}{
+		nil,
+		{ParamsSend{Txs: []string{"0x01", "0x02"}, BlockNum: "0xa"}},
+		{"<html>&", 1, json.RawMessage(`{"a": 1}`)},
+	} {
+		msg, err := newMessage("eth_sendBundle", params...)
+		testutil.Ok(t, err)
+		exp, err := json.Marshal(msg)
+		testutil.Ok(t, err)
+
+		var buf bytes.Buffer
+		testutil.Ok(t, encodeMessage(&buf, StdCodec{}, "eth_sendBundle", params...))
+		testutil.Equals(t, string(exp), buf.String())
+	}
+}
+
+func TestSignatureHeader(t *testing.T) {
+	var bodies [][]byte
+	var headers []string
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var buf bytes.Buffer
+		_, err := buf.ReadFrom(r.Body)
+		testutil.Ok(t, err)
+		bodies = append(bodies, buf.Bytes())
+		headers = append(headers, r.Header.Get("X-Flashbots-Signature"))
+		_, _ = w.Write([]byte(`{"jsonrpc":"2.0","id":1,"result":{"bundleHash":"0xbundle"}}`))
+	}))
+	defer srv.Close()
+
+	fb := newTestFlashbot(t, srv.URL)
+	_, err := fb.SendBundle(context.Background(), []string{"0x01"}, 10)
+	testutil.Ok(t, err)
+
+	key, err := crypto.GenerateKey()
+	testutil.Ok(t, err)
+	next, err := NewKeySigner(key)
+	testutil.Ok(t, err)
+	prev := fb.Signer().Address()
+	testutil.Ok(t, fb.RotateAuthSigner(next, false))
+	_, err = fb.SendBundle(context.Background(), []string{"0x01"}, 10)
+	testutil.Ok(t, err)
+
+	// The cached address follows the signer rotation.
+	for i, exp := range []interface{}{prev, next.Address()} {
+		addr, err := VerifySignatureHeader(bodies[i], headers[i])
+		testutil.Ok(t, err)
+		testutil.Equals(t, exp, addr)
+	}
+}
+
+func BenchmarkSendBundle(b *testing.B) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		_, _ = w.Write([]byte(`{"jsonrpc":"2.0","id":1,"result":{"bundleHash":"0xbundle"}}`))
+	}))
+	defer srv.Close()
+
+	prvKey, err := crypto.GenerateKey()
+	testutil.Ok(b, err)
+	fb, err := New(prvKey, &Api{URL: srv.URL})
+	testutil.Ok(b, err)
+	txs := make([]string, 20)
+	for i := range txs {
+		txs[i] = "0x" + string(bytes.Repeat([]byte("ab"), 200))
+	}
+
+	b.ReportAllocs()
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		if _, err := fb.SendBundle(context.Background(), txs, 10); err != nil {
+			b.Fatal(err)
+		}
+	}
+}
+
+func TestMaxResponseSize(t *testing.T) {
+	reply := `{"jsonrpc":"2.0","id":1,"result":{"bundleHash":"0xbundle"}}`
+	for _, chunked := range []bool{false, true} {
+		srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			if r.URL.Path == "/error" {
+				w.WriteHeader(http.StatusInternalServerError)
+				_, _ = w.Write(bytes.Repeat([]byte("x"), 10000))
+				return
+			}
+			_, _ = w.Write([]byte(reply))
+			if chunked {
+				// Flushing before the handler returns drops the content length.
+				w.(http.Flusher).Flush()
+			}
+		}))
+
+		fb := newTestFlashbot(t, srv.URL)
+		fb.api.MaxResponseSize = int64(len(reply))
+		resp, err := fb.SendBundle(context.Background(), []string{"0x01"}, 10)
+		testutil.Ok(t, err)
+		testutil.Equals(t, "0xbundle", resp.BundleHash)
+
+		fb.api.MaxResponseSize = int64(len(reply) - 1)
+		_, err = fb.SendBundle(context.Background(), []string{"0x01"}, 10)
+		testutil.Assert(t, errors.Is(err, ErrResponseTooLarge), "chunked:%v unexpected error:%v", chunked, err)
+
+		// The dump of an error reply is cut at the max size.
+		fb.api.URL = srv.URL + "/error"
+		fb.api.MaxResponseSize = 100
+		_, err = fb.SendBundle(context.Background(), []string{"0x01"}, 10)
+		var statusErr *StatusError
+		testutil.Assert(t, errors.As(err, &statusErr), "unexpected error:%v", err)
+		testutil.Assert(t, len(statusErr.Msg) < 5000, "error dump should be limited:%v", len(statusErr.Msg))
+		srv.Close()
+	}
+}
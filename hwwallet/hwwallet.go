@@ -0,0 +1,120 @@
+// Copyright (c) The Cryptorium Authors.
+// Licensed under the MIT License.
+
+// Package hwwallet provides flashbot signers backed by Ledger and Trezor devices.
+//
+// Every signature requires a confirmation on the device which takes seconds,
+// so these signers are only suitable for low frequency, high value bundles like treasury rescues
+// and not for competitive searching.
+// The devices can't sign the personal messages used for the X-Flashbots-Signature header,
+// so use them as the TX signer together with a separate auth key (see flashbot.Flashbot.SetTxSigner).
+package hwwallet
+
+import (
+	"time"
+
+	"github.com/ethereum/go-ethereum/accounts"
+	"github.com/ethereum/go-ethereum/accounts/usbwallet"
+	"github.com/kachan28/flashbot"
+	"github.com/pkg/errors"
+)
+
+type Kind string
+
+const (
+	Ledger Kind = "ledger"
+	Trezor Kind = "trezor"
+)
+
+// Signer is a flashbot signer that holds an open device.
+type Signer struct {
+	flashbot.Signer
+	wallet accounts.Wallet
+}
+
+// Close releases the device.
+func (self *Signer) Close() error {
+	return self.wallet.Close()
+}
+
+// Unlock returns the secret requested by a locked Trezor device.
+// It is called with usbwallet.ErrTrezorPINNeeded for the PIN, entered as the positions
+// of the digits in the scrambled matrix shown on the device screen,
+// and with usbwallet.ErrTrezorPassphraseNeeded for the passphrase.
+type Unlock func(needed error) (string, error)
+
+// NewSigner opens the first connected device of the given kind
+// and derives the account at the derivation path, i.e. m/44'/60'/0'/0/0.
+// A locked Trezor device needs the unlock callback for the PIN and the passphrase,
+// it can be nil for Ledger devices which are unlocked on the device itself.
+func NewSigner(kind Kind, path string, unlock Unlock) (*Signer, error) {
+	dPath, err := accounts.ParseDerivationPath(path)
+	if err != nil {
+		return nil, errors.Wrapf(err, "parse derivation path:%v", path)
+	}
+
+	var hub *usbwallet.Hub
+	switch kind {
+	case Ledger:
+		hub, err = usbwallet.NewLedgerHub()
+	case Trezor:
+		hub, err = usbwallet.NewTrezorHubWithWebUSB()
+	default:
+		return nil, errors.Errorf("unsupported hardware wallet kind:%v", kind)
+	}
+	if err != nil {
+		return nil, errors.Wrapf(err, "create usb hub kind:%v", kind)
+	}
+
+	wallets := hub.Wallets()
+	if len(wallets) == 0 {
+		return nil, errors.Errorf("no connected device kind:%v", kind)
+	}
+	wallet := wallets[0]
+	if err := open(wallet, unlock); err != nil {
+		// Release the USB device, the hub itself stops with its last wallet.
+		_ = wallet.Close()
+		return nil, errors.Wrapf(err, "open device:%v", wallet.URL())
+	}
+
+	// The device self derivation runs in the background so wait for it to settle.
+	var account accounts.Account
+	for i := 0; ; i++ {
+		account, err = wallet.Derive(dPath, true)
+		if err == nil {
+			break
+		}
+		if i > 10 {
+			_ = wallet.Close()
+			return nil, errors.Wrapf(err, "derive account path:%v", path)
+		}
+		time.Sleep(500 * time.Millisecond)
+	}
+
+	signer, err := flashbot.NewWalletSigner(wallet, account)
+	if err != nil {
+		_ = wallet.Close()
+		return nil, err
+	}
+	return &Signer{Signer: signer, wallet: wallet}, nil
+}
+
+// open opens the wallet and answers the PIN and passphrase requests of a locked Trezor through unlock.
+func open(wallet accounts.Wallet, unlock Unlock) error {
+	err := wallet.Open("")
+	// A PIN request can be followed by a passphrase request so the device is asked at most twice.
+	for i := 0; i < 2 && err != nil; i++ {
+		if !errors.Is(err, usbwallet.ErrTrezorPINNeeded) && !errors.Is(err, usbwallet.ErrTrezorPassphraseNeeded) {
+			return err
+		}
+		if unlock == nil {
+			return errors.Wrap(err, "device is locked and no unlock callback is set")
+		}
+		secret, errU := unlock(err)
+		if errU != nil {
+			return errors.Wrap(errU, "unlock callback")
+		}
+		err = wallet.Open(secret)
+	}
+	return err
+}
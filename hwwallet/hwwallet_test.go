@@ -0,0 +1,47 @@
+// Copyright (c) The Cryptorium Authors.
+// Licensed under the MIT License.
+
+package hwwallet
+
+import (
+	"testing"
+
+	"github.com/cryptoriums/packages/testutil"
+	"github.com/ethereum/go-ethereum/accounts"
+	"github.com/ethereum/go-ethereum/accounts/usbwallet"
+	"github.com/pkg/errors"
+)
+
+// trezorMock replies to Open like a locked Trezor that needs a PIN and a passphrase.
+type trezorMock struct {
+	accounts.Wallet
+	opens []string
+}
+
+func (self *trezorMock) Open(passphrase string) error {
+	self.opens = append(self.opens, passphrase)
+	switch len(self.opens) {
+	case 1:
+		return usbwallet.ErrTrezorPINNeeded
+	case 2:
+		return usbwallet.ErrTrezorPassphraseNeeded
+	}
+	return nil
+}
+
+func TestOpenUnlock(t *testing.T) {
+	w := &trezorMock{}
+	var asked []error
+	testutil.Ok(t, open(w, func(needed error) (string, error) {
+		asked = append(asked, needed)
+		if errors.Is(needed, usbwallet.ErrTrezorPINNeeded) {
+			return "1234", nil
+		}
+		return "secret", nil
+	}))
+	testutil.Equals(t, []string{"", "1234", "secret"}, w.opens)
+	testutil.Equals(t, []error{usbwallet.ErrTrezorPINNeeded, usbwallet.ErrTrezorPassphraseNeeded}, asked)
+
+	testutil.NotOk(t, open(&trezorMock{}, nil))
+	testutil.NotOk(t, open(&trezorMock{}, func(needed error) (string, error) { return "", errors.New("canceled") }))
+}
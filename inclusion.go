@@ -0,0 +1,171 @@
+// Copyright (c) The Cryptorium Authors.
+// Licensed under the MIT License.
+
+package flashbot
+
+import (
+	"context"
+	"math/big"
+	"time"
+
+	"github.com/ethereum/go-ethereum"
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/pkg/errors"
+)
+
+// InclusionBackend is implemented by ethclient.Client and Node.
+type InclusionBackend interface {
+	ReceiptBackend
+	BlockNumber(ctx context.Context) (uint64, error)
+	HeaderByNumber(ctx context.Context, number *big.Int) (*types.Header, error)
+}
+
+type InclusionStatus string
+
+const (
+	InclusionPending   InclusionStatus = "pending"
+	InclusionIncluded  InclusionStatus = "included"
+	InclusionConfirmed InclusionStatus = "confirmed"
+	// InclusionReorged is reported when a previously included bundle is no longer in the canonical chain.
+	InclusionReorged InclusionStatus = "reorged"
+)
+
+type InclusionUpdate struct {
+	Status        InclusionStatus
+	BlockNum      uint64
+	BlockHash     common.Hash
+	Receipts      []*types.Receipt
+	Confirmations uint64
+	Err           error
+}
+
+// InclusionWatcher polls the chain to detect when a bundle lands on-chain
+// and waits for the given number of confirmations.
+type InclusionWatcher struct {
+	backend       InclusionBackend
+	confirmations uint64
+	interval      time.Duration
+}
+
+func NewInclusionWatcher(backend InclusionBackend, confirmations uint64, interval time.Duration) *InclusionWatcher {
+	if confirmations == 0 {
+		confirmations = 1
+	}
+	if interval <= 0 {
+		interval = time.Second
+	}
+	return &InclusionWatcher{backend: backend, confirmations: confirmations, interval: interval}
+}
+
+// Watch sends an update on every status change of the bundle with the given TX hashes.
+// The channel is closed once the bundle is confirmed or the context is done.
+// Polling errors are sent as updates with Err set and the watching continues.
+func (self *InclusionWatcher) Watch(ctx context.Context, txHashes []common.Hash) <-chan InclusionUpdate {
+	updates := make(chan InclusionUpdate, 1)
+	go func() {
+		defer close(updates)
+		send := func(u InclusionUpdate) bool {
+			select {
+			case updates <- u:
+				return true
+			case <-ctx.Done():
+				return false
+			}
+		}
+
+		ticker := time.NewTicker(self.interval)
+		defer ticker.Stop()
+
+		var last InclusionUpdate
+		for {
+			u, err := self.check(ctx, txHashes)
+			if err != nil {
+				if ctx.Err() != nil || !send(InclusionUpdate{Status: last.Status, Err: err}) {
+					return
+				}
+			} else {
+				if last.Status == InclusionIncluded && u.Status == InclusionPending {
+					u.Status = InclusionReorged
+				}
+				if u.Status != last.Status || u.BlockHash != last.BlockHash {
+					if !send(*u) {
+						return
+					}
+				}
+				if u.Status == InclusionConfirmed {
+					return
+				}
+				if u.Status == InclusionReorged {
+					u.Status = InclusionPending
+				}
+				last = *u
+			}
+
+			select {
+			case <-ctx.Done():
+				return
+			case <-ticker.C:
+			}
+		}
+	}()
+	return updates
+}
+
+// WaitConfirmed blocks until the bundle has the required confirmations.
+func (self *InclusionWatcher) WaitConfirmed(ctx context.Context, txHashes []common.Hash) (*InclusionUpdate, error) {
+	for u := range self.Watch(ctx, txHashes) {
+		if u.Status == InclusionConfirmed {
+			return &u, nil
+		}
+	}
+	return nil, errors.Wrap(ctx.Err(), "wait bundle confirmation")
+}
+
+func (self *InclusionWatcher) check(ctx context.Context, txHashes []common.Hash) (*InclusionUpdate, error) {
+	if len(txHashes) == 0 {
+		return nil, errors.New("bundle has no TXs")
+	}
+	receipts := make([]*types.Receipt, 0, len(txHashes))
+	for _, h := range txHashes {
+		r, err := self.backend.TransactionReceipt(ctx, h)
+		if err != nil {
+			if errors.Is(err, ethereum.NotFound) {
+				return &InclusionUpdate{Status: InclusionPending}, nil
+			}
+			return nil, errors.Wrapf(err, "get receipt TX:%v", h.Hex())
+		}
+		if len(receipts) > 0 && r.BlockHash != receipts[0].BlockHash {
+			// A partial inclusion means the TXs were mined outside of the bundle.
+			return nil, errors.Errorf("bundle TXs in different blocks TX:%v", h.Hex())
+		}
+		receipts = append(receipts, r)
+	}
+	blockNum := receipts[0].BlockNumber.Uint64()
+
+	header, err := self.backend.HeaderByNumber(ctx, receipts[0].BlockNumber)
+	if err != nil {
+		return nil, errors.Wrapf(err, "get header block:%v", blockNum)
+	}
+	if header.Hash() != receipts[0].BlockHash {
+		return &InclusionUpdate{Status: InclusionPending}, nil
+	}
+	head, err := self.backend.BlockNumber(ctx)
+	if err != nil {
+		return nil, errors.Wrap(err, "get head block number")
+	}
+
+	u := &InclusionUpdate{
+		Status:    InclusionIncluded,
+		BlockNum:  blockNum,
+		BlockHash: receipts[0].BlockHash,
+		Receipts:  receipts,
+	}
+	if head >= blockNum {
+		u.Confirmations = head - blockNum + 1
+	}
+	if u.Confirmations >= self.confirmations {
+		u.Status = InclusionConfirmed
+	}
+	return u, nil
+}
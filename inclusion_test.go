@@ -0,0 +1,95 @@
+// Copyright (c) The Cryptorium Authors.
+// Licensed under the MIT License.
+
+package flashbot
+
+import (
+	"context"
+	"math/big"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/cryptoriums/packages/testutil"
+	"github.com/ethereum/go-ethereum"
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/core/types"
+)
+
+// chainMock is a chain where every receipt request advances the head by one block.
+type chainMock struct {
+	mtx      sync.Mutex
+	polls    uint64
+	head     uint64
+	headers  map[uint64]*types.Header
+	receipts map[common.Hash]*types.Receipt
+	onPoll   func(c *chainMock, poll uint64)
+}
+
+func (self *chainMock) TransactionReceipt(ctx context.Context, txHash common.Hash) (*types.Receipt, error) {
+	self.mtx.Lock()
+	defer self.mtx.Unlock()
+	if self.onPoll != nil {
+		self.onPoll(self, self.polls)
+	}
+	self.polls++
+	r, ok := self.receipts[txHash]
+	if !ok {
+		return nil, ethereum.NotFound
+	}
+	return r, nil
+}
+
+func (self *chainMock) HeaderByNumber(ctx context.Context, number *big.Int) (*types.Header, error) {
+	self.mtx.Lock()
+	defer self.mtx.Unlock()
+	h, ok := self.headers[number.Uint64()]
+	if !ok {
+		return nil, ethereum.NotFound
+	}
+	return h, nil
+}
+
+func (self *chainMock) BlockNumber(ctx context.Context) (uint64, error) {
+	self.mtx.Lock()
+	defer self.mtx.Unlock()
+	return self.head, nil
+}
+
+func (self *chainMock) include(txHash common.Hash, blockNum uint64, extra []byte) {
+	header := &types.Header{Number: new(big.Int).SetUint64(blockNum), Extra: extra}
+	self.headers[blockNum] = header
+	self.receipts[txHash] = &types.Receipt{TxHash: txHash, BlockNumber: header.Number, BlockHash: header.Hash()}
+}
+
+func TestInclusionWatcher(t *testing.T) {
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	txHash := common.HexToHash("0x01")
+	chain := &chainMock{head: 10, headers: map[uint64]*types.Header{}, receipts: map[common.Hash]*types.Receipt{}}
+	chain.include(txHash, 10, []byte("a"))
+
+	chain.onPoll = func(c *chainMock, poll uint64) {
+		c.head = 10 + poll
+		switch poll {
+		case 1:
+			// Replace block 10 with a fork that doesn't have the TX.
+			c.headers[10] = &types.Header{Number: big.NewInt(10), Extra: []byte("b")}
+			delete(c.receipts, txHash)
+		case 2:
+			c.include(txHash, 12, []byte("c"))
+		}
+	}
+
+	var statuses []InclusionStatus
+	var last InclusionUpdate
+	for u := range NewInclusionWatcher(chain, 3, time.Millisecond).Watch(ctx, []common.Hash{txHash}) {
+		testutil.Ok(t, u.Err)
+		statuses = append(statuses, u.Status)
+		last = u
+	}
+	testutil.Equals(t, []InclusionStatus{InclusionIncluded, InclusionReorged, InclusionIncluded, InclusionConfirmed}, statuses)
+	testutil.Equals(t, uint64(3), last.Confirmations)
+	testutil.Equals(t, uint64(12), last.BlockNum)
+}
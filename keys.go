@@ -0,0 +1,176 @@
+// Copyright (c) The Cryptorium Authors.
+// Licensed under the MIT License.
+
+package flashbot
+
+import (
+	"crypto/ecdsa"
+	"crypto/hmac"
+	"crypto/sha512"
+	"encoding/binary"
+	"math/big"
+	"os"
+	"strings"
+
+	"github.com/ethereum/go-ethereum/accounts"
+	"github.com/ethereum/go-ethereum/accounts/keystore"
+	"github.com/ethereum/go-ethereum/common/math"
+	"github.com/ethereum/go-ethereum/console/prompt"
+	"github.com/ethereum/go-ethereum/crypto"
+	"github.com/pkg/errors"
+	"github.com/tyler-smith/go-bip39"
+)
+
+// KeystorePassEnvVar is the env var used for the keystore passphrase when one is not provided explicitly.
+const KeystorePassEnvVar = "FLASHBOT_KEYSTORE_PASS"
+
+// KeyFromKeystore decrypts a go-ethereum keystore file.
+// When the passphrase is empty it is read from the KeystorePassEnvVar env var
+// and when that is empty as well it is prompted for on the terminal.
+func KeyFromKeystore(path string, passphrase string) (*ecdsa.PrivateKey, error) {
+	keyJSON, err := os.ReadFile(path)
+	if err != nil {
+		return nil, errors.Wrapf(err, "reading the keystore file:%v", path)
+	}
+
+	if passphrase == "" {
+		passphrase = os.Getenv(KeystorePassEnvVar)
+	}
+	if passphrase == "" {
+		passphrase, err = prompt.Stdin.PromptPassword("Keystore passphrase: ")
+		if err != nil {
+			return nil, errors.Wrap(err, "prompt for the keystore passphrase")
+		}
+	}
+
+	key, err := keystore.DecryptKey(keyJSON, passphrase)
+	if err != nil {
+		return nil, errors.Wrapf(err, "decrypt the keystore file:%v", path)
+	}
+	return key.PrivateKey, nil
+}
+
+// NewFromKeystore creates an instance with the key loaded from an encrypted keystore file.
+// See KeyFromKeystore for how the passphrase is resolved.
+func NewFromKeystore(path string, passphrase string, api *Api) (Flashboter, error) {
+	prvKey, err := KeyFromKeystore(path, passphrase)
+	if err != nil {
+		return nil, err
+	}
+	return New(prvKey, api)
+}
+
+// KeyFromMnemonic derives a private key from a BIP-39 mnemonic at the given BIP-32 derivation path.
+// Use separate paths for the relay auth identity and the accounts that sign the bundle TXs
+// so that the reputation of the auth key is not tied to any on-chain activity.
+func KeyFromMnemonic(mnemonic string, passphrase string, path string) (*ecdsa.PrivateKey, error) {
+	dPath, err := accounts.ParseDerivationPath(path)
+	if err != nil {
+		return nil, errors.Wrapf(err, "parse derivation path:%v", path)
+	}
+	master, err := masterKeyFromMnemonic(mnemonic, passphrase)
+	if err != nil {
+		return nil, err
+	}
+	return master.derive(dPath)
+}
+
+// KeysFromMnemonic derives n consecutive private keys starting at the base derivation path,
+// i.e. m/44'/60'/0'/0/0, m/44'/60'/0'/0/1 and so on for the default base path.
+func KeysFromMnemonic(mnemonic string, passphrase string, basePath string, n int) ([]*ecdsa.PrivateKey, error) {
+	dPath, err := accounts.ParseDerivationPath(basePath)
+	if err != nil {
+		return nil, errors.Wrapf(err, "parse derivation path:%v", basePath)
+	}
+	master, err := masterKeyFromMnemonic(mnemonic, passphrase)
+	if err != nil {
+		return nil, err
+	}
+
+	var keys []*ecdsa.PrivateKey
+	next := accounts.DefaultIterator(dPath)
+	for i := 0; i < n; i++ {
+		p := next()
+		key, err := master.derive(p)
+		if err != nil {
+			return nil, errors.Wrapf(err, "derive key path:%v", p.String())
+		}
+		keys = append(keys, key)
+	}
+	return keys, nil
+}
+
+// NewFromMnemonic creates an instance with the key derived from a BIP-39 mnemonic.
+func NewFromMnemonic(mnemonic string, passphrase string, path string, api *Api) (Flashboter, error) {
+	prvKey, err := KeyFromMnemonic(mnemonic, passphrase, path)
+	if err != nil {
+		return nil, err
+	}
+	return New(prvKey, api)
+}
+
+type extendedKey struct {
+	key       *big.Int
+	chainCode []byte
+}
+
+func masterKeyFromMnemonic(mnemonic string, passphrase string) (*extendedKey, error) {
+	seed, err := bip39.NewSeedWithErrorChecking(strings.TrimSpace(mnemonic), passphrase)
+	if err != nil {
+		return nil, errors.Wrap(err, "mnemonic to seed")
+	}
+	mac := hmac.New(sha512.New, []byte("Bitcoin seed"))
+	if _, err := mac.Write(seed); err != nil {
+		return nil, errors.Wrap(err, "hmac seed")
+	}
+	sum := mac.Sum(nil)
+
+	key := new(big.Int).SetBytes(sum[:32])
+	if key.Sign() == 0 || key.Cmp(crypto.S256().Params().N) >= 0 {
+		return nil, errors.New("invalid master key")
+	}
+	return &extendedKey{key: key, chainCode: sum[32:]}, nil
+}
+
+// derive implements the BIP-32 private parent to private child key derivation.
+func (self *extendedKey) derive(path accounts.DerivationPath) (*ecdsa.PrivateKey, error) {
+	curveN := crypto.S256().Params().N
+	key := self.key
+	chainCode := self.chainCode
+	for _, index := range path {
+		var data []byte
+		if index >= hdHardenedOffset {
+			data = append([]byte{0}, math.PaddedBigBytes(key, 32)...)
+		} else {
+			prv, err := crypto.ToECDSA(math.PaddedBigBytes(key, 32))
+			if err != nil {
+				return nil, errors.Wrap(err, "parent key to ECDSA")
+			}
+			data = crypto.CompressPubkey(&prv.PublicKey)
+		}
+		idx := make([]byte, 4)
+		binary.BigEndian.PutUint32(idx, index)
+		data = append(data, idx...)
+
+		mac := hmac.New(sha512.New, chainCode)
+		if _, err := mac.Write(data); err != nil {
+			return nil, errors.Wrap(err, "hmac child key")
+		}
+		sum := mac.Sum(nil)
+
+		il := new(big.Int).SetBytes(sum[:32])
+		if il.Cmp(curveN) >= 0 {
+			return nil, errors.Errorf("invalid child key at index:%v", index)
+		}
+		child := new(big.Int).Add(il, key)
+		child.Mod(child, curveN)
+		if child.Sign() == 0 {
+			return nil, errors.Errorf("invalid child key at index:%v", index)
+		}
+		key = child
+		chainCode = sum[32:]
+	}
+	return crypto.ToECDSA(math.PaddedBigBytes(key, 32))
+}
+
+const hdHardenedOffset = 0x80000000
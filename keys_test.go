@@ -0,0 +1,73 @@
+// Copyright (c) The Cryptorium Authors.
+// Licensed under the MIT License.
+
+package flashbot
+
+import (
+	"path/filepath"
+	"testing"
+
+	"github.com/cryptoriums/packages/testutil"
+	"github.com/ethereum/go-ethereum/accounts/keystore"
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/crypto"
+)
+
+func TestKeyFromKeystore(t *testing.T) {
+	dir := t.TempDir()
+	ks := keystore.NewKeyStore(dir, keystore.LightScryptN, keystore.LightScryptP)
+
+	prvKey, err := crypto.GenerateKey()
+	testutil.Ok(t, err)
+	acc, err := ks.ImportECDSA(prvKey, "pass")
+	testutil.Ok(t, err)
+
+	path := filepath.Join(dir, filepath.Base(acc.URL.Path))
+	act, err := KeyFromKeystore(path, "pass")
+	testutil.Ok(t, err)
+	testutil.Equals(t, crypto.FromECDSA(prvKey), crypto.FromECDSA(act))
+
+	t.Setenv(KeystorePassEnvVar, "pass")
+	fb, err := NewFromKeystore(path, "", &Api{URL: "http://localhost"})
+	testutil.Ok(t, err)
+	testutil.Equals(t, acc.Address, fb.(*Flashbot).Signer().Address())
+
+	_, err = KeyFromKeystore(path, "wrong")
+	testutil.NotOk(t, err)
+}
+
+func TestKeysFromMnemonic(t *testing.T) {
+	const mnemonic = "test test test test test test test test test test test junk"
+
+	key, err := KeyFromMnemonic(mnemonic, "", "m/44'/60'/0'/0/0")
+	testutil.Ok(t, err)
+	testutil.Equals(t, common.HexToAddress("0xf39Fd6e51aad88F6F4ce6aB8827279cffFb92266"), crypto.PubkeyToAddress(key.PublicKey))
+
+	keys, err := KeysFromMnemonic(mnemonic, "", "m/44'/60'/0'/0/0", 2)
+	testutil.Ok(t, err)
+	testutil.Equals(t, 2, len(keys))
+	testutil.Equals(t, common.HexToAddress("0xf39Fd6e51aad88F6F4ce6aB8827279cffFb92266"), crypto.PubkeyToAddress(keys[0].PublicKey))
+	testutil.Equals(t, common.HexToAddress("0x70997970C51812dc3A010C7d01b50e0d17dc79C8"), crypto.PubkeyToAddress(keys[1].PublicKey))
+
+	_, err = KeyFromMnemonic("test test", "", "m/44'/60'/0'/0/0")
+	testutil.NotOk(t, err)
+}
+
+func TestRotateAuthKey(t *testing.T) {
+	prvKey, err := crypto.GenerateKey()
+	testutil.Ok(t, err)
+	fb, err := New(prvKey, &Api{URL: "http://localhost"})
+	testutil.Ok(t, err)
+	f := fb.(*Flashbot)
+
+	newKey, err := crypto.GenerateKey()
+	testutil.Ok(t, err)
+	testutil.Ok(t, f.RotateAuthKey(newKey, true))
+
+	testutil.Equals(t, crypto.PubkeyToAddress(newKey.PublicKey), f.Signer().Address())
+	testutil.Equals(t, crypto.PubkeyToAddress(prvKey.PublicKey), f.TxSigner().Address())
+	testutil.Equals(t, crypto.PubkeyToAddress(prvKey.PublicKey), f.PrevAuthSigner().Address())
+
+	testutil.Ok(t, f.RotateAuthKey(prvKey, false))
+	testutil.Equals(t, nil, f.PrevAuthSigner())
+}
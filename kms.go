@@ -0,0 +1,143 @@
+// Copyright (c) The Cryptorium Authors.
+// Licensed under the MIT License.
+
+package flashbot
+
+import (
+	"context"
+	"crypto/ecdsa"
+	"encoding/asn1"
+	"math/big"
+	"time"
+
+	"github.com/ethereum/go-ethereum/accounts"
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/crypto"
+	"github.com/pkg/errors"
+)
+
+// KMSBackend is implemented by the remote key management services
+// that hold a secp256k1 key and can sign digests with it.
+// See the awskms package for the AWS KMS implementation.
+type KMSBackend interface {
+	PublicKey(ctx context.Context) (*ecdsa.PublicKey, error)
+	// SignDigest signs a 32 byte digest and returns the ASN.1 DER encoded ECDSA signature.
+	SignDigest(ctx context.Context, digest []byte) ([]byte, error)
+}
+
+// DefaultKMSSignTimeout is the timeout of each signing request to the key management service.
+const DefaultKMSSignTimeout = 10 * time.Second
+
+type kmsSigner struct {
+	backend KMSBackend
+	pubKey  *ecdsa.PublicKey
+	addr    common.Address
+	timeout time.Duration
+}
+
+// NewKMSSigner returns a signer that delegates the signing to a key management service.
+// The context is used only for the public key lookup
+// and each signing request times out after DefaultKMSSignTimeout.
+func NewKMSSigner(ctx context.Context, backend KMSBackend) (Signer, error) {
+	if backend == nil {
+		return nil, errors.New("kms backend can't be empty")
+	}
+	pubKey, err := backend.PublicKey(ctx)
+	if err != nil {
+		return nil, errors.Wrap(err, "get kms public key")
+	}
+	return &kmsSigner{
+		backend: backend,
+		pubKey:  pubKey,
+		addr:    crypto.PubkeyToAddress(*pubKey),
+		timeout: DefaultKMSSignTimeout,
+	}, nil
+}
+
+func (self *kmsSigner) Address() common.Address {
+	return self.addr
+}
+
+func (self *kmsSigner) SignText(text []byte) ([]byte, error) {
+	return self.signHash(accounts.TextHash(text))
+}
+
+func (self *kmsSigner) SignTx(tx *types.Transaction, chainID *big.Int) (*types.Transaction, error) {
+	return self.SignTxWith(tx, types.LatestSignerForChainID(chainID))
+}
+
+func (self *kmsSigner) SignTxWith(tx *types.Transaction, chainSigner types.Signer) (*types.Transaction, error) {
+	h := chainSigner.Hash(tx)
+	sig, err := self.signHash(h[:])
+	if err != nil {
+		return nil, err
+	}
+	return tx.WithSignature(chainSigner, sig)
+}
+
+func (self *kmsSigner) signHash(hash []byte) ([]byte, error) {
+	ctx, cncl := context.WithTimeout(context.Background(), self.timeout)
+	defer cncl()
+	der, err := self.backend.SignDigest(ctx, hash)
+	if err != nil {
+		return nil, errors.Wrap(err, "kms sign digest")
+	}
+	return derToEthSignature(der, hash, self.pubKey)
+}
+
+var (
+	secp256k1N     = crypto.S256().Params().N
+	secp256k1HalfN = new(big.Int).Rsh(crypto.S256().Params().N, 1)
+)
+
+// derToEthSignature converts a DER encoded ECDSA signature to the [R || S || V] format.
+// S is normalized to the lower half of the curve order as required by EIP-2
+// and V is found by trying which recovery id gives back the expected public key.
+func derToEthSignature(der []byte, hash []byte, pubKey *ecdsa.PublicKey) ([]byte, error) {
+	var sigRS struct {
+		R *big.Int
+		S *big.Int
+	}
+	if _, err := asn1.Unmarshal(der, &sigRS); err != nil {
+		return nil, errors.Wrap(err, "decode DER signature")
+	}
+	if sigRS.S.Cmp(secp256k1HalfN) > 0 {
+		sigRS.S = new(big.Int).Sub(secp256k1N, sigRS.S)
+	}
+
+	sig := make([]byte, crypto.SignatureLength)
+	sigRS.R.FillBytes(sig[:32])
+	sigRS.S.FillBytes(sig[32:64])
+
+	exp := crypto.FromECDSAPub(pubKey)
+	for _, v := range []byte{0, 1} {
+		sig[64] = v
+		act, err := crypto.Ecrecover(hash, sig)
+		if err == nil && string(act) == string(exp) {
+			return sig, nil
+		}
+	}
+	return nil, errors.New("signature doesn't match the kms public key")
+}
+
+// ParsePKIXPublicKey decodes a DER SubjectPublicKeyInfo secp256k1 public key
+// as returned by most key management services.
+// The standard library x509 parser doesn't support the secp256k1 curve.
+func ParsePKIXPublicKey(der []byte) (*ecdsa.PublicKey, error) {
+	var info struct {
+		Algorithm struct {
+			Algorithm  asn1.ObjectIdentifier
+			Parameters asn1.ObjectIdentifier
+		}
+		PublicKey asn1.BitString
+	}
+	if _, err := asn1.Unmarshal(der, &info); err != nil {
+		return nil, errors.Wrap(err, "decode DER public key")
+	}
+	pubKey, err := crypto.UnmarshalPubkey(info.PublicKey.Bytes)
+	if err != nil {
+		return nil, errors.Wrap(err, "unmarshal secp256k1 public key")
+	}
+	return pubKey, nil
+}
@@ -0,0 +1,96 @@
+// Copyright (c) The Cryptorium Authors.
+// Licensed under the MIT License.
+
+package flashbot
+
+import (
+	"context"
+	"crypto/ecdsa"
+	"crypto/rand"
+	"math/big"
+	"testing"
+	"time"
+
+	"github.com/cryptoriums/packages/testutil"
+	"github.com/ethereum/go-ethereum/accounts"
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/crypto"
+	"github.com/pkg/errors"
+)
+
+type kmsBackendMock struct {
+	prvKey *ecdsa.PrivateKey
+}
+
+func (self *kmsBackendMock) PublicKey(ctx context.Context) (*ecdsa.PublicKey, error) {
+	return &self.prvKey.PublicKey, nil
+}
+
+func (self *kmsBackendMock) SignDigest(ctx context.Context, digest []byte) ([]byte, error) {
+	return ecdsa.SignASN1(rand.Reader, self.prvKey, digest)
+}
+
+func TestKMSSigner(t *testing.T) {
+	prvKey, err := crypto.GenerateKey()
+	testutil.Ok(t, err)
+	addr := crypto.PubkeyToAddress(prvKey.PublicKey)
+
+	signer, err := NewKMSSigner(context.Background(), &kmsBackendMock{prvKey: prvKey})
+	testutil.Ok(t, err)
+	testutil.Equals(t, addr, signer.Address())
+
+	// Sign multiple times to cover both recovery ids and the high S normalization.
+	for i := 0; i < 10; i++ {
+		sig, err := signer.SignText([]byte("foo"))
+		testutil.Ok(t, err)
+		pub, err := crypto.SigToPub(accounts.TextHash([]byte("foo")), sig)
+		testutil.Ok(t, err)
+		testutil.Equals(t, addr, crypto.PubkeyToAddress(*pub))
+	}
+
+	chainID := big.NewInt(5)
+	tx, err := signer.SignTx(types.NewTx(&types.DynamicFeeTx{
+		ChainID:   chainID,
+		Gas:       21_000,
+		GasFeeCap: big.NewInt(1),
+		To:        &addr,
+	}), chainID)
+	testutil.Ok(t, err)
+	sender, err := types.Sender(types.LatestSignerForChainID(chainID), tx)
+	testutil.Ok(t, err)
+	testutil.Equals(t, addr, sender)
+}
+
+type kmsBlockingMock struct {
+	kmsBackendMock
+}
+
+func (self *kmsBlockingMock) SignDigest(ctx context.Context, digest []byte) ([]byte, error) {
+	<-ctx.Done()
+	return nil, ctx.Err()
+}
+
+func TestKMSSignerTimeout(t *testing.T) {
+	prvKey, err := crypto.GenerateKey()
+	testutil.Ok(t, err)
+	signer, err := NewKMSSigner(context.Background(), &kmsBlockingMock{kmsBackendMock{prvKey: prvKey}})
+	testutil.Ok(t, err)
+	signer.(*kmsSigner).timeout = 10 * time.Millisecond
+	_, err = signer.SignText([]byte("foo"))
+	testutil.Assert(t, errors.Is(err, context.DeadlineExceeded), "unexpected error:%v", err)
+}
+
+func TestKMSSignerChainSigner(t *testing.T) {
+	prvKey, err := crypto.GenerateKey()
+	testutil.Ok(t, err)
+	addr := crypto.PubkeyToAddress(prvKey.PublicKey)
+	signer, err := NewKMSSigner(context.Background(), &kmsBackendMock{prvKey: prvKey})
+	testutil.Ok(t, err)
+
+	chainSigner := types.NewEIP155Signer(big.NewInt(5))
+	tx, err := signer.(chainSignerTx).SignTxWith(types.NewTx(&types.LegacyTx{Gas: 21_000, GasPrice: big.NewInt(1), To: &addr}), chainSigner)
+	testutil.Ok(t, err)
+	sender, err := types.Sender(chainSigner, tx)
+	testutil.Ok(t, err)
+	testutil.Equals(t, addr, sender)
+}
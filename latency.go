@@ -0,0 +1,191 @@
+// Copyright (c) The Cryptorium Authors.
+// Licensed under the MIT License.
+
+package flashbot
+
+import (
+	"sort"
+	"sync"
+	"time"
+)
+
+// LatencyBuckets are the upper bounds of the latency histogram buckets.
+var LatencyBuckets = []time.Duration{
+	time.Millisecond,
+	2 * time.Millisecond,
+	5 * time.Millisecond,
+	10 * time.Millisecond,
+	20 * time.Millisecond,
+	50 * time.Millisecond,
+	100 * time.Millisecond,
+	200 * time.Millisecond,
+	500 * time.Millisecond,
+	time.Second,
+	2 * time.Second,
+	5 * time.Second,
+	10 * time.Second,
+}
+
+// LatencyHistogram records request latencies in the LatencyBuckets and one more unbounded bucket.
+type LatencyHistogram struct {
+	mtx    sync.Mutex
+	counts []uint64
+	count  uint64
+	sum    time.Duration
+	max    time.Duration
+}
+
+func NewLatencyHistogram() *LatencyHistogram {
+	return &LatencyHistogram{counts: make([]uint64, len(LatencyBuckets)+1)}
+}
+
+func (self *LatencyHistogram) Observe(d time.Duration) {
+	i := sort.Search(len(LatencyBuckets), func(i int) bool { return d <= LatencyBuckets[i] })
+	self.mtx.Lock()
+	defer self.mtx.Unlock()
+	self.counts[i]++
+	self.count++
+	self.sum += d
+	if d > self.max {
+		self.max = d
+	}
+}
+
+func (self *LatencyHistogram) Count() uint64 {
+	self.mtx.Lock()
+	defer self.mtx.Unlock()
+	return self.count
+}
+
+// LatencyBucket is a histogram bucket with the cumulative count like in Prometheus.
+type LatencyBucket struct {
+	UpperBound time.Duration
+	Count      uint64
+}
+
+type LatencySnapshot struct {
+	Count uint64
+	Sum   time.Duration
+	Max   time.Duration
+	// Buckets don't include the unbounded one whose count is Count.
+	Buckets       []LatencyBucket
+	P50, P95, P99 time.Duration
+}
+
+// Snapshot returns the current state of the histogram with the quantiles estimated from the buckets.
+func (self *LatencyHistogram) Snapshot() LatencySnapshot {
+	self.mtx.Lock()
+	defer self.mtx.Unlock()
+	s := LatencySnapshot{Count: self.count, Sum: self.sum, Max: self.max}
+	var cum uint64
+	for i, b := range LatencyBuckets {
+		cum += self.counts[i]
+		s.Buckets = append(s.Buckets, LatencyBucket{UpperBound: b, Count: cum})
+	}
+	s.P50 = self.quantile(0.5)
+	s.P95 = self.quantile(0.95)
+	s.P99 = self.quantile(0.99)
+	return s
+}
+
+// Quantile estimates the latency quantile with a linear interpolation inside the bucket containing it.
+func (self *LatencyHistogram) Quantile(q float64) time.Duration {
+	self.mtx.Lock()
+	defer self.mtx.Unlock()
+	return self.quantile(q)
+}
+
+func (self *LatencyHistogram) quantile(q float64) time.Duration {
+	if self.count == 0 {
+		return 0
+	}
+	rank := q * float64(self.count)
+	var cum uint64
+	for i, c := range self.counts {
+		if c == 0 || float64(cum+c) < rank {
+			cum += c
+			continue
+		}
+		var lower time.Duration
+		if i > 0 {
+			lower = LatencyBuckets[i-1]
+		}
+		upper := self.max
+		if i < len(LatencyBuckets) && LatencyBuckets[i] < upper {
+			upper = LatencyBuckets[i]
+		}
+		if upper < lower {
+			return upper
+		}
+		return lower + time.Duration(float64(upper-lower)*(rank-float64(cum))/float64(c))
+	}
+	return self.max
+}
+
+// RelayLatency are the latency histograms of a relay.
+type RelayLatency struct {
+	Submit   *LatencyHistogram
+	Simulate *LatencyHistogram
+}
+
+func newRelayLatency() *RelayLatency {
+	return &RelayLatency{Submit: NewLatencyHistogram(), Simulate: NewLatencyHistogram()}
+}
+
+// Latency returns the submission and simulation latency histograms of the relay.
+// Only the successful requests are recorded, each retry attempt separately.
+func (self *Flashbot) Latency() *RelayLatency {
+	self.latencyOnce.Do(func() {
+		self.latency = newRelayLatency()
+	})
+	return self.latency
+}
+
+func (self *Flashbot) observeLatency(t RequestTiming) {
+	var (
+		h    *LatencyHistogram
+		name string
+	)
+	switch t.Kind {
+	case RequestSubmit:
+		h, name = self.Latency().Submit, MetricSubmitLatency
+	case RequestSimulate:
+		h, name = self.Latency().Simulate, MetricSimulateLatency
+	default:
+		return
+	}
+	h.Observe(t.Total)
+	if self.api.Metrics != nil {
+		self.api.Metrics.Observe(name, t.Total.Seconds(), "relay", self.api.URL)
+	}
+}
+
+// SortByLatency orders the relays by the quantile of their submission latency, fastest first.
+// The relays without any submissions go last in their original order.
+func SortByLatency(relays []Flashboter, q float64) []Flashboter {
+	type entry struct {
+		relay   Flashboter
+		latency time.Duration
+		known   bool
+	}
+	entries := make([]entry, len(relays))
+	for i, r := range relays {
+		entries[i].relay = r
+		if fb, ok := r.(*Flashbot); ok {
+			h := fb.Latency().Submit
+			entries[i].latency = h.Quantile(q)
+			entries[i].known = h.Count() > 0
+		}
+	}
+	sort.SliceStable(entries, func(i, j int) bool {
+		if entries[i].known != entries[j].known {
+			return entries[i].known
+		}
+		return entries[i].latency < entries[j].latency
+	})
+	res := make([]Flashboter, len(entries))
+	for i, e := range entries {
+		res[i] = e.relay
+	}
+	return res
+}
@@ -0,0 +1,66 @@
+// Copyright (c) The Cryptorium Authors.
+// Licensed under the MIT License.
+
+package flashbot
+
+import (
+	"context"
+	"encoding/json"
+	"testing"
+	"time"
+
+	"github.com/cryptoriums/packages/testutil"
+)
+
+func TestLatencyHistogram(t *testing.T) {
+	h := NewLatencyHistogram()
+	testutil.Equals(t, time.Duration(0), h.Quantile(0.5))
+
+	// 90 fast and 10 slow requests.
+	for i := 0; i < 90; i++ {
+		h.Observe(15 * time.Millisecond)
+	}
+	for i := 0; i < 10; i++ {
+		h.Observe(300 * time.Millisecond)
+	}
+	s := h.Snapshot()
+	testutil.Equals(t, uint64(100), s.Count)
+	testutil.Equals(t, 90*15*time.Millisecond+10*300*time.Millisecond, s.Sum)
+	testutil.Equals(t, 300*time.Millisecond, s.Max)
+	testutil.Equals(t, len(LatencyBuckets), len(s.Buckets))
+	testutil.Equals(t, LatencyBucket{UpperBound: 20 * time.Millisecond, Count: 90}, s.Buckets[4])
+	testutil.Equals(t, LatencyBucket{UpperBound: 500 * time.Millisecond, Count: 100}, s.Buckets[8])
+
+	testutil.Assert(t, s.P50 > 10*time.Millisecond && s.P50 <= 20*time.Millisecond, "p50:%v", s.P50)
+	testutil.Assert(t, s.P95 > 200*time.Millisecond && s.P95 <= 300*time.Millisecond, "p95:%v", s.P95)
+	testutil.Assert(t, s.P99 <= s.Max && s.P99 >= s.P95, "p99:%v", s.P99)
+
+	// Over the last bucket the max is used.
+	h.Observe(time.Minute)
+	testutil.Equals(t, time.Minute, h.Quantile(1))
+}
+
+func TestRelayLatency(t *testing.T) {
+	relay := newRelayMock(t, func(method string, params json.RawMessage) (interface{}, *jsonError) {
+		if method == "eth_callBundle" {
+			time.Sleep(5 * time.Millisecond)
+		}
+		return Result{BundleHash: "0xbundle"}, nil
+	})
+	fast := newTestFlashbot(t, relay.URL)
+	for i := 0; i < 3; i++ {
+		_, err := fast.SendBundle(context.Background(), []string{"0x01"}, 10)
+		testutil.Ok(t, err)
+	}
+	_, err := fast.CallBundle(context.Background(), []string{"0x01"}, 0)
+	testutil.Ok(t, err)
+	testutil.Equals(t, uint64(3), fast.Latency().Submit.Count())
+	testutil.Equals(t, uint64(1), fast.Latency().Simulate.Count())
+
+	slow := newTestFlashbot(t, relay.URL)
+	slow.Latency().Submit.Observe(time.Second)
+	idle := newTestFlashbot(t, relay.URL)
+
+	sorted := SortByLatency([]Flashboter{idle, slow, fast}, 0.99)
+	testutil.Equals(t, []Flashboter{fast, slow, idle}, sorted)
+}
@@ -0,0 +1,322 @@
+// Copyright (c) The Cryptorium Authors.
+// Licensed under the MIT License.
+
+// Package localsim simulates bundles locally with the go-ethereum EVM
+// against the state fetched on demand from an RPC node
+// so that the gas, the logs and the state changes are known without a relay round trip.
+//
+// The EVM is the one of the go-ethereum version used by the module
+// so the forks after London(PUSH0 etc.) aren't supported.
+package localsim
+
+import (
+	"bytes"
+	"context"
+	"math/big"
+	"sort"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/common/hexutil"
+	"github.com/ethereum/go-ethereum/core"
+	"github.com/ethereum/go-ethereum/core/rawdb"
+	"github.com/ethereum/go-ethereum/core/state"
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/core/vm"
+	"github.com/ethereum/go-ethereum/params"
+	"github.com/kachan28/flashbot"
+	"github.com/pkg/errors"
+)
+
+// Backend is the subset of the ethclient methods used to fetch the state.
+type Backend interface {
+	HeaderByNumber(ctx context.Context, number *big.Int) (*types.Header, error)
+	BalanceAt(ctx context.Context, account common.Address, blockNumber *big.Int) (*big.Int, error)
+	NonceAt(ctx context.Context, account common.Address, blockNumber *big.Int) (uint64, error)
+	CodeAt(ctx context.Context, account common.Address, blockNumber *big.Int) ([]byte, error)
+	StorageAt(ctx context.Context, account common.Address, key common.Hash, blockNumber *big.Int) ([]byte, error)
+}
+
+type Simulator struct {
+	backend Backend
+	config  *params.ChainConfig
+}
+
+// New creates a simulator.
+// When the config is nil it uses the mainnet config.
+func New(backend Backend, config *params.ChainConfig) *Simulator {
+	if config == nil {
+		config = params.MainnetChainConfig
+	}
+	return &Simulator{backend: backend, config: config}
+}
+
+type Opts struct {
+	// StateBlock is the block which state the bundle is executed on top of.
+	// When nil the latest block is used.
+	StateBlock *big.Int
+	// Coinbase overrides the fee recipient which is the state block coinbase by default.
+	Coinbase *common.Address
+	// Timestamp overrides the block time which is the state block time + 12 seconds by default.
+	Timestamp uint64
+	// Overrides replace the state block accounts fields.
+	// The state diff is relative to the overridden state.
+	Overrides flashbot.StateOverride
+}
+
+type TxResult struct {
+	Hash    common.Hash
+	From    common.Address
+	GasUsed uint64
+	// Err is the EVM execution error when the TX reverted.
+	Err          error
+	Revert       string
+	Logs         []*types.Log
+	CoinbaseDiff *big.Int
+	GasFees      *big.Int
+}
+
+type Result struct {
+	BlockNum     uint64
+	BaseFee      *big.Int
+	Coinbase     common.Address
+	GasUsed      uint64
+	CoinbaseDiff *big.Int
+	GasFees      *big.Int
+	Results      []TxResult
+	StateDiff    map[common.Address]*AccountDiff
+}
+
+// Simulate executes the bundle TXs in the block following the state block.
+// The execution stops at the first TX that can't be included at all like one with an invalid nonce,
+// while reverting TXs are recorded with their error.
+func (self *Simulator) Simulate(ctx context.Context, txsHex []string, opts Opts) (*Result, error) {
+	parent, err := self.backend.HeaderByNumber(ctx, opts.StateBlock)
+	if err != nil {
+		return nil, errors.Wrap(err, "get state block header")
+	}
+
+	header := &types.Header{
+		ParentHash: parent.Hash(),
+		Coinbase:   parent.Coinbase,
+		Number:     new(big.Int).Add(parent.Number, big.NewInt(1)),
+		GasLimit:   parent.GasLimit,
+		Time:       parent.Time + 12,
+		Difficulty: new(big.Int),
+		MixDigest:  parent.MixDigest,
+		BaseFee:    flashbot.NextBaseFee(parent),
+	}
+	if opts.Coinbase != nil {
+		header.Coinbase = *opts.Coinbase
+	}
+	if opts.Timestamp != 0 {
+		header.Time = opts.Timestamp
+	}
+
+	db, err := state.New(common.Hash{}, state.NewDatabase(rawdb.NewMemoryDatabase()), nil)
+	if err != nil {
+		return nil, errors.Wrap(err, "create in-memory state")
+	}
+	statedb := newRemoteState(ctx, self.backend, parent.Number, db)
+	statedb.override(opts.Overrides)
+	if statedb.err != nil {
+		return nil, statedb.err
+	}
+
+	getHash := func(n uint64) common.Hash {
+		h, err := self.backend.HeaderByNumber(ctx, new(big.Int).SetUint64(n))
+		if err != nil {
+			if statedb.err == nil {
+				statedb.err = errors.Wrapf(err, "get block hash:%v", n)
+			}
+			return common.Hash{}
+		}
+		return h.Hash()
+	}
+	blockCtx := vm.BlockContext{
+		CanTransfer: core.CanTransfer,
+		Transfer:    core.Transfer,
+		GetHash:     getHash,
+		Coinbase:    header.Coinbase,
+		BlockNumber: header.Number,
+		Time:        new(big.Int).SetUint64(header.Time),
+		Difficulty:  header.Difficulty,
+		BaseFee:     header.BaseFee,
+		GasLimit:    header.GasLimit,
+	}
+	if parent.Difficulty == nil || parent.Difficulty.Sign() == 0 {
+		random := parent.MixDigest
+		blockCtx.Random = &random
+	}
+	evm := vm.NewEVM(blockCtx, vm.TxContext{}, statedb, self.config, vm.Config{})
+	signer := types.MakeSigner(self.config, header.Number)
+	gp := new(core.GasPool).AddGas(header.GasLimit)
+
+	res := &Result{
+		BlockNum:     header.Number.Uint64(),
+		BaseFee:      header.BaseFee,
+		Coinbase:     header.Coinbase,
+		CoinbaseDiff: new(big.Int),
+		GasFees:      new(big.Int),
+	}
+	for i, txHex := range txsHex {
+		tx, err := flashbot.DecodeTx(txHex)
+		if err != nil {
+			return nil, errors.Wrapf(err, "decode TX index:%v", i)
+		}
+		msg, err := tx.AsMessage(signer, header.BaseFee)
+		if err != nil {
+			return nil, errors.Wrapf(err, "TX to message index:%v", i)
+		}
+
+		coinbaseBefore := new(big.Int).Set(statedb.GetBalance(header.Coinbase))
+		statedb.Prepare(tx.Hash(), i)
+		evm.Reset(core.NewEVMTxContext(msg), statedb)
+		exec, err := core.ApplyMessage(evm, msg, gp)
+		if statedb.err != nil {
+			return nil, statedb.err
+		}
+		if err != nil {
+			return nil, errors.Wrapf(err, "apply TX index:%v hash:%v", i, tx.Hash().Hex())
+		}
+		statedb.finaliseTx()
+
+		tip := new(big.Int).Sub(msg.GasPrice(), header.BaseFee)
+		txRes := TxResult{
+			Hash:         tx.Hash(),
+			From:         msg.From(),
+			GasUsed:      exec.UsedGas,
+			Err:          exec.Err,
+			Logs:         statedb.GetLogs(tx.Hash(), common.Hash{}),
+			CoinbaseDiff: new(big.Int).Sub(statedb.GetBalance(header.Coinbase), coinbaseBefore),
+			GasFees:      tip.Mul(tip, new(big.Int).SetUint64(exec.UsedGas)),
+		}
+		if revert := exec.Revert(); len(revert) > 0 {
+			txRes.Revert = hexutil.Encode(revert)
+			if reason, err := flashbot.DecodeRevertData(revert); err == nil {
+				txRes.Revert = reason.String()
+			}
+		}
+		res.Results = append(res.Results, txRes)
+		res.GasUsed += exec.UsedGas
+		res.CoinbaseDiff.Add(res.CoinbaseDiff, txRes.CoinbaseDiff)
+		res.GasFees.Add(res.GasFees, txRes.GasFees)
+	}
+	res.StateDiff = statedb.diff()
+	return res, nil
+}
+
+// CallBundle simulates the bundle on top of the state block, the latest when 0,
+// and returns the result in the relay shape so that the simulator can replace a relay,
+// i.e. as the flashbot.Replayer simulator with an archive node backend.
+func (self *Simulator) CallBundle(ctx context.Context, txsHex []string, blockNumState uint64) (*flashbot.Response, error) {
+	return self.CallBundleAt(ctx, txsHex, flashbot.CallOpts{StateBlock: blockNumState})
+}
+
+// CallBundleAt is the same as CallBundle with the simulated block fields set by the opts.
+// The simulated block is always the one after the state block.
+func (self *Simulator) CallBundleAt(ctx context.Context, txsHex []string, opts flashbot.CallOpts) (*flashbot.Response, error) {
+	simOpts := Opts{Coinbase: opts.Coinbase, Timestamp: opts.Timestamp, Overrides: opts.Overrides}
+	if opts.StateBlock != 0 {
+		simOpts.StateBlock = new(big.Int).SetUint64(opts.StateBlock)
+	}
+	if opts.BlockNum != 0 && opts.BlockNum != opts.StateBlock+1 {
+		return nil, errors.Errorf("simulated block:%v should be the one after the state block:%v", opts.BlockNum, opts.StateBlock)
+	}
+	res, err := self.Simulate(ctx, txsHex, simOpts)
+	if err != nil {
+		return nil, err
+	}
+	return &flashbot.Response{Result: *res.Relay()}, nil
+}
+
+// TouchedContracts simulates the bundle on top of the state block, the latest when 0,
+// and returns the TX recipients and the contracts that emitted logs or changed state.
+func (self *Simulator) TouchedContracts(ctx context.Context, txsHex []string, blockNumState uint64) ([]common.Address, error) {
+	var opts Opts
+	if blockNumState != 0 {
+		opts.StateBlock = new(big.Int).SetUint64(blockNumState)
+	}
+	res, err := self.Simulate(ctx, txsHex, opts)
+	if err != nil {
+		return nil, err
+	}
+	var (
+		touched []common.Address
+		seen    = map[common.Address]bool{res.Coinbase: true}
+	)
+	add := func(a common.Address) {
+		if !seen[a] {
+			seen[a] = true
+			touched = append(touched, a)
+		}
+	}
+	bundle, err := flashbot.DecodeBundle(txsHex)
+	if err != nil {
+		return nil, err
+	}
+	for _, tx := range bundle {
+		// The senders changed only their nonce and balance.
+		seen[tx.Sender] = true
+		if tx.Tx.To() != nil {
+			add(*tx.Tx.To())
+		}
+	}
+	for _, r := range res.Results {
+		for _, l := range r.Logs {
+			add(l.Address)
+		}
+	}
+	// Sorted so that the result doesn't depend on the map order.
+	var changed []common.Address
+	for a := range res.StateDiff {
+		changed = append(changed, a)
+	}
+	sort.Slice(changed, func(i, j int) bool { return bytes.Compare(changed[i][:], changed[j][:]) < 0 })
+	for _, a := range changed {
+		add(a)
+	}
+	return touched, nil
+}
+
+var (
+	_ flashbot.TouchedBackend      = (*Simulator)(nil)
+	_ flashbot.ReplaySimulator     = (*Simulator)(nil)
+	_ flashbot.HistoricalSimulator = (*Simulator)(nil)
+)
+
+// Relay converts the result to the shape returned by the relay eth_callBundle
+// so that both can be used interchangeably.
+func (self *Result) Relay() *flashbot.Result {
+	res := &flashbot.Result{
+		Metadata: flashbot.Metadata{
+			CoinbaseDiff:      self.CoinbaseDiff.String(),
+			EthSentToCoinbase: new(big.Int).Sub(self.CoinbaseDiff, self.GasFees).String(),
+			GasFees:           self.GasFees.String(),
+		},
+		BundleGasPrice: "0",
+	}
+	if self.GasUsed > 0 {
+		res.BundleGasPrice = new(big.Int).Div(self.CoinbaseDiff, new(big.Int).SetUint64(self.GasUsed)).String()
+	}
+	for _, r := range self.Results {
+		tx := flashbot.TxResult{
+			Metadata: flashbot.Metadata{
+				CoinbaseDiff:      r.CoinbaseDiff.String(),
+				EthSentToCoinbase: new(big.Int).Sub(r.CoinbaseDiff, r.GasFees).String(),
+				GasFees:           r.GasFees.String(),
+			},
+			FromAddress: r.From.Hex(),
+			TxHash:      r.Hash.Hex(),
+			Revert:      r.Revert,
+			GasUsed:     r.GasUsed,
+		}
+		if r.GasUsed > 0 {
+			tx.GasPrice = new(big.Int).Div(r.CoinbaseDiff, new(big.Int).SetUint64(r.GasUsed)).String()
+		}
+		if r.Err != nil {
+			tx.Error = r.Err.Error()
+		}
+		res.Results = append(res.Results, tx)
+	}
+	return res
+}
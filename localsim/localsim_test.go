@@ -0,0 +1,239 @@
+// Copyright (c) The Cryptorium Authors.
+// Licensed under the MIT License.
+
+package localsim
+
+import (
+	"context"
+	"math/big"
+	"testing"
+
+	"github.com/cryptoriums/packages/testutil"
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/common/hexutil"
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/crypto"
+	"github.com/ethereum/go-ethereum/params"
+	"github.com/kachan28/flashbot"
+)
+
+type backendMock struct {
+	header   *types.Header
+	balances map[common.Address]*big.Int
+	codes    map[common.Address][]byte
+	storage  map[common.Address]map[common.Hash]common.Hash
+	calls    int
+}
+
+func (self *backendMock) HeaderByNumber(ctx context.Context, number *big.Int) (*types.Header, error) {
+	return self.header, nil
+}
+
+func (self *backendMock) BalanceAt(ctx context.Context, account common.Address, blockNumber *big.Int) (*big.Int, error) {
+	self.calls++
+	if b, ok := self.balances[account]; ok {
+		return new(big.Int).Set(b), nil
+	}
+	return new(big.Int), nil
+}
+
+func (self *backendMock) NonceAt(ctx context.Context, account common.Address, blockNumber *big.Int) (uint64, error) {
+	return 0, nil
+}
+
+func (self *backendMock) CodeAt(ctx context.Context, account common.Address, blockNumber *big.Int) ([]byte, error) {
+	return self.codes[account], nil
+}
+
+func (self *backendMock) StorageAt(ctx context.Context, account common.Address, key common.Hash, blockNumber *big.Int) ([]byte, error) {
+	val := self.storage[account][key]
+	return val[:], nil
+}
+
+func TestSimulate(t *testing.T) {
+	prvKey, err := crypto.GenerateKey()
+	testutil.Ok(t, err)
+	from := crypto.PubkeyToAddress(prvKey.PublicKey)
+	coinbase := common.HexToAddress("0xc0")
+	store := common.HexToAddress("0xa1")
+	reverter := common.HexToAddress("0xa2")
+
+	backend := &backendMock{
+		header: &types.Header{
+			Number:     big.NewInt(100),
+			Coinbase:   coinbase,
+			GasLimit:   30_000_000,
+			GasUsed:    15_000_000,
+			BaseFee:    big.NewInt(params.GWei),
+			Difficulty: new(big.Int),
+		},
+		balances: map[common.Address]*big.Int{from: big.NewInt(params.Ether)},
+		codes: map[common.Address][]byte{
+			// Store 0x2a at slot 0 and emit an empty log.
+			store: common.FromHex("0x602a60005560006000a000"),
+			// Revert with an empty reason.
+			reverter: common.FromHex("0x60006000fd"),
+		},
+		storage: map[common.Address]map[common.Hash]common.Hash{
+			store: {{}: common.BigToHash(big.NewInt(7))},
+		},
+	}
+
+	config := params.AllEthashProtocolChanges
+	signer := types.LatestSignerForChainID(config.ChainID)
+	var txs []string
+	for i, to := range []common.Address{store, reverter} {
+		to := to
+		tx, err := types.SignNewTx(prvKey, signer, &types.DynamicFeeTx{
+			ChainID:   config.ChainID,
+			Nonce:     uint64(i),
+			GasTipCap: big.NewInt(2 * params.GWei),
+			GasFeeCap: big.NewInt(10 * params.GWei),
+			Gas:       100_000,
+			To:        &to,
+		})
+		testutil.Ok(t, err)
+		raw, err := tx.MarshalBinary()
+		testutil.Ok(t, err)
+		txs = append(txs, hexutil.Encode(raw))
+	}
+
+	res, err := New(backend, config).Simulate(context.Background(), txs, Opts{})
+	testutil.Ok(t, err)
+	testutil.Equals(t, uint64(101), res.BlockNum)
+	testutil.Equals(t, big.NewInt(params.GWei), res.BaseFee)
+	testutil.Equals(t, 2, len(res.Results))
+
+	testutil.Ok(t, res.Results[0].Err)
+	testutil.Equals(t, 1, len(res.Results[0].Logs))
+	testutil.Equals(t, store, res.Results[0].Logs[0].Address)
+	testutil.NotOk(t, res.Results[1].Err)
+	testutil.Equals(t, res.Results[0].GasUsed+res.Results[1].GasUsed, res.GasUsed)
+
+	fees := new(big.Int).Mul(big.NewInt(2*params.GWei), new(big.Int).SetUint64(res.GasUsed))
+	testutil.Equals(t, fees, res.CoinbaseDiff)
+	testutil.Equals(t, fees, res.GasFees)
+
+	diff := res.StateDiff[store]
+	testutil.Assert(t, diff != nil, "missing store contract diff")
+	testutil.Equals(t, [2]common.Hash{common.BigToHash(big.NewInt(7)), common.BigToHash(big.NewInt(42))}, diff.Storage[common.Hash{}])
+	testutil.Equals(t, uint64(2), res.StateDiff[from].NonceAfter)
+	testutil.Assert(t, res.StateDiff[reverter] == nil, "reverted contract shouldn't have a diff")
+
+	relay := res.Relay()
+	testutil.Equals(t, fees.String(), relay.CoinbaseDiff)
+	testutil.Equals(t, "0", relay.EthSentToCoinbase)
+	testutil.Equals(t, "2000000000", relay.BundleGasPrice)
+	testutil.Equals(t, res.Results[1].Err.Error(), relay.Results[1].Error)
+
+	// The replay of the bundle at its target block finds the reverting TX.
+	bundles := flashbot.NewMemoryStore()
+	testutil.Ok(t, bundles.Save(context.Background(), &flashbot.ManagedBundle{
+		ID:     "a",
+		Params: flashbot.ParamsSend{BlockNum: hexutil.EncodeUint64(101), Txs: txs},
+	}))
+	replay, err := flashbot.NewReplayer(bundles, New(backend, config)).Replay(context.Background(), "a")
+	testutil.Ok(t, err)
+	testutil.Equals(t, uint64(100), replay.StateBlock)
+	testutil.Equals(t, flashbot.ReplayReverted, replay.Outcome)
+	testutil.Equals(t, relay.Results, replay.Sim.Results)
+
+	_, err = New(backend, config).CallBundleAt(context.Background(), txs, flashbot.CallOpts{StateBlock: 100, BlockNum: 105})
+	testutil.NotOk(t, err)
+	other := common.HexToAddress("0xc1")
+	resp, err := New(backend, config).CallBundleAt(context.Background(), txs, flashbot.CallOpts{StateBlock: 100, Coinbase: &other})
+	testutil.Ok(t, err)
+	testutil.Equals(t, relay.CoinbaseDiff, resp.CoinbaseDiff)
+}
+
+func TestSimulateOverrides(t *testing.T) {
+	prvKey, err := crypto.GenerateKey()
+	testutil.Ok(t, err)
+	from := crypto.PubkeyToAddress(prvKey.PublicKey)
+	store := common.HexToAddress("0xa1")
+
+	backend := &backendMock{
+		header: &types.Header{
+			Number:     big.NewInt(100),
+			GasLimit:   30_000_000,
+			GasUsed:    15_000_000,
+			BaseFee:    big.NewInt(params.GWei),
+			Difficulty: new(big.Int),
+		},
+		storage: map[common.Address]map[common.Hash]common.Hash{
+			store: {{}: common.BigToHash(big.NewInt(7))},
+		},
+	}
+
+	config := params.AllEthashProtocolChanges
+	tx, err := types.SignNewTx(prvKey, types.LatestSignerForChainID(config.ChainID), &types.DynamicFeeTx{
+		ChainID:   config.ChainID,
+		Nonce:     5,
+		GasTipCap: big.NewInt(params.GWei),
+		GasFeeCap: big.NewInt(10 * params.GWei),
+		Gas:       100_000,
+		To:        &store,
+	})
+	testutil.Ok(t, err)
+	raw, err := tx.MarshalBinary()
+	testutil.Ok(t, err)
+
+	// The sender has no funds and the contract isn't deployed without the overrides.
+	_, err = New(backend, config).Simulate(context.Background(), []string{hexutil.Encode(raw)}, Opts{})
+	testutil.NotOk(t, err)
+
+	nonce := hexutil.Uint64(5)
+	code := hexutil.Bytes(common.FromHex("0x602a60005560006000a000"))
+	res, err := New(backend, config).Simulate(context.Background(), []string{hexutil.Encode(raw)}, Opts{
+		Overrides: flashbot.StateOverride{
+			from:  {Balance: (*hexutil.Big)(big.NewInt(params.Ether)), Nonce: &nonce},
+			store: {Code: &code, State: map[common.Hash]common.Hash{}},
+		},
+	})
+	testutil.Ok(t, err)
+	testutil.Ok(t, res.Results[0].Err)
+	testutil.Equals(t, [2]common.Hash{{}, common.BigToHash(big.NewInt(42))}, res.StateDiff[store].Storage[common.Hash{}])
+	testutil.Equals(t, big.NewInt(params.Ether), res.StateDiff[from].BalanceBefore)
+	testutil.Equals(t, uint64(6), res.StateDiff[from].NonceAfter)
+}
+
+func TestTouchedContracts(t *testing.T) {
+	prvKey, err := crypto.GenerateKey()
+	testutil.Ok(t, err)
+	from := crypto.PubkeyToAddress(prvKey.PublicKey)
+	pool := common.HexToAddress("0xa1")
+	bot := common.HexToAddress("0xb0")
+
+	backend := &backendMock{
+		header: &types.Header{
+			Number:     big.NewInt(100),
+			Coinbase:   common.HexToAddress("0xc0"),
+			GasLimit:   30_000_000,
+			BaseFee:    big.NewInt(params.GWei),
+			Difficulty: new(big.Int),
+		},
+		balances: map[common.Address]*big.Int{from: big.NewInt(params.Ether)},
+		codes: map[common.Address][]byte{
+			// Store 0x2a at slot 0 and emit an empty log.
+			pool: common.FromHex("0x602a60005560006000a000"),
+			// Call the pool without args: PUSH1 0 x5, PUSH20 pool, GAS, CALL, STOP.
+			bot: append(append(common.FromHex("0x6000600060006000600073"), pool.Bytes()...), common.FromHex("0x5af100")...),
+		},
+		storage: map[common.Address]map[common.Hash]common.Hash{},
+	}
+	config := params.AllEthashProtocolChanges
+	tx, err := types.SignNewTx(prvKey, types.LatestSignerForChainID(config.ChainID), &types.DynamicFeeTx{
+		ChainID:   config.ChainID,
+		GasTipCap: big.NewInt(2 * params.GWei),
+		GasFeeCap: big.NewInt(10 * params.GWei),
+		Gas:       100_000,
+		To:        &bot,
+	})
+	testutil.Ok(t, err)
+	raw, err := tx.MarshalBinary()
+	testutil.Ok(t, err)
+
+	touched, err := New(backend, config).TouchedContracts(context.Background(), []string{hexutil.Encode(raw)}, 100)
+	testutil.Ok(t, err)
+	testutil.Equals(t, []common.Address{bot, pool}, touched)
+}
@@ -0,0 +1,315 @@
+// Copyright (c) The Cryptorium Authors.
+// Licensed under the MIT License.
+
+package localsim
+
+import (
+	"bytes"
+	"context"
+	"math/big"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/core/state"
+	"github.com/kachan28/flashbot"
+	"github.com/pkg/errors"
+)
+
+type slotKey struct {
+	addr common.Address
+	key  common.Hash
+}
+
+type remoteAccount struct {
+	balance *big.Int
+	nonce   uint64
+	code    []byte
+}
+
+// remoteState is a vm.StateDB that loads the accounts and the storage slots from the node on the first access
+// into an in-memory state.
+// The loads are journaled by the in-memory state so the load marks are dropped on reverts
+// to load the values again when needed.
+type remoteState struct {
+	*state.StateDB
+	ctx      context.Context
+	backend  Backend
+	blockNum *big.Int
+
+	accounts map[common.Address]*remoteAccount
+	slots    map[slotKey]common.Hash
+	// noRemoteStorage are the accounts which storage is replaced by an override.
+	noRemoteStorage map[common.Address]bool
+
+	loaded      map[common.Address]bool
+	loadedSlots map[slotKey]bool
+	loadLog     []interface{}
+	snapLog     map[int]int
+	// txSlots are the slots loaded in the current TX which the in-memory state doesn't see as committed yet.
+	txSlots map[slotKey]common.Hash
+
+	err error
+}
+
+func newRemoteState(ctx context.Context, backend Backend, blockNum *big.Int, db *state.StateDB) *remoteState {
+	return &remoteState{
+		StateDB:         db,
+		ctx:             ctx,
+		backend:         backend,
+		blockNum:        blockNum,
+		accounts:        make(map[common.Address]*remoteAccount),
+		slots:           make(map[slotKey]common.Hash),
+		noRemoteStorage: make(map[common.Address]bool),
+		loaded:          make(map[common.Address]bool),
+		loadedSlots:     make(map[slotKey]bool),
+		snapLog:         make(map[int]int),
+		txSlots:         make(map[slotKey]common.Hash),
+	}
+}
+
+func (self *remoteState) remoteAccount(addr common.Address) *remoteAccount {
+	if acc, ok := self.accounts[addr]; ok {
+		return acc
+	}
+	acc := &remoteAccount{balance: new(big.Int)}
+	if self.err == nil {
+		var err error
+		if acc.balance, err = self.backend.BalanceAt(self.ctx, addr, self.blockNum); err != nil {
+			self.err = errors.Wrapf(err, "get balance account:%v", addr.Hex())
+			acc.balance = new(big.Int)
+		} else if acc.nonce, err = self.backend.NonceAt(self.ctx, addr, self.blockNum); err != nil {
+			self.err = errors.Wrapf(err, "get nonce account:%v", addr.Hex())
+		} else if acc.code, err = self.backend.CodeAt(self.ctx, addr, self.blockNum); err != nil {
+			self.err = errors.Wrapf(err, "get code account:%v", addr.Hex())
+		}
+	}
+	self.accounts[addr] = acc
+	return acc
+}
+
+func (self *remoteState) ensure(addr common.Address) {
+	if self.loaded[addr] {
+		return
+	}
+	self.loaded[addr] = true
+	self.loadLog = append(self.loadLog, addr)
+
+	acc := self.remoteAccount(addr)
+	if acc.balance.Sign() == 0 && acc.nonce == 0 && len(acc.code) == 0 {
+		// Non existing accounts aren't created so that Exist and Empty stay correct.
+		return
+	}
+	self.StateDB.SetBalance(addr, acc.balance)
+	self.StateDB.SetNonce(addr, acc.nonce)
+	if len(acc.code) > 0 {
+		self.StateDB.SetCode(addr, acc.code)
+	}
+}
+
+func (self *remoteState) ensureSlot(addr common.Address, key common.Hash) {
+	self.ensure(addr)
+	k := slotKey{addr: addr, key: key}
+	if self.loadedSlots[k] {
+		return
+	}
+	self.loadedSlots[k] = true
+	self.loadLog = append(self.loadLog, k)
+
+	val, ok := self.slots[k]
+	if !ok {
+		if self.err == nil && !self.noRemoteStorage[addr] {
+			raw, err := self.backend.StorageAt(self.ctx, addr, key, self.blockNum)
+			if err != nil {
+				self.err = errors.Wrapf(err, "get storage account:%v slot:%v", addr.Hex(), key.Hex())
+			}
+			val = common.BytesToHash(raw)
+		}
+		self.slots[k] = val
+	}
+	if val != (common.Hash{}) {
+		self.StateDB.SetState(addr, key, val)
+		self.txSlots[k] = val
+	}
+}
+
+// override applies the overrides to the remote values
+// so that they are used as the state before the bundle.
+func (self *remoteState) override(overrides flashbot.StateOverride) {
+	for addr, o := range overrides {
+		acc := self.remoteAccount(addr)
+		if o.Balance != nil {
+			acc.balance = new(big.Int).Set(o.Balance.ToInt())
+		}
+		if o.Nonce != nil {
+			acc.nonce = uint64(*o.Nonce)
+		}
+		if o.Code != nil {
+			acc.code = *o.Code
+		}
+		if o.State != nil {
+			self.noRemoteStorage[addr] = true
+			for k, v := range o.State {
+				self.slots[slotKey{addr: addr, key: k}] = v
+			}
+		}
+		for k, v := range o.StateDiff {
+			self.slots[slotKey{addr: addr, key: k}] = v
+		}
+	}
+}
+
+// finaliseTx makes the loaded slots committed for the next TX.
+func (self *remoteState) finaliseTx() {
+	self.StateDB.Finalise(true)
+	self.txSlots = make(map[slotKey]common.Hash)
+}
+
+func (self *remoteState) Snapshot() int {
+	id := self.StateDB.Snapshot()
+	self.snapLog[id] = len(self.loadLog)
+	return id
+}
+
+func (self *remoteState) RevertToSnapshot(id int) {
+	self.StateDB.RevertToSnapshot(id)
+	n, ok := self.snapLog[id]
+	if !ok {
+		return
+	}
+	for _, e := range self.loadLog[n:] {
+		switch e := e.(type) {
+		case common.Address:
+			delete(self.loaded, e)
+		case slotKey:
+			delete(self.loadedSlots, e)
+			delete(self.txSlots, e)
+		}
+	}
+	self.loadLog = self.loadLog[:n]
+}
+
+func (self *remoteState) CreateAccount(addr common.Address) {
+	self.ensure(addr)
+	self.StateDB.CreateAccount(addr)
+}
+
+func (self *remoteState) SubBalance(addr common.Address, amount *big.Int) {
+	self.ensure(addr)
+	self.StateDB.SubBalance(addr, amount)
+}
+
+func (self *remoteState) AddBalance(addr common.Address, amount *big.Int) {
+	self.ensure(addr)
+	self.StateDB.AddBalance(addr, amount)
+}
+
+func (self *remoteState) GetBalance(addr common.Address) *big.Int {
+	self.ensure(addr)
+	return self.StateDB.GetBalance(addr)
+}
+
+func (self *remoteState) GetNonce(addr common.Address) uint64 {
+	self.ensure(addr)
+	return self.StateDB.GetNonce(addr)
+}
+
+func (self *remoteState) SetNonce(addr common.Address, nonce uint64) {
+	self.ensure(addr)
+	self.StateDB.SetNonce(addr, nonce)
+}
+
+func (self *remoteState) GetCodeHash(addr common.Address) common.Hash {
+	self.ensure(addr)
+	return self.StateDB.GetCodeHash(addr)
+}
+
+func (self *remoteState) GetCode(addr common.Address) []byte {
+	self.ensure(addr)
+	return self.StateDB.GetCode(addr)
+}
+
+func (self *remoteState) SetCode(addr common.Address, code []byte) {
+	self.ensure(addr)
+	self.StateDB.SetCode(addr, code)
+}
+
+func (self *remoteState) GetCodeSize(addr common.Address) int {
+	self.ensure(addr)
+	return self.StateDB.GetCodeSize(addr)
+}
+
+func (self *remoteState) GetCommittedState(addr common.Address, key common.Hash) common.Hash {
+	self.ensureSlot(addr, key)
+	if val, ok := self.txSlots[slotKey{addr: addr, key: key}]; ok {
+		return val
+	}
+	return self.StateDB.GetCommittedState(addr, key)
+}
+
+func (self *remoteState) GetState(addr common.Address, key common.Hash) common.Hash {
+	self.ensureSlot(addr, key)
+	return self.StateDB.GetState(addr, key)
+}
+
+func (self *remoteState) SetState(addr common.Address, key common.Hash, value common.Hash) {
+	self.ensureSlot(addr, key)
+	self.StateDB.SetState(addr, key, value)
+}
+
+func (self *remoteState) Suicide(addr common.Address) bool {
+	self.ensure(addr)
+	return self.StateDB.Suicide(addr)
+}
+
+func (self *remoteState) HasSuicided(addr common.Address) bool {
+	self.ensure(addr)
+	return self.StateDB.HasSuicided(addr)
+}
+
+func (self *remoteState) Exist(addr common.Address) bool {
+	self.ensure(addr)
+	return self.StateDB.Exist(addr)
+}
+
+func (self *remoteState) Empty(addr common.Address) bool {
+	self.ensure(addr)
+	return self.StateDB.Empty(addr)
+}
+
+// AccountDiff is the change of an account caused by the bundle.
+type AccountDiff struct {
+	BalanceBefore *big.Int
+	BalanceAfter  *big.Int
+	NonceBefore   uint64
+	NonceAfter    uint64
+	CodeChanged   bool
+	// Storage has the changed slots with their before and after values.
+	Storage map[common.Hash][2]common.Hash
+}
+
+func (self *remoteState) diff() map[common.Address]*AccountDiff {
+	res := make(map[common.Address]*AccountDiff)
+	for addr, acc := range self.accounts {
+		// Loads reverted by a failed TX need to be redone to compare against the remote values.
+		self.ensure(addr)
+		d := &AccountDiff{
+			BalanceBefore: acc.balance,
+			BalanceAfter:  self.StateDB.GetBalance(addr),
+			NonceBefore:   acc.nonce,
+			NonceAfter:    self.StateDB.GetNonce(addr),
+			CodeChanged:   !bytes.Equal(self.StateDB.GetCode(addr), acc.code),
+			Storage:       make(map[common.Hash][2]common.Hash),
+		}
+		for k, before := range self.slots {
+			if k.addr != addr {
+				continue
+			}
+			if after := self.GetState(addr, k.key); after != before {
+				d.Storage[k.key] = [2]common.Hash{before, after}
+			}
+		}
+		if d.BalanceBefore.Cmp(d.BalanceAfter) != 0 || d.NonceBefore != d.NonceAfter || d.CodeChanged || len(d.Storage) > 0 {
+			res[addr] = d
+		}
+	}
+	return res
+}
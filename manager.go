@@ -0,0 +1,439 @@
+// Copyright (c) The Cryptorium Authors.
+// Licensed under the MIT License.
+
+package flashbot
+
+import (
+	"context"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/common/hexutil"
+	"github.com/ethereum/go-ethereum/crypto"
+	"github.com/google/uuid"
+	"github.com/pkg/errors"
+)
+
+type BundleState string
+
+const (
+	BundleBuilt     BundleState = "built"
+	BundleSimulated BundleState = "simulated"
+	BundleSubmitted BundleState = "submitted"
+	BundlePending   BundleState = "pending"
+	BundleIncluded  BundleState = "included"
+	BundleDropped   BundleState = "dropped"
+	BundleExpired   BundleState = "expired"
+	BundleCancelled BundleState = "cancelled"
+	BundleFailed    BundleState = "failed"
+)
+
+// bundleTransitions lists the allowed next states for each state.
+// Expired and dropped bundles can be submitted again for a later block.
+var bundleTransitions = map[BundleState][]BundleState{
+	BundleBuilt:     {BundleSimulated, BundleSubmitted, BundleCancelled, BundleFailed},
+	BundleSimulated: {BundleSimulated, BundleSubmitted, BundleCancelled, BundleFailed},
+	BundleSubmitted: {BundlePending, BundleFailed},
+	BundlePending:   {BundleIncluded, BundleDropped, BundleExpired, BundleCancelled, BundleSubmitted},
+	BundleIncluded:  {BundleDropped},
+	BundleDropped:   {BundleSubmitted},
+	BundleExpired:   {BundleSubmitted},
+	BundleCancelled: {},
+	BundleFailed:    {BundleSubmitted, BundleCancelled},
+}
+
+// Terminal returns whether the bundle won't change state without an explicit resubmission.
+func (self BundleState) Terminal() bool {
+	switch self {
+	case BundleIncluded, BundleDropped, BundleExpired, BundleCancelled, BundleFailed:
+		return true
+	}
+	return false
+}
+
+func (self BundleState) canTransition(next BundleState) bool {
+	for _, s := range bundleTransitions[self] {
+		if s == next {
+			return true
+		}
+	}
+	return false
+}
+
+type BundleStateChange struct {
+	State BundleState
+	Time  time.Time
+	Block uint64 `json:",omitempty"`
+	Err   string `json:",omitempty"`
+}
+
+// ManagedBundle is a snapshot of a bundle owned by the BundleManager.
+type ManagedBundle struct {
+	// ID is the replacement UUID of the bundle.
+	ID string
+	// Relay is the URL of the relay the bundle is sent to.
+	Relay      string
+	Params     ParamsSend
+	TxHashes   []common.Hash
+	BundleHash string
+	State      BundleState
+	History    []BundleStateChange
+	SimResult  *Response
+	SendResult *Response
+	// IncludedBlock is set once the bundle is included.
+	IncludedBlock uint64
+	CreatedAt     time.Time
+	UpdatedAt     time.Time
+}
+
+// TargetBlock returns the block number the bundle targets.
+func (self *ManagedBundle) TargetBlock() uint64 {
+	blockNum, _ := hexutil.DecodeUint64(self.Params.BlockNum)
+	return blockNum
+}
+
+func (self *ManagedBundle) copy() *ManagedBundle {
+	c := *self
+	c.Params.Txs = append([]string(nil), self.Params.Txs...)
+	c.Params.RevertingTxHashes = append([]string(nil), self.Params.RevertingTxHashes...)
+	c.TxHashes = append([]common.Hash(nil), self.TxHashes...)
+	c.History = append([]BundleStateChange(nil), self.History...)
+	return &c
+}
+
+// BundleManager owns the submitted bundles and tracks each of them as a state machine:
+// built → simulated → submitted → pending → included/dropped/expired.
+// Every bundle gets a replacement UUID which is used as its ID so that it can be canceled at the relay.
+type BundleManager struct {
+	fbMtx   sync.RWMutex
+	fb      Flashboter
+	mtx     sync.RWMutex
+	bundles map[string]*ManagedBundle
+	byHash  map[string]string
+	store   BundleStore
+	// drain tracks the running submissions for Close.
+	drain drain
+
+	evMtx sync.RWMutex
+	hooks BundleHooks
+	subs  []chan BundleEvent
+}
+
+func NewBundleManager(fb Flashboter) *BundleManager {
+	return &BundleManager{
+		fb:      fb,
+		bundles: make(map[string]*ManagedBundle),
+		byHash:  make(map[string]string),
+	}
+}
+
+// Add registers a bundle in the built state and returns its ID.
+func (self *BundleManager) Add(params ParamsSend) (string, error) {
+	if !self.drain.enter() {
+		return "", ErrClosed
+	}
+	defer self.drain.leave()
+	if len(params.Txs) == 0 {
+		return "", errors.New("bundle has no TXs")
+	}
+	if _, err := hexutil.DecodeUint64(params.BlockNum); err != nil {
+		return "", errors.Wrapf(err, "decode bundle block number:%v", params.BlockNum)
+	}
+	if params.ReplacementUUID == "" {
+		params.ReplacementUUID = uuid.NewString()
+	}
+
+	hashes := make([]common.Hash, 0, len(params.Txs))
+	for i, txHex := range params.Txs {
+		raw, err := hexutil.Decode(txHex)
+		if err != nil {
+			return "", errors.Wrapf(err, "decode TX index:%v", i)
+		}
+		hashes = append(hashes, crypto.Keccak256Hash(raw))
+	}
+
+	self.mtx.Lock()
+	if _, ok := self.bundles[params.ReplacementUUID]; ok {
+		self.mtx.Unlock()
+		return "", errors.Errorf("bundle already exists id:%v", params.ReplacementUUID)
+	}
+	now := time.Now()
+	self.bundles[params.ReplacementUUID] = &ManagedBundle{
+		ID:        params.ReplacementUUID,
+		Relay:     self.relay(),
+		Params:    params,
+		TxHashes:  hashes,
+		State:     BundleBuilt,
+		History:   []BundleStateChange{{State: BundleBuilt, Time: now}},
+		CreatedAt: now,
+		UpdatedAt: now,
+	}
+	self.mtx.Unlock()
+	if err := self.persist(params.ReplacementUUID); err != nil {
+		return params.ReplacementUUID, err
+	}
+	return params.ReplacementUUID, nil
+}
+
+// Simulate runs the bundle through CallBundle and moves it to the simulated or the failed state.
+func (self *BundleManager) Simulate(ctx context.Context, id string) (*Response, error) {
+	if !self.drain.enter() {
+		return nil, ErrClosed
+	}
+	defer self.drain.leave()
+	b, err := self.Get(id)
+	if err != nil {
+		return nil, err
+	}
+	resp, err := self.client().CallBundle(ctx, b.Params.Txs, 0)
+	if err != nil {
+		errT := self.transition(id, BundleFailed, 0, err)
+		self.emit(BundleEvent{Type: EventError, Err: err}, id)
+		if errT != nil {
+			return nil, errors.Wrapf(err, "state transition:%v", errT)
+		}
+		return nil, err
+	}
+	if err := self.transitionWith(id, BundleSimulated, 0, nil, func(b *ManagedBundle) { b.SimResult = resp }); err != nil {
+		return resp, err
+	}
+	self.emit(BundleEvent{Type: EventSimulated, Response: resp}, id)
+	return resp, nil
+}
+
+// Submit sends the bundle and moves it to the pending state when the relay accepts it.
+func (self *BundleManager) Submit(ctx context.Context, id string) (*Response, error) {
+	if !self.drain.enter() {
+		return nil, ErrClosed
+	}
+	defer self.drain.leave()
+	if err := self.transition(id, BundleSubmitted, 0, nil); err != nil {
+		return nil, err
+	}
+	b, err := self.Get(id)
+	if err != nil {
+		return nil, err
+	}
+	resp, err := self.client().SendBundleParams(ctx, b.Params)
+	if err != nil {
+		errT := self.transition(id, BundleFailed, 0, err)
+		self.emit(BundleEvent{Type: EventError, Block: b.TargetBlock(), Err: err}, id)
+		if errT != nil {
+			return nil, errors.Wrapf(err, "state transition:%v", errT)
+		}
+		return nil, err
+	}
+
+	self.mtx.Lock()
+	if mb, ok := self.bundles[id]; ok {
+		mb.SendResult = resp
+		if resp.BundleHash != "" {
+			mb.BundleHash = resp.BundleHash
+			self.byHash[resp.BundleHash] = id
+		}
+	}
+	self.mtx.Unlock()
+
+	if err := self.transition(id, BundlePending, 0, nil); err != nil {
+		return resp, err
+	}
+	self.emit(BundleEvent{Type: EventSubmitted, Block: b.TargetBlock(), Response: resp}, id)
+	return resp, nil
+}
+
+// Retarget changes the target block of a bundle that is not pending or included anymore
+// so it can be submitted again.
+func (self *BundleManager) Retarget(id string, blockNum uint64) error {
+	self.mtx.Lock()
+	b, ok := self.bundles[id]
+	if !ok {
+		self.mtx.Unlock()
+		return errors.Errorf("bundle not found id:%v", id)
+	}
+	if !b.State.canTransition(BundleSubmitted) {
+		self.mtx.Unlock()
+		return errors.Errorf("bundle can't be retargeted in state:%v id:%v", b.State, id)
+	}
+	b.Params.BlockNum = hexutil.EncodeUint64(blockNum)
+	b.UpdatedAt = time.Now()
+	self.mtx.Unlock()
+	return self.persist(id)
+}
+
+// Cancel cancels the bundle at the relay through its replacement UUID.
+func (self *BundleManager) Cancel(ctx context.Context, id string) error {
+	if !self.drain.enter() {
+		return ErrClosed
+	}
+	defer self.drain.leave()
+	b, err := self.Get(id)
+	if err != nil {
+		return err
+	}
+	if !b.State.canTransition(BundleCancelled) {
+		return errors.Errorf("bundle can't be canceled in state:%v id:%v", b.State, id)
+	}
+	if b.State == BundlePending {
+		if _, err := self.client().CancelBundle(ctx, b.ID); err != nil {
+			return errors.Wrapf(err, "cancel bundle id:%v", id)
+		}
+	}
+	return self.transition(id, BundleCancelled, 0, nil)
+}
+
+// MarkIncluded records that the bundle landed in the block.
+func (self *BundleManager) MarkIncluded(id string, blockNum uint64) error {
+	if err := self.transitionWith(id, BundleIncluded, blockNum, nil, func(b *ManagedBundle) { b.IncludedBlock = blockNum }); err != nil {
+		return err
+	}
+	self.emit(BundleEvent{Type: EventIncluded, Block: blockNum}, id)
+	return nil
+}
+
+// MarkDropped records that the bundle won't land, i.e. rejected by the builders or reorged out.
+func (self *BundleManager) MarkDropped(id string, reason error) error {
+	if err := self.transitionWith(id, BundleDropped, 0, reason, func(b *ManagedBundle) { b.IncludedBlock = 0 }); err != nil {
+		return err
+	}
+	self.emit(BundleEvent{Type: EventDropped, Err: reason}, id)
+	return nil
+}
+
+// Expire moves all pending bundles with a target block lower than or equal to the head to the expired state
+// and returns their IDs. Call it after the inclusion of the head block was checked.
+func (self *BundleManager) Expire(head uint64) []string {
+	var expired []string
+	for _, b := range self.List(BundlePending) {
+		if b.TargetBlock() <= head {
+			if err := self.applyTransition(b.ID, BundleExpired, head, nil, nil); err == nil {
+				// Expire can't report errors so a failed save is retried with the next change.
+				_ = self.persist(b.ID)
+				expired = append(expired, b.ID)
+				self.emit(BundleEvent{Type: EventDropped, Block: head}, b.ID)
+			}
+		}
+	}
+	return expired
+}
+
+// Get returns a snapshot of the bundle.
+func (self *BundleManager) Get(id string) (*ManagedBundle, error) {
+	self.mtx.RLock()
+	defer self.mtx.RUnlock()
+	b, ok := self.bundles[id]
+	if !ok {
+		return nil, errors.Errorf("bundle not found id:%v", id)
+	}
+	return b.copy(), nil
+}
+
+// GetByHash returns a snapshot of the bundle with the relay returned bundle hash.
+func (self *BundleManager) GetByHash(bundleHash string) (*ManagedBundle, error) {
+	self.mtx.RLock()
+	id, ok := self.byHash[bundleHash]
+	self.mtx.RUnlock()
+	if !ok {
+		return nil, errors.Errorf("bundle not found hash:%v", bundleHash)
+	}
+	return self.Get(id)
+}
+
+// List returns snapshots of the bundles in any of the states or all bundles when no states are given
+// sorted by creation time.
+func (self *BundleManager) List(states ...BundleState) []*ManagedBundle {
+	self.mtx.RLock()
+	defer self.mtx.RUnlock()
+
+	var res []*ManagedBundle
+	for _, b := range self.bundles {
+		if len(states) == 0 || containsState(states, b.State) {
+			res = append(res, b.copy())
+		}
+	}
+	sort.Slice(res, func(i, j int) bool { return res[i].CreatedAt.Before(res[j].CreatedAt) })
+	return res
+}
+
+// Remove forgets a bundle in a terminal state.
+func (self *BundleManager) Remove(id string) error {
+	self.mtx.Lock()
+	defer self.mtx.Unlock()
+	b, ok := self.bundles[id]
+	if !ok {
+		return errors.Errorf("bundle not found id:%v", id)
+	}
+	if !b.State.Terminal() {
+		return errors.Errorf("bundle can't be removed in state:%v id:%v", b.State, id)
+	}
+	delete(self.bundles, id)
+	delete(self.byHash, b.BundleHash)
+	// The store keeps the bundle for the history.
+	return nil
+}
+
+// SetClient replaces the relay client, i.e. after a config reload, while keeping all bundles.
+func (self *BundleManager) SetClient(fb Flashboter) {
+	self.fbMtx.Lock()
+	defer self.fbMtx.Unlock()
+	self.fb = fb
+}
+
+func (self *BundleManager) client() Flashboter {
+	self.fbMtx.RLock()
+	defer self.fbMtx.RUnlock()
+	return self.fb
+}
+
+func (self *BundleManager) relay() string {
+	if api := self.client().Api(); api != nil {
+		return api.URL
+	}
+	return ""
+}
+
+func (self *BundleManager) transition(id string, next BundleState, blockNum uint64, cause error) error {
+	return self.transitionWith(id, next, blockNum, cause, nil)
+}
+
+// transitionWith also applies the set func to the bundle, only when the transition is allowed.
+func (self *BundleManager) transitionWith(id string, next BundleState, blockNum uint64, cause error, set func(b *ManagedBundle)) error {
+	if err := self.applyTransition(id, next, blockNum, cause, set); err != nil {
+		return err
+	}
+	return self.persist(id)
+}
+
+func (self *BundleManager) applyTransition(id string, next BundleState, blockNum uint64, cause error, set func(b *ManagedBundle)) error {
+	self.mtx.Lock()
+	defer self.mtx.Unlock()
+
+	b, ok := self.bundles[id]
+	if !ok {
+		return errors.Errorf("bundle not found id:%v", id)
+	}
+	if !b.State.canTransition(next) {
+		return errors.Errorf("invalid bundle state transition from:%v to:%v id:%v", b.State, next, id)
+	}
+	if set != nil {
+		set(b)
+	}
+	change := BundleStateChange{State: next, Time: time.Now(), Block: blockNum}
+	if cause != nil {
+		change.Err = cause.Error()
+	}
+	b.State = next
+	b.History = append(b.History, change)
+	b.UpdatedAt = change.Time
+	return nil
+}
+
+func containsState(states []BundleState, state BundleState) bool {
+	for _, s := range states {
+		if s == state {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,196 @@
+// Copyright (c) The Cryptorium Authors.
+// Licensed under the MIT License.
+
+package flashbot
+
+import (
+	"context"
+	"encoding/json"
+	"math/big"
+	"testing"
+
+	"github.com/cryptoriums/packages/testutil"
+)
+
+func TestBundleManager(t *testing.T) {
+	ctx := context.Background()
+	relay := newRelayMock(t, func(method string, params json.RawMessage) (interface{}, *jsonError) {
+		return Result{BundleHash: "0xbundle"}, nil
+	})
+	fb := newTestFlashbot(t, relay.URL)
+
+	to := fb.TxSigner().Address()
+	params, err := fb.NewBundleBuilder(ctx, 5).
+		Nonce(0).
+		AddTx(TxSpec{To: &to, Gas: 21_000, GasFeeCap: big.NewInt(1)}).
+		TargetBlock(10).
+		Build()
+	testutil.Ok(t, err)
+
+	m := NewBundleManager(fb)
+	id, err := m.Add(params)
+	testutil.Ok(t, err)
+
+	_, err = m.Simulate(ctx, id)
+	testutil.Ok(t, err)
+	_, err = m.Submit(ctx, id)
+	testutil.Ok(t, err)
+
+	b, err := m.GetByHash("0xbundle")
+	testutil.Ok(t, err)
+	testutil.Equals(t, BundlePending, b.State)
+	testutil.Equals(t, id, b.Params.ReplacementUUID)
+
+	testutil.Equals(t, 0, len(m.Expire(9)))
+	testutil.Equals(t, []string{id}, m.Expire(10))
+
+	// Expired bundles can only be submitted again.
+	testutil.NotOk(t, m.MarkIncluded(id, 10))
+	b, err = m.Get(id)
+	testutil.Ok(t, err)
+	testutil.Equals(t, uint64(0), b.IncludedBlock)
+	testutil.Ok(t, m.Retarget(id, 11))
+	_, err = m.Submit(ctx, id)
+	testutil.Ok(t, err)
+	testutil.Ok(t, m.Cancel(ctx, id))
+
+	b, err = m.Get(id)
+	testutil.Ok(t, err)
+	testutil.Equals(t, BundleCancelled, b.State)
+	testutil.Equals(t, uint64(11), b.TargetBlock())
+	testutil.Equals(t, []string{"eth_callBundle", "eth_sendBundle", "eth_sendBundle", "eth_cancelBundle"}, relay.Methods())
+}
+
+func TestBundleManagerSetClient(t *testing.T) {
+	ctx := context.Background()
+	handler := func(method string, params json.RawMessage) (interface{}, *jsonError) {
+		return Result{BundleHash: "0xbundle"}, nil
+	}
+	relay := newRelayMock(t, handler)
+	fb := newTestFlashbot(t, relay.URL)
+
+	m := NewBundleManager(fb)
+	id, err := m.Add(ParamsSend{Txs: []string{"0x01"}, BlockNum: "0xa"})
+	testutil.Ok(t, err)
+	_, err = m.Submit(ctx, id)
+	testutil.Ok(t, err)
+
+	// The bundle survives the client swap and the new client is used for the following requests.
+	newRelay := newRelayMock(t, handler)
+	m.SetClient(newTestFlashbot(t, newRelay.URL))
+	testutil.Equals(t, []string{id}, m.Expire(10))
+	testutil.Ok(t, m.Retarget(id, 11))
+	_, err = m.Submit(ctx, id)
+	testutil.Ok(t, err)
+
+	testutil.Equals(t, []string{"eth_sendBundle"}, relay.Methods())
+	testutil.Equals(t, []string{"eth_sendBundle"}, newRelay.Methods())
+}
+
+func TestBundleManagerEvents(t *testing.T) {
+	ctx := context.Background()
+	relay := newRelayMock(t, func(method string, params json.RawMessage) (interface{}, *jsonError) {
+		return Result{BundleHash: "0xbundle"}, nil
+	})
+	fb := newTestFlashbot(t, relay.URL)
+
+	to := fb.TxSigner().Address()
+	params, err := fb.NewBundleBuilder(ctx, 5).
+		Nonce(0).
+		AddTx(TxSpec{To: &to, Gas: 21_000, GasFeeCap: big.NewInt(1)}).
+		TargetBlock(10).
+		Build()
+	testutil.Ok(t, err)
+
+	m := NewBundleManager(fb)
+	var included []BundleEvent
+	m.SetHooks(BundleHooks{
+		OnIncluded: func(e BundleEvent) { included = append(included, e) },
+		// The hooks can subscribe and unsubscribe without a deadlock.
+		OnConsidered: func(e BundleEvent) {
+			_, unsubscribe := m.Subscribe(1)
+			unsubscribe()
+		},
+	})
+	events, unsubscribe := m.Subscribe(10)
+
+	id, err := m.Add(params)
+	testutil.Ok(t, err)
+	_, err = m.Simulate(ctx, id)
+	testutil.Ok(t, err)
+	_, err = m.Submit(ctx, id)
+	testutil.Ok(t, err)
+	testutil.Ok(t, m.MarkConsidered(id, "builder0x69"))
+	testutil.Ok(t, m.MarkIncluded(id, 10))
+	unsubscribe()
+
+	var types []BundleEventType
+	for e := range events {
+		types = append(types, e.Type)
+	}
+	testutil.Equals(t, []BundleEventType{EventSimulated, EventSubmitted, EventConsidered, EventIncluded}, types)
+	testutil.Equals(t, 1, len(included))
+	testutil.Equals(t, uint64(10), included[0].Block)
+	testutil.Equals(t, BundleIncluded, included[0].Bundle.State)
+}
+
+func TestBundleManagerRestore(t *testing.T) {
+	ctx := context.Background()
+	relay := newRelayMock(t, func(method string, params json.RawMessage) (interface{}, *jsonError) {
+		return Result{BundleHash: "0xbundle"}, nil
+	})
+	fb := newTestFlashbot(t, relay.URL)
+
+	to := fb.TxSigner().Address()
+	params, err := fb.NewBundleBuilder(ctx, 5).
+		Nonce(0).
+		AddTx(TxSpec{To: &to, Gas: 21_000, GasFeeCap: big.NewInt(1)}).
+		TargetBlock(10).
+		Build()
+	testutil.Ok(t, err)
+
+	store := NewMemoryStore()
+	m := NewBundleManager(fb)
+	m.SetStore(store)
+	id, err := m.Add(params)
+	testutil.Ok(t, err)
+	_, err = m.Submit(ctx, id)
+	testutil.Ok(t, err)
+
+	stored, err := store.Get(ctx, id)
+	testutil.Ok(t, err)
+	testutil.Equals(t, BundlePending, stored.State)
+	testutil.Equals(t, "0xbundle", stored.BundleHash)
+
+	// A new manager after a crash picks up the in-flight bundle.
+	m = NewBundleManager(fb)
+	m.SetStore(store)
+	ids, err := m.Restore(ctx)
+	testutil.Ok(t, err)
+	testutil.Equals(t, []string{id}, ids)
+	testutil.Ok(t, m.MarkIncluded(id, 10))
+
+	stored, err = store.Get(ctx, id)
+	testutil.Ok(t, err)
+	testutil.Equals(t, BundleIncluded, stored.State)
+	testutil.Equals(t, uint64(10), stored.IncludedBlock)
+
+	// A crash during the submission leaves the bundle as submitted
+	// and it's restored as pending since the relay might have received it.
+	id, err = m.Add(params)
+	testutil.Ok(t, err)
+	testutil.Ok(t, m.transition(id, BundleSubmitted, 0, nil))
+	m = NewBundleManager(fb)
+	m.SetStore(store)
+	ids, err = m.Restore(ctx)
+	testutil.Ok(t, err)
+	testutil.Equals(t, []string{id}, ids)
+	b, err := m.Get(id)
+	testutil.Ok(t, err)
+	testutil.Equals(t, BundlePending, b.State)
+	testutil.Equals(t, errInterruptedSubmit.Error(), b.History[len(b.History)-1].Err)
+	stored, err = store.Get(ctx, id)
+	testutil.Ok(t, err)
+	testutil.Equals(t, BundlePending, stored.State)
+	testutil.Equals(t, []string{id}, m.Expire(10))
+}
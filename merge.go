@@ -0,0 +1,93 @@
+// Copyright (c) The Cryptorium Authors.
+// Licensed under the MIT License.
+
+package flashbot
+
+import (
+	"context"
+	"strings"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/common/hexutil"
+	"github.com/ethereum/go-ethereum/crypto"
+	"github.com/pkg/errors"
+)
+
+type senderNonce struct {
+	sender common.Address
+	nonce  uint64
+}
+
+// MergeBundles merges the bundles targeting the same block into one.
+// The TXs keep their order with duplicates removed, the revert allowances of every bundle are kept
+// and the timestamp ranges are intersected.
+// It fails when two different TXs use the same sender and nonce since only one of them can land.
+func MergeBundles(bundles ...ParamsSend) (ParamsSend, error) {
+	if len(bundles) == 0 {
+		return ParamsSend{}, errors.New("no bundles to merge")
+	}
+	merged := ParamsSend{BlockNum: bundles[0].BlockNum}
+	seenTxs := make(map[common.Hash]bool)
+	seenReverts := make(map[string]bool)
+	nonces := make(map[senderNonce]common.Hash)
+
+	for i, b := range bundles {
+		if b.BlockNum != merged.BlockNum {
+			return ParamsSend{}, errors.Errorf("bundle index:%v targets block:%v instead of:%v", i, b.BlockNum, merged.BlockNum)
+		}
+		for j, txHex := range b.Txs {
+			raw, err := hexutil.Decode(txHex)
+			if err != nil {
+				return ParamsSend{}, errors.Wrapf(err, "decode TX bundle index:%v TX index:%v", i, j)
+			}
+			hash := crypto.Keccak256Hash(raw)
+			if seenTxs[hash] {
+				continue
+			}
+			// Set code TXs can't be decoded here so they are only deduplicated by hash.
+			if tx, err := DecodeTx(txHex); err == nil {
+				sender, err := TxSender(tx)
+				if err != nil {
+					return ParamsSend{}, err
+				}
+				key := senderNonce{sender: sender, nonce: tx.Nonce()}
+				if other, ok := nonces[key]; ok {
+					return ParamsSend{}, errors.Errorf("nonce conflict sender:%v nonce:%v TXs:%v,%v", sender.Hex(), key.nonce, other.Hex(), hash.Hex())
+				}
+				nonces[key] = hash
+			}
+			seenTxs[hash] = true
+			merged.Txs = append(merged.Txs, txHex)
+		}
+		for _, h := range b.RevertingTxHashes {
+			if !seenReverts[strings.ToLower(h)] {
+				seenReverts[strings.ToLower(h)] = true
+				merged.RevertingTxHashes = append(merged.RevertingTxHashes, h)
+			}
+		}
+		if b.MinTimestamp > merged.MinTimestamp {
+			merged.MinTimestamp = b.MinTimestamp
+		}
+		if b.MaxTimestamp != 0 && (merged.MaxTimestamp == 0 || b.MaxTimestamp < merged.MaxTimestamp) {
+			merged.MaxTimestamp = b.MaxTimestamp
+		}
+	}
+	if merged.MaxTimestamp != 0 && merged.MinTimestamp > merged.MaxTimestamp {
+		return ParamsSend{}, errors.Errorf("bundles timestamp ranges don't overlap min:%v max:%v", merged.MinTimestamp, merged.MaxTimestamp)
+	}
+	return merged, nil
+}
+
+// MergeAndSimulate merges the bundles and simulates the result
+// since the merged TXs can interact with each other.
+func (self *Flashbot) MergeAndSimulate(ctx context.Context, bundles ...ParamsSend) (ParamsSend, *Response, error) {
+	merged, err := MergeBundles(bundles...)
+	if err != nil {
+		return ParamsSend{}, nil, err
+	}
+	sim, err := self.CallBundle(ctx, merged.Txs, 0)
+	if err != nil {
+		return merged, nil, errors.Wrap(err, "simulate merged bundle")
+	}
+	return merged, sim, nil
+}
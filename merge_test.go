@@ -0,0 +1,43 @@
+// Copyright (c) The Cryptorium Authors.
+// Licensed under the MIT License.
+
+package flashbot
+
+import (
+	"context"
+	"math/big"
+	"testing"
+
+	"github.com/cryptoriums/packages/testutil"
+	"github.com/ethereum/go-ethereum/common"
+)
+
+func TestMergeBundles(t *testing.T) {
+	fb := newTestFlashbot(t, "http://localhost")
+	spec := TxSpec{To: &common.Address{}, Gas: 21_000, GasFeeCap: big.NewInt(1)}
+	txs, _, err := fb.SignTxs(context.Background(), 1, 0, []TxSpec{spec, spec, spec})
+	testutil.Ok(t, err)
+
+	b1 := ParamsSend{BlockNum: "0xa", Txs: txs[:2], RevertingTxHashes: []string{"0x01"}, MinTimestamp: 10, MaxTimestamp: 100}
+	b2 := ParamsSend{BlockNum: "0xa", Txs: txs[1:], RevertingTxHashes: []string{"0x01", "0x02"}, MinTimestamp: 20}
+
+	merged, err := MergeBundles(b1, b2)
+	testutil.Ok(t, err)
+	testutil.Equals(t, txs, merged.Txs)
+	testutil.Equals(t, []string{"0x01", "0x02"}, merged.RevertingTxHashes)
+	testutil.Equals(t, uint64(20), merged.MinTimestamp)
+	testutil.Equals(t, uint64(100), merged.MaxTimestamp)
+
+	_, err = MergeBundles(b1, ParamsSend{BlockNum: "0xb"})
+	testutil.NotOk(t, err)
+
+	// A different TX with the same nonce.
+	spec.Value = big.NewInt(1)
+	conflict, _, err := fb.BuildTx(context.Background(), 1, 0, spec)
+	testutil.Ok(t, err)
+	_, err = MergeBundles(b1, ParamsSend{BlockNum: "0xa", Txs: []string{conflict}})
+	testutil.NotOk(t, err)
+
+	_, err = MergeBundles(b1, ParamsSend{BlockNum: "0xa", MinTimestamp: 200})
+	testutil.NotOk(t, err)
+}
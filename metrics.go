@@ -0,0 +1,45 @@
+// Copyright (c) The Cryptorium Authors.
+// Licensed under the MIT License.
+
+package flashbot
+
+// Metrics is the hook that exports the measurements of the clients and the trackers,
+// see the prommetrics package for a Prometheus implementation.
+// The labels are key value pairs and a metric always gets the same label keys.
+// The durations are in seconds.
+type Metrics interface {
+	// Observe adds a sample to a histogram.
+	Observe(name string, value float64, labels ...string)
+	// Add increments a counter.
+	Add(name string, delta float64, labels ...string)
+	// Set sets a gauge.
+	Set(name string, value float64, labels ...string)
+}
+
+// The names of the exported metrics.
+const (
+	// MetricSubmitLatency is the latency of the successful submissions with the relay label.
+	MetricSubmitLatency = "relay_submit_latency_seconds"
+	// MetricSimulateLatency is the latency of the successful simulations with the relay label.
+	MetricSimulateLatency = "relay_simulate_latency_seconds"
+	// MetricRequestPhase is the time of each phase of every relay request attempt
+	// with the relay, method, kind and phase labels, the phases are dns, connect, tls, ttfb and total.
+	MetricRequestPhase = "relay_request_phase_seconds"
+	// MetricHandshake is the TLS handshake time with the relay and resumed labels.
+	MetricHandshake = "relay_tls_handshake_seconds"
+	// MetricHandshakeFailures counts the failed TLS handshakes with the relay label.
+	MetricHandshakeFailures = "relay_tls_handshake_failures_total"
+	// MetricPnLStrategy are the P&L totals in wei per strategy with the strategy and field labels,
+	// the fields are gas_paid, coinbase_paid, revenue, refunds and net.
+	MetricPnLStrategy = "pnl_strategy_wei"
+	// MetricPnLStrategyBundles is the included bundles count per strategy with the strategy label.
+	MetricPnLStrategyBundles = "pnl_strategy_bundles"
+	// MetricPnLDay is like MetricPnLStrategy with the day label in the 2006-01-02 format instead of the strategy.
+	MetricPnLDay        = "pnl_day_wei"
+	MetricPnLDayBundles = "pnl_day_bundles"
+	// MetricBuilderEvents counts the builder events with the builder and kind labels,
+	// the kinds are considered, sealed, included and won.
+	MetricBuilderEvents = "builder_events_total"
+	// MetricBuilderSealLatency is the time from the bundle submission to the seal with the builder label.
+	MetricBuilderSealLatency = "builder_seal_latency_seconds"
+)
@@ -0,0 +1,73 @@
+// Copyright (c) The Cryptorium Authors.
+// Licensed under the MIT License.
+
+package flashbot
+
+import (
+	"context"
+	"encoding/json"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/cryptoriums/packages/testutil"
+)
+
+// metricsMock records the samples by the metric name and the label values.
+type metricsMock struct {
+	mtx     sync.Mutex
+	samples map[string][]float64
+}
+
+func newMetricsMock() *metricsMock {
+	return &metricsMock{samples: make(map[string][]float64)}
+}
+
+func (self *metricsMock) record(name string, value float64, labels []string) {
+	self.mtx.Lock()
+	defer self.mtx.Unlock()
+	key := strings.Join(append([]string{name}, labels...), ",")
+	self.samples[key] = append(self.samples[key], value)
+}
+
+func (self *metricsMock) Observe(name string, value float64, labels ...string) {
+	self.record(name, value, labels)
+}
+
+func (self *metricsMock) Add(name string, delta float64, labels ...string) {
+	self.record(name, delta, labels)
+}
+
+func (self *metricsMock) Set(name string, value float64, labels ...string) {
+	self.record(name, value, labels)
+}
+
+func (self *metricsMock) get(name string, labels ...string) []float64 {
+	self.mtx.Lock()
+	defer self.mtx.Unlock()
+	return self.samples[strings.Join(append([]string{name}, labels...), ",")]
+}
+
+func TestLatencyMetrics(t *testing.T) {
+	relay := newRelayMock(t, func(method string, params json.RawMessage) (interface{}, *jsonError) {
+		return Result{BundleHash: "0xbundle"}, nil
+	})
+	fb := newTestFlashbot(t, relay.URL)
+	metrics := newMetricsMock()
+	// The simulations share the custom method with the submissions.
+	fb.Api().MethodSend = "custom_bundle"
+	fb.Api().Metrics = metrics
+
+	_, err := fb.SendBundle(context.Background(), []string{"0x01"}, 10)
+	testutil.Ok(t, err)
+	_, err = fb.CallBundle(context.Background(), []string{"0x01"}, 0)
+	testutil.Ok(t, err)
+	_, err = fb.CallBundle(context.Background(), []string{"0x02"}, 0)
+	testutil.Ok(t, err)
+
+	testutil.Equals(t, []string{"custom_bundle", "custom_bundle", "custom_bundle"}, relay.Methods())
+	testutil.Equals(t, uint64(1), fb.Latency().Submit.Count())
+	testutil.Equals(t, uint64(2), fb.Latency().Simulate.Count())
+	testutil.Equals(t, 1, len(metrics.get(MetricSubmitLatency, "relay", relay.URL)))
+	testutil.Equals(t, 2, len(metrics.get(MetricSimulateLatency, "relay", relay.URL)))
+}
@@ -0,0 +1,143 @@
+// Copyright (c) The Cryptorium Authors.
+// Licensed under the MIT License.
+
+package flashbot
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"encoding/json"
+	"net/http"
+	"time"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/common/hexutil"
+	"github.com/pkg/errors"
+)
+
+// MevShareEventsURL is the MEV-Share server-sent events endpoint on mainnet.
+const MevShareEventsURL = "https://mev-share.flashbots.net"
+
+// MevShareEvent is a hint about a pending TX or bundle, the fields are only set when shared by the user.
+type MevShareEvent struct {
+	Hash        common.Hash   `json:"hash"`
+	Logs        []MevShareLog `json:"logs,omitempty"`
+	Txs         []MevShareTx  `json:"txs,omitempty"`
+	MevGasPrice *hexutil.Big  `json:"mevGasPrice,omitempty"`
+	GasUsed     *hexutil.Big  `json:"gasUsed,omitempty"`
+}
+
+type MevShareLog struct {
+	Address common.Address `json:"address"`
+	Topics  []common.Hash  `json:"topics"`
+	Data    hexutil.Bytes  `json:"data"`
+}
+
+type MevShareTx struct {
+	To               *common.Address `json:"to,omitempty"`
+	FunctionSelector hexutil.Bytes   `json:"functionSelector,omitempty"`
+	CallData         hexutil.Bytes   `json:"callData,omitempty"`
+}
+
+type MevShareStreamOpts struct {
+	// URL defaults to MevShareEventsURL.
+	URL    string
+	Client *http.Client
+	// MinBackoff and MaxBackoff bound the exponential reconnect delay, 100ms and 30s by default.
+	MinBackoff time.Duration
+	MaxBackoff time.Duration
+	// OnError receives the stream errors before reconnecting.
+	OnError func(error)
+}
+
+// StreamMevShareEvents connects to the MEV-Share events stream and sends the hints to the channel.
+// It reconnects with a backoff when the stream breaks and closes the channel when the context is done.
+func StreamMevShareEvents(ctx context.Context, opts MevShareStreamOpts) <-chan MevShareEvent {
+	if opts.URL == "" {
+		opts.URL = MevShareEventsURL
+	}
+	if opts.Client == nil {
+		// No timeout as the stream is long lived.
+		opts.Client = &http.Client{}
+	}
+	if opts.MinBackoff <= 0 {
+		opts.MinBackoff = 100 * time.Millisecond
+	}
+	if opts.MaxBackoff < opts.MinBackoff {
+		opts.MaxBackoff = 30 * time.Second
+	}
+
+	events := make(chan MevShareEvent, 100)
+	go func() {
+		defer close(events)
+		backoff := opts.MinBackoff
+		for {
+			received, err := streamMevShare(ctx, opts, events)
+			if ctx.Err() != nil {
+				return
+			}
+			if opts.OnError != nil {
+				opts.OnError(err)
+			}
+			if received {
+				backoff = opts.MinBackoff
+			}
+			select {
+			case <-ctx.Done():
+				return
+			case <-time.After(backoff):
+			}
+			backoff *= 2
+			if backoff > opts.MaxBackoff {
+				backoff = opts.MaxBackoff
+			}
+		}
+	}()
+	return events
+}
+
+// streamMevShare reads the stream until it breaks and reports whether any event was received.
+func streamMevShare(ctx context.Context, opts MevShareStreamOpts, events chan<- MevShareEvent) (bool, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, opts.URL, nil)
+	if err != nil {
+		return false, errors.Wrap(err, "create mev-share stream request")
+	}
+	req.Header.Set("Accept", "text/event-stream")
+	resp, err := opts.Client.Do(req)
+	if err != nil {
+		return false, errors.Wrap(err, "mev-share stream request")
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return false, errors.Errorf("bad mev-share stream response status:%v", resp.Status)
+	}
+
+	received := false
+	scanner := bufio.NewScanner(resp.Body)
+	scanner.Buffer(make([]byte, 64*1024), 1024*1024)
+	for scanner.Scan() {
+		line := scanner.Bytes()
+		// Comments like the keep alive pings and the other fields are skipped.
+		if !bytes.HasPrefix(line, []byte("data:")) {
+			continue
+		}
+		e := MevShareEvent{}
+		if err := json.Unmarshal(bytes.TrimSpace(line[len("data:"):]), &e); err != nil {
+			if opts.OnError != nil {
+				opts.OnError(errors.Wrap(err, "unmarshal mev-share event"))
+			}
+			continue
+		}
+		received = true
+		select {
+		case events <- e:
+		case <-ctx.Done():
+			return received, ctx.Err()
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		return received, errors.Wrap(err, "read mev-share stream")
+	}
+	return received, errors.New("mev-share stream closed")
+}
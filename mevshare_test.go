@@ -0,0 +1,53 @@
+// Copyright (c) The Cryptorium Authors.
+// Licensed under the MIT License.
+
+package flashbot
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/cryptoriums/packages/testutil"
+	"github.com/ethereum/go-ethereum/common"
+)
+
+func TestStreamMevShareEvents(t *testing.T) {
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	var conns int32
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "text/event-stream")
+		// Every connection sends one event and breaks the stream to test the reconnect.
+		switch atomic.AddInt32(&conns, 1) {
+		case 1:
+			_, _ = w.Write([]byte(":ping\n\ndata: {\"hash\":\"0x0000000000000000000000000000000000000000000000000000000000000001\",\"txs\":[{\"to\":\"0x0000000000000000000000000000000000000002\",\"functionSelector\":\"0xa9059cbb\"}]}\n\n"))
+		default:
+			_, _ = w.Write([]byte("data: {\"hash\":\"0x0000000000000000000000000000000000000000000000000000000000000002\",\"logs\":[{\"address\":\"0x0000000000000000000000000000000000000003\",\"topics\":[],\"data\":\"0x\"}]}\n\n"))
+		}
+	}))
+	defer srv.Close()
+
+	var errs int32
+	events := StreamMevShareEvents(ctx, MevShareStreamOpts{
+		URL:        srv.URL,
+		MinBackoff: time.Millisecond,
+		OnError:    func(error) { atomic.AddInt32(&errs, 1) },
+	})
+
+	e := <-events
+	testutil.Equals(t, common.HexToHash("0x01"), e.Hash)
+	testutil.Equals(t, []byte{0xa9, 0x05, 0x9c, 0xbb}, []byte(e.Txs[0].FunctionSelector))
+	e = <-events
+	testutil.Equals(t, common.HexToHash("0x02"), e.Hash)
+	testutil.Equals(t, common.HexToAddress("0x03"), e.Logs[0].Address)
+	testutil.Assert(t, atomic.LoadInt32(&errs) >= 1, "the broken stream should be reported")
+
+	cancel()
+	for range events {
+	}
+}
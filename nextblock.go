@@ -0,0 +1,59 @@
+// Copyright (c) The Cryptorium Authors.
+// Licensed under the MIT License.
+
+package flashbot
+
+import (
+	"context"
+
+	"github.com/pkg/errors"
+)
+
+// SetHeadBackend sets the backend used for the chain head by SendBundleNextBlock, i.e. an ethclient or a HeadWatcher.
+func (self *Flashbot) SetHeadBackend(heads BlockNumberBackend) {
+	self.mtx.Lock()
+	defer self.mtx.Unlock()
+	self.heads = heads
+}
+
+func (self *Flashbot) nextBlock(ctx context.Context) (uint64, error) {
+	self.mtx.RLock()
+	heads := self.heads
+	self.mtx.RUnlock()
+	if heads == nil {
+		return 0, errors.New("head backend is not set")
+	}
+	head, err := heads.BlockNumber(ctx)
+	if err != nil {
+		return 0, errors.Wrap(err, "get head block number")
+	}
+	return head + 1, nil
+}
+
+// SendBundleNextBlock sends the bundle for the block after the current head of the head backend.
+func (self *Flashbot) SendBundleNextBlock(ctx context.Context, txsHex []string) (*Response, error) {
+	blockNum, err := self.nextBlock(ctx)
+	if err != nil {
+		return nil, err
+	}
+	return self.SendBundle(ctx, txsHex, blockNum)
+}
+
+// SendBundleNextBlocks sends the bundle for each of the nBlocks blocks after the current head,
+// i.e. 2 for head+1 and head+2.
+func (self *Flashbot) SendBundleNextBlocks(ctx context.Context, txsHex []string, nBlocks uint64) ([]BlockSubmission, error) {
+	blockNum, err := self.nextBlock(ctx)
+	if err != nil {
+		return nil, err
+	}
+	return self.SendBundleForBlocks(ctx, txsHex, blockNum, nBlocks)
+}
+
+// BlockNumber returns the number of the latest head so that the watcher can be used as a head backend without a request.
+func (self *HeadWatcher) BlockNumber(ctx context.Context) (uint64, error) {
+	h := self.Head()
+	if h == nil {
+		return 0, errors.New("no head received yet")
+	}
+	return h.Number.Uint64(), nil
+}
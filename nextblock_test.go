@@ -0,0 +1,54 @@
+// Copyright (c) The Cryptorium Authors.
+// Licensed under the MIT License.
+
+package flashbot
+
+import (
+	"context"
+	"encoding/json"
+	"sync"
+	"testing"
+
+	"github.com/cryptoriums/packages/testutil"
+)
+
+func TestSendBundleNextBlock(t *testing.T) {
+	ctx := context.Background()
+	var (
+		mtx    sync.Mutex
+		blocks []string
+	)
+	relay := newRelayMock(t, func(method string, params json.RawMessage) (interface{}, *jsonError) {
+		var p []ParamsSend
+		testutil.Ok(t, json.Unmarshal(params, &p))
+		mtx.Lock()
+		blocks = append(blocks, p[0].BlockNum)
+		mtx.Unlock()
+		return Result{BundleHash: "0xbundle"}, nil
+	})
+	fb := newTestFlashbot(t, relay.URL)
+
+	_, err := fb.SendBundleNextBlock(ctx, []string{"0x01"})
+	testutil.NotOk(t, err)
+
+	fb.SetHeadBackend(&blockNumberMock{head: 10})
+	_, err = fb.SendBundleNextBlock(ctx, []string{"0x01"})
+	testutil.Ok(t, err)
+	testutil.Equals(t, []string{"0xb"}, blocks)
+
+	res, err := fb.SendBundleNextBlocks(ctx, []string{"0x01"}, 2)
+	testutil.Ok(t, err)
+	testutil.Equals(t, uint64(11), res[0].BlockNum)
+	testutil.Equals(t, uint64(12), res[1].BlockNum)
+
+	w := NewHeadWatcher(&pollingHeads{head: 20}, 0)
+	_, err = w.BlockNumber(ctx)
+	testutil.NotOk(t, err)
+	h, err := w.backend.HeaderByNumber(ctx, nil)
+	testutil.Ok(t, err)
+	w.publish(h)
+	fb.SetHeadBackend(w)
+	res, err = fb.SendBundleNextBlocks(ctx, []string{"0x01"}, 1)
+	testutil.Ok(t, err)
+	testutil.Equals(t, uint64(21), res[0].BlockNum)
+}
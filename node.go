@@ -0,0 +1,108 @@
+// Copyright (c) The Cryptorium Authors.
+// Licensed under the MIT License.
+
+package flashbot
+
+import (
+	"context"
+
+	"github.com/ethereum/go-ethereum"
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/common/hexutil"
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/ethclient"
+	"github.com/ethereum/go-ethereum/ethclient/gethclient"
+	"github.com/ethereum/go-ethereum/rpc"
+	"github.com/pkg/errors"
+)
+
+// Node is a client for the execution node RPC methods used by the TX builders.
+// It embeds the ethclient so it can be used anywhere an ethclient compatible backend is expected.
+type Node struct {
+	*ethclient.Client
+	rpc  *rpc.Client
+	geth *gethclient.Client
+}
+
+func NewNode(ctx context.Context, url string) (*Node, error) {
+	client, err := rpc.DialContext(ctx, url)
+	if err != nil {
+		return nil, errors.Wrapf(err, "connect to node:%v", url)
+	}
+	return NewNodeFromRPC(client), nil
+}
+
+func NewNodeFromRPC(client *rpc.Client) *Node {
+	return &Node{
+		Client: ethclient.NewClient(client),
+		rpc:    client,
+		geth:   gethclient.New(client),
+	}
+}
+
+func (self *Node) RPC() *rpc.Client {
+	return self.rpc
+}
+
+// CreateAccessList returns the access list and the gas used with it for the call.
+func (self *Node) CreateAccessList(ctx context.Context, msg ethereum.CallMsg) (types.AccessList, uint64, error) {
+	al, gasUsed, vmErr, err := self.geth.CreateAccessList(ctx, msg)
+	if err != nil {
+		return nil, 0, errors.Wrap(err, "eth_createAccessList request")
+	}
+	if vmErr != "" {
+		return nil, 0, errors.Errorf("eth_createAccessList execution failed:%v", vmErr)
+	}
+	if al == nil {
+		return types.AccessList{}, gasUsed, nil
+	}
+	return *al, gasUsed, nil
+}
+
+// TouchedContracts returns the contracts accessed by the bundle TXs on top of the state block using eth_createAccessList.
+// Every TX is traced alone on the state block so the later TXs don't see the changes of the earlier ones,
+// which is enough to find the contracts called through a router or a bot contract.
+func (self *Node) TouchedContracts(ctx context.Context, txsHex []string, blockNumState uint64) ([]common.Address, error) {
+	bundle, err := DecodeBundle(txsHex)
+	if err != nil {
+		return nil, err
+	}
+	var (
+		res  []common.Address
+		seen = make(map[common.Address]bool)
+	)
+	add := func(a common.Address) {
+		if !seen[a] {
+			seen[a] = true
+			res = append(res, a)
+		}
+	}
+	for i, tx := range bundle {
+		arg := map[string]interface{}{
+			"from":  tx.Sender,
+			"gas":   hexutil.Uint64(tx.Tx.Gas()),
+			"value": (*hexutil.Big)(tx.Tx.Value()),
+			"data":  hexutil.Bytes(tx.Tx.Data()),
+		}
+		if tx.Tx.To() != nil {
+			arg["to"] = tx.Tx.To()
+			// The recipient is never in the created access list.
+			add(*tx.Tx.To())
+		}
+		// No fee fields so that the call doesn't depend on the sender balance.
+		var result struct {
+			AccessList types.AccessList `json:"accessList"`
+		}
+		if err := self.rpc.CallContext(ctx, &result, "eth_createAccessList", arg, hexutil.EncodeUint64(blockNumState)); err != nil {
+			return nil, errors.Wrapf(err, "eth_createAccessList request TX index:%v", i)
+		}
+		// A reverted TX still returns the contracts accessed until the revert.
+		for _, t := range result.AccessList {
+			add(t.Address)
+		}
+		for _, t := range tx.Tx.AccessList() {
+			add(t.Address)
+		}
+	}
+	return res, nil
+}
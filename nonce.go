@@ -0,0 +1,124 @@
+// Copyright (c) The Cryptorium Authors.
+// Licensed under the MIT License.
+
+package flashbot
+
+import (
+	"context"
+	"sync"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/pkg/errors"
+)
+
+// NonceBackend is implemented by ethclient.Client and Node.
+type NonceBackend interface {
+	PendingNonceAt(ctx context.Context, account common.Address) (uint64, error)
+}
+
+// NonceManager hands out nonces per sender so that bundles can be built concurrently from the same EOA.
+// Bundle TXs that don't land never reach the chain so call Reconcile
+// once the target blocks have passed to reuse their nonces.
+type NonceManager struct {
+	backend NonceBackend
+	mtx     sync.Mutex
+	next    map[common.Address]uint64
+}
+
+func NewNonceManager(backend NonceBackend) *NonceManager {
+	return &NonceManager{
+		backend: backend,
+		next:    make(map[common.Address]uint64),
+	}
+}
+
+// Next reserves a single nonce for the sender.
+func (self *NonceManager) Next(ctx context.Context, addr common.Address) (uint64, error) {
+	return self.Reserve(ctx, addr, 1)
+}
+
+// Reserve reserves n sequential nonces for the sender and returns the first one.
+// The nonce is fetched from the node the first time the sender is seen.
+// The fetch doesn't hold the lock so a slow node doesn't block the other senders
+// and when another reservation set the sender in the meantime its nonce is used instead.
+func (self *NonceManager) Reserve(ctx context.Context, addr common.Address, n uint64) (uint64, error) {
+	self.mtx.Lock()
+	_, ok := self.next[addr]
+	self.mtx.Unlock()
+
+	var pending uint64
+	if !ok {
+		var err error
+		pending, err = self.backend.PendingNonceAt(ctx, addr)
+		if err != nil {
+			return 0, errors.Wrapf(err, "getting pending nonce addr:%v", addr.Hex())
+		}
+	}
+
+	self.mtx.Lock()
+	defer self.mtx.Unlock()
+	next, ok := self.next[addr]
+	if !ok {
+		next = pending
+	}
+	self.next[addr] = next + n
+	return next, nil
+}
+
+// Release gives back a reservation that wasn't used.
+// It only has an effect when no nonces were reserved after it.
+func (self *NonceManager) Release(addr common.Address, nonce uint64, n uint64) {
+	self.mtx.Lock()
+	defer self.mtx.Unlock()
+
+	if next, ok := self.next[addr]; ok && next == nonce+n {
+		self.next[addr] = nonce
+	}
+}
+
+// Reconcile resets the sender to the pending nonce reported by the node and returns it.
+func (self *NonceManager) Reconcile(ctx context.Context, addr common.Address) (uint64, error) {
+	nonce, err := self.backend.PendingNonceAt(ctx, addr)
+	if err != nil {
+		return 0, errors.Wrapf(err, "getting pending nonce addr:%v", addr.Hex())
+	}
+	self.mtx.Lock()
+	defer self.mtx.Unlock()
+	self.next[addr] = nonce
+	return nonce, nil
+}
+
+// Set overrides the next nonce for the sender.
+func (self *NonceManager) Set(addr common.Address, nonce uint64) {
+	self.mtx.Lock()
+	defer self.mtx.Unlock()
+	self.next[addr] = nonce
+}
+
+// Peek returns the next nonce for the sender without reserving it.
+func (self *NonceManager) Peek(addr common.Address) (uint64, bool) {
+	self.mtx.Lock()
+	defer self.mtx.Unlock()
+	next, ok := self.next[addr]
+	return next, ok
+}
+
+// SignTxsWithNonces signs the specs like SignTxs with nonces reserved from the manager
+// for the TX signer account. The reservation is released when the signing fails.
+func (self *Flashbot) SignTxsWithNonces(ctx context.Context, netID int64, nonces *NonceManager, specs []TxSpec, opts ...TxOption) ([]string, []*types.Transaction, error) {
+	signer := self.TxSigner()
+	if signer == nil {
+		return nil, nil, errors.New("private key or signer is not set")
+	}
+	nonce, err := nonces.Reserve(ctx, signer.Address(), uint64(len(specs)))
+	if err != nil {
+		return nil, nil, err
+	}
+	txsHex, txs, err := self.SignTxs(ctx, netID, nonce, specs, opts...)
+	if err != nil {
+		nonces.Release(signer.Address(), nonce, uint64(len(specs)))
+		return nil, nil, err
+	}
+	return txsHex, txs, nil
+}
@@ -0,0 +1,88 @@
+// Copyright (c) The Cryptorium Authors.
+// Licensed under the MIT License.
+
+package flashbot
+
+import (
+	"context"
+	"sort"
+	"sync"
+	"testing"
+
+	"github.com/cryptoriums/packages/testutil"
+	"github.com/ethereum/go-ethereum/common"
+)
+
+type nonceBackendMock struct {
+	nonce uint64
+	// block delays the replies when set until it is closed.
+	block chan struct{}
+}
+
+func (self *nonceBackendMock) PendingNonceAt(ctx context.Context, account common.Address) (uint64, error) {
+	if self.block != nil {
+		<-self.block
+	}
+	return self.nonce, nil
+}
+
+func TestNonceManager(t *testing.T) {
+	ctx := context.Background()
+	backend := &nonceBackendMock{nonce: 5}
+	nm := NewNonceManager(backend)
+	addr := common.HexToAddress("0x1")
+
+	nonce, err := nm.Reserve(ctx, addr, 3)
+	testutil.Ok(t, err)
+	testutil.Equals(t, uint64(5), nonce)
+
+	nonce, err = nm.Next(ctx, addr)
+	testutil.Ok(t, err)
+	testutil.Equals(t, uint64(8), nonce)
+
+	// Not the last reservation so it can't be released.
+	nm.Release(addr, 5, 3)
+	next, _ := nm.Peek(addr)
+	testutil.Equals(t, uint64(9), next)
+
+	nm.Release(addr, 8, 1)
+	next, _ = nm.Peek(addr)
+	testutil.Equals(t, uint64(8), next)
+
+	backend.nonce = 6
+	nonce, err = nm.Reconcile(ctx, addr)
+	testutil.Ok(t, err)
+	testutil.Equals(t, uint64(6), nonce)
+}
+
+func TestNonceManagerConcurrentFetch(t *testing.T) {
+	ctx := context.Background()
+	backend := &nonceBackendMock{nonce: 5, block: make(chan struct{})}
+	nm := NewNonceManager(backend)
+	addr, other := common.HexToAddress("0x1"), common.HexToAddress("0x2")
+	nm.Set(other, 10)
+
+	// The other senders aren't blocked while the nonce is fetched.
+	var wg sync.WaitGroup
+	nonces := make([]uint64, 2)
+	for i := range nonces {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			nonce, err := nm.Reserve(ctx, addr, 2)
+			testutil.Ok(t, err)
+			nonces[i] = nonce
+		}(i)
+	}
+	nonce, err := nm.Next(ctx, other)
+	testutil.Ok(t, err)
+	testutil.Equals(t, uint64(10), nonce)
+
+	// The concurrent fetches don't hand out the same nonces.
+	close(backend.block)
+	wg.Wait()
+	sort.Slice(nonces, func(i, j int) bool { return nonces[i] < nonces[j] })
+	testutil.Equals(t, []uint64{5, 7}, nonces)
+	next, _ := nm.Peek(addr)
+	testutil.Equals(t, uint64(9), next)
+}
@@ -0,0 +1,213 @@
+// Copyright (c) The Cryptorium Authors.
+// Licensed under the MIT License.
+
+package flashbot
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"time"
+
+	"github.com/pkg/errors"
+)
+
+// Notifier posts the bundle lifecycle events to an alerting channel.
+type Notifier interface {
+	Notify(ctx context.Context, e BundleEvent) error
+}
+
+type notifyConfig struct {
+	client  *http.Client
+	events  []BundleEventType
+	baseURL string
+}
+
+type NotifyOption func(*notifyConfig)
+
+// WithNotifyEvents sets the events that are posted.
+// By default only the included, dropped and error events are posted.
+func WithNotifyEvents(types ...BundleEventType) NotifyOption {
+	return func(c *notifyConfig) { c.events = types }
+}
+
+func WithNotifyHTTPClient(client *http.Client) NotifyOption {
+	return func(c *notifyConfig) { c.client = client }
+}
+
+// WithNotifyBaseURL overrides the API URL of the Telegram notifier.
+func WithNotifyBaseURL(baseURL string) NotifyOption {
+	return func(c *notifyConfig) { c.baseURL = baseURL }
+}
+
+func newNotifyConfig(opts []NotifyOption) *notifyConfig {
+	c := &notifyConfig{
+		client: &http.Client{Timeout: 10 * time.Second},
+		events: []BundleEventType{EventIncluded, EventDropped, EventError},
+	}
+	for _, opt := range opts {
+		opt(c)
+	}
+	return c
+}
+
+func (self *notifyConfig) enabled(t BundleEventType) bool {
+	for _, e := range self.events {
+		if e == t {
+			return true
+		}
+	}
+	return false
+}
+
+// post sends the payload to the URL.
+// The URL isn't included in the errors as it can contain a secret, i.e. the Telegram bot token.
+func (self *notifyConfig) post(ctx context.Context, target string, payload interface{}) error {
+	body, err := json.Marshal(payload)
+	if err != nil {
+		return errors.Wrap(err, "marshal notification")
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, target, bytes.NewReader(body))
+	if err != nil {
+		return errors.Wrap(withoutURL(err), "create notification request")
+	}
+	req.Header.Set("Content-Type", "application/json")
+	resp, err := self.client.Do(req)
+	if err != nil {
+		return errors.Wrap(withoutURL(err), "notification request")
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode/100 != 2 {
+		respBody, _ := io.ReadAll(io.LimitReader(resp.Body, 1024))
+		return errors.Errorf("bad notification response status:%v body:%v", resp.Status, string(respBody))
+	}
+	return nil
+}
+
+func withoutURL(err error) error {
+	var uErr *url.Error
+	if errors.As(err, &uErr) {
+		return uErr.Err
+	}
+	return err
+}
+
+// NotifyPayload is the JSON body posted by the webhook notifier.
+type NotifyPayload struct {
+	Type       BundleEventType
+	ID         string      `json:",omitempty"`
+	State      BundleState `json:",omitempty"`
+	Block      uint64      `json:",omitempty"`
+	BundleHash string      `json:",omitempty"`
+	Builder    string      `json:",omitempty"`
+	Err        string      `json:",omitempty"`
+	Time       time.Time
+}
+
+func newNotifyPayload(e BundleEvent) NotifyPayload {
+	p := NotifyPayload{Type: e.Type, Block: e.Block, Builder: e.Builder, Time: e.Time}
+	if e.Bundle != nil {
+		p.ID = e.Bundle.ID
+		p.State = e.Bundle.State
+		p.BundleHash = e.Bundle.BundleHash
+	}
+	if e.Err != nil {
+		p.Err = e.Err.Error()
+	}
+	return p
+}
+
+// String formats the event as a short human readable message.
+func (self NotifyPayload) String() string {
+	msg := fmt.Sprintf("bundle %v id:%v", self.Type, self.ID)
+	if self.Block != 0 {
+		msg += fmt.Sprintf(" block:%v", self.Block)
+	}
+	if self.BundleHash != "" {
+		msg += fmt.Sprintf(" hash:%v", self.BundleHash)
+	}
+	if self.Builder != "" {
+		msg += fmt.Sprintf(" builder:%v", self.Builder)
+	}
+	if self.Err != "" {
+		msg += fmt.Sprintf(" err:%v", self.Err)
+	}
+	return msg
+}
+
+type webhookNotifier struct {
+	*notifyConfig
+	url string
+}
+
+// NewWebhookNotifier posts the events as NotifyPayload JSON to the URL.
+func NewWebhookNotifier(url string, opts ...NotifyOption) Notifier {
+	return &webhookNotifier{notifyConfig: newNotifyConfig(opts), url: url}
+}
+
+func (self *webhookNotifier) Notify(ctx context.Context, e BundleEvent) error {
+	if !self.enabled(e.Type) {
+		return nil
+	}
+	return self.post(ctx, self.url, newNotifyPayload(e))
+}
+
+type slackNotifier struct {
+	*notifyConfig
+	url string
+}
+
+// NewSlackNotifier posts the events to a Slack incoming webhook URL.
+func NewSlackNotifier(webhookURL string, opts ...NotifyOption) Notifier {
+	return &slackNotifier{notifyConfig: newNotifyConfig(opts), url: webhookURL}
+}
+
+func (self *slackNotifier) Notify(ctx context.Context, e BundleEvent) error {
+	if !self.enabled(e.Type) {
+		return nil
+	}
+	return self.post(ctx, self.url, map[string]string{"text": newNotifyPayload(e).String()})
+}
+
+type telegramNotifier struct {
+	*notifyConfig
+	token  string
+	chatID string
+}
+
+// NewTelegramNotifier posts the events to a Telegram chat through a bot.
+func NewTelegramNotifier(botToken, chatID string, opts ...NotifyOption) Notifier {
+	c := newNotifyConfig(append([]NotifyOption{WithNotifyBaseURL("https://api.telegram.org")}, opts...))
+	return &telegramNotifier{notifyConfig: c, token: botToken, chatID: chatID}
+}
+
+func (self *telegramNotifier) Notify(ctx context.Context, e BundleEvent) error {
+	if !self.enabled(e.Type) {
+		return nil
+	}
+	u := self.baseURL + "/bot" + url.PathEscape(self.token) + "/sendMessage"
+	return self.post(ctx, u, map[string]string{"chat_id": self.chatID, "text": newNotifyPayload(e).String()})
+}
+
+// Notify posts the manager events to the notifiers until the context is done.
+// Failed notifications are passed to onErr when it is not nil.
+func (self *BundleManager) Notify(ctx context.Context, onErr func(error), notifiers ...Notifier) {
+	events, unsubscribe := self.Subscribe(100)
+	defer unsubscribe()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case e := <-events:
+			for _, n := range notifiers {
+				if err := n.Notify(ctx, e); err != nil && onErr != nil {
+					onErr(err)
+				}
+			}
+		}
+	}
+}
@@ -0,0 +1,61 @@
+// Copyright (c) The Cryptorium Authors.
+// Licensed under the MIT License.
+
+package flashbot
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/cryptoriums/packages/testutil"
+)
+
+func TestNotifiers(t *testing.T) {
+	ctx := context.Background()
+	var (
+		paths  []string
+		bodies []map[string]interface{}
+	)
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body := map[string]interface{}{}
+		testutil.Ok(t, json.NewDecoder(r.Body).Decode(&body))
+		paths = append(paths, r.URL.Path)
+		bodies = append(bodies, body)
+	}))
+	defer srv.Close()
+
+	e := BundleEvent{
+		Type:   EventDropped,
+		Bundle: &ManagedBundle{ID: "id1", State: BundleDropped},
+		Block:  10,
+		Err:    errors.New("reorged"),
+	}
+
+	testutil.Ok(t, NewWebhookNotifier(srv.URL+"/hook").Notify(ctx, e))
+	testutil.Ok(t, NewSlackNotifier(srv.URL+"/slack").Notify(ctx, e))
+	testutil.Ok(t, NewTelegramNotifier("token", "42", WithNotifyBaseURL(srv.URL)).Notify(ctx, e))
+	// Filtered out by default.
+	testutil.Ok(t, NewWebhookNotifier(srv.URL+"/hook").Notify(ctx, BundleEvent{Type: EventSimulated}))
+
+	testutil.Equals(t, []string{"/hook", "/slack", "/bottoken/sendMessage"}, paths)
+	testutil.Equals(t, "id1", bodies[0]["ID"])
+	testutil.Equals(t, "reorged", bodies[0]["Err"])
+	testutil.Equals(t, "bundle dropped id:id1 block:10 err:reorged", bodies[1]["text"])
+	testutil.Equals(t, "42", bodies[2]["chat_id"])
+
+	srv.Config.Handler = http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusInternalServerError)
+	})
+	testutil.NotOk(t, NewWebhookNotifier(srv.URL).Notify(ctx, e))
+
+	// The bot token in the URL isn't leaked through the transport errors.
+	srv.Close()
+	err := NewTelegramNotifier("secret-token", "42", WithNotifyBaseURL(srv.URL)).Notify(ctx, e)
+	testutil.NotOk(t, err)
+	testutil.Assert(t, !strings.Contains(err.Error(), "secret-token"), "token in the error:%v", err)
+}
@@ -0,0 +1,72 @@
+// Copyright (c) The Cryptorium Authors.
+// Licensed under the MIT License.
+
+package flashbot
+
+import (
+	"context"
+	"encoding/json"
+	"math/big"
+	"testing"
+
+	"github.com/cryptoriums/packages/testutil"
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/common/hexutil"
+)
+
+func TestCallBundleOverride(t *testing.T) {
+	var got []ParamsCall
+	relay := newRelayMock(t, func(method string, params json.RawMessage) (interface{}, *jsonError) {
+		testutil.Ok(t, json.Unmarshal(params, &got))
+		return Result{BundleHash: "0xbundle"}, nil
+	})
+	fb := newTestFlashbot(t, relay.URL)
+
+	addr := common.HexToAddress("0x01")
+	overrides := StateOverride{addr: {
+		Balance:   (*hexutil.Big)(big.NewInt(100)),
+		StateDiff: map[common.Hash]common.Hash{{}: common.BigToHash(big.NewInt(1))},
+	}}
+	_, err := fb.CallBundleOverride(context.Background(), []string{"0x01"}, 10, overrides)
+	testutil.Ok(t, err)
+	testutil.Equals(t, overrides, got[0].StateOverrides)
+	testutil.Equals(t, "0xa", got[0].StateBlockNum)
+
+	got = nil
+	_, err = fb.CallBundle(context.Background(), []string{"0x01"}, 0)
+	testutil.Ok(t, err)
+	testutil.Assert(t, got[0].StateOverrides == nil, "unexpected overrides")
+}
+
+func TestCallBundleAt(t *testing.T) {
+	var got []ParamsCall
+	relay := newRelayMock(t, func(method string, params json.RawMessage) (interface{}, *jsonError) {
+		testutil.Ok(t, json.Unmarshal(params, &got))
+		return Result{BundleHash: "0xbundle"}, nil
+	})
+	fb := newTestFlashbot(t, relay.URL)
+
+	// The simulated block follows the pinned state block by default.
+	_, err := fb.CallBundleAt(context.Background(), []string{"0x01"}, CallOpts{StateBlock: 15_000_000})
+	testutil.Ok(t, err)
+	testutil.Equals(t, hexutil.EncodeUint64(15_000_000), got[0].StateBlockNum)
+	testutil.Equals(t, hexutil.EncodeUint64(15_000_001), got[0].BlockNum)
+	testutil.Equals(t, uint64(0), got[0].Timestamp)
+	testutil.Equals(t, "", got[0].Coinbase)
+
+	coinbase := common.HexToAddress("0xc0")
+	_, err = fb.CallBundleAt(context.Background(), []string{"0x01"}, CallOpts{
+		StateBlock: 15_000_000,
+		BlockNum:   15_000_002,
+		Timestamp:  1_656_000_000,
+		Coinbase:   &coinbase,
+	})
+	testutil.Ok(t, err)
+	testutil.Equals(t, hexutil.EncodeUint64(15_000_002), got[0].BlockNum)
+	testutil.Equals(t, uint64(1_656_000_000), got[0].Timestamp)
+	testutil.Equals(t, coinbase.Hex(), got[0].Coinbase)
+
+	_, err = fb.CallBundleAt(context.Background(), []string{"0x01"}, CallOpts{})
+	testutil.Ok(t, err)
+	testutil.Equals(t, "latest", got[0].StateBlockNum)
+}
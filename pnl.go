@@ -0,0 +1,383 @@
+// Copyright (c) The Cryptorium Authors.
+// Licensed under the MIT License.
+
+package flashbot
+
+import (
+	"math/big"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/crypto"
+	"github.com/pkg/errors"
+)
+
+// transferTopic is the topic of the ERC20 Transfer event.
+var transferTopic = crypto.Keccak256Hash([]byte("Transfer(address,address,uint256)"))
+
+// BundlePnL is the realized profit and loss of an included bundle for the tracked accounts.
+type BundlePnL struct {
+	ID       string
+	Strategy string
+	Block    uint64
+	Time     time.Time
+	// GasPaid is the gas cost of the TXs sent by the tracked accounts.
+	GasPaid *big.Int
+	// CoinbasePaid are the ETH payments to the block coinbase,
+	// the simulation EthSentToCoinbase when recorded with RecordSim
+	// and otherwise only the top level transfers from the tracked accounts.
+	CoinbasePaid *big.Int
+	// Revenue is the ETH gained by the strategy as reported by the caller.
+	Revenue *big.Int
+	// Refunds are the fee and MEV refunds received for the bundle, see RecordRefund.
+	Refunds *big.Int
+	// TokenDeltas are the net ERC20 transfers to the tracked accounts per token contract.
+	TokenDeltas map[common.Address]*big.Int
+}
+
+// Net returns the revenue and the refunds minus the gas and the coinbase payments.
+func (self *BundlePnL) Net() *big.Int {
+	net := new(big.Int).Set(self.Revenue)
+	if self.Refunds != nil {
+		net.Add(net, self.Refunds)
+	}
+	net.Sub(net, self.GasPaid)
+	return net.Sub(net, self.CoinbasePaid)
+}
+
+type PnLTotals struct {
+	Bundles      int
+	GasPaid      *big.Int
+	CoinbasePaid *big.Int
+	Revenue      *big.Int
+	Refunds      *big.Int
+	Net          *big.Int
+	TokenDeltas  map[common.Address]*big.Int
+}
+
+func newPnLTotals() *PnLTotals {
+	return &PnLTotals{
+		GasPaid:      new(big.Int),
+		CoinbasePaid: new(big.Int),
+		Revenue:      new(big.Int),
+		Refunds:      new(big.Int),
+		Net:          new(big.Int),
+		TokenDeltas:  make(map[common.Address]*big.Int),
+	}
+}
+
+func (self *PnLTotals) add(p *BundlePnL) {
+	self.Bundles++
+	self.GasPaid.Add(self.GasPaid, p.GasPaid)
+	self.CoinbasePaid.Add(self.CoinbasePaid, p.CoinbasePaid)
+	self.Revenue.Add(self.Revenue, p.Revenue)
+	if p.Refunds != nil {
+		self.Refunds.Add(self.Refunds, p.Refunds)
+	}
+	self.Net.Add(self.Net, p.Net())
+	addDeltas(self.TokenDeltas, p.TokenDeltas)
+}
+
+func (self *PnLTotals) addRefund(amount *big.Int) {
+	self.Refunds.Add(self.Refunds, amount)
+	self.Net.Add(self.Net, amount)
+}
+
+func (self *PnLTotals) copy() *PnLTotals {
+	cpy := newPnLTotals()
+	cpy.Bundles = self.Bundles
+	cpy.GasPaid.Set(self.GasPaid)
+	cpy.CoinbasePaid.Set(self.CoinbasePaid)
+	cpy.Revenue.Set(self.Revenue)
+	cpy.Refunds.Set(self.Refunds)
+	cpy.Net.Set(self.Net)
+	addDeltas(cpy.TokenDeltas, self.TokenDeltas)
+	return cpy
+}
+
+// DefaultPnLRecords is the number of the last recorded bundles kept by the tracker.
+const DefaultPnLRecords = 10_000
+
+// PnLTracker accumulates the realized P&L of the included bundles sent by the tracked accounts.
+// The totals are kept for all bundles while only the last bundles are kept for Records and RecordRefund.
+type PnLTracker struct {
+	mtx        sync.RWMutex
+	accounts   map[common.Address]bool
+	records    []*BundlePnL
+	maxRecords int
+	totals     *PnLTotals
+	byStrategy map[string]*PnLTotals
+	byDay      map[string]*PnLTotals
+	metrics    Metrics
+}
+
+func NewPnLTracker(accounts ...common.Address) *PnLTracker {
+	self := &PnLTracker{
+		accounts:   make(map[common.Address]bool),
+		maxRecords: DefaultPnLRecords,
+		totals:     newPnLTotals(),
+		byStrategy: make(map[string]*PnLTotals),
+		byDay:      make(map[string]*PnLTotals),
+	}
+	for _, a := range accounts {
+		self.accounts[a] = true
+	}
+	return self
+}
+
+// SetMaxRecords sets how many of the last recorded bundles are kept, DefaultPnLRecords by default.
+func (self *PnLTracker) SetMaxRecords(n int) {
+	self.mtx.Lock()
+	defer self.mtx.Unlock()
+	self.maxRecords = n
+	self.pruneLocked()
+}
+
+func (self *PnLTracker) pruneLocked() {
+	if over := len(self.records) - self.maxRecords; over > 0 {
+		self.records = append([]*BundlePnL(nil), self.records[over:]...)
+	}
+}
+
+// SetMetrics exports the per strategy and the per day totals after every recorded bundle and refund,
+// see MetricPnLStrategy and MetricPnLDay.
+func (self *PnLTracker) SetMetrics(m Metrics) {
+	self.mtx.Lock()
+	defer self.mtx.Unlock()
+	self.metrics = m
+}
+
+// Record calculates the P&L of an included bundle from its TXs, their receipts and the block header.
+// The revenue can be nil when only the costs are tracked.
+// The coinbase payments made by the contract calls aren't visible in the receipts, see RecordSim.
+func (self *PnLTracker) Record(
+	id, strategy string,
+	header *types.Header,
+	txs []*types.Transaction,
+	receipts []*types.Receipt,
+	revenue *big.Int,
+) (*BundlePnL, error) {
+	return self.record(id, strategy, header, txs, receipts, nil, revenue)
+}
+
+// RecordSim is Record with the coinbase payments taken from the bundle simulation
+// so that the payments made by the contract calls are included.
+func (self *PnLTracker) RecordSim(
+	id, strategy string,
+	header *types.Header,
+	txs []*types.Transaction,
+	receipts []*types.Receipt,
+	sim *Response,
+	revenue *big.Int,
+) (*BundlePnL, error) {
+	profit, err := ParseSimProfit(sim)
+	if err != nil {
+		return nil, err
+	}
+	return self.record(id, strategy, header, txs, receipts, profit.EthSentToCoinbase, revenue)
+}
+
+func (self *PnLTracker) record(
+	id, strategy string,
+	header *types.Header,
+	txs []*types.Transaction,
+	receipts []*types.Receipt,
+	coinbasePaid *big.Int,
+	revenue *big.Int,
+) (*BundlePnL, error) {
+	if len(txs) != len(receipts) {
+		return nil, errors.Errorf("TXs and receipts count mismatch txs:%v receipts:%v", len(txs), len(receipts))
+	}
+	p := &BundlePnL{
+		ID:           id,
+		Strategy:     strategy,
+		Block:        header.Number.Uint64(),
+		Time:         time.Unix(int64(header.Time), 0).UTC(),
+		GasPaid:      new(big.Int),
+		CoinbasePaid: new(big.Int),
+		Revenue:      new(big.Int),
+		Refunds:      new(big.Int),
+		TokenDeltas:  make(map[common.Address]*big.Int),
+	}
+	if revenue != nil {
+		p.Revenue.Set(revenue)
+	}
+
+	for i, tx := range txs {
+		sender, err := types.Sender(types.LatestSignerForChainID(tx.ChainId()), tx)
+		if err != nil {
+			return nil, errors.Wrapf(err, "recover TX sender index:%v", i)
+		}
+		if self.tracked(sender) {
+			gasPrice := EffectiveGasPrice(tx, header.BaseFee)
+			p.GasPaid.Add(p.GasPaid, new(big.Int).Mul(gasPrice, new(big.Int).SetUint64(receipts[i].GasUsed)))
+			if coinbasePaid == nil && tx.To() != nil && *tx.To() == header.Coinbase && receipts[i].Status == types.ReceiptStatusSuccessful {
+				p.CoinbasePaid.Add(p.CoinbasePaid, tx.Value())
+			}
+		}
+		for _, l := range receipts[i].Logs {
+			if len(l.Topics) != 3 || l.Topics[0] != transferTopic || len(l.Data) != 32 {
+				continue
+			}
+			amount := new(big.Int).SetBytes(l.Data)
+			if self.tracked(common.BytesToAddress(l.Topics[2].Bytes())) {
+				addDelta(p.TokenDeltas, l.Address, amount)
+			}
+			if self.tracked(common.BytesToAddress(l.Topics[1].Bytes())) {
+				addDelta(p.TokenDeltas, l.Address, new(big.Int).Neg(amount))
+			}
+		}
+	}
+
+	if coinbasePaid != nil {
+		p.CoinbasePaid.Set(coinbasePaid)
+	}
+
+	self.mtx.Lock()
+	self.records = append(self.records, p)
+	self.pruneLocked()
+	self.totals.add(p)
+	self.groupLocked(self.byStrategy, p.Strategy).add(p)
+	self.groupLocked(self.byDay, pnlDay(p)).add(p)
+	self.mtx.Unlock()
+	self.export(p)
+	return p, nil
+}
+
+func (self *PnLTracker) groupLocked(groups map[string]*PnLTotals, key string) *PnLTotals {
+	if groups[key] == nil {
+		groups[key] = newPnLTotals()
+	}
+	return groups[key]
+}
+
+func pnlDay(p *BundlePnL) string {
+	return p.Time.Format("2006-01-02")
+}
+
+// RecordRefund adds a refund received for the recorded bundle with the given ID,
+// i.e. one of the FeeRefunds matched by the bundle hash.
+func (self *PnLTracker) RecordRefund(id string, amount *big.Int) error {
+	self.mtx.Lock()
+	var found *BundlePnL
+	for i := len(self.records) - 1; i >= 0; i-- {
+		if p := self.records[i]; p.ID == id {
+			if p.Refunds == nil {
+				p.Refunds = new(big.Int)
+			}
+			p.Refunds.Add(p.Refunds, amount)
+			self.totals.addRefund(amount)
+			self.groupLocked(self.byStrategy, p.Strategy).addRefund(amount)
+			self.groupLocked(self.byDay, pnlDay(p)).addRefund(amount)
+			found = p
+			break
+		}
+	}
+	self.mtx.Unlock()
+	if found == nil {
+		return errors.Wrapf(ErrBundleNotFound, "id:%v", id)
+	}
+	self.export(found)
+	return nil
+}
+
+// export sets the total gauges of the strategy and the day of the changed bundle.
+func (self *PnLTracker) export(p *BundlePnL) {
+	self.mtx.RLock()
+	m := self.metrics
+	self.mtx.RUnlock()
+	if m == nil {
+		return
+	}
+	day := pnlDay(p)
+	self.mtx.RLock()
+	strategy, daily := self.byStrategy[p.Strategy].copy(), self.byDay[day].copy()
+	self.mtx.RUnlock()
+	exportPnLTotals(m, MetricPnLStrategy, MetricPnLStrategyBundles, "strategy", p.Strategy, strategy)
+	exportPnLTotals(m, MetricPnLDay, MetricPnLDayBundles, "day", day, daily)
+}
+
+func exportPnLTotals(m Metrics, name, bundles, group, key string, totals *PnLTotals) {
+	for field, v := range map[string]*big.Int{
+		"gas_paid":      totals.GasPaid,
+		"coinbase_paid": totals.CoinbasePaid,
+		"revenue":       totals.Revenue,
+		"refunds":       totals.Refunds,
+		"net":           totals.Net,
+	} {
+		f, _ := new(big.Float).SetInt(v).Float64()
+		m.Set(name, f, group, key, "field", field)
+	}
+	m.Set(bundles, float64(totals.Bundles), group, key)
+}
+
+// Records returns the last recorded bundles sorted by block, see SetMaxRecords.
+func (self *PnLTracker) Records() []*BundlePnL {
+	self.mtx.RLock()
+	defer self.mtx.RUnlock()
+	res := append([]*BundlePnL(nil), self.records...)
+	sort.SliceStable(res, func(i, j int) bool { return res[i].Block < res[j].Block })
+	return res
+}
+
+// Totals returns the totals of all the recorded bundles.
+func (self *PnLTracker) Totals() *PnLTotals {
+	self.mtx.RLock()
+	defer self.mtx.RUnlock()
+	return self.totals.copy()
+}
+
+func (self *PnLTracker) ByStrategy() map[string]*PnLTotals {
+	self.mtx.RLock()
+	defer self.mtx.RUnlock()
+	return copyPnLGroups(self.byStrategy)
+}
+
+// ByDay groups the totals by the UTC day of the block in the 2006-01-02 format.
+func (self *PnLTracker) ByDay() map[string]*PnLTotals {
+	self.mtx.RLock()
+	defer self.mtx.RUnlock()
+	return copyPnLGroups(self.byDay)
+}
+
+func copyPnLGroups(groups map[string]*PnLTotals) map[string]*PnLTotals {
+	res := make(map[string]*PnLTotals, len(groups))
+	for k, v := range groups {
+		res[k] = v.copy()
+	}
+	return res
+}
+
+func (self *PnLTracker) tracked(addr common.Address) bool {
+	self.mtx.RLock()
+	defer self.mtx.RUnlock()
+	return self.accounts[addr]
+}
+
+// EffectiveGasPrice returns the gas price paid by the TX in a block with the given base fee.
+func EffectiveGasPrice(tx *types.Transaction, baseFee *big.Int) *big.Int {
+	if baseFee == nil || tx.Type() == types.LegacyTxType || tx.Type() == types.AccessListTxType {
+		return tx.GasPrice()
+	}
+	price := new(big.Int).Add(baseFee, tx.GasTipCap())
+	if price.Cmp(tx.GasFeeCap()) > 0 {
+		return new(big.Int).Set(tx.GasFeeCap())
+	}
+	return price
+}
+
+func addDelta(deltas map[common.Address]*big.Int, token common.Address, amount *big.Int) {
+	if deltas[token] == nil {
+		deltas[token] = new(big.Int)
+	}
+	deltas[token].Add(deltas[token], amount)
+}
+
+func addDeltas(dst, src map[common.Address]*big.Int) {
+	for token, amount := range src {
+		addDelta(dst, token, amount)
+	}
+}
@@ -0,0 +1,125 @@
+// Copyright (c) The Cryptorium Authors.
+// Licensed under the MIT License.
+
+package flashbot
+
+import (
+	"math/big"
+	"testing"
+
+	"github.com/cryptoriums/packages/testutil"
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/crypto"
+)
+
+func TestPnLTracker(t *testing.T) {
+	prvKey, err := crypto.GenerateKey()
+	testutil.Ok(t, err)
+	from := crypto.PubkeyToAddress(prvKey.PublicKey)
+	coinbase := common.HexToAddress("0xc0")
+	token := common.HexToAddress("0x70")
+
+	sign := func(to common.Address, value int64) *types.Transaction {
+		tx, err := types.SignNewTx(prvKey, types.LatestSignerForChainID(big.NewInt(1)), &types.DynamicFeeTx{
+			ChainID:   big.NewInt(1),
+			To:        &to,
+			Value:     big.NewInt(value),
+			Gas:       21000,
+			GasFeeCap: big.NewInt(30),
+			GasTipCap: big.NewInt(5),
+		})
+		testutil.Ok(t, err)
+		return tx
+	}
+	swap := sign(token, 0)
+	payment := sign(coinbase, 1000)
+
+	transfer := &types.Log{
+		Address: token,
+		Topics:  []common.Hash{transferTopic, common.HexToHash("0x01"), from.Hash()},
+		Data:    common.LeftPadBytes(big.NewInt(500).Bytes(), 32),
+	}
+	receipts := []*types.Receipt{
+		{Status: types.ReceiptStatusSuccessful, GasUsed: 100, Logs: []*types.Log{transfer}},
+		{Status: types.ReceiptStatusSuccessful, GasUsed: 21000},
+	}
+	header := &types.Header{Number: big.NewInt(10), Time: 1640995200, Coinbase: coinbase, BaseFee: big.NewInt(10)}
+
+	tracker := NewPnLTracker(from)
+	metrics := newMetricsMock()
+	tracker.SetMetrics(metrics)
+	p, err := tracker.Record("a", "arb", header, []*types.Transaction{swap, payment}, receipts, big.NewInt(1_000_000))
+	testutil.Ok(t, err)
+	// The base fee plus the tip is below the fee cap.
+	testutil.Equals(t, big.NewInt(15*21100), p.GasPaid)
+	testutil.Equals(t, big.NewInt(1000), p.CoinbasePaid)
+	testutil.Equals(t, big.NewInt(1_000_000-15*21100-1000), p.Net())
+	testutil.Equals(t, big.NewInt(500), p.TokenDeltas[token])
+
+	header = &types.Header{Number: big.NewInt(11), Time: 1641081600, Coinbase: coinbase, BaseFee: big.NewInt(10)}
+	_, err = tracker.Record("b", "liquidation", header, []*types.Transaction{payment}, receipts[1:], nil)
+	testutil.Ok(t, err)
+
+	testutil.Ok(t, tracker.RecordRefund("a", big.NewInt(300)))
+	testutil.Equals(t, big.NewInt(1_000_000+300-15*21100-1000), p.Net())
+	testutil.NotOk(t, tracker.RecordRefund("unknown", big.NewInt(1)))
+
+	totals := tracker.Totals()
+	testutil.Equals(t, 2, totals.Bundles)
+	testutil.Equals(t, big.NewInt(2000), totals.CoinbasePaid)
+	testutil.Equals(t, big.NewInt(300), totals.Refunds)
+	testutil.Equals(t, 2, len(tracker.ByStrategy()))
+	days := tracker.ByDay()
+	testutil.Equals(t, 1, days["2022-01-01"].Bundles)
+	testutil.Equals(t, 1, days["2022-01-02"].Bundles)
+
+	// The gauges are updated with the refund.
+	net := metrics.get(MetricPnLStrategy, "strategy", "arb", "field", "net")
+	testutil.Equals(t, []float64{1_000_000 - 15*21100 - 1000, 1_000_000 + 300 - 15*21100 - 1000}, net)
+	testutil.Equals(t, []float64{300}, metrics.get(MetricPnLDay, "day", "2022-01-01", "field", "refunds")[1:])
+	testutil.Equals(t, []float64{1}, metrics.get(MetricPnLDayBundles, "day", "2022-01-02"))
+	testutil.Equals(t, []float64{-1000 - 15*21000}, metrics.get(MetricPnLStrategy, "strategy", "liquidation", "field", "net"))
+}
+
+func TestPnLTrackerSimAndRotation(t *testing.T) {
+	prvKey, err := crypto.GenerateKey()
+	testutil.Ok(t, err)
+	from := crypto.PubkeyToAddress(prvKey.PublicKey)
+	contract := common.HexToAddress("0x70")
+	tx, err := types.SignNewTx(prvKey, types.LatestSignerForChainID(big.NewInt(1)), &types.DynamicFeeTx{
+		ChainID:   big.NewInt(1),
+		To:        &contract,
+		Gas:       21000,
+		GasFeeCap: big.NewInt(30),
+		GasTipCap: big.NewInt(5),
+	})
+	testutil.Ok(t, err)
+	receipts := []*types.Receipt{{Status: types.ReceiptStatusSuccessful, GasUsed: 21000}}
+	header := &types.Header{Number: big.NewInt(10), Time: 1640995200, Coinbase: common.HexToAddress("0xc0"), BaseFee: big.NewInt(10)}
+
+	tracker := NewPnLTracker(from)
+	tracker.SetMaxRecords(2)
+	// The contract pays the coinbase which only the simulation shows.
+	for i, id := range []string{"a", "b", "c"} {
+		sim := &Response{Result: Result{Metadata: Metadata{CoinbaseDiff: "105700", EthSentToCoinbase: "700", GasFees: "105000"}}}
+		p, err := tracker.RecordSim(id, "arb", header, []*types.Transaction{tx}, receipts, sim, big.NewInt(int64(i)))
+		testutil.Ok(t, err)
+		testutil.Equals(t, big.NewInt(700), p.CoinbasePaid)
+	}
+
+	recs := tracker.Records()
+	testutil.Equals(t, 2, len(recs))
+	testutil.Equals(t, "b", recs[0].ID)
+	testutil.NotOk(t, tracker.RecordRefund("a", big.NewInt(1)))
+	testutil.Ok(t, tracker.RecordRefund("c", big.NewInt(100)))
+
+	// The totals still include the rotated out bundle.
+	totals := tracker.Totals()
+	testutil.Equals(t, 3, totals.Bundles)
+	testutil.Equals(t, big.NewInt(2100), totals.CoinbasePaid)
+	testutil.Equals(t, big.NewInt(100), totals.Refunds)
+	testutil.Equals(t, big.NewInt(3+100-3*(15*21000+700)), totals.Net)
+	testutil.Equals(t, totals, tracker.ByStrategy()["arb"])
+	testutil.Equals(t, totals, tracker.ByDay()["2022-01-01"])
+}
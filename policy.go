@@ -0,0 +1,62 @@
+// Copyright (c) The Cryptorium Authors.
+// Licensed under the MIT License.
+
+package flashbot
+
+import (
+	"math/big"
+	"strings"
+
+	"github.com/pkg/errors"
+)
+
+// ErrPolicyRejected is wrapped by the errors of the built-in simulation policies.
+var ErrPolicyRejected = errors.New("bundle rejected by policy")
+
+// SimPolicy accepts or rejects a simulated bundle before it is sent.
+type SimPolicy func(sim *SimProfit) error
+
+// MaxGasUsedPolicy rejects bundles that use more than the given gas.
+func MaxGasUsedPolicy(max uint64) SimPolicy {
+	return func(sim *SimProfit) error {
+		var gas uint64
+		for _, tx := range sim.Sim.Results {
+			gas += tx.GasUsed
+		}
+		if gas > max {
+			return errors.Wrapf(ErrPolicyRejected, "gas used:%v max:%v", gas, max)
+		}
+		return nil
+	}
+}
+
+// MinGasPricePolicy rejects bundles with an effective bundle gas price below the given one.
+func MinGasPricePolicy(min *big.Int) SimPolicy {
+	return func(sim *SimProfit) error {
+		price, err := parseWei("bundle gas price", sim.Sim.BundleGasPrice)
+		if err != nil {
+			return err
+		}
+		if price.Cmp(min) < 0 {
+			return errors.Wrapf(ErrPolicyRejected, "bundle gas price:%v min:%v", price, min)
+		}
+		return nil
+	}
+}
+
+// NoRevertPolicy rejects bundles with reverting TXs except the ones with the given hashes.
+func NoRevertPolicy(allowed ...string) SimPolicy {
+	allow := make(map[string]bool, len(allowed))
+	for _, h := range allowed {
+		allow[strings.ToLower(h)] = true
+	}
+	return func(sim *SimProfit) error {
+		for i, tx := range sim.Sim.Results {
+			if (tx.Error == "" && tx.Revert == "") || allow[strings.ToLower(tx.TxHash)] {
+				continue
+			}
+			return errors.Wrapf(ErrPolicyRejected, "TX reverted index:%v hash:%v err:%v revert:%v", i, tx.TxHash, tx.Error, tx.Revert)
+		}
+		return nil
+	}
+}
@@ -0,0 +1,61 @@
+// Copyright (c) The Cryptorium Authors.
+// Licensed under the MIT License.
+
+package flashbot
+
+import (
+	"context"
+	"encoding/json"
+	"math/big"
+	"testing"
+
+	"github.com/cryptoriums/packages/testutil"
+	"github.com/pkg/errors"
+)
+
+func TestSimulateAndSendPolicies(t *testing.T) {
+	ctx := context.Background()
+	relay := newRelayMock(t, func(method string, params json.RawMessage) (interface{}, *jsonError) {
+		if method == "eth_callBundle" {
+			return Result{
+				BundleGasPrice: "50",
+				Metadata:       Metadata{CoinbaseDiff: "1500", GasFees: "500"},
+				Results: []TxResult{
+					{TxHash: "0xAA", GasUsed: 20},
+					{TxHash: "0xbb", GasUsed: 10, Error: "execution reverted"},
+				},
+			}, nil
+		}
+		return Result{BundleHash: "0xbundle"}, nil
+	})
+	fb := newTestFlashbot(t, relay.URL)
+
+	for name, tc := range map[string]struct {
+		policy SimPolicy
+		ok     bool
+	}{
+		"gas under max":      {MaxGasUsedPolicy(30), true},
+		"gas over max":       {MaxGasUsedPolicy(29), false},
+		"gas price above":    {MinGasPricePolicy(big.NewInt(50)), true},
+		"gas price below":    {MinGasPricePolicy(big.NewInt(51)), false},
+		"revert not allowed": {NoRevertPolicy("0xaa"), false},
+		"revert allowed":     {NoRevertPolicy("0xBB"), true},
+	} {
+		t.Run(name, func(t *testing.T) {
+			resp, profit, err := fb.SimulateAndSend(ctx, []string{"0x01"}, 10, SimulateAndSendOpts{Policies: []SimPolicy{tc.policy}})
+			testutil.Equals(t, "50", profit.Sim.BundleGasPrice)
+			if tc.ok {
+				testutil.Ok(t, err)
+				testutil.Equals(t, "0xbundle", resp.BundleHash)
+				return
+			}
+			testutil.Assert(t, errors.Is(err, ErrPolicyRejected), "unexpected error:%v", err)
+		})
+	}
+
+	custom := errors.New("custom")
+	_, _, err := fb.SimulateAndSend(ctx, []string{"0x01"}, 10, SimulateAndSendOpts{
+		Policies: []SimPolicy{MaxGasUsedPolicy(100), func(*SimProfit) error { return custom }},
+	})
+	testutil.Assert(t, errors.Is(err, custom), "unexpected error:%v", err)
+}
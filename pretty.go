@@ -0,0 +1,128 @@
+// Copyright (c) The Cryptorium Authors.
+// Licensed under the MIT License.
+
+package flashbot
+
+import (
+	"fmt"
+	"math/big"
+	"strings"
+	"time"
+
+	"github.com/ethereum/go-ethereum/params"
+)
+
+// FormatWei renders a wei amount in ETH from 0.001 ETH, in gwei below that down to 0.001 gwei and in wei for the dust.
+func FormatWei(wei *big.Int) string {
+	if wei == nil || wei.Sign() == 0 {
+		return "0"
+	}
+	abs := new(big.Int).Abs(wei)
+	if abs.Cmp(big.NewInt(params.Ether/1000)) >= 0 {
+		return formatUnit(wei, params.Ether, 6) + " ETH"
+	}
+	if abs.Cmp(big.NewInt(params.GWei/1000)) < 0 {
+		return wei.String() + " wei"
+	}
+	return formatUnit(wei, params.GWei, 3) + " gwei"
+}
+
+func formatUnit(wei *big.Int, unit int64, prec int) string {
+	v := new(big.Float).Quo(new(big.Float).SetInt(wei), big.NewFloat(float64(unit)))
+	s := v.Text('f', prec)
+	if strings.Contains(s, ".") {
+		s = strings.TrimRight(strings.TrimRight(s, "0"), ".")
+	}
+	return s
+}
+
+// formatWeiString renders a decimal wei string as returned by the relays and keeps the invalid values as they are.
+func formatWeiString(wei string) string {
+	v, err := parseWei("wei", wei)
+	if err != nil {
+		return wei
+	}
+	return FormatWei(v)
+}
+
+func (self Response) String() string {
+	if self.Error.Code != 0 {
+		return fmt.Sprintf("error code:%v msg:%v", self.Error.Code, self.Error.Message)
+	}
+	return self.Result.String()
+}
+
+func (self Result) String() string {
+	var b strings.Builder
+	fmt.Fprintf(&b, "bundle hash:%v gas price:%v coinbase diff:%v sent to coinbase:%v gas fees:%v txs:%v",
+		self.BundleHash, formatWeiString(self.BundleGasPrice), formatWeiString(self.CoinbaseDiff),
+		formatWeiString(self.EthSentToCoinbase), formatWeiString(self.GasFees), len(self.Results))
+	if gas := self.TotalGasUsed(); gas > 0 {
+		fmt.Fprintf(&b, " gas used:%v", gas)
+	}
+	for i, tx := range self.Results {
+		fmt.Fprintf(&b, "\n  %v: %v", i, tx)
+	}
+	return b.String()
+}
+
+func (self TxResult) String() string {
+	msg := fmt.Sprintf("tx:%v from:%v gas used:%v gas price:%v coinbase diff:%v",
+		self.TxHash, self.FromAddress, self.GasUsed, formatWeiString(self.GasPrice), formatWeiString(self.CoinbaseDiff))
+	if self.Error != "" {
+		msg += fmt.Sprintf(" err:%v", self.Error)
+	}
+	if self.Revert != "" {
+		msg += fmt.Sprintf(" revert:%v", self.Revert)
+	}
+	return msg
+}
+
+// String includes the builders stats reported by the relays that track the builders.
+func (self BundleStats) String() string {
+	msg := fmt.Sprintf("status:%v high priority:%v", self.Status(), self.IsHighPriority)
+	for _, t := range []struct {
+		name string
+		t    time.Time
+	}{
+		{"simulated", self.SimulatedAt},
+		{"submitted", self.SubmittedAt},
+		{"sent", self.SentToMinersAt},
+	} {
+		if !t.t.IsZero() {
+			msg += fmt.Sprintf(" %v:%v", t.name, t.t.UTC().Format(time.RFC3339Nano))
+		}
+	}
+	for _, s := range []struct {
+		name  string
+		stats []BuilderStats
+	}{
+		{"considered by", self.ConsideredByBuildersAt},
+		{"sealed by", self.SealedByBuildersAt},
+	} {
+		if len(s.stats) == 0 {
+			continue
+		}
+		builders := make([]string, len(s.stats))
+		for i, b := range s.stats {
+			builders[i] = b.String()
+		}
+		msg += fmt.Sprintf(" %v:[%v]", s.name, strings.Join(builders, " "))
+	}
+	return msg
+}
+
+func (self BuilderStats) String() string {
+	key := self.Pubkey
+	if len(key) > 14 {
+		key = shortHash(key)
+	}
+	return fmt.Sprintf("%v@%v", key, self.Timestamp.UTC().Format(time.RFC3339Nano))
+}
+
+func (self BundleUserStats) String() string {
+	return fmt.Sprintf("high priority:%v miner payments all time:%v 7d:%v 1d:%v gas simulated all time:%v 7d:%v 1d:%v",
+		self.IsHighPriority,
+		formatWeiString(self.AllTimeMinerPayments), formatWeiString(self.Last7dMinerPayments), formatWeiString(self.Last1dMinerPayments),
+		self.AllTimeGasSimulated, self.Last7dGasSimulated, self.Last1dGasSimulated)
+}
@@ -0,0 +1,60 @@
+// Copyright (c) The Cryptorium Authors.
+// Licensed under the MIT License.
+
+package flashbot
+
+import (
+	"fmt"
+	"math/big"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/cryptoriums/packages/testutil"
+)
+
+func TestFormatWei(t *testing.T) {
+	for wei, exp := range map[int64]string{
+		0:                          "0",
+		1:                          "1 wei",
+		1_500_000_000:              "1.5 gwei",
+		123_456_789_000:            "123.457 gwei",
+		1_000_000_000_000_000:      "0.001 ETH",
+		-2_500_000_000_000_000_000: "-2.5 ETH",
+	} {
+		testutil.Equals(t, exp, FormatWei(big.NewInt(wei)))
+	}
+	testutil.Equals(t, "0", FormatWei(nil))
+	testutil.Equals(t, "bad", formatWeiString("bad"))
+}
+
+func TestPrettyPrint(t *testing.T) {
+	resp := &Response{Result: Result{
+		BundleHash:     "0xbundle",
+		BundleGasPrice: "2000000000",
+		Metadata:       Metadata{CoinbaseDiff: "10000000000000000"},
+		Results: []TxResult{
+			{TxHash: "0xaa", GasUsed: 21_000, GasPrice: "1000000000", Revert: "too late"},
+		},
+	}}
+	out := fmt.Sprintf("%+v", resp)
+	testutil.Assert(t, strings.Contains(out, "gas price:2 gwei coinbase diff:0.01 ETH"), "unexpected output:%v", out)
+	testutil.Assert(t, strings.Contains(out, "\n  0: tx:0xaa"), "missing TX:%v", out)
+	testutil.Assert(t, strings.Contains(out, "revert:too late"), "missing revert:%v", out)
+	testutil.Assert(t, strings.Contains(out, "gas used:21000"), "missing gas used:%v", out)
+
+	testutil.Equals(t, "error code:-32000 msg:bad bundle", Response{Error: Error{Code: -32000, Message: "bad bundle"}}.String())
+
+	ts := time.Date(2022, 6, 1, 0, 0, 0, 0, time.UTC)
+	stats := BundleStats{
+		IsSimulated:            true,
+		SimulatedAt:            ts,
+		ConsideredByBuildersAt: []BuilderStats{{Pubkey: "0x81babeec8c9f2bb9c329fd8a3b176032fe0ab5f3b92a3f44d4575a231c7bd9c31d10b6328ef68ed1e8c02a3dbc8e80f9", Timestamp: ts}},
+	}
+	testutil.Equals(t,
+		"status:considered high priority:false simulated:2022-06-01T00:00:00Z considered by:[0x81babe..80f9@2022-06-01T00:00:00Z]",
+		fmt.Sprintf("%v", stats))
+
+	user := BundleUserStats{AllTimeMinerPayments: "2000000000000000000", AllTimeGasSimulated: "21000"}
+	testutil.Assert(t, strings.Contains(user.String(), "all time:2 ETH"), "unexpected output:%v", user)
+}
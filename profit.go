@@ -0,0 +1,138 @@
+// Copyright (c) The Cryptorium Authors.
+// Licensed under the MIT License.
+
+package flashbot
+
+import (
+	"context"
+	"math/big"
+
+	"github.com/pkg/errors"
+)
+
+// ErrBelowMinProfit is returned when the simulated profit is not above the threshold.
+var ErrBelowMinProfit = errors.New("bundle profit below the minimum")
+
+// SimProfit are the bundle simulation totals in wei.
+type SimProfit struct {
+	CoinbaseDiff      *big.Int
+	EthSentToCoinbase *big.Int
+	GasFees           *big.Int
+	// Profit is the net profit used for the min profit check.
+	Profit *big.Int
+	// Sim is the full simulation response.
+	Sim *Response
+}
+
+// ParseSimProfit parses the simulation totals.
+func ParseSimProfit(resp *Response) (*SimProfit, error) {
+	if resp == nil {
+		return nil, errors.New("empty simulation response")
+	}
+	res := &SimProfit{Sim: resp}
+	for _, f := range []struct {
+		name string
+		val  string
+		dst  **big.Int
+	}{
+		{"CoinbaseDiff", resp.CoinbaseDiff, &res.CoinbaseDiff},
+		{"EthSentToCoinbase", resp.EthSentToCoinbase, &res.EthSentToCoinbase},
+		{"GasFees", resp.GasFees, &res.GasFees},
+	} {
+		v, err := parseWei(f.name, f.val)
+		if err != nil {
+			return nil, errors.Wrap(err, "simulation totals")
+		}
+		*f.dst = v
+	}
+	return res, nil
+}
+
+type SimulateAndSendOpts struct {
+	// MinProfit is the threshold the net profit needs to exceed, nil disables the check.
+	MinProfit *big.Int
+	// Profit calculates the net profit from the simulation.
+	// By default it is the CoinbaseDiff minus the GasFees,
+	// i.e. the payment on top of the gas which for bundles that share the profit with the builder tracks the profit.
+	Profit func(sim *SimProfit) *big.Int
+	// StateBlock is the block whose state is used for the simulation, zero means the latest.
+	StateBlock uint64
+	// Budget reserves the simulated gas fees and coinbase payments under the bundle hash
+	// and rejects the bundle when they would exceed the spending cap.
+	// The reservation is released when the send fails.
+	Budget *BudgetGuard
+	// Policies are run in order after the profit check and the first error rejects the bundle.
+	Policies []SimPolicy
+	// Blocks is the number of consecutive blocks targeted from the block number, 1 when zero.
+	// The bundle is sent through SendBundleForBlocks and the response is of the first block that accepted it.
+	Blocks uint64
+}
+
+// SimulateAndSend simulates the bundle and sends it only when the net profit exceeds the threshold
+// and all policies accept it
+// to avoid submitting negative-EV bundles when the conditions change between the build and the send.
+// The returned profit includes the simulation response.
+func (self *Flashbot) SimulateAndSend(ctx context.Context, txsHex []string, blockNum uint64, opts SimulateAndSendOpts) (*Response, *SimProfit, error) {
+	sim, err := self.CallBundle(ctx, txsHex, opts.StateBlock)
+	if err != nil {
+		return nil, nil, errors.Wrap(err, "simulate bundle")
+	}
+	profit, err := ParseSimProfit(sim)
+	if err != nil {
+		return nil, nil, err
+	}
+	if opts.Profit != nil {
+		profit.Profit = opts.Profit(profit)
+	} else {
+		profit.Profit = new(big.Int).Sub(profit.CoinbaseDiff, profit.GasFees)
+	}
+
+	if opts.MinProfit != nil && profit.Profit.Cmp(opts.MinProfit) <= 0 {
+		return nil, profit, errors.Wrapf(ErrBelowMinProfit, "profit:%v min:%v", profit.Profit, opts.MinProfit)
+	}
+	for _, p := range opts.Policies {
+		if err := p(profit); err != nil {
+			return nil, profit, errors.Wrap(err, "simulation policy")
+		}
+	}
+
+	var release func()
+	if opts.Budget != nil {
+		key, err := ComputeBundleHash(txsHex)
+		if err != nil {
+			return nil, profit, errors.Wrap(err, "bundle hash for the budget")
+		}
+		reserved, err := opts.Budget.reserve(key.Hex(), new(big.Int).Add(profit.GasFees, profit.EthSentToCoinbase))
+		if err != nil {
+			return nil, profit, err
+		}
+		if reserved {
+			release = func() { opts.Budget.Release(key.Hex()) }
+		}
+	}
+
+	resp, err := self.sendSimulated(ctx, txsHex, blockNum, opts.Blocks)
+	if err != nil {
+		if release != nil {
+			release()
+		}
+		return nil, profit, err
+	}
+	return resp, profit, nil
+}
+
+func (self *Flashbot) sendSimulated(ctx context.Context, txsHex []string, blockNum, blocks uint64) (*Response, error) {
+	if blocks <= 1 {
+		return self.SendBundle(ctx, txsHex, blockNum)
+	}
+	subs, err := self.SendBundleForBlocks(ctx, txsHex, blockNum, blocks)
+	if err != nil {
+		return nil, err
+	}
+	for _, s := range subs {
+		if s.Err == nil {
+			return s.Response, nil
+		}
+	}
+	return nil, errors.Errorf("bundle rejected for all blocks from:%v count:%v", blockNum, blocks)
+}
@@ -0,0 +1,68 @@
+// Copyright (c) The Cryptorium Authors.
+// Licensed under the MIT License.
+
+package flashbot
+
+import (
+	"context"
+	"encoding/json"
+	"math/big"
+	"sort"
+	"testing"
+
+	"github.com/cryptoriums/packages/testutil"
+	"github.com/pkg/errors"
+)
+
+func TestSimulateAndSend(t *testing.T) {
+	ctx := context.Background()
+	relay := newRelayMock(t, func(method string, params json.RawMessage) (interface{}, *jsonError) {
+		if method == "eth_callBundle" {
+			return Result{Metadata: Metadata{CoinbaseDiff: "1500", EthSentToCoinbase: "1000", GasFees: "500"}}, nil
+		}
+		return Result{BundleHash: "0xbundle"}, nil
+	})
+	fb := newTestFlashbot(t, relay.URL)
+
+	_, profit, err := fb.SimulateAndSend(ctx, []string{"0x01"}, 10, SimulateAndSendOpts{MinProfit: big.NewInt(1000)})
+	testutil.Assert(t, errors.Is(err, ErrBelowMinProfit), "unexpected error:%v", err)
+	testutil.Equals(t, big.NewInt(1000), profit.Profit)
+	testutil.Equals(t, []string{"eth_callBundle"}, relay.Methods())
+
+	resp, profit, err := fb.SimulateAndSend(ctx, []string{"0x01"}, 10, SimulateAndSendOpts{MinProfit: big.NewInt(999)})
+	testutil.Ok(t, err)
+	testutil.Equals(t, "0xbundle", resp.BundleHash)
+	testutil.Equals(t, big.NewInt(500), profit.GasFees)
+
+	// A custom profit from the expected revenue.
+	_, _, err = fb.SimulateAndSend(ctx, []string{"0x01"}, 10, SimulateAndSendOpts{
+		MinProfit: big.NewInt(0),
+		Profit: func(sim *SimProfit) *big.Int {
+			return new(big.Int).Sub(big.NewInt(1200), sim.CoinbaseDiff)
+		},
+	})
+	testutil.Assert(t, errors.Is(err, ErrBelowMinProfit), "unexpected error:%v", err)
+}
+
+func TestSimulateAndSendBlocks(t *testing.T) {
+	ctx := context.Background()
+	var blocks []string
+	relay := newRelayMock(t, func(method string, params json.RawMessage) (interface{}, *jsonError) {
+		if method == "eth_callBundle" {
+			return Result{Metadata: Metadata{CoinbaseDiff: "1500", GasFees: "500"}}, nil
+		}
+		var p []ParamsSend
+		testutil.Ok(t, json.Unmarshal(params, &p))
+		blocks = append(blocks, p[0].BlockNum)
+		return Result{BundleHash: "0xbundle"}, nil
+	})
+	fb := newTestFlashbot(t, relay.URL)
+
+	resp, _, err := fb.SimulateAndSend(ctx, []string{"0x01"}, 10, SimulateAndSendOpts{Blocks: 3})
+	testutil.Ok(t, err)
+	testutil.Equals(t, "0xbundle", resp.BundleHash)
+	// The bundle is simulated once for all blocks.
+	testutil.Equals(t, []string{"eth_callBundle", "eth_sendBundle", "eth_sendBundle", "eth_sendBundle"}, relay.Methods())
+	sort.Strings(blocks)
+	testutil.Equals(t, []string{"0xa", "0xb", "0xc"}, blocks)
+}
@@ -0,0 +1,120 @@
+// Copyright (c) The Cryptorium Authors.
+// Licensed under the MIT License.
+
+// Package prommetrics exports the flashbot metrics to Prometheus.
+// The metric vectors are registered on first use with the label keys of that first sample.
+package prommetrics
+
+import (
+	"strings"
+	"sync"
+
+	"github.com/kachan28/flashbot"
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+type Metrics struct {
+	reg       prometheus.Registerer
+	namespace string
+	buckets   []float64
+
+	mtx        sync.Mutex
+	histograms map[string]*prometheus.HistogramVec
+	counters   map[string]*prometheus.CounterVec
+	gauges     map[string]*prometheus.GaugeVec
+}
+
+var _ flashbot.Metrics = (*Metrics)(nil)
+
+// New returns metrics registered in the registry with the namespace prefixed to every name.
+// The histograms use the flashbot.LatencyBuckets.
+func New(reg prometheus.Registerer, namespace string) *Metrics {
+	buckets := make([]float64, len(flashbot.LatencyBuckets))
+	for i, b := range flashbot.LatencyBuckets {
+		buckets[i] = b.Seconds()
+	}
+	return &Metrics{
+		reg:        reg,
+		namespace:  namespace,
+		buckets:    buckets,
+		histograms: make(map[string]*prometheus.HistogramVec),
+		counters:   make(map[string]*prometheus.CounterVec),
+		gauges:     make(map[string]*prometheus.GaugeVec),
+	}
+}
+
+// Observe adds the sample to the histogram.
+// The samples with other label keys than the first one of the metric are dropped.
+func (self *Metrics) Observe(name string, value float64, labels ...string) {
+	keys, values := split(labels)
+	self.mtx.Lock()
+	vec, ok := self.histograms[name]
+	if !ok {
+		vec = prometheus.NewHistogramVec(prometheus.HistogramOpts{
+			Namespace: self.namespace,
+			Name:      name,
+			Help:      help(name),
+			Buckets:   self.buckets,
+		}, keys)
+		self.register(vec)
+		self.histograms[name] = vec
+	}
+	self.mtx.Unlock()
+	if o, err := vec.GetMetricWithLabelValues(values...); err == nil {
+		o.Observe(value)
+	}
+}
+
+// Add increments the counter.
+func (self *Metrics) Add(name string, delta float64, labels ...string) {
+	keys, values := split(labels)
+	self.mtx.Lock()
+	vec, ok := self.counters[name]
+	if !ok {
+		vec = prometheus.NewCounterVec(prometheus.CounterOpts{Namespace: self.namespace, Name: name, Help: help(name)}, keys)
+		self.register(vec)
+		self.counters[name] = vec
+	}
+	self.mtx.Unlock()
+	if c, err := vec.GetMetricWithLabelValues(values...); err == nil {
+		c.Add(delta)
+	}
+}
+
+// Set sets the gauge.
+func (self *Metrics) Set(name string, value float64, labels ...string) {
+	keys, values := split(labels)
+	self.mtx.Lock()
+	vec, ok := self.gauges[name]
+	if !ok {
+		vec = prometheus.NewGaugeVec(prometheus.GaugeOpts{Namespace: self.namespace, Name: name, Help: help(name)}, keys)
+		self.register(vec)
+		self.gauges[name] = vec
+	}
+	self.mtx.Unlock()
+	if g, err := vec.GetMetricWithLabelValues(values...); err == nil {
+		g.Set(value)
+	}
+}
+
+// register registers the collector and ignores the duplicates,
+// i.e. when two Metrics share the registry the second one is only kept locally.
+func (self *Metrics) register(c prometheus.Collector) {
+	if self.reg != nil {
+		_ = self.reg.Register(c)
+	}
+}
+
+func split(labels []string) ([]string, []string) {
+	keys := make([]string, 0, len(labels)/2)
+	values := make([]string, 0, len(labels)/2)
+	for i := 0; i+1 < len(labels); i += 2 {
+		keys = append(keys, labels[i])
+		values = append(values, labels[i+1])
+	}
+	return keys, values
+}
+
+func help(name string) string {
+	return "flashbot " + strings.ReplaceAll(name, "_", " ")
+}
@@ -0,0 +1,34 @@
+// Copyright (c) The Cryptorium Authors.
+// Licensed under the MIT License.
+
+package prommetrics
+
+import (
+	"testing"
+
+	"github.com/cryptoriums/packages/testutil"
+	"github.com/prometheus/client_golang/prometheus"
+	promtest "github.com/prometheus/client_golang/prometheus/testutil"
+)
+
+func TestMetrics(t *testing.T) {
+	reg := prometheus.NewRegistry()
+	m := New(reg, "flashbot")
+
+	m.Observe("relay_submit_latency_seconds", 0.1, "relay", "a")
+	m.Observe("relay_submit_latency_seconds", 0.2, "relay", "b")
+	// Other label keys are dropped.
+	m.Observe("relay_submit_latency_seconds", 0.3, "other", "a")
+	m.Add("bundles_total", 2, "relay", "a")
+	m.Add("bundles_total", 1, "relay", "a")
+	m.Set("pnl_wei", 5, "strategy", "arb")
+	m.Set("pnl_wei", 7, "strategy", "arb")
+
+	testutil.Equals(t, 2, promtest.CollectAndCount(m.histograms["relay_submit_latency_seconds"]))
+	testutil.Equals(t, 3.0, promtest.ToFloat64(m.counters["bundles_total"]))
+	testutil.Equals(t, 7.0, promtest.ToFloat64(m.gauges["pnl_wei"]))
+
+	problems, err := promtest.GatherAndLint(reg)
+	testutil.Ok(t, err)
+	testutil.Equals(t, 0, len(problems), "lint problems:%v", problems)
+}
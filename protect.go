@@ -0,0 +1,211 @@
+// Copyright (c) The Cryptorium Authors.
+// Licensed under the MIT License.
+
+package flashbot
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/url"
+	"strconv"
+	"time"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/pkg/errors"
+)
+
+const (
+	ProtectStatusURL = "https://protect.flashbots.net/tx"
+	ProtectRPCURL    = "https://rpc.flashbots.net"
+)
+
+type ProtectStatus string
+
+const (
+	ProtectPending   ProtectStatus = "PENDING"
+	ProtectIncluded  ProtectStatus = "INCLUDED"
+	ProtectFailed    ProtectStatus = "FAILED"
+	ProtectCancelled ProtectStatus = "CANCELLED"
+	ProtectUnknown   ProtectStatus = "UNKNOWN"
+)
+
+// Final returns whether the status won't change anymore.
+func (self ProtectStatus) Final() bool {
+	return self == ProtectIncluded || self == ProtectFailed || self == ProtectCancelled
+}
+
+type ProtectTxStatus struct {
+	Status         ProtectStatus `json:"status"`
+	Hash           common.Hash   `json:"hash"`
+	MaxBlockNumber uint64        `json:"maxBlockNumber"`
+	FastMode       bool          `json:"fastMode"`
+	SeenInMempool  bool          `json:"seenInMempool"`
+}
+
+// Protect is a client for the Flashbots Protect APIs.
+type Protect struct {
+	StatusURL string
+	RPCURL    string
+	Client    *http.Client
+	// DryRun returns the TX hash from SendRawTransaction without sending the TX.
+	DryRun bool
+}
+
+// NewProtect returns a client for the mainnet Protect endpoints.
+func NewProtect(client *http.Client) *Protect {
+	if client == nil {
+		client = &http.Client{Timeout: 30 * time.Second}
+	}
+	return &Protect{StatusURL: ProtectStatusURL, RPCURL: ProtectRPCURL, Client: client}
+}
+
+// Status returns the status of a TX sent through Protect.
+func (self *Protect) Status(ctx context.Context, txHash common.Hash) (*ProtectTxStatus, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, self.StatusURL+"/"+txHash.Hex(), nil)
+	if err != nil {
+		return nil, errors.Wrap(err, "create protect status request")
+	}
+	resp, err := self.Client.Do(req)
+	if err != nil {
+		return nil, errors.Wrap(err, "protect status request")
+	}
+	defer resp.Body.Close()
+	body, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return nil, errors.Wrap(err, "read protect status response")
+	}
+	if resp.StatusCode != http.StatusOK {
+		return nil, errors.Errorf("bad protect status response status:%v body:%v", resp.Status, string(body))
+	}
+	status := &ProtectTxStatus{}
+	if err := json.Unmarshal(body, status); err != nil {
+		return nil, errors.Wrapf(err, "unmarshal protect status response:%v", string(body))
+	}
+	return status, nil
+}
+
+// WaitForStatus polls the TX status every interval until it is one of the given statuses
+// or any final status when none are given.
+func (self *Protect) WaitForStatus(ctx context.Context, txHash common.Hash, interval time.Duration, statuses ...ProtectStatus) (*ProtectTxStatus, error) {
+	if interval <= 0 {
+		interval = time.Second
+	}
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		status, err := self.Status(ctx, txHash)
+		if err != nil {
+			return nil, err
+		}
+		if len(statuses) == 0 && status.Status.Final() {
+			return status, nil
+		}
+		for _, s := range statuses {
+			if status.Status == s {
+				return status, nil
+			}
+		}
+		select {
+		case <-ctx.Done():
+			return status, errors.Wrapf(ctx.Err(), "wait protect status last:%v", status.Status)
+		case <-ticker.C:
+		}
+	}
+}
+
+// ProtectPreferences are the Protect RPC options that are passed as URL query parameters.
+type ProtectPreferences struct {
+	// Fast shares the TX with all builders and enables the fast mode.
+	Fast bool
+	// Hints selects the TX data shared with the searchers, i.e. calldata, contract_address, logs, function_selector, hash.
+	Hints []string
+	// Builders are the builders that may receive the TX.
+	Builders []string
+	// RefundAddress receives the MEV refunds instead of the TX sender.
+	RefundAddress *common.Address
+	// RefundPercent is the share of the MEV refunded, the Protect default when zero.
+	RefundPercent int
+	// OriginID identifies the application sending the TX.
+	OriginID string
+}
+
+// URL returns the Protect RPC URL with the preferences.
+func (self ProtectPreferences) URL(baseURL string) string {
+	if self.Fast {
+		baseURL += "/fast"
+	}
+	q := url.Values{}
+	for _, h := range self.Hints {
+		q.Add("hint", h)
+	}
+	for _, b := range self.Builders {
+		q.Add("builder", b)
+	}
+	if self.RefundAddress != nil {
+		refund := self.RefundAddress.Hex()
+		if self.RefundPercent > 0 {
+			refund += ":" + strconv.Itoa(self.RefundPercent)
+		}
+		q.Set("refund", refund)
+	}
+	if self.OriginID != "" {
+		q.Set("originId", self.OriginID)
+	}
+	if len(q) == 0 {
+		return baseURL
+	}
+	return baseURL + "?" + q.Encode()
+}
+
+// SendRawTransaction sends the signed TX through the Protect RPC and returns its hash.
+// In dry run mode the TX is only decoded to return its hash.
+func (self *Protect) SendRawTransaction(ctx context.Context, txHex string, prefs ProtectPreferences) (common.Hash, error) {
+	if self.DryRun {
+		tx, err := DecodeTx(txHex)
+		if err != nil {
+			return common.Hash{}, errors.Wrap(err, "decode dry run TX")
+		}
+		return tx.Hash(), nil
+	}
+	msg, err := newMessage("eth_sendRawTransaction", txHex)
+	if err != nil {
+		return common.Hash{}, errors.Wrap(err, "marshaling protect params")
+	}
+	payload, err := json.Marshal(msg)
+	if err != nil {
+		return common.Hash{}, err
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, prefs.URL(self.RPCURL), bytes.NewReader(payload))
+	if err != nil {
+		return common.Hash{}, errors.Wrap(err, "create protect request")
+	}
+	req.Header.Set("content-type", "application/json")
+	resp, err := self.Client.Do(req)
+	if err != nil {
+		return common.Hash{}, errors.Wrap(err, "protect request")
+	}
+	defer resp.Body.Close()
+	body, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return common.Hash{}, errors.Wrap(err, "read protect response")
+	}
+	if resp.StatusCode != http.StatusOK {
+		return common.Hash{}, errors.Errorf("bad protect response status:%v body:%v", resp.Status, string(body))
+	}
+
+	rr := &jsonrpcMessage{}
+	if err := json.Unmarshal(body, rr); err != nil {
+		return common.Hash{}, errors.Wrapf(err, "unmarshal protect response:%v", string(body))
+	}
+	if rr.Error != nil {
+		return common.Hash{}, errors.Errorf("protect request returned an error:%+v", *rr.Error)
+	}
+	var txHash common.Hash
+	if err := json.Unmarshal(rr.Result, &txHash); err != nil {
+		return common.Hash{}, errors.Wrapf(err, "unmarshal protect TX hash:%v", string(rr.Result))
+	}
+	return txHash, nil
+}
@@ -0,0 +1,93 @@
+// Copyright (c) The Cryptorium Authors.
+// Licensed under the MIT License.
+
+package flashbot
+
+import (
+	"context"
+	"encoding/json"
+	"math/big"
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/cryptoriums/packages/testutil"
+	"github.com/ethereum/go-ethereum/common"
+)
+
+func TestProtectWaitForStatus(t *testing.T) {
+	txHash := common.HexToHash("0x01")
+	var polls int32
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		testutil.Equals(t, "/tx/"+txHash.Hex(), r.URL.Path)
+		status := ProtectPending
+		if atomic.AddInt32(&polls, 1) > 2 {
+			status = ProtectIncluded
+		}
+		testutil.Ok(t, json.NewEncoder(w).Encode(ProtectTxStatus{Status: status, Hash: txHash}))
+	}))
+	defer srv.Close()
+
+	p := NewProtect(nil)
+	p.StatusURL = srv.URL + "/tx"
+	status, err := p.WaitForStatus(context.Background(), txHash, time.Millisecond)
+	testutil.Ok(t, err)
+	testutil.Equals(t, ProtectIncluded, status.Status)
+	testutil.Equals(t, int32(3), atomic.LoadInt32(&polls))
+}
+
+func TestProtectSendRawTransaction(t *testing.T) {
+	txHash := common.HexToHash("0x01")
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		testutil.Equals(t, "/fast", r.URL.Path)
+		q := r.URL.Query()
+		testutil.Equals(t, []string{"calldata", "logs"}, q["hint"])
+		testutil.Equals(t, []string{"flashbots"}, q["builder"])
+		testutil.Equals(t, common.HexToAddress("0x02").Hex()+":90", q.Get("refund"))
+
+		msg := &jsonrpcMessage{}
+		testutil.Ok(t, json.NewDecoder(r.Body).Decode(msg))
+		testutil.Equals(t, "eth_sendRawTransaction", msg.Method)
+		result, err := json.Marshal(txHash)
+		testutil.Ok(t, err)
+		testutil.Ok(t, json.NewEncoder(w).Encode(jsonrpcMessage{Version: "2.0", ID: msg.ID, Result: result}))
+	}))
+	defer srv.Close()
+
+	p := NewProtect(nil)
+	p.RPCURL = srv.URL
+	refund := common.HexToAddress("0x02")
+	hash, err := p.SendRawTransaction(context.Background(), "0x01", ProtectPreferences{
+		Fast:          true,
+		Hints:         []string{"calldata", "logs"},
+		Builders:      []string{"flashbots"},
+		RefundAddress: &refund,
+		RefundPercent: 90,
+	})
+	testutil.Ok(t, err)
+	testutil.Equals(t, txHash, hash)
+	testutil.Equals(t, ProtectRPCURL, ProtectPreferences{}.URL(ProtectRPCURL))
+}
+
+func TestProtectDryRun(t *testing.T) {
+	var calls int32
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		atomic.AddInt32(&calls, 1)
+	}))
+	defer srv.Close()
+
+	fb := newTestFlashbot(t, srv.URL)
+	to := fb.TxSigner().Address()
+	txHex, tx, err := fb.SignTx(TxSpec{To: &to, Gas: 21_000, GasFeeCap: big.NewInt(10)}.TxData(1, 0))
+	testutil.Ok(t, err)
+
+	p := NewProtect(nil)
+	p.RPCURL = srv.URL
+	p.DryRun = true
+	hash, err := p.SendRawTransaction(context.Background(), txHex, ProtectPreferences{})
+	testutil.Ok(t, err)
+	testutil.Equals(t, tx.Hash(), hash)
+	testutil.Equals(t, int32(0), atomic.LoadInt32(&calls))
+}
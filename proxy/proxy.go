@@ -0,0 +1,338 @@
+// Copyright (c) The Cryptorium Authors.
+// Licensed under the MIT License.
+
+// Package proxy is a private relay proxy that authenticates the signed searcher requests,
+// applies the policies and forwards them to the upstream relays signed with the proxy identities.
+package proxy
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"io"
+	"net/http"
+	"sync"
+	"time"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/go-kit/log"
+	"github.com/go-kit/log/level"
+	"github.com/kachan28/flashbot"
+	"github.com/pkg/errors"
+)
+
+// Upstream is implemented by *flashbot.Flashbot which signs the forwarded requests with its own identity.
+type Upstream interface {
+	Forward(ctx context.Context, method string, params json.RawMessage) ([]byte, error)
+}
+
+// DefaultMethods are the methods forwarded when the config has none.
+var DefaultMethods = []string{
+	"eth_sendBundle",
+	"eth_callBundle",
+	"eth_cancelBundle",
+	"eth_sendPrivateTransaction",
+	"eth_cancelPrivateTransaction",
+	"mev_sendBundle",
+	"mev_simBundle",
+}
+
+const defaultMaxBodySize = 10 << 20
+
+type Config struct {
+	// Upstreams receive every request and the reply of the first one that succeeds is returned.
+	Upstreams []Upstream
+	// Searchers are the allowed searcher identities, any signed request is allowed when empty.
+	Searchers []common.Address
+	// Methods are the allowed methods, DefaultMethods when empty.
+	Methods []string
+	// TxPolicy is checked for the TXs of the bundles and the private TXs, nil allows all.
+	TxPolicy *flashbot.AddressPolicy
+	// RateLimit is the max requests per second for each searcher with bursts of up to RateBurst, no limit when 0.
+	RateLimit float64
+	RateBurst int
+	// MaxBodySize is the max request size in bytes, 10MB by default.
+	MaxBodySize int64
+}
+
+type Proxy struct {
+	logger      log.Logger
+	upstreams   []Upstream
+	searchers   map[common.Address]bool
+	methods     map[string]bool
+	txPolicy    *flashbot.AddressPolicy
+	rateLimit   float64
+	rateBurst   int
+	maxBodySize int64
+
+	mtx      sync.Mutex
+	limiters map[common.Address]*flashbot.RateLimiter
+}
+
+func New(logger log.Logger, cfg Config) (*Proxy, error) {
+	if len(cfg.Upstreams) == 0 {
+		return nil, errors.New("at least one upstream is required")
+	}
+	if cfg.RateLimit < 0 || cfg.RateBurst < 0 {
+		return nil, errors.New("rate limit can't be negative")
+	}
+	self := &Proxy{
+		logger:      logger,
+		upstreams:   cfg.Upstreams,
+		searchers:   make(map[common.Address]bool),
+		methods:     make(map[string]bool),
+		txPolicy:    cfg.TxPolicy,
+		rateLimit:   cfg.RateLimit,
+		rateBurst:   cfg.RateBurst,
+		maxBodySize: cfg.MaxBodySize,
+		limiters:    make(map[common.Address]*flashbot.RateLimiter),
+	}
+	if self.maxBodySize == 0 {
+		self.maxBodySize = defaultMaxBodySize
+	}
+	for _, s := range cfg.Searchers {
+		self.searchers[s] = true
+	}
+	methods := cfg.Methods
+	if len(methods) == 0 {
+		methods = DefaultMethods
+	}
+	for _, m := range methods {
+		self.methods[m] = true
+	}
+	return self, nil
+}
+
+type request struct {
+	ID     json.RawMessage `json:"id"`
+	Method string          `json:"method"`
+	Params json.RawMessage `json:"params"`
+}
+
+type rpcError struct {
+	Code    int    `json:"code"`
+	Message string `json:"message"`
+}
+
+const (
+	codeInvalidRequest = -32600
+	codeMethodNotFound = -32601
+	codeInvalidParams  = -32602
+	codeUnauthorized   = -32001
+	codePolicy         = -32002
+	codeRateLimited    = -32005
+	codeUpstream       = -32603
+)
+
+func (self *Proxy) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		writeError(w, http.StatusMethodNotAllowed, nil, codeInvalidRequest, "only POST is supported")
+		return
+	}
+	body, err := io.ReadAll(http.MaxBytesReader(w, r.Body, self.maxBodySize))
+	if err != nil {
+		writeError(w, http.StatusRequestEntityTooLarge, nil, codeInvalidRequest, "read body:"+err.Error())
+		return
+	}
+
+	var req request
+	if err := json.Unmarshal(body, &req); err != nil {
+		writeError(w, http.StatusBadRequest, nil, codeInvalidRequest, "invalid JSON-RPC request")
+		return
+	}
+
+	searcher, err := flashbot.VerifySignatureHeader(body, r.Header.Get("X-Flashbots-Signature"))
+	if err != nil {
+		writeError(w, http.StatusUnauthorized, req.ID, codeUnauthorized, err.Error())
+		return
+	}
+	if len(self.searchers) > 0 && !self.searchers[searcher] {
+		writeError(w, http.StatusForbidden, req.ID, codeUnauthorized, "searcher not allowed:"+searcher.Hex())
+		return
+	}
+	if !self.limiter(searcher).Allow() {
+		writeError(w, http.StatusTooManyRequests, req.ID, codeRateLimited, "rate limit exceeded")
+		return
+	}
+	if !self.methods[req.Method] {
+		writeError(w, http.StatusBadRequest, req.ID, codeMethodNotFound, "method not allowed:"+req.Method)
+		return
+	}
+	if err := self.checkTxs(req.Method, req.Params); err != nil {
+		code := codeInvalidParams
+		if errors.Is(err, flashbot.ErrPolicyRejected) {
+			code = codePolicy
+		}
+		writeError(w, http.StatusBadRequest, req.ID, code, err.Error())
+		return
+	}
+
+	resp, err := self.forward(r.Context(), req)
+	if err != nil {
+		level.Error(self.logger).Log("msg", "forward request", "method", req.Method, "searcher", searcher.Hex(), "err", err)
+		writeError(w, http.StatusBadGateway, req.ID, codeUpstream, err.Error())
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	_, _ = w.Write(resp)
+}
+
+// forward sends the request to all upstreams and returns the first reply without a JSON-RPC error.
+// The slower upstreams aren't waited for but still get the request
+// as it isn't canceled when the searcher request is done.
+func (self *Proxy) forward(ctx context.Context, req request) ([]byte, error) {
+	type result struct {
+		resp []byte
+		err  error
+	}
+	results := make(chan result, len(self.upstreams))
+	upCtx := detached{ctx}
+	for _, u := range self.upstreams {
+		go func(u Upstream) {
+			resp, err := u.Forward(upCtx, req.Method, req.Params)
+			if err == nil && hasError(resp) {
+				err = errors.Errorf("upstream error reply:%v", string(resp))
+			}
+			results <- result{resp: resp, err: err}
+		}(u)
+	}
+
+	var (
+		errs     []error
+		errReply []byte
+	)
+	for range self.upstreams {
+		select {
+		case r := <-results:
+			if r.err == nil {
+				return withID(r.resp, req.ID), nil
+			}
+			if errReply == nil && hasError(r.resp) {
+				errReply = r.resp
+			}
+			errs = append(errs, r.err)
+		case <-ctx.Done():
+			return nil, errors.Wrap(ctx.Err(), "waiting for upstreams")
+		}
+	}
+	// Return the relay error so that the searcher sees why the request was rejected.
+	if errReply != nil {
+		return withID(errReply, req.ID), nil
+	}
+	return nil, errors.Errorf("all upstreams failed:%v", errs)
+}
+
+// hasError returns whether the reply is a JSON-RPC error.
+func hasError(resp []byte) bool {
+	var msg struct {
+		Error json.RawMessage `json:"error"`
+	}
+	if json.Unmarshal(resp, &msg) != nil {
+		return false
+	}
+	return len(msg.Error) > 0 && string(msg.Error) != "null"
+}
+
+// detached keeps the values of the context without its cancellation.
+type detached struct {
+	parent context.Context
+}
+
+func (self detached) Deadline() (time.Time, bool)       { return time.Time{}, false }
+func (self detached) Done() <-chan struct{}             { return nil }
+func (self detached) Err() error                        { return nil }
+func (self detached) Value(key interface{}) interface{} { return self.parent.Value(key) }
+
+// withID replaces the upstream reply ID with the searcher request one.
+func withID(resp []byte, id json.RawMessage) []byte {
+	var msg map[string]json.RawMessage
+	if len(id) == 0 || json.Unmarshal(resp, &msg) != nil {
+		return resp
+	}
+	msg["id"] = id
+	res, err := json.Marshal(msg)
+	if err != nil {
+		return resp
+	}
+	return res
+}
+
+func (self *Proxy) limiter(searcher common.Address) *flashbot.RateLimiter {
+	if self.rateLimit == 0 {
+		return nil
+	}
+	self.mtx.Lock()
+	defer self.mtx.Unlock()
+	l, ok := self.limiters[searcher]
+	if !ok {
+		// The config is validated in New so this can't fail.
+		l, _ = flashbot.NewRateLimiter(self.rateLimit, self.rateBurst)
+		self.limiters[searcher] = l
+	}
+	return l
+}
+
+// noTxMethods are the forwarded methods whose params carry no TXs so the TX policy isn't applied to them.
+var noTxMethods = map[string]bool{
+	"eth_cancelBundle":             true,
+	"eth_cancelPrivateTransaction": true,
+}
+
+// checkTxs applies the TX policy to the "txs" and "tx" fields of the request params
+// and to the TXs of the sbundle body of mev_sendBundle and mev_simBundle including the nested bundles.
+// The string params are checked as raw TXs, i.e. for eth_sendRawTransaction.
+// With a policy the params that can't be decoded are rejected so a malformed field can't skip the check.
+func (self *Proxy) checkTxs(method string, params json.RawMessage) error {
+	if self.txPolicy == nil || noTxMethods[method] {
+		return nil
+	}
+	if len(params) == 0 {
+		return nil
+	}
+	var items []json.RawMessage
+	if err := json.Unmarshal(params, &items); err != nil {
+		return errors.Wrap(err, "decode params")
+	}
+	for i, item := range items {
+		var txs []string
+		if raw := bytes.TrimSpace(item); len(raw) > 0 && raw[0] == '"' {
+			var tx string
+			if err := json.Unmarshal(raw, &tx); err != nil {
+				return errors.Wrapf(err, "decode param index:%v", i)
+			}
+			txs = append(txs, tx)
+		} else {
+			var a struct {
+				Txs  []string                 `json:"txs"`
+				Tx   string                   `json:"tx"`
+				Body []flashbot.MevBundleItem `json:"body"`
+			}
+			if err := json.Unmarshal(raw, &a); err != nil {
+				return errors.Wrapf(err, "decode param index:%v", i)
+			}
+			txs = a.Txs
+			if a.Tx != "" {
+				txs = append(txs, a.Tx)
+			}
+			sbundle := flashbot.MevSendBundleParams{Body: a.Body}
+			txs = append(txs, sbundle.SignedTxs()...)
+		}
+		if err := self.txPolicy.CheckBundle(txs); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func writeError(w http.ResponseWriter, status int, id json.RawMessage, code int, msg string) {
+	if len(id) == 0 {
+		id = json.RawMessage("null")
+	}
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	_ = json.NewEncoder(w).Encode(struct {
+		Version string          `json:"jsonrpc"`
+		ID      json.RawMessage `json:"id"`
+		Error   rpcError        `json:"error"`
+	}{Version: "2.0", ID: id, Error: rpcError{Code: code, Message: msg}})
+}
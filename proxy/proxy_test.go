@@ -0,0 +1,236 @@
+// Copyright (c) The Cryptorium Authors.
+// Licensed under the MIT License.
+
+package proxy
+
+import (
+	"bytes"
+	"context"
+	"crypto/ecdsa"
+	"encoding/json"
+	"io"
+	"math/big"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+
+	"github.com/cryptoriums/packages/testutil"
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/common/hexutil"
+	"github.com/ethereum/go-ethereum/crypto"
+	"github.com/go-kit/log"
+	"github.com/kachan28/flashbot"
+	"github.com/pkg/errors"
+)
+
+type upstreamMock struct {
+	mtx   sync.Mutex
+	calls []string
+	resp  string
+	err   error
+	// wait blocks the reply until it is closed when set.
+	wait chan struct{}
+}
+
+func (self *upstreamMock) Forward(ctx context.Context, method string, params json.RawMessage) ([]byte, error) {
+	self.mtx.Lock()
+	self.calls = append(self.calls, method)
+	self.mtx.Unlock()
+	if self.wait != nil {
+		<-self.wait
+	}
+	return []byte(self.resp), self.err
+}
+
+func (self *upstreamMock) Calls() int {
+	self.mtx.Lock()
+	defer self.mtx.Unlock()
+	return len(self.calls)
+}
+
+func signedRequest(t *testing.T, key *ecdsa.PrivateKey, body string) *http.Request {
+	signer, err := flashbot.NewKeySigner(key)
+	testutil.Ok(t, err)
+	sig, err := signer.SignText([]byte(hexutil.Encode(crypto.Keccak256([]byte(body)))))
+	testutil.Ok(t, err)
+	req := httptest.NewRequest(http.MethodPost, "/", bytes.NewBufferString(body))
+	req.Header.Set("X-Flashbots-Signature", signer.Address().Hex()+":"+hexutil.Encode(sig))
+	return req
+}
+
+func serve(p *Proxy, req *http.Request) (int, map[string]interface{}) {
+	rec := httptest.NewRecorder()
+	p.ServeHTTP(rec, req)
+	var res map[string]interface{}
+	_ = json.Unmarshal(rec.Body.Bytes(), &res)
+	return rec.Code, res
+}
+
+const sendBundle = `{"jsonrpc":"2.0","id":7,"method":"eth_sendBundle","params":[{"txs":["0x01"],"blockNumber":"0x10"}]}`
+
+func TestProxy(t *testing.T) {
+	searcher, err := crypto.GenerateKey()
+	testutil.Ok(t, err)
+	other, err := crypto.GenerateKey()
+	testutil.Ok(t, err)
+
+	failing := &upstreamMock{err: errors.New("down")}
+	ok := &upstreamMock{resp: `{"jsonrpc":"2.0","id":1,"result":{"bundleHash":"0xbundle"}}`}
+	p, err := New(log.NewNopLogger(), Config{
+		Upstreams: []Upstream{failing, ok},
+		Searchers: []common.Address{crypto.PubkeyToAddress(searcher.PublicKey)},
+		RateLimit: 1,
+		RateBurst: 2,
+	})
+	testutil.Ok(t, err)
+
+	// The reply of the first successful upstream is returned with the searcher request ID.
+	code, res := serve(p, signedRequest(t, searcher, sendBundle))
+	testutil.Equals(t, http.StatusOK, code)
+	testutil.Equals(t, float64(7), res["id"])
+	testutil.Equals(t, map[string]interface{}{"bundleHash": "0xbundle"}, res["result"])
+	testutil.Equals(t, 1, ok.Calls())
+
+	// Unsigned, tampered, not allowed and unknown method requests aren't forwarded.
+	req := httptest.NewRequest(http.MethodPost, "/", bytes.NewBufferString(sendBundle))
+	code, _ = serve(p, req)
+	testutil.Equals(t, http.StatusUnauthorized, code)
+
+	req = signedRequest(t, searcher, sendBundle)
+	req.Body = io.NopCloser(bytes.NewBufferString(sendBundle + " "))
+	code, _ = serve(p, req)
+	testutil.Equals(t, http.StatusUnauthorized, code)
+
+	code, _ = serve(p, signedRequest(t, other, sendBundle))
+	testutil.Equals(t, http.StatusForbidden, code)
+
+	code, res = serve(p, signedRequest(t, searcher, `{"jsonrpc":"2.0","id":1,"method":"eth_sendRawTransaction","params":[]}`))
+	testutil.Equals(t, http.StatusBadRequest, code)
+	testutil.Equals(t, float64(codeMethodNotFound), res["error"].(map[string]interface{})["code"])
+	testutil.Equals(t, 1, ok.Calls())
+
+	// The burst of 2 is used by the first request and the one with the unknown method.
+	code, _ = serve(p, signedRequest(t, searcher, sendBundle))
+	testutil.Equals(t, http.StatusTooManyRequests, code)
+
+	// All upstreams failing.
+	p, err = New(log.NewNopLogger(), Config{Upstreams: []Upstream{failing}})
+	testutil.Ok(t, err)
+	code, _ = serve(p, signedRequest(t, other, sendBundle))
+	testutil.Equals(t, http.StatusBadGateway, code)
+
+	_, err = New(log.NewNopLogger(), Config{})
+	testutil.NotOk(t, err)
+}
+
+func TestProxyTxPolicy(t *testing.T) {
+	searcher, err := crypto.GenerateKey()
+	testutil.Ok(t, err)
+	fb, err := flashbot.New(searcher, &flashbot.Api{URL: "http://localhost"})
+	testutil.Ok(t, err)
+	to := common.HexToAddress("0x1111111111111111111111111111111111111111")
+	txsHex, _, err := fb.(*flashbot.Flashbot).SignTxs(context.Background(), 5, 0, []flashbot.TxSpec{{
+		To:        &to,
+		Gas:       21_000,
+		GasFeeCap: big.NewInt(2e9),
+		GasTipCap: big.NewInt(1e9),
+	}})
+	testutil.Ok(t, err)
+
+	up := &upstreamMock{resp: `{"jsonrpc":"2.0","id":1,"result":{}}`}
+	p, err := New(log.NewNopLogger(), Config{Upstreams: []Upstream{up}, TxPolicy: flashbot.NewDenylist(to)})
+	testutil.Ok(t, err)
+
+	body := `{"jsonrpc":"2.0","id":1,"method":"eth_sendBundle","params":[{"txs":["` + txsHex[0] + `"],"blockNumber":"0x10"}]}`
+	code, res := serve(p, signedRequest(t, searcher, body))
+	testutil.Equals(t, http.StatusBadRequest, code)
+	testutil.Equals(t, float64(codePolicy), res["error"].(map[string]interface{})["code"])
+	testutil.Equals(t, 0, up.Calls())
+
+	// The TXs of the sbundle body including the nested bundles are checked too.
+	for _, method := range []string{"mev_sendBundle", "mev_simBundle"} {
+		body = `{"jsonrpc":"2.0","id":1,"method":"` + method + `","params":[{"version":"v0.1","inclusion":{"block":"0x10"},` +
+			`"body":[{"hash":"0x0000000000000000000000000000000000000000000000000000000000000001"},` +
+			`{"bundle":{"version":"v0.1","inclusion":{"block":"0x10"},"body":[{"tx":"` + txsHex[0] + `"}]}}]}]}`
+		code, res = serve(p, signedRequest(t, searcher, body))
+		testutil.Equals(t, http.StatusBadRequest, code, "method:%v", method)
+		testutil.Equals(t, float64(codePolicy), res["error"].(map[string]interface{})["code"])
+	}
+	testutil.Equals(t, 0, up.Calls())
+
+	// A wrong typed field doesn't skip the check.
+	body = `{"jsonrpc":"2.0","id":1,"method":"eth_sendBundle","params":[{"txs":["` + txsHex[0] + `"],"tx":1,"blockNumber":"0x10"}]}`
+	code, res = serve(p, signedRequest(t, searcher, body))
+	testutil.Equals(t, http.StatusBadRequest, code)
+	testutil.Equals(t, float64(codeInvalidParams), res["error"].(map[string]interface{})["code"])
+	code, _ = serve(p, signedRequest(t, searcher, `{"jsonrpc":"2.0","id":1,"method":"eth_sendBundle","params":{"txs":[]}}`))
+	testutil.Equals(t, http.StatusBadRequest, code)
+	testutil.Equals(t, 0, up.Calls())
+
+	// The cancel params have no TXs.
+	code, _ = serve(p, signedRequest(t, searcher, `{"jsonrpc":"2.0","id":1,"method":"eth_cancelBundle","params":[{"replacementUuid":"x"}]}`))
+	testutil.Equals(t, http.StatusOK, code)
+	code, _ = serve(p, signedRequest(t, searcher, `{"jsonrpc":"2.0","id":1,"method":"eth_cancelPrivateTransaction","params":[{"txHash":1}]}`))
+	testutil.Equals(t, http.StatusOK, code)
+}
+
+func TestProxyForwardsWithOwnIdentity(t *testing.T) {
+	proxyKey, err := crypto.GenerateKey()
+	testutil.Ok(t, err)
+	searcher, err := crypto.GenerateKey()
+	testutil.Ok(t, err)
+
+	var signers []common.Address
+	var params []json.RawMessage
+	var mtx sync.Mutex
+	relay := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, err := io.ReadAll(r.Body)
+		testutil.Ok(t, err)
+		signer, err := flashbot.VerifySignatureHeader(body, r.Header.Get("X-Flashbots-Signature"))
+		testutil.Ok(t, err)
+		var req request
+		testutil.Ok(t, json.Unmarshal(body, &req))
+		mtx.Lock()
+		signers = append(signers, signer)
+		params = append(params, req.Params)
+		mtx.Unlock()
+		_, _ = w.Write([]byte(`{"jsonrpc":"2.0","id":1,"result":{"bundleHash":"0xbundle"}}`))
+	}))
+	defer relay.Close()
+
+	fb, err := flashbot.New(proxyKey, &flashbot.Api{URL: relay.URL})
+	testutil.Ok(t, err)
+	p, err := New(log.NewNopLogger(), Config{Upstreams: []Upstream{fb.(*flashbot.Flashbot)}})
+	testutil.Ok(t, err)
+
+	code, _ := serve(p, signedRequest(t, searcher, sendBundle))
+	testutil.Equals(t, http.StatusOK, code)
+	testutil.Equals(t, []common.Address{crypto.PubkeyToAddress(proxyKey.PublicKey)}, signers)
+	testutil.Equals(t, `[{"txs":["0x01"],"blockNumber":"0x10"}]`, string(params[0]))
+}
+
+func TestProxyForwardFirstSuccess(t *testing.T) {
+	searcher, err := crypto.GenerateKey()
+	testutil.Ok(t, err)
+
+	rejected := &upstreamMock{resp: `{"jsonrpc":"2.0","id":1,"error":{"code":-32000,"message":"bundle rejected"}}`}
+	slow := &upstreamMock{resp: `{"jsonrpc":"2.0","id":1,"result":{"bundleHash":"0xslow"}}`, wait: make(chan struct{})}
+	defer close(slow.wait)
+	ok := &upstreamMock{resp: `{"jsonrpc":"2.0","id":1,"result":{"bundleHash":"0xbundle"}}`}
+	p, err := New(log.NewNopLogger(), Config{Upstreams: []Upstream{rejected, slow, ok}})
+	testutil.Ok(t, err)
+
+	// The error reply is skipped and the slow upstream isn't waited for.
+	code, res := serve(p, signedRequest(t, searcher, sendBundle))
+	testutil.Equals(t, http.StatusOK, code)
+	testutil.Equals(t, map[string]interface{}{"bundleHash": "0xbundle"}, res["result"])
+
+	// The relay error is returned when no upstream succeeded.
+	p, err = New(log.NewNopLogger(), Config{Upstreams: []Upstream{&upstreamMock{err: errors.New("down")}, rejected}})
+	testutil.Ok(t, err)
+	code, res = serve(p, signedRequest(t, searcher, sendBundle))
+	testutil.Equals(t, http.StatusOK, code)
+	testutil.Equals(t, float64(7), res["id"])
+	testutil.Equals(t, "bundle rejected", res["error"].(map[string]interface{})["message"])
+}
@@ -0,0 +1,161 @@
+// Copyright (c) The Cryptorium Authors.
+// Licensed under the MIT License.
+
+package flashbot
+
+import (
+	"context"
+	"math/big"
+	"sort"
+	"sync"
+
+	"github.com/pkg/errors"
+)
+
+// Candidate is a simulated bundle waiting for submission.
+type Candidate struct {
+	Txs      []string
+	BlockNum uint64
+	Score    *big.Int
+	Sim      *Response
+}
+
+// CandidateSubmission is the reply of one relay for one of the selected candidates.
+type CandidateSubmission struct {
+	Candidate *Candidate
+	Relay     string
+	Response  *Response
+	Err       error
+}
+
+// BundleQueue collects candidate bundles per target block
+// and submits only the best scored ones to each relay to stay within the relay rate limits.
+type BundleQueue struct {
+	mtx     sync.Mutex
+	relays  []Flashboter
+	topK    int
+	byBlock map[uint64][]*Candidate
+	drain   drain
+}
+
+// NewBundleQueue creates a queue that submits the topK candidates per block to each of the relays.
+// The candidates are simulated through the first relay that supports simulations.
+func NewBundleQueue(topK int, relays ...Flashboter) (*BundleQueue, error) {
+	if topK <= 0 {
+		return nil, errors.New("top K should be positive")
+	}
+	if len(relays) == 0 {
+		return nil, errors.New("queue needs at least one relay")
+	}
+	return &BundleQueue{relays: relays, topK: topK, byBlock: make(map[uint64][]*Candidate)}, nil
+}
+
+// Add simulates and scores the bundle and queues it for the target block.
+func (self *BundleQueue) Add(ctx context.Context, txsHex []string, blockNum uint64) (*Candidate, error) {
+	if !self.drain.enter() {
+		return nil, ErrClosed
+	}
+	defer self.drain.leave()
+
+	var sim Flashboter
+	for _, r := range self.relays {
+		if r.Api().SupportsSimulation {
+			sim = r
+			break
+		}
+	}
+	if sim == nil {
+		return nil, errors.New("no relay supports simulations")
+	}
+	resp, err := sim.CallBundle(ctx, txsHex, 0)
+	if err != nil {
+		return nil, errors.Wrap(err, "simulate candidate")
+	}
+	score, err := ScoreBundle(&resp.Result)
+	if err != nil {
+		return nil, errors.Wrap(err, "score candidate")
+	}
+	c := &Candidate{Txs: txsHex, BlockNum: blockNum, Score: score, Sim: resp}
+	self.AddScored(c)
+	return c, nil
+}
+
+// AddScored queues a candidate that was already scored.
+// The candidate is dropped after Close.
+func (self *BundleQueue) AddScored(c *Candidate) {
+	if self.drain.isClosed() {
+		return
+	}
+	self.mtx.Lock()
+	defer self.mtx.Unlock()
+	self.byBlock[c.BlockNum] = append(self.byBlock[c.BlockNum], c)
+}
+
+// Len returns the number of candidates for the block.
+func (self *BundleQueue) Len(blockNum uint64) int {
+	self.mtx.Lock()
+	defer self.mtx.Unlock()
+	return len(self.byBlock[blockNum])
+}
+
+// Top returns the best scored candidates for the block without removing them.
+func (self *BundleQueue) Top(blockNum uint64) []*Candidate {
+	self.mtx.Lock()
+	defer self.mtx.Unlock()
+	return self.topLocked(blockNum)
+}
+
+func (self *BundleQueue) topLocked(blockNum uint64) []*Candidate {
+	candidates := append([]*Candidate(nil), self.byBlock[blockNum]...)
+	// Stable to prefer the earlier candidates on equal scores.
+	sort.SliceStable(candidates, func(i, j int) bool { return candidates[i].Score.Cmp(candidates[j].Score) > 0 })
+	if len(candidates) > self.topK {
+		candidates = candidates[:self.topK]
+	}
+	return candidates
+}
+
+// Flush removes the candidates for the block and sends the top K to every relay concurrently.
+// After Close the submissions fail with ErrClosed.
+func (self *BundleQueue) Flush(ctx context.Context, blockNum uint64) []CandidateSubmission {
+	self.mtx.Lock()
+	top := self.topLocked(blockNum)
+	delete(self.byBlock, blockNum)
+	self.mtx.Unlock()
+
+	res := make([]CandidateSubmission, len(top)*len(self.relays))
+	if !self.drain.enter() {
+		for i, c := range top {
+			for j, r := range self.relays {
+				res[i*len(self.relays)+j] = CandidateSubmission{Candidate: c, Relay: r.Api().URL, Err: ErrClosed}
+			}
+		}
+		return res
+	}
+	defer self.drain.leave()
+
+	var wg sync.WaitGroup
+	for i, c := range top {
+		for j, r := range self.relays {
+			wg.Add(1)
+			go func(idx int, c *Candidate, r Flashboter) {
+				defer wg.Done()
+				resp, err := r.SendBundle(ctx, c.Txs, c.BlockNum)
+				res[idx] = CandidateSubmission{Candidate: c, Relay: r.Api().URL, Response: resp, Err: err}
+			}(i*len(self.relays)+j, c, r)
+		}
+	}
+	wg.Wait()
+	return res
+}
+
+// Prune drops the candidates for the blocks up to and including the head.
+func (self *BundleQueue) Prune(head uint64) {
+	self.mtx.Lock()
+	defer self.mtx.Unlock()
+	for blockNum := range self.byBlock {
+		if blockNum <= head {
+			delete(self.byBlock, blockNum)
+		}
+	}
+}
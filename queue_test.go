@@ -0,0 +1,53 @@
+// Copyright (c) The Cryptorium Authors.
+// Licensed under the MIT License.
+
+package flashbot
+
+import (
+	"context"
+	"encoding/json"
+	"testing"
+
+	"github.com/cryptoriums/packages/testutil"
+)
+
+func TestBundleQueue(t *testing.T) {
+	ctx := context.Background()
+	handler := func(method string, params json.RawMessage) (interface{}, *jsonError) {
+		if method == "eth_callBundle" {
+			var p []ParamsCall
+			testutil.Ok(t, json.Unmarshal(params, &p))
+			// The score is the TX payload.
+			return Result{
+				Metadata: Metadata{CoinbaseDiff: p[0].Txs[0][2:]},
+				Results:  []TxResult{{GasUsed: 1}},
+			}, nil
+		}
+		return Result{BundleHash: "0xbundle"}, nil
+	}
+	relay1 := newRelayMock(t, handler)
+	relay2 := newRelayMock(t, handler)
+
+	q, err := NewBundleQueue(2, newTestFlashbot(t, relay1.URL), newTestFlashbot(t, relay2.URL))
+	testutil.Ok(t, err)
+	for _, tx := range []string{"0x10", "0x30", "0x20"} {
+		_, err := q.Add(ctx, []string{tx}, 10)
+		testutil.Ok(t, err)
+	}
+	_, err = q.Add(ctx, []string{"0x40"}, 11)
+	testutil.Ok(t, err)
+
+	res := q.Flush(ctx, 10)
+	testutil.Equals(t, 4, len(res))
+	testutil.Equals(t, "0x30", res[0].Candidate.Txs[0])
+	testutil.Equals(t, relay2.URL, res[1].Relay)
+	testutil.Equals(t, "0x20", res[2].Candidate.Txs[0])
+	for _, r := range res {
+		testutil.Ok(t, r.Err)
+	}
+	testutil.Equals(t, 0, q.Len(10))
+
+	q.Prune(11)
+	testutil.Equals(t, 0, q.Len(11))
+	testutil.Equals(t, []string{"eth_sendBundle", "eth_sendBundle"}, relay2.Methods())
+}
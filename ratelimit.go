@@ -0,0 +1,109 @@
+// Copyright (c) The Cryptorium Authors.
+// Licensed under the MIT License.
+
+package flashbot
+
+import (
+	"context"
+	"sync"
+	"time"
+
+	"github.com/pkg/errors"
+)
+
+// RateLimiter is a token bucket that spaces out the relay requests
+// so that load spikes don't get the identity rate limited or banned by the relay.
+// The same limiter can be shared by the clients of the same relay.
+type RateLimiter struct {
+	mtx    sync.Mutex
+	rate   float64
+	burst  float64
+	tokens float64
+	last   time.Time
+	now    func() time.Time
+}
+
+// NewRateLimiter allows perSecond requests on average with bursts of up to burst requests,
+// a burst of 0 is the same as 1.
+func NewRateLimiter(perSecond float64, burst int) (*RateLimiter, error) {
+	if perSecond <= 0 {
+		return nil, errors.New("requests per second should be positive")
+	}
+	if burst < 0 {
+		return nil, errors.New("burst can't be negative")
+	}
+	if burst == 0 {
+		burst = 1
+	}
+	return &RateLimiter{
+		rate:   perSecond,
+		burst:  float64(burst),
+		tokens: float64(burst),
+		now:    time.Now,
+	}, nil
+}
+
+// reserve takes a token and returns how long to wait before using it.
+func (self *RateLimiter) reserve() time.Duration {
+	self.mtx.Lock()
+	defer self.mtx.Unlock()
+	now := self.now()
+	if !self.last.IsZero() {
+		self.tokens += now.Sub(self.last).Seconds() * self.rate
+		if self.tokens > self.burst {
+			self.tokens = self.burst
+		}
+	}
+	self.last = now
+	self.tokens--
+	if self.tokens >= 0 {
+		return 0
+	}
+	return time.Duration(-self.tokens / self.rate * float64(time.Second))
+}
+
+// cancel returns a token taken with reserve that wasn't used.
+func (self *RateLimiter) cancel() {
+	self.mtx.Lock()
+	defer self.mtx.Unlock()
+	self.tokens++
+	if self.tokens > self.burst {
+		self.tokens = self.burst
+	}
+}
+
+// Wait blocks until a request is allowed or the context is done.
+func (self *RateLimiter) Wait(ctx context.Context) error {
+	if self == nil {
+		return nil
+	}
+	wait := self.reserve()
+	if wait == 0 {
+		return nil
+	}
+	if deadline, ok := ctx.Deadline(); ok && time.Until(deadline) < wait {
+		self.cancel()
+		return errors.Errorf("rate limit wait:%v exceeds the context deadline", wait)
+	}
+	t := time.NewTimer(wait)
+	defer t.Stop()
+	select {
+	case <-ctx.Done():
+		self.cancel()
+		return errors.Wrap(ctx.Err(), "rate limit wait")
+	case <-t.C:
+		return nil
+	}
+}
+
+// Allow takes a token without waiting and returns false when there is none.
+func (self *RateLimiter) Allow() bool {
+	if self == nil {
+		return true
+	}
+	if self.reserve() == 0 {
+		return true
+	}
+	self.cancel()
+	return false
+}
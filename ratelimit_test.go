@@ -0,0 +1,73 @@
+// Copyright (c) The Cryptorium Authors.
+// Licensed under the MIT License.
+
+package flashbot
+
+import (
+	"context"
+	"encoding/json"
+	"testing"
+	"time"
+
+	"github.com/cryptoriums/packages/testutil"
+)
+
+func TestRateLimiter(t *testing.T) {
+	l, err := NewRateLimiter(2, 3)
+	testutil.Ok(t, err)
+	now := time.Unix(1000, 0)
+	l.now = func() time.Time { return now }
+
+	for i := 0; i < 3; i++ {
+		testutil.Assert(t, l.Allow(), "burst request:%v not allowed", i)
+	}
+	testutil.Assert(t, !l.Allow(), "request over the burst allowed")
+
+	// Half a second at 2 per second refills one token.
+	now = now.Add(500 * time.Millisecond)
+	testutil.Assert(t, l.Allow(), "refilled request not allowed")
+	testutil.Assert(t, !l.Allow(), "request over the refill allowed")
+
+	// The bucket doesn't grow over the burst.
+	now = now.Add(time.Hour)
+	for i := 0; i < 3; i++ {
+		testutil.Assert(t, l.Allow(), "burst request:%v not allowed", i)
+	}
+	testutil.Assert(t, !l.Allow(), "request over the burst allowed")
+
+	testutil.Equals(t, 500*time.Millisecond, l.reserve())
+	l.cancel()
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Millisecond)
+	defer cancel()
+	testutil.NotOk(t, l.Wait(ctx))
+	// The failed wait returns its token.
+	testutil.Equals(t, 500*time.Millisecond, l.reserve())
+
+	_, err = NewRateLimiter(0, 1)
+	testutil.NotOk(t, err)
+	_, err = NewRateLimiter(1, -1)
+	testutil.NotOk(t, err)
+
+	var nilLimiter *RateLimiter
+	testutil.Ok(t, nilLimiter.Wait(context.Background()))
+}
+
+func TestRateLimitedRelay(t *testing.T) {
+	mock := newRelayMock(t, func(method string, params json.RawMessage) (interface{}, *jsonError) {
+		return map[string]string{"bundleHash": "0x01"}, nil
+	})
+	f := newTestFlashbot(t, mock.URL)
+	l, err := NewRateLimiter(20, 1)
+	testutil.Ok(t, err)
+	f.api.RateLimiter = l
+
+	start := time.Now()
+	for i := 0; i < 3; i++ {
+		_, err := f.SendBundle(context.Background(), []string{"0x01"}, 10)
+		testutil.Ok(t, err)
+	}
+	// The first request uses the burst and the next two wait 50ms each.
+	testutil.Assert(t, time.Since(start) >= 90*time.Millisecond, "requests not rate limited:%v", time.Since(start))
+	testutil.Equals(t, 3, len(mock.Methods()))
+}
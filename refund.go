@@ -0,0 +1,187 @@
+// Copyright (c) The Cryptorium Authors.
+// Licensed under the MIT License.
+
+package flashbot
+
+import (
+	"context"
+	"encoding/json"
+	"math/big"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/common/hexutil"
+	"github.com/pkg/errors"
+)
+
+// RefundRecipient receives a share of the MEV refund of a private TX.
+type RefundRecipient struct {
+	Address common.Address `json:"address"`
+	Percent int            `json:"percent"`
+}
+
+type PrivateTxValidity struct {
+	Refund []RefundRecipient `json:"refund,omitempty"`
+}
+
+type PrivateTxOpts struct {
+	// MaxBlock is the last block the TX can be included in.
+	MaxBlock uint64
+	Fast     bool
+	// Refunds direct the MEV refunds, to the TX sender when empty.
+	Refunds []RefundRecipient
+}
+
+// SendPrivateTransactionOpts sends a private TX with the refund recipients.
+func (self *Flashbot) SendPrivateTransactionOpts(ctx context.Context, txHex string, opts PrivateTxOpts) (*SendPrivateTransactionResponse, error) {
+	total := 0
+	for _, r := range opts.Refunds {
+		if r.Percent <= 0 {
+			return nil, errors.Errorf("refund percent should be positive address:%v", r.Address.Hex())
+		}
+		total += r.Percent
+	}
+	if total > 100 {
+		return nil, errors.Errorf("refund percents sum:%v over 100", total)
+	}
+
+	param := ParamsPrivateTransaction{
+		Tx:             txHex,
+		МaxBlockNumber: hexutil.EncodeUint64(opts.MaxBlock),
+	}
+	param.Preferences.Fast = opts.Fast
+	if len(opts.Refunds) > 0 {
+		param.Preferences.Validity = &PrivateTxValidity{Refund: opts.Refunds}
+	}
+	return self.sendPrivateTransaction(ctx, param, opts.MaxBlock)
+}
+
+// FeeRefundRecipient is the address the gas fee refunds of the TXs and bundles sent by From are paid to.
+type FeeRefundRecipient struct {
+	From common.Address `json:"from"`
+	To   common.Address `json:"to"`
+}
+
+// SetFeeRefundRecipient directs the gas fee refunds earned by the auth signer identity to the recipient.
+func (self *Flashbot) SetFeeRefundRecipient(ctx context.Context, recipient common.Address) (*FeeRefundRecipient, error) {
+	signer := self.Signer()
+	if signer == nil {
+		return nil, errors.New("private key or signer is not set")
+	}
+	res := &FeeRefundRecipient{}
+	if err := self.call(ctx, res, "flashbots_setFeeRefundRecipient", signer.Address(), recipient); err != nil {
+		return nil, err
+	}
+	return res, nil
+}
+
+// FeeRefundTotals are the gas fee refunds of a recipient in wei.
+type FeeRefundTotals struct {
+	Pending  *big.Int
+	Received *big.Int
+	// MaxBlockNumber is the last block included in the totals.
+	MaxBlockNumber uint64
+}
+
+// Total returns the pending and the received refunds.
+func (self *FeeRefundTotals) Total() *big.Int {
+	return new(big.Int).Add(self.Pending, self.Received)
+}
+
+// FeeRefundTotals returns the accrued gas fee refunds of the recipient.
+func (self *Flashbot) FeeRefundTotals(ctx context.Context, recipient common.Address) (*FeeRefundTotals, error) {
+	var res struct {
+		Pending        *hexutil.Big   `json:"pending"`
+		Received       *hexutil.Big   `json:"received"`
+		MaxBlockNumber hexutil.Uint64 `json:"maxBlockNumber"`
+	}
+	if err := self.call(ctx, &res, "flashbots_getFeeRefundTotalsByRecipient", recipient); err != nil {
+		return nil, err
+	}
+	totals := &FeeRefundTotals{Pending: new(big.Int), Received: new(big.Int), MaxBlockNumber: uint64(res.MaxBlockNumber)}
+	if res.Pending != nil {
+		totals.Pending.Set(res.Pending.ToInt())
+	}
+	if res.Received != nil {
+		totals.Received.Set(res.Received.ToInt())
+	}
+	return totals, nil
+}
+
+type FeeRefund struct {
+	// Hash is the hash of the refunded TX or bundle.
+	Hash        common.Hash
+	Amount      *big.Int
+	BlockNumber uint64
+	// Status is either pending or received.
+	Status    string
+	Recipient common.Address
+}
+
+// ParamsFeeRefunds is the flashbots_getFeeRefundsByRecipient param object.
+type ParamsFeeRefunds struct {
+	Recipient common.Address `json:"recipient"`
+	Cursor    string         `json:"cursor,omitempty"`
+}
+
+type FeeRefundsPage struct {
+	Refunds []FeeRefund
+	// Cursor to pass for the next page, empty on the last page.
+	Cursor string
+}
+
+// FeeRefunds returns a page of the individual gas fee refunds of the recipient starting from the cursor,
+// the first page when the cursor is empty.
+func (self *Flashbot) FeeRefunds(ctx context.Context, recipient common.Address, cursor string) (*FeeRefundsPage, error) {
+	var res struct {
+		Refunds []struct {
+			Hash        common.Hash    `json:"hash"`
+			Amount      *hexutil.Big   `json:"amount"`
+			BlockNumber hexutil.Uint64 `json:"blockNumber"`
+			Status      string         `json:"status"`
+			Recipient   common.Address `json:"recipient"`
+		} `json:"refunds"`
+		Cursor string `json:"cursor"`
+	}
+	param := ParamsFeeRefunds{Recipient: recipient, Cursor: cursor}
+	if err := self.call(ctx, &res, "flashbots_getFeeRefundsByRecipient", param); err != nil {
+		return nil, err
+	}
+	page := &FeeRefundsPage{Cursor: res.Cursor}
+	for _, r := range res.Refunds {
+		amount := new(big.Int)
+		if r.Amount != nil {
+			amount.Set(r.Amount.ToInt())
+		}
+		page.Refunds = append(page.Refunds, FeeRefund{
+			Hash:        r.Hash,
+			Amount:      amount,
+			BlockNumber: uint64(r.BlockNumber),
+			Status:      r.Status,
+			Recipient:   r.Recipient,
+		})
+	}
+	return page, nil
+}
+
+// call sends the request and decodes the JSON-RPC result into dst.
+func (self *Flashbot) call(ctx context.Context, dst interface{}, method string, params ...interface{}) error {
+	resp, err := self.req(ctx, method, params...)
+	if err != nil {
+		return errors.Wrapf(err, "flashbot %v request", method)
+	}
+	rr := &jsonrpcMessage{}
+	if err := json.Unmarshal(resp, rr); err != nil {
+		return errors.Wrapf(err, "unmarshal flashbot response:%v", string(resp))
+	}
+	if rr.Error != nil {
+		return errors.WithMessage(&RelayError{
+			Code:    rr.Error.Code,
+			Message: rr.Error.Message,
+			Class:   ClassifyRelayError(self.api.Retry.ErrorRules, rr.Error.Code, rr.Error.Message),
+		}, "flashbot request returned an error")
+	}
+	if err := json.Unmarshal(rr.Result, dst); err != nil {
+		return errors.Wrapf(err, "unmarshal flashbot %v result:%v", method, string(rr.Result))
+	}
+	return nil
+}
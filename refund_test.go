@@ -0,0 +1,105 @@
+// Copyright (c) The Cryptorium Authors.
+// Licensed under the MIT License.
+
+package flashbot
+
+import (
+	"context"
+	"encoding/json"
+	"math/big"
+	"testing"
+
+	"github.com/cryptoriums/packages/testutil"
+	"github.com/ethereum/go-ethereum/common"
+)
+
+func TestFeeRefunds(t *testing.T) {
+	recipient := common.HexToAddress("0xfe")
+	var (
+		got    []json.RawMessage
+		gotRaw string
+	)
+	mock := newRelayMock(t, func(method string, params json.RawMessage) (interface{}, *jsonError) {
+		gotRaw = string(params)
+		testutil.Ok(t, json.Unmarshal(params, &got))
+		switch method {
+		case "flashbots_setFeeRefundRecipient":
+			var from, to common.Address
+			testutil.Ok(t, json.Unmarshal(got[0], &from))
+			testutil.Ok(t, json.Unmarshal(got[1], &to))
+			return map[string]interface{}{"from": from, "to": to}, nil
+		case "flashbots_getFeeRefundTotalsByRecipient":
+			return map[string]string{"pending": "0x64", "received": "0xc8", "maxBlockNumber": "0x10"}, nil
+		case "flashbots_getFeeRefundsByRecipient":
+			var param ParamsFeeRefunds
+			testutil.Ok(t, json.Unmarshal(got[0], &param))
+			if param.Cursor != "" {
+				return map[string]interface{}{"refunds": []interface{}{}}, nil
+			}
+			return map[string]interface{}{
+				"refunds": []map[string]string{{
+					"hash":        common.Hash{1}.Hex(),
+					"amount":      "0x64",
+					"blockNumber": "0xf",
+					"status":      "pending",
+					"recipient":   recipient.Hex(),
+				}},
+				"cursor": "0x1",
+			}, nil
+		}
+		return nil, &jsonError{Code: -32601, Message: "method not found"}
+	})
+	fb := newTestFlashbot(t, mock.URL)
+	ctx := context.Background()
+
+	set, err := fb.SetFeeRefundRecipient(ctx, recipient)
+	testutil.Ok(t, err)
+	testutil.Equals(t, FeeRefundRecipient{From: fb.Signer().Address(), To: recipient}, *set)
+
+	totals, err := fb.FeeRefundTotals(ctx, recipient)
+	testutil.Ok(t, err)
+	testutil.Equals(t, big.NewInt(100), totals.Pending)
+	testutil.Equals(t, big.NewInt(200), totals.Received)
+	testutil.Equals(t, big.NewInt(300), totals.Total())
+	testutil.Equals(t, uint64(16), totals.MaxBlockNumber)
+
+	page, err := fb.FeeRefunds(ctx, recipient, "")
+	testutil.Ok(t, err)
+	testutil.Equals(t, `[{"recipient":"0x00000000000000000000000000000000000000fe"}]`, gotRaw)
+	testutil.Equals(t, "0x1", page.Cursor)
+	testutil.Equals(t, []FeeRefund{{
+		Hash:        common.Hash{1},
+		Amount:      big.NewInt(100),
+		BlockNumber: 15,
+		Status:      "pending",
+		Recipient:   recipient,
+	}}, page.Refunds)
+
+	page, err = fb.FeeRefunds(ctx, recipient, page.Cursor)
+	testutil.Ok(t, err)
+	testutil.Equals(t, `[{"recipient":"0x00000000000000000000000000000000000000fe","cursor":"0x1"}]`, gotRaw)
+	testutil.Equals(t, 0, len(page.Refunds))
+	testutil.Equals(t, "", page.Cursor)
+}
+
+func TestSendPrivateTransactionRefunds(t *testing.T) {
+	var got []ParamsPrivateTransaction
+	mock := newRelayMock(t, func(method string, params json.RawMessage) (interface{}, *jsonError) {
+		testutil.Ok(t, json.Unmarshal(params, &got))
+		return "0xhash", nil
+	})
+	fb := newTestFlashbot(t, mock.URL)
+
+	refunds := []RefundRecipient{{Address: common.HexToAddress("0xfe"), Percent: 90}}
+	resp, err := fb.SendPrivateTransactionOpts(context.Background(), "0x01", PrivateTxOpts{MaxBlock: 20, Fast: true, Refunds: refunds})
+	testutil.Ok(t, err)
+	testutil.Equals(t, "0xhash", resp.Result)
+	testutil.Equals(t, "0x14", got[0].МaxBlockNumber)
+	testutil.Assert(t, got[0].Preferences.Fast, "fast preference not set")
+	testutil.Equals(t, refunds, got[0].Preferences.Validity.Refund)
+
+	_, err = fb.SendPrivateTransactionOpts(context.Background(), "0x01", PrivateTxOpts{Refunds: []RefundRecipient{{Percent: 60}, {Percent: 50}}})
+	testutil.NotOk(t, err)
+	_, err = fb.SendPrivateTransactionOpts(context.Background(), "0x01", PrivateTxOpts{Refunds: []RefundRecipient{{}}})
+	testutil.NotOk(t, err)
+}
@@ -0,0 +1,61 @@
+// Copyright (c) The Cryptorium Authors.
+// Licensed under the MIT License.
+
+package flashbot
+
+import (
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+
+	"github.com/cryptoriums/packages/testutil"
+	"github.com/ethereum/go-ethereum/crypto"
+)
+
+// relayMock is a JSON-RPC relay that answers with the handler results and records the called methods.
+type relayMock struct {
+	*httptest.Server
+	mtx     sync.Mutex
+	methods []string
+}
+
+func newRelayMock(t *testing.T, handler func(method string, params json.RawMessage) (interface{}, *jsonError)) *relayMock {
+	m := &relayMock{}
+	m.Server = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, err := io.ReadAll(r.Body)
+		testutil.Ok(t, err)
+		msg := &jsonrpcMessage{}
+		testutil.Ok(t, json.Unmarshal(body, msg))
+
+		m.mtx.Lock()
+		m.methods = append(m.methods, msg.Method)
+		m.mtx.Unlock()
+
+		result, errRPC := handler(msg.Method, msg.Params)
+		resp := &jsonrpcMessage{Version: "2.0", ID: msg.ID, Error: errRPC}
+		if errRPC == nil {
+			resp.Result, err = json.Marshal(result)
+			testutil.Ok(t, err)
+		}
+		testutil.Ok(t, json.NewEncoder(w).Encode(resp))
+	}))
+	t.Cleanup(m.Close)
+	return m
+}
+
+func (self *relayMock) Methods() []string {
+	self.mtx.Lock()
+	defer self.mtx.Unlock()
+	return append([]string(nil), self.methods...)
+}
+
+func newTestFlashbot(t *testing.T, url string) *Flashbot {
+	prvKey, err := crypto.GenerateKey()
+	testutil.Ok(t, err)
+	fb, err := New(prvKey, &Api{URL: url, SupportsSimulation: true})
+	testutil.Ok(t, err)
+	return fb.(*Flashbot)
+}
@@ -0,0 +1,169 @@
+// Copyright (c) The Cryptorium Authors.
+// Licensed under the MIT License.
+
+package flashbot
+
+import (
+	"context"
+	"net/http"
+	"net/url"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/pkg/errors"
+)
+
+// MevBoostRelays are the data API URLs of the major mainnet mev-boost relays.
+var MevBoostRelays = []string{
+	"https://boost-relay.flashbots.net",
+	"https://bloxroute.max-profit.blxrbdn.com",
+	"https://bloxroute.regulated.blxrbdn.com",
+	"https://relay.ultrasound.money",
+	"https://agnostic-relay.net",
+	"https://aestus.live",
+	"https://mainnet-relay.securerpc.com",
+}
+
+type BidTrace struct {
+	Slot                 uint64 `json:"slot,string"`
+	ParentHash           string `json:"parent_hash"`
+	BlockHash            string `json:"block_hash"`
+	BuilderPubkey        string `json:"builder_pubkey"`
+	ProposerPubkey       string `json:"proposer_pubkey"`
+	ProposerFeeRecipient string `json:"proposer_fee_recipient"`
+	GasLimit             uint64 `json:"gas_limit,string"`
+	GasUsed              uint64 `json:"gas_used,string"`
+	// Value is the payment to the proposer in wei.
+	Value       string `json:"value"`
+	BlockNumber uint64 `json:"block_number,string"`
+	NumTx       uint64 `json:"num_tx,string"`
+	// Set only for the received builder blocks.
+	TimestampMs uint64 `json:"timestamp_ms,string,omitempty"`
+	// Relay is the data API URL that returned the trace.
+	Relay string `json:"-"`
+}
+
+type ValidatorRegistration struct {
+	Message struct {
+		FeeRecipient string `json:"fee_recipient"`
+		GasLimit     uint64 `json:"gas_limit,string"`
+		Timestamp    uint64 `json:"timestamp,string"`
+		Pubkey       string `json:"pubkey"`
+	} `json:"message"`
+	Signature string `json:"signature"`
+}
+
+// BidTraceQuery filters the bid traces, the zero values are not sent.
+type BidTraceQuery struct {
+	Slot          uint64
+	Cursor        uint64
+	Limit         int
+	BlockHash     string
+	BlockNumber   uint64
+	BuilderPubkey string
+	// ProposerPubkey is used only for the delivered payloads.
+	ProposerPubkey string
+}
+
+func (self BidTraceQuery) values() url.Values {
+	q := url.Values{}
+	if self.Slot != 0 {
+		q.Set("slot", strconv.FormatUint(self.Slot, 10))
+	}
+	if self.Cursor != 0 {
+		q.Set("cursor", strconv.FormatUint(self.Cursor, 10))
+	}
+	if self.Limit != 0 {
+		q.Set("limit", strconv.Itoa(self.Limit))
+	}
+	if self.BlockHash != "" {
+		q.Set("block_hash", self.BlockHash)
+	}
+	if self.BlockNumber != 0 {
+		q.Set("block_number", strconv.FormatUint(self.BlockNumber, 10))
+	}
+	if self.BuilderPubkey != "" {
+		q.Set("builder_pubkey", self.BuilderPubkey)
+	}
+	if self.ProposerPubkey != "" {
+		q.Set("proposer_pubkey", self.ProposerPubkey)
+	}
+	return q
+}
+
+// RelayData is a client for the standard mev-boost relay data API.
+type RelayData struct {
+	baseURL string
+	client  *http.Client
+}
+
+func NewRelayData(baseURL string, client *http.Client) *RelayData {
+	if client == nil {
+		client = &http.Client{Timeout: 30 * time.Second}
+	}
+	return &RelayData{baseURL: baseURL, client: client}
+}
+
+// PayloadsDelivered returns the payloads the relay delivered to the proposers, i.e. the winning blocks.
+func (self *RelayData) PayloadsDelivered(ctx context.Context, query BidTraceQuery) ([]BidTrace, error) {
+	return self.bidTraces(ctx, "/relay/v1/data/bidtraces/proposer_payload_delivered", query)
+}
+
+// BuilderBlocksReceived returns the blocks the builders submitted to the relay.
+func (self *RelayData) BuilderBlocksReceived(ctx context.Context, query BidTraceQuery) ([]BidTrace, error) {
+	query.ProposerPubkey = ""
+	return self.bidTraces(ctx, "/relay/v1/data/bidtraces/builder_blocks_received", query)
+}
+
+// ValidatorRegistration returns the latest registration of the validator.
+func (self *RelayData) ValidatorRegistration(ctx context.Context, pubkey string) (*ValidatorRegistration, error) {
+	reg := &ValidatorRegistration{}
+	u := self.baseURL + "/relay/v1/data/validator_registration?" + url.Values{"pubkey": {pubkey}}.Encode()
+	if err := getJSON(ctx, self.client, u, reg); err != nil {
+		return nil, errors.Wrapf(err, "validator registration relay:%v", self.baseURL)
+	}
+	return reg, nil
+}
+
+func (self *RelayData) bidTraces(ctx context.Context, path string, query BidTraceQuery) ([]BidTrace, error) {
+	u := self.baseURL + path
+	if q := query.values(); len(q) > 0 {
+		u += "?" + q.Encode()
+	}
+	var traces []BidTrace
+	if err := getJSON(ctx, self.client, u, &traces); err != nil {
+		return nil, errors.Wrapf(err, "bid traces relay:%v", self.baseURL)
+	}
+	for i := range traces {
+		traces[i].Relay = self.baseURL
+	}
+	return traces, nil
+}
+
+// PayloadsDeliveredAll queries the delivered payloads from all relays concurrently.
+// The errors of the failed relays are returned together with the traces from the others.
+func PayloadsDeliveredAll(ctx context.Context, relays []*RelayData, query BidTraceQuery) ([]BidTrace, map[string]error) {
+	var (
+		mtx    sync.Mutex
+		wg     sync.WaitGroup
+		traces []BidTrace
+		errs   = make(map[string]error)
+	)
+	for _, r := range relays {
+		wg.Add(1)
+		go func(r *RelayData) {
+			defer wg.Done()
+			t, err := r.PayloadsDelivered(ctx, query)
+			mtx.Lock()
+			defer mtx.Unlock()
+			if err != nil {
+				errs[r.baseURL] = err
+				return
+			}
+			traces = append(traces, t...)
+		}(r)
+	}
+	wg.Wait()
+	return traces, errs
+}
@@ -0,0 +1,45 @@
+// Copyright (c) The Cryptorium Authors.
+// Licensed under the MIT License.
+
+package flashbot
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/cryptoriums/packages/testutil"
+)
+
+func TestRelayData(t *testing.T) {
+	ctx := context.Background()
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/relay/v1/data/bidtraces/proposer_payload_delivered":
+			testutil.Equals(t, "10", r.URL.Query().Get("block_number"))
+			_, _ = w.Write([]byte(`[{"slot":"100","block_number":"10","builder_pubkey":"0xb1","value":"123","gas_used":"21000","num_tx":"1"}]`))
+		case "/relay/v1/data/validator_registration":
+			_, _ = w.Write([]byte(`{"message":{"fee_recipient":"0xfe","gas_limit":"30000000","timestamp":"1","pubkey":"0xpk"},"signature":"0x"}`))
+		default:
+			w.WriteHeader(http.StatusNotFound)
+		}
+	}))
+	defer srv.Close()
+
+	relay := NewRelayData(srv.URL, nil)
+	traces, err := relay.PayloadsDelivered(ctx, BidTraceQuery{BlockNumber: 10})
+	testutil.Ok(t, err)
+	testutil.Equals(t, 1, len(traces))
+	testutil.Equals(t, uint64(100), traces[0].Slot)
+	testutil.Equals(t, "0xb1", traces[0].BuilderPubkey)
+	testutil.Equals(t, srv.URL, traces[0].Relay)
+
+	reg, err := relay.ValidatorRegistration(ctx, "0xpk")
+	testutil.Ok(t, err)
+	testutil.Equals(t, uint64(30000000), reg.Message.GasLimit)
+
+	traces, errs := PayloadsDeliveredAll(ctx, []*RelayData{relay, NewRelayData(srv.URL+"/missing", nil)}, BidTraceQuery{BlockNumber: 10})
+	testutil.Equals(t, 1, len(traces))
+	testutil.Equals(t, 1, len(errs))
+}
@@ -0,0 +1,138 @@
+// Copyright (c) The Cryptorium Authors.
+// Licensed under the MIT License.
+
+package flashbot
+
+import (
+	"context"
+	"fmt"
+	"math/big"
+	"strings"
+
+	"github.com/pkg/errors"
+)
+
+// ReplaySimulator simulates a bundle on top of a historical state block.
+// It is implemented by the relay clients, which usually only keep the recent state,
+// and by the localsim Simulator which can use an archive node for any state block.
+type ReplaySimulator interface {
+	CallBundle(ctx context.Context, txsHex []string, blockNumState uint64) (*Response, error)
+}
+
+type ReplayOutcome string
+
+const (
+	// ReplayValid bundles would have been included as simulated so they were most likely outbid.
+	ReplayValid ReplayOutcome = "valid"
+	// ReplayReverted bundles have TXs that revert and aren't allowed to.
+	ReplayReverted ReplayOutcome = "reverted"
+	// ReplayFailed bundles couldn't be simulated at all, i.e. because of a used nonce or a low fee cap.
+	ReplayFailed ReplayOutcome = "failed"
+)
+
+// ReplayResult is the simulation of a persisted bundle on top of the state it targeted.
+type ReplayResult struct {
+	Bundle *ManagedBundle
+	// StateBlock is the block before the target one.
+	StateBlock uint64
+	Outcome    ReplayOutcome
+	// Reason explains the outcome in a human readable way.
+	Reason string
+	Sim    *Response
+	Err    error
+	// ProfitDiff is the replay coinbase diff minus the one of the original simulation,
+	// nil when either is unknown.
+	ProfitDiff *big.Int
+}
+
+// Replayer re-simulates the persisted bundles at their original target block
+// to find out after the fact why they missed or reverted.
+type Replayer struct {
+	store BundleStore
+	sim   ReplaySimulator
+}
+
+func NewReplayer(store BundleStore, sim ReplaySimulator) *Replayer {
+	return &Replayer{store: store, sim: sim}
+}
+
+// Replay re-simulates the stored bundle with the given ID.
+func (self *Replayer) Replay(ctx context.Context, id string) (*ReplayResult, error) {
+	b, err := self.store.Get(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	return self.ReplayBundle(ctx, b)
+}
+
+// ReplayAll re-simulates all stored bundles matching the filter in their creation order.
+func (self *Replayer) ReplayAll(ctx context.Context, filter BundleFilter) ([]*ReplayResult, error) {
+	bundles, err := self.store.List(ctx, filter)
+	if err != nil {
+		return nil, errors.Wrap(err, "list bundles")
+	}
+	res := make([]*ReplayResult, 0, len(bundles))
+	for _, b := range bundles {
+		r, err := self.ReplayBundle(ctx, b)
+		if err != nil {
+			return res, errors.Wrapf(err, "replay bundle id:%v", b.ID)
+		}
+		res = append(res, r)
+	}
+	return res, nil
+}
+
+// ReplayBundle simulates the bundle on top of the block before its target.
+// A failed simulation is reported in the result and only the invalid bundles return an error.
+func (self *Replayer) ReplayBundle(ctx context.Context, b *ManagedBundle) (*ReplayResult, error) {
+	target := b.TargetBlock()
+	if target == 0 {
+		return nil, errors.Errorf("bundle has no target block id:%v", b.ID)
+	}
+	res := &ReplayResult{Bundle: b, StateBlock: target - 1}
+
+	sim, err := self.sim.CallBundle(ctx, b.Params.Txs, res.StateBlock)
+	if err != nil {
+		res.Outcome = ReplayFailed
+		res.Err = err
+		res.Reason = fmt.Sprintf("simulation failed:%v", err)
+		return res, nil
+	}
+	res.Sim = sim
+
+	allowed := make(map[string]bool, len(b.Params.RevertingTxHashes))
+	for _, h := range b.Params.RevertingTxHashes {
+		allowed[strings.ToLower(h)] = true
+	}
+	var reverts []string
+	for i, tx := range sim.Results {
+		if (tx.Error == "" && tx.Revert == "") || allowed[strings.ToLower(tx.TxHash)] {
+			continue
+		}
+		reason := tx.Revert
+		if reason == "" {
+			reason = tx.Error
+		}
+		reverts = append(reverts, fmt.Sprintf("TX index:%v hash:%v %v", i, tx.TxHash, reason))
+	}
+
+	if b.SimResult != nil {
+		if orig, err := b.SimResult.Result.TotalCoinbaseDiff(); err == nil {
+			if replay, err := sim.Result.TotalCoinbaseDiff(); err == nil {
+				res.ProfitDiff = new(big.Int).Sub(replay, orig)
+			}
+		}
+	}
+
+	if len(reverts) > 0 {
+		res.Outcome = ReplayReverted
+		res.Reason = "reverted " + strings.Join(reverts, "; ")
+		return res, nil
+	}
+	res.Outcome = ReplayValid
+	res.Reason = "valid at the target block, most likely outbid"
+	if res.ProfitDiff != nil && res.ProfitDiff.Sign() < 0 {
+		res.Reason += fmt.Sprintf(" and the coinbase diff was lower than simulated by:%v", FormatWei(new(big.Int).Neg(res.ProfitDiff)))
+	}
+	return res, nil
+}
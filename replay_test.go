@@ -0,0 +1,78 @@
+// Copyright (c) The Cryptorium Authors.
+// Licensed under the MIT License.
+
+package flashbot
+
+import (
+	"context"
+	"math/big"
+	"testing"
+
+	"github.com/cryptoriums/packages/testutil"
+	"github.com/pkg/errors"
+)
+
+type replaySimMock struct {
+	stateBlocks []uint64
+	resp        map[string]*Response
+}
+
+func (self *replaySimMock) CallBundle(ctx context.Context, txsHex []string, blockNumState uint64) (*Response, error) {
+	self.stateBlocks = append(self.stateBlocks, blockNumState)
+	resp, ok := self.resp[txsHex[0]]
+	if !ok {
+		return nil, errors.New("nonce too low")
+	}
+	return resp, nil
+}
+
+func TestReplayer(t *testing.T) {
+	ctx := context.Background()
+	store := NewMemoryStore()
+	for id, b := range map[string]*ManagedBundle{
+		"valid":    {Params: ParamsSend{BlockNum: "0x65", Txs: []string{"0x01"}}, SimResult: &Response{Result: Result{Results: []TxResult{{Metadata: Metadata{CoinbaseDiff: "300"}}}}}},
+		"reverted": {Params: ParamsSend{BlockNum: "0x65", Txs: []string{"0x02", "0x03"}}},
+		"allowed":  {Params: ParamsSend{BlockNum: "0x65", Txs: []string{"0x02"}, RevertingTxHashes: []string{"0xABC"}}},
+		"failed":   {Params: ParamsSend{BlockNum: "0x65", Txs: []string{"0x04"}}},
+	} {
+		b.ID = id
+		testutil.Ok(t, store.Save(ctx, b))
+	}
+	sim := &replaySimMock{resp: map[string]*Response{
+		"0x01": {Result: Result{Results: []TxResult{{Metadata: Metadata{CoinbaseDiff: "100"}}}}},
+		"0x02": {Result: Result{Results: []TxResult{{TxHash: "0xabc", Error: "execution reverted", Metadata: Metadata{CoinbaseDiff: "0"}}}}},
+	}}
+	replayer := NewReplayer(store, sim)
+
+	res, err := replayer.Replay(ctx, "valid")
+	testutil.Ok(t, err)
+	testutil.Equals(t, ReplayValid, res.Outcome)
+	testutil.Equals(t, uint64(100), res.StateBlock)
+	testutil.Equals(t, big.NewInt(-200), res.ProfitDiff)
+	testutil.Equals(t, []uint64{100}, sim.stateBlocks)
+
+	res, err = replayer.Replay(ctx, "reverted")
+	testutil.Ok(t, err)
+	testutil.Equals(t, ReplayReverted, res.Outcome)
+	testutil.Assert(t, res.ProfitDiff == nil, "profit diff without an original simulation")
+
+	// The reverts allowed by the bundle don't count.
+	res, err = replayer.Replay(ctx, "allowed")
+	testutil.Ok(t, err)
+	testutil.Equals(t, ReplayValid, res.Outcome)
+
+	res, err = replayer.Replay(ctx, "failed")
+	testutil.Ok(t, err)
+	testutil.Equals(t, ReplayFailed, res.Outcome)
+	testutil.NotOk(t, res.Err)
+
+	_, err = replayer.Replay(ctx, "unknown")
+	testutil.Assert(t, errors.Is(err, ErrBundleNotFound), "unexpected error:%v", err)
+
+	all, err := replayer.ReplayAll(ctx, BundleFilter{})
+	testutil.Ok(t, err)
+	testutil.Equals(t, 4, len(all))
+
+	_, err = replayer.ReplayBundle(ctx, &ManagedBundle{ID: "x", Params: ParamsSend{BlockNum: "0x0"}})
+	testutil.NotOk(t, err)
+}
@@ -0,0 +1,119 @@
+// Copyright (c) The Cryptorium Authors.
+// Licensed under the MIT License.
+
+package flashbot
+
+import (
+	"fmt"
+	"math/big"
+	"strings"
+	"text/tabwriter"
+
+	"github.com/pkg/errors"
+)
+
+type GasReportTx struct {
+	Index         int
+	TxHash        string
+	From          string
+	GasUsed       uint64
+	CumulativeGas uint64
+	// GasPrice is the effective price paid to the coinbase per gas.
+	GasPrice        *big.Int
+	CoinbasePayment *big.Int
+	// BlockGasPercent is the percentage of the block gas limit used by the TX.
+	BlockGasPercent float64
+	Error           string
+}
+
+// GasReport is the per TX gas breakdown of a bundle simulation.
+type GasReport struct {
+	Txs             []GasReportTx
+	GasUsed         uint64
+	BlockGasLimit   uint64
+	BlockGasPercent float64
+	CoinbaseDiff    *big.Int
+	BundleGasPrice  *big.Int
+	GasFees         *big.Int
+	NetProfit       *big.Int
+}
+
+// NewGasReport creates a report from the simulation result.
+// The block gas limit is used only for the percentages which are 0 when it is not set.
+func NewGasReport(result *Result, blockGasLimit uint64) (*GasReport, error) {
+	coinbaseDiff, err := parseWei("coinbase diff", result.CoinbaseDiff)
+	if err != nil {
+		return nil, err
+	}
+	gasPrice, err := parseWei("bundle gas price", result.BundleGasPrice)
+	if err != nil {
+		return nil, err
+	}
+	totals, err := result.Totals()
+	if err != nil {
+		return nil, err
+	}
+	report := &GasReport{
+		BlockGasLimit:  blockGasLimit,
+		CoinbaseDiff:   coinbaseDiff,
+		BundleGasPrice: gasPrice,
+		GasFees:        totals.GasFees,
+		NetProfit:      totals.NetProfit,
+	}
+	for i, tx := range result.Results {
+		price, err := parseWei("gas price", tx.GasPrice)
+		if err != nil {
+			return nil, errors.Wrapf(err, "TX index:%v", i)
+		}
+		payment, err := parseWei("coinbase diff", tx.CoinbaseDiff)
+		if err != nil {
+			return nil, errors.Wrapf(err, "TX index:%v", i)
+		}
+		report.GasUsed += tx.GasUsed
+		r := GasReportTx{
+			Index:           i,
+			TxHash:          tx.TxHash,
+			From:            tx.FromAddress,
+			GasUsed:         tx.GasUsed,
+			CumulativeGas:   report.GasUsed,
+			GasPrice:        price,
+			CoinbasePayment: payment,
+			BlockGasPercent: gasPercent(tx.GasUsed, blockGasLimit),
+			Error:           tx.Error,
+		}
+		if tx.Revert != "" {
+			r.Error = strings.TrimPrefix(r.Error+": "+tx.Revert, ": ")
+		}
+		report.Txs = append(report.Txs, r)
+	}
+	report.BlockGasPercent = gasPercent(report.GasUsed, blockGasLimit)
+	return report, nil
+}
+
+func gasPercent(gas, limit uint64) float64 {
+	if limit == 0 {
+		return 0
+	}
+	return float64(gas) * 100 / float64(limit)
+}
+
+func (self *GasReport) String() string {
+	var b strings.Builder
+	w := tabwriter.NewWriter(&b, 0, 0, 2, ' ', tabwriter.AlignRight)
+	fmt.Fprintln(w, "#\tTX\tGAS\tCUMULATIVE\tBLOCK %\tGAS PRICE\tCOINBASE\tERROR\t")
+	for _, tx := range self.Txs {
+		fmt.Fprintf(w, "%d\t%v\t%d\t%d\t%.3f\t%v\t%v\t%v\t\n",
+			tx.Index, shortHash(tx.TxHash), tx.GasUsed, tx.CumulativeGas, tx.BlockGasPercent, tx.GasPrice, tx.CoinbasePayment, tx.Error)
+	}
+	fmt.Fprintf(w, "total\t\t%d\t\t%.3f\t%v\t%v\t\t\n", self.GasUsed, self.BlockGasPercent, self.BundleGasPrice, self.CoinbaseDiff)
+	_ = w.Flush()
+	fmt.Fprintf(&b, "gas fees:%v net profit:%v\n", self.GasFees, self.NetProfit)
+	return b.String()
+}
+
+func shortHash(hash string) string {
+	if len(hash) <= 14 {
+		return hash
+	}
+	return hash[:8] + ".." + hash[len(hash)-4:]
+}
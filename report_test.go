@@ -0,0 +1,42 @@
+// Copyright (c) The Cryptorium Authors.
+// Licensed under the MIT License.
+
+package flashbot
+
+import (
+	"math/big"
+	"strings"
+	"testing"
+
+	"github.com/cryptoriums/packages/testutil"
+)
+
+func TestGasReport(t *testing.T) {
+	result := &Result{
+		BundleGasPrice: "2000",
+		Metadata:       Metadata{CoinbaseDiff: "150000000"},
+		Results: []TxResult{
+			{TxHash: "0x26fc6ebdb3fa23fb2145e822e58bebc1bc91867f50ef0a5f8fffff2e3178f9fd", GasUsed: 50_000, GasPrice: "1000", Metadata: Metadata{CoinbaseDiff: "50000000", GasFees: "50000000"}},
+			{TxHash: "0xbb", GasUsed: 25_000, GasPrice: "4000", Error: "execution reverted", Revert: "too late", Metadata: Metadata{CoinbaseDiff: "100000000"}},
+		},
+	}
+	report, err := NewGasReport(result, 1_000_000)
+	testutil.Ok(t, err)
+	testutil.Equals(t, uint64(75_000), report.GasUsed)
+	testutil.Equals(t, 7.5, report.BlockGasPercent)
+	testutil.Equals(t, uint64(75_000), report.Txs[1].CumulativeGas)
+	testutil.Equals(t, 2.5, report.Txs[1].BlockGasPercent)
+	testutil.Equals(t, big.NewInt(4000), report.Txs[1].GasPrice)
+	testutil.Equals(t, "execution reverted: too late", report.Txs[1].Error)
+
+	testutil.Equals(t, big.NewInt(50_000_000), report.GasFees)
+	testutil.Equals(t, big.NewInt(100_000_000), report.NetProfit)
+
+	out := report.String()
+	testutil.Equals(t, 5, len(strings.Split(strings.TrimSpace(out), "\n")))
+	testutil.Assert(t, strings.Contains(out, "net profit:100000000"), "missing net profit:\n%v", out)
+	testutil.Assert(t, strings.Contains(out, "0x26fc6e..f9fd"), "missing short hash:\n%v", out)
+
+	_, err = NewGasReport(&Result{Results: []TxResult{{GasPrice: "bad"}}}, 0)
+	testutil.NotOk(t, err)
+}
@@ -0,0 +1,299 @@
+// Copyright (c) The Cryptorium Authors.
+// Licensed under the MIT License.
+
+package flashbot
+
+import (
+	"context"
+	"math/big"
+	"time"
+
+	"github.com/ethereum/go-ethereum"
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/common/hexutil"
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/crypto"
+	"github.com/pkg/errors"
+)
+
+// ReceiptBackend is implemented by ethclient.Client and Node.
+type ReceiptBackend interface {
+	TransactionReceipt(ctx context.Context, txHash common.Hash) (*types.Receipt, error)
+}
+
+type ResubmitConfig struct {
+	// MaxBlocks is the maximum number of targeted blocks, zero means no limit.
+	MaxBlocks uint64
+	// RangeBlocks makes each attempt valid for this many blocks through the mev_sendBundle inclusion range
+	// and the bundle is resubmitted only after the range has passed.
+	// Zero or one sends a single block eth_sendBundle for every block.
+	RangeBlocks uint64
+	// MaxTime is the maximum time to keep resubmitting, zero means no limit.
+	MaxTime time.Duration
+	// Rebuild is called before every attempt after the first one with the attempt number starting at 1
+	// and allows escalating the fees between the attempts. When nil the same TXs are resent.
+	Rebuild func(ctx context.Context, attempt int, blockNum uint64) ([]string, error)
+	// Events receives an event for each attempt and a final one, the sends don't block.
+	Events chan<- ResubmitEvent
+	// CanRevert are the indexes of the bundle TXs allowed to revert in every attempt,
+	// sent as the revertingTxHashes of eth_sendBundle and the canRevert body items of mev_sendBundle.
+	CanRevert []int
+}
+
+type ResubmitEvent struct {
+	Attempt  int
+	BlockNum uint64
+	// MaxBlock is the last block of the attempt inclusion range.
+	MaxBlock uint64
+	Response *Response
+	Err      error
+	// Done is set on the final event.
+	Done     bool
+	Included bool
+}
+
+type ResubmitResult struct {
+	Included bool
+	// BlockNum is the inclusion block or the last targeted block when not included.
+	BlockNum uint64
+	Attempts int
+	// Blocks is the number of targeted blocks which is higher than the attempts when using ranges.
+	Blocks uint64
+	// Txs are the TXs of the last attempt.
+	Txs []string
+}
+
+// ResubmitUntilIncluded sends the bundle for the block after the head
+// and on every new head re-targets it at the next block until it lands or the deadline is reached.
+// A bundle is considered included once all its TXs from any of the attempts have receipts.
+// With RangeBlocks a single attempt covers several blocks and the bundle
+// isn't resubmitted while its range is still valid.
+func (self *Flashbot) ResubmitUntilIncluded(
+	ctx context.Context,
+	txsHex []string,
+	head uint64,
+	heads <-chan *types.Header,
+	receipts ReceiptBackend,
+	cfg ResubmitConfig,
+) (*ResubmitResult, error) {
+	if cfg.MaxTime > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, cfg.MaxTime)
+		defer cancel()
+	}
+
+	emit := func(e ResubmitEvent) {
+		if cfg.Events == nil {
+			return
+		}
+		select {
+		case cfg.Events <- e:
+		default:
+		}
+	}
+
+	var (
+		versions [][]common.Hash
+		res      = &ResubmitResult{Txs: txsHex}
+		// The first block of the current attempt range, the last one is res.BlockNum.
+		from uint64
+	)
+	submit := func(blockNum uint64) error {
+		if res.Attempts > 0 && cfg.Rebuild != nil {
+			txs, err := cfg.Rebuild(ctx, res.Attempts, blockNum)
+			if err != nil {
+				return errors.Wrapf(err, "rebuild bundle attempt:%v", res.Attempts)
+			}
+			res.Txs = txs
+		}
+		hashes, err := txHashes(res.Txs)
+		if err != nil {
+			return err
+		}
+		if len(versions) == 0 || !sameHashes(versions[len(versions)-1], hashes) {
+			versions = append(versions, hashes)
+		}
+
+		maxBlock := blockNum
+		if cfg.RangeBlocks > 1 {
+			maxBlock = blockNum + cfg.RangeBlocks - 1
+		}
+		if cfg.MaxBlocks > 0 && res.Blocks+maxBlock-blockNum+1 > cfg.MaxBlocks {
+			maxBlock = blockNum + cfg.MaxBlocks - res.Blocks - 1
+		}
+
+		res.Attempts++
+		res.Blocks += maxBlock - blockNum + 1
+		res.BlockNum = maxBlock
+		from = blockNum
+		param := ParamsSend{Txs: res.Txs, BlockNum: hexutil.EncodeUint64(blockNum)}
+		for _, i := range cfg.CanRevert {
+			if i < 0 || i >= len(hashes) {
+				return errors.Errorf("can revert index:%v out of range TXs count:%v", i, len(hashes))
+			}
+			param.RevertingTxHashes = append(param.RevertingTxHashes, hashes[i].Hex())
+		}
+		var resp *Response
+		if maxBlock > blockNum {
+			resp, err = self.sendBundleRange(ctx, param, maxBlock)
+		} else {
+			resp, err = self.SendBundleParams(ctx, param)
+		}
+		emit(ResubmitEvent{Attempt: res.Attempts, BlockNum: blockNum, MaxBlock: maxBlock, Response: resp, Err: err})
+		// A rejected attempt is not fatal as the bundle may still be accepted for the next block.
+		return nil
+	}
+	done := func(included bool, err error) (*ResubmitResult, error) {
+		res.Included = included
+		emit(ResubmitEvent{Attempt: res.Attempts, BlockNum: res.BlockNum, Done: true, Included: included, Err: err})
+		if err != nil {
+			return res, err
+		}
+		return res, nil
+	}
+
+	if err := submit(head + 1); err != nil {
+		return done(false, err)
+	}
+
+	for {
+		select {
+		case <-ctx.Done():
+			return done(false, errors.Wrap(ctx.Err(), "resubmission deadline"))
+		case h, ok := <-heads:
+			if !ok {
+				return done(false, errors.New("heads channel closed"))
+			}
+			if h.Number.Uint64() < from {
+				continue
+			}
+			blockNum, included, err := bundleIncluded(ctx, receipts, versions)
+			if err != nil {
+				return done(false, err)
+			}
+			if included {
+				res.BlockNum = blockNum
+				return done(true, nil)
+			}
+			// The current attempt is still valid for the next blocks.
+			if h.Number.Uint64() < res.BlockNum {
+				continue
+			}
+			if cfg.MaxBlocks > 0 && res.Blocks >= cfg.MaxBlocks {
+				return done(false, errors.Errorf("bundle not included after blocks:%v", res.Blocks))
+			}
+			if err := submit(h.Number.Uint64() + 1); err != nil {
+				return done(false, err)
+			}
+		}
+	}
+}
+
+// sendBundleRange sends the bundle as a MEV-Share bundle valid from its block until the max block.
+// The reverting TX hashes are sent as the canRevert body items.
+func (self *Flashbot) sendBundleRange(ctx context.Context, param ParamsSend, maxBlock uint64) (*Response, error) {
+	reverting := make(map[common.Hash]bool, len(param.RevertingTxHashes))
+	for _, h := range param.RevertingTxHashes {
+		reverting[common.HexToHash(h)] = true
+	}
+	params := MevSendBundleParams{
+		Inclusion: Inclusion{
+			Block:    param.BlockNum,
+			MaxBlock: hexutil.EncodeUint64(maxBlock),
+		},
+	}
+	for _, txHex := range param.Txs {
+		item := MevBundleItem{Tx: txHex}
+		if len(reverting) > 0 {
+			tx, err := DecodeTx(txHex)
+			if err != nil {
+				return nil, errors.Wrap(err, "decode bundle TX")
+			}
+			item.CanRevert = reverting[tx.Hash()]
+		}
+		params.Body = append(params.Body, item)
+	}
+	rr, err := self.MevSendBundle(ctx, params)
+	if err != nil {
+		return nil, err
+	}
+	return &Response{Error: rr.Error, Result: Result{BundleHash: rr.Result.BundleHash}}, nil
+}
+
+// FeeEscalation returns a ResubmitConfig.Rebuild function that signs the specs
+// with the tips and fee caps increased by bumpPercent for every attempt.
+func (self *Flashbot) FeeEscalation(netID int64, nonce uint64, specs []TxSpec, bumpPercent int64, opts ...TxOption) func(ctx context.Context, attempt int, blockNum uint64) ([]string, error) {
+	return func(ctx context.Context, attempt int, blockNum uint64) ([]string, error) {
+		bumped := make([]TxSpec, len(specs))
+		for i, spec := range specs {
+			if spec.GasTipCap != nil {
+				spec.GasTipCap = escalate(spec.GasTipCap, bumpPercent, attempt)
+			}
+			if spec.GasFeeCap != nil {
+				spec.GasFeeCap = escalate(spec.GasFeeCap, bumpPercent, attempt)
+			}
+			bumped[i] = spec
+		}
+		txsHex, _, err := self.SignTxs(ctx, netID, nonce, bumped, opts...)
+		return txsHex, err
+	}
+}
+
+func escalate(v *big.Int, bumpPercent int64, attempt int) *big.Int {
+	res := new(big.Int).Set(v)
+	for i := 0; i < attempt; i++ {
+		res.Mul(res, big.NewInt(100+bumpPercent))
+		res.Div(res, big.NewInt(100))
+	}
+	return res
+}
+
+func txHashes(txsHex []string) ([]common.Hash, error) {
+	if len(txsHex) == 0 {
+		return nil, errors.New("bundle has no TXs")
+	}
+	hashes := make([]common.Hash, 0, len(txsHex))
+	for i, txHex := range txsHex {
+		raw, err := hexutil.Decode(txHex)
+		if err != nil {
+			return nil, errors.Wrapf(err, "decode TX index:%v", i)
+		}
+		hashes = append(hashes, crypto.Keccak256Hash(raw))
+	}
+	return hashes, nil
+}
+
+// sameHashes returns whether both bundle versions have the same TXs in the same order.
+func sameHashes(a, b []common.Hash) bool {
+	if len(a) != len(b) {
+		return false
+	}
+	for i := range a {
+		if a[i] != b[i] {
+			return false
+		}
+	}
+	return true
+}
+
+func bundleIncluded(ctx context.Context, receipts ReceiptBackend, versions [][]common.Hash) (uint64, bool, error) {
+	for _, hashes := range versions {
+		var blockNum uint64
+		included := true
+		for _, h := range hashes {
+			r, err := receipts.TransactionReceipt(ctx, h)
+			if err != nil {
+				if errors.Is(err, ethereum.NotFound) {
+					included = false
+					break
+				}
+				return 0, false, errors.Wrapf(err, "get receipt TX:%v", h.Hex())
+			}
+			blockNum = r.BlockNumber.Uint64()
+		}
+		if included {
+			return blockNum, true, nil
+		}
+	}
+	return 0, false, nil
+}
@@ -0,0 +1,264 @@
+// Copyright (c) The Cryptorium Authors.
+// Licensed under the MIT License.
+
+package flashbot
+
+import (
+	"context"
+	"encoding/json"
+	"math/big"
+	"sync"
+	"testing"
+
+	"github.com/cryptoriums/packages/testutil"
+	"github.com/ethereum/go-ethereum"
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/core/types"
+)
+
+type receiptsMock struct {
+	mtx      sync.Mutex
+	included map[common.Hash]uint64
+}
+
+func (self *receiptsMock) TransactionReceipt(ctx context.Context, txHash common.Hash) (*types.Receipt, error) {
+	self.mtx.Lock()
+	defer self.mtx.Unlock()
+	blockNum, ok := self.included[txHash]
+	if !ok {
+		return nil, ethereum.NotFound
+	}
+	return &types.Receipt{TxHash: txHash, BlockNumber: new(big.Int).SetUint64(blockNum)}, nil
+}
+
+func TestResubmitUntilIncluded(t *testing.T) {
+	ctx := context.Background()
+	var (
+		mtx     sync.Mutex
+		targets []string
+	)
+	relay := newRelayMock(t, func(method string, params json.RawMessage) (interface{}, *jsonError) {
+		var p []ParamsSend
+		testutil.Ok(t, json.Unmarshal(params, &p))
+		mtx.Lock()
+		targets = append(targets, p[0].BlockNum)
+		mtx.Unlock()
+		return Result{BundleHash: "0xbundle"}, nil
+	})
+	fb := newTestFlashbot(t, relay.URL)
+
+	specs := []TxSpec{{To: &common.Address{}, Gas: 21000, GasFeeCap: big.NewInt(100), GasTipCap: big.NewInt(10)}}
+	txsHex, _, err := fb.SignTxs(ctx, 1, 0, specs)
+	testutil.Ok(t, err)
+
+	receipts := &receiptsMock{included: map[common.Hash]uint64{}}
+	heads := make(chan *types.Header, 3)
+	events := make(chan ResubmitEvent, 10)
+	cfg := ResubmitConfig{Events: events}
+
+	var last []string
+	cfg.Rebuild = func(ctx context.Context, attempt int, blockNum uint64) ([]string, error) {
+		txs, err := fb.FeeEscalation(1, 0, specs, 10)(ctx, attempt, blockNum)
+		last = txs
+		if attempt == 2 {
+			// Include the second escalated version in the block after it was sent.
+			hashes, err := txHashes(txs)
+			testutil.Ok(t, err)
+			receipts.mtx.Lock()
+			receipts.included[hashes[0]] = blockNum
+			receipts.mtx.Unlock()
+		}
+		return txs, err
+	}
+
+	heads <- &types.Header{Number: big.NewInt(11)}
+	heads <- &types.Header{Number: big.NewInt(12)}
+	heads <- &types.Header{Number: big.NewInt(13)}
+
+	res, err := fb.ResubmitUntilIncluded(ctx, txsHex, 10, heads, receipts, cfg)
+	testutil.Ok(t, err)
+	testutil.Assert(t, res.Included, "bundle should be included")
+	testutil.Equals(t, 3, res.Attempts)
+	testutil.Equals(t, uint64(13), res.BlockNum)
+	testutil.Equals(t, last, res.Txs)
+	testutil.Equals(t, []string{"0xb", "0xc", "0xd"}, targets)
+
+	decoded, err := DecodeTx(res.Txs[0])
+	testutil.Ok(t, err)
+	testutil.Equals(t, big.NewInt(12), decoded.GasTipCap())
+
+	testutil.Equals(t, 4, len(events))
+}
+
+func TestResubmitRebuildLaterTx(t *testing.T) {
+	ctx := context.Background()
+	relay := newRelayMock(t, func(method string, params json.RawMessage) (interface{}, *jsonError) {
+		return Result{BundleHash: "0xbundle"}, nil
+	})
+	fb := newTestFlashbot(t, relay.URL)
+
+	specs := func(bribe int64) []TxSpec {
+		return []TxSpec{
+			{To: &common.Address{}, Gas: 21000, GasFeeCap: big.NewInt(100), GasTipCap: big.NewInt(10)},
+			{To: &common.Address{1}, Gas: 21000, GasFeeCap: big.NewInt(100), GasTipCap: big.NewInt(bribe)},
+		}
+	}
+	txsHex, _, err := fb.SignTxs(ctx, 1, 0, specs(10))
+	testutil.Ok(t, err)
+
+	receipts := &receiptsMock{included: map[common.Hash]uint64{}}
+	cfg := ResubmitConfig{}
+	// Only the bribe TX is repriced so the first TX of the bundle stays the same.
+	cfg.Rebuild = func(ctx context.Context, attempt int, blockNum uint64) ([]string, error) {
+		txs, _, err := fb.SignTxs(ctx, 1, 0, specs(20))
+		testutil.Ok(t, err)
+		hashes, err := txHashes(txs)
+		testutil.Ok(t, err)
+		receipts.mtx.Lock()
+		for _, h := range hashes {
+			receipts.included[h] = blockNum
+		}
+		receipts.mtx.Unlock()
+		return txs, nil
+	}
+
+	heads := make(chan *types.Header, 2)
+	heads <- &types.Header{Number: big.NewInt(11)}
+	heads <- &types.Header{Number: big.NewInt(12)}
+	close(heads)
+
+	res, err := fb.ResubmitUntilIncluded(ctx, txsHex, 10, heads, receipts, cfg)
+	testutil.Ok(t, err)
+	testutil.Assert(t, res.Included, "rebuilt bundle should be included")
+	testutil.Equals(t, 2, res.Attempts)
+	testutil.Equals(t, txsHex[0], res.Txs[0])
+}
+
+func TestResubmitMaxBlocks(t *testing.T) {
+	ctx := context.Background()
+	relay := newRelayMock(t, func(method string, params json.RawMessage) (interface{}, *jsonError) {
+		return Result{BundleHash: "0xbundle"}, nil
+	})
+	fb := newTestFlashbot(t, relay.URL)
+
+	specs := []TxSpec{{To: &common.Address{}, Gas: 21000, GasFeeCap: big.NewInt(100), GasTipCap: big.NewInt(10)}}
+	txsHex, _, err := fb.SignTxs(ctx, 1, 0, specs)
+	testutil.Ok(t, err)
+
+	heads := make(chan *types.Header, 2)
+	heads <- &types.Header{Number: big.NewInt(11)}
+	heads <- &types.Header{Number: big.NewInt(12)}
+
+	res, err := fb.ResubmitUntilIncluded(ctx, txsHex, 10, heads, &receiptsMock{}, ResubmitConfig{MaxBlocks: 2})
+	testutil.NotOk(t, err)
+	testutil.Assert(t, !res.Included, "bundle shouldn't be included")
+	testutil.Equals(t, 2, res.Attempts)
+}
+
+func TestResubmitRange(t *testing.T) {
+	ctx := context.Background()
+	var (
+		mtx        sync.Mutex
+		inclusions []Inclusion
+	)
+	relay := newRelayMock(t, func(method string, params json.RawMessage) (interface{}, *jsonError) {
+		testutil.Equals(t, "mev_sendBundle", method)
+		var p []MevSendBundleParams
+		testutil.Ok(t, json.Unmarshal(params, &p))
+		mtx.Lock()
+		inclusions = append(inclusions, p[0].Inclusion)
+		mtx.Unlock()
+		return map[string]string{"bundleHash": "0xbundle"}, nil
+	})
+	fb := newTestFlashbot(t, relay.URL)
+
+	specs := []TxSpec{{To: &common.Address{}, Gas: 21000, GasFeeCap: big.NewInt(100), GasTipCap: big.NewInt(10)}}
+	txsHex, _, err := fb.SignTxs(ctx, 1, 0, specs)
+	testutil.Ok(t, err)
+
+	heads := make(chan *types.Header, 5)
+	for n := int64(11); n <= 15; n++ {
+		heads <- &types.Header{Number: big.NewInt(n)}
+	}
+	res, err := fb.ResubmitUntilIncluded(ctx, txsHex, 10, heads, &receiptsMock{}, ResubmitConfig{RangeBlocks: 3, MaxBlocks: 5})
+	testutil.NotOk(t, err)
+	testutil.Equals(t, 2, res.Attempts)
+	testutil.Equals(t, uint64(5), res.Blocks)
+	// The second range is cut at the max blocks limit.
+	testutil.Equals(t, []Inclusion{{Block: "0xb", MaxBlock: "0xd"}, {Block: "0xe", MaxBlock: "0xf"}}, inclusions)
+
+	// Included in the middle of the range without a resubmission.
+	hashes, err := txHashes(txsHex)
+	testutil.Ok(t, err)
+	receipts := &receiptsMock{included: map[common.Hash]uint64{hashes[0]: 12}}
+	inclusions = nil
+	heads <- &types.Header{Number: big.NewInt(11)}
+	heads <- &types.Header{Number: big.NewInt(12)}
+	res, err = fb.ResubmitUntilIncluded(ctx, txsHex, 10, heads, receipts, ResubmitConfig{RangeBlocks: 3})
+	testutil.Ok(t, err)
+	testutil.Assert(t, res.Included, "bundle should be included")
+	testutil.Equals(t, uint64(12), res.BlockNum)
+	testutil.Equals(t, 1, len(inclusions))
+}
+
+func TestResubmitRangeGuards(t *testing.T) {
+	ctx := context.Background()
+	var (
+		mtx    sync.Mutex
+		bodies [][]MevBundleItem
+		sends  []ParamsSend
+	)
+	relay := newRelayMock(t, func(method string, params json.RawMessage) (interface{}, *jsonError) {
+		mtx.Lock()
+		defer mtx.Unlock()
+		if method == "mev_sendBundle" {
+			var p []MevSendBundleParams
+			testutil.Ok(t, json.Unmarshal(params, &p))
+			bodies = append(bodies, p[0].Body)
+		} else {
+			var p []ParamsSend
+			testutil.Ok(t, json.Unmarshal(params, &p))
+			sends = append(sends, p[0])
+		}
+		return map[string]string{"bundleHash": "0xbundle"}, nil
+	})
+	fb := newTestFlashbot(t, relay.URL)
+
+	specs := []TxSpec{
+		{To: &common.Address{}, Gas: 21000, GasFeeCap: big.NewInt(100), GasTipCap: big.NewInt(10)},
+		{To: &common.Address{}, Gas: 21000, GasFeeCap: big.NewInt(100), GasTipCap: big.NewInt(10)},
+	}
+	txsHex, _, err := fb.SignTxs(ctx, 1, 0, specs)
+	testutil.Ok(t, err)
+	hashes, err := txHashes(txsHex)
+	testutil.Ok(t, err)
+
+	// The reverting TXs are kept in the range and in the single block submissions.
+	heads := make(chan *types.Header, 1)
+	close(heads)
+	_, err = fb.ResubmitUntilIncluded(ctx, txsHex, 10, heads, &receiptsMock{}, ResubmitConfig{RangeBlocks: 3, CanRevert: []int{1}})
+	testutil.NotOk(t, err)
+	testutil.Equals(t, 1, len(bodies))
+	testutil.Equals(t, []bool{false, true}, []bool{bodies[0][0].CanRevert, bodies[0][1].CanRevert})
+	heads = make(chan *types.Header)
+	close(heads)
+	_, err = fb.ResubmitUntilIncluded(ctx, txsHex, 10, heads, &receiptsMock{}, ResubmitConfig{CanRevert: []int{1}})
+	testutil.NotOk(t, err)
+	testutil.Equals(t, []string{hashes[1].Hex()}, sends[0].RevertingTxHashes)
+
+	// The range submissions go through the spam guard.
+	guard, err := NewSpamGuard(1)
+	testutil.Ok(t, err)
+	fb.SetSpamGuard(guard)
+	events := make(chan ResubmitEvent, 10)
+	heads = make(chan *types.Header)
+	close(heads)
+	_, err = fb.ResubmitUntilIncluded(ctx, txsHex, 10, heads, &receiptsMock{}, ResubmitConfig{RangeBlocks: 3, Events: events})
+	testutil.NotOk(t, err)
+	_, err = fb.ResubmitUntilIncluded(ctx, txsHex, 10, heads, &receiptsMock{}, ResubmitConfig{RangeBlocks: 3, Events: events})
+	testutil.NotOk(t, err)
+	testutil.Equals(t, 2, len(bodies))
+	<-events
+	<-events
+	testutil.Assert(t, (<-events).Err != nil, "the second range should be rejected by the spam guard")
+}
@@ -0,0 +1,214 @@
+// Copyright (c) The Cryptorium Authors.
+// Licensed under the MIT License.
+
+package flashbot
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+
+	"github.com/pkg/errors"
+)
+
+// StatusError is returned when the relay replies with a non 2xx HTTP status.
+type StatusError struct {
+	StatusCode int
+	Msg        string
+}
+
+func (self *StatusError) Error() string {
+	return self.Msg
+}
+
+// ErrorClass tells whether a failed relay request is worth sending again.
+type ErrorClass int
+
+const (
+	ErrorClassUnknown ErrorClass = iota
+	// ErrorClassTransient errors might succeed on a retry.
+	ErrorClassTransient
+	// ErrorClassPermanent errors fail the same way on every retry.
+	ErrorClassPermanent
+	// ErrorClassReprice errors need the bundle TXs to be resigned with higher fees.
+	ErrorClassReprice
+)
+
+func (self ErrorClass) String() string {
+	switch self {
+	case ErrorClassTransient:
+		return "transient"
+	case ErrorClassPermanent:
+		return "permanent"
+	case ErrorClassReprice:
+		return "reprice"
+	default:
+		return "unknown"
+	}
+}
+
+// ErrorRule classifies the relay JSON-RPC errors by their code and message.
+type ErrorRule struct {
+	// Code matches any code when 0.
+	Code int
+	// Contains is matched case insensitively within the message and matches any message when empty.
+	Contains string
+	Class    ErrorClass
+}
+
+func (self ErrorRule) match(code int, msg string) bool {
+	if self.Code != 0 && self.Code != code {
+		return false
+	}
+	return self.Contains == "" || strings.Contains(strings.ToLower(msg), strings.ToLower(self.Contains))
+}
+
+// DefaultErrorRules are the known relay errors, the first matching rule wins.
+var DefaultErrorRules = []ErrorRule{
+	{Contains: "already known", Class: ErrorClassPermanent},
+	{Contains: "nonce too low", Class: ErrorClassPermanent},
+	{Contains: "underpriced", Class: ErrorClassReprice},
+	{Contains: "less than block base fee", Class: ErrorClassReprice},
+	{Contains: "fee too low", Class: ErrorClassReprice},
+	{Contains: "timeout", Class: ErrorClassTransient},
+	{Contains: "timed out", Class: ErrorClassTransient},
+	{Contains: "rate limit", Class: ErrorClassTransient},
+	{Contains: "too many requests", Class: ErrorClassTransient},
+	{Contains: "try again", Class: ErrorClassTransient},
+	{Code: -32700, Class: ErrorClassPermanent}, // Parse error.
+	{Code: -32600, Class: ErrorClassPermanent}, // Invalid request.
+	{Code: -32601, Class: ErrorClassPermanent}, // Method not found.
+	{Code: -32602, Class: ErrorClassPermanent}, // Invalid params.
+	{Code: -32603, Class: ErrorClassTransient}, // Internal error.
+	{Code: -32005, Class: ErrorClassTransient}, // Limit exceeded.
+}
+
+// ClassifyRelayError returns the class of the first matching rule
+// with the given rules checked before the default ones.
+func ClassifyRelayError(rules []ErrorRule, code int, msg string) ErrorClass {
+	for _, r := range rules {
+		if r.match(code, msg) {
+			return r.Class
+		}
+	}
+	for _, r := range DefaultErrorRules {
+		if r.match(code, msg) {
+			return r.Class
+		}
+	}
+	return ErrorClassUnknown
+}
+
+// RelayError is a JSON-RPC error returned by the relay.
+type RelayError struct {
+	Code    int
+	Message string
+	Class   ErrorClass
+}
+
+func (self *RelayError) Error() string {
+	return fmt.Sprintf("relay error code:%v message:%v class:%v", self.Code, self.Message, self.Class)
+}
+
+// Classify returns the class of a relay request error.
+// The transport and HTTP status errors are transient when they would be retried.
+func (self RetryPolicy) Classify(err error) ErrorClass {
+	if err == nil {
+		return ErrorClassUnknown
+	}
+	var relayErr *RelayError
+	if errors.As(err, &relayErr) {
+		return relayErr.Class
+	}
+	if retryable(err) {
+		return ErrorClassTransient
+	}
+	var statusErr *StatusError
+	if errors.As(err, &statusErr) {
+		return ErrorClassPermanent
+	}
+	return ErrorClassUnknown
+}
+
+// RetryPolicy retries the relay requests that failed because of a network error,
+// a server error, rate limiting or a transient relay error with an exponential backoff.
+type RetryPolicy struct {
+	// MaxAttempts includes the first attempt so 0 and 1 disable the retries.
+	MaxAttempts int
+	// Backoff is the wait before the first retry and doubles for each of the next ones, 100ms by default.
+	Backoff time.Duration
+	// MaxBackoff caps the wait between the retries, no cap when 0.
+	MaxBackoff time.Duration
+	// ErrorRules extend and take precedence over DefaultErrorRules.
+	ErrorRules []ErrorRule
+}
+
+const defaultRetryBackoff = 100 * time.Millisecond
+
+// do runs f until it succeeds or the attempts run out.
+// The replies are decoded with the codec to check for a transient relay error
+// only while there are attempts left so nothing is decoded when the retries are off.
+func (self RetryPolicy) do(ctx context.Context, codec Codec, f func() ([]byte, error)) ([]byte, error) {
+	backoff := self.Backoff
+	if backoff == 0 {
+		backoff = defaultRetryBackoff
+	}
+	for attempt := 1; ; attempt++ {
+		res, err := f()
+		if err == nil {
+			// The relay errors are returned with the reply as before
+			// and only the transient ones are retried.
+			if attempt >= self.MaxAttempts {
+				return res, nil
+			}
+			if relayErr := self.relayError(codec, res); relayErr == nil || relayErr.Class != ErrorClassTransient {
+				return res, nil
+			}
+		} else if attempt >= self.MaxAttempts || !retryable(err) {
+			if attempt > 1 {
+				return nil, errors.Wrapf(err, "attempts:%v", attempt)
+			}
+			return nil, err
+		}
+		select {
+		case <-ctx.Done():
+			if err == nil {
+				return res, nil
+			}
+			return nil, errors.Wrapf(err, "context done while retrying attempts:%v", attempt)
+		case <-time.After(backoff):
+		}
+		backoff *= 2
+		if self.MaxBackoff > 0 && backoff > self.MaxBackoff {
+			backoff = self.MaxBackoff
+		}
+	}
+}
+
+func retryable(err error) bool {
+	var statusErr *StatusError
+	if errors.As(err, &statusErr) {
+		return statusErr.StatusCode >= 500 || statusErr.StatusCode == http.StatusTooManyRequests
+	}
+	// Only the transport errors are retried and a canceled request can't succeed on a retry.
+	var urlErr *url.Error
+	return errors.As(err, &urlErr) && !errors.Is(err, context.Canceled) && !errors.Is(err, context.DeadlineExceeded)
+}
+
+// relayError returns the classified JSON-RPC error of the reply if it has one.
+func (self RetryPolicy) relayError(codec Codec, resp []byte) *RelayError {
+	var msg struct {
+		Error *jsonError `json:"error"`
+	}
+	if err := codec.Unmarshal(resp, &msg); err != nil || msg.Error == nil || (msg.Error.Code == 0 && msg.Error.Message == "") {
+		return nil
+	}
+	return &RelayError{
+		Code:    msg.Error.Code,
+		Message: msg.Error.Message,
+		Class:   ClassifyRelayError(self.ErrorRules, msg.Error.Code, msg.Error.Message),
+	}
+}
@@ -0,0 +1,120 @@
+// Copyright (c) The Cryptorium Authors.
+// Licensed under the MIT License.
+
+package flashbot
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/cryptoriums/packages/testutil"
+	"github.com/pkg/errors"
+)
+
+func TestRetryPolicy(t *testing.T) {
+	var calls int32
+	status := int32(http.StatusServiceUnavailable)
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if atomic.AddInt32(&calls, 1) < 3 {
+			w.WriteHeader(int(atomic.LoadInt32(&status)))
+			return
+		}
+		_, _ = w.Write([]byte(`{"jsonrpc":"2.0","id":1,"result":{"bundleHash":"0xbundle"}}`))
+	}))
+	defer srv.Close()
+
+	fb := newTestFlashbot(t, srv.URL)
+	fb.Api().Retry = RetryPolicy{MaxAttempts: 3, Backoff: time.Millisecond}
+	resp, err := fb.SendBundle(context.Background(), []string{"0x01"}, 10)
+	testutil.Ok(t, err)
+	testutil.Equals(t, "0xbundle", resp.BundleHash)
+	testutil.Equals(t, int32(3), atomic.LoadInt32(&calls))
+
+	// The client errors aren't retried.
+	atomic.StoreInt32(&calls, 0)
+	atomic.StoreInt32(&status, http.StatusBadRequest)
+	_, err = fb.SendBundle(context.Background(), []string{"0x01"}, 10)
+	var statusErr *StatusError
+	testutil.Assert(t, errors.As(err, &statusErr), "unexpected error:%v", err)
+	testutil.Equals(t, http.StatusBadRequest, statusErr.StatusCode)
+	testutil.Equals(t, int32(1), atomic.LoadInt32(&calls))
+
+	// Without a policy there are no retries.
+	atomic.StoreInt32(&calls, 0)
+	atomic.StoreInt32(&status, http.StatusTooManyRequests)
+	fb.Api().Retry = RetryPolicy{}
+	_, err = fb.SendBundle(context.Background(), []string{"0x01"}, 10)
+	testutil.NotOk(t, err)
+	testutil.Equals(t, int32(1), atomic.LoadInt32(&calls))
+}
+
+func TestRetryRelayErrors(t *testing.T) {
+	var calls int32
+	var reply atomic.Value
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if atomic.AddInt32(&calls, 1) < 3 {
+			_, _ = w.Write([]byte(reply.Load().(string)))
+			return
+		}
+		_, _ = w.Write([]byte(`{"jsonrpc":"2.0","id":1,"result":{"bundleHash":"0xbundle"}}`))
+	}))
+	defer srv.Close()
+
+	fb := newTestFlashbot(t, srv.URL)
+	fb.Api().Retry = RetryPolicy{MaxAttempts: 3, Backoff: time.Millisecond}
+	codec := &countingCodec{}
+	fb.Api().Codec = codec
+
+	// Transient relay errors are retried.
+	reply.Store(`{"jsonrpc":"2.0","id":1,"error":{"code":-32000,"message":"request timeout"}}`)
+	resp, err := fb.SendBundle(context.Background(), []string{"0x01"}, 10)
+	testutil.Ok(t, err)
+	testutil.Equals(t, "0xbundle", resp.BundleHash)
+	testutil.Equals(t, int32(3), atomic.LoadInt32(&calls))
+	// The replies with attempts left are checked with the codec and the last one is only parsed.
+	testutil.Equals(t, int32(3), atomic.LoadInt32(&codec.unmarshals))
+
+	// Permanent ones are returned right away.
+	atomic.StoreInt32(&calls, 0)
+	reply.Store(`{"jsonrpc":"2.0","id":1,"error":{"code":-32000,"message":"bundle already known"}}`)
+	_, err = fb.SendBundle(context.Background(), []string{"0x01"}, 10)
+	var relayErr *RelayError
+	testutil.Assert(t, errors.As(err, &relayErr), "unexpected error:%v", err)
+	testutil.Equals(t, ErrorClassPermanent, relayErr.Class)
+	testutil.Equals(t, ErrorClassPermanent, fb.Api().Retry.Classify(err))
+	testutil.Equals(t, int32(1), atomic.LoadInt32(&calls))
+
+	// User rules take precedence.
+	atomic.StoreInt32(&calls, 0)
+	fb.Api().Retry.ErrorRules = []ErrorRule{{Code: -32000, Contains: "already known", Class: ErrorClassTransient}}
+	_, err = fb.SendBundle(context.Background(), []string{"0x01"}, 10)
+	testutil.Ok(t, err)
+	testutil.Equals(t, int32(3), atomic.LoadInt32(&calls))
+}
+
+func TestClassifyRelayError(t *testing.T) {
+	for _, tc := range []struct {
+		code  int
+		msg   string
+		class ErrorClass
+	}{
+		{code: -32000, msg: "bundle already known", class: ErrorClassPermanent},
+		{code: -32000, msg: "Replacement transaction underpriced", class: ErrorClassReprice},
+		{code: -32000, msg: "max fee per gas less than block base fee", class: ErrorClassReprice},
+		{code: -32000, msg: "upstream timed out", class: ErrorClassTransient},
+		{code: -32602, msg: "invalid block number", class: ErrorClassPermanent},
+		{code: -32603, msg: "internal", class: ErrorClassTransient},
+		{code: -32000, msg: "something else", class: ErrorClassUnknown},
+	} {
+		testutil.Equals(t, tc.class, ClassifyRelayError(nil, tc.code, tc.msg), tc.msg)
+	}
+
+	rules := []ErrorRule{{Contains: "something", Class: ErrorClassReprice}}
+	testutil.Equals(t, ErrorClassReprice, ClassifyRelayError(rules, -32000, "something else"))
+	testutil.Equals(t, ErrorClassTransient, RetryPolicy{}.Classify(&StatusError{StatusCode: http.StatusBadGateway}))
+	testutil.Equals(t, ErrorClassPermanent, RetryPolicy{}.Classify(&StatusError{StatusCode: http.StatusBadRequest}))
+}
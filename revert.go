@@ -0,0 +1,147 @@
+// Copyright (c) The Cryptorium Authors.
+// Licensed under the MIT License.
+
+package flashbot
+
+import (
+	"bytes"
+	"fmt"
+	"math/big"
+	"strings"
+
+	"github.com/ethereum/go-ethereum/accounts/abi"
+	"github.com/ethereum/go-ethereum/common/hexutil"
+	"github.com/ethereum/go-ethereum/crypto"
+	"github.com/pkg/errors"
+)
+
+type RevertKind string
+
+const (
+	RevertEmpty  RevertKind = "empty"
+	RevertError  RevertKind = "error"
+	RevertPanic  RevertKind = "panic"
+	RevertCustom RevertKind = "custom"
+	// RevertRaw is for revert data that doesn't match any of the known signatures.
+	RevertRaw RevertKind = "raw"
+)
+
+var (
+	errorSelector = crypto.Keccak256([]byte("Error(string)"))[:4]
+	panicSelector = crypto.Keccak256([]byte("Panic(uint256)"))[:4]
+)
+
+// panicReasons are the solidity panic codes descriptions.
+var panicReasons = map[uint64]string{
+	0x00: "generic compiler panic",
+	0x01: "assert failed",
+	0x11: "arithmetic overflow or underflow",
+	0x12: "division or modulo by zero",
+	0x21: "invalid enum value",
+	0x22: "invalid storage byte array encoding",
+	0x31: "pop on empty array",
+	0x32: "array index out of bounds",
+	0x41: "out of memory",
+	0x51: "call to uninitialized internal function",
+}
+
+type RevertReason struct {
+	Kind RevertKind
+	// Message is the Error(string) message or the panic code description.
+	Message   string
+	PanicCode *big.Int
+	// Name and Args are set for the custom errors.
+	Name string
+	Args []interface{}
+	Data []byte
+}
+
+func (self *RevertReason) String() string {
+	switch self.Kind {
+	case RevertEmpty:
+		return "reverted without a reason"
+	case RevertError:
+		return self.Message
+	case RevertPanic:
+		return fmt.Sprintf("panic 0x%x: %v", self.PanicCode, self.Message)
+	case RevertCustom:
+		args := make([]string, len(self.Args))
+		for i, a := range self.Args {
+			args[i] = fmt.Sprintf("%v", a)
+		}
+		return self.Name + "(" + strings.Join(args, ", ") + ")"
+	default:
+		return hexutil.Encode(self.Data)
+	}
+}
+
+// DecodeRevert decodes the hex encoded revert data as returned in the simulation results.
+// The custom errors are looked up in the given ABIs.
+// A revert that isn't hex encoded is already decoded by the relay and is returned as an error message.
+func DecodeRevert(revert string, abis ...*abi.ABI) (*RevertReason, error) {
+	if revert == "" || revert == "0x" {
+		return &RevertReason{Kind: RevertEmpty}, nil
+	}
+	if !strings.HasPrefix(revert, "0x") {
+		return &RevertReason{Kind: RevertError, Message: revert}, nil
+	}
+	data, err := hexutil.Decode(revert)
+	if err != nil {
+		return nil, errors.Wrap(err, "decode revert hex")
+	}
+	return DecodeRevertData(data, abis...)
+}
+
+// DecodeRevertData decodes the raw revert data.
+func DecodeRevertData(data []byte, abis ...*abi.ABI) (*RevertReason, error) {
+	if len(data) == 0 {
+		return &RevertReason{Kind: RevertEmpty}, nil
+	}
+	if len(data) < 4 {
+		return &RevertReason{Kind: RevertRaw, Data: data}, nil
+	}
+
+	switch {
+	case bytes.Equal(data[:4], errorSelector):
+		msg, err := abi.UnpackRevert(data)
+		if err != nil {
+			return nil, errors.Wrap(err, "unpack Error(string)")
+		}
+		return &RevertReason{Kind: RevertError, Message: msg, Data: data}, nil
+	case bytes.Equal(data[:4], panicSelector):
+		if len(data) != 36 {
+			return nil, errors.Errorf("invalid Panic(uint256) data length:%v", len(data))
+		}
+		code := new(big.Int).SetBytes(data[4:])
+		reason := "unknown panic code"
+		if code.IsUint64() {
+			if r, ok := panicReasons[code.Uint64()]; ok {
+				reason = r
+			}
+		}
+		return &RevertReason{Kind: RevertPanic, Message: reason, PanicCode: code, Data: data}, nil
+	}
+
+	for _, a := range abis {
+		for _, e := range a.Errors {
+			if !bytes.Equal(e.ID[:4], data[:4]) {
+				continue
+			}
+			args, err := e.Inputs.Unpack(data[4:])
+			if err != nil {
+				return nil, errors.Wrapf(err, "unpack custom error:%v", e.Name)
+			}
+			return &RevertReason{Kind: RevertCustom, Name: e.Name, Args: args, Data: data}, nil
+		}
+	}
+	return &RevertReason{Kind: RevertRaw, Data: data}, nil
+}
+
+// RevertReason decodes the TX revert data.
+// It returns nil when the TX didn't revert.
+func (self *TxResult) RevertReason(abis ...*abi.ABI) (*RevertReason, error) {
+	if self.Revert == "" && self.Error == "" {
+		return nil, nil
+	}
+	return DecodeRevert(self.Revert, abis...)
+}
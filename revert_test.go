@@ -0,0 +1,59 @@
+// Copyright (c) The Cryptorium Authors.
+// Licensed under the MIT License.
+
+package flashbot
+
+import (
+	"math/big"
+	"strings"
+	"testing"
+
+	"github.com/cryptoriums/packages/testutil"
+	"github.com/ethereum/go-ethereum/accounts/abi"
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/common/hexutil"
+	"github.com/ethereum/go-ethereum/crypto"
+)
+
+func TestDecodeRevert(t *testing.T) {
+	strTyp, err := abi.NewType("string", "", nil)
+	testutil.Ok(t, err)
+	msg, err := abi.Arguments{{Type: strTyp}}.Pack("not enough balance")
+	testutil.Ok(t, err)
+	reason, err := DecodeRevert(hexutil.Encode(append(crypto.Keccak256([]byte("Error(string)"))[:4], msg...)))
+	testutil.Ok(t, err)
+	testutil.Equals(t, RevertError, reason.Kind)
+	testutil.Equals(t, "not enough balance", reason.String())
+
+	panicData := append(crypto.Keccak256([]byte("Panic(uint256)"))[:4], common.BigToHash(big.NewInt(0x11)).Bytes()...)
+	reason, err = DecodeRevert(hexutil.Encode(panicData))
+	testutil.Ok(t, err)
+	testutil.Equals(t, RevertPanic, reason.Kind)
+	testutil.Equals(t, "panic 0x11: arithmetic overflow or underflow", reason.String())
+
+	parsed, err := abi.JSON(strings.NewReader(`[{"type":"error","name":"TooLow","inputs":[{"name":"min","type":"uint256"},{"name":"to","type":"address"}]}]`))
+	testutil.Ok(t, err)
+	to := common.HexToAddress("0x01")
+	custom, err := parsed.Errors["TooLow"].Inputs.Pack(big.NewInt(5), to)
+	testutil.Ok(t, err)
+	customHex := hexutil.Encode(append(parsed.Errors["TooLow"].ID.Bytes()[:4], custom...))
+
+	reason, err = DecodeRevert(customHex, &parsed)
+	testutil.Ok(t, err)
+	testutil.Equals(t, RevertCustom, reason.Kind)
+	testutil.Equals(t, "TooLow(5, "+to.Hex()+")", reason.String())
+
+	// Without the ABI the data stays raw.
+	reason, err = DecodeRevert(customHex)
+	testutil.Ok(t, err)
+	testutil.Equals(t, RevertRaw, reason.Kind)
+	testutil.Equals(t, customHex, reason.String())
+
+	reason, err = (&TxResult{Error: "execution reverted", Revert: "already decoded"}).RevertReason()
+	testutil.Ok(t, err)
+	testutil.Equals(t, "already decoded", reason.Message)
+
+	reason, err = (&TxResult{}).RevertReason()
+	testutil.Ok(t, err)
+	testutil.Assert(t, reason == nil, "unexpected reason for a successful TX")
+}
@@ -0,0 +1,526 @@
+// Copyright (c) The Cryptorium Authors.
+// Licensed under the MIT License.
+
+package flashbot
+
+import (
+	"context"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/common/hexutil"
+	"github.com/pkg/errors"
+)
+
+const mevBundleVersion = "v0.1"
+
+// MevSendBundleParams is the MEV-Share bundle (sbundle) body of mev_sendBundle.
+type MevSendBundleParams struct {
+	Version   string          `json:"version"`
+	Inclusion Inclusion       `json:"inclusion"`
+	Body      []MevBundleItem `json:"body"`
+	Validity  *MevValidity    `json:"validity,omitempty"`
+	Privacy   *MevPrivacy     `json:"privacy,omitempty"`
+}
+
+// MevHint selects the bundle data shared with the searchers through the MEV-Share event stream.
+// Sharing more data makes backruns and so refunds more likely.
+type MevHint string
+
+const (
+	MevHintCalldata         MevHint = "calldata"
+	MevHintContractAddress  MevHint = "contract_address"
+	MevHintLogs             MevHint = "logs"
+	MevHintFunctionSelector MevHint = "function_selector"
+	MevHintHash             MevHint = "hash"
+	MevHintTxHash           MevHint = "tx_hash"
+)
+
+var knownMevHints = map[MevHint]bool{
+	MevHintCalldata:         true,
+	MevHintContractAddress:  true,
+	MevHintLogs:             true,
+	MevHintFunctionSelector: true,
+	MevHintHash:             true,
+	MevHintTxHash:           true,
+}
+
+type MevPrivacy struct {
+	Hints []MevHint `json:"hints,omitempty"`
+	// Builders that may receive the bundle, the MEV-Share default when empty.
+	Builders []string `json:"builders,omitempty"`
+}
+
+// MevPrivacyMax shares only the bundle hash so that it can be backrun blindly without leaking any of its data.
+func MevPrivacyMax(builders ...string) *MevPrivacy {
+	return &MevPrivacy{Hints: []MevHint{MevHintHash}, Builders: builders}
+}
+
+// MevPrivacyDefault shares the same data as the Protect default,
+// the called contracts, the function selectors and the logs.
+func MevPrivacyDefault(builders ...string) *MevPrivacy {
+	return &MevPrivacy{
+		Hints:    []MevHint{MevHintHash, MevHintContractAddress, MevHintFunctionSelector, MevHintLogs},
+		Builders: builders,
+	}
+}
+
+// MevPrivacyMaxRefund shares all data for the best refund potential.
+func MevPrivacyMaxRefund(builders ...string) *MevPrivacy {
+	return &MevPrivacy{
+		Hints: []MevHint{
+			MevHintHash,
+			MevHintTxHash,
+			MevHintCalldata,
+			MevHintContractAddress,
+			MevHintFunctionSelector,
+			MevHintLogs,
+		},
+		Builders: builders,
+	}
+}
+
+func (self *MevPrivacy) validate() error {
+	if self == nil {
+		return nil
+	}
+	for _, h := range self.Hints {
+		if !knownMevHints[h] {
+			return errors.Errorf("unknown MEV-Share hint:%v", h)
+		}
+	}
+	return nil
+}
+
+// MevBundleItem is either the hash of a pending TX from a hint, a signed TX or a nested bundle.
+type MevBundleItem struct {
+	Hash      *common.Hash         `json:"hash,omitempty"`
+	Tx        string               `json:"tx,omitempty"`
+	Bundle    *MevSendBundleParams `json:"bundle,omitempty"`
+	CanRevert bool                 `json:"canRevert,omitempty"`
+}
+
+func validateMevBody(body []MevBundleItem) error {
+	if len(body) == 0 {
+		return errors.New("bundle body is empty")
+	}
+	for i, item := range body {
+		var set int
+		if item.Hash != nil {
+			set++
+		}
+		if item.Tx != "" {
+			set++
+		}
+		if item.Bundle != nil {
+			set++
+		}
+		if set != 1 {
+			return errors.Errorf("body item index:%v must have exactly one of a hash, a TX or a bundle", i)
+		}
+		if item.Bundle != nil {
+			if err := validateMevBody(item.Bundle.Body); err != nil {
+				return errors.Wrapf(err, "nested bundle index:%v", i)
+			}
+			if err := item.Bundle.Validity.validate(len(item.Bundle.Body)); err != nil {
+				return errors.Wrapf(err, "nested bundle index:%v", i)
+			}
+		}
+	}
+	return nil
+}
+
+// SignedTxs returns the signed TXs of the bundle including the ones of the nested bundles.
+func (self *MevSendBundleParams) SignedTxs() []string {
+	return mevBodyTxs(self.Body)
+}
+
+// mevBodyTxs returns the signed TXs of the body including the ones of the nested bundles.
+func mevBodyTxs(body []MevBundleItem) []string {
+	var txsHex []string
+	for _, item := range body {
+		if item.Tx != "" {
+			txsHex = append(txsHex, item.Tx)
+		}
+		if item.Bundle != nil {
+			txsHex = append(txsHex, mevBodyTxs(item.Bundle.Body)...)
+		}
+	}
+	return txsHex
+}
+
+// blockRange returns the inclusion range of the bundle.
+func (self Inclusion) blockRange() (uint64, uint64, error) {
+	from, err := hexutil.DecodeUint64(self.Block)
+	if err != nil {
+		return 0, 0, errors.Wrapf(err, "decode inclusion block:%v", self.Block)
+	}
+	to := from
+	if self.MaxBlock != "" {
+		if to, err = hexutil.DecodeUint64(self.MaxBlock); err != nil {
+			return 0, 0, errors.Wrapf(err, "decode inclusion max block:%v", self.MaxBlock)
+		}
+	}
+	return from, to, nil
+}
+
+type MevValidity struct {
+	// Refund sets the share of the profit for the body items, i.e. the user TX being backrun.
+	Refund []MevRefund `json:"refund,omitempty"`
+	// RefundConfig splits the refund of this bundle between the addresses, the percents must add up to 100.
+	RefundConfig []MevRefundConfig `json:"refundConfig,omitempty"`
+}
+
+// validate checks that the refunds point to the body items and the percentages add up.
+// The refunds can't exceed 100% of the profit and the refund config split has to add up to exactly 100%.
+func (self *MevValidity) validate(bodyLen int) error {
+	if self == nil {
+		return nil
+	}
+	var total int
+	for _, r := range self.Refund {
+		if r.BodyIdx < 0 || r.BodyIdx >= bodyLen {
+			return errors.Errorf("refund body index:%v out of range body items count:%v", r.BodyIdx, bodyLen)
+		}
+		if r.Percent <= 0 || r.Percent > 100 {
+			return errors.Errorf("invalid refund percent:%v body index:%v", r.Percent, r.BodyIdx)
+		}
+		total += r.Percent
+	}
+	if total > 100 {
+		return errors.Errorf("total refund percent:%v exceeds 100", total)
+	}
+
+	if len(self.RefundConfig) == 0 {
+		return nil
+	}
+	total = 0
+	for i, r := range self.RefundConfig {
+		if (r.Address == common.Address{}) {
+			return errors.Errorf("empty refund config address index:%v", i)
+		}
+		if r.Percent <= 0 || r.Percent > 100 {
+			return errors.Errorf("invalid refund config percent:%v address:%v", r.Percent, r.Address.Hex())
+		}
+		total += r.Percent
+	}
+	if total != 100 {
+		return errors.Errorf("refund config percents add up to:%v instead of 100", total)
+	}
+	return nil
+}
+
+type MevRefund struct {
+	BodyIdx int `json:"bodyIdx"`
+	Percent int `json:"percent"`
+}
+
+type MevRefundConfig struct {
+	Address common.Address `json:"address"`
+	Percent int            `json:"percent"`
+}
+
+type MevSendBundleResponse struct {
+	Error  `json:"error,omitempty"`
+	Result struct {
+		BundleHash string `json:"bundleHash"`
+	} `json:"result"`
+}
+
+// MevSendBundle sends a MEV-Share bundle.
+// It runs the same checks as SendBundleParams, the target check is against the last block of the inclusion range
+// and the spam quota is taken for the first one.
+func (self *Flashbot) MevSendBundle(ctx context.Context, params MevSendBundleParams) (*MevSendBundleResponse, error) {
+	if params.Version == "" {
+		params.Version = mevBundleVersion
+	}
+	if err := params.Privacy.validate(); err != nil {
+		return nil, err
+	}
+	if err := validateMevBody(params.Body); err != nil {
+		return nil, err
+	}
+	if err := params.Validity.validate(len(params.Body)); err != nil {
+		return nil, err
+	}
+	blockNum, maxBlock, err := params.Inclusion.blockRange()
+	if err != nil {
+		return nil, err
+	}
+	// The bundle can still land while the head is before the last block of the range.
+	if err := self.checkBundle(ctx, "mev_sendBundle", params, mevBodyTxs(params.Body), maxBlock, 0, 0); err != nil {
+		return nil, err
+	}
+	if self.DryRun() {
+		return nil, self.dryRunGate(RequestSubmit, "mev_sendBundle", []interface{}{params})
+	}
+	release, err := self.reserveBudget(mevBodyTxs(params.Body))
+	if err != nil {
+		return nil, err
+	}
+	if err := self.takeSpamQuota(blockNum); err != nil {
+		release()
+		return nil, err
+	}
+	resp, err := self.reqKind(ctx, RequestSubmit, "mev_sendBundle", params)
+	if err != nil {
+		release()
+		return nil, errors.Wrap(err, "flashbot mev send bundle request")
+	}
+	rr := &MevSendBundleResponse{}
+	if err := self.codec().Unmarshal(resp, rr); err != nil {
+		release()
+		return nil, errors.Wrapf(err, "unmarshal flashbot response:%v", string(resp))
+	}
+	if rr.Error.Code != 0 {
+		release()
+		return nil, errors.Errorf("flashbot request returned an error:%+v block:%v", rr.Error, params.Inclusion.Block)
+	}
+	return rr, nil
+}
+
+type BackrunOpts struct {
+	// MaxBlocks is the number of blocks the bundle is valid for starting at the target block, one by default.
+	MaxBlocks uint64
+	// RefundPercent is the share of the profit refunded to the user TX.
+	RefundPercent int
+	RefundConfig  []MevRefundConfig
+	// Privacy of the backrun bundle, not shared when nil.
+	Privacy *MevPrivacy
+}
+
+// NewBackrunBundle assembles the sbundle that places the backrun TX right after the hinted TX.
+func NewBackrunBundle(hint MevShareEvent, backrunTxHex string, blockNum uint64, opts BackrunOpts) (MevSendBundleParams, error) {
+	if (hint.Hash == common.Hash{}) {
+		return MevSendBundleParams{}, errors.New("hint has no hash")
+	}
+	if backrunTxHex == "" {
+		return MevSendBundleParams{}, errors.New("backrun TX can't be empty")
+	}
+	if opts.RefundPercent < 0 || opts.RefundPercent > 100 {
+		return MevSendBundleParams{}, errors.Errorf("invalid refund percent:%v", opts.RefundPercent)
+	}
+	maxBlocks := opts.MaxBlocks
+	if maxBlocks == 0 {
+		maxBlocks = 1
+	}
+
+	hash := hint.Hash
+	params := MevSendBundleParams{
+		Version: mevBundleVersion,
+		Inclusion: Inclusion{
+			Block:    hexutil.EncodeUint64(blockNum),
+			MaxBlock: hexutil.EncodeUint64(blockNum + maxBlocks - 1),
+		},
+		Body:    []MevBundleItem{{Hash: &hash}, {Tx: backrunTxHex}},
+		Privacy: opts.Privacy,
+	}
+	if opts.RefundPercent > 0 || len(opts.RefundConfig) > 0 {
+		params.Validity = &MevValidity{RefundConfig: opts.RefundConfig}
+		if opts.RefundPercent > 0 {
+			params.Validity.Refund = []MevRefund{{BodyIdx: 0, Percent: opts.RefundPercent}}
+		}
+		if err := params.Validity.validate(len(params.Body)); err != nil {
+			return MevSendBundleParams{}, err
+		}
+	}
+	return params, nil
+}
+
+// MevBundleBuilder assembles a MEV-Share bundle mixing the hashes of pending TXs from hints with signed TXs.
+// The items are included in the order they are added.
+// A nested bundle limits the inclusion range of the outer one to the blocks in which both are valid.
+// The first error stops all further steps and is returned by Build.
+//
+//	params, err := flashbot.NewMevBundleBuilder().
+//		AddHash(event.Hash).
+//		Refund(0, 90).
+//		AddSignedTx(backrunTx).
+//		AddSignedTx(cleanupTx).
+//		CanRevert(2).
+//		TargetBlock(head+1, head+3).
+//		Build()
+type MevBundleBuilder struct {
+	body         []MevBundleItem
+	refunds      []MevRefund
+	refundConfig []MevRefundConfig
+	privacy      *MevPrivacy
+	blockNum     uint64
+	maxBlock     uint64
+	err          error
+}
+
+func NewMevBundleBuilder() *MevBundleBuilder {
+	return &MevBundleBuilder{}
+}
+
+// AddHash appends the pending TX with the hash, usually from a MEV-Share hint.
+func (self *MevBundleBuilder) AddHash(hash common.Hash) *MevBundleBuilder {
+	if self.err != nil {
+		return self
+	}
+	if (hash == common.Hash{}) {
+		self.err = errors.Errorf("empty hash body item index:%v", len(self.body))
+		return self
+	}
+	self.body = append(self.body, MevBundleItem{Hash: &hash})
+	return self
+}
+
+// AddSignedTx appends a hex encoded signed TX.
+func (self *MevBundleBuilder) AddSignedTx(txHex string) *MevBundleBuilder {
+	if self.err != nil {
+		return self
+	}
+	if _, err := hexutil.Decode(txHex); err != nil {
+		self.err = errors.Wrapf(err, "decode TX body item index:%v", len(self.body))
+		return self
+	}
+	self.body = append(self.body, MevBundleItem{Tx: txHex})
+	return self
+}
+
+// AddBundle appends a nested bundle, i.e. another sbundle to backrun.
+// The refunds and the inclusion range of the nested bundle stay in effect.
+func (self *MevBundleBuilder) AddBundle(bundle MevSendBundleParams) *MevBundleBuilder {
+	if self.err != nil {
+		return self
+	}
+	if bundle.Version == "" {
+		bundle.Version = mevBundleVersion
+	}
+	if err := validateMevBody(bundle.Body); err != nil {
+		self.err = errors.Wrapf(err, "nested bundle index:%v", len(self.body))
+		return self
+	}
+	if _, _, err := bundle.Inclusion.blockRange(); err != nil {
+		self.err = errors.Wrapf(err, "nested bundle index:%v", len(self.body))
+		return self
+	}
+	self.body = append(self.body, MevBundleItem{Bundle: &bundle})
+	return self
+}
+
+// CanRevert allows the body item at the index to revert without invalidating the bundle.
+func (self *MevBundleBuilder) CanRevert(i int) *MevBundleBuilder {
+	if self.err != nil {
+		return self
+	}
+	if i < 0 || i >= len(self.body) {
+		self.err = errors.Errorf("can revert index:%v out of range body items count:%v", i, len(self.body))
+		return self
+	}
+	self.body[i].CanRevert = true
+	return self
+}
+
+// Refund sets the share of the bundle profit refunded to the body item at the index.
+func (self *MevBundleBuilder) Refund(i int, percent int) *MevBundleBuilder {
+	if self.err != nil {
+		return self
+	}
+	if i < 0 || i >= len(self.body) {
+		self.err = errors.Errorf("refund index:%v out of range body items count:%v", i, len(self.body))
+		return self
+	}
+	if percent <= 0 || percent > 100 {
+		self.err = errors.Errorf("invalid refund percent:%v", percent)
+		return self
+	}
+	self.refunds = append(self.refunds, MevRefund{BodyIdx: i, Percent: percent})
+	return self
+}
+
+// RefundConfig splits the refund of this bundle between the addresses.
+func (self *MevBundleBuilder) RefundConfig(cfg ...MevRefundConfig) *MevBundleBuilder {
+	self.refundConfig = append(self.refundConfig, cfg...)
+	return self
+}
+
+func (self *MevBundleBuilder) Privacy(privacy *MevPrivacy) *MevBundleBuilder {
+	self.privacy = privacy
+	return self
+}
+
+// TargetBlock sets the block range in which the bundle is valid, the max block defaults to the target block.
+func (self *MevBundleBuilder) TargetBlock(blockNum uint64, maxBlock uint64) *MevBundleBuilder {
+	self.blockNum = blockNum
+	self.maxBlock = maxBlock
+	return self
+}
+
+func (self *MevBundleBuilder) Build() (MevSendBundleParams, error) {
+	if self.err != nil {
+		return MevSendBundleParams{}, self.err
+	}
+	if err := validateMevBody(self.body); err != nil {
+		return MevSendBundleParams{}, err
+	}
+	if len(mevBodyTxs(self.body)) == 0 {
+		return MevSendBundleParams{}, errors.New("bundle has no signed TXs")
+	}
+	blockNum, maxBlock, err := self.blockRange()
+	if err != nil {
+		return MevSendBundleParams{}, err
+	}
+	if err := self.privacy.validate(); err != nil {
+		return MevSendBundleParams{}, err
+	}
+
+	params := MevSendBundleParams{
+		Version: mevBundleVersion,
+		Inclusion: Inclusion{
+			Block:    hexutil.EncodeUint64(blockNum),
+			MaxBlock: hexutil.EncodeUint64(maxBlock),
+		},
+		Body:    append([]MevBundleItem(nil), self.body...),
+		Privacy: self.privacy,
+	}
+	if len(self.refunds) > 0 || len(self.refundConfig) > 0 {
+		params.Validity = &MevValidity{
+			Refund:       append([]MevRefund(nil), self.refunds...),
+			RefundConfig: append([]MevRefundConfig(nil), self.refundConfig...),
+		}
+		if err := params.Validity.validate(len(params.Body)); err != nil {
+			return MevSendBundleParams{}, err
+		}
+	}
+	return params, nil
+}
+
+// blockRange returns the inclusion range narrowed to the ranges of the nested bundles.
+// Without a target block the range of the nested bundles is used.
+func (self *MevBundleBuilder) blockRange() (uint64, uint64, error) {
+	from, to := self.blockNum, self.maxBlock
+	if to == 0 {
+		to = from
+	}
+	if from != 0 && to < from {
+		return 0, 0, errors.Errorf("bundle max block:%v is before the target block:%v", to, from)
+	}
+	for i, item := range self.body {
+		if item.Bundle == nil {
+			continue
+		}
+		nFrom, nTo, err := item.Bundle.Inclusion.blockRange()
+		if err != nil {
+			return 0, 0, errors.Wrapf(err, "nested bundle index:%v", i)
+		}
+		if from == 0 {
+			from, to = nFrom, nTo
+			continue
+		}
+		if nFrom > from {
+			from = nFrom
+		}
+		if nTo < to {
+			to = nTo
+		}
+		if to < from {
+			return 0, 0, errors.Errorf("nested bundle index:%v inclusion range %v-%v doesn't overlap with the bundle range", i, nFrom, nTo)
+		}
+	}
+	if from == 0 {
+		return 0, 0, errors.New("bundle target block is not set")
+	}
+	return from, to, nil
+}
@@ -0,0 +1,260 @@
+// Copyright (c) The Cryptorium Authors.
+// Licensed under the MIT License.
+
+package flashbot
+
+import (
+	"context"
+	"encoding/json"
+	"testing"
+
+	"github.com/cryptoriums/packages/testutil"
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/pkg/errors"
+)
+
+func TestBackrunBundle(t *testing.T) {
+	ctx := context.Background()
+	var sent []MevSendBundleParams
+	relay := newRelayMock(t, func(method string, params json.RawMessage) (interface{}, *jsonError) {
+		testutil.Equals(t, "mev_sendBundle", method)
+		testutil.Ok(t, json.Unmarshal(params, &sent))
+		return map[string]string{"bundleHash": "0xbundle"}, nil
+	})
+	fb := newTestFlashbot(t, relay.URL)
+
+	hint := MevShareEvent{Hash: common.HexToHash("0x01")}
+	refundTo := common.HexToAddress("0x02")
+	params, err := NewBackrunBundle(hint, "0xbeef", 10, BackrunOpts{
+		MaxBlocks:     3,
+		RefundPercent: 90,
+		RefundConfig:  []MevRefundConfig{{Address: refundTo, Percent: 100}},
+	})
+	testutil.Ok(t, err)
+
+	resp, err := fb.MevSendBundle(ctx, params)
+	testutil.Ok(t, err)
+	testutil.Equals(t, "0xbundle", resp.Result.BundleHash)
+
+	testutil.Equals(t, 1, len(sent))
+	testutil.Equals(t, "v0.1", sent[0].Version)
+	testutil.Equals(t, Inclusion{Block: "0xa", MaxBlock: "0xc"}, sent[0].Inclusion)
+	testutil.Equals(t, hint.Hash, *sent[0].Body[0].Hash)
+	testutil.Equals(t, "0xbeef", sent[0].Body[1].Tx)
+	testutil.Equals(t, []MevRefund{{BodyIdx: 0, Percent: 90}}, sent[0].Validity.Refund)
+	testutil.Equals(t, refundTo, sent[0].Validity.RefundConfig[0].Address)
+
+	_, err = NewBackrunBundle(MevShareEvent{}, "0xbeef", 10, BackrunOpts{})
+	testutil.NotOk(t, err)
+}
+
+func TestMevPrivacy(t *testing.T) {
+	var sent []json.RawMessage
+	relay := newRelayMock(t, func(method string, params json.RawMessage) (interface{}, *jsonError) {
+		testutil.Ok(t, json.Unmarshal(params, &sent))
+		return map[string]string{"bundleHash": "0xbundle"}, nil
+	})
+	fb := newTestFlashbot(t, relay.URL)
+
+	params, err := NewBackrunBundle(MevShareEvent{Hash: common.HexToHash("0x01")}, "0xbeef", 10, BackrunOpts{
+		Privacy: MevPrivacyMaxRefund("flashbots", "titan"),
+	})
+	testutil.Ok(t, err)
+	_, err = fb.MevSendBundle(context.Background(), params)
+	testutil.Ok(t, err)
+
+	var got struct {
+		Privacy struct {
+			Hints    []string `json:"hints"`
+			Builders []string `json:"builders"`
+		} `json:"privacy"`
+	}
+	testutil.Ok(t, json.Unmarshal(sent[0], &got))
+	testutil.Equals(t, []string{"hash", "tx_hash", "calldata", "contract_address", "function_selector", "logs"}, got.Privacy.Hints)
+	testutil.Equals(t, []string{"flashbots", "titan"}, got.Privacy.Builders)
+
+	testutil.Equals(t, []MevHint{MevHintHash}, MevPrivacyMax().Hints)
+	testutil.Equals(t, 4, len(MevPrivacyDefault().Hints))
+
+	// Without privacy the field isn't sent at all.
+	params.Privacy = nil
+	_, err = fb.MevSendBundle(context.Background(), params)
+	testutil.Ok(t, err)
+	var raw map[string]json.RawMessage
+	testutil.Ok(t, json.Unmarshal(sent[0], &raw))
+	_, ok := raw["privacy"]
+	testutil.Assert(t, !ok, "unexpected privacy field")
+
+	params.Privacy = &MevPrivacy{Hints: []MevHint{"everything"}}
+	_, err = fb.MevSendBundle(context.Background(), params)
+	testutil.NotOk(t, err)
+}
+
+func TestMevBundleBuilder(t *testing.T) {
+	userA := common.HexToHash("0x0a")
+	userB := common.HexToHash("0x0b")
+
+	params, err := NewMevBundleBuilder().
+		AddHash(userA).
+		AddSignedTx("0xbeef").
+		AddHash(userB).
+		AddSignedTx("0xcafe").
+		CanRevert(3).
+		Refund(0, 40).
+		Refund(2, 50).
+		TargetBlock(10, 12).
+		Build()
+	testutil.Ok(t, err)
+	testutil.Equals(t, 4, len(params.Body))
+	testutil.Equals(t, userA, *params.Body[0].Hash)
+	testutil.Equals(t, "0xbeef", params.Body[1].Tx)
+	testutil.Equals(t, userB, *params.Body[2].Hash)
+	testutil.Equals(t, "0xcafe", params.Body[3].Tx)
+	testutil.Assert(t, params.Body[3].CanRevert && !params.Body[1].CanRevert, "unexpected can revert flags")
+	testutil.Equals(t, []MevRefund{{BodyIdx: 0, Percent: 40}, {BodyIdx: 2, Percent: 50}}, params.Validity.Refund)
+	testutil.Equals(t, Inclusion{Block: "0xa", MaxBlock: "0xc"}, params.Inclusion)
+
+	raw, err := json.Marshal(params.Body)
+	testutil.Ok(t, err)
+	testutil.Equals(t, `[{"hash":"`+userA.Hex()+`"},{"tx":"0xbeef"},{"hash":"`+userB.Hex()+`"},{"tx":"0xcafe","canRevert":true}]`, string(raw))
+
+	_, err = NewMevBundleBuilder().AddHash(userA).TargetBlock(10, 0).Build()
+	testutil.NotOk(t, err, "bundle without signed TXs")
+	_, err = NewMevBundleBuilder().AddSignedTx("0xbeef").CanRevert(1).TargetBlock(10, 0).Build()
+	testutil.NotOk(t, err, "can revert index out of range")
+	_, err = NewMevBundleBuilder().AddHash(userA).AddSignedTx("0xbeef").Refund(0, 60).Refund(0, 50).TargetBlock(10, 0).Build()
+	testutil.NotOk(t, err, "refunds over 100%")
+	_, err = NewMevBundleBuilder().AddSignedTx("beef").TargetBlock(10, 0).Build()
+	testutil.NotOk(t, err, "invalid hex")
+	_, err = NewMevBundleBuilder().AddSignedTx("0xbeef").TargetBlock(10, 9).Build()
+	testutil.NotOk(t, err, "max block before target")
+
+	relay := newRelayMock(t, func(method string, params json.RawMessage) (interface{}, *jsonError) {
+		return map[string]string{"bundleHash": "0xbundle"}, nil
+	})
+	fb := newTestFlashbot(t, relay.URL)
+	_, err = fb.MevSendBundle(context.Background(), MevSendBundleParams{Body: []MevBundleItem{{Hash: &userA, Tx: "0xbeef"}}})
+	testutil.NotOk(t, err, "item with both a hash and a TX")
+}
+
+func TestMevNestedBundle(t *testing.T) {
+	user := common.HexToHash("0x0a")
+	inner, err := NewMevBundleBuilder().
+		AddHash(user).
+		Refund(0, 80).
+		AddSignedTx("0xbeef").
+		TargetBlock(10, 14).
+		Build()
+	testutil.Ok(t, err)
+
+	// The target block is inherited from the nested bundle.
+	outer, err := NewMevBundleBuilder().AddBundle(inner).AddSignedTx("0xcafe").Build()
+	testutil.Ok(t, err)
+	testutil.Equals(t, inner.Inclusion, outer.Inclusion)
+	testutil.Equals(t, inner.Validity.Refund, outer.Body[0].Bundle.Validity.Refund)
+
+	// The range is narrowed to the blocks in which both are valid.
+	outer, err = NewMevBundleBuilder().AddBundle(inner).AddSignedTx("0xcafe").TargetBlock(12, 20).Build()
+	testutil.Ok(t, err)
+	testutil.Equals(t, Inclusion{Block: "0xc", MaxBlock: "0xe"}, outer.Inclusion)
+
+	_, err = NewMevBundleBuilder().AddBundle(inner).AddSignedTx("0xcafe").TargetBlock(15, 20).Build()
+	testutil.NotOk(t, err, "ranges don't overlap")
+	_, err = NewMevBundleBuilder().AddBundle(MevSendBundleParams{Inclusion: inner.Inclusion}).Build()
+	testutil.NotOk(t, err, "empty nested bundle")
+
+	var sent []json.RawMessage
+	relay := newRelayMock(t, func(method string, params json.RawMessage) (interface{}, *jsonError) {
+		testutil.Ok(t, json.Unmarshal(params, &sent))
+		return map[string]string{"bundleHash": "0xbundle"}, nil
+	})
+	fb := newTestFlashbot(t, relay.URL)
+	outer, err = NewMevBundleBuilder().AddBundle(inner).AddSignedTx("0xcafe").Build()
+	testutil.Ok(t, err)
+	_, err = fb.MevSendBundle(context.Background(), outer)
+	testutil.Ok(t, err)
+
+	var got MevSendBundleParams
+	testutil.Ok(t, json.Unmarshal(sent[0], &got))
+	testutil.Equals(t, "v0.1", got.Body[0].Bundle.Version)
+	testutil.Equals(t, user, *got.Body[0].Bundle.Body[0].Hash)
+	testutil.Equals(t, "0xbeef", got.Body[0].Bundle.Body[1].Tx)
+	testutil.Equals(t, "0xcafe", got.Body[1].Tx)
+	testutil.Equals(t, []string{"0xbeef", "0xcafe"}, mevBodyTxs(outer.Body))
+}
+
+func TestMevValidity(t *testing.T) {
+	a := common.HexToAddress("0x0a")
+	b := common.HexToAddress("0x0b")
+
+	for name, tc := range map[string]struct {
+		validity *MevValidity
+		valid    bool
+	}{
+		"nil":          {nil, true},
+		"refund":       {&MevValidity{Refund: []MevRefund{{BodyIdx: 0, Percent: 90}}}, true},
+		"split":        {&MevValidity{RefundConfig: []MevRefundConfig{{Address: a, Percent: 70}, {Address: b, Percent: 30}}}, true},
+		"index":        {&MevValidity{Refund: []MevRefund{{BodyIdx: 2, Percent: 10}}}, false},
+		"over 100":     {&MevValidity{Refund: []MevRefund{{BodyIdx: 0, Percent: 60}, {BodyIdx: 1, Percent: 50}}}, false},
+		"zero percent": {&MevValidity{Refund: []MevRefund{{BodyIdx: 0, Percent: 0}}}, false},
+		"split sum":    {&MevValidity{RefundConfig: []MevRefundConfig{{Address: a, Percent: 70}, {Address: b, Percent: 20}}}, false},
+		"no address":   {&MevValidity{RefundConfig: []MevRefundConfig{{Percent: 100}}}, false},
+	} {
+		err := tc.validity.validate(2)
+		testutil.Assert(t, (err == nil) == tc.valid, "case:%v err:%v", name, err)
+	}
+
+	_, err := NewBackrunBundle(MevShareEvent{Hash: common.HexToHash("0x01")}, "0xbeef", 10, BackrunOpts{
+		RefundPercent: 50,
+		RefundConfig:  []MevRefundConfig{{Address: a, Percent: 50}},
+	})
+	testutil.NotOk(t, err, "refund config split under 100")
+
+	user := common.HexToHash("0x01")
+	relay := newRelayMock(t, func(method string, params json.RawMessage) (interface{}, *jsonError) {
+		return map[string]string{"bundleHash": "0xbundle"}, nil
+	})
+	fb := newTestFlashbot(t, relay.URL)
+	_, err = fb.MevSendBundle(context.Background(), MevSendBundleParams{
+		Body:     []MevBundleItem{{Hash: &user}, {Tx: "0xbeef"}},
+		Validity: &MevValidity{Refund: []MevRefund{{BodyIdx: 5, Percent: 10}}},
+	})
+	testutil.NotOk(t, err)
+}
+
+func TestMevSendBundleGuards(t *testing.T) {
+	ctx := context.Background()
+	relay := newRelayMock(t, func(method string, params json.RawMessage) (interface{}, *jsonError) {
+		return map[string]string{"bundleHash": "0xbundle"}, nil
+	})
+	fb := newTestFlashbot(t, relay.URL)
+	params := MevSendBundleParams{
+		Inclusion: Inclusion{Block: "0xa"},
+		Body:      []MevBundleItem{{Tx: "0x01"}, {Tx: "0x02"}},
+	}
+
+	fb.SetDryRun(true)
+	_, err := fb.MevSendBundle(ctx, params)
+	testutil.Assert(t, errors.Is(err, ErrDryRun), "dry run not enforced:%v", err)
+	recs := fb.DryRunRecords()
+	testutil.Equals(t, 1, len(recs))
+	testutil.Equals(t, "mev_sendBundle", recs[0].Method)
+	fb.SetDryRun(false)
+
+	fb.api.Limits = RelayLimits{MaxTxs: 1}
+	_, err = fb.MevSendBundle(ctx, params)
+	var verr *ValidationError
+	testutil.Assert(t, errors.As(err, &verr), "unexpected error type:%v", err)
+	testutil.Assert(t, verr.Has(ViolationTxCount), "missing tx count violation")
+	fb.api.Limits = RelayLimits{}
+
+	guard, err := NewSpamGuard(1)
+	testutil.Ok(t, err)
+	fb.SetSpamGuard(guard)
+	_, err = fb.MevSendBundle(ctx, params)
+	testutil.Ok(t, err)
+	_, err = fb.MevSendBundle(ctx, params)
+	testutil.Assert(t, errors.Is(err, ErrSpamCapReached), "spam cap not enforced:%v", err)
+
+	testutil.Equals(t, []string{"mev_sendBundle"}, relay.Methods())
+}
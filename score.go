@@ -0,0 +1,56 @@
+// Copyright (c) The Cryptorium Authors.
+// Licensed under the MIT License.
+
+package flashbot
+
+import (
+	"math/big"
+	"strings"
+
+	"github.com/pkg/errors"
+)
+
+// ScoreBundle calculates the bundle score used by the Flashbots builders from the simulation result:
+// the total miner payment minus the gas fees of the TXs that are visible in the mempool divided by the gas used.
+// The mempool TXs are given by hash since the miner would get their fees without the bundle.
+func ScoreBundle(result *Result, mempoolTxs ...string) (*big.Int, error) {
+	if result == nil {
+		return nil, errors.New("empty simulation result")
+	}
+	mempool := make(map[string]bool, len(mempoolTxs))
+	for _, h := range mempoolTxs {
+		mempool[strings.ToLower(h)] = true
+	}
+
+	payment, err := parseWei("CoinbaseDiff", result.CoinbaseDiff)
+	if err != nil {
+		return nil, err
+	}
+	var gasUsed uint64
+	for i, r := range result.Results {
+		gasUsed += r.GasUsed
+		if !mempool[strings.ToLower(r.TxHash)] {
+			continue
+		}
+		fees, err := parseWei("GasFees", r.GasFees)
+		if err != nil {
+			return nil, errors.Wrapf(err, "TX index:%v", i)
+		}
+		payment.Sub(payment, fees)
+	}
+	if gasUsed == 0 {
+		return nil, errors.New("simulation result has no gas used")
+	}
+	return payment.Div(payment, new(big.Int).SetUint64(gasUsed)), nil
+}
+
+func parseWei(name, val string) (*big.Int, error) {
+	v := new(big.Int)
+	if val == "" {
+		return v, nil
+	}
+	if _, ok := v.SetString(val, 10); !ok {
+		return nil, errors.Errorf("parse %v:%v", name, val)
+	}
+	return v, nil
+}
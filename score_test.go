@@ -0,0 +1,33 @@
+// Copyright (c) The Cryptorium Authors.
+// Licensed under the MIT License.
+
+package flashbot
+
+import (
+	"math/big"
+	"testing"
+
+	"github.com/cryptoriums/packages/testutil"
+)
+
+func TestScoreBundle(t *testing.T) {
+	result := &Result{
+		Metadata: Metadata{CoinbaseDiff: "3000000"},
+		Results: []TxResult{
+			{TxHash: "0xAA", GasUsed: 20_000, Metadata: Metadata{GasFees: "1000000"}},
+			{TxHash: "0xbb", GasUsed: 10_000, Metadata: Metadata{GasFees: "500000"}},
+		},
+	}
+
+	score, err := ScoreBundle(result)
+	testutil.Ok(t, err)
+	testutil.Equals(t, big.NewInt(100), score)
+
+	// The fees of the mempool TX don't count.
+	score, err = ScoreBundle(result, "0xaa")
+	testutil.Ok(t, err)
+	testutil.Equals(t, big.NewInt(66), score)
+
+	_, err = ScoreBundle(&Result{})
+	testutil.NotOk(t, err)
+}
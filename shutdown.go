@@ -0,0 +1,123 @@
+// Copyright (c) The Cryptorium Authors.
+// Licensed under the MIT License.
+
+package flashbot
+
+import (
+	"context"
+	"sync"
+
+	"github.com/pkg/errors"
+)
+
+// ErrClosed is returned for the new requests after Close.
+var ErrClosed = errors.New("closed")
+
+// contextCloser is implemented by the clients that can drain their in-flight requests.
+type contextCloser interface {
+	Close(ctx context.Context) error
+}
+
+// drain tracks the in-flight work so that Close can wait for it.
+// The zero value accepts work.
+type drain struct {
+	mtx     sync.Mutex
+	closed  bool
+	pending int
+	// idle is closed when the last pending work is done after close.
+	idle chan struct{}
+}
+
+// enter registers new work and returns false after close.
+func (self *drain) enter() bool {
+	self.mtx.Lock()
+	defer self.mtx.Unlock()
+	if self.closed {
+		return false
+	}
+	self.pending++
+	return true
+}
+
+func (self *drain) leave() {
+	self.mtx.Lock()
+	defer self.mtx.Unlock()
+	self.pending--
+	if self.pending == 0 && self.idle != nil {
+		close(self.idle)
+		self.idle = nil
+	}
+}
+
+func (self *drain) isClosed() bool {
+	self.mtx.Lock()
+	defer self.mtx.Unlock()
+	return self.closed
+}
+
+// close stops accepting new work and waits for the pending work until the context is done.
+func (self *drain) close(ctx context.Context) error {
+	self.mtx.Lock()
+	self.closed = true
+	if self.pending == 0 {
+		self.mtx.Unlock()
+		return nil
+	}
+	if self.idle == nil {
+		self.idle = make(chan struct{})
+	}
+	idle := self.idle
+	pending := self.pending
+	self.mtx.Unlock()
+
+	select {
+	case <-idle:
+		return nil
+	case <-ctx.Done():
+		return errors.Wrapf(ctx.Err(), "waiting for in-flight requests count:%v", pending)
+	}
+}
+
+// Close stops accepting new relay requests, waits for the in-flight ones until the context is done
+// and closes the idle connections. The requests after Close fail with ErrClosed.
+func (self *Flashbot) Close(ctx context.Context) error {
+	err := self.drain.close(ctx)
+	self.CloseIdleConnections()
+	return err
+}
+
+// Close stops accepting new candidates, waits for the running flushes until the context is done
+// and then closes the relays.
+func (self *BundleQueue) Close(ctx context.Context) error {
+	err := self.drain.close(ctx)
+	for _, r := range self.relays {
+		if c, ok := r.(contextCloser); ok {
+			if errC := c.Close(ctx); errC != nil && err == nil {
+				err = errors.Wrapf(errC, "close relay:%v", r.Api().URL)
+			}
+		}
+	}
+	return err
+}
+
+// Close stops accepting new bundles and submissions, waits for the running ones until the context is done,
+// closes the relay client and at the end the store so that all bundle changes are persisted.
+// The store is closed even when the deadline is reached so the saved changes aren't lost.
+func (self *BundleManager) Close(ctx context.Context) error {
+	err := self.drain.close(ctx)
+	if c, ok := self.client().(contextCloser); ok {
+		if errC := c.Close(ctx); errC != nil && err == nil {
+			err = errors.Wrap(errC, "close relay client")
+		}
+	}
+
+	self.mtx.RLock()
+	store := self.store
+	self.mtx.RUnlock()
+	if store != nil {
+		if errS := store.Close(); errS != nil && err == nil {
+			err = errors.Wrap(errS, "close bundle store")
+		}
+	}
+	return err
+}
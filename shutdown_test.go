@@ -0,0 +1,145 @@
+// Copyright (c) The Cryptorium Authors.
+// Licensed under the MIT License.
+
+package flashbot
+
+import (
+	"context"
+	"encoding/json"
+	"math/big"
+	"testing"
+	"time"
+
+	"github.com/cryptoriums/packages/testutil"
+	"github.com/pkg/errors"
+)
+
+func TestFlashbotClose(t *testing.T) {
+	started := make(chan struct{}, 1)
+	release := make(chan struct{})
+	relay := newRelayMock(t, func(method string, params json.RawMessage) (interface{}, *jsonError) {
+		started <- struct{}{}
+		<-release
+		return Result{BundleHash: "0xbundle"}, nil
+	})
+	fb := newTestFlashbot(t, relay.URL)
+
+	sent := make(chan error, 1)
+	go func() {
+		_, err := fb.SendBundle(context.Background(), []string{"0x01"}, 10)
+		sent <- err
+	}()
+	<-started
+
+	closed := make(chan error, 1)
+	go func() { closed <- fb.Close(context.Background()) }()
+	// Wait until the close started so that the new requests are rejected.
+	for !fb.drain.isClosed() {
+		time.Sleep(time.Millisecond)
+	}
+	_, err := fb.SendBundle(context.Background(), []string{"0x01"}, 11)
+	testutil.Assert(t, errors.Is(err, ErrClosed), "unexpected error:%v", err)
+	testutil.NotOk(t, fb.Warm(context.Background()))
+
+	select {
+	case err := <-closed:
+		t.Fatalf("close returned before the in-flight request finished err:%v", err)
+	case <-time.After(20 * time.Millisecond):
+	}
+	close(release)
+	testutil.Ok(t, <-sent)
+	testutil.Ok(t, <-closed)
+	testutil.Equals(t, []string{"eth_sendBundle"}, relay.Methods())
+}
+
+func TestFlashbotCloseDeadline(t *testing.T) {
+	started := make(chan struct{}, 1)
+	release := make(chan struct{})
+	defer close(release)
+	relay := newRelayMock(t, func(method string, params json.RawMessage) (interface{}, *jsonError) {
+		started <- struct{}{}
+		<-release
+		return Result{BundleHash: "0xbundle"}, nil
+	})
+	fb := newTestFlashbot(t, relay.URL)
+
+	go func() { _, _ = fb.SendBundle(context.Background(), []string{"0x01"}, 10) }()
+	<-started
+
+	ctx, cncl := context.WithTimeout(context.Background(), 20*time.Millisecond)
+	defer cncl()
+	err := fb.Close(ctx)
+	testutil.Assert(t, errors.Is(err, context.DeadlineExceeded), "unexpected error:%v", err)
+}
+
+type closeStore struct {
+	BundleStore
+	closed bool
+}
+
+func (self *closeStore) Close() error {
+	self.closed = true
+	return self.BundleStore.Close()
+}
+
+func TestBundleManagerClose(t *testing.T) {
+	ctx := context.Background()
+	relay := newRelayMock(t, func(method string, params json.RawMessage) (interface{}, *jsonError) {
+		return Result{BundleHash: "0xbundle"}, nil
+	})
+	fb := newTestFlashbot(t, relay.URL)
+
+	to := fb.TxSigner().Address()
+	params, err := fb.NewBundleBuilder(ctx, 5).
+		Nonce(0).
+		AddTx(TxSpec{To: &to, Gas: 21_000, GasFeeCap: big.NewInt(1)}).
+		TargetBlock(10).
+		Build()
+	testutil.Ok(t, err)
+
+	store := &closeStore{BundleStore: NewMemoryStore()}
+	m := NewBundleManager(fb)
+	m.SetStore(store)
+	id, err := m.Add(params)
+	testutil.Ok(t, err)
+	_, err = m.Submit(ctx, id)
+	testutil.Ok(t, err)
+
+	testutil.Ok(t, m.Close(ctx))
+	testutil.Assert(t, store.closed, "store not closed")
+
+	params.ReplacementUUID = ""
+	_, err = m.Add(params)
+	testutil.Assert(t, errors.Is(err, ErrClosed), "unexpected error:%v", err)
+	_, err = m.Submit(ctx, id)
+	testutil.Assert(t, errors.Is(err, ErrClosed), "unexpected error:%v", err)
+	// The relay client is closed with the manager.
+	_, err = fb.SendBundle(ctx, params.Txs, 11)
+	testutil.Assert(t, errors.Is(err, ErrClosed), "unexpected error:%v", err)
+
+	b, err := m.Get(id)
+	testutil.Ok(t, err)
+	testutil.Equals(t, BundlePending, b.State)
+}
+
+func TestBundleQueueClose(t *testing.T) {
+	relay := newRelayMock(t, func(method string, params json.RawMessage) (interface{}, *jsonError) {
+		return Result{BundleHash: "0xbundle"}, nil
+	})
+	fb := newTestFlashbot(t, relay.URL)
+	q, err := NewBundleQueue(1, fb)
+	testutil.Ok(t, err)
+
+	q.AddScored(&Candidate{Txs: []string{"0x01"}, BlockNum: 10, Score: big.NewInt(1)})
+	testutil.Ok(t, q.Close(context.Background()))
+
+	q.AddScored(&Candidate{Txs: []string{"0x02"}, BlockNum: 10, Score: big.NewInt(2)})
+	testutil.Equals(t, 1, q.Len(10))
+	_, err = q.Add(context.Background(), []string{"0x03"}, 10)
+	testutil.Assert(t, errors.Is(err, ErrClosed), "unexpected error:%v", err)
+
+	res := q.Flush(context.Background(), 10)
+	testutil.Equals(t, 1, len(res))
+	testutil.Equals(t, ErrClosed, res[0].Err)
+	testutil.Equals(t, 0, len(relay.Methods()))
+}
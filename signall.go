@@ -0,0 +1,105 @@
+// Copyright (c) The Cryptorium Authors.
+// Licensed under the MIT License.
+
+package flashbot
+
+import (
+	"context"
+	"math/big"
+	"runtime"
+	"sync"
+
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/pkg/errors"
+)
+
+// SignJob is a TX spec to sign with SignAll.
+type SignJob struct {
+	Nonce uint64
+	Spec  TxSpec
+}
+
+// SignAll builds and signs the jobs concurrently on the given number of workers, GOMAXPROCS when zero.
+// The output keeps the order of the jobs and the first failed job by index cancels the rest.
+// The options are applied concurrently so they need to be safe for concurrent use.
+func SignAll(ctx context.Context, signer Signer, netID int64, jobs []SignJob, workers int, opts ...TxOption) ([]string, []*types.Transaction, error) {
+	return signAll(ctx, signer, big.NewInt(netID), nil, jobs, workers, opts...)
+}
+
+// SignAll signs the jobs concurrently with the TX signer.
+func (self *Flashbot) SignAll(ctx context.Context, netID int64, jobs []SignJob, workers int, opts ...TxOption) ([]string, []*types.Transaction, error) {
+	chainID, chainSigner, err := self.ChainSigner(netID)
+	if err != nil {
+		return nil, nil, err
+	}
+	return signAll(ctx, self.TxSigner(), chainID, chainSigner, jobs, workers, opts...)
+}
+
+func signAll(ctx context.Context, signer Signer, chainID *big.Int, chainSigner types.Signer, jobs []SignJob, workers int, opts ...TxOption) ([]string, []*types.Transaction, error) {
+	if signer == nil {
+		return nil, nil, errors.New("private key or signer is not set")
+	}
+	if workers <= 0 {
+		workers = runtime.GOMAXPROCS(0)
+	}
+	if workers > len(jobs) {
+		workers = len(jobs)
+	}
+
+	txsHex := make([]string, len(jobs))
+	txs := make([]*types.Transaction, len(jobs))
+	errs := make([]error, len(jobs))
+
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+
+	next := make(chan int)
+	var wg sync.WaitGroup
+	for w := 0; w < workers; w++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for i := range next {
+				if ctx.Err() != nil {
+					errs[i] = ctx.Err()
+					continue
+				}
+				txsHex[i], txs[i], errs[i] = buildTx(ctx, signer, chainID, chainSigner, jobs[i].Nonce, jobs[i].Spec, opts...)
+				if errs[i] != nil {
+					cancel()
+				}
+			}
+		}()
+	}
+	for i := range jobs {
+		next <- i
+	}
+	close(next)
+	wg.Wait()
+
+	// The failed job is reported rather than the jobs canceled because of it.
+	var firstErr error
+	for i, err := range errs {
+		if err == nil {
+			continue
+		}
+		if !errors.Is(err, context.Canceled) {
+			return nil, nil, errors.Wrapf(err, "build TX index:%v", i)
+		}
+		if firstErr == nil {
+			firstErr = errors.Wrapf(err, "build TX index:%v", i)
+		}
+	}
+	if firstErr != nil {
+		return nil, nil, firstErr
+	}
+	return txsHex, txs, nil
+}
+
+func sequentialJobs(nonce uint64, specs []TxSpec) []SignJob {
+	jobs := make([]SignJob, len(specs))
+	for i, spec := range specs {
+		jobs[i] = SignJob{Nonce: nonce + uint64(i), Spec: spec}
+	}
+	return jobs
+}
@@ -0,0 +1,90 @@
+// Copyright (c) The Cryptorium Authors.
+// Licensed under the MIT License.
+
+package flashbot
+
+import (
+	"context"
+	"math/big"
+	"testing"
+
+	"github.com/cryptoriums/packages/testutil"
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/crypto"
+	"github.com/pkg/errors"
+)
+
+func TestSignAll(t *testing.T) {
+	ctx := context.Background()
+	prvKey, err := crypto.GenerateKey()
+	testutil.Ok(t, err)
+	signer, err := NewKeySigner(prvKey)
+	testutil.Ok(t, err)
+
+	to := common.HexToAddress("0x01")
+	var jobs []SignJob
+	for i := 0; i < 50; i++ {
+		jobs = append(jobs, SignJob{Nonce: uint64(100 - i), Spec: TxSpec{To: &to, Gas: 21_000, GasFeeCap: big.NewInt(int64(1e9 + i))}})
+	}
+	txsHex, txs, err := SignAll(ctx, signer, 1, jobs, 8)
+	testutil.Ok(t, err)
+	testutil.Equals(t, len(jobs), len(txsHex))
+	for i, tx := range txs {
+		// The order of the jobs is kept.
+		testutil.Equals(t, jobs[i].Nonce, tx.Nonce())
+		testutil.Equals(t, jobs[i].Spec.GasFeeCap, tx.GasFeeCap())
+		decoded, err := DecodeTx(txsHex[i])
+		testutil.Ok(t, err)
+		testutil.Equals(t, tx.Hash(), decoded.Hash())
+	}
+
+	// The failed job is reported and not the ones canceled because of it.
+	failAt := func(ctx context.Context, from common.Address, spec *TxSpec) error {
+		if spec.GasFeeCap.Int64() == 1e9+30 {
+			return errors.New("boom")
+		}
+		return nil
+	}
+	_, _, err = SignAll(ctx, signer, 1, jobs, 4, failAt)
+	testutil.NotOk(t, err)
+	testutil.Assert(t, errors.Cause(err).Error() == "boom", "unexpected error:%v", err)
+
+	// The builder signs the specs with sequential nonces.
+	params, err := NewBundleBuilder(ctx, signer, 1).
+		Nonce(7).
+		AddTxs(jobs[0].Spec, jobs[1].Spec, jobs[2].Spec).
+		AddTx(jobs[3].Spec).
+		TargetBlock(10).
+		Build()
+	testutil.Ok(t, err)
+	for i, txHex := range params.Txs {
+		tx, err := DecodeTx(txHex)
+		testutil.Ok(t, err)
+		testutil.Equals(t, uint64(7+i), tx.Nonce())
+	}
+}
+
+func BenchmarkSignAll(b *testing.B) {
+	prvKey, err := crypto.GenerateKey()
+	testutil.Ok(b, err)
+	signer, err := NewKeySigner(prvKey)
+	testutil.Ok(b, err)
+	to := common.HexToAddress("0x01")
+	specs := make([]TxSpec, 100)
+	for i := range specs {
+		specs[i] = TxSpec{To: &to, Gas: 21_000, GasFeeCap: big.NewInt(1e9)}
+	}
+	for _, workers := range []int{1, 0} {
+		name := "sequential"
+		if workers == 0 {
+			name = "parallel"
+		}
+		b.Run(name, func(b *testing.B) {
+			for i := 0; i < b.N; i++ {
+				if _, _, err := SignAll(context.Background(), signer, 1, sequentialJobs(0, specs), workers); err != nil {
+					b.Fatal(err)
+				}
+			}
+		})
+	}
+}
@@ -0,0 +1,259 @@
+// Copyright (c) The Cryptorium Authors.
+// Licensed under the MIT License.
+
+package flashbot
+
+import (
+	"context"
+	"crypto/ecdsa"
+	"math/big"
+	"strings"
+	"sync"
+
+	"github.com/ethereum/go-ethereum/accounts"
+	"github.com/ethereum/go-ethereum/accounts/external"
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/common/hexutil"
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/crypto"
+	"github.com/ethereum/go-ethereum/rpc"
+	"github.com/pkg/errors"
+)
+
+// Signer signs the relay request payloads and the bundle transactions
+// so that the keys don't need to live in the process memory.
+type Signer interface {
+	Address() common.Address
+	// SignText signs the EIP-191 personal message hash of the text
+	// and returns the signature in the [R || S || V] format with V being 0 or 1.
+	SignText(text []byte) ([]byte, error)
+	SignTx(tx *types.Transaction, chainID *big.Int) (*types.Transaction, error)
+}
+
+// chainSignerTx is implemented by the signers that can sign with a given TX signing scheme
+// instead of the latest one for the chain ID.
+type chainSignerTx interface {
+	SignTxWith(tx *types.Transaction, chainSigner types.Signer) (*types.Transaction, error)
+}
+
+// ErrChainSignerUnsupported is returned by the remote signers which sign only with the latest
+// TX signing scheme for the chain ID when another one is set with WithChainSigner.
+var ErrChainSignerUnsupported = errors.New("signer supports only the latest TX signing scheme")
+
+// signTxLatest signs with the signer when the chain signer is the latest one for its chain ID.
+func signTxLatest(signer Signer, tx *types.Transaction, chainSigner types.Signer) (*types.Transaction, error) {
+	if !chainSigner.Equal(types.LatestSignerForChainID(chainSigner.ChainID())) {
+		return nil, errors.Wrapf(ErrChainSignerUnsupported, "chain ID:%v", chainSigner.ChainID())
+	}
+	return signer.SignTx(tx, chainSigner.ChainID())
+}
+
+type keySigner struct {
+	prvKey *ecdsa.PrivateKey
+	addr   common.Address
+
+	mtx sync.Mutex
+	// latest is the signing scheme of the last chain ID.
+	latest types.Signer
+}
+
+// NewKeySigner returns a signer that uses a private key loaded in memory.
+func NewKeySigner(prvKey *ecdsa.PrivateKey) (Signer, error) {
+	if prvKey == nil {
+		return nil, errors.New("private key can't be empty")
+	}
+	pubKeyE, ok := prvKey.Public().(*ecdsa.PublicKey)
+	if !ok {
+		return nil, errors.New("casting private key to ECDSA")
+	}
+	return &keySigner{prvKey: prvKey, addr: crypto.PubkeyToAddress(*pubKeyE)}, nil
+}
+
+func (self *keySigner) Address() common.Address {
+	return self.addr
+}
+
+func (self *keySigner) SignText(text []byte) ([]byte, error) {
+	return crypto.Sign(accounts.TextHash(text), self.prvKey)
+}
+
+func (self *keySigner) SignTx(tx *types.Transaction, chainID *big.Int) (*types.Transaction, error) {
+	self.mtx.Lock()
+	if self.latest == nil || chainID == nil || self.latest.ChainID() == nil || self.latest.ChainID().Cmp(chainID) != 0 {
+		self.latest = types.LatestSignerForChainID(chainID)
+	}
+	latest := self.latest
+	self.mtx.Unlock()
+	return types.SignTx(tx, latest, self.prvKey)
+}
+
+func (self *keySigner) SignTxWith(tx *types.Transaction, chainSigner types.Signer) (*types.Transaction, error) {
+	return types.SignTx(tx, chainSigner, self.prvKey)
+}
+
+type walletSigner struct {
+	wallet  accounts.Wallet
+	account accounts.Account
+}
+
+// NewWalletSigner returns a signer backed by a go-ethereum wallet like a keystore or a hardware wallet.
+func NewWalletSigner(wallet accounts.Wallet, account accounts.Account) (Signer, error) {
+	if wallet == nil {
+		return nil, errors.New("wallet can't be empty")
+	}
+	if !wallet.Contains(account) {
+		return nil, errors.Errorf("wallet doesn't contain account:%v", account.Address.Hex())
+	}
+	return &walletSigner{wallet: wallet, account: account}, nil
+}
+
+// NewClefSigner returns a signer backed by a clef instance listening at the given endpoint.
+func NewClefSigner(endpoint string, addr common.Address) (Signer, error) {
+	wallet, err := external.NewExternalSigner(endpoint)
+	if err != nil {
+		return nil, errors.Wrapf(err, "connect to clef:%v", endpoint)
+	}
+	return NewWalletSigner(wallet, accounts.Account{Address: addr})
+}
+
+func (self *walletSigner) Address() common.Address {
+	return self.account.Address
+}
+
+func (self *walletSigner) SignText(text []byte) ([]byte, error) {
+	sig, err := self.wallet.SignText(self.account, text)
+	if err != nil {
+		return nil, err
+	}
+	return normalizeSigV(sig)
+}
+
+func (self *walletSigner) SignTx(tx *types.Transaction, chainID *big.Int) (*types.Transaction, error) {
+	return self.wallet.SignTx(self.account, tx, chainID)
+}
+
+// SignTxWith fails for any other than the latest signing scheme as the wallets pick it themselves.
+func (self *walletSigner) SignTxWith(tx *types.Transaction, chainSigner types.Signer) (*types.Transaction, error) {
+	return signTxLatest(self, tx, chainSigner)
+}
+
+type web3Signer struct {
+	client *rpc.Client
+	addr   common.Address
+}
+
+// NewWeb3Signer returns a signer backed by a web3signer instance
+// running in eth1 mode at the given JSON-RPC endpoint.
+func NewWeb3Signer(ctx context.Context, endpoint string, addr common.Address) (Signer, error) {
+	client, err := rpc.DialContext(ctx, endpoint)
+	if err != nil {
+		return nil, errors.Wrapf(err, "connect to web3signer:%v", endpoint)
+	}
+	return &web3Signer{client: client, addr: addr}, nil
+}
+
+func (self *web3Signer) Address() common.Address {
+	return self.addr
+}
+
+func (self *web3Signer) SignText(text []byte) ([]byte, error) {
+	var sig hexutil.Bytes
+	if err := self.client.Call(&sig, "eth_sign", self.addr, hexutil.Bytes(text)); err != nil {
+		return nil, errors.Wrap(err, "web3signer eth_sign request")
+	}
+	return normalizeSigV(sig)
+}
+
+func (self *web3Signer) SignTx(tx *types.Transaction, chainID *big.Int) (*types.Transaction, error) {
+	args := map[string]interface{}{
+		"from":  self.addr,
+		"nonce": hexutil.Uint64(tx.Nonce()),
+		"gas":   hexutil.Uint64(tx.Gas()),
+		"value": (*hexutil.Big)(tx.Value()),
+		"data":  hexutil.Bytes(tx.Data()),
+	}
+	if tx.To() != nil {
+		args["to"] = tx.To()
+	}
+	switch tx.Type() {
+	case types.LegacyTxType, types.AccessListTxType:
+		args["gasPrice"] = (*hexutil.Big)(tx.GasPrice())
+	case types.DynamicFeeTxType:
+		args["maxFeePerGas"] = (*hexutil.Big)(tx.GasFeeCap())
+		args["maxPriorityFeePerGas"] = (*hexutil.Big)(tx.GasTipCap())
+	default:
+		return nil, errors.Errorf("unsupported TX type:%v", tx.Type())
+	}
+	if chainID != nil {
+		args["chainId"] = (*hexutil.Big)(chainID)
+	}
+	if tx.Type() != types.LegacyTxType {
+		// The access list also tells the signer to keep the TX type.
+		args["accessList"] = tx.AccessList()
+		if tx.ChainId().Sign() != 0 {
+			args["chainId"] = (*hexutil.Big)(tx.ChainId())
+		}
+	}
+
+	var raw hexutil.Bytes
+	if err := self.client.Call(&raw, "eth_signTransaction", args); err != nil {
+		return nil, errors.Wrap(err, "web3signer eth_signTransaction request")
+	}
+	signed := &types.Transaction{}
+	if err := signed.UnmarshalBinary(raw); err != nil {
+		return nil, errors.Wrap(err, "decode signed TX")
+	}
+	sender, err := types.Sender(types.LatestSignerForChainID(chainID), signed)
+	if err != nil {
+		return nil, errors.Wrap(err, "recover signed TX sender")
+	}
+	if sender != self.addr {
+		return nil, errors.Errorf("signed TX sender mismatch exp:%v act:%v", self.addr.Hex(), sender.Hex())
+	}
+	return signed, nil
+}
+
+// SignTxWith fails for any other than the latest signing scheme as web3signer picks it itself.
+func (self *web3Signer) SignTxWith(tx *types.Transaction, chainSigner types.Signer) (*types.Transaction, error) {
+	return signTxLatest(self, tx, chainSigner)
+}
+
+// normalizeSigV transforms V from the Ethereum legacy 27/28 to 0/1.
+func normalizeSigV(sig []byte) ([]byte, error) {
+	if len(sig) != crypto.SignatureLength {
+		return nil, errors.Errorf("invalid signature length:%v", len(sig))
+	}
+	if sig[64] == 27 || sig[64] == 28 {
+		sig[64] -= 27
+	}
+	return sig, nil
+}
+
+// ErrInvalidSignature is returned when the X-Flashbots-Signature header doesn't match the request body.
+var ErrInvalidSignature = errors.New("invalid flashbots signature")
+
+// VerifySignatureHeader verifies the X-Flashbots-Signature header of a request with the given body
+// and returns the address of the signer.
+// It is the receiving side of the signing done for the relay requests.
+func VerifySignatureHeader(body []byte, header string) (common.Address, error) {
+	addrHex, sigHex, ok := strings.Cut(strings.TrimSpace(header), ":")
+	if !ok || !common.IsHexAddress(addrHex) {
+		return common.Address{}, errors.Wrap(ErrInvalidSignature, "malformed header")
+	}
+	sig, err := hexutil.Decode(sigHex)
+	if err != nil {
+		return common.Address{}, errors.Wrap(ErrInvalidSignature, "decode signature")
+	}
+	if sig, err = normalizeSigV(sig); err != nil {
+		return common.Address{}, errors.Wrap(ErrInvalidSignature, err.Error())
+	}
+	pubKey, err := crypto.SigToPub(accounts.TextHash([]byte(hexutil.Encode(crypto.Keccak256(body)))), sig)
+	if err != nil {
+		return common.Address{}, errors.Wrap(ErrInvalidSignature, "recover public key")
+	}
+	addr := common.HexToAddress(addrHex)
+	if signer := crypto.PubkeyToAddress(*pubKey); signer != addr {
+		return common.Address{}, errors.Wrapf(ErrInvalidSignature, "header address:%v signer:%v", addr.Hex(), signer.Hex())
+	}
+	return addr, nil
+}
@@ -0,0 +1,247 @@
+// Copyright (c) The Cryptorium Authors.
+// Licensed under the MIT License.
+
+package flashbot
+
+import (
+	"context"
+	"crypto/ecdsa"
+	"math/big"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/cryptoriums/packages/testutil"
+	"github.com/ethereum/go-ethereum/accounts"
+	"github.com/ethereum/go-ethereum/accounts/keystore"
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/common/hexutil"
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/crypto"
+	"github.com/ethereum/go-ethereum/rpc"
+	"github.com/ethereum/go-ethereum/signer/core/apitypes"
+	"github.com/pkg/errors"
+)
+
+func TestVerifySignatureHeader(t *testing.T) {
+	prvKey, err := crypto.GenerateKey()
+	testutil.Ok(t, err)
+	signer, err := NewKeySigner(prvKey)
+	testutil.Ok(t, err)
+
+	body := []byte(`{"jsonrpc":"2.0","id":1,"method":"eth_sendBundle","params":[]}`)
+	header, err := signPayload(body, signer)
+	testutil.Ok(t, err)
+
+	addr, err := VerifySignatureHeader(body, header)
+	testutil.Ok(t, err)
+	testutil.Equals(t, signer.Address(), addr)
+
+	// The legacy 27/28 V is accepted.
+	sig, err := hexutil.Decode(header[len(addr.Hex())+1:])
+	testutil.Ok(t, err)
+	sig[64] += 27
+	addr, err = VerifySignatureHeader(body, signer.Address().Hex()+":"+hexutil.Encode(sig))
+	testutil.Ok(t, err)
+	testutil.Equals(t, signer.Address(), addr)
+
+	// A different body or address.
+	_, err = VerifySignatureHeader(append(body, ' '), header)
+	testutil.Assert(t, errors.Is(err, ErrInvalidSignature), "unexpected error:%v", err)
+	other := common.HexToAddress("0x1111111111111111111111111111111111111111")
+	_, err = VerifySignatureHeader(body, other.Hex()+header[len(other.Hex()):])
+	testutil.Assert(t, errors.Is(err, ErrInvalidSignature), "unexpected error:%v", err)
+
+	for _, h := range []string{"", "0x01", "nothex:0x01", signer.Address().Hex() + ":0x01"} {
+		_, err = VerifySignatureHeader(body, h)
+		testutil.Assert(t, errors.Is(err, ErrInvalidSignature), "unexpected error:%v header:%v", err, h)
+	}
+}
+
+// signerMock is a fake web3signer and clef JSON-RPC server that signs with a local key.
+type signerMock struct {
+	t      *testing.T
+	prvKey *ecdsa.PrivateKey
+	// args are the last TX signing request args.
+	args apitypes.SendTxArgs
+}
+
+func (self *signerMock) sign(args apitypes.SendTxArgs) (*types.Transaction, error) {
+	self.args = args
+	tx := args.ToTransaction()
+	return types.SignTx(tx, types.LatestSignerForChainID((*big.Int)(args.ChainID)), self.prvKey)
+}
+
+type web3SignerMock struct{ *signerMock }
+
+func (self web3SignerMock) Sign(addr common.Address, data hexutil.Bytes) (hexutil.Bytes, error) {
+	return crypto.Sign(accounts.TextHash(data), self.prvKey)
+}
+
+func (self web3SignerMock) SignTransaction(args apitypes.SendTxArgs) (hexutil.Bytes, error) {
+	tx, err := self.sign(args)
+	if err != nil {
+		return nil, err
+	}
+	return tx.MarshalBinary()
+}
+
+type clefMock struct{ *signerMock }
+
+func (self clefMock) Version() string {
+	return "6.0.0"
+}
+
+func (self clefMock) List() []common.Address {
+	return []common.Address{crypto.PubkeyToAddress(self.prvKey.PublicKey)}
+}
+
+func (self clefMock) SignData(mimeType string, addr common.MixedcaseAddress, data hexutil.Bytes) (hexutil.Bytes, error) {
+	testutil.Equals(self.t, accounts.MimetypeTextPlain, mimeType)
+	sig, err := crypto.Sign(accounts.TextHash(data), self.prvKey)
+	if err != nil {
+		return nil, err
+	}
+	sig[64] += 27
+	return sig, nil
+}
+
+func (self clefMock) SignTransaction(args apitypes.SendTxArgs) (map[string]interface{}, error) {
+	tx, err := self.sign(args)
+	if err != nil {
+		return nil, err
+	}
+	raw, err := tx.MarshalBinary()
+	if err != nil {
+		return nil, err
+	}
+	return map[string]interface{}{"raw": hexutil.Bytes(raw), "tx": tx}, nil
+}
+
+func newSignerMock(t *testing.T, namespace string, service func(m *signerMock) interface{}) (*signerMock, string) {
+	prvKey, err := crypto.GenerateKey()
+	testutil.Ok(t, err)
+	m := &signerMock{t: t, prvKey: prvKey}
+	srv := rpc.NewServer()
+	testutil.Ok(t, srv.RegisterName(namespace, service(m)))
+	httpSrv := httptest.NewServer(srv)
+	t.Cleanup(func() {
+		httpSrv.Close()
+		srv.Stop()
+	})
+	return m, httpSrv.URL
+}
+
+// signerTxs are the TXs of each type with an access list for the ones that support it.
+func signerTxs() []*types.Transaction {
+	to := common.HexToAddress("0x02")
+	al := types.AccessList{{Address: to, StorageKeys: []common.Hash{common.HexToHash("0x03")}}}
+	return []*types.Transaction{
+		types.NewTx(&types.LegacyTx{Nonce: 1, To: &to, Gas: 21_000, GasPrice: big.NewInt(10), Value: big.NewInt(1), Data: []byte{1}}),
+		types.NewTx(&types.AccessListTx{ChainID: big.NewInt(1), Nonce: 2, To: &to, Gas: 30_000, GasPrice: big.NewInt(10), AccessList: al, Data: []byte{1}}),
+		types.NewTx(&types.DynamicFeeTx{ChainID: big.NewInt(1), Nonce: 3, To: &to, Gas: 30_000, GasFeeCap: big.NewInt(10), GasTipCap: big.NewInt(2), AccessList: al, Data: []byte{1}}),
+	}
+}
+
+func testRemoteSigner(t *testing.T, m *signerMock, signer Signer) {
+	addr := crypto.PubkeyToAddress(m.prvKey.PublicKey)
+	testutil.Equals(t, addr, signer.Address())
+
+	sig, err := signer.SignText([]byte("text"))
+	testutil.Ok(t, err)
+	pubKey, err := crypto.SigToPub(accounts.TextHash([]byte("text")), sig)
+	testutil.Ok(t, err)
+	testutil.Equals(t, addr, crypto.PubkeyToAddress(*pubKey))
+
+	for _, tx := range signerTxs() {
+		signed, err := signer.SignTx(tx, big.NewInt(1))
+		testutil.Ok(t, err)
+		testutil.Equals(t, tx.Type(), signed.Type())
+		testutil.Equals(t, tx.AccessList(), signed.AccessList())
+		testutil.Equals(t, tx.Data(), signed.Data())
+		sender, err := types.Sender(types.LatestSignerForChainID(big.NewInt(1)), signed)
+		testutil.Ok(t, err)
+		testutil.Equals(t, addr, sender)
+		if tx.Type() == types.LegacyTxType {
+			testutil.Assert(t, m.args.AccessList == nil, "legacy TX sent with an access list")
+		} else {
+			testutil.Equals(t, tx.AccessList(), *m.args.AccessList)
+		}
+	}
+}
+
+func TestWeb3Signer(t *testing.T) {
+	m, url := newSignerMock(t, "eth", func(m *signerMock) interface{} { return web3SignerMock{m} })
+	signer, err := NewWeb3Signer(context.Background(), url, crypto.PubkeyToAddress(m.prvKey.PublicKey))
+	testutil.Ok(t, err)
+	testRemoteSigner(t, m, signer)
+}
+
+func TestClefSigner(t *testing.T) {
+	m, url := newSignerMock(t, "account", func(m *signerMock) interface{} { return clefMock{m} })
+	signer, err := NewClefSigner(url, crypto.PubkeyToAddress(m.prvKey.PublicKey))
+	testutil.Ok(t, err)
+	testRemoteSigner(t, m, signer)
+
+	_, err = NewClefSigner(url, common.HexToAddress("0x01"))
+	testutil.NotOk(t, err, "account not in clef")
+}
+
+func TestWalletSigner(t *testing.T) {
+	ks := keystore.NewKeyStore(t.TempDir(), keystore.LightScryptN, keystore.LightScryptP)
+	account, err := ks.NewAccount("pass")
+	testutil.Ok(t, err)
+	testutil.Ok(t, ks.Unlock(account, "pass"))
+	wallet := ks.Wallets()[0]
+
+	signer, err := NewWalletSigner(wallet, account)
+	testutil.Ok(t, err)
+	sig, err := signer.SignText([]byte("text"))
+	testutil.Ok(t, err)
+	pubKey, err := crypto.SigToPub(accounts.TextHash([]byte("text")), sig)
+	testutil.Ok(t, err)
+	testutil.Equals(t, account.Address, crypto.PubkeyToAddress(*pubKey))
+
+	for _, tx := range signerTxs() {
+		signed, err := signer.SignTx(tx, big.NewInt(1))
+		testutil.Ok(t, err)
+		testutil.Equals(t, tx.AccessList(), signed.AccessList())
+		sender, err := types.Sender(types.LatestSignerForChainID(big.NewInt(1)), signed)
+		testutil.Ok(t, err)
+		testutil.Equals(t, account.Address, sender)
+	}
+
+	// The wallets pick the signing scheme themselves.
+	tx := signerTxs()[2]
+	_, err = signer.(chainSignerTx).SignTxWith(tx, types.NewEIP155Signer(big.NewInt(1)))
+	testutil.Assert(t, errors.Is(err, ErrChainSignerUnsupported), "unexpected error:%v", err)
+	_, err = signer.(chainSignerTx).SignTxWith(tx, types.LatestSignerForChainID(big.NewInt(1)))
+	testutil.Ok(t, err)
+
+	_, err = NewWalletSigner(wallet, accounts.Account{Address: common.HexToAddress("0x01")})
+	testutil.NotOk(t, err, "account not in the wallet")
+}
+
+// sliceSigner is a signer whose dynamic type isn't comparable.
+type sliceSigner struct {
+	Signer
+	tags []string
+}
+
+func TestSignatureHeaderSigners(t *testing.T) {
+	fb := newTestFlashbot(t, "http://localhost")
+	for i := 0; i < 2; i++ {
+		prvKey, err := crypto.GenerateKey()
+		testutil.Ok(t, err)
+		signer, err := NewKeySigner(prvKey)
+		testutil.Ok(t, err)
+		testutil.Ok(t, fb.SetSigner(sliceSigner{Signer: signer}))
+
+		for j := 0; j < 2; j++ {
+			header, err := fb.signatureHeader([]byte("body"))
+			testutil.Ok(t, err)
+			addr, err := VerifySignatureHeader([]byte("body"), header)
+			testutil.Ok(t, err)
+			testutil.Equals(t, signer.Address(), addr)
+		}
+	}
+}
@@ -0,0 +1,153 @@
+// Copyright (c) The Cryptorium Authors.
+// Licensed under the MIT License.
+
+package flashbot
+
+import (
+	"context"
+	"sync"
+	"time"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/core/types"
+)
+
+type simKey struct {
+	relay      string
+	bundle     common.Hash
+	stateBlock uint64
+}
+
+type simEntry struct {
+	resp    *Response
+	expires time.Time
+}
+
+// SimCache caches the CallBundle results by bundle hash and state block
+// so that scoring the same bundle many times within a block doesn't use the relay quota.
+// The simulations on top of the latest state are cached as state block 0
+// and all entries are dropped on a new head with OnHead.
+type SimCache struct {
+	mtx     sync.Mutex
+	ttl     time.Duration
+	now     func() time.Time
+	entries map[simKey]simEntry
+	hits    uint64
+	misses  uint64
+}
+
+// NewSimCache creates a cache with the entries expiring after the ttl, a block time is a good default.
+func NewSimCache(ttl time.Duration) *SimCache {
+	return &SimCache{ttl: ttl, now: time.Now, entries: make(map[simKey]simEntry)}
+}
+
+func (self *SimCache) get(k simKey) (*Response, bool) {
+	self.mtx.Lock()
+	defer self.mtx.Unlock()
+	e, ok := self.entries[k]
+	if !ok || !self.now().Before(e.expires) {
+		delete(self.entries, k)
+		self.misses++
+		return nil, false
+	}
+	self.hits++
+	return copyResponse(e.resp), true
+}
+
+func (self *SimCache) put(k simKey, resp *Response) {
+	self.mtx.Lock()
+	defer self.mtx.Unlock()
+	self.entries[k] = simEntry{resp: copyResponse(resp), expires: self.now().Add(self.ttl)}
+}
+
+// copyResponse copies the response with its TX results so that the callers can't change the cached entries.
+func copyResponse(resp *Response) *Response {
+	r := *resp
+	if resp.Results != nil {
+		r.Results = append(make([]TxResult, 0, len(resp.Results)), resp.Results...)
+	}
+	return &r
+}
+
+// OnHead drops the entries simulated on top of the latest state since the new head changes it,
+// the entries for the state blocks at or after the head which could have been reorged and the expired ones.
+func (self *SimCache) OnHead(blockNum uint64) {
+	self.mtx.Lock()
+	defer self.mtx.Unlock()
+	now := self.now()
+	for k, e := range self.entries {
+		if k.stateBlock == 0 || k.stateBlock >= blockNum || !now.Before(e.expires) {
+			delete(self.entries, k)
+		}
+	}
+}
+
+// Invalidate drops all entries.
+func (self *SimCache) Invalidate() {
+	self.mtx.Lock()
+	defer self.mtx.Unlock()
+	self.entries = make(map[simKey]simEntry)
+}
+
+// Len returns the number of cached entries including the expired ones not dropped yet.
+func (self *SimCache) Len() int {
+	self.mtx.Lock()
+	defer self.mtx.Unlock()
+	return len(self.entries)
+}
+
+// Stats returns the number of the cache hits and misses.
+func (self *SimCache) Stats() (hits, misses uint64) {
+	self.mtx.Lock()
+	defer self.mtx.Unlock()
+	return self.hits, self.misses
+}
+
+// WatchHeads calls OnHead for every head of the watcher until the context is done.
+func (self *SimCache) WatchHeads(ctx context.Context, heads *HeadWatcher) {
+	ch, unsubscribe := heads.Subscribe(1)
+	defer unsubscribe()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case h, ok := <-ch:
+			if !ok {
+				return
+			}
+			self.OnHead(headNumber(h))
+		}
+	}
+}
+
+func headNumber(h *types.Header) uint64 {
+	if h == nil || h.Number == nil {
+		return 0
+	}
+	return h.Number.Uint64()
+}
+
+// SetSimCache enables caching of the CallBundle results without state overrides.
+// The same cache can be shared between the clients of different relays.
+func (self *Flashbot) SetSimCache(c *SimCache) {
+	self.mtx.Lock()
+	defer self.mtx.Unlock()
+	self.simCache = c
+}
+
+func (self *Flashbot) cachedSim(txsHex []string, stateBlock uint64) (*SimCache, simKey, *Response) {
+	self.mtx.RLock()
+	c := self.simCache
+	self.mtx.RUnlock()
+	if c == nil {
+		return nil, simKey{}, nil
+	}
+	hash, err := ComputeBundleHash(txsHex)
+	if err != nil {
+		// Let the relay return the error for the invalid TXs.
+		return nil, simKey{}, nil
+	}
+	k := simKey{relay: self.api.URL, bundle: hash, stateBlock: stateBlock}
+	resp, _ := c.get(k)
+	return c, k, resp
+}
@@ -0,0 +1,107 @@
+// Copyright (c) The Cryptorium Authors.
+// Licensed under the MIT License.
+
+package flashbot
+
+import (
+	"context"
+	"encoding/json"
+	"math/big"
+	"testing"
+	"time"
+
+	"github.com/cryptoriums/packages/testutil"
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/common/hexutil"
+	"github.com/ethereum/go-ethereum/core/types"
+)
+
+func TestSimCache(t *testing.T) {
+	mock := newRelayMock(t, func(method string, params json.RawMessage) (interface{}, *jsonError) {
+		return map[string]interface{}{"bundleHash": "0x01", "coinbaseDiff": "100"}, nil
+	})
+	fb := newTestFlashbot(t, mock.URL)
+	cache := NewSimCache(time.Minute)
+	now := time.Unix(1000, 0)
+	cache.now = func() time.Time { return now }
+	fb.SetSimCache(cache)
+
+	ctx := context.Background()
+	txs := []string{"0x01"}
+	resp, err := fb.CallBundle(ctx, txs, 10)
+	testutil.Ok(t, err)
+	testutil.Equals(t, "100", resp.CoinbaseDiff)
+	_, err = fb.CallBundle(ctx, txs, 10)
+	testutil.Ok(t, err)
+	testutil.Equals(t, 1, len(mock.Methods()))
+
+	// A different state block, a different bundle or state overrides miss the cache.
+	_, err = fb.CallBundle(ctx, txs, 11)
+	testutil.Ok(t, err)
+	_, err = fb.CallBundle(ctx, []string{"0x02"}, 10)
+	testutil.Ok(t, err)
+	_, err = fb.CallBundleOverride(ctx, txs, 10, StateOverride{common.Address{}: {Balance: (*hexutil.Big)(big.NewInt(1))}})
+	testutil.Ok(t, err)
+	testutil.Equals(t, 4, len(mock.Methods()))
+	hits, misses := cache.Stats()
+	testutil.Equals(t, uint64(1), hits)
+	testutil.Equals(t, uint64(3), misses)
+
+	// The entries expire after the TTL.
+	now = now.Add(time.Minute)
+	_, err = fb.CallBundle(ctx, txs, 10)
+	testutil.Ok(t, err)
+	testutil.Equals(t, 5, len(mock.Methods()))
+
+	// A new head drops the latest state entries, the ones at or after the head and the expired ones.
+	_, err = fb.CallBundle(ctx, txs, 0)
+	testutil.Ok(t, err)
+	_, err = fb.CallBundle(ctx, txs, 11)
+	testutil.Ok(t, err)
+	testutil.Equals(t, 4, cache.Len())
+	cache.OnHead(11)
+	testutil.Equals(t, 1, cache.Len())
+	_, err = fb.CallBundle(ctx, txs, 10)
+	testutil.Ok(t, err)
+	testutil.Equals(t, 7, len(mock.Methods()))
+
+	cache.Invalidate()
+	testutil.Equals(t, 0, cache.Len())
+}
+
+func TestSimCacheCopy(t *testing.T) {
+	cache := NewSimCache(time.Minute)
+	k := simKey{bundle: common.Hash{1}}
+	resp := &Response{Result: Result{Results: []TxResult{{TxHash: "0x01"}}}}
+	cache.put(k, resp)
+	resp.Results[0].TxHash = "0x02"
+
+	// The stored and the returned entries don't share the TX results.
+	got, ok := cache.get(k)
+	testutil.Assert(t, ok, "entry should be cached")
+	testutil.Equals(t, "0x01", got.Results[0].TxHash)
+	got.Results[0].TxHash = "0x03"
+	got, ok = cache.get(k)
+	testutil.Assert(t, ok, "entry should be cached")
+	testutil.Equals(t, "0x01", got.Results[0].TxHash)
+}
+
+func TestSimCacheWatchHeads(t *testing.T) {
+	cache := NewSimCache(time.Minute)
+	cache.put(simKey{bundle: common.Hash{1}}, &Response{})
+	w := NewHeadWatcher(nil, time.Second)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan struct{})
+	go func() {
+		cache.WatchHeads(ctx, w)
+		close(done)
+	}()
+	// The heads are published until the watcher has subscribed.
+	for n := int64(1); cache.Len() != 0; n++ {
+		w.publish(&types.Header{Number: big.NewInt(n)})
+		time.Sleep(time.Millisecond)
+	}
+	cancel()
+	<-done
+}
@@ -0,0 +1,149 @@
+// Copyright (c) The Cryptorium Authors.
+// Licensed under the MIT License.
+
+package flashbot
+
+import (
+	"context"
+	"sort"
+	"strconv"
+	"sync"
+
+	"github.com/pkg/errors"
+)
+
+// RelaySim is the simulation result of one of the relays.
+type RelaySim struct {
+	Relay    string
+	Response *Response
+	Err      error
+}
+
+// SimDivergence is a simulation field which value isn't the same for all relays.
+// TxIndex is -1 for the bundle totals.
+type SimDivergence struct {
+	TxIndex int
+	TxHash  string
+	Field   string
+	// Values are the field values by relay URL.
+	Values map[string]string
+}
+
+type SimDiff struct {
+	Sims        []RelaySim
+	Divergences []SimDivergence
+}
+
+// Diverged returns true when at least one field differs between the relays.
+func (self *SimDiff) Diverged() bool {
+	return len(self.Divergences) > 0
+}
+
+// SimulateAcross simulates the same bundle on all relays concurrently
+// and compares the gas used, the coinbase diffs and the reverts between the successful simulations.
+// An error is returned only when all simulations failed.
+func SimulateAcross(ctx context.Context, relays []Flashboter, txsHex []string, blockNumState uint64) (*SimDiff, error) {
+	if len(relays) == 0 {
+		return nil, errors.New("no relays to simulate with")
+	}
+
+	sims := make([]RelaySim, len(relays))
+	var wg sync.WaitGroup
+	for i, r := range relays {
+		wg.Add(1)
+		go func(i int, r Flashboter) {
+			defer wg.Done()
+			resp, err := r.CallBundle(ctx, txsHex, blockNumState)
+			sims[i] = RelaySim{Relay: r.Api().URL, Response: resp, Err: err}
+		}(i, r)
+	}
+	wg.Wait()
+
+	var ok []RelaySim
+	for _, s := range sims {
+		if s.Err == nil {
+			ok = append(ok, s)
+		}
+	}
+	if len(ok) == 0 {
+		return nil, errors.Wrap(sims[0].Err, "simulation failed on all relays")
+	}
+
+	diff := &SimDiff{Sims: sims}
+	diff.compare(-1, "", "gasUsed", ok, func(r *Result) (string, bool) {
+		var total uint64
+		for _, tx := range r.Results {
+			total += tx.GasUsed
+		}
+		return strconv.FormatUint(total, 10), true
+	})
+	diff.compare(-1, "", "coinbaseDiff", ok, func(r *Result) (string, bool) { return r.CoinbaseDiff, true })
+	diff.compare(-1, "", "txCount", ok, func(r *Result) (string, bool) { return strconv.Itoa(len(r.Results)), true })
+
+	for i := range txsHex {
+		i := i
+		tx := func(r *Result) (*TxResult, bool) {
+			if i >= len(r.Results) {
+				return nil, false
+			}
+			return &r.Results[i], true
+		}
+		var hash string
+		if t, ok := tx(&ok[0].Response.Result); ok {
+			hash = t.TxHash
+		}
+		diff.compare(i, hash, "gasUsed", ok, func(r *Result) (string, bool) {
+			t, ok := tx(r)
+			if !ok {
+				return "", false
+			}
+			return strconv.FormatUint(t.GasUsed, 10), true
+		})
+		diff.compare(i, hash, "coinbaseDiff", ok, func(r *Result) (string, bool) {
+			t, ok := tx(r)
+			if !ok {
+				return "", false
+			}
+			return t.CoinbaseDiff, true
+		})
+		diff.compare(i, hash, "revert", ok, func(r *Result) (string, bool) {
+			t, ok := tx(r)
+			if !ok {
+				return "", false
+			}
+			if t.Error != "" && t.Revert != "" {
+				return t.Error + ": " + t.Revert, true
+			}
+			return t.Error + t.Revert, true
+		})
+	}
+	return diff, nil
+}
+
+func (self *SimDiff) compare(txIndex int, txHash, field string, sims []RelaySim, value func(*Result) (string, bool)) {
+	values := make(map[string]string, len(sims))
+	set := make(map[string]struct{})
+	for _, s := range sims {
+		v, ok := value(&s.Response.Result)
+		if !ok {
+			continue
+		}
+		values[s.Relay] = v
+		set[v] = struct{}{}
+	}
+	if len(set) > 1 {
+		self.Divergences = append(self.Divergences, SimDivergence{TxIndex: txIndex, TxHash: txHash, Field: field, Values: values})
+	}
+}
+
+// Failed returns the URLs of the relays which simulation failed.
+func (self *SimDiff) Failed() []string {
+	var res []string
+	for _, s := range self.Sims {
+		if s.Err != nil {
+			res = append(res, s.Relay)
+		}
+	}
+	sort.Strings(res)
+	return res
+}
@@ -0,0 +1,48 @@
+// Copyright (c) The Cryptorium Authors.
+// Licensed under the MIT License.
+
+package flashbot
+
+import (
+	"context"
+	"encoding/json"
+	"testing"
+
+	"github.com/cryptoriums/packages/testutil"
+)
+
+func TestSimulateAcross(t *testing.T) {
+	sim := func(gasUsed uint64, revert string) *relayMock {
+		return newRelayMock(t, func(method string, params json.RawMessage) (interface{}, *jsonError) {
+			return Result{
+				Metadata: Metadata{CoinbaseDiff: "100"},
+				Results: []TxResult{
+					{TxHash: "0xaa", GasUsed: 21000, Metadata: Metadata{CoinbaseDiff: "50"}},
+					{TxHash: "0xbb", GasUsed: gasUsed, Revert: revert, Metadata: Metadata{CoinbaseDiff: "50"}},
+				},
+			}, nil
+		})
+	}
+	r1, r2 := sim(30000, ""), sim(30000, "")
+	failing := newRelayMock(t, func(method string, params json.RawMessage) (interface{}, *jsonError) {
+		return nil, &jsonError{Code: -32000, Message: "unknown block"}
+	})
+	relays := []Flashboter{newTestFlashbot(t, r1.URL), newTestFlashbot(t, r2.URL), newTestFlashbot(t, failing.URL)}
+
+	diff, err := SimulateAcross(context.Background(), relays, []string{"0x01", "0x02"}, 0)
+	testutil.Ok(t, err)
+	testutil.Assert(t, !diff.Diverged(), "unexpected divergences:%+v", diff.Divergences)
+	testutil.Equals(t, []string{failing.URL}, diff.Failed())
+
+	r3 := sim(35000, "out of gas")
+	relays = []Flashboter{newTestFlashbot(t, r1.URL), newTestFlashbot(t, r3.URL)}
+	diff, err = SimulateAcross(context.Background(), relays, []string{"0x01", "0x02"}, 0)
+	testutil.Ok(t, err)
+	testutil.Equals(t, 3, len(diff.Divergences))
+	testutil.Equals(t, -1, diff.Divergences[0].TxIndex)
+	testutil.Equals(t, map[string]string{r1.URL: "51000", r3.URL: "56000"}, diff.Divergences[0].Values)
+	testutil.Equals(t, SimDivergence{TxIndex: 1, TxHash: "0xbb", Field: "revert", Values: map[string]string{r1.URL: "", r3.URL: "out of gas"}}, diff.Divergences[2])
+
+	_, err = SimulateAcross(context.Background(), []Flashboter{newTestFlashbot(t, failing.URL)}, []string{"0x01"}, 0)
+	testutil.NotOk(t, err)
+}
@@ -0,0 +1,92 @@
+// Copyright (c) The Cryptorium Authors.
+// Licensed under the MIT License.
+
+package flashbot
+
+import (
+	"context"
+	"time"
+
+	"github.com/pkg/errors"
+)
+
+const (
+	// MainnetGenesisTime is the beacon chain genesis time of mainnet.
+	MainnetGenesisTime int64 = 1606824023
+	SlotDuration             = 12 * time.Second
+)
+
+// SlotClock maps the wall time to the beacon chain slots.
+type SlotClock struct {
+	genesis      time.Time
+	slotDuration time.Duration
+	now          func() time.Time
+}
+
+// NewSlotClock returns a clock for a chain with the given genesis unix time and slot duration,
+// zero duration means the default 12 seconds.
+func NewSlotClock(genesisTime int64, slotDuration time.Duration) *SlotClock {
+	if slotDuration <= 0 {
+		slotDuration = SlotDuration
+	}
+	return &SlotClock{genesis: time.Unix(genesisTime, 0), slotDuration: slotDuration, now: time.Now}
+}
+
+// Slot returns the slot at the time, zero before the genesis.
+func (self *SlotClock) Slot(t time.Time) uint64 {
+	if t.Before(self.genesis) {
+		return 0
+	}
+	return uint64(t.Sub(self.genesis) / self.slotDuration)
+}
+
+func (self *SlotClock) SlotStart(slot uint64) time.Time {
+	return self.genesis.Add(time.Duration(slot) * self.slotDuration)
+}
+
+func (self *SlotClock) CurrentSlot() uint64 {
+	return self.Slot(self.now())
+}
+
+// TimeInSlot returns the time elapsed since the start of the current slot.
+func (self *SlotClock) TimeInSlot() time.Duration {
+	now := self.now()
+	return now.Sub(self.SlotStart(self.Slot(now)))
+}
+
+// TimeRemaining returns the time until the start of the next slot.
+func (self *SlotClock) TimeRemaining() time.Duration {
+	return self.slotDuration - self.TimeInSlot()
+}
+
+// WaitOffset blocks until the offset within the current slot and returns the slot.
+// When the offset has already passed it returns immediately.
+func (self *SlotClock) WaitOffset(ctx context.Context, offset time.Duration) (uint64, error) {
+	if offset < 0 || offset >= self.slotDuration {
+		return 0, errors.Errorf("slot offset:%v outside of the slot duration:%v", offset, self.slotDuration)
+	}
+	now := self.now()
+	slot := self.Slot(now)
+	wait := self.SlotStart(slot).Add(offset).Sub(now)
+	if wait <= 0 {
+		return slot, nil
+	}
+	timer := time.NewTimer(wait)
+	defer timer.Stop()
+	select {
+	case <-ctx.Done():
+		return 0, ctx.Err()
+	case <-timer.C:
+		return slot, nil
+	}
+}
+
+// SendBundleAtSlotOffset waits until the offset within the current slot and then sends the bundle
+// so that it reaches the builders just before the final block of the slot is built.
+// The blockNum should be the block of the next slot.
+func (self *Flashbot) SendBundleAtSlotOffset(ctx context.Context, clock *SlotClock, offset time.Duration, txsHex []string, blockNum uint64) (*Response, error) {
+	if _, err := clock.WaitOffset(ctx, offset); err != nil {
+		return nil, errors.Wrap(err, "wait slot offset")
+	}
+	return self.SendBundle(ctx, txsHex, blockNum)
+}
@@ -0,0 +1,52 @@
+// Copyright (c) The Cryptorium Authors.
+// Licensed under the MIT License.
+
+package flashbot
+
+import (
+	"context"
+	"encoding/json"
+	"testing"
+	"time"
+
+	"github.com/cryptoriums/packages/testutil"
+)
+
+func TestSlotClock(t *testing.T) {
+	clock := NewSlotClock(MainnetGenesisTime, 0)
+	now := time.Unix(MainnetGenesisTime, 0).Add(100*SlotDuration + 3*time.Second)
+	clock.now = func() time.Time { return now }
+
+	testutil.Equals(t, uint64(100), clock.CurrentSlot())
+	testutil.Equals(t, 3*time.Second, clock.TimeInSlot())
+	testutil.Equals(t, 9*time.Second, clock.TimeRemaining())
+	testutil.Equals(t, uint64(0), clock.Slot(time.Unix(0, 0)))
+
+	// The offset has passed already.
+	slot, err := clock.WaitOffset(context.Background(), time.Second)
+	testutil.Ok(t, err)
+	testutil.Equals(t, uint64(100), slot)
+
+	_, err = clock.WaitOffset(context.Background(), SlotDuration)
+	testutil.NotOk(t, err)
+}
+
+func TestSendBundleAtSlotOffset(t *testing.T) {
+	relay := newRelayMock(t, func(method string, params json.RawMessage) (interface{}, *jsonError) {
+		return Result{BundleHash: "0xbundle"}, nil
+	})
+	fb := newTestFlashbot(t, relay.URL)
+
+	clock := NewSlotClock(time.Now().Unix(), time.Hour)
+	start := time.Now()
+	offset := time.Since(time.Unix(time.Now().Unix(), 0)) + 50*time.Millisecond
+	_, err := fb.SendBundleAtSlotOffset(context.Background(), clock, offset, []string{"0x01"}, 10)
+	testutil.Ok(t, err)
+	testutil.Assert(t, time.Since(start) >= 40*time.Millisecond, "sent before the offset")
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	_, err = fb.SendBundleAtSlotOffset(ctx, clock, 59*time.Minute, []string{"0x01"}, 10)
+	testutil.NotOk(t, err)
+	testutil.Equals(t, 1, len(relay.Methods()))
+}
@@ -0,0 +1,91 @@
+// Copyright (c) The Cryptorium Authors.
+// Licensed under the MIT License.
+
+package flashbot
+
+import (
+	"sync"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/pkg/errors"
+)
+
+// ErrSpamCapReached is returned when the identity already sent the max number of bundles for the target block.
+var ErrSpamCapReached = errors.New("bundles per block cap reached")
+
+type spamKey struct {
+	identity common.Address
+	block    uint64
+}
+
+// SpamGuard caps the number of bundles per target block for each signing identity
+// since the relays lower the reputation of the identities that send many low quality bundles.
+// The same guard can be shared between the clients of different relays to enforce a global cap.
+type SpamGuard struct {
+	mtx    sync.Mutex
+	cap    int
+	counts map[spamKey]int
+}
+
+func NewSpamGuard(cap int) (*SpamGuard, error) {
+	if cap <= 0 {
+		return nil, errors.New("bundles per block cap should be positive")
+	}
+	return &SpamGuard{cap: cap, counts: make(map[spamKey]int)}, nil
+}
+
+// Take counts a submission and returns ErrSpamCapReached when the cap is already reached.
+func (self *SpamGuard) Take(identity common.Address, blockNum uint64) error {
+	self.mtx.Lock()
+	defer self.mtx.Unlock()
+	k := spamKey{identity: identity, block: blockNum}
+	if self.counts[k] >= self.cap {
+		return errors.Wrapf(ErrSpamCapReached, "identity:%v block:%v cap:%v", identity.Hex(), blockNum, self.cap)
+	}
+	self.counts[k]++
+	return nil
+}
+
+// Count returns the number of the bundles sent by the identity for the target block.
+func (self *SpamGuard) Count(identity common.Address, blockNum uint64) int {
+	self.mtx.Lock()
+	defer self.mtx.Unlock()
+	return self.counts[spamKey{identity: identity, block: blockNum}]
+}
+
+// Remaining returns how many bundles the identity can still send for the target block.
+func (self *SpamGuard) Remaining(identity common.Address, blockNum uint64) int {
+	return self.cap - self.Count(identity, blockNum)
+}
+
+// Prune drops the counters of the blocks before the given one.
+func (self *SpamGuard) Prune(beforeBlock uint64) {
+	self.mtx.Lock()
+	defer self.mtx.Unlock()
+	for k := range self.counts {
+		if k.block < beforeBlock {
+			delete(self.counts, k)
+		}
+	}
+}
+
+// SetSpamGuard enables the cap of bundles per block for the auth signer identity.
+// The bundles over the cap are rejected without sending them.
+func (self *Flashbot) SetSpamGuard(g *SpamGuard) {
+	self.mtx.Lock()
+	defer self.mtx.Unlock()
+	self.spamGuard = g
+}
+
+func (self *Flashbot) takeSpamQuota(blockNum uint64) error {
+	self.mtx.RLock()
+	g, signer := self.spamGuard, self.signer
+	self.mtx.RUnlock()
+	if g == nil {
+		return nil
+	}
+	if signer == nil {
+		return errors.New("private key or signer is not set")
+	}
+	return g.Take(signer.Address(), blockNum)
+}
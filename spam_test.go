@@ -0,0 +1,58 @@
+// Copyright (c) The Cryptorium Authors.
+// Licensed under the MIT License.
+
+package flashbot
+
+import (
+	"context"
+	"encoding/json"
+	"testing"
+
+	"github.com/cryptoriums/packages/testutil"
+	"github.com/pkg/errors"
+)
+
+func TestSpamGuard(t *testing.T) {
+	ctx := context.Background()
+	relay := newRelayMock(t, func(method string, params json.RawMessage) (interface{}, *jsonError) {
+		return Result{BundleHash: "0xbundle"}, nil
+	})
+	fb := newTestFlashbot(t, relay.URL)
+	other := newTestFlashbot(t, relay.URL)
+
+	_, err := NewSpamGuard(0)
+	testutil.NotOk(t, err)
+	guard, err := NewSpamGuard(2)
+	testutil.Ok(t, err)
+	fb.SetSpamGuard(guard)
+	other.SetSpamGuard(guard)
+
+	for i := 0; i < 2; i++ {
+		_, err := fb.SendBundle(ctx, []string{"0x01"}, 10)
+		testutil.Ok(t, err)
+	}
+	_, err = fb.SendBundle(ctx, []string{"0x01"}, 10)
+	testutil.Assert(t, errors.Is(err, ErrSpamCapReached), "unexpected error:%v", err)
+	testutil.Equals(t, 2, len(relay.Methods()))
+
+	// The cap is per block and per identity.
+	_, err = fb.SendBundle(ctx, []string{"0x01"}, 11)
+	testutil.Ok(t, err)
+	_, err = other.SendBundle(ctx, []string{"0x01"}, 10)
+	testutil.Ok(t, err)
+
+	id := fb.Signer().Address()
+	testutil.Equals(t, 0, guard.Remaining(id, 10))
+	testutil.Equals(t, 1, guard.Remaining(id, 11))
+	guard.Prune(11)
+	testutil.Equals(t, 0, guard.Count(id, 10))
+	testutil.Equals(t, 1, guard.Count(id, 11))
+
+	// Without an identity the bundle is rejected instead of panicking.
+	anon, err := New(nil, &Api{URL: relay.URL})
+	testutil.Ok(t, err)
+	anon.(*Flashbot).SetSpamGuard(guard)
+	_, err = anon.SendBundle(ctx, []string{"0x01"}, 12)
+	testutil.NotOk(t, err)
+	testutil.Equals(t, 0, guard.Count(id, 12))
+}
@@ -0,0 +1,105 @@
+// Copyright (c) The Cryptorium Authors.
+// Licensed under the MIT License.
+
+package flashbot
+
+import (
+	"context"
+	"time"
+)
+
+type BundleStatus int
+
+const (
+	BundleStatusUnknown BundleStatus = iota
+	BundleStatusSimulated
+	BundleStatusSentToBuilders
+	BundleStatusConsidered
+	BundleStatusSealed
+)
+
+func (self BundleStatus) String() string {
+	switch self {
+	case BundleStatusSimulated:
+		return "simulated"
+	case BundleStatusSentToBuilders:
+		return "sent to builders"
+	case BundleStatusConsidered:
+		return "considered"
+	case BundleStatusSealed:
+		return "sealed"
+	default:
+		return "unknown"
+	}
+}
+
+// Status returns the furthest stage the bundle reached.
+func (self BundleStats) Status() BundleStatus {
+	switch {
+	case len(self.SealedByBuildersAt) > 0:
+		return BundleStatusSealed
+	case len(self.ConsideredByBuildersAt) > 0:
+		return BundleStatusConsidered
+	case !self.SentToMinersAt.IsZero():
+		return BundleStatusSentToBuilders
+	case self.IsSimulated:
+		return BundleStatusSimulated
+	default:
+		return BundleStatusUnknown
+	}
+}
+
+type BundleStatsUpdate struct {
+	Status BundleStatus
+	Stats  *BundleStats
+	Err    error
+}
+
+// WatchBundleStats polls the bundle stats through GetBundleStatsV2 every interval
+// and sends an update each time the bundle reaches a new stage.
+// The V2 stats don't report the SentToBuilders stage so it is skipped by the relays that only have V2.
+// The channel is closed when the bundle is sealed or the context is done.
+// Request errors are sent as updates with Err set and the polling continues.
+func (self *Flashbot) WatchBundleStats(ctx context.Context, bundleHash string, blockNum uint64, interval time.Duration) <-chan BundleStatsUpdate {
+	if interval <= 0 {
+		interval = time.Second
+	}
+	updates := make(chan BundleStatsUpdate, 1)
+	go func() {
+		defer close(updates)
+		ticker := time.NewTicker(interval)
+		defer ticker.Stop()
+
+		last := BundleStatusUnknown
+		for {
+			var u *BundleStatsUpdate
+			stats, err := self.GetBundleStatsV2(ctx, bundleHash, blockNum)
+			if err != nil {
+				if ctx.Err() != nil {
+					return
+				}
+				u = &BundleStatsUpdate{Status: last, Err: err}
+			} else if status := stats.Result.Status(); status > last {
+				last = status
+				u = &BundleStatsUpdate{Status: status, Stats: &stats.Result}
+			}
+			if u != nil {
+				select {
+				case updates <- *u:
+				case <-ctx.Done():
+					return
+				}
+			}
+			if last == BundleStatusSealed {
+				return
+			}
+
+			select {
+			case <-ctx.Done():
+				return
+			case <-ticker.C:
+			}
+		}
+	}()
+	return updates
+}
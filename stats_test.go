@@ -0,0 +1,55 @@
+// Copyright (c) The Cryptorium Authors.
+// Licensed under the MIT License.
+
+package flashbot
+
+import (
+	"context"
+	"encoding/json"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/cryptoriums/packages/testutil"
+)
+
+func TestWatchBundleStats(t *testing.T) {
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	var polls int32
+	now := time.Now().UTC()
+	relay := newRelayMock(t, func(method string, params json.RawMessage) (interface{}, *jsonError) {
+		testutil.Equals(t, "flashbots_getBundleStatsV2", method)
+		stats := BundleStats{}
+		switch atomic.AddInt32(&polls, 1) {
+		case 1:
+		case 2, 3:
+			stats.IsSimulated = true
+		case 4:
+			stats.IsSimulated = true
+			stats.ConsideredByBuildersAt = []BuilderStats{{Pubkey: "0x01", Timestamp: now}}
+		default:
+			stats.IsSimulated = true
+			stats.ReceivedAt = now
+			stats.ConsideredByBuildersAt = []BuilderStats{{Pubkey: "0x01", Timestamp: now}}
+			stats.SealedByBuildersAt = []BuilderStats{{Pubkey: "0x01", Timestamp: now}}
+		}
+		return stats, nil
+	})
+	fb := newTestFlashbot(t, relay.URL)
+
+	var (
+		statuses []BundleStatus
+		last     *BundleStats
+	)
+	for u := range fb.WatchBundleStats(ctx, "0xbundle", 10, time.Millisecond) {
+		testutil.Ok(t, u.Err)
+		statuses = append(statuses, u.Status)
+		last = u.Stats
+	}
+	testutil.Equals(t, []BundleStatus{BundleStatusSimulated, BundleStatusConsidered, BundleStatusSealed}, statuses)
+	// The V2 received time is the submission time used for the seal latency.
+	testutil.Equals(t, now, last.SubmittedAt.UTC())
+	testutil.Equals(t, int32(5), atomic.LoadInt32(&polls))
+}
@@ -0,0 +1,178 @@
+// Copyright (c) The Cryptorium Authors.
+// Licensed under the MIT License.
+
+package flashbot
+
+import (
+	"context"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/pkg/errors"
+)
+
+// ErrBundleNotFound is returned by the stores for unknown bundle IDs.
+var ErrBundleNotFound = errors.New("bundle not found")
+
+// BundleStore persists the managed bundles for audits and crash recovery.
+// See the boltstore package for a file based implementation.
+type BundleStore interface {
+	Save(ctx context.Context, b *ManagedBundle) error
+	Get(ctx context.Context, id string) (*ManagedBundle, error)
+	// List returns the bundles matching the filter sorted by creation time.
+	List(ctx context.Context, filter BundleFilter) ([]*ManagedBundle, error)
+	Delete(ctx context.Context, id string) error
+	Close() error
+}
+
+// BundleFilter selects bundles by creation time, state and relay, the zero values match all.
+type BundleFilter struct {
+	From   time.Time
+	To     time.Time
+	States []BundleState
+	Relay  string
+}
+
+// Match returns whether the bundle matches the filter.
+func (self BundleFilter) Match(b *ManagedBundle) bool {
+	if !self.From.IsZero() && b.CreatedAt.Before(self.From) {
+		return false
+	}
+	if !self.To.IsZero() && !b.CreatedAt.Before(self.To) {
+		return false
+	}
+	if len(self.States) > 0 && !containsState(self.States, b.State) {
+		return false
+	}
+	if self.Relay != "" && b.Relay != self.Relay {
+		return false
+	}
+	return true
+}
+
+// SortBundles sorts the bundles by creation time.
+func SortBundles(bundles []*ManagedBundle) {
+	sort.SliceStable(bundles, func(i, j int) bool { return bundles[i].CreatedAt.Before(bundles[j].CreatedAt) })
+}
+
+type memoryStore struct {
+	mtx     sync.RWMutex
+	bundles map[string]*ManagedBundle
+}
+
+// NewMemoryStore returns a store that keeps the bundles in memory, mostly useful for tests.
+func NewMemoryStore() BundleStore {
+	return &memoryStore{bundles: make(map[string]*ManagedBundle)}
+}
+
+func (self *memoryStore) Save(ctx context.Context, b *ManagedBundle) error {
+	self.mtx.Lock()
+	defer self.mtx.Unlock()
+	self.bundles[b.ID] = b.copy()
+	return nil
+}
+
+func (self *memoryStore) Get(ctx context.Context, id string) (*ManagedBundle, error) {
+	self.mtx.RLock()
+	defer self.mtx.RUnlock()
+	b, ok := self.bundles[id]
+	if !ok {
+		return nil, errors.Wrapf(ErrBundleNotFound, "id:%v", id)
+	}
+	return b.copy(), nil
+}
+
+func (self *memoryStore) List(ctx context.Context, filter BundleFilter) ([]*ManagedBundle, error) {
+	self.mtx.RLock()
+	defer self.mtx.RUnlock()
+	var res []*ManagedBundle
+	for _, b := range self.bundles {
+		if filter.Match(b) {
+			res = append(res, b.copy())
+		}
+	}
+	SortBundles(res)
+	return res, nil
+}
+
+func (self *memoryStore) Delete(ctx context.Context, id string) error {
+	self.mtx.Lock()
+	defer self.mtx.Unlock()
+	delete(self.bundles, id)
+	return nil
+}
+
+func (self *memoryStore) Close() error {
+	return nil
+}
+
+// SetStore makes the manager save every bundle change to the store.
+func (self *BundleManager) SetStore(store BundleStore) {
+	self.mtx.Lock()
+	defer self.mtx.Unlock()
+	self.store = store
+}
+
+// errInterruptedSubmit is the cause recorded for the bundles restored in the submitted state.
+var errInterruptedSubmit = errors.New("restored after an interrupted submission")
+
+// Restore loads the bundles that are not in a terminal state from the store,
+// i.e. the in-flight bundles after a crash, and returns their IDs.
+// The submission of the bundles stored as submitted was interrupted so it is unknown whether the relay got them.
+// These are restored as pending so that the inclusion checks and the expiry cover them,
+// and they can be submitted again.
+func (self *BundleManager) Restore(ctx context.Context) ([]string, error) {
+	self.mtx.RLock()
+	store := self.store
+	self.mtx.RUnlock()
+	if store == nil {
+		return nil, errors.New("manager has no store")
+	}
+
+	bundles, err := store.List(ctx, BundleFilter{
+		States: []BundleState{BundleBuilt, BundleSimulated, BundleSubmitted, BundlePending},
+	})
+	if err != nil {
+		return nil, errors.Wrap(err, "list stored bundles")
+	}
+
+	self.mtx.Lock()
+	var ids, interrupted []string
+	for _, b := range bundles {
+		if _, ok := self.bundles[b.ID]; ok {
+			continue
+		}
+		self.bundles[b.ID] = b
+		if b.BundleHash != "" {
+			self.byHash[b.BundleHash] = b.ID
+		}
+		ids = append(ids, b.ID)
+		if b.State == BundleSubmitted {
+			interrupted = append(interrupted, b.ID)
+		}
+	}
+	self.mtx.Unlock()
+
+	for _, id := range interrupted {
+		if err := self.transition(id, BundlePending, 0, errInterruptedSubmit); err != nil {
+			return nil, err
+		}
+	}
+	return ids, nil
+}
+
+// persist saves a snapshot of the bundle when the manager has a store.
+func (self *BundleManager) persist(id string) error {
+	self.mtx.RLock()
+	store := self.store
+	b, ok := self.bundles[id]
+	if ok {
+		b = b.copy()
+	}
+	self.mtx.RUnlock()
+	if store == nil || !ok {
+		return nil
+	}
+	return errors.Wrapf(store.Save(context.Background(), b), "persist bundle id:%v", id)
+}
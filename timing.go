@@ -0,0 +1,148 @@
+// Copyright (c) The Cryptorium Authors.
+// Licensed under the MIT License.
+
+package flashbot
+
+import (
+	"context"
+	"crypto/tls"
+	"net/http/httptrace"
+	"sync"
+	"time"
+)
+
+// RequestKind tells the submissions and the simulations apart independent of the configured method names.
+type RequestKind string
+
+const (
+	RequestSubmit   RequestKind = "submit"
+	RequestSimulate RequestKind = "simulate"
+	RequestOther    RequestKind = "other"
+)
+
+// RequestTiming is the time spent in each phase of a relay request.
+// The DNS, Connect and TLS phases are zero when the request reused a kept alive connection.
+type RequestTiming struct {
+	Method string
+	Kind   RequestKind
+	DNS    time.Duration
+	// Connect is the TCP connect time.
+	Connect time.Duration
+	TLS     time.Duration
+	// TTFB is the time from sending the request until the first response byte.
+	TTFB time.Duration
+	// Total includes reading the whole response.
+	Total  time.Duration
+	Reused bool
+	// Attempts is the number of attempts including the retries, the phases are of the last one.
+	Attempts int
+}
+
+// requestTrace collects the phase times of a single request.
+// The dial callbacks can run on another goroutine than the request so all fields are guarded.
+type requestTrace struct {
+	fb *Flashbot
+
+	mtx       sync.Mutex
+	start     time.Time
+	dnsStart  time.Time
+	connStart time.Time
+	tlsStart  time.Time
+	wrote     time.Time
+	timing    RequestTiming
+}
+
+func newRequestTrace(fb *Flashbot) *requestTrace {
+	return &requestTrace{fb: fb, start: time.Now()}
+}
+
+func (self *requestTrace) context(ctx context.Context) context.Context {
+	return httptrace.WithClientTrace(ctx, &httptrace.ClientTrace{
+		DNSStart: func(httptrace.DNSStartInfo) {
+			self.mtx.Lock()
+			defer self.mtx.Unlock()
+			self.dnsStart = time.Now()
+		},
+		DNSDone: func(httptrace.DNSDoneInfo) {
+			self.mtx.Lock()
+			defer self.mtx.Unlock()
+			self.timing.DNS = time.Since(self.dnsStart)
+		},
+		ConnectStart: func(network, addr string) {
+			self.mtx.Lock()
+			defer self.mtx.Unlock()
+			self.connStart = time.Now()
+		},
+		ConnectDone: func(network, addr string, err error) {
+			self.mtx.Lock()
+			defer self.mtx.Unlock()
+			self.timing.Connect = time.Since(self.connStart)
+		},
+		TLSHandshakeStart: func() {
+			self.mtx.Lock()
+			defer self.mtx.Unlock()
+			self.tlsStart = time.Now()
+		},
+		TLSHandshakeDone: func(state tls.ConnectionState, err error) {
+			self.mtx.Lock()
+			took := time.Since(self.tlsStart)
+			self.timing.TLS = took
+			self.mtx.Unlock()
+			self.fb.recordHandshake(state, took, err)
+		},
+		GotConn: func(info httptrace.GotConnInfo) {
+			self.mtx.Lock()
+			defer self.mtx.Unlock()
+			self.timing.Reused = info.Reused
+		},
+		WroteRequest: func(httptrace.WroteRequestInfo) {
+			self.mtx.Lock()
+			defer self.mtx.Unlock()
+			self.wrote = time.Now()
+		},
+		GotFirstResponseByte: func() {
+			self.mtx.Lock()
+			defer self.mtx.Unlock()
+			if !self.wrote.IsZero() {
+				self.timing.TTFB = time.Since(self.wrote)
+			}
+		},
+	})
+}
+
+// done returns the timing with the total time until now.
+func (self *requestTrace) done(method string) RequestTiming {
+	self.mtx.Lock()
+	defer self.mtx.Unlock()
+	t := self.timing
+	t.Method = method
+	t.Total = time.Since(self.start)
+	return t
+}
+
+// LastTiming returns the timing of the last relay request.
+func (self *Flashbot) LastTiming() RequestTiming {
+	self.mtx.RLock()
+	defer self.mtx.RUnlock()
+	return self.lastTiming
+}
+
+func (self *Flashbot) recordTiming(t RequestTiming) {
+	self.mtx.Lock()
+	self.lastTiming = t
+	self.mtx.Unlock()
+	if self.api.OnTiming != nil {
+		self.api.OnTiming(t)
+	}
+	if m := self.api.Metrics; m != nil {
+		labels := []string{"relay", self.api.URL, "method", t.Method, "kind", string(t.Kind)}
+		// The connection phases are skipped for the reused connections to not skew the histograms to zero.
+		if !t.Reused {
+			m.Observe(MetricRequestPhase, t.DNS.Seconds(), append(labels, "phase", "dns")...)
+			m.Observe(MetricRequestPhase, t.Connect.Seconds(), append(labels, "phase", "connect")...)
+			m.Observe(MetricRequestPhase, t.TLS.Seconds(), append(labels, "phase", "tls")...)
+		}
+		m.Observe(MetricRequestPhase, t.TTFB.Seconds(), append(labels, "phase", "ttfb")...)
+		m.Observe(MetricRequestPhase, t.Total.Seconds(), append(labels, "phase", "total")...)
+	}
+}
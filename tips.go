@@ -0,0 +1,151 @@
+// Copyright (c) The Cryptorium Authors.
+// Licensed under the MIT License.
+
+package flashbot
+
+import (
+	"context"
+	"math/big"
+	"sort"
+	"sync"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/common/hexutil"
+	"github.com/pkg/errors"
+)
+
+// FeeHistory is the eth_feeHistory result which the ethclient of the used go-ethereum version predates.
+type FeeHistory struct {
+	OldestBlock *big.Int
+	// Reward are the tips at the requested percentiles for each block.
+	Reward       [][]*big.Int
+	BaseFee      []*big.Int
+	GasUsedRatio []float64
+}
+
+// FeeHistoryBackend is implemented by Node.
+type FeeHistoryBackend interface {
+	FeeHistory(ctx context.Context, blockCount uint64, lastBlock *big.Int, rewardPercentiles []float64) (*FeeHistory, error)
+}
+
+// FeeHistory returns the fee history of the blockCount blocks up to lastBlock, the latest when nil.
+func (self *Node) FeeHistory(ctx context.Context, blockCount uint64, lastBlock *big.Int, rewardPercentiles []float64) (*FeeHistory, error) {
+	var res struct {
+		OldestBlock  *hexutil.Big     `json:"oldestBlock"`
+		Reward       [][]*hexutil.Big `json:"reward"`
+		BaseFee      []*hexutil.Big   `json:"baseFeePerGas"`
+		GasUsedRatio []float64        `json:"gasUsedRatio"`
+	}
+	last := "latest"
+	if lastBlock != nil {
+		last = hexutil.EncodeBig(lastBlock)
+	}
+	if err := self.rpc.CallContext(ctx, &res, "eth_feeHistory", hexutil.Uint(blockCount), last, rewardPercentiles); err != nil {
+		return nil, errors.Wrap(err, "eth_feeHistory request")
+	}
+	if res.OldestBlock == nil {
+		return nil, errors.New("eth_feeHistory returned no oldest block")
+	}
+	h := &FeeHistory{OldestBlock: res.OldestBlock.ToInt(), GasUsedRatio: res.GasUsedRatio}
+	for _, r := range res.Reward {
+		rewards := make([]*big.Int, len(r))
+		for i, v := range r {
+			rewards[i] = v.ToInt()
+		}
+		h.Reward = append(h.Reward, rewards)
+	}
+	for _, b := range res.BaseFee {
+		h.BaseFee = append(h.BaseFee, b.ToInt())
+	}
+	return h, nil
+}
+
+var _ FeeHistoryBackend = (*Node)(nil)
+
+type TipOracleConfig struct {
+	// Blocks is the number of the latest blocks to sample, 10 by default.
+	Blocks uint64
+	// Percentile of the tips paid within each block, 60 by default.
+	Percentile float64
+	// Min and Max clamp the suggested tip, no clamping when nil.
+	Min *big.Int
+	Max *big.Int
+}
+
+// TipOracle suggests a competitive priority tip from the tips paid in the latest blocks.
+type TipOracle struct {
+	backend FeeHistoryBackend
+	cfg     TipOracleConfig
+}
+
+func NewTipOracle(backend FeeHistoryBackend, cfg TipOracleConfig) (*TipOracle, error) {
+	if cfg.Blocks == 0 {
+		cfg.Blocks = 10
+	}
+	if cfg.Percentile == 0 {
+		cfg.Percentile = 60
+	}
+	if cfg.Percentile < 0 || cfg.Percentile > 100 {
+		return nil, errors.Errorf("percentile should be between 0 and 100:%v", cfg.Percentile)
+	}
+	if cfg.Min != nil && cfg.Max != nil && cfg.Min.Cmp(cfg.Max) > 0 {
+		return nil, errors.Errorf("min tip:%v higher than the max:%v", cfg.Min, cfg.Max)
+	}
+	return &TipOracle{backend: backend, cfg: cfg}, nil
+}
+
+// SuggestTip returns the median across the sampled blocks of the tip at the configured percentile.
+// The empty blocks are skipped and when all are empty the min tip, or zero, is returned.
+func (self *TipOracle) SuggestTip(ctx context.Context) (*big.Int, error) {
+	h, err := self.backend.FeeHistory(ctx, self.cfg.Blocks, nil, []float64{self.cfg.Percentile})
+	if err != nil {
+		return nil, err
+	}
+	var tips []*big.Int
+	for i, r := range h.Reward {
+		if i < len(h.GasUsedRatio) && h.GasUsedRatio[i] == 0 {
+			continue
+		}
+		if len(r) > 0 && r[0] != nil {
+			tips = append(tips, r[0])
+		}
+	}
+	tip := new(big.Int)
+	if len(tips) > 0 {
+		sort.Slice(tips, func(i, j int) bool { return tips[i].Cmp(tips[j]) < 0 })
+		tip.Set(tips[len(tips)/2])
+	}
+	if self.cfg.Min != nil && tip.Cmp(self.cfg.Min) < 0 {
+		tip.Set(self.cfg.Min)
+	}
+	if self.cfg.Max != nil && tip.Cmp(self.cfg.Max) > 0 {
+		tip.Set(self.cfg.Max)
+	}
+	return tip, nil
+}
+
+// TxOption returns an option that sets the suggested tip for the specs without one.
+// The tip is fetched once by the first spec and reused by the rest so an option should be created for each bundle.
+// It should come before WithAutoFees so that the fee cap includes the tip.
+func (self *TipOracle) TxOption() TxOption {
+	var (
+		mtx sync.Mutex
+		tip *big.Int
+	)
+	return func(ctx context.Context, _ common.Address, spec *TxSpec) error {
+		if spec.GasTipCap != nil {
+			return nil
+		}
+		mtx.Lock()
+		defer mtx.Unlock()
+		if tip == nil {
+			v, err := self.SuggestTip(ctx)
+			if err != nil {
+				return errors.Wrap(err, "suggest tip")
+			}
+			tip = v
+		}
+		spec.GasTipCap = new(big.Int).Set(tip)
+		return nil
+	}
+}
@@ -0,0 +1,112 @@
+// Copyright (c) The Cryptorium Authors.
+// Licensed under the MIT License.
+
+package flashbot
+
+import (
+	"context"
+	"encoding/json"
+	"math/big"
+	"testing"
+
+	"github.com/cryptoriums/packages/testutil"
+	"github.com/ethereum/go-ethereum/common"
+)
+
+type feeHistoryMock struct {
+	history *FeeHistory
+	calls   *int
+}
+
+func (self feeHistoryMock) FeeHistory(ctx context.Context, blockCount uint64, lastBlock *big.Int, rewardPercentiles []float64) (*FeeHistory, error) {
+	if self.calls != nil {
+		*self.calls++
+	}
+	return self.history, nil
+}
+
+func TestNodeFeeHistory(t *testing.T) {
+	mock := newRelayMock(t, func(method string, params json.RawMessage) (interface{}, *jsonError) {
+		var args []interface{}
+		if err := json.Unmarshal(params, &args); err != nil {
+			return nil, &jsonError{Code: -32602, Message: err.Error()}
+		}
+		if method != "eth_feeHistory" || args[0] != "0x2" || args[1] != "latest" {
+			return nil, &jsonError{Code: -32601, Message: "unexpected request"}
+		}
+		return map[string]interface{}{
+			"oldestBlock":   "0x64",
+			"reward":        [][]string{{"0x1"}, {"0x2"}},
+			"baseFeePerGas": []string{"0x10", "0x11", "0x12"},
+			"gasUsedRatio":  []float64{0.5, 0.6},
+		}, nil
+	})
+	node, err := NewNode(context.Background(), mock.URL)
+	testutil.Ok(t, err)
+
+	h, err := node.FeeHistory(context.Background(), 2, nil, []float64{50})
+	testutil.Ok(t, err)
+	testutil.Equals(t, big.NewInt(100), h.OldestBlock)
+	testutil.Equals(t, [][]*big.Int{{big.NewInt(1)}, {big.NewInt(2)}}, h.Reward)
+	testutil.Equals(t, 3, len(h.BaseFee))
+	testutil.Equals(t, []float64{0.5, 0.6}, h.GasUsedRatio)
+}
+
+func TestSuggestTip(t *testing.T) {
+	backend := feeHistoryMock{history: &FeeHistory{
+		Reward: [][]*big.Int{
+			{big.NewInt(3)}, {big.NewInt(0)}, {big.NewInt(1)}, {big.NewInt(2)}, {big.NewInt(5)},
+		},
+		GasUsedRatio: []float64{0.5, 0, 0.5, 0.5, 0.5},
+	}}
+
+	oracle, err := NewTipOracle(backend, TipOracleConfig{})
+	testutil.Ok(t, err)
+	tip, err := oracle.SuggestTip(context.Background())
+	testutil.Ok(t, err)
+	// The empty block is skipped so the median is taken from 1, 2, 3, 5.
+	testutil.Equals(t, big.NewInt(3), tip)
+
+	oracle, err = NewTipOracle(backend, TipOracleConfig{Max: big.NewInt(2)})
+	testutil.Ok(t, err)
+	tip, err = oracle.SuggestTip(context.Background())
+	testutil.Ok(t, err)
+	testutil.Equals(t, big.NewInt(2), tip)
+
+	oracle, err = NewTipOracle(feeHistoryMock{history: &FeeHistory{}}, TipOracleConfig{Min: big.NewInt(7)})
+	testutil.Ok(t, err)
+	tip, err = oracle.SuggestTip(context.Background())
+	testutil.Ok(t, err)
+	testutil.Equals(t, big.NewInt(7), tip)
+
+	_, err = NewTipOracle(backend, TipOracleConfig{Percentile: 101})
+	testutil.NotOk(t, err)
+	_, err = NewTipOracle(backend, TipOracleConfig{Min: big.NewInt(2), Max: big.NewInt(1)})
+	testutil.NotOk(t, err)
+}
+
+func TestTipOracleTxOption(t *testing.T) {
+	var calls int
+	oracle, err := NewTipOracle(feeHistoryMock{history: &FeeHistory{Reward: [][]*big.Int{{big.NewInt(4)}}}, calls: &calls}, TipOracleConfig{})
+	testutil.Ok(t, err)
+
+	opt := oracle.TxOption()
+	spec := &TxSpec{}
+	testutil.Ok(t, opt(context.Background(), common.Address{}, spec))
+	testutil.Equals(t, big.NewInt(4), spec.GasTipCap)
+
+	spec = &TxSpec{GasTipCap: big.NewInt(9)}
+	testutil.Ok(t, opt(context.Background(), common.Address{}, spec))
+	testutil.Equals(t, big.NewInt(9), spec.GasTipCap)
+
+	// The tip is fetched once for all the specs of the bundle.
+	other := &TxSpec{}
+	testutil.Ok(t, opt(context.Background(), common.Address{}, other))
+	testutil.Equals(t, big.NewInt(4), other.GasTipCap)
+	testutil.Equals(t, 1, calls)
+	other.GasTipCap.SetInt64(5)
+	spec = &TxSpec{}
+	testutil.Ok(t, opt(context.Background(), common.Address{}, spec))
+	testutil.Equals(t, big.NewInt(4), spec.GasTipCap)
+	testutil.Equals(t, 1, calls)
+}
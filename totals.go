@@ -0,0 +1,83 @@
+// Copyright (c) The Cryptorium Authors.
+// Licensed under the MIT License.
+
+package flashbot
+
+import (
+	"math/big"
+
+	"github.com/pkg/errors"
+)
+
+// ResultTotals are the sums of the per TX metadata of a simulation result in wei.
+type ResultTotals struct {
+	GasUsed           uint64
+	GasFees           *big.Int
+	CoinbaseDiff      *big.Int
+	EthSentToCoinbase *big.Int
+	// NetProfit is the coinbase diff minus the gas fees,
+	// the same as the default profit of SimulateAndSend.
+	NetProfit *big.Int
+}
+
+// Totals sums the metadata of all TXs.
+func (self *Result) Totals() (*ResultTotals, error) {
+	res := &ResultTotals{
+		GasFees:           new(big.Int),
+		CoinbaseDiff:      new(big.Int),
+		EthSentToCoinbase: new(big.Int),
+	}
+	for i, tx := range self.Results {
+		res.GasUsed += tx.GasUsed
+		for _, f := range []struct {
+			name string
+			val  string
+			dst  *big.Int
+		}{
+			{"gas fees", tx.GasFees, res.GasFees},
+			{"coinbase diff", tx.CoinbaseDiff, res.CoinbaseDiff},
+			{"eth sent to coinbase", tx.EthSentToCoinbase, res.EthSentToCoinbase},
+		} {
+			v, err := parseWei(f.name, f.val)
+			if err != nil {
+				return nil, errors.Wrapf(err, "TX index:%v", i)
+			}
+			f.dst.Add(f.dst, v)
+		}
+	}
+	res.NetProfit = new(big.Int).Sub(res.CoinbaseDiff, res.GasFees)
+	return res, nil
+}
+
+func (self *Result) TotalGasUsed() uint64 {
+	var gas uint64
+	for _, tx := range self.Results {
+		gas += tx.GasUsed
+	}
+	return gas
+}
+
+func (self *Result) TotalGasFees() (*big.Int, error) {
+	t, err := self.Totals()
+	if err != nil {
+		return nil, err
+	}
+	return t.GasFees, nil
+}
+
+func (self *Result) TotalCoinbaseDiff() (*big.Int, error) {
+	t, err := self.Totals()
+	if err != nil {
+		return nil, err
+	}
+	return t.CoinbaseDiff, nil
+}
+
+// NetProfit returns the coinbase diff minus the gas fees of all TXs.
+func (self *Result) NetProfit() (*big.Int, error) {
+	t, err := self.Totals()
+	if err != nil {
+		return nil, err
+	}
+	return t.NetProfit, nil
+}
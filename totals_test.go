@@ -0,0 +1,41 @@
+// Copyright (c) The Cryptorium Authors.
+// Licensed under the MIT License.
+
+package flashbot
+
+import (
+	"math/big"
+	"testing"
+
+	"github.com/cryptoriums/packages/testutil"
+)
+
+func TestResultTotals(t *testing.T) {
+	result := &Result{Results: []TxResult{
+		{GasUsed: 21_000, Metadata: Metadata{CoinbaseDiff: "300", GasFees: "200", EthSentToCoinbase: "100"}},
+		{GasUsed: 50_000, Metadata: Metadata{CoinbaseDiff: "500", GasFees: "500"}},
+	}}
+	totals, err := result.Totals()
+	testutil.Ok(t, err)
+	testutil.Equals(t, &ResultTotals{
+		GasUsed:           71_000,
+		GasFees:           big.NewInt(700),
+		CoinbaseDiff:      big.NewInt(800),
+		EthSentToCoinbase: big.NewInt(100),
+		NetProfit:         big.NewInt(100),
+	}, totals)
+	testutil.Equals(t, uint64(71_000), result.TotalGasUsed())
+
+	fees, err := result.TotalGasFees()
+	testutil.Ok(t, err)
+	testutil.Equals(t, big.NewInt(700), fees)
+	diff, err := result.TotalCoinbaseDiff()
+	testutil.Ok(t, err)
+	testutil.Equals(t, big.NewInt(800), diff)
+	profit, err := result.NetProfit()
+	testutil.Ok(t, err)
+	testutil.Equals(t, big.NewInt(100), profit)
+
+	_, err = (&Result{Results: []TxResult{{Metadata: Metadata{GasFees: "1.5"}}}}).Totals()
+	testutil.NotOk(t, err)
+}
@@ -0,0 +1,247 @@
+// Copyright (c) The Cryptorium Authors.
+// Licensed under the MIT License.
+
+package flashbot
+
+import (
+	"context"
+	"crypto/tls"
+	"net"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/pkg/errors"
+)
+
+const (
+	defaultMaxIdleConns        = 100
+	defaultMaxIdleConnsPerHost = 10
+	defaultIdleConnTimeout     = 90 * time.Second
+	defaultDialTimeout         = 30 * time.Second
+	defaultTLSHandshakeTimeout = 10 * time.Second
+	defaultTLSSessionCacheSize = 64
+)
+
+// TransportConfig tunes the connection pool of the relay HTTP client.
+type TransportConfig struct {
+	// MaxIdleConns is the max number of idle connections across all hosts, 100 by default.
+	MaxIdleConns int
+	// MaxIdleConnsPerHost is the max number of idle connections kept to the relay, 10 by default.
+	MaxIdleConnsPerHost int
+	// IdleConnTimeout is how long an idle connection stays in the pool, 90s by default.
+	IdleConnTimeout time.Duration
+	// ForceHTTP2 attempts HTTP/2 through ALPN and falls back to HTTP/1.1 when the relay doesn't support it.
+	ForceHTTP2 bool
+	// NoDelay sets TCP_NODELAY on the relay connections, the Go default is on.
+	NoDelay *bool
+	// DialTimeout is the TCP connect timeout, 30s by default.
+	DialTimeout time.Duration
+	// TLSHandshakeTimeout is 10s by default.
+	TLSHandshakeTimeout time.Duration
+	// TLSSessionCacheSize is the number of TLS sessions kept for resumption, 64 by default and disabled when negative.
+	// A resumed session skips the full handshake when a new connection is opened.
+	TLSSessionCacheSize int
+	// Resolver skips the DNS lookups for the pre-resolved and pinned hosts.
+	Resolver *PinnedResolver
+	// InsecureSkipVerify disables the verification of the relay certificate,
+	// only for testing against relays with self-signed certificates.
+	InsecureSkipVerify bool
+}
+
+// NewTransport returns the transport used for the relay requests.
+// The connections are kept alive and reused between the requests
+// and the relay certificate is verified unless InsecureSkipVerify is set.
+func NewTransport(cfg TransportConfig) *http.Transport {
+	if cfg.MaxIdleConns == 0 {
+		cfg.MaxIdleConns = defaultMaxIdleConns
+	}
+	if cfg.MaxIdleConnsPerHost == 0 {
+		cfg.MaxIdleConnsPerHost = defaultMaxIdleConnsPerHost
+	}
+	if cfg.IdleConnTimeout == 0 {
+		cfg.IdleConnTimeout = defaultIdleConnTimeout
+	}
+	if cfg.DialTimeout == 0 {
+		cfg.DialTimeout = defaultDialTimeout
+	}
+	if cfg.TLSHandshakeTimeout == 0 {
+		cfg.TLSHandshakeTimeout = defaultTLSHandshakeTimeout
+	}
+	tlsCfg := &tls.Config{InsecureSkipVerify: cfg.InsecureSkipVerify}
+	switch {
+	case cfg.TLSSessionCacheSize == 0:
+		tlsCfg.ClientSessionCache = tls.NewLRUClientSessionCache(defaultTLSSessionCacheSize)
+	case cfg.TLSSessionCacheSize > 0:
+		tlsCfg.ClientSessionCache = tls.NewLRUClientSessionCache(cfg.TLSSessionCacheSize)
+	}
+	dialer := &net.Dialer{
+		Timeout:   cfg.DialTimeout,
+		KeepAlive: 30 * time.Second,
+	}
+	dial := DialFunc(dialer.DialContext)
+	if cfg.Resolver != nil {
+		dial = cfg.Resolver.Dial(dial)
+	}
+	if cfg.NoDelay != nil {
+		noDelay := *cfg.NoDelay
+		base := dial
+		dial = func(ctx context.Context, network, addr string) (net.Conn, error) {
+			conn, err := base(ctx, network, addr)
+			if err != nil {
+				return nil, err
+			}
+			if tcp, ok := conn.(*net.TCPConn); ok {
+				if err := tcp.SetNoDelay(noDelay); err != nil {
+					conn.Close()
+					return nil, err
+				}
+			}
+			return conn, nil
+		}
+	}
+	return &http.Transport{
+		Proxy:                 http.ProxyFromEnvironment,
+		DialContext:           dial,
+		ForceAttemptHTTP2:     cfg.ForceHTTP2,
+		MaxIdleConns:          cfg.MaxIdleConns,
+		MaxIdleConnsPerHost:   cfg.MaxIdleConnsPerHost,
+		IdleConnTimeout:       cfg.IdleConnTimeout,
+		TLSHandshakeTimeout:   cfg.TLSHandshakeTimeout,
+		ExpectContinueTimeout: time.Second,
+		TLSClientConfig:       tlsCfg,
+	}
+}
+
+// ConnInfo describes the connection negotiated with the relay for diagnostics.
+type ConnInfo struct {
+	// Proto is the HTTP protocol like HTTP/1.1 or HTTP/2.0.
+	Proto string
+	// TLSVersion and CipherSuite are the tls package constants, zero for plain HTTP.
+	TLSVersion  uint16
+	CipherSuite uint16
+	// ALPN is the application protocol negotiated in the TLS handshake.
+	ALPN string
+}
+
+func connInfo(resp *http.Response) ConnInfo {
+	info := ConnInfo{Proto: resp.Proto}
+	if resp.TLS != nil {
+		info.TLSVersion = resp.TLS.Version
+		info.CipherSuite = resp.TLS.CipherSuite
+		info.ALPN = resp.TLS.NegotiatedProtocol
+	}
+	return info
+}
+
+// ConnInfo returns the connection details of the last relay response.
+func (self *Flashbot) ConnInfo() ConnInfo {
+	self.mtx.RLock()
+	defer self.mtx.RUnlock()
+	return self.connInfo
+}
+
+// httpClient returns the client shared by all requests to the relay.
+// It is created on first use so that the Api can still be changed after New.
+func (self *Flashbot) httpClient() *http.Client {
+	self.clientOnce.Do(func() {
+		self.client = self.api.Client
+		if self.client == nil {
+			self.client = &http.Client{Transport: NewTransport(self.api.Transport)}
+		}
+	})
+	return self.client
+}
+
+// CloseIdleConnections closes the pooled connections to the relay that are not in use.
+func (self *Flashbot) CloseIdleConnections() {
+	self.httpClient().CloseIdleConnections()
+}
+
+// HandshakeStats are the TLS handshakes done for the relay requests.
+type HandshakeStats struct {
+	Handshakes uint64
+	// Resumed is the number of handshakes that reused a TLS session.
+	Resumed uint64
+	Failed  uint64
+	// Total is the time spent in all handshakes.
+	Total time.Duration
+	Last  time.Duration
+}
+
+// Avg returns the average handshake time.
+func (self HandshakeStats) Avg() time.Duration {
+	if self.Handshakes == 0 {
+		return 0
+	}
+	return self.Total / time.Duration(self.Handshakes)
+}
+
+// HandshakeStats returns the TLS handshakes done so far.
+// The requests sent over a kept alive connection don't do any handshake.
+func (self *Flashbot) HandshakeStats() HandshakeStats {
+	self.mtx.RLock()
+	defer self.mtx.RUnlock()
+	return self.handshakes
+}
+
+func (self *Flashbot) recordHandshake(state tls.ConnectionState, took time.Duration, err error) {
+	if m := self.api.Metrics; m != nil {
+		if err != nil {
+			m.Add(MetricHandshakeFailures, 1, "relay", self.api.URL)
+		} else {
+			m.Observe(MetricHandshake, took.Seconds(), "relay", self.api.URL, "resumed", strconv.FormatBool(state.DidResume))
+		}
+	}
+
+	self.mtx.Lock()
+	defer self.mtx.Unlock()
+	if err != nil {
+		self.handshakes.Failed++
+		return
+	}
+	self.handshakes.Handshakes++
+	self.handshakes.Total += took
+	self.handshakes.Last = took
+	if state.DidResume {
+		self.handshakes.Resumed++
+	}
+}
+
+// Warm opens a connection to the relay ahead of a submission so that it doesn't wait for the TCP and TLS handshakes.
+// The reply status is ignored as any response means the connection is established.
+func (self *Flashbot) Warm(ctx context.Context) error {
+	if self.drain.isClosed() {
+		return ErrClosed
+	}
+	req, err := http.NewRequestWithContext(newRequestTrace(self).context(ctx), http.MethodHead, self.api.URL, nil)
+	if err != nil {
+		return errors.Wrap(err, "create warm up request")
+	}
+	resp, err := self.httpClient().Do(req)
+	if err != nil {
+		return errors.Wrap(err, "warm up request")
+	}
+	return resp.Body.Close()
+}
+
+// KeepWarm calls Warm every interval until the context is done or the client is closed
+// so that the pooled connection doesn't hit the idle timeout, 30s by default.
+// The errors are ignored as the next request just opens a new connection.
+func (self *Flashbot) KeepWarm(ctx context.Context, interval time.Duration) {
+	if interval <= 0 {
+		interval = 30 * time.Second
+	}
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		if err := self.Warm(ctx); errors.Is(err, ErrClosed) {
+			return
+		}
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
@@ -0,0 +1,178 @@
+// Copyright (c) The Cryptorium Authors.
+// Licensed under the MIT License.
+
+package flashbot
+
+import (
+	"context"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/cryptoriums/packages/testutil"
+)
+
+func TestConnectionReuse(t *testing.T) {
+	var conns int32
+	srv := httptest.NewUnstartedServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("content-type", "application/json")
+		_, _ = w.Write([]byte(`{"jsonrpc":"2.0","id":1,"result":{"bundleHash":"0xbundle"}}`))
+	}))
+	srv.Config.ConnState = func(c net.Conn, s http.ConnState) {
+		if s == http.StateNew {
+			atomic.AddInt32(&conns, 1)
+		}
+	}
+	srv.Start()
+	defer srv.Close()
+
+	fb := newTestFlashbot(t, srv.URL)
+	fb.api.Timeout = time.Second
+	for i := 0; i < 5; i++ {
+		_, err := fb.req(context.Background(), "eth_sendBundle")
+		testutil.Ok(t, err)
+	}
+	testutil.Equals(t, int32(1), atomic.LoadInt32(&conns))
+	fb.CloseIdleConnections()
+
+	// A shared client overrides the one created for the relay.
+	var used int32
+	client := &http.Client{Transport: roundTripFunc(func(r *http.Request) (*http.Response, error) {
+		atomic.AddInt32(&used, 1)
+		return http.DefaultTransport.RoundTrip(r)
+	})}
+	fb = newTestFlashbot(t, srv.URL)
+	fb.api.Client = client
+	_, err := fb.req(context.Background(), "eth_sendBundle")
+	testutil.Ok(t, err)
+	testutil.Equals(t, int32(1), atomic.LoadInt32(&used))
+}
+
+func TestNewTransport(t *testing.T) {
+	tr := NewTransport(TransportConfig{})
+	testutil.Equals(t, defaultMaxIdleConns, tr.MaxIdleConns)
+	testutil.Equals(t, defaultMaxIdleConnsPerHost, tr.MaxIdleConnsPerHost)
+	testutil.Equals(t, defaultIdleConnTimeout, tr.IdleConnTimeout)
+
+	tr = NewTransport(TransportConfig{MaxIdleConns: 3, MaxIdleConnsPerHost: 2, IdleConnTimeout: time.Second})
+	testutil.Equals(t, 3, tr.MaxIdleConns)
+	testutil.Equals(t, 2, tr.MaxIdleConnsPerHost)
+	testutil.Equals(t, time.Second, tr.IdleConnTimeout)
+}
+
+type roundTripFunc func(*http.Request) (*http.Response, error)
+
+func (self roundTripFunc) RoundTrip(r *http.Request) (*http.Response, error) {
+	return self(r)
+}
+
+func TestTransportHTTP2(t *testing.T) {
+	srv := httptest.NewUnstartedServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		_, _ = w.Write([]byte(`{"jsonrpc":"2.0","id":1,"result":{"bundleHash":"0xbundle"}}`))
+	}))
+	srv.EnableHTTP2 = true
+	srv.StartTLS()
+	defer srv.Close()
+
+	// The test server certificate is self-signed.
+	fb := newTestFlashbot(t, srv.URL)
+	_, err := fb.req(context.Background(), "eth_sendBundle")
+	testutil.NotOk(t, err, "the certificate should be verified by default")
+
+	fb = newTestFlashbot(t, srv.URL)
+	fb.api.Transport.InsecureSkipVerify = true
+	_, err = fb.req(context.Background(), "eth_sendBundle")
+	testutil.Ok(t, err)
+	testutil.Equals(t, "HTTP/1.1", fb.ConnInfo().Proto)
+
+	noDelay := false
+	fb = newTestFlashbot(t, srv.URL)
+	fb.api.Transport = TransportConfig{ForceHTTP2: true, NoDelay: &noDelay, DialTimeout: time.Second, InsecureSkipVerify: true}
+	_, err = fb.req(context.Background(), "eth_sendBundle")
+	testutil.Ok(t, err)
+	info := fb.ConnInfo()
+	testutil.Equals(t, "HTTP/2.0", info.Proto)
+	testutil.Equals(t, "h2", info.ALPN)
+	testutil.Assert(t, info.TLSVersion != 0, "TLS version should be set")
+}
+
+func TestTLSResumption(t *testing.T) {
+	srv := httptest.NewTLSServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		_, _ = w.Write([]byte(`{"jsonrpc":"2.0","id":1,"result":{"bundleHash":"0xbundle"}}`))
+	}))
+	defer srv.Close()
+
+	fb := newTestFlashbot(t, srv.URL)
+	fb.api.Transport.InsecureSkipVerify = true
+	metrics := newMetricsMock()
+	fb.api.Metrics = metrics
+	testutil.Ok(t, fb.Warm(context.Background()))
+	_, err := fb.req(context.Background(), "eth_sendBundle")
+	testutil.Ok(t, err)
+	// The request reuses the warm connection.
+	stats := fb.HandshakeStats()
+	testutil.Equals(t, uint64(1), stats.Handshakes)
+	testutil.Equals(t, uint64(0), stats.Resumed)
+	testutil.Assert(t, stats.Avg() > 0, "handshake time should be measured")
+
+	fb.CloseIdleConnections()
+	_, err = fb.req(context.Background(), "eth_sendBundle")
+	testutil.Ok(t, err)
+	stats = fb.HandshakeStats()
+	testutil.Equals(t, uint64(2), stats.Handshakes)
+	testutil.Equals(t, uint64(1), stats.Resumed)
+	testutil.Equals(t, 1, len(metrics.get(MetricHandshake, "relay", srv.URL, "resumed", "false")))
+	testutil.Equals(t, 1, len(metrics.get(MetricHandshake, "relay", srv.URL, "resumed", "true")))
+
+	fb = newTestFlashbot(t, srv.URL)
+	fb.api.Transport = TransportConfig{TLSSessionCacheSize: -1, InsecureSkipVerify: true}
+	for i := 0; i < 2; i++ {
+		_, err = fb.req(context.Background(), "eth_sendBundle")
+		testutil.Ok(t, err)
+		fb.CloseIdleConnections()
+	}
+	testutil.Equals(t, uint64(0), fb.HandshakeStats().Resumed)
+}
+
+func TestRequestTiming(t *testing.T) {
+	srv := httptest.NewTLSServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		time.Sleep(20 * time.Millisecond)
+		_, _ = w.Write([]byte(`{"jsonrpc":"2.0","id":1,"result":{"bundleHash":"0xbundle"}}`))
+	}))
+	defer srv.Close()
+
+	fb := newTestFlashbot(t, srv.URL)
+	fb.api.Transport.InsecureSkipVerify = true
+	var timings []RequestTiming
+	fb.api.OnTiming = func(t RequestTiming) {
+		timings = append(timings, t)
+	}
+	metrics := newMetricsMock()
+	fb.api.Metrics = metrics
+	resp, err := fb.SendBundle(context.Background(), []string{"0x01"}, 10)
+	testutil.Ok(t, err)
+
+	timing := resp.Timing
+	testutil.Equals(t, "eth_sendBundle", timing.Method)
+	testutil.Equals(t, 1, timing.Attempts)
+	testutil.Assert(t, !timing.Reused, "first request should open a new connection")
+	testutil.Assert(t, timing.Connect > 0 && timing.TLS > 0, "connect and TLS should be measured:%+v", timing)
+	testutil.Assert(t, timing.TTFB >= 20*time.Millisecond, "TTFB should include the relay processing:%v", timing.TTFB)
+	testutil.Assert(t, timing.Total >= timing.TTFB+timing.TLS, "total should include all phases:%+v", timing)
+	testutil.Equals(t, timing, fb.LastTiming())
+
+	resp, err = fb.SendBundle(context.Background(), []string{"0x01"}, 10)
+	testutil.Ok(t, err)
+	testutil.Assert(t, resp.Timing.Reused, "second request should reuse the connection")
+	testutil.Equals(t, time.Duration(0), resp.Timing.TLS)
+	testutil.Equals(t, 2, len(timings))
+
+	labels := []string{"relay", srv.URL, "method", "eth_sendBundle", "kind", "submit", "phase"}
+	testutil.Equals(t, 2, len(metrics.get(MetricRequestPhase, append(labels, "total")...)))
+	testutil.Equals(t, 2, len(metrics.get(MetricRequestPhase, append(labels, "ttfb")...)))
+	// The reused connection has no TLS phase.
+	testutil.Equals(t, []float64{timing.TLS.Seconds()}, metrics.get(MetricRequestPhase, append(labels, "tls")...))
+}
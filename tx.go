@@ -0,0 +1,433 @@
+// Copyright (c) The Cryptorium Authors.
+// Licensed under the MIT License.
+
+package flashbot
+
+import (
+	"context"
+	"crypto/ecdsa"
+	"math/big"
+
+	"github.com/ethereum/go-ethereum"
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/common/hexutil"
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/crypto"
+	"github.com/ethereum/go-ethereum/rlp"
+	"github.com/pkg/errors"
+)
+
+const (
+	// SetCodeTxType is the EIP-7702 transaction type.
+	SetCodeTxType = 0x04
+	// setCodeAuthMagic is the EIP-7702 prefix of the authorization signing hash.
+	setCodeAuthMagic = 0x05
+)
+
+// SetCodeAuthorization is an EIP-7702 authorization tuple.
+// Once included it sets the code of the signing EOA to a delegation to Address.
+type SetCodeAuthorization struct {
+	ChainID *big.Int
+	Address common.Address
+	Nonce   uint64
+	V       uint8
+	R       *big.Int
+	S       *big.Int
+}
+
+// SetCodeTx is an EIP-7702 transaction.
+// The go-ethereum version used by this package predates the type
+// so it is encoded and signed here instead of through types.Transaction.
+type SetCodeTx struct {
+	ChainID    *big.Int
+	Nonce      uint64
+	GasTipCap  *big.Int
+	GasFeeCap  *big.Int
+	Gas        uint64
+	To         common.Address
+	Value      *big.Int
+	Data       []byte
+	AccessList types.AccessList
+	AuthList   []SetCodeAuthorization
+	V          *big.Int
+	R          *big.Int
+	S          *big.Int
+}
+
+// SigHash returns the hash which the authorizing EOA signs.
+func (self SetCodeAuthorization) SigHash() (common.Hash, error) {
+	enc, err := rlp.EncodeToBytes([]interface{}{
+		self.ChainID,
+		self.Address,
+		self.Nonce,
+	})
+	if err != nil {
+		return common.Hash{}, errors.Wrap(err, "rlp encode authorization")
+	}
+	return crypto.Keccak256Hash(append([]byte{setCodeAuthMagic}, enc...)), nil
+}
+
+// Authority recovers the address of the EOA that signed the authorization.
+func (self SetCodeAuthorization) Authority() (common.Address, error) {
+	sigHash, err := self.SigHash()
+	if err != nil {
+		return common.Address{}, err
+	}
+	return recoverAddress(sigHash, self.R, self.S, self.V)
+}
+
+// SigHash returns the hash which the transaction sender signs.
+func (self *SetCodeTx) SigHash() (common.Hash, error) {
+	enc, err := rlp.EncodeToBytes([]interface{}{
+		self.ChainID,
+		self.Nonce,
+		self.GasTipCap,
+		self.GasFeeCap,
+		self.Gas,
+		self.To,
+		self.Value,
+		self.Data,
+		self.AccessList,
+		self.authList(),
+	})
+	if err != nil {
+		return common.Hash{}, errors.Wrap(err, "rlp encode set code tx")
+	}
+	return crypto.Keccak256Hash(append([]byte{SetCodeTxType}, enc...)), nil
+}
+
+// MarshalBinary returns the typed envelope encoding of a signed transaction.
+func (self *SetCodeTx) MarshalBinary() ([]byte, error) {
+	if self.V == nil || self.R == nil || self.S == nil {
+		return nil, errors.New("transaction is not signed")
+	}
+	enc, err := rlp.EncodeToBytes([]interface{}{
+		self.ChainID,
+		self.Nonce,
+		self.GasTipCap,
+		self.GasFeeCap,
+		self.Gas,
+		self.To,
+		self.Value,
+		self.Data,
+		self.AccessList,
+		self.authList(),
+		self.V,
+		self.R,
+		self.S,
+	})
+	if err != nil {
+		return nil, errors.Wrap(err, "rlp encode set code tx")
+	}
+	return append([]byte{SetCodeTxType}, enc...), nil
+}
+
+// Hash returns the transaction hash of a signed transaction.
+func (self *SetCodeTx) Hash() (common.Hash, error) {
+	data, err := self.MarshalBinary()
+	if err != nil {
+		return common.Hash{}, err
+	}
+	return crypto.Keccak256Hash(data), nil
+}
+
+// Sender recovers the address that signed the transaction.
+func (self *SetCodeTx) Sender() (common.Address, error) {
+	if self.V == nil || self.R == nil || self.S == nil {
+		return common.Address{}, errors.New("transaction is not signed")
+	}
+	sigHash, err := self.SigHash()
+	if err != nil {
+		return common.Address{}, err
+	}
+	return recoverAddress(sigHash, self.R, self.S, uint8(self.V.Uint64()))
+}
+
+// authList makes sure an empty list is encoded as such and not as a nil string.
+func (self *SetCodeTx) authList() []SetCodeAuthorization {
+	if self.AuthList == nil {
+		return []SetCodeAuthorization{}
+	}
+	return self.AuthList
+}
+
+// SignSetCodeAuthorization signs an authorization that delegates the code of the key's EOA to the delegate address.
+// When the same EOA also sends the transaction carrying the authorization,
+// the nonce needs to be the transaction nonce + 1 as the sender nonce is incremented before the authorization is processed.
+func SignSetCodeAuthorization(prvKey *ecdsa.PrivateKey, netID int64, delegate common.Address, nonce uint64) (SetCodeAuthorization, error) {
+	if prvKey == nil {
+		return SetCodeAuthorization{}, errors.New("private key is not set")
+	}
+	auth := SetCodeAuthorization{
+		ChainID: big.NewInt(netID),
+		Address: delegate,
+		Nonce:   nonce,
+	}
+	sigHash, err := auth.SigHash()
+	if err != nil {
+		return SetCodeAuthorization{}, err
+	}
+	sig, err := crypto.Sign(sigHash[:], prvKey)
+	if err != nil {
+		return SetCodeAuthorization{}, errors.Wrap(err, "sign authorization")
+	}
+	auth.R = new(big.Int).SetBytes(sig[:32])
+	auth.S = new(big.Int).SetBytes(sig[32:64])
+	auth.V = sig[64]
+
+	return auth, nil
+}
+
+// SignSetCodeAuthorization signs an authorization with the local key.
+func (self *Flashbot) SignSetCodeAuthorization(netID int64, delegate common.Address, nonce uint64) (SetCodeAuthorization, error) {
+	return SignSetCodeAuthorization(self.PrvKey(), netID, delegate, nonce)
+}
+
+// NewSignedSetCodeTX creates an EIP-7702 transaction signed with the local key
+// and returns it together with its hex encoding as expected by the bundle params.
+func (self *Flashbot) NewSignedSetCodeTX(
+	netID int64,
+	nonce uint64,
+	to common.Address,
+	data []byte,
+	gasLimit uint64,
+	gasMaxFee *big.Int,
+	gasTip *big.Int,
+	value *big.Int,
+	auths []SetCodeAuthorization,
+) (*SetCodeTx, string, error) {
+	prvKey := self.PrvKey()
+	if prvKey == nil {
+		return nil, "", errors.New("private key is not set, external signers can't sign set code TXs")
+	}
+	if len(auths) == 0 {
+		return nil, "", errors.New("set code TXs require at least one authorization")
+	}
+	if gasMaxFee == nil || gasMaxFee.Sign() == 0 {
+		return nil, "", errors.New("for EIP1559 TXs the gasMaxFee should not be zero")
+	}
+	if gasTip == nil {
+		gasTip = new(big.Int)
+	}
+	if value == nil {
+		value = new(big.Int)
+	}
+
+	tx := &SetCodeTx{
+		ChainID:   big.NewInt(netID),
+		Nonce:     nonce,
+		GasTipCap: gasTip,
+		GasFeeCap: gasMaxFee,
+		Gas:       gasLimit,
+		To:        to,
+		Value:     value,
+		Data:      data,
+		AuthList:  auths,
+	}
+
+	sigHash, err := tx.SigHash()
+	if err != nil {
+		return nil, "", err
+	}
+	sig, err := crypto.Sign(sigHash[:], prvKey)
+	if err != nil {
+		return nil, "", errors.Wrap(err, "sign transaction")
+	}
+	tx.R = new(big.Int).SetBytes(sig[:32])
+	tx.S = new(big.Int).SetBytes(sig[32:64])
+	tx.V = new(big.Int).SetUint64(uint64(sig[64]))
+
+	dataM, err := tx.MarshalBinary()
+	if err != nil {
+		return nil, "", errors.Wrap(err, "marshal tx data")
+	}
+
+	return tx, hexutil.Encode(dataM), nil
+}
+
+// SignTx signs arbitrary TX data with the configured signer
+// and returns its hex encoding as expected by the bundle params.
+// The chain ID is taken from the TX data, for legacy TXs from the EIP-155 V
+// so the unprotected ones are not supported.
+func (self *Flashbot) SignTx(txdata types.TxData) (string, *types.Transaction, error) {
+	chainID, err := txChainID(txdata)
+	if err != nil {
+		return "", nil, err
+	}
+	chainSigner, err := self.chainSignerFor(chainID)
+	if err != nil {
+		return "", nil, err
+	}
+	return signTxData(self.TxSigner(), txdata, chainSigner)
+}
+
+// SignTxData signs the TX data with the signer and returns its hex encoding.
+func SignTxData(signer Signer, txdata types.TxData) (string, *types.Transaction, error) {
+	return signTxData(signer, txdata, nil)
+}
+
+// signTxData signs with the chain signer when the signer supports it.
+func signTxData(signer Signer, txdata types.TxData, chainSigner types.Signer) (string, *types.Transaction, error) {
+	if signer == nil {
+		return "", nil, errors.New("private key or signer is not set")
+	}
+	chainID, err := txChainID(txdata)
+	if err != nil {
+		return "", nil, err
+	}
+
+	var tx *types.Transaction
+	if s, ok := signer.(chainSignerTx); ok && chainSigner != nil {
+		tx, err = s.SignTxWith(types.NewTx(txdata), chainSigner)
+	} else {
+		tx, err = signer.SignTx(types.NewTx(txdata), chainID)
+	}
+	if err != nil {
+		return "", nil, errors.Wrap(err, "sign transaction")
+	}
+	dataM, err := tx.MarshalBinary()
+	if err != nil {
+		return "", nil, errors.Wrap(err, "marshal tx data")
+	}
+
+	return hexutil.Encode(dataM), tx, nil
+}
+
+// TxSpec describes an unsigned EIP1559 TX.
+// The chain ID and nonce are filled in when signing.
+type TxSpec struct {
+	To         *common.Address
+	Data       []byte
+	Value      *big.Int
+	Gas        uint64
+	GasFeeCap  *big.Int
+	GasTipCap  *big.Int
+	AccessList types.AccessList
+}
+
+// TxData returns the dynamic fee TX data for the spec.
+func (self TxSpec) TxData(netID int64, nonce uint64) *types.DynamicFeeTx {
+	return self.txData(big.NewInt(netID), nonce)
+}
+
+func (self TxSpec) txData(chainID *big.Int, nonce uint64) *types.DynamicFeeTx {
+	value := self.Value
+	if value == nil {
+		value = new(big.Int)
+	}
+	return &types.DynamicFeeTx{
+		ChainID:    chainID,
+		Nonce:      nonce,
+		GasTipCap:  self.GasTipCap,
+		GasFeeCap:  self.GasFeeCap,
+		Gas:        self.Gas,
+		To:         self.To,
+		Value:      value,
+		Data:       self.Data,
+		AccessList: self.AccessList,
+	}
+}
+
+// TxOption fills or adjusts a TX spec before it is signed.
+type TxOption func(ctx context.Context, from common.Address, spec *TxSpec) error
+
+// WithAutoAccessList sets the spec access list to the one generated by the node through eth_createAccessList.
+func WithAutoAccessList(node *Node) TxOption {
+	return func(ctx context.Context, from common.Address, spec *TxSpec) error {
+		if spec.To == nil {
+			return nil
+		}
+		al, _, err := node.CreateAccessList(ctx, ethereum.CallMsg{
+			From:  from,
+			To:    spec.To,
+			Gas:   spec.Gas,
+			Value: spec.Value,
+			Data:  spec.Data,
+		})
+		if err != nil {
+			return errors.Wrap(err, "create access list")
+		}
+		spec.AccessList = al
+		return nil
+	}
+}
+
+// BuildTx applies the options to the spec and signs it with the configured signer.
+func (self *Flashbot) BuildTx(ctx context.Context, netID int64, nonce uint64, spec TxSpec, opts ...TxOption) (string, *types.Transaction, error) {
+	chainID, chainSigner, err := self.ChainSigner(netID)
+	if err != nil {
+		return "", nil, err
+	}
+	return buildTx(ctx, self.TxSigner(), chainID, chainSigner, nonce, spec, opts...)
+}
+
+// BuildTx applies the options to the spec and signs it with the signer.
+func BuildTx(ctx context.Context, signer Signer, netID int64, nonce uint64, spec TxSpec, opts ...TxOption) (string, *types.Transaction, error) {
+	return buildTx(ctx, signer, big.NewInt(netID), nil, nonce, spec, opts...)
+}
+
+func buildTx(ctx context.Context, signer Signer, chainID *big.Int, chainSigner types.Signer, nonce uint64, spec TxSpec, opts ...TxOption) (string, *types.Transaction, error) {
+	if signer == nil {
+		return "", nil, errors.New("private key or signer is not set")
+	}
+	for _, opt := range opts {
+		if err := opt(ctx, signer.Address(), &spec); err != nil {
+			return "", nil, err
+		}
+	}
+	if spec.GasFeeCap == nil || spec.GasFeeCap.Sign() == 0 {
+		return "", nil, errors.New("for EIP1559 TXs the gasMaxFee should not be zero")
+	}
+	return signTxData(signer, spec.txData(chainID, nonce), chainSigner)
+}
+
+// SignTxs signs all specs with the configured signer using sequential nonces starting at the given nonce
+// and returns the hex list ready for SendBundle.
+// The specs are signed concurrently, see SignAll.
+func (self *Flashbot) SignTxs(ctx context.Context, netID int64, nonce uint64, specs []TxSpec, opts ...TxOption) ([]string, []*types.Transaction, error) {
+	return self.SignAll(ctx, netID, sequentialJobs(nonce, specs), 0, opts...)
+}
+
+func txChainID(txdata types.TxData) (*big.Int, error) {
+	var chainID *big.Int
+	switch txd := txdata.(type) {
+	case *types.DynamicFeeTx:
+		chainID = txd.ChainID
+	case *types.AccessListTx:
+		chainID = txd.ChainID
+	case *types.LegacyTx:
+		tx := types.NewTx(txd)
+		if !tx.Protected() {
+			return nil, errors.New("unprotected legacy TXs don't include a chain ID, set the EIP-155 V or use a typed TX")
+		}
+		chainID = tx.ChainId()
+	default:
+		return nil, errors.Errorf("unsupported TX data type:%T", txdata)
+	}
+	if chainID == nil || chainID.Sign() == 0 {
+		return nil, errors.New("TX data chain ID is not set")
+	}
+	return chainID, nil
+}
+
+func recoverAddress(sigHash common.Hash, r, s *big.Int, v uint8) (common.Address, error) {
+	if r == nil || s == nil {
+		return common.Address{}, errors.New("missing signature values")
+	}
+	if r.BitLen() > 256 || s.BitLen() > 256 {
+		return common.Address{}, errors.New("invalid signature values")
+	}
+	if v > 1 {
+		return common.Address{}, errors.Errorf("invalid signature y parity:%v", v)
+	}
+	sig := make([]byte, crypto.SignatureLength)
+	r.FillBytes(sig[:32])
+	s.FillBytes(sig[32:64])
+	sig[64] = v
+
+	pub, err := crypto.SigToPub(sigHash[:], sig)
+	if err != nil {
+		return common.Address{}, errors.Wrap(err, "recover public key")
+	}
+	return crypto.PubkeyToAddress(*pub), nil
+}
@@ -0,0 +1,126 @@
+// Copyright (c) The Cryptorium Authors.
+// Licensed under the MIT License.
+
+package flashbot
+
+import (
+	"context"
+	"math/big"
+	"testing"
+
+	"github.com/cryptoriums/packages/testutil"
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/common/hexutil"
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/crypto"
+)
+
+func TestSetCodeTX(t *testing.T) {
+	prvKey, err := crypto.GenerateKey()
+	testutil.Ok(t, err)
+	pubKey := crypto.PubkeyToAddress(prvKey.PublicKey)
+
+	fb, err := New(prvKey, &Api{URL: "http://localhost"})
+	testutil.Ok(t, err)
+
+	delegate := common.HexToAddress("0x000000000000000000000000000000000000dead")
+	auth, err := fb.(*Flashbot).SignSetCodeAuthorization(1, delegate, 1)
+	testutil.Ok(t, err)
+
+	authority, err := auth.Authority()
+	testutil.Ok(t, err)
+	testutil.Equals(t, pubKey, authority)
+
+	tx, txHex, err := fb.(*Flashbot).NewSignedSetCodeTX(
+		1,
+		0,
+		pubKey,
+		nil,
+		100_000,
+		big.NewInt(2e9),
+		big.NewInt(1e9),
+		nil,
+		[]SetCodeAuthorization{auth},
+	)
+	testutil.Ok(t, err)
+
+	sender, err := tx.Sender()
+	testutil.Ok(t, err)
+	testutil.Equals(t, pubKey, sender)
+
+	raw, err := hexutil.Decode(txHex)
+	testutil.Ok(t, err)
+	testutil.Equals(t, byte(SetCodeTxType), raw[0])
+
+	hash, err := tx.Hash()
+	testutil.Ok(t, err)
+	testutil.Equals(t, crypto.Keccak256Hash(raw), hash)
+
+	_, _, err = fb.(*Flashbot).NewSignedSetCodeTX(1, 0, pubKey, nil, 100_000, big.NewInt(2e9), nil, nil, nil)
+	testutil.NotOk(t, err)
+}
+
+func TestSignTx(t *testing.T) {
+	prvKey, err := crypto.GenerateKey()
+	testutil.Ok(t, err)
+	pubKey := crypto.PubkeyToAddress(prvKey.PublicKey)
+
+	fb, err := New(prvKey, &Api{URL: "http://localhost"})
+	testutil.Ok(t, err)
+
+	txHex, tx, err := fb.SignTx(&types.DynamicFeeTx{
+		ChainID:   big.NewInt(5),
+		Nonce:     3,
+		GasTipCap: big.NewInt(1e9),
+		GasFeeCap: big.NewInt(2e9),
+		Gas:       21_000,
+		To:        &pubKey,
+		Value:     big.NewInt(0),
+	})
+	testutil.Ok(t, err)
+
+	sender, err := types.Sender(types.LatestSignerForChainID(big.NewInt(5)), tx)
+	testutil.Ok(t, err)
+	testutil.Equals(t, pubKey, sender)
+
+	data, err := tx.MarshalBinary()
+	testutil.Ok(t, err)
+	testutil.Equals(t, hexutil.Encode(data), txHex)
+
+	_, _, err = fb.SignTx(&types.LegacyTx{Nonce: 3, Gas: 21_000, To: &pubKey})
+	testutil.NotOk(t, err)
+
+	// The protected legacy TXs carry the chain ID in the EIP-155 V
+	// which also works for the chain IDs that don't fit in an int64.
+	for _, chainID := range []*big.Int{big.NewInt(5), new(big.Int).Lsh(big.NewInt(1), 70)} {
+		v := new(big.Int).Add(new(big.Int).Mul(chainID, big.NewInt(2)), big.NewInt(35))
+		_, tx, err = fb.SignTx(&types.LegacyTx{Nonce: 3, Gas: 21_000, GasPrice: big.NewInt(1), To: &pubKey, V: v})
+		testutil.Ok(t, err)
+		testutil.Equals(t, chainID, tx.ChainId())
+		sender, err = types.Sender(types.NewEIP155Signer(chainID), tx)
+		testutil.Ok(t, err)
+		testutil.Equals(t, pubKey, sender)
+	}
+}
+
+func TestSignTxs(t *testing.T) {
+	prvKey, err := crypto.GenerateKey()
+	testutil.Ok(t, err)
+	pubKey := crypto.PubkeyToAddress(prvKey.PublicKey)
+
+	fb, err := New(prvKey, &Api{URL: "http://localhost"})
+	testutil.Ok(t, err)
+
+	spec := TxSpec{
+		To:        &pubKey,
+		Gas:       21_000,
+		GasFeeCap: big.NewInt(2e9),
+		GasTipCap: big.NewInt(1e9),
+	}
+	txsHex, txs, err := fb.(*Flashbot).SignTxs(context.Background(), 5, 7, []TxSpec{spec, spec, spec})
+	testutil.Ok(t, err)
+	testutil.Equals(t, 3, len(txsHex))
+	for i, tx := range txs {
+		testutil.Equals(t, uint64(7+i), tx.Nonce())
+	}
+}
@@ -0,0 +1,242 @@
+// Copyright (c) The Cryptorium Authors.
+// Licensed under the MIT License.
+
+package flashbot
+
+import (
+	"bytes"
+	"fmt"
+	"math"
+	"math/big"
+	"strings"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/common/hexutil"
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/params"
+	"github.com/pkg/errors"
+)
+
+type ViolationKind string
+
+const (
+	ViolationChainID      ViolationKind = "chainID"
+	ViolationDuplicate    ViolationKind = "duplicate"
+	ViolationNonce        ViolationKind = "nonce"
+	ViolationIntrinsicGas ViolationKind = "intrinsicGas"
+	ViolationFeeCap       ViolationKind = "feeCap"
+	ViolationTipCap       ViolationKind = "tipCap"
+	ViolationBlockGas     ViolationKind = "blockGas"
+	ViolationTxCount      ViolationKind = "txCount"
+	ViolationBodySize     ViolationKind = "bodySize"
+)
+
+// Violation is a single failed bundle check.
+type Violation struct {
+	Kind ViolationKind
+	// Index of the offending TX, -1 for the bundle wide checks.
+	Index int
+	Msg   string
+}
+
+func (self Violation) String() string {
+	return self.Msg
+}
+
+// ValidationError is returned when a bundle fails any of the pre-submission checks.
+type ValidationError struct {
+	Violations []Violation
+}
+
+func (self *ValidationError) Error() string {
+	msgs := make([]string, len(self.Violations))
+	for i, v := range self.Violations {
+		msgs[i] = v.Msg
+	}
+	return "invalid bundle: " + strings.Join(msgs, "; ")
+}
+
+// Has returns whether any of the violations is of the given kind.
+func (self *ValidationError) Has(kind ViolationKind) bool {
+	for _, v := range self.Violations {
+		if v.Kind == kind {
+			return true
+		}
+	}
+	return false
+}
+
+// RelayLimits are the relay maximums for a single bundle, zero values skip the checks.
+type RelayLimits struct {
+	MaxTxs int
+	// MaxBodySize is the maximum size in bytes of the JSON-RPC request body.
+	MaxBodySize int
+}
+
+// Check returns the violations for a bundle with the given TX count and request body size.
+func (self RelayLimits) Check(txCount, bodySize int) []Violation {
+	var violations []Violation
+	if self.MaxTxs != 0 && txCount > self.MaxTxs {
+		violations = append(violations, Violation{
+			Kind:  ViolationTxCount,
+			Index: -1,
+			Msg:   fmt.Sprintf("TX count:%v higher than the relay max:%v", txCount, self.MaxTxs),
+		})
+	}
+	if self.MaxBodySize != 0 && bodySize > self.MaxBodySize {
+		violations = append(violations, Violation{
+			Kind:  ViolationBodySize,
+			Index: -1,
+			Msg:   fmt.Sprintf("request size:%v bytes higher than the relay max:%v", bodySize, self.MaxBodySize),
+		})
+	}
+	return violations
+}
+
+// BundleRequestSize returns the size in bytes of the eth_sendBundle request body for the TXs.
+// The block number is assumed to be the longest possible so the result is an upper bound
+// for bundles without extra params.
+func BundleRequestSize(txsHex []string) (int, error) {
+	return requestSize("eth_sendBundle", ParamsSend{Txs: txsHex, BlockNum: hexutil.EncodeUint64(math.MaxUint64)})
+}
+
+func requestSize(method string, params ...interface{}) (int, error) {
+	var buf bytes.Buffer
+	if err := encodeMessage(&buf, StdCodec{}, method, params...); err != nil {
+		return 0, err
+	}
+	return buf.Len(), nil
+}
+
+// The EIP-3860 init code cost and the EIP-7702 authorization cost
+// which the params package of the used go-ethereum version predates.
+const (
+	initCodeWordGas = 2
+	setCodeAuthGas  = 25_000
+)
+
+type BundleValidationOpts struct {
+	// ChainID all TXs should be signed for, when nil the chain ID of the first TX is used.
+	ChainID *big.Int
+	// BlockGasLimit of the target block, zero skips the check.
+	BlockGasLimit uint64
+	// BaseFee estimate for the target block, nil skips the check.
+	BaseFee *big.Int
+	// Nonces are the current account nonces by sender.
+	// Senders that are not included are only checked for sequential nonces within the bundle.
+	Nonces map[common.Address]uint64
+	// Limits of the relay the bundle is sent to.
+	Limits RelayLimits
+}
+
+// ValidateBundle runs the checks which would otherwise fail only after a round trip to the relay.
+// All violations are included in the returned *ValidationError.
+func ValidateBundle(txsHex []string, opts BundleValidationOpts) error {
+	if len(txsHex) == 0 {
+		return errors.New("bundle has no TXs")
+	}
+	txs := make([]*bundleTx, 0, len(txsHex))
+	senders := make([]common.Address, 0, len(txsHex))
+	for i, txHex := range txsHex {
+		tx, err := decodeBundleTx(txHex)
+		if err != nil {
+			return errors.Wrapf(err, "decode TX index:%v", i)
+		}
+		sender, err := tx.sender()
+		if err != nil {
+			return errors.Wrapf(err, "TX index:%v", i)
+		}
+		txs = append(txs, tx)
+		senders = append(senders, sender)
+	}
+
+	var (
+		violations []Violation
+		chainID    = opts.ChainID
+		totalGas   uint64
+		hashes     = make(map[common.Hash]int)
+		nonces     = make(map[common.Address]uint64)
+	)
+	for addr, nonce := range opts.Nonces {
+		nonces[addr] = nonce
+	}
+
+	for i, tx := range txs {
+		sender := senders[i]
+		if chainID == nil {
+			chainID = tx.chainID
+		}
+		if tx.chainID.Cmp(chainID) != 0 {
+			violations = append(violations, txViolation(ViolationChainID, i, fmt.Sprintf("TX index:%v chain ID:%v doesn't match:%v", i, tx.chainID, chainID)))
+		}
+
+		if prev, ok := hashes[tx.hash]; ok {
+			violations = append(violations, txViolation(ViolationDuplicate, i, fmt.Sprintf("TX index:%v is a duplicate of index:%v hash:%v", i, prev, tx.hash.Hex())))
+		}
+		hashes[tx.hash] = i
+
+		if exp, ok := nonces[sender]; ok && tx.nonce != exp {
+			violations = append(violations, txViolation(ViolationNonce, i, fmt.Sprintf("TX index:%v sender:%v nonce:%v expected:%v", i, sender.Hex(), tx.nonce, exp)))
+		}
+		nonces[sender] = tx.nonce + 1
+
+		intrinsic := IntrinsicGas(tx.data, tx.accessList, tx.to == nil)
+		intrinsic += uint64(len(tx.authList)) * setCodeAuthGas
+		if tx.gas < intrinsic {
+			violations = append(violations, txViolation(ViolationIntrinsicGas, i, fmt.Sprintf("TX index:%v gas limit:%v lower than the intrinsic gas:%v", i, tx.gas, intrinsic)))
+		}
+		totalGas += tx.gas
+
+		if opts.BaseFee != nil && tx.gasFeeCap.Cmp(opts.BaseFee) < 0 {
+			violations = append(violations, txViolation(ViolationFeeCap, i, fmt.Sprintf("TX index:%v fee cap:%v lower than the base fee:%v", i, tx.gasFeeCap, opts.BaseFee)))
+		}
+		if tx.gasTipCap.Cmp(tx.gasFeeCap) > 0 {
+			violations = append(violations, txViolation(ViolationTipCap, i, fmt.Sprintf("TX index:%v tip cap:%v higher than the fee cap:%v", i, tx.gasTipCap, tx.gasFeeCap)))
+		}
+	}
+
+	if opts.BlockGasLimit != 0 && totalGas > opts.BlockGasLimit {
+		violations = append(violations, Violation{
+			Kind:  ViolationBlockGas,
+			Index: -1,
+			Msg:   fmt.Sprintf("total gas limit:%v higher than the block gas limit:%v", totalGas, opts.BlockGasLimit),
+		})
+	}
+
+	if opts.Limits != (RelayLimits{}) {
+		size, err := BundleRequestSize(txsHex)
+		if err != nil {
+			return errors.Wrap(err, "bundle request size")
+		}
+		violations = append(violations, opts.Limits.Check(len(txsHex), size)...)
+	}
+
+	if len(violations) > 0 {
+		return &ValidationError{Violations: violations}
+	}
+	return nil
+}
+
+func txViolation(kind ViolationKind, index int, msg string) Violation {
+	return Violation{Kind: kind, Index: index, Msg: msg}
+}
+
+// IntrinsicGas returns the gas charged before any execution
+// according to the current (post Shanghai) rules.
+func IntrinsicGas(data []byte, accessList types.AccessList, isContractCreation bool) uint64 {
+	gas := params.TxGas
+	if isContractCreation {
+		gas = params.TxGasContractCreation
+		gas += initCodeWordGas * ((uint64(len(data)) + 31) / 32)
+	}
+	for _, b := range data {
+		if b == 0 {
+			gas += params.TxDataZeroGas
+		} else {
+			gas += params.TxDataNonZeroGasEIP2028
+		}
+	}
+	gas += uint64(len(accessList)) * params.TxAccessListAddressGas
+	gas += uint64(accessList.StorageKeys()) * params.TxAccessListStorageKeyGas
+	return gas
+}
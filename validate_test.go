@@ -0,0 +1,152 @@
+// Copyright (c) The Cryptorium Authors.
+// Licensed under the MIT License.
+
+package flashbot
+
+import (
+	"context"
+	"encoding/json"
+	"math/big"
+	"testing"
+
+	"github.com/cryptoriums/packages/testutil"
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/crypto"
+	"github.com/pkg/errors"
+)
+
+func TestValidateBundle(t *testing.T) {
+	prvKey, err := crypto.GenerateKey()
+	testutil.Ok(t, err)
+	pubKey := crypto.PubkeyToAddress(prvKey.PublicKey)
+
+	fb, err := New(prvKey, &Api{URL: "http://localhost"})
+	testutil.Ok(t, err)
+	f := fb.(*Flashbot)
+
+	spec := TxSpec{
+		To:        &pubKey,
+		Gas:       21_000,
+		GasFeeCap: big.NewInt(2e9),
+		GasTipCap: big.NewInt(1e9),
+	}
+	txsHex, _, err := f.SignTxs(context.Background(), 5, 3, []TxSpec{spec, spec})
+	testutil.Ok(t, err)
+
+	testutil.Ok(t, ValidateBundle(txsHex, BundleValidationOpts{
+		ChainID:       big.NewInt(5),
+		BlockGasLimit: 30_000_000,
+		BaseFee:       big.NewInt(1e9),
+		Nonces:        map[common.Address]uint64{pubKey: 3},
+	}))
+
+	// Wrong chain, gas over the block limit, fee cap under the base fee and wrong starting nonce.
+	testutil.NotOk(t, ValidateBundle(txsHex, BundleValidationOpts{ChainID: big.NewInt(1)}))
+	testutil.NotOk(t, ValidateBundle(txsHex, BundleValidationOpts{BlockGasLimit: 30_000}))
+	testutil.NotOk(t, ValidateBundle(txsHex, BundleValidationOpts{BaseFee: big.NewInt(3e9)}))
+	testutil.NotOk(t, ValidateBundle(txsHex, BundleValidationOpts{Nonces: map[common.Address]uint64{pubKey: 2}}))
+
+	// Out of order nonces and duplicates.
+	testutil.NotOk(t, ValidateBundle([]string{txsHex[1], txsHex[0]}, BundleValidationOpts{}))
+	testutil.NotOk(t, ValidateBundle([]string{txsHex[0], txsHex[0]}, BundleValidationOpts{}))
+
+	// Gas limit under the intrinsic gas.
+	spec.Data = []byte{1, 2, 3}
+	txsHex, _, err = f.SignTxs(context.Background(), 5, 3, []TxSpec{spec})
+	testutil.Ok(t, err)
+	testutil.NotOk(t, ValidateBundle(txsHex, BundleValidationOpts{}))
+}
+
+func TestValidateBundleLimits(t *testing.T) {
+	prvKey, err := crypto.GenerateKey()
+	testutil.Ok(t, err)
+	pubKey := crypto.PubkeyToAddress(prvKey.PublicKey)
+
+	fb, err := New(prvKey, &Api{URL: "http://localhost"})
+	testutil.Ok(t, err)
+	f := fb.(*Flashbot)
+
+	spec := TxSpec{
+		To:        &pubKey,
+		Gas:       21_000,
+		GasFeeCap: big.NewInt(2e9),
+		GasTipCap: big.NewInt(1e9),
+	}
+	txsHex, _, err := f.SignTxs(context.Background(), 5, 0, []TxSpec{spec, spec, spec})
+	testutil.Ok(t, err)
+	size, err := BundleRequestSize(txsHex)
+	testutil.Ok(t, err)
+
+	testutil.Ok(t, ValidateBundle(txsHex, BundleValidationOpts{Limits: RelayLimits{MaxTxs: 3, MaxBodySize: size}}))
+
+	err = ValidateBundle(txsHex, BundleValidationOpts{
+		BlockGasLimit: 50_000,
+		Limits:        RelayLimits{MaxTxs: 2, MaxBodySize: size - 1},
+	})
+	var verr *ValidationError
+	testutil.Assert(t, errors.As(err, &verr), "unexpected error type:%v", err)
+	testutil.Equals(t, 3, len(verr.Violations))
+	testutil.Assert(t, verr.Has(ViolationBlockGas), "missing block gas violation")
+	testutil.Assert(t, verr.Has(ViolationTxCount), "missing tx count violation")
+	testutil.Assert(t, verr.Has(ViolationBodySize), "missing body size violation")
+	testutil.Assert(t, !verr.Has(ViolationNonce), "unexpected nonce violation")
+	for _, v := range verr.Violations {
+		testutil.Equals(t, -1, v.Index)
+	}
+
+	err = ValidateBundle([]string{txsHex[1], txsHex[0]}, BundleValidationOpts{})
+	testutil.Assert(t, errors.As(err, &verr), "unexpected error type:%v", err)
+	testutil.Equals(t, []Violation{{
+		Kind:  ViolationNonce,
+		Index: 1,
+		Msg:   verr.Violations[0].Msg,
+	}}, verr.Violations)
+}
+
+func TestSendBundleRelayLimits(t *testing.T) {
+	mock := newRelayMock(t, func(method string, params json.RawMessage) (interface{}, *jsonError) {
+		return map[string]string{"bundleHash": "0x01"}, nil
+	})
+	f := newTestFlashbot(t, mock.URL)
+	f.api.Limits = RelayLimits{MaxTxs: 1}
+
+	_, err := f.SendBundleParams(context.Background(), ParamsSend{BlockNum: "0x1", Txs: []string{"0x01", "0x02"}})
+	var verr *ValidationError
+	testutil.Assert(t, errors.As(err, &verr), "unexpected error type:%v", err)
+	testutil.Assert(t, verr.Has(ViolationTxCount), "missing tx count violation")
+	testutil.Equals(t, 0, len(mock.Methods()))
+
+	_, err = f.SendBundleParams(context.Background(), ParamsSend{BlockNum: "0x1", Txs: []string{"0x01"}})
+	testutil.Ok(t, err)
+	testutil.Equals(t, 1, len(mock.Methods()))
+}
+
+func TestValidateBundleSetCode(t *testing.T) {
+	prvKey, err := crypto.GenerateKey()
+	testutil.Ok(t, err)
+	pubKey := crypto.PubkeyToAddress(prvKey.PublicKey)
+	fb, err := New(prvKey, &Api{URL: "http://localhost"})
+	testutil.Ok(t, err)
+	f := fb.(*Flashbot)
+
+	txHex, _, err := f.BuildTx(context.Background(), 5, 0, TxSpec{To: &pubKey, Gas: 21_000, GasFeeCap: big.NewInt(2e9)})
+	testutil.Ok(t, err)
+	auth, err := f.SignSetCodeAuthorization(5, common.HexToAddress("0xd"), 2)
+	testutil.Ok(t, err)
+	_, setCodeHex, err := f.NewSignedSetCodeTX(5, 1, pubKey, nil, 50_000, big.NewInt(2e9), big.NewInt(1e9), nil, []SetCodeAuthorization{auth})
+	testutil.Ok(t, err)
+
+	testutil.Ok(t, ValidateBundle([]string{txHex, setCodeHex}, BundleValidationOpts{
+		ChainID: big.NewInt(5),
+		Nonces:  map[common.Address]uint64{pubKey: 0},
+	}))
+	testutil.NotOk(t, ValidateBundle([]string{setCodeHex, txHex}, BundleValidationOpts{}))
+
+	// The authorization cost is part of the intrinsic gas.
+	_, setCodeHex, err = f.NewSignedSetCodeTX(5, 1, pubKey, nil, 30_000, big.NewInt(2e9), big.NewInt(1e9), nil, []SetCodeAuthorization{auth})
+	testutil.Ok(t, err)
+	err = ValidateBundle([]string{setCodeHex}, BundleValidationOpts{})
+	var verr *ValidationError
+	testutil.Assert(t, errors.As(err, &verr), "unexpected error type:%v", err)
+	testutil.Assert(t, verr.Has(ViolationIntrinsicGas), "missing intrinsic gas violation")
+}